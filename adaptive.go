@@ -0,0 +1,137 @@
+package limiter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// AdaptiveLimiter 根据下游反馈按 AIMD（加性增、乘性减）自动调整被包装限流器的速率，
+// 用于保护不稳定的上游：调用成功时缓慢提速，失败时快速降速。
+//
+// 学到的速率写入被包装限流器的按 key 覆盖配置（rate 字段），由 Lua 脚本原子更新；
+// 所有实例的限流脚本读取同一个覆盖配置，因此会收敛到同一个速率。
+// 被包装的限流器必须是开启了覆盖模式的 *TokenBucketLimiter 或 *LeakyBucketLimiter。
+type AdaptiveLimiter struct {
+	// RateLimiter 被包装的限流器，Allow / Wait / State 等调用直接转发给它
+	RateLimiter
+
+	client      *redis.Client
+	overrideKey string
+
+	// InitialRate 还没有学到速率时的初始速率，默认为被包装限流器的速率
+	InitialRate float64
+	// MinRate 速率下限，默认为 InitialRate 的 1%
+	MinRate float64
+	// MaxRate 速率上限，默认为 InitialRate
+	MaxRate float64
+	// Increase 每次成功增加的速率，默认为 MaxRate 的 1%
+	Increase float64
+	// Decrease 每次失败乘以的系数（0~1），默认 0.5
+	Decrease float64
+	// Cooldown 两次减速之间的最小间隔，默认 1s
+	Cooldown time.Duration
+
+	// ServerTime 为 true（默认）时脚本使用 Redis TIME 计算减速间隔
+	ServerTime bool
+	// Clock 时钟，默认 SystemClock
+	Clock Clock
+}
+
+// NewAdaptiveLimiter 为 l 包装自适应调速能力。
+// l 必须是通过 WithTokenBucketOverrides / WithLeakyBucketOverrides 开启了覆盖模式的令牌桶或漏桶。
+func NewAdaptiveLimiter(l RateLimiter, opts ...AdaptiveOption) *AdaptiveLimiter {
+	a := &AdaptiveLimiter{
+		RateLimiter: l,
+		Decrease:    0.5,
+		Cooldown:    time.Second,
+		ServerTime:  true,
+		Clock:       SystemClock,
+	}
+
+	switch v := l.(type) {
+	case *TokenBucketLimiter:
+		if v.OverridePrefix == "" {
+			panic("adaptive: token bucket overrides are not enabled")
+		}
+		a.client, a.overrideKey = v.client, v.overrideKey()
+		a.InitialRate, _ = v.limits()
+	case *LeakyBucketLimiter:
+		if v.OverridePrefix == "" {
+			panic("adaptive: leaky bucket overrides are not enabled")
+		}
+		a.client, a.overrideKey = v.client, v.overrideKey()
+		a.InitialRate, _ = v.limits()
+	default:
+		panic("adaptive: limiter must be *TokenBucketLimiter or *LeakyBucketLimiter")
+	}
+
+	for _, opt := range opts {
+		opt(a)
+	}
+
+	if a.MaxRate <= 0 {
+		a.MaxRate = a.InitialRate
+	}
+	if a.MinRate <= 0 {
+		a.MinRate = a.InitialRate / 100
+	}
+	if a.Increase <= 0 {
+		a.Increase = a.MaxRate / 100
+	}
+	if a.MinRate > a.MaxRate {
+		panic("adaptive: min rate must <= max rate")
+	}
+	if a.Decrease <= 0 || a.Decrease >= 1 {
+		panic("adaptive: decrease must in (0, 1)")
+	}
+	return a
+}
+
+// Record 上报一次下游调用结果并返回调整后的速率。
+// ok 为 true 时速率增加 Increase，为 false 时乘以 Decrease（Cooldown 内只减一次）。
+func (a *AdaptiveLimiter) Record(ctx context.Context, ok bool) (float64, error) {
+	okArg := 0
+	if ok {
+		okArg = 1
+	}
+	res, err := adaptiveScript.Run(
+		ctx,
+		a.client,
+		[]string{a.overrideKey},
+		scriptNow(a.ServerTime, a.Clock),
+		okArg,
+		a.InitialRate,
+		a.MinRate,
+		a.MaxRate,
+		a.Increase,
+		a.Decrease,
+		a.Cooldown.Milliseconds(),
+	).Text()
+	if err != nil {
+		return 0, err
+	}
+	rate, err := strconv.ParseFloat(res, 64)
+	if err != nil {
+		return 0, fmt.Errorf("adaptive: unexpected script result: %q", res)
+	}
+	return rate, nil
+}
+
+// Rate 返回当前学到的速率；还没有上报过结果时返回 InitialRate。
+func (a *AdaptiveLimiter) Rate(ctx context.Context) (float64, error) {
+	rate, err := a.client.HGet(ctx, a.overrideKey, "rate").Float64()
+	if errors.Is(err, redis.Nil) {
+		return a.InitialRate, nil
+	}
+	return rate, err
+}
+
+// ResetRate 清除学到的速率，恢复使用被包装限流器自身的配置。
+func (a *AdaptiveLimiter) ResetRate(ctx context.Context) error {
+	return a.client.HDel(ctx, a.overrideKey, "rate", "decreased_at").Err()
+}
@@ -0,0 +1,68 @@
+package limiter
+
+import "time"
+
+// AdaptiveOption 为自适应限流器的配置项。
+type AdaptiveOption func(*AdaptiveLimiter)
+
+// WithAdaptiveRateRange 设置速率的上下限。
+func WithAdaptiveRateRange(minRate, maxRate float64) AdaptiveOption {
+	return func(a *AdaptiveLimiter) {
+		if minRate <= 0 || maxRate <= 0 {
+			panic("adaptive: rate range must > 0")
+		}
+		a.MinRate = minRate
+		a.MaxRate = maxRate
+	}
+}
+
+// WithAdaptiveInitialRate 设置还没有学到速率时的初始速率。
+func WithAdaptiveInitialRate(rate float64) AdaptiveOption {
+	return func(a *AdaptiveLimiter) {
+		if rate > 0 {
+			a.InitialRate = rate
+		}
+	}
+}
+
+// WithAdaptiveIncrease 设置每次成功增加的速率。
+func WithAdaptiveIncrease(step float64) AdaptiveOption {
+	return func(a *AdaptiveLimiter) {
+		if step > 0 {
+			a.Increase = step
+		}
+	}
+}
+
+// WithAdaptiveDecrease 设置每次失败乘以的系数（0~1）。
+func WithAdaptiveDecrease(factor float64) AdaptiveOption {
+	return func(a *AdaptiveLimiter) {
+		a.Decrease = factor
+	}
+}
+
+// WithAdaptiveCooldown 设置两次减速之间的最小间隔。
+func WithAdaptiveCooldown(d time.Duration) AdaptiveOption {
+	return func(a *AdaptiveLimiter) {
+		if d >= 0 {
+			a.Cooldown = d
+		}
+	}
+}
+
+// WithAdaptiveServerTime 设置是否使用 Redis TIME 计算减速间隔（默认开启）。
+func WithAdaptiveServerTime(enabled bool) AdaptiveOption {
+	return func(a *AdaptiveLimiter) {
+		a.ServerTime = enabled
+	}
+}
+
+// WithAdaptiveClock 注入时钟（主要用于测试），同时关闭 ServerTime。
+func WithAdaptiveClock(c Clock) AdaptiveOption {
+	return func(a *AdaptiveLimiter) {
+		if c != nil {
+			a.Clock = c
+			a.ServerTime = false
+		}
+	}
+}
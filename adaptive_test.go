@@ -0,0 +1,74 @@
+package limiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdaptiveLimiter(t *testing.T) {
+	client := newSpecClient(t)
+	ctx := context.Background()
+
+	now := time.UnixMilli(1_700_000_000_000)
+	tb := NewTokenBucketLimiter(client, "adaptive",
+		WithTokenBucketRate(100), WithTokenBucketCapacity(100), WithTokenBucketOverrides(""))
+	a := NewAdaptiveLimiter(tb,
+		WithAdaptiveRateRange(10, 100),
+		WithAdaptiveIncrease(5),
+		WithAdaptiveClock(ClockFunc(func() time.Time { return now })),
+	)
+
+	rate, err := a.Rate(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, float64(100), rate)
+
+	// 失败：乘性减速
+	rate, err = a.Record(ctx, false)
+	assert.NoError(t, err)
+	assert.Equal(t, float64(50), rate)
+
+	// Cooldown 内的连续失败不会重复减速
+	rate, err = a.Record(ctx, false)
+	assert.NoError(t, err)
+	assert.Equal(t, float64(50), rate)
+
+	now = now.Add(time.Second)
+	rate, err = a.Record(ctx, false)
+	assert.NoError(t, err)
+	assert.Equal(t, float64(25), rate)
+
+	// 成功：加性提速
+	rate, err = a.Record(ctx, true)
+	assert.NoError(t, err)
+	assert.Equal(t, float64(30), rate)
+
+	// 学到的速率通过覆盖配置对所有实例生效
+	o, ok, err := tb.GetOverride(ctx)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, float64(30), o.Rate)
+
+	// 不低于下限
+	for i := 0; i < 5; i++ {
+		now = now.Add(time.Second)
+		rate, err = a.Record(ctx, false)
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, float64(10), rate)
+
+	assert.NoError(t, a.ResetRate(ctx))
+	rate, err = a.Rate(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, float64(100), rate)
+
+	ok, err = a.Allow(ctx)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	assert.Panics(t, func() {
+		NewAdaptiveLimiter(NewTokenBucketLimiter(client, "adaptive"))
+	})
+}
@@ -0,0 +1,325 @@
+// Package adminapi 提供可嵌入的限流器运维管理 HTTP 接口：
+// 列出已注册的限流器、查询状态、重置（人工解封）以及设置按 key 覆盖的限额，
+// 免去手工拼 redis-cli 命令排查限流问题。
+//
+// 接口（均返回 JSON）：
+//
+//	GET    /limiters                   列出已注册的限流器
+//	GET    /limiters/{name}/state      查询状态，分片限流器需通过 ?key= 指定 shardKey
+//	POST   /limiters/{name}/reset      重置，分片限流器通过 ?key= 指定 shardKey，或 ?all=true 重置全部分片
+//	PUT    /limiters/{name}/override   写入覆盖配置，请求体为 {"rate": 100, "capacity": 200}
+//	DELETE /limiters/{name}/override   删除覆盖配置
+//	GET    /keys?match=user:*          扫描 Redis 中当前存在的限流 key，需要通过 WithKeyScanner 开启
+//
+// Server 实现了 http.Handler，挂载到子路径时请配合 http.StripPrefix 使用。
+package adminapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	limiter "github.com/lifei6671/go-redis-limiter"
+)
+
+// overrider 是支持按 key 覆盖限额的限流器（令牌桶、漏桶）。
+type overrider interface {
+	SetOverride(ctx context.Context, o limiter.LimitOverride) error
+	ClearOverride(ctx context.Context) error
+}
+
+// Option 为管理接口的配置项。
+type Option func(*Server)
+
+// WithAuthToken 要求请求携带 "Authorization: Bearer <token>"，为空表示不校验（默认）。
+// 管理接口可以解封任意 key，暴露到内网以外时务必开启。
+func WithAuthToken(token string) Option {
+	return func(s *Server) {
+		s.token = token
+	}
+}
+
+// WithRegistry 使用 r 管理限流器，默认每个 Server 使用独立的 Registry。
+// 传入 limiter.DefaultRegistry 或与 ConfigWatcher 共享的 Registry 后，其中的限流器无需再单独 Register。
+func WithRegistry(r *limiter.Registry) Option {
+	return func(s *Server) {
+		if r != nil {
+			s.registry = r
+		}
+	}
+}
+
+// WithKeyScanner 开启 GET /keys 接口，使用 scanner 扫描 Redis 中当前存在的限流 key。
+func WithKeyScanner(scanner *limiter.KeyScanner) Option {
+	return func(s *Server) {
+		s.scanner = scanner
+	}
+}
+
+// Server 是限流器运维管理接口。
+type Server struct {
+	registry *limiter.Registry
+
+	token   string
+	scanner *limiter.KeyScanner
+	mux     *http.ServeMux
+}
+
+var _ http.Handler = (*Server)(nil)
+
+// New 创建一个管理接口，限流器通过 Register / RegisterSharded 注册。
+func New(opts ...Option) *Server {
+	s := &Server{
+		registry: limiter.NewRegistry(),
+		mux:      http.NewServeMux(),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	s.mux.HandleFunc("GET /limiters", s.handleList)
+	s.mux.HandleFunc("GET /limiters/{name}/state", s.handleState)
+	s.mux.HandleFunc("POST /limiters/{name}/reset", s.handleReset)
+	s.mux.HandleFunc("PUT /limiters/{name}/override", s.handleSetOverride)
+	s.mux.HandleFunc("DELETE /limiters/{name}/override", s.handleClearOverride)
+	s.mux.HandleFunc("GET /keys", s.handleKeys)
+	return s
+}
+
+// Register 以 name 注册一个单桶限流器，同名时覆盖。
+func (s *Server) Register(name string, l limiter.RateLimiter) {
+	if name == "" {
+		panic("adminapi: name is empty")
+	}
+	if l == nil {
+		panic("adminapi: limiter is nil")
+	}
+	s.registry.Register(name, l)
+}
+
+// RegisterSharded 以 name 注册一个分片限流器，同名时覆盖。
+func (s *Server) RegisterSharded(name string, l limiter.RateShardedLimiter) {
+	if name == "" {
+		panic("adminapi: name is empty")
+	}
+	if l == nil {
+		panic("adminapi: limiter is nil")
+	}
+	s.registry.RegisterSharded(name, l)
+}
+
+// Unregister 移除名为 name 的限流器。
+func (s *Server) Unregister(name string) {
+	s.registry.Unregister(name)
+}
+
+// ServeHTTP 实现 http.Handler。
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.token != "" && r.Header.Get("Authorization") != "Bearer "+s.token {
+		writeError(w, http.StatusUnauthorized, errors.New("unauthorized"))
+		return
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+// LimiterInfo 是 GET /limiters 返回的单个限流器信息。
+type LimiterInfo struct {
+	Name     string `json:"name"`
+	Sharded  bool   `json:"sharded"`
+	Override bool   `json:"override"` // 是否支持覆盖配置
+}
+
+// State 是状态接口返回的 JSON 结构，字段与 limiter.LimiterState 一一对应。
+type State struct {
+	Key               string  `json:"key"`
+	Type              string  `json:"type"`
+	Level             float64 `json:"level"`
+	Remaining         float64 `json:"remaining"`
+	Capacity          float64 `json:"capacity"`
+	SoftLimit         float64 `json:"soft_limit,omitempty"`
+	Overage           float64 `json:"overage,omitempty"`
+	Rate              float64 `json:"rate"`
+	LastUpdated       int64   `json:"last_updated"`
+	NextAvailableTime int64   `json:"next_available_time"`
+}
+
+// Key 是 GET /keys 返回的单个限流 key，字段与 limiter.KeyInfo 一一对应。
+type Key struct {
+	Key   string `json:"key"`
+	Type  string `json:"type"`
+	Shard int    `json:"shard"` // 非分片 key 为 -1
+}
+
+// Override 是覆盖配置接口的请求体，零值字段表示不覆盖。
+type Override struct {
+	Rate     float64 `json:"rate"`
+	Capacity float64 `json:"capacity"`
+}
+
+func (s *Server) handleList(w http.ResponseWriter, _ *http.Request) {
+	entries := s.registry.Entries()
+	list := make([]LimiterInfo, 0, len(entries))
+	for _, e := range entries {
+		_, ok := e.Limiter.(overrider)
+		list = append(list, LimiterInfo{Name: e.Name, Sharded: e.Sharded != nil, Override: ok})
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+func (s *Server) handleState(w http.ResponseWriter, r *http.Request) {
+	e, ok := s.lookup(w, r)
+	if !ok {
+		return
+	}
+
+	var (
+		st  limiter.LimiterState
+		err error
+	)
+	if e.Sharded != nil {
+		key := r.URL.Query().Get("key")
+		if key == "" {
+			writeError(w, http.StatusBadRequest, errors.New("query parameter key is required for sharded limiter"))
+			return
+		}
+		st, err = e.Sharded.State(r.Context(), key)
+	} else {
+		st, err = e.Limiter.State(r.Context())
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, State{
+		Key:               st.Key,
+		Type:              st.Type,
+		Level:             st.Level,
+		Remaining:         st.Remaining,
+		Capacity:          st.Capacity,
+		SoftLimit:         st.SoftLimit,
+		Overage:           st.Overage,
+		Rate:              st.Rate,
+		LastUpdated:       st.LastUpdated,
+		NextAvailableTime: st.NextAvailableTime,
+	})
+}
+
+func (s *Server) handleReset(w http.ResponseWriter, r *http.Request) {
+	e, ok := s.lookup(w, r)
+	if !ok {
+		return
+	}
+
+	var err error
+	switch {
+	case e.Limiter != nil:
+		err = e.Limiter.Reset(r.Context())
+	case r.URL.Query().Get("key") != "":
+		err = e.Sharded.Reset(r.Context(), r.URL.Query().Get("key"))
+	default:
+		all, _ := strconv.ParseBool(r.URL.Query().Get("all"))
+		if !all {
+			writeError(w, http.StatusBadRequest, errors.New("query parameter key or all=true is required for sharded limiter"))
+			return
+		}
+		err = e.Sharded.ResetAll(r.Context())
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleSetOverride(w http.ResponseWriter, r *http.Request) {
+	o, ok := s.overrider(w, r)
+	if !ok {
+		return
+	}
+
+	var body Override
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid body: %w", err))
+		return
+	}
+	if body.Rate < 0 || body.Capacity < 0 {
+		writeError(w, http.StatusBadRequest, errors.New("rate and capacity must >= 0"))
+		return
+	}
+
+	err := o.SetOverride(r.Context(), limiter.LimitOverride{Rate: body.Rate, Capacity: body.Capacity})
+	writeOverrideResult(w, err)
+}
+
+func (s *Server) handleClearOverride(w http.ResponseWriter, r *http.Request) {
+	o, ok := s.overrider(w, r)
+	if !ok {
+		return
+	}
+	writeOverrideResult(w, o.ClearOverride(r.Context()))
+}
+
+func (s *Server) handleKeys(w http.ResponseWriter, r *http.Request) {
+	if s.scanner == nil {
+		writeError(w, http.StatusNotImplemented, errors.New("key scanning is not enabled"))
+		return
+	}
+
+	infos, err := s.scanner.ScanKeys(r.Context(), r.URL.Query().Get("match"))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	keys := make([]Key, 0, len(infos))
+	for _, info := range infos {
+		keys = append(keys, Key{Key: info.Key, Type: info.Type, Shard: info.Shard})
+	}
+	writeJSON(w, http.StatusOK, keys)
+}
+
+// lookup 按路径中的 name 查找限流器，找不到时写出 404。
+func (s *Server) lookup(w http.ResponseWriter, r *http.Request) (limiter.RegistryEntry, bool) {
+	name := r.PathValue("name")
+	e, ok := s.registry.Lookup(name)
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("limiter %q not found", name))
+	}
+	return e, ok
+}
+
+// overrider 查找支持覆盖配置的限流器，不支持时写出 400。
+func (s *Server) overrider(w http.ResponseWriter, r *http.Request) (overrider, bool) {
+	e, ok := s.lookup(w, r)
+	if !ok {
+		return nil, false
+	}
+	o, ok := e.Limiter.(overrider)
+	if !ok {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("limiter %q does not support overrides", r.PathValue("name")))
+	}
+	return o, ok
+}
+
+func writeOverrideResult(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, limiter.ErrOverrideDisabled):
+		writeError(w, http.StatusBadRequest, err)
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, err)
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,142 @@
+package adminapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+
+	limiter "github.com/lifei6671/go-redis-limiter"
+)
+
+func newServer(t *testing.T, opts ...Option) (*Server, *limiter.TokenBucketLimiter, *limiter.ShardedTokenBucketLimiter) {
+	t.Helper()
+	client := newClient(t)
+
+	tb := limiter.NewTokenBucketLimiter(client, "login",
+		limiter.WithTokenBucketRate(0.001), limiter.WithTokenBucketCapacity(10),
+		limiter.WithTokenBucketOverrides("limits"))
+	sharded := limiter.NewShardedTokenBucketLimiter(client, "users", limiter.WithShardedTokenBucketCount(2),
+		limiter.WithShardedTokenBucket(limiter.WithTokenBucketRate(0.001), limiter.WithTokenBucketCapacity(20)))
+
+	s := New(opts...)
+	s.Register("login", tb)
+	s.RegisterSharded("users", sharded)
+	return s, tb, sharded
+}
+
+func newClient(t *testing.T) *redis.Client {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	return client
+}
+
+func do(s http.Handler, method, target, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(method, target, strings.NewReader(body)))
+	return w
+}
+
+func TestServer(t *testing.T) {
+	s, tb, sharded := newServer(t)
+	ctx := context.Background()
+
+	w := do(s, http.MethodGet, "/limiters", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var list []LimiterInfo
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	assert.Equal(t, []LimiterInfo{
+		{Name: "login", Override: true},
+		{Name: "users", Sharded: true},
+	}, list)
+
+	_, _ = tb.AllowN(ctx, 4)
+	w = do(s, http.MethodGet, "/limiters/login/state", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var st State
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &st))
+	assert.Equal(t, "token_bucket", st.Type)
+	assert.InDelta(t, 6, st.Remaining, 0.1)
+
+	assert.Equal(t, http.StatusNoContent, do(s, http.MethodPost, "/limiters/login/reset", "").Code)
+	res, _ := tb.CheckN(ctx, 1)
+	assert.Equal(t, float64(10), res.Remaining)
+
+	assert.Equal(t, http.StatusNoContent, do(s, http.MethodPut, "/limiters/login/override", `{"capacity": 50}`).Code)
+	res, _ = tb.CheckN(ctx, 1)
+	assert.Equal(t, float64(50), res.Limit)
+	assert.Equal(t, http.StatusNoContent, do(s, http.MethodDelete, "/limiters/login/override", "").Code)
+	res, _ = tb.CheckN(ctx, 1)
+	assert.Equal(t, float64(10), res.Limit)
+
+	_, _ = sharded.AllowN(ctx, "u1", 3)
+	assert.Equal(t, http.StatusBadRequest, do(s, http.MethodGet, "/limiters/users/state", "").Code)
+	w = do(s, http.MethodGet, "/limiters/users/state?key=u1", "")
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &st))
+	assert.InDelta(t, 7, st.Remaining, 0.1)
+
+	assert.Equal(t, http.StatusBadRequest, do(s, http.MethodPost, "/limiters/users/reset", "").Code)
+	assert.Equal(t, http.StatusNoContent, do(s, http.MethodPost, "/limiters/users/reset?all=true", "").Code)
+	st2, _ := sharded.State(ctx, "u1")
+	assert.InDelta(t, 10, st2.Remaining, 0.1)
+
+	assert.Equal(t, http.StatusBadRequest, do(s, http.MethodPut, "/limiters/users/override", `{"rate": 1}`).Code)
+	assert.Equal(t, http.StatusBadRequest, do(s, http.MethodPut, "/limiters/login/override", `{"rate": -1}`).Code)
+	assert.Equal(t, http.StatusNotFound, do(s, http.MethodGet, "/limiters/missing/state", "").Code)
+
+	s.Unregister("users")
+	assert.Equal(t, http.StatusNotFound, do(s, http.MethodGet, "/limiters/users/state?key=u1", "").Code)
+}
+
+func TestServer_AuthToken(t *testing.T) {
+	s, _, _ := newServer(t, WithAuthToken("secret"))
+
+	assert.Equal(t, http.StatusUnauthorized, do(s, http.MethodGet, "/limiters", "").Code)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/limiters", nil)
+	r.Header.Set("Authorization", "Bearer secret")
+	s.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestServer_Keys(t *testing.T) {
+	client := newClient(t)
+	ctx := context.Background()
+
+	assert.Equal(t, http.StatusNotImplemented, do(New(), http.MethodGet, "/keys", "").Code)
+
+	_, _ = limiter.NewTokenBucketLimiter(client, "user:1").Allow(ctx)
+	_, _ = limiter.NewSlidingWindowLimiter(client, "sms:1").Allow(ctx)
+
+	s := New(WithKeyScanner(limiter.NewKeyScanner(client)))
+	w := do(s, http.MethodGet, "/keys?match=user:*", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var keys []Key
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &keys))
+	assert.Equal(t, []Key{{Key: "user:1", Type: "token_bucket", Shard: -1}}, keys)
+}
+
+func TestServer_Registry(t *testing.T) {
+	client := newClient(t)
+	reg := limiter.NewRegistry()
+	reg.Register("login", limiter.NewTokenBucketLimiter(client, "login"))
+
+	s := New(WithRegistry(reg))
+	w := do(s, http.MethodGet, "/limiters", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `[{"name": "login", "sharded": false, "override": true}]`, w.Body.String())
+
+	// 通过 Server 注册的限流器同样进入共享的 Registry
+	s.RegisterSharded("users", limiter.NewShardedTokenBucketLimiter(client, "users"))
+	_, ok := reg.GetSharded("users")
+	assert.True(t, ok)
+}
@@ -0,0 +1,124 @@
+package limiter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// KeyResult 是 AllowKeys 中单个 key 的判定结果。
+type KeyResult struct {
+	// Key 调用方传入的 key
+	Key string
+	Result
+	// Err 该 key 判定出错（例如 Redis 错误）时不为 nil，此时 Result 为零值
+	Err error
+}
+
+// scriptCall 是一次限流脚本调用：KEYS / ARGV 在执行前准备好，parse 把脚本返回值（或执行错误）解析为判定结果。
+// 拆成两步后，同一次调用既可以单独执行（run），也可以和其他调用合并到一个 pipeline 中（runPipelined）。
+type scriptCall struct {
+	client *redis.Client
+	script *redis.Script
+	keys   []string
+	args   []interface{}
+	parse  func(ctx context.Context, res interface{}, err error) (Result, error)
+}
+
+// run 单独执行脚本：先 EVALSHA，遇到 NOSCRIPT 时退回 EVAL。
+func (c scriptCall) run(ctx context.Context) (Result, error) {
+	res, err := c.script.Run(ctx, c.client, c.keys, c.args...).Result()
+	return c.parse(ctx, res, err)
+}
+
+// withHooks 在解析结果后触发判定钩子。
+func (c scriptCall) withHooks(hooks Hooks, algorithm, key string, n int64) scriptCall {
+	parse := c.parse
+	c.parse = func(ctx context.Context, res interface{}, err error) (Result, error) {
+		r, err := parse(ctx, res, err)
+		fireHooks(ctx, hooks, algorithm, key, n, r, err)
+		return r, err
+	}
+	return c
+}
+
+// allowCaller 是能把一次 AllowN 判定拆成 scriptCall 的单桶限流器，
+// 目前为 TokenBucketLimiter、LeakyBucketLimiter、SingleSlidingWindowLimiter 与 FixedWindowLimiter。
+type allowCaller interface {
+	allowCall(n int64) (scriptCall, error)
+}
+
+// allowAll 对 limiters 各获取 1 个许可：能拆分为 scriptCall 的按 Redis 客户端合并到 pipeline 中，
+// 每个客户端一次往返；其余（例如包装器）逐个调用 AllowWithResult。
+// 返回与 keys 一一对应的结果，以及全部出错 key 的错误（errors.Join）。
+func allowAll(ctx context.Context, keys []string, limiters []RateLimiter) ([]KeyResult, error) {
+	out := make([]KeyResult, len(keys))
+	calls := make([]scriptCall, 0, len(keys))
+	idx := make([]int, 0, len(keys))
+	for i, l := range limiters {
+		out[i].Key = keys[i]
+		if c, ok := l.(allowCaller); ok {
+			call, err := c.allowCall(1)
+			if err != nil {
+				out[i].Err = err
+				continue
+			}
+			calls = append(calls, call)
+			idx = append(idx, i)
+			continue
+		}
+		out[i].Result, out[i].Err = l.AllowWithResult(ctx)
+	}
+
+	for j, r := range runPipelined(ctx, calls) {
+		out[idx[j]].Result, out[idx[j]].Err = r.Result, r.Err
+	}
+
+	var errs []error
+	for _, r := range out {
+		if r.Err != nil {
+			errs = append(errs, fmt.Errorf("key %q: %w", r.Key, r.Err))
+		}
+	}
+	return out, errors.Join(errs...)
+}
+
+// runPipelined 按 Redis 客户端分组，用 pipeline 执行 calls，返回与 calls 一一对应的结果（Key 为空）。
+// pipeline 中只能使用 EVALSHA，返回 NOSCRIPT 的调用会单独重试一次（退回 EVAL，同时把脚本载入 Redis）。
+// 各调用的 key 通常不在同一个 slot，pipeline 不保证原子性，每个调用各自原子。
+func runPipelined(ctx context.Context, calls []scriptCall) []KeyResult {
+	out := make([]KeyResult, len(calls))
+
+	groups := make(map[*redis.Client][]int)
+	var order []*redis.Client
+	for i, c := range calls {
+		if _, ok := groups[c.client]; !ok {
+			order = append(order, c.client)
+		}
+		groups[c.client] = append(groups[c.client], i)
+	}
+
+	for _, client := range order {
+		idx := groups[client]
+		pipe := client.Pipeline()
+		cmds := make([]*redis.Cmd, len(idx))
+		for j, i := range idx {
+			cmds[j] = calls[i].script.EvalSha(ctx, pipe, calls[i].keys, calls[i].args...)
+		}
+		// 单条命令的错误由各调用自行解析
+		_, _ = pipe.Exec(ctx)
+
+		for j, i := range idx {
+			c := calls[i]
+			res, err := cmds[j].Result()
+			if err != nil && strings.HasPrefix(err.Error(), "NOSCRIPT") {
+				res, err = c.script.Run(ctx, client, c.keys, c.args...).Result()
+			}
+			out[i].Result, out[i].Err = c.parse(ctx, res, err)
+		}
+	}
+	return out
+}
@@ -0,0 +1,73 @@
+package limiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyedLimiter_AllowKeys(t *testing.T) {
+	ctx := context.Background()
+	client := newSpecClient(t)
+
+	k := NewKeyedLimiter(func(key string) RateLimiter {
+		switch key {
+		case "fw":
+			return NewFixedWindowLimiter(client, key, WithFixedWindowLimit(1))
+		case "protected":
+			// 包装器无法合并到 pipeline，退回逐个判定
+			return NewProtectedLimiter(NewTokenBucketLimiter(client, key, WithTokenBucketCapacity(1)))
+		}
+		return NewTokenBucketLimiter(client, key, WithTokenBucketRate(1), WithTokenBucketCapacity(1), WithTokenBucketTTL(time.Minute))
+	})
+
+	keys := []string{"a", "b", "a", "fw", "fw", "protected", "protected"}
+	want := []bool{true, true, false, true, false, true, false}
+
+	// 第一次执行时脚本尚未加载，pipeline 中的 EVALSHA 全部返回 NOSCRIPT 后单独重试
+	res, err := k.AllowKeys(ctx, keys)
+	assert.NoError(t, err)
+	assert.Len(t, res, len(keys))
+	for i, r := range res {
+		assert.Equal(t, keys[i], r.Key)
+		assert.NoError(t, r.Err)
+		assert.Equal(t, want[i], r.Allowed, "key %d %s", i, r.Key)
+	}
+	assert.Greater(t, res[2].RetryAfter, time.Duration(0))
+
+	// 脚本已加载：一次往返完成
+	hook := &countingHook{}
+	client.AddHook(hook)
+	for _, key := range []string{"a", "b"} {
+		assert.NoError(t, k.Reset(ctx, key))
+	}
+	hook.n.Store(0)
+	res, err = k.AllowKeys(ctx, []string{"a", "b", "a"})
+	assert.NoError(t, err)
+	assert.Equal(t, []bool{true, true, false}, []bool{res[0].Allowed, res[1].Allowed, res[2].Allowed})
+	assert.Equal(t, int64(3), hook.n.Load())
+}
+
+func TestShardedTokenBucketLimiter_AllowKeys(t *testing.T) {
+	ctx := context.Background()
+	client := newSpecClient(t)
+
+	s := NewShardedTokenBucketLimiter(client, "tenants",
+		WithShardedTokenBucketCount(4),
+		WithShardedTokenBucket(WithTokenBucketRate(4), WithTokenBucketCapacity(4), WithTokenBucketTTL(time.Minute)),
+	)
+
+	keys := []string{"t1", "t2", "t3", "t1", "t1"}
+	res, err := s.AllowKeys(ctx, keys)
+	assert.NoError(t, err)
+
+	// 每个分片容量为 1：同一个分片上第二次起被拒绝
+	seen := make(map[int]bool)
+	for i, r := range res {
+		shard := s.pick(keys[i])
+		assert.Equal(t, !seen[shard], r.Allowed, "key %d", i)
+		seen[shard] = true
+	}
+}
@@ -0,0 +1,114 @@
+package limiter
+
+import (
+	"context"
+	"math/rand/v2"
+	"time"
+)
+
+// Backoff 描述客户端被限流后的“礼貌重试”策略。
+//   - 每次等待时长 = max(服务端给出的 retry-after, Base * 2^attempt)，并受 Max 限制
+//   - 在此基础上再叠加 [0, Jitter] 比例的随机抖动，避免大量客户端同时重试
+//   - 所有等待时长之和不会超过 Budget
+//
+// 注意：抖动只会“加”不会“减”，保证不会早于 retry-after 重试。
+type Backoff struct {
+	Base        time.Duration // 初始退避时长，默认 10ms
+	Max         time.Duration // 单次退避上限（retry-after 更大时以 retry-after 为准），默认 5s
+	Budget      time.Duration // 总等待预算，默认 30s
+	Jitter      float64       // 抖动比例，例如 0.2 表示在基础时长上随机增加 0~20%
+	MaxAttempts int           // 最大重试次数，<=0 表示只受 Budget 限制
+}
+
+// BackoffPlan 是一次重试过程的退避计划，记录每一步的等待时长。
+// 由于 retry-after 只有在被拒绝时才能得知，计划是逐步生成的：每次被拒绝后调用 Next。
+type BackoffPlan struct {
+	cfg   Backoff
+	spent time.Duration
+
+	// Steps 已生成的等待时长序列
+	Steps []time.Duration
+}
+
+// NewPlan 基于当前配置创建一个新的退避计划。
+func (b Backoff) NewPlan() *BackoffPlan {
+	if b.Base <= 0 {
+		b.Base = 10 * time.Millisecond
+	}
+	if b.Max <= 0 {
+		b.Max = 5 * time.Second
+	}
+	if b.Budget <= 0 {
+		b.Budget = 30 * time.Second
+	}
+	if b.Jitter < 0 {
+		b.Jitter = 0
+	}
+	return &BackoffPlan{cfg: b}
+}
+
+// Next 根据服务端给出的 retryAfter 计算下一次等待时长。
+// 若超过重试次数或总预算，返回 false。
+func (p *BackoffPlan) Next(retryAfter time.Duration) (time.Duration, bool) {
+	attempt := len(p.Steps)
+	if p.cfg.MaxAttempts > 0 && attempt >= p.cfg.MaxAttempts {
+		return 0, false
+	}
+
+	d := p.cfg.Base << min(attempt, 30)
+	if d <= 0 || d > p.cfg.Max {
+		d = p.cfg.Max
+	}
+	if retryAfter > d {
+		d = retryAfter
+	}
+	if p.cfg.Jitter > 0 {
+		d += time.Duration(float64(d) * p.cfg.Jitter * rand.Float64())
+	}
+
+	if p.spent+d > p.cfg.Budget {
+		return 0, false
+	}
+	p.spent += d
+	p.Steps = append(p.Steps, d)
+	return d, true
+}
+
+// Spent 返回计划中已经分配的等待总时长。
+func (p *BackoffPlan) Spent() time.Duration {
+	return p.spent
+}
+
+// Do 在限流器允许时执行 fn；被限流时按退避计划等待后重试。
+//   - retry-after 取自 AllowWithResult 返回的 RetryAfter（与判定同一次往返）
+//   - 超出重试次数返回匹配 ErrLimiter 的 *LimitExceededError，超出总预算时同时匹配 ErrTimeout
+//   - fn 返回的错误会原样返回，不会触发重试
+func (b Backoff) Do(ctx context.Context, l RateLimiter, fn func(ctx context.Context) error) error {
+	plan := b.NewPlan()
+
+	timer := time.NewTimer(time.Second)
+	defer timer.Stop()
+
+	for {
+		res, err := l.AllowWithResult(ctx)
+		if err != nil {
+			return err
+		}
+		if res.Allowed {
+			return fn(ctx)
+		}
+
+		sleep, ok := plan.Next(res.RetryAfter)
+		if !ok {
+			timeout := b.MaxAttempts <= 0 || len(plan.Steps) < b.MaxAttempts
+			return newLimitExceededError("", "", res, timeout)
+		}
+
+		timer.Reset(sleep)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
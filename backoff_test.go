@@ -0,0 +1,73 @@
+package limiter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackoffPlan_Next(t *testing.T) {
+	t.Run("BackoffPlan_exponential", func(t *testing.T) {
+		plan := Backoff{
+			Base:   10 * time.Millisecond,
+			Max:    50 * time.Millisecond,
+			Budget: time.Second,
+		}.NewPlan()
+
+		for _, want := range []time.Duration{10, 20, 40, 50, 50} {
+			d, ok := plan.Next(0)
+			assert.True(t, ok)
+			assert.Equal(t, want*time.Millisecond, d)
+		}
+		assert.Equal(t, 170*time.Millisecond, plan.Spent())
+	})
+
+	t.Run("BackoffPlan_honor_retry_after", func(t *testing.T) {
+		plan := Backoff{
+			Base:   10 * time.Millisecond,
+			Max:    50 * time.Millisecond,
+			Budget: time.Second,
+		}.NewPlan()
+
+		d, ok := plan.Next(200 * time.Millisecond)
+		assert.True(t, ok)
+		assert.Equal(t, 200*time.Millisecond, d)
+	})
+
+	t.Run("BackoffPlan_jitter_never_early", func(t *testing.T) {
+		plan := Backoff{
+			Base:   10 * time.Millisecond,
+			Budget: time.Minute,
+			Jitter: 0.5,
+		}.NewPlan()
+
+		for i := 0; i < 10; i++ {
+			d, ok := plan.Next(100 * time.Millisecond)
+			assert.True(t, ok)
+			assert.GreaterOrEqual(t, d, 100*time.Millisecond)
+			assert.LessOrEqual(t, d, 7500*time.Millisecond)
+		}
+	})
+
+	t.Run("BackoffPlan_budget", func(t *testing.T) {
+		plan := Backoff{
+			Base:   100 * time.Millisecond,
+			Budget: 250 * time.Millisecond,
+		}.NewPlan()
+
+		_, ok := plan.Next(0)
+		assert.True(t, ok)
+		_, ok = plan.Next(0)
+		assert.False(t, ok)
+	})
+
+	t.Run("BackoffPlan_max_attempts", func(t *testing.T) {
+		plan := Backoff{MaxAttempts: 1}.NewPlan()
+
+		_, ok := plan.Next(0)
+		assert.True(t, ok)
+		_, ok = plan.Next(0)
+		assert.False(t, ok)
+	})
+}
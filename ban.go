@@ -0,0 +1,250 @@
+package limiter
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// BanLimiter 对屡次被限流的 key 自动升级为封禁：
+//   - key 在 Window 内被拒绝超过 Threshold 次后，封禁 BaseBan 时长
+//   - 封禁结束后 Memory 时长内再次触发，封禁时长翻倍，最长 MaxBan
+//   - 封禁期间 Allow 直接拒绝，不再执行被包装限流器的脚本
+//
+// 封禁状态保存在 Redis 中（"ban:{key}:until"），所有实例共享；
+// 每个实例在本地缓存已知的封禁到期时间，封禁期间的请求不会访问 Redis，
+// 避免恶意客户端借限流器本身持续压 Redis。
+//
+// BanLimiter 实现了 RateShardedLimiter，可直接用于 httplimit / grpclimit 中间件。
+type BanLimiter struct {
+	client  *redis.Client
+	limiter RateShardedLimiter
+
+	// Prefix 封禁相关 key 的前缀，默认 "ban"
+	Prefix string
+	// Threshold Window 内允许被拒绝的次数，超过后触发封禁，默认 10
+	Threshold int64
+	// Window 统计被拒绝次数的窗口，默认 1 分钟
+	Window time.Duration
+	// BaseBan 第一次封禁的时长，默认 1 分钟
+	BaseBan time.Duration
+	// MaxBan 封禁时长上限，默认 24 小时
+	MaxBan time.Duration
+	// Memory 封禁结束后仍然记住封禁等级的时长，默认 24 小时
+	Memory time.Duration
+
+	// OnBan 触发封禁时的回调（可选），例如告警
+	OnBan func(key string, d time.Duration)
+
+	// Clock 时钟，用于本地封禁缓存，默认 SystemClock
+	Clock Clock
+
+	mu     sync.Mutex
+	banned map[string]time.Time // key -> 封禁到期时间
+}
+
+var _ RateShardedLimiter = (*BanLimiter)(nil)
+
+// NewBanLimiter 为 l 包装自动封禁能力。
+func NewBanLimiter(client *redis.Client, l RateShardedLimiter, opts ...BanOption) *BanLimiter {
+	if client == nil {
+		panic("ban: redis client is nil")
+	}
+	if l == nil {
+		panic("ban: limiter is nil")
+	}
+
+	b := &BanLimiter{
+		client:    client,
+		limiter:   l,
+		Prefix:    "ban",
+		Threshold: 10,
+		Window:    time.Minute,
+		BaseBan:   time.Minute,
+		MaxBan:    24 * time.Hour,
+		Memory:    24 * time.Hour,
+		Clock:     SystemClock,
+		banned:    make(map[string]time.Time),
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+	if b.BaseBan > b.MaxBan {
+		panic("ban: base ban must <= max ban")
+	}
+	return b
+}
+
+// strikesKey 返回统计被拒绝次数的 key。
+func (b *BanLimiter) strikesKey(key string) string {
+	return fmt.Sprintf("%s:{%s}:strikes", b.Prefix, key)
+}
+
+// levelKey 返回封禁等级的 key。
+func (b *BanLimiter) levelKey(key string) string {
+	return fmt.Sprintf("%s:{%s}:level", b.Prefix, key)
+}
+
+// banKey 返回封禁标记的 key。
+func (b *BanLimiter) banKey(key string) string {
+	return fmt.Sprintf("%s:{%s}:until", b.Prefix, key)
+}
+
+// localBan 返回本地缓存中 key 剩余的封禁时长，未封禁时为 0。
+func (b *BanLimiter) localBan(key string) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	until, ok := b.banned[key]
+	if !ok {
+		return 0
+	}
+	left := until.Sub(b.Clock.Now())
+	if left <= 0 {
+		delete(b.banned, key)
+		return 0
+	}
+	return left
+}
+
+// remember 在本地缓存 key 的封禁到期时间，顺便清理已过期的条目。
+func (b *BanLimiter) remember(key string, d time.Duration) {
+	now := b.Clock.Now()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for k, until := range b.banned {
+		if !until.After(now) {
+			delete(b.banned, k)
+		}
+	}
+	b.banned[key] = now.Add(d)
+}
+
+// Banned 返回 key 剩余的封禁时长，未封禁时为 0。
+func (b *BanLimiter) Banned(ctx context.Context, key string) (time.Duration, error) {
+	if d := b.localBan(key); d > 0 {
+		return d, nil
+	}
+	d, err := b.client.PTTL(ctx, b.banKey(key)).Result()
+	if err != nil {
+		return 0, err
+	}
+	if d <= 0 {
+		return 0, nil
+	}
+	b.remember(key, d)
+	return d, nil
+}
+
+// Ban 手动封禁 key d 时长，不影响封禁等级。其他实例在本地缓存未命中时即可看到。
+func (b *BanLimiter) Ban(ctx context.Context, key string, d time.Duration) error {
+	if d <= 0 {
+		return fmt.Errorf("ban: duration must > 0")
+	}
+	if err := b.client.Set(ctx, b.banKey(key), 0, d).Err(); err != nil {
+		return err
+	}
+	b.remember(key, d)
+	return nil
+}
+
+// Unban 解除 key 的封禁并清空封禁等级与被拒绝次数。
+// 其他实例的本地缓存不会被清除，最迟在原封禁到期时恢复。
+func (b *BanLimiter) Unban(ctx context.Context, key string) error {
+	b.mu.Lock()
+	delete(b.banned, key)
+	b.mu.Unlock()
+	return b.client.Del(ctx, b.strikesKey(key), b.levelKey(key), b.banKey(key)).Err()
+}
+
+// strike 记录一次被拒绝，返回本次触发的封禁时长（未触发时为 0）。
+func (b *BanLimiter) strike(ctx context.Context, key string) (time.Duration, error) {
+	keys := []string{b.strikesKey(key), b.levelKey(key), b.banKey(key)}
+	args := []interface{}{
+		b.Threshold,
+		b.Window.Milliseconds(),
+		b.BaseBan.Milliseconds(),
+		b.MaxBan.Milliseconds(),
+		b.Memory.Milliseconds(),
+	}
+	ms, err := banScript.Run(ctx, b.client, keys, args...).Int64()
+	if err != nil {
+		return 0, err
+	}
+	d := time.Duration(ms) * time.Millisecond
+	if d > 0 {
+		b.remember(key, d)
+		if b.OnBan != nil {
+			b.OnBan(key, d)
+		}
+	}
+	return d, nil
+}
+
+func (b *BanLimiter) Allow(ctx context.Context, key string) (bool, error) {
+	return b.AllowN(ctx, key, 1)
+}
+
+func (b *BanLimiter) AllowWithResult(ctx context.Context, key string) (Result, error) {
+	return b.allow(ctx, key, func() (Result, error) {
+		return b.limiter.AllowWithResult(ctx, key)
+	})
+}
+
+func (b *BanLimiter) AllowN(ctx context.Context, key string, n int64) (bool, error) {
+	res, err := b.allow(ctx, key, func() (Result, error) {
+		ok, err := b.limiter.AllowN(ctx, key, n)
+		return Result{Allowed: ok}, err
+	})
+	return res.Allowed, err
+}
+
+// allow 先检查封禁，再交给被包装的限流器判定；被拒绝时记录一次 strike。
+// 封禁期间 RetryAfter 为剩余的封禁时长。
+func (b *BanLimiter) allow(ctx context.Context, key string, try func() (Result, error)) (Result, error) {
+	if d, err := b.Banned(ctx, key); err != nil || d > 0 {
+		return Result{RetryAfter: d}, err
+	}
+
+	res, err := try()
+	if err != nil || res.Allowed {
+		return res, err
+	}
+
+	d, err := b.strike(ctx, key)
+	if err != nil {
+		return res, err
+	}
+	if d > 0 {
+		res.RetryAfter = d
+	}
+	return res, nil
+}
+
+// Wait 阻塞直到获得一个许可，封禁期间按剩余封禁时长判断是否值得等待。
+func (b *BanLimiter) Wait(ctx context.Context, key string, maxWait time.Duration) error {
+	return waitFor(ctx, key, "ban", maxWait, nil, func(ctx context.Context) (Result, error) {
+		return b.AllowWithResult(ctx, key)
+	})
+}
+
+func (b *BanLimiter) State(ctx context.Context, key string) (LimiterState, error) {
+	return b.limiter.State(ctx, key)
+}
+
+// Reset 重置 key 的限流状态，同时解除封禁。
+func (b *BanLimiter) Reset(ctx context.Context, key string) error {
+	if err := b.Unban(ctx, key); err != nil {
+		return err
+	}
+	return b.limiter.Reset(ctx, key)
+}
+
+// ResetAll 重置被包装限流器的全部状态，不影响已有的封禁。
+func (b *BanLimiter) ResetAll(ctx context.Context) error {
+	return b.limiter.ResetAll(ctx)
+}
@@ -0,0 +1,62 @@
+package limiter
+
+import "time"
+
+// BanOption 为自动封禁限流器的配置项。
+type BanOption func(*BanLimiter)
+
+// WithBanThreshold 设置触发封禁的条件：window 内被拒绝超过 n 次。
+func WithBanThreshold(n int64, window time.Duration) BanOption {
+	return func(b *BanLimiter) {
+		if n <= 0 || window < time.Millisecond {
+			panic("ban: threshold must > 0 and window >= 1ms")
+		}
+		b.Threshold = n
+		b.Window = window
+	}
+}
+
+// WithBanDuration 设置第一次封禁的时长与封禁时长上限。
+func WithBanDuration(base, maxBan time.Duration) BanOption {
+	return func(b *BanLimiter) {
+		if base < time.Millisecond || maxBan < base {
+			panic("ban: base must >= 1ms and max must >= base")
+		}
+		b.BaseBan = base
+		b.MaxBan = maxBan
+	}
+}
+
+// WithBanMemory 设置封禁结束后仍然记住封禁等级的时长，期间再次触发封禁时时长翻倍。
+func WithBanMemory(d time.Duration) BanOption {
+	return func(b *BanLimiter) {
+		if d >= 0 {
+			b.Memory = d
+		}
+	}
+}
+
+// WithBanPrefix 设置封禁相关 key 的前缀。
+func WithBanPrefix(prefix string) BanOption {
+	return func(b *BanLimiter) {
+		if prefix != "" {
+			b.Prefix = prefix
+		}
+	}
+}
+
+// WithBanHandler 设置触发封禁时的回调，例如告警。
+func WithBanHandler(fn func(key string, d time.Duration)) BanOption {
+	return func(b *BanLimiter) {
+		b.OnBan = fn
+	}
+}
+
+// WithBanClock 设置本地封禁缓存使用的时钟，通常用于测试。
+func WithBanClock(c Clock) BanOption {
+	return func(b *BanLimiter) {
+		if c != nil {
+			b.Clock = c
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package limiter
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBanLimiter(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	ctx := context.Background()
+
+	now := time.Now()
+	advance := func(d time.Duration) {
+		now = now.Add(d)
+		mr.FastForward(d)
+	}
+
+	keyed := NewKeyedLimiter(func(key string) RateLimiter {
+		return NewFixedWindowLimiter(client, key, WithFixedWindowLimit(1), WithFixedWindowWindow(time.Hour))
+	})
+	var bans []time.Duration
+	b := NewBanLimiter(client, keyed,
+		WithBanThreshold(2, time.Minute),
+		WithBanDuration(10*time.Second, 15*time.Second),
+		WithBanClock(ClockFunc(func() time.Time { return now })),
+		WithBanHandler(func(key string, d time.Duration) { bans = append(bans, d) }))
+
+	ok, err := b.Allow(ctx, "abuser")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	// 被拒绝 2 次内不封禁，第 3 次触发封禁
+	for i := 0; i < 2; i++ {
+		res, err := b.AllowWithResult(ctx, "abuser")
+		assert.NoError(t, err)
+		assert.False(t, res.Allowed)
+		assert.Greater(t, res.RetryAfter, 10*time.Second)
+	}
+	res, err := b.AllowWithResult(ctx, "abuser")
+	assert.NoError(t, err)
+	assert.False(t, res.Allowed)
+	assert.Equal(t, 10*time.Second, res.RetryAfter)
+	assert.Equal(t, []time.Duration{10 * time.Second}, bans)
+
+	// 封禁期间命中本地缓存，不访问 Redis
+	before := mr.CommandCount()
+	res, err = b.AllowWithResult(ctx, "abuser")
+	assert.NoError(t, err)
+	assert.False(t, res.Allowed)
+	assert.Equal(t, before, mr.CommandCount())
+	assert.True(t, errors.Is(b.Wait(ctx, "abuser", time.Second), ErrLimiter))
+
+	// 其他实例通过 Redis 看到同一个封禁
+	other := NewBanLimiter(client, keyed)
+	d, err := other.Banned(ctx, "abuser")
+	assert.NoError(t, err)
+	assert.Equal(t, 10*time.Second, d)
+
+	// 封禁结束后再次触发，时长翻倍但不超过上限
+	advance(11 * time.Second)
+	for i := 0; i < 3; i++ {
+		_, _ = b.Allow(ctx, "abuser")
+	}
+	assert.Equal(t, []time.Duration{10 * time.Second, 15 * time.Second}, bans)
+
+	assert.NoError(t, b.Reset(ctx, "abuser"))
+	d, err = b.Banned(ctx, "abuser")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Duration(0), d)
+	ok, err = b.Allow(ctx, "abuser")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	assert.NoError(t, b.Ban(ctx, "manual", time.Minute))
+	ok, err = other.Allow(ctx, "manual")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
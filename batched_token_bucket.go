@@ -0,0 +1,216 @@
+package limiter
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// BatchedTokenBucketLimiter 是令牌桶的“批量预取”模式，用于单实例数万 QPS 的场景：
+//   - 本地 token 不足时，一次脚本调用从 Redis 租借 BatchSize 个 token；
+//   - 之后的请求直接从本地原子计数器扣减，不再访问 Redis；
+//   - 租借的 token 超过 FlushInterval 仍未用完时归还给 Redis，Close 时也会归还。
+//
+// 代价是精度：每个实例最多可能“囤积” BatchSize 个 token，其他实例在这段时间内看不到它们。
+// BatchSize 建议取单实例 FlushInterval 内的预期请求量，且远小于 Capacity。
+type BatchedTokenBucketLimiter struct {
+	tb *TokenBucketLimiter
+
+	// BatchSize 每次从 Redis 租借的 token 数，默认 100
+	BatchSize int64
+	// FlushInterval 租借的 token 闲置多久后归还给 Redis，默认 1s
+	FlushInterval time.Duration
+
+	// Hooks 判定事件钩子（可选），每次 Allow 判定后回调，包括本地命中的请求。
+	// 内部令牌桶上设置的钩子只在访问了 Redis 的请求上按最终结果触发一次，N 为本次请求的数量。
+	Hooks Hooks
+
+	local    atomic.Int64 // 本地剩余的已租借 token
+	leasedAt atomic.Int64 // 最近一次租借时间（毫秒）
+
+	mu        sync.Mutex // 串行化租借，避免多个 goroutine 同时打到 Redis
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+var _ RateLimiter = (*BatchedTokenBucketLimiter)(nil)
+
+// NewBatchedTokenBucketLimiter 基于已有的令牌桶创建批量预取限流器，并启动后台归还协程。
+// 使用完毕后需调用 Close 归还剩余 token。
+func NewBatchedTokenBucketLimiter(tb *TokenBucketLimiter, opts ...BatchedTokenBucketOption) *BatchedTokenBucketLimiter {
+	if tb == nil {
+		panic("batched token bucket: token bucket is nil")
+	}
+
+	b := &BatchedTokenBucketLimiter{
+		tb:            tb,
+		BatchSize:     100,
+		FlushInterval: time.Second,
+		stop:          make(chan struct{}),
+		done:          make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+	if _, capacity := tb.limits(); float64(b.BatchSize) > capacity {
+		panic("batched token bucket: batch size must <= capacity")
+	}
+
+	go b.flushLoop()
+	return b
+}
+
+// Allow 尝试获取 1 个 token。
+func (b *BatchedTokenBucketLimiter) Allow(ctx context.Context) (bool, error) {
+	return b.AllowN(ctx, 1)
+}
+
+// AllowWithResult 尝试获取 1 个 token。本地命中时 Remaining 为本地剩余的已租借 token 数。
+func (b *BatchedTokenBucketLimiter) AllowWithResult(ctx context.Context) (Result, error) {
+	return b.AllowNWithResult(ctx, 1)
+}
+
+// AllowN 尝试一次获取 n 个 token。
+func (b *BatchedTokenBucketLimiter) AllowN(ctx context.Context, n int64) (bool, error) {
+	res, err := b.AllowNWithResult(ctx, n)
+	if err != nil {
+		return false, err
+	}
+	return res.Allowed, nil
+}
+
+// AllowNWithResult 优先从本地扣减；不足时向 Redis 租借 max(BatchSize, n) 个 token，
+// 租借失败则退化为只申请本次需要的 n 个。
+func (b *BatchedTokenBucketLimiter) AllowNWithResult(ctx context.Context, n int64) (Result, error) {
+	res, err := b.allowN(ctx, n)
+	fireHooks(ctx, b.Hooks, "token_bucket", b.tb.Key, n, res, err)
+	return res, err
+}
+
+// allowN 实现 AllowNWithResult 的本地扣减与批量租借。
+func (b *BatchedTokenBucketLimiter) allowN(ctx context.Context, n int64) (Result, error) {
+	if n <= 0 {
+		return Result{}, fmt.Errorf("batched token bucket: n must > 0")
+	}
+	_, capacity := b.tb.limits()
+	if left, ok := b.take(n); ok {
+		return Result{Allowed: true, Limit: capacity, Remaining: float64(left)}, nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// 等锁期间可能已有其他 goroutine 完成了租借
+	if left, ok := b.take(n); ok {
+		return Result{Allowed: true, Limit: capacity, Remaining: float64(left)}, nil
+	}
+
+	// 租借与退化后的判定都不计统计、不触发钩子，由 settle 按本次请求的最终结果记录一次
+	lease := max(b.BatchSize, n)
+	res, err := b.tb.call(lease, "", false).run(ctx)
+	var granted int64
+	switch {
+	case err != nil:
+	case res.Allowed:
+		b.leasedAt.Store(b.tb.Clock.Now().UnixMilli())
+		res.Remaining = float64(b.local.Add(lease - n))
+		granted = lease
+	case lease > n:
+		res, err = b.tb.call(n, "", false).run(ctx)
+		if err == nil && res.Allowed {
+			granted = n
+		}
+	}
+	b.settle(ctx, n, granted, res, err)
+	if err != nil {
+		return Result{}, err
+	}
+	return res, nil
+}
+
+// settle 按本次请求的最终结果触发一次内部令牌桶的钩子。开启统计时放行数按实际从 Redis 取走的 token 数累加
+// （租借成功时为整批），被拒绝时只累加本次请求的 n。统计只用于观测，写入失败不影响判定。
+func (b *BatchedTokenBucketLimiter) settle(ctx context.Context, n, granted int64, res Result, err error) {
+	tb := b.tb
+	if err == nil && tb.StatsTTL > 0 {
+		var denied int64
+		if !res.Allowed {
+			denied = n
+		}
+		_ = recordStats(ctx, tb.client, statsBase(tb.Prefix, tb.Key), tb.StatsTTL, granted, denied)
+	}
+	fireHooks(ctx, tb.Hooks, "token_bucket", tb.Key, n, res, err)
+}
+
+// take 尝试从本地扣减 n 个 token，成功时返回扣减后的剩余数。
+func (b *BatchedTokenBucketLimiter) take(n int64) (int64, bool) {
+	for {
+		cur := b.local.Load()
+		if cur < n {
+			return cur, false
+		}
+		if b.local.CompareAndSwap(cur, cur-n) {
+			return cur - n, true
+		}
+	}
+}
+
+// Wait 阻塞直到成功获取 1 个 token 或超时。
+func (b *BatchedTokenBucketLimiter) Wait(ctx context.Context, maxWait time.Duration) error {
+	return waitFor(ctx, b.tb.Key, "token_bucket", maxWait, waitStrategy(b.tb.WaitStrategy, b.tb.WaitJitter), b.AllowWithResult)
+}
+
+// State 返回 Redis 中令牌桶的状态，不包含本地尚未用完的已租借 token。
+func (b *BatchedTokenBucketLimiter) State(ctx context.Context) (LimiterState, error) {
+	return b.tb.State(ctx)
+}
+
+// Reset 丢弃本地已租借的 token，并重置 Redis 中的令牌桶。
+func (b *BatchedTokenBucketLimiter) Reset(ctx context.Context) error {
+	b.local.Store(0)
+	return b.tb.Reset(ctx)
+}
+
+// Flush 立即把本地剩余的已租借 token 归还给 Redis。
+func (b *BatchedTokenBucketLimiter) Flush(ctx context.Context) error {
+	n := b.local.Swap(0)
+	if n <= 0 {
+		return nil
+	}
+	return b.tb.ReturnN(ctx, n)
+}
+
+// Close 停止后台归还协程，并归还剩余 token。可重复调用。
+func (b *BatchedTokenBucketLimiter) Close(ctx context.Context) error {
+	b.closeOnce.Do(func() {
+		close(b.stop)
+	})
+	<-b.done
+	return b.Flush(ctx)
+}
+
+// flushLoop 定期归还闲置超过 FlushInterval 的已租借 token。
+func (b *BatchedTokenBucketLimiter) flushLoop() {
+	defer close(b.done)
+
+	ticker := time.NewTicker(b.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-b.stop:
+			return
+		case <-ticker.C:
+			idle := b.tb.Clock.Now().Sub(time.UnixMilli(b.leasedAt.Load()))
+			if idle >= b.FlushInterval {
+				ctx, cancel := context.WithTimeout(context.Background(), b.FlushInterval)
+				// 归还失败只会让这部分 token 提前作废，不影响正确性
+				_ = b.Flush(ctx)
+				cancel()
+			}
+		}
+	}
+}
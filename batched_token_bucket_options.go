@@ -0,0 +1,31 @@
+package limiter
+
+import "time"
+
+// BatchedTokenBucketOption 为批量预取令牌桶的配置项。
+type BatchedTokenBucketOption func(*BatchedTokenBucketLimiter)
+
+// WithBatchSize 设置每次从 Redis 租借的 token 数。
+func WithBatchSize(n int64) BatchedTokenBucketOption {
+	return func(b *BatchedTokenBucketLimiter) {
+		if n > 0 {
+			b.BatchSize = n
+		}
+	}
+}
+
+// WithBatchFlushInterval 设置已租借 token 闲置多久后归还给 Redis。
+func WithBatchFlushInterval(d time.Duration) BatchedTokenBucketOption {
+	return func(b *BatchedTokenBucketLimiter) {
+		if d > 0 {
+			b.FlushInterval = d
+		}
+	}
+}
+
+// WithBatchHooks 设置判定事件钩子，用于接入日志、指标或告警。
+func WithBatchHooks(h Hooks) BatchedTokenBucketOption {
+	return func(b *BatchedTokenBucketLimiter) {
+		b.Hooks = h
+	}
+}
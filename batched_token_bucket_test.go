@@ -0,0 +1,121 @@
+package limiter
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBatchedTokenBucketLimiter(t *testing.T) {
+	ctx := context.Background()
+	client := newSpecClient(t)
+
+	tb := NewTokenBucketLimiter(client, "batch",
+		WithTokenBucketRate(0.001),
+		WithTokenBucketCapacity(100),
+		WithTokenBucketTTL(time.Minute),
+	)
+	b := NewBatchedTokenBucketLimiter(tb, WithBatchSize(10), WithBatchFlushInterval(time.Hour))
+
+	for i := 0; i < 5; i++ {
+		ok, err := b.Allow(ctx)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+	}
+	// 只租借了一次
+	assert.InDelta(t, 90, getFloat(t, client, tb.tokensKey()), 0.01)
+
+	// 大于 BatchSize 的请求直接按 n 租借
+	ok, err := b.AllowN(ctx, 20)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.InDelta(t, 70, getFloat(t, client, tb.tokensKey()), 0.01)
+
+	// Close 归还本地剩余的 5 个
+	assert.NoError(t, b.Close(ctx))
+	assert.InDelta(t, 75, getFloat(t, client, tb.tokensKey()), 0.01)
+	assert.NoError(t, b.Close(ctx))
+}
+
+func TestBatchedTokenBucketLimiter_Concurrent(t *testing.T) {
+	ctx := context.Background()
+	client := newSpecClient(t)
+
+	tb := NewTokenBucketLimiter(client, "batch-concurrent",
+		WithTokenBucketRate(0.001),
+		WithTokenBucketCapacity(23),
+		WithTokenBucketTTL(time.Minute),
+	)
+	b := NewBatchedTokenBucketLimiter(tb, WithBatchSize(5), WithBatchFlushInterval(time.Hour))
+	defer b.Close(ctx)
+
+	var allowed atomic.Int64
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if ok, err := b.Allow(ctx); err == nil && ok {
+				allowed.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// 4 次整批租借后剩余 3 个，不足一批时逐个申请
+	assert.Equal(t, int64(23), allowed.Load())
+}
+
+func TestBatchedTokenBucketLimiter_Hooks(t *testing.T) {
+	ctx := context.Background()
+	client := newSpecClient(t)
+
+	type event struct {
+		allowed bool
+		n       int64
+	}
+	var inner, outer []event
+	record := func(events *[]event) Hooks {
+		return HookFuncs{
+			Allow: func(_ context.Context, e HookEvent) { *events = append(*events, event{true, e.N}) },
+			Deny:  func(_ context.Context, e HookEvent) { *events = append(*events, event{false, e.N}) },
+		}
+	}
+	tb := NewTokenBucketLimiter(client, "batch-hooks",
+		WithTokenBucketRate(0.001),
+		WithTokenBucketCapacity(10),
+		WithTokenBucketHooks(record(&inner)),
+		WithTokenBucketStats(time.Hour),
+	)
+	b := NewBatchedTokenBucketLimiter(tb, WithBatchSize(8), WithBatchFlushInterval(time.Hour), WithBatchHooks(record(&outer)))
+	defer b.Close(ctx)
+
+	// 租借 8 个，随后的 7 个从本地扣减
+	ok, err := b.Allow(ctx)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = b.AllowN(ctx, 7)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	// Redis 只剩 2 个：租借被拒绝后退化为只申请本次的 n，内部钩子只按最终结果触发一次
+	ok, err = b.Allow(ctx)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = b.AllowN(ctx, 5)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	assert.Equal(t, []event{{true, 1}, {true, 1}, {false, 5}}, inner)
+	assert.Equal(t, []event{{true, 1}, {true, 7}, {true, 1}, {false, 5}}, outer)
+
+	// 放行数为实际从 Redis 取走的 token（整批 8 个 + 退化的 1 个），拒绝数只计本次请求的 5 个
+	stats, err := tb.Stats(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(9), stats.Allowed)
+	assert.Equal(t, int64(5), stats.Denied)
+}
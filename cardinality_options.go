@@ -0,0 +1,47 @@
+package limiter
+
+import "time"
+
+// CardinalityOption 为不同成员数量限制器的配置项。
+type CardinalityOption func(*CardinalityLimiter)
+
+// WithCardinalityLimit 设置窗口内允许的不同成员数。
+func WithCardinalityLimit(limit int64) CardinalityOption {
+	return func(l *CardinalityLimiter) {
+		if limit > 0 {
+			l.Limit = limit
+		}
+	}
+}
+
+// WithCardinalityWindow 设置窗口大小（最小精度为毫秒）。
+func WithCardinalityWindow(d time.Duration) CardinalityOption {
+	return func(l *CardinalityLimiter) {
+		if d >= time.Millisecond {
+			l.Window = d
+		}
+	}
+}
+
+// WithCardinalityMode 设置存储方式：CardinalityExact（SET，默认）或 CardinalityApprox（HyperLogLog）。
+func WithCardinalityMode(mode CardinalityMode) CardinalityOption {
+	return func(l *CardinalityLimiter) {
+		l.Mode = mode
+	}
+}
+
+// WithCardinalityPrefix 设置 Redis key 前缀。
+func WithCardinalityPrefix(prefix string) CardinalityOption {
+	return func(l *CardinalityLimiter) {
+		if prefix != "" {
+			l.Prefix = prefix
+		}
+	}
+}
+
+// WithCardinalityHooks 设置判定事件钩子，用于接入日志、指标或告警。
+func WithCardinalityHooks(h Hooks) CardinalityOption {
+	return func(l *CardinalityLimiter) {
+		l.Hooks = h
+	}
+}
@@ -0,0 +1,77 @@
+package limiter
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCardinalityLimiter(t *testing.T) {
+	for name, mode := range map[string]CardinalityMode{"exact": CardinalityExact, "approx": CardinalityApprox} {
+		t.Run(name, func(t *testing.T) {
+			mr := miniredis.RunT(t)
+			client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+			ctx := context.Background()
+
+			l := NewCardinalityLimiter(client, "account:42",
+				WithCardinalityLimit(3), WithCardinalityWindow(time.Hour), WithCardinalityMode(mode))
+
+			for i, ip := range []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"} {
+				res, err := l.AllowMemberWithResult(ctx, ip)
+				assert.NoError(t, err)
+				assert.True(t, res.Allowed)
+				assert.Equal(t, float64(2-i), res.Remaining)
+			}
+
+			// 第 4 个不同成员被拒绝
+			res, err := l.AllowMemberWithResult(ctx, "10.0.0.4")
+			assert.NoError(t, err)
+			assert.False(t, res.Allowed)
+			assert.Greater(t, res.RetryAfter, time.Duration(0))
+
+			// 已出现过的成员不受限制，也不重复计数。
+			// miniredis 的 HyperLogLog 在 PFCOUNT 之后会误报 PFADD 修改了寄存器，近似模式无法在这里验证。
+			if mode == CardinalityExact {
+				ok, err := l.AllowMember(ctx, "10.0.0.2")
+				assert.NoError(t, err)
+				assert.True(t, ok)
+			}
+			n, err := l.Count(ctx)
+			assert.NoError(t, err)
+			assert.Equal(t, int64(3), n)
+
+			// 窗口结束后重新计数
+			mr.FastForward(time.Hour)
+			ok, err := l.AllowMember(ctx, "10.0.0.4")
+			assert.NoError(t, err)
+			assert.True(t, ok)
+
+			assert.NoError(t, l.Reset(ctx))
+			n, err = l.Count(ctx)
+			assert.NoError(t, err)
+			assert.Equal(t, int64(0), n)
+		})
+	}
+}
+
+func TestCardinalityLimiter_ApproxLarge(t *testing.T) {
+	client := newSpecClient(t)
+	ctx := context.Background()
+
+	l := NewCardinalityLimiter(client, "card:big", WithCardinalityLimit(1000), WithCardinalityMode(CardinalityApprox))
+	allowed := 0
+	for i := 0; i < 1200; i++ {
+		ok, err := l.AllowMember(ctx, fmt.Sprintf("member-%d", i))
+		assert.NoError(t, err)
+		if ok {
+			allowed++
+		}
+	}
+	// HyperLogLog 为估算值，放行数量在上限附近
+	assert.InDelta(t, 1000, allowed, 50)
+}
@@ -0,0 +1,54 @@
+package limiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type checker interface {
+	CheckN(ctx context.Context, n int64) (Result, error)
+	AllowNWithResult(ctx context.Context, n int64) (Result, error)
+}
+
+func TestCheckN_DoesNotConsume(t *testing.T) {
+	client := newSpecClient(t)
+	ctx := context.Background()
+
+	cases := map[string]checker{
+		"token_bucket":           NewTokenBucketLimiter(client, "check", WithTokenBucketRate(1), WithTokenBucketCapacity(10)),
+		"leaky_bucket":           NewLeakyBucketLimiter(client, "check", WithLeakyBucketRate(1), WithLeakyBucketCapacity(10)),
+		"sliding_window":         NewSlidingWindowLimiter(client, "check", WithSlidingWindowLimit(10)),
+		"sliding_window_counter": NewSlidingWindowCounterLimiter(client, "check", WithSlidingWindowCounterLimit(10)),
+		"fixed_window":           NewFixedWindowLimiter(client, "check", WithFixedWindowLimit(10), WithFixedWindowWindow(time.Minute)),
+	}
+
+	for name, l := range cases {
+		t.Run(name, func(t *testing.T) {
+			for i := 0; i < 3; i++ {
+				res, err := l.CheckN(ctx, 4)
+				assert.NoError(t, err)
+				assert.True(t, res.Allowed)
+				assert.Equal(t, float64(10), res.Remaining)
+			}
+
+			res, err := l.AllowNWithResult(ctx, 8)
+			assert.NoError(t, err)
+			assert.True(t, res.Allowed)
+
+			res, err = l.CheckN(ctx, 4)
+			assert.NoError(t, err)
+			assert.False(t, res.Allowed)
+			assert.Greater(t, res.RetryAfter, time.Duration(0))
+
+			res, err = l.CheckN(ctx, 2)
+			assert.NoError(t, err)
+			assert.True(t, res.Allowed)
+
+			_, err = l.CheckN(ctx, 0)
+			assert.Error(t, err)
+		})
+	}
+}
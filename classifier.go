@@ -0,0 +1,36 @@
+package limiter
+
+import "context"
+
+// Classifier 负责把一个请求归类为一次限流决策：
+//   - key:  限流 key（例如 "user:123"、"ip:1.2.3.4"），返回空字符串表示该请求不限流
+//   - cost: 本次请求消耗的许可数量（通常为 1，重接口可以更大）
+//   - rule: 命中的规则名称，用于选择限流器、打点和排查问题
+//
+// 所有中间件（HTTP、gRPC 等）都通过 Classifier 决定“限什么、限多少”，
+// 让这部分路由逻辑集中在一处并且可以单独测试。req 的具体类型由调用方决定，
+// 例如 HTTP 中间件传入 *http.Request。
+type Classifier interface {
+	Classify(ctx context.Context, req any) (key string, cost int64, rule string)
+}
+
+// ClassifierFunc 让普通函数满足 Classifier 接口。
+type ClassifierFunc func(ctx context.Context, req any) (key string, cost int64, rule string)
+
+// Classify 实现 Classifier 接口。
+func (f ClassifierFunc) Classify(ctx context.Context, req any) (string, int64, string) {
+	return f(ctx, req)
+}
+
+// FirstMatch 将多个 Classifier 串联：依次调用，返回第一个 key 非空的结果。
+// 全部未命中时返回空 key（不限流）。
+func FirstMatch(classifiers ...Classifier) Classifier {
+	return ClassifierFunc(func(ctx context.Context, req any) (string, int64, string) {
+		for _, c := range classifiers {
+			if key, cost, rule := c.Classify(ctx, req); key != "" {
+				return key, cost, rule
+			}
+		}
+		return "", 0, ""
+	})
+}
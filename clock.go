@@ -0,0 +1,20 @@
+package limiter
+
+import "time"
+
+// Clock 为限流器提供当前时间，测试中可以注入可控的时钟，
+// 替代 gomonkey 打桩或在 redismock 断言中忽略 nowMs。
+type Clock interface {
+	Now() time.Time
+}
+
+// ClockFunc 将普通函数适配为 Clock。
+type ClockFunc func() time.Time
+
+// Now 实现 Clock。
+func (f ClockFunc) Now() time.Time {
+	return f()
+}
+
+// SystemClock 使用本机时间，是所有限流器的默认时钟。
+var SystemClock Clock = ClockFunc(time.Now)
@@ -0,0 +1,183 @@
+// limiter-admin 启动一个独立的限流器运维管理服务（见 adminapi 包）。
+//
+// 限流器通过可重复的 -limiter 参数声明，格式为 name=algorithm:key[?参数]，例如：
+//
+//	limiter-admin -redis 127.0.0.1:6379 \
+//	  -limiter 'login=token_bucket:api:/v1/login?rate=10&capacity=20&overrides=limits' \
+//	  -limiter 'users=token_bucket:api:/v1/chat?rate=1000&capacity=1000&shards=16' \
+//	  -limiter 'sms=sliding_window:sms?limit=5&window=1m'
+//
+// algorithm 可选 token_bucket、leaky_bucket、sliding_window、sliding_window_counter、fixed_window；
+// 参数 rate / capacity / limit / window / overrides / shards 与应用中创建限流器时的配置保持一致，
+// 否则 State 中的容量、速率会与实际不符。shards 仅适用于令牌桶、漏桶与滑动窗口。
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+
+	limiter "github.com/lifei6671/go-redis-limiter"
+	"github.com/lifei6671/go-redis-limiter/adminapi"
+)
+
+// specs 收集可重复的 -limiter 参数。
+type specs []string
+
+func (s *specs) String() string     { return strings.Join(*s, ", ") }
+func (s *specs) Set(v string) error { *s = append(*s, v); return nil }
+
+func main() {
+	var (
+		listen   = flag.String("listen", ":8080", "HTTP listen address")
+		addr     = flag.String("redis", "127.0.0.1:6379", "Redis address")
+		password = flag.String("password", "", "Redis password")
+		db       = flag.Int("db", 0, "Redis database")
+		token    = flag.String("token", "", "bearer token required by the admin API (empty disables auth)")
+		limiters specs
+	)
+	flag.Var(&limiters, "limiter", "limiter spec name=algorithm:key[?params], repeatable")
+	flag.Parse()
+
+	client := redis.NewClient(&redis.Options{Addr: *addr, Password: *password, DB: *db})
+	defer client.Close()
+
+	srv := adminapi.New(
+		adminapi.WithAuthToken(*token),
+		adminapi.WithKeyScanner(limiter.NewKeyScanner(client)),
+	)
+	for _, spec := range limiters {
+		if err := register(srv, client, spec); err != nil {
+			log.Fatalf("limiter %q: %v", spec, err)
+		}
+	}
+
+	log.Printf("limiter-admin listening on %s (%d limiters)", *listen, len(limiters))
+	log.Fatal(http.ListenAndServe(*listen, srv))
+}
+
+// register 解析一条 -limiter 参数，创建对应的限流器并注册到 srv。
+func register(srv *adminapi.Server, client *redis.Client, spec string) error {
+	name, rest, ok := strings.Cut(spec, "=")
+	if !ok || name == "" {
+		return fmt.Errorf("missing name")
+	}
+	algorithm, key, ok := strings.Cut(rest, ":")
+	if !ok || key == "" {
+		return fmt.Errorf("missing key")
+	}
+	var query url.Values
+	if i := strings.LastIndex(key, "?"); i >= 0 {
+		q, err := url.ParseQuery(key[i+1:])
+		if err != nil {
+			return err
+		}
+		key, query = key[:i], q
+	}
+
+	p := params{Values: query}
+	rate, capacity, limit, window := p.float("rate"), p.float("capacity"), p.int("limit"), p.duration("window")
+	overrides, shards := query.Get("overrides"), int(p.int("shards"))
+	if p.err != nil {
+		return p.err
+	}
+
+	switch algorithm {
+	case "token_bucket":
+		var opts []limiter.TokenBucketOption
+		if rate > 0 {
+			opts = append(opts, limiter.WithTokenBucketRate(rate))
+		}
+		if capacity > 0 {
+			opts = append(opts, limiter.WithTokenBucketCapacity(capacity))
+		}
+		if overrides != "" {
+			opts = append(opts, limiter.WithTokenBucketOverrides(overrides))
+		}
+		if shards > 0 {
+			srv.RegisterSharded(name, limiter.NewShardedTokenBucketLimiter(client, key,
+				limiter.WithShardedTokenBucketCount(shards), limiter.WithShardedTokenBucket(opts...)))
+			return nil
+		}
+		srv.Register(name, limiter.NewTokenBucketLimiter(client, key, opts...))
+	case "leaky_bucket":
+		var opts []limiter.LeakyBucketOption
+		if rate > 0 {
+			opts = append(opts, limiter.WithLeakyBucketRate(rate))
+		}
+		if capacity > 0 {
+			opts = append(opts, limiter.WithLeakyBucketCapacity(capacity))
+		}
+		if overrides != "" {
+			opts = append(opts, limiter.WithLeakyBucketOverrides(overrides))
+		}
+		if shards > 0 {
+			srv.RegisterSharded(name, limiter.NewShardedLeakyBucketLimiter(client, key,
+				limiter.WithShardedLeakyBucketCount(shards), limiter.WithShardedLeakyBucket(opts...)))
+			return nil
+		}
+		srv.Register(name, limiter.NewLeakyBucketLimiter(client, key, opts...))
+	case "sliding_window":
+		opts := []limiter.SlidingWindowOption{
+			limiter.WithSlidingWindowLimit(limit),
+			limiter.WithSlidingWindowWindow(window),
+		}
+		if shards > 0 {
+			srv.RegisterSharded(name, limiter.NewShardedSlidingWindowLimiter(client, key,
+				limiter.WithShardedSlidingWindowCount(shards), limiter.WithShardedSlidingWindow(opts...)))
+			return nil
+		}
+		srv.Register(name, limiter.NewSlidingWindowLimiter(client, key, opts...))
+	case "sliding_window_counter":
+		srv.Register(name, limiter.NewSlidingWindowCounterLimiter(client, key,
+			limiter.WithSlidingWindowCounterLimit(limit),
+			limiter.WithSlidingWindowCounterWindow(window),
+		))
+	case "fixed_window":
+		srv.Register(name, limiter.NewFixedWindowLimiter(client, key,
+			limiter.WithFixedWindowLimit(limit),
+			limiter.WithFixedWindowWindow(window),
+		))
+	default:
+		return fmt.Errorf("unknown algorithm %q", algorithm)
+	}
+	return nil
+}
+
+// params 解析查询参数，记录遇到的第一个错误；未提供的参数返回零值，对应配置保留默认值。
+type params struct {
+	url.Values
+	err error
+}
+
+func (p *params) parse(name string, fn func(string) error) {
+	v := p.Get(name)
+	if v == "" || p.err != nil {
+		return
+	}
+	if err := fn(v); err != nil {
+		p.err = fmt.Errorf("invalid %s: %w", name, err)
+	}
+}
+
+func (p *params) float(name string) (f float64) {
+	p.parse(name, func(v string) (err error) { f, err = strconv.ParseFloat(v, 64); return })
+	return f
+}
+
+func (p *params) int(name string) (n int64) {
+	p.parse(name, func(v string) (err error) { n, err = strconv.ParseInt(v, 10, 64); return })
+	return n
+}
+
+func (p *params) duration(name string) (d time.Duration) {
+	p.parse(name, func(v string) (err error) { d, err = time.ParseDuration(v); return })
+	return d
+}
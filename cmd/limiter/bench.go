@@ -0,0 +1,237 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"text/tabwriter"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+
+	limiter "github.com/lifei6671/go-redis-limiter"
+)
+
+// benchAlgorithms 是 bench 支持的算法，也是 -algorithms=all 时的执行顺序。
+var benchAlgorithms = []string{
+	"token_bucket",
+	"leaky_bucket",
+	"sliding_window",
+	"sliding_window_counter",
+	"fixed_window",
+}
+
+// benchConfig 是一次压测的参数。
+type benchConfig struct {
+	concurrency int
+	qps         float64 // 目标总 QPS，0 表示不限速
+	duration    time.Duration
+
+	rate     float64       // 令牌桶 / 漏桶速率
+	capacity float64       // 令牌桶 / 漏桶容量
+	limit    int64         // 窗口类算法的上限
+	window   time.Duration // 窗口类算法的窗口大小
+}
+
+// benchResult 是单个算法的压测结果。
+type benchResult struct {
+	algorithm string
+	allowed   int64
+	denied    int64
+	errors    int64
+	elapsed   time.Duration
+	latencies []time.Duration // 已排序
+}
+
+func (r benchResult) total() int64 {
+	return r.allowed + r.denied + r.errors
+}
+
+// percentile 返回第 p（0~1）分位的延迟。
+func (r benchResult) percentile(p float64) time.Duration {
+	if len(r.latencies) == 0 {
+		return 0
+	}
+	i := int(math.Ceil(p*float64(len(r.latencies)))) - 1
+	return r.latencies[max(i, 0)]
+}
+
+func runBench(args []string) error {
+	fs := flag.NewFlagSet("bench", flag.ContinueOnError)
+	var (
+		addr       = fs.String("redis", "127.0.0.1:6379", "Redis address")
+		password   = fs.String("password", "", "Redis password")
+		db         = fs.Int("db", 0, "Redis database")
+		algorithms = fs.String("algorithms", "all", "comma-separated algorithms to run: "+strings.Join(benchAlgorithms, ", "))
+		prefix     = fs.String("key", "bench", "business key prefix; each run uses <key>:<algorithm>:<timestamp>")
+		cfg        benchConfig
+	)
+	fs.IntVar(&cfg.concurrency, "concurrency", 16, "number of concurrent workers")
+	fs.Float64Var(&cfg.qps, "qps", 0, "target total QPS across workers (0 = as fast as possible)")
+	fs.DurationVar(&cfg.duration, "duration", 10*time.Second, "duration per algorithm")
+	fs.Float64Var(&cfg.rate, "rate", 1000, "token/leaky bucket rate per second")
+	fs.Float64Var(&cfg.capacity, "capacity", 1000, "token/leaky bucket capacity")
+	fs.Int64Var(&cfg.limit, "limit", 1000, "window limit for window-based algorithms")
+	fs.DurationVar(&cfg.window, "window", time.Second, "window size for window-based algorithms")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if cfg.concurrency <= 0 || cfg.duration <= 0 || cfg.qps < 0 {
+		return fmt.Errorf("concurrency and duration must > 0, qps must >= 0")
+	}
+
+	algs := benchAlgorithms
+	if *algorithms != "all" {
+		algs = strings.Split(*algorithms, ",")
+		for _, alg := range algs {
+			if !slices.Contains(benchAlgorithms, alg) {
+				return fmt.Errorf("unknown algorithm %q", alg)
+			}
+		}
+	}
+
+	client := redis.NewClient(&redis.Options{
+		Addr:     *addr,
+		Password: *password,
+		DB:       *db,
+		PoolSize: cfg.concurrency,
+	})
+	defer client.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if err := client.Ping(ctx).Err(); err != nil {
+		return fmt.Errorf("ping redis: %w", err)
+	}
+
+	results := make([]benchResult, 0, len(algs))
+	for _, alg := range algs {
+		key := fmt.Sprintf("%s:%s:%d", *prefix, alg, time.Now().UnixNano())
+		l := newBenchLimiter(client, alg, key, cfg)
+
+		fmt.Fprintf(os.Stderr, "running %s for %s ...\n", alg, cfg.duration)
+		res := bench(ctx, l, cfg)
+		res.algorithm = alg
+		results = append(results, res)
+
+		if err := l.Reset(context.Background()); err != nil {
+			fmt.Fprintf(os.Stderr, "reset %s: %v\n", key, err)
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	printBench(results)
+	return nil
+}
+
+// newBenchLimiter 按 bench 参数创建指定算法的单桶限流器。
+func newBenchLimiter(client *redis.Client, alg, key string, cfg benchConfig) limiter.RateLimiter {
+	switch alg {
+	case "token_bucket":
+		return limiter.NewTokenBucketLimiter(client, key,
+			limiter.WithTokenBucketRate(cfg.rate), limiter.WithTokenBucketCapacity(cfg.capacity))
+	case "leaky_bucket":
+		return limiter.NewLeakyBucketLimiter(client, key,
+			limiter.WithLeakyBucketRate(cfg.rate), limiter.WithLeakyBucketCapacity(cfg.capacity))
+	case "sliding_window":
+		return limiter.NewSlidingWindowLimiter(client, key,
+			limiter.WithSlidingWindowLimit(cfg.limit), limiter.WithSlidingWindowWindow(cfg.window))
+	case "sliding_window_counter":
+		return limiter.NewSlidingWindowCounterLimiter(client, key,
+			limiter.WithSlidingWindowCounterLimit(cfg.limit), limiter.WithSlidingWindowCounterWindow(cfg.window))
+	default:
+		return limiter.NewFixedWindowLimiter(client, key,
+			limiter.WithFixedWindowLimit(cfg.limit), limiter.WithFixedWindowWindow(cfg.window))
+	}
+}
+
+// bench 以 cfg.concurrency 个 worker 持续调用 Allow，直到 cfg.duration 结束或 ctx 取消。
+// 设置了目标 QPS 时，每个 worker 按 concurrency/qps 的间隔匀速发起请求。
+func bench(ctx context.Context, l limiter.RateLimiter, cfg benchConfig) benchResult {
+	ctx, cancel := context.WithTimeout(ctx, cfg.duration)
+	defer cancel()
+
+	var interval time.Duration
+	if cfg.qps > 0 {
+		interval = time.Duration(float64(time.Second) * float64(cfg.concurrency) / cfg.qps)
+	}
+
+	var (
+		allowed, denied, errs atomic.Int64
+		wg                    sync.WaitGroup
+		lats                  = make([][]time.Duration, cfg.concurrency)
+	)
+	start := time.Now()
+	for i := range cfg.concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			next := time.Now()
+			for ctx.Err() == nil {
+				if interval > 0 {
+					if d := time.Until(next); d > 0 {
+						time.Sleep(d)
+					}
+					next = next.Add(interval)
+				}
+
+				// 判定本身不使用 ctx，避免压测结束时正在进行的调用被计为错误
+				t := time.Now()
+				ok, err := l.Allow(context.Background())
+				lats[i] = append(lats[i], time.Since(t))
+				switch {
+				case err != nil:
+					errs.Add(1)
+				case ok:
+					allowed.Add(1)
+				default:
+					denied.Add(1)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	res := benchResult{
+		allowed: allowed.Load(),
+		denied:  denied.Load(),
+		errors:  errs.Load(),
+		elapsed: time.Since(start),
+	}
+	for _, l := range lats {
+		res.latencies = append(res.latencies, l...)
+	}
+	slices.Sort(res.latencies)
+	return res
+}
+
+func printBench(results []benchResult) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ALGORITHM\tREQUESTS\tQPS\tDENY%\tERRORS\tP50\tP99\tMAX\t")
+	for _, r := range results {
+		var denyRate float64
+		if n := r.allowed + r.denied; n > 0 {
+			denyRate = float64(r.denied) / float64(n) * 100
+		}
+		fmt.Fprintf(w, "%s\t%d\t%.0f\t%.1f\t%d\t%s\t%s\t%s\t\n",
+			r.algorithm,
+			r.total(),
+			float64(r.total())/r.elapsed.Seconds(),
+			denyRate,
+			r.errors,
+			r.percentile(0.50).Round(time.Microsecond),
+			r.percentile(0.99).Round(time.Microsecond),
+			r.percentile(1).Round(time.Microsecond),
+		)
+	}
+	_ = w.Flush()
+}
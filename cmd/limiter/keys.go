@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/go-redis/redis/v8"
+
+	limiter "github.com/lifei6671/go-redis-limiter"
+)
+
+// prefixFlags 收集可重复的 -prefix prefix=type 参数。
+type prefixFlags []string
+
+func (p *prefixFlags) String() string     { return strings.Join(*p, ", ") }
+func (p *prefixFlags) Set(v string) error { *p = append(*p, v); return nil }
+
+func runKeys(args []string) error {
+	fs := flag.NewFlagSet("keys", flag.ContinueOnError)
+	var (
+		addr     = fs.String("redis", "127.0.0.1:6379", "Redis address")
+		password = fs.String("password", "", "Redis password")
+		db       = fs.Int("db", 0, "Redis database")
+		match    = fs.String("match", "*", "glob pattern matched against business keys")
+		prefixes prefixFlags
+	)
+	fs.Var(&prefixes, "prefix", "custom key prefix as prefix=type (e.g. auth=fixed_window), repeatable")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	opts := make([]limiter.KeyScannerOption, 0, len(prefixes))
+	for _, p := range prefixes {
+		prefix, typ, ok := strings.Cut(p, "=")
+		if !ok || prefix == "" || typ == "" {
+			return fmt.Errorf("invalid -prefix %q, want prefix=type", p)
+		}
+		opts = append(opts, limiter.WithKeyScannerPrefix(prefix, typ))
+	}
+
+	client := redis.NewClient(&redis.Options{Addr: *addr, Password: *password, DB: *db})
+	defer client.Close()
+
+	keys, err := limiter.NewKeyScanner(client, opts...).ScanKeys(context.Background(), *match)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TYPE\tKEY\tSHARD\t")
+	for _, k := range keys {
+		shard := "-"
+		if k.Shard >= 0 {
+			shard = fmt.Sprint(k.Shard)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t\n", k.Type, k.Key, shard)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,48 @@
+// limiter 是 go-redis-limiter 的命令行工具。
+//
+// 子命令：
+//
+//	bench   针对真实 Redis 压测各算法，报告吞吐、拒绝率与延迟分位，用于上线前评估 Redis 容量
+//	keys    扫描 Redis 中当前存在的限流 key
+//
+// 运行 "limiter <command> -h" 查看子命令参数。
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	var err error
+	switch cmd, args := os.Args[1], os.Args[2:]; cmd {
+	case "bench":
+		err = runBench(args)
+	case "keys":
+		err = runKeys(args)
+	case "-h", "-help", "--help", "help":
+		usage()
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "limiter: unknown command %q\n\n", cmd)
+		usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "limiter %s: %v\n", os.Args[1], err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, `usage: limiter <command> [flags]
+
+commands:
+  bench   drive limiter algorithms against a real Redis and report throughput, deny rate and latency
+  keys    list limiter keys currently present in Redis`)
+}
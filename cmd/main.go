@@ -16,9 +16,12 @@ func main() {
 		Addr:     "localhost:6379",
 		Password: "redis_password",
 	})
-	redisLimiter := limiter.NewShardedTokenBucketLimiter(client, "limiter:", 2,
-		limiter.WithTokenBucketRate(2),
-		limiter.WithTokenBucketCapacity(2),
+	redisLimiter := limiter.NewShardedTokenBucketLimiter(client, "limiter:",
+		limiter.WithShardedTokenBucketCount(2),
+		limiter.WithShardedTokenBucket(
+			limiter.WithTokenBucketRate(2),
+			limiter.WithTokenBucketCapacity(2),
+		),
 	)
 	ctx := context.Background()
 
@@ -0,0 +1,140 @@
+package limiter
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// coalesceBatch 是合并窗口内攒下的一批请求。
+type coalesceBatch struct {
+	reqs    []int64  // 各请求需要的 token 数，按到达顺序
+	results []Result // 与 reqs 一一对应的判定结果
+	err     error
+	done    chan struct{}
+}
+
+// coalesce 把窗口内对同一个桶的并发请求合并成一次脚本调用，结果在本地分发：
+//   - 第一个请求开启一批，CoalesceWindow 后由后台协程统一执行；之后到达的请求加入同一批
+//   - 整批能放下时一次脚本调用全部放行
+//   - 放不下时按到达顺序挑出桶内剩余 token 能容纳的请求，再扣减一次，其余请求被拒绝
+//
+// 两次脚本调用都不在脚本中统计，批次判定完成后按每个请求的最终结果记录放行 / 拒绝数量；
+// 钩子由 AllowNWithResult 按每个调用方的结果各触发一次。
+//
+// 调用方在结果返回前 ctx 结束时直接返回 ctx 错误，但它的请求仍会参与本批判定（可能扣减了 token）。
+func (tb *TokenBucketLimiter) coalesce(ctx context.Context, n int64) (Result, error) {
+	tb.cmu.Lock()
+	b := tb.batch
+	if b == nil {
+		b = &coalesceBatch{done: make(chan struct{})}
+		tb.batch = b
+		// 批次不应随第一个请求的 ctx 一起取消，只保留其中的值（例如链路追踪信息）
+		fctx := context.WithoutCancel(ctx)
+		time.AfterFunc(tb.CoalesceWindow, func() { tb.flushBatch(fctx, b) })
+	}
+	i := len(b.reqs)
+	b.reqs = append(b.reqs, n)
+	tb.cmu.Unlock()
+
+	select {
+	case <-ctx.Done():
+		return Result{}, ctx.Err()
+	case <-b.done:
+	}
+	if b.err != nil {
+		return Result{}, b.err
+	}
+	return b.results[i], nil
+}
+
+// flushBatch 对一批请求执行判定并唤醒等待中的调用方。
+func (tb *TokenBucketLimiter) flushBatch(ctx context.Context, b *coalesceBatch) {
+	tb.cmu.Lock()
+	if tb.batch == b {
+		tb.batch = nil
+	}
+	tb.cmu.Unlock()
+	defer close(b.done)
+
+	var total int64
+	for _, n := range b.reqs {
+		total += n
+	}
+	res, err := tb.call(total, "", false).run(ctx)
+	if err != nil {
+		b.err = err
+		return
+	}
+
+	granted := make([]bool, len(b.reqs))
+	if res.Allowed {
+		for i := range granted {
+			granted[i] = true
+		}
+		b.results = coalesceResults(b.reqs, granted, res, 0)
+		tb.recordBatchStats(ctx, b.reqs, granted)
+		return
+	}
+
+	// 整批被拒绝时脚本按缺口给出了等待时间，据此换算出实际生效的补充速率（token/ms），
+	// 用于估算每个被拒绝的请求各自需要等待多久
+	rate := (float64(total) - res.Remaining) / float64(max(res.RetryAfter.Milliseconds(), 1))
+	if fit := pickFit(b.reqs, int64(res.Remaining), granted); fit > 0 {
+		after, err := tb.call(fit, "", false).run(ctx)
+		if err != nil {
+			b.err = err
+			return
+		}
+		if !after.Allowed {
+			// 两次调用之间 token 被其他实例取走，整批拒绝
+			clear(granted)
+		}
+		res = after
+	}
+	b.results = coalesceResults(b.reqs, granted, res, rate)
+	tb.recordBatchStats(ctx, b.reqs, granted)
+}
+
+// recordBatchStats 按每个请求的最终结果累加统计计数器（需要开启统计）。
+// 统计只用于观测，写入失败不影响已经完成的判定。
+func (tb *TokenBucketLimiter) recordBatchStats(ctx context.Context, reqs []int64, granted []bool) {
+	if tb.StatsTTL <= 0 {
+		return
+	}
+	var allowed, denied int64
+	for i, n := range reqs {
+		if granted[i] {
+			allowed += n
+		} else {
+			denied += n
+		}
+	}
+	_ = recordStats(ctx, tb.client, statsBase(tb.Prefix, tb.Key), tb.StatsTTL, allowed, denied)
+}
+
+// coalesceResults 根据最后一次判定结果生成每个请求的结果，被拒绝的请求按 rate（token/ms）估算 RetryAfter。
+func coalesceResults(reqs []int64, granted []bool, res Result, rate float64) []Result {
+	out := make([]Result, len(reqs))
+	for i, n := range reqs {
+		out[i] = Result{Allowed: granted[i], Limit: res.Limit, Remaining: res.Remaining}
+		if !granted[i] {
+			need := max(float64(n)-res.Remaining, 1)
+			out[i].RetryAfter = time.Duration(math.Ceil(need/rate)) * time.Millisecond
+		}
+	}
+	return out
+}
+
+// pickFit 按顺序挑出总量不超过 avail 的请求（放不下的跳过，继续尝试后面更小的请求），
+// 在 granted 中标记并返回挑出的总量。
+func pickFit(reqs []int64, avail int64, granted []bool) int64 {
+	var fit int64
+	for i, n := range reqs {
+		if fit+n <= avail {
+			fit += n
+			granted[i] = true
+		}
+	}
+	return fit
+}
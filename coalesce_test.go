@@ -0,0 +1,124 @@
+package limiter
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+)
+
+// countingHook 统计客户端执行的命令数。
+type countingHook struct{ n atomic.Int64 }
+
+func (h *countingHook) BeforeProcess(ctx context.Context, _ redis.Cmder) (context.Context, error) {
+	h.n.Add(1)
+	return ctx, nil
+}
+
+func (h *countingHook) AfterProcess(context.Context, redis.Cmder) error { return nil }
+
+func (h *countingHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	h.n.Add(int64(len(cmds)))
+	return ctx, nil
+}
+
+func (h *countingHook) AfterProcessPipeline(context.Context, []redis.Cmder) error { return nil }
+
+func TestTokenBucketLimiter_Coalesce(t *testing.T) {
+	ctx := context.Background()
+	client := newSpecClient(t)
+	hook := &countingHook{}
+	client.AddHook(hook)
+
+	tb := NewTokenBucketLimiter(client, "coalesce",
+		WithTokenBucketRate(1),
+		WithTokenBucketCapacity(5),
+		WithTokenBucketTTL(time.Minute),
+		WithTokenBucketCoalesce(100*time.Millisecond),
+	)
+
+	// 10 个并发请求合并成一批：整批放不下，按到达顺序放行 5 个，共两次脚本调用
+	var allowed, denied atomic.Int64
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res, err := tb.AllowWithResult(ctx)
+			assert.NoError(t, err)
+			if res.Allowed {
+				allowed.Add(1)
+				return
+			}
+			denied.Add(1)
+			assert.Greater(t, res.RetryAfter, time.Duration(0))
+			assert.LessOrEqual(t, res.RetryAfter, 2*time.Second)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int64(5), allowed.Load())
+	assert.Equal(t, int64(5), denied.Load())
+	assert.LessOrEqual(t, hook.n.Load(), int64(4)) // EVALSHA 遇到 NOSCRIPT 时会多一次 EVAL
+
+	// 整批能放下时只执行一次脚本
+	assert.NoError(t, tb.Reset(ctx))
+	hook.n.Store(0)
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ok, err := tb.Allow(ctx)
+			assert.NoError(t, err)
+			assert.True(t, ok)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int64(1), hook.n.Load())
+}
+
+func TestTokenBucketLimiter_CoalesceStats(t *testing.T) {
+	ctx := context.Background()
+	client := newSpecClient(t)
+
+	var hookAllowed, hookDenied atomic.Int64
+	tb := NewTokenBucketLimiter(client, "coalesce:stats",
+		WithTokenBucketRate(1),
+		WithTokenBucketCapacity(5),
+		WithTokenBucketTTL(time.Minute),
+		WithTokenBucketCoalesce(100*time.Millisecond),
+		WithTokenBucketStats(time.Minute),
+		WithTokenBucketHooks(HookFuncs{
+			Allow: func(context.Context, HookEvent) { hookAllowed.Add(1) },
+			Deny:  func(context.Context, HookEvent) { hookDenied.Add(1) },
+		}),
+	)
+
+	// 部分放行的批次按每个请求的最终结果各统计一次，不会把探测调用记成拒绝
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := tb.AllowWithResult(ctx)
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	stats, err := tb.Stats(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, LimiterStats{Allowed: 5, Denied: 3}, stats)
+	assert.Equal(t, int64(5), hookAllowed.Load())
+	assert.Equal(t, int64(3), hookDenied.Load())
+}
+
+func TestPickFit(t *testing.T) {
+	granted := make([]bool, 4)
+	fit := pickFit([]int64{2, 3, 1, 1}, 4, granted)
+	assert.Equal(t, int64(4), fit)
+	assert.Equal(t, []bool{true, false, true, true}, granted)
+}
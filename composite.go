@@ -0,0 +1,256 @@
+package limiter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// CompositeRule 是组合限流器中的一条固定窗口规则，例如“每分钟 300 次”。
+type CompositeRule struct {
+	// Name 规则名称，用于 Redis key 及拒绝时告知调用方是哪条规则生效，例如 "minute"
+	Name string
+	// Window 窗口大小（最小精度为毫秒）
+	Window time.Duration
+	// Limit 窗口内最大允许请求数
+	Limit int64
+}
+
+// CompositeResult 是组合限流一次判定的结果。
+type CompositeResult struct {
+	Result
+
+	// DeniedBy 被拒绝时为需要等待最久的规则名称，放行时为空
+	DeniedBy string
+}
+
+// CompositeLimiter 对同一个 key 同时施加多条窗口规则，
+// 例如“10 次/秒 且 300 次/分钟 且 5000 次/天”。
+// 全部规则在同一个 Lua 脚本中原子判断：任意一条不满足即整体拒绝，且不扣减任何规则的计数。
+// 每条规则都是一个固定窗口计数器，语义与 FixedWindowLimiter 相同。
+type CompositeLimiter struct {
+	client *redis.Client
+
+	Key    string          // 业务 key
+	Prefix string          // Redis key 前缀，默认 "cmp"
+	Rules  []CompositeRule // 规则列表，至少一条
+
+	// WaitJitter Wait 重试前的随机抖动比例（0~1）
+	WaitJitter float64
+	// WaitStrategy Wait 被拒绝后的等待策略（可选），默认按 RetryAfter 等待并叠加 WaitJitter，见 WaitStrategy。
+	WaitStrategy WaitStrategy
+
+	// Clock 时钟，默认 SystemClock。
+	Clock Clock
+
+	// Hooks 判定事件钩子（可选），每次放行 / 拒绝 / 出错时回调，见 Hooks。
+	Hooks Hooks
+}
+
+// NewCompositeLimiter 创建一个组合限流器。
+func NewCompositeLimiter(
+	client *redis.Client,
+	key string,
+	rules []CompositeRule,
+	opts ...CompositeOption,
+) *CompositeLimiter {
+
+	if client == nil {
+		panic("composite: redis client is nil")
+	}
+	if key == "" {
+		panic("composite: key is empty")
+	}
+	if len(rules) == 0 {
+		panic("composite: rules are empty")
+	}
+	names := make(map[string]struct{}, len(rules))
+	for _, r := range rules {
+		if r.Name == "" {
+			panic("composite: rule name is empty")
+		}
+		if _, ok := names[r.Name]; ok {
+			panic("composite: duplicate rule name " + r.Name)
+		}
+		if r.Window < time.Millisecond || r.Limit <= 0 {
+			panic("composite: rule " + r.Name + " must have window >= 1ms and limit > 0")
+		}
+		names[r.Name] = struct{}{}
+	}
+
+	l := &CompositeLimiter{
+		client: client,
+		Key:    key,
+		Prefix: "cmp",
+		Rules:  append([]CompositeRule(nil), rules...),
+		Clock:  SystemClock,
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// ruleKey 返回规则的计数 key。所有规则共享同一个 hash tag，保证在 Cluster 下落在同一个 slot。
+func (l *CompositeLimiter) ruleKey(r CompositeRule) string {
+	return fmt.Sprintf("%s:{%s}:%s", l.Prefix, l.Key, r.Name)
+}
+
+// keys 返回全部规则的计数 key。
+func (l *CompositeLimiter) keys() []string {
+	keys := make([]string, len(l.Rules))
+	for i, r := range l.Rules {
+		keys[i] = l.ruleKey(r)
+	}
+	return keys
+}
+
+// minLimit 返回所有规则中最小的上限，作为 Result.Limit。
+func (l *CompositeLimiter) minLimit() int64 {
+	limit := l.Rules[0].Limit
+	for _, r := range l.Rules[1:] {
+		limit = min(limit, r.Limit)
+	}
+	return limit
+}
+
+// Allow 尝试占一个名额。
+func (l *CompositeLimiter) Allow(ctx context.Context) (bool, error) {
+	return l.AllowN(ctx, 1)
+}
+
+// AllowWithResult 尝试占一个名额，并返回剩余名额及重试等待时间。
+func (l *CompositeLimiter) AllowWithResult(ctx context.Context) (Result, error) {
+	return l.AllowNWithResult(ctx, 1)
+}
+
+// AllowN 尝试一次占用 n 个名额，任意规则不足时整体拒绝。
+func (l *CompositeLimiter) AllowN(ctx context.Context, n int64) (bool, error) {
+	res, err := l.AllowNWithResult(ctx, n)
+	if err != nil {
+		return false, err
+	}
+	return res.Allowed, nil
+}
+
+// AllowNWithResult 尝试一次占用 n 个名额，并返回剩余名额及重试等待时间。
+func (l *CompositeLimiter) AllowNWithResult(ctx context.Context, n int64) (Result, error) {
+	res, err := l.AllowNDetail(ctx, n)
+	if err != nil {
+		return Result{}, err
+	}
+	return res.Result, nil
+}
+
+// AllowNDetail 与 AllowNWithResult 相同，但额外返回拒绝本次请求的规则名称。
+//   - Remaining 为所有规则中最小的剩余名额
+//   - RetryAfter 为所有不满足的规则中最晚的窗口结束时间
+func (l *CompositeLimiter) AllowNDetail(ctx context.Context, n int64) (CompositeResult, error) {
+	res, err := l.eval(ctx, n)
+	fireHooks(ctx, l.Hooks, "composite", l.Key, n, res.Result, err)
+	return res, err
+}
+
+// eval 执行组合规则脚本。
+func (l *CompositeLimiter) eval(ctx context.Context, n int64) (CompositeResult, error) {
+	if n <= 0 {
+		return CompositeResult{}, fmt.Errorf("composite: n must > 0")
+	}
+
+	args := make([]interface{}, 0, 1+2*len(l.Rules))
+	args = append(args, n)
+	for _, r := range l.Rules {
+		args = append(args, r.Window.Milliseconds(), r.Limit)
+	}
+
+	res, err := compositeScript.Run(ctx, l.client, l.keys(), args...).Result()
+	if err != nil {
+		return CompositeResult{}, err
+	}
+
+	vals, ok := scriptInts(res, 4)
+	if !ok || vals[3] >= int64(len(l.Rules)) {
+		return CompositeResult{}, fmt.Errorf("composite: unexpected script result: %#v", res)
+	}
+
+	out := CompositeResult{
+		Result: Result{
+			Allowed:    vals[0] == 1,
+			Limit:      float64(l.minLimit()),
+			Remaining:  float64(vals[1]),
+			RetryAfter: time.Duration(vals[2]) * time.Millisecond,
+		},
+	}
+	if vals[3] >= 0 {
+		out.DeniedBy = l.Rules[vals[3]].Name
+	}
+	return out, nil
+}
+
+// Wait 阻塞直到获得一个名额，或 ctx 取消 / 超过 maxWait。
+func (l *CompositeLimiter) Wait(ctx context.Context, maxWait time.Duration) error {
+	return waitFor(ctx, l.Key, "composite", maxWait, waitStrategy(l.WaitStrategy, l.WaitJitter), l.AllowWithResult)
+}
+
+// Reset 删除全部规则的计数。
+func (l *CompositeLimiter) Reset(ctx context.Context) error {
+	return l.client.Del(ctx, l.keys()...).Err()
+}
+
+// State 返回剩余名额最少（最接近被拒绝）的那条规则的状态。
+func (l *CompositeLimiter) State(ctx context.Context) (LimiterState, error) {
+	now := l.Clock.Now().UnixMilli()
+
+	gets := make([]*redis.StringCmd, len(l.Rules))
+	ttls := make([]*redis.DurationCmd, len(l.Rules))
+	_, err := l.client.Pipelined(ctx, func(p redis.Pipeliner) error {
+		for i, r := range l.Rules {
+			gets[i] = p.Get(ctx, l.ruleKey(r))
+			ttls[i] = p.PTTL(ctx, l.ruleKey(r))
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return LimiterState{}, err
+	}
+
+	var st LimiterState
+	for i, r := range l.Rules {
+		var used int64
+		usedStr, err := gets[i].Result()
+		if err != nil && !errors.Is(err, redis.Nil) {
+			return LimiterState{}, err
+		}
+		if err == nil {
+			used, err = strconv.ParseInt(usedStr, 10, 64)
+			if err != nil {
+				return LimiterState{}, fmt.Errorf("composite: invalid count for rule %s: %v", r.Name, err)
+			}
+		}
+
+		remaining := float64(max(r.Limit-used, 0))
+		if i > 0 && remaining >= st.Remaining {
+			continue
+		}
+
+		next := now
+		if used >= r.Limit {
+			next += max(ttls[i].Val().Milliseconds(), 0)
+		}
+		st = LimiterState{
+			Level:             float64(used),
+			Remaining:         remaining,
+			Capacity:          float64(r.Limit),
+			Rate:              float64(r.Limit) / r.Window.Seconds(),
+			LastUpdated:       now,
+			NextAvailableTime: next,
+			Type:              "composite",
+			Key:               l.Key,
+		}
+	}
+	return st, nil
+}
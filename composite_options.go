@@ -0,0 +1,47 @@
+package limiter
+
+// CompositeOption 为组合限流器的配置项。
+type CompositeOption func(*CompositeLimiter)
+
+// WithCompositeWaitJitter 设置 Wait 重试前的随机抖动比例，取值范围 [0, 1]。
+func WithCompositeWaitJitter(ratio float64) CompositeOption {
+	return func(l *CompositeLimiter) {
+		if ratio < 0 || ratio > 1 {
+			panic("composite: wait jitter must be in [0, 1]")
+		}
+		l.WaitJitter = ratio
+	}
+}
+
+// WithCompositeWaitStrategy 设置 Wait 被拒绝后的等待策略，例如 ExponentialWait、NewPacedWait。
+// 设置后 WaitJitter 不再生效，抖动由策略自身负责。
+func WithCompositeWaitStrategy(s WaitStrategy) CompositeOption {
+	return func(l *CompositeLimiter) {
+		l.WaitStrategy = s
+	}
+}
+
+// WithCompositeClock 设置时钟，通常用于测试中控制时间。
+func WithCompositeClock(c Clock) CompositeOption {
+	return func(l *CompositeLimiter) {
+		if c != nil {
+			l.Clock = c
+		}
+	}
+}
+
+// WithCompositePrefix 设置 Redis key 前缀。
+func WithCompositePrefix(prefix string) CompositeOption {
+	return func(l *CompositeLimiter) {
+		if prefix != "" {
+			l.Prefix = prefix
+		}
+	}
+}
+
+// WithCompositeHooks 设置判定事件钩子，用于接入日志、指标或告警。
+func WithCompositeHooks(h Hooks) CompositeOption {
+	return func(l *CompositeLimiter) {
+		l.Hooks = h
+	}
+}
@@ -0,0 +1,61 @@
+package limiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompositeLimiter(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	ctx := context.Background()
+
+	l := NewCompositeLimiter(client, "user:1", []CompositeRule{
+		{Name: "second", Window: time.Second, Limit: 2},
+		{Name: "minute", Window: time.Minute, Limit: 3},
+	})
+
+	res, err := l.AllowNDetail(ctx, 2)
+	assert.NoError(t, err)
+	assert.True(t, res.Allowed)
+	assert.Equal(t, float64(0), res.Remaining)
+
+	res, err = l.AllowNDetail(ctx, 1)
+	assert.NoError(t, err)
+	assert.False(t, res.Allowed)
+	assert.Equal(t, "second", res.DeniedBy)
+	assert.Equal(t, time.Second, res.RetryAfter)
+
+	// 秒级窗口过期后，分钟规则仍只剩 1 个名额；被拒绝的请求不扣减任何规则
+	mr.FastForward(time.Second)
+	res, err = l.AllowNDetail(ctx, 2)
+	assert.NoError(t, err)
+	assert.False(t, res.Allowed)
+	assert.Equal(t, "minute", res.DeniedBy)
+	assert.False(t, mr.Exists("cmp:{user:1}:second"))
+
+	ok, err := l.Allow(ctx)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	st, err := l.State(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, float64(3), st.Capacity)
+	assert.Equal(t, float64(0), st.Remaining)
+
+	assert.NoError(t, l.Reset(ctx))
+	assert.Empty(t, mr.Keys())
+
+	assert.Panics(t, func() {
+		NewCompositeLimiter(client, "dup", []CompositeRule{
+			{Name: "a", Window: time.Second, Limit: 1},
+			{Name: "a", Window: time.Minute, Limit: 1},
+		})
+	})
+}
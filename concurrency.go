@@ -0,0 +1,239 @@
+package limiter
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// ErrLeaseNotFound 表示租约不存在：已被释放，或已超过租约时长被回收。
+var ErrLeaseNotFound = errors.New("concurrency lease not found")
+
+// ConcurrencyLimiter 实现分布式并发数限制（信号量）。
+// 与速率型限流器不同，它限制的是“同时在途”的请求数：
+//   - Acquire 成功后返回一个租约 token，处理结束后必须调用 Release 归还
+//   - 每个租约都有 Lease 时长，持有者崩溃未归还时由脚本自动回收
+//   - 处理时间可能超过 Lease 的任务应定期调用 Extend 续期
+type ConcurrencyLimiter struct {
+	client *redis.Client
+
+	Key    string        // 业务 key
+	Prefix string        // Redis key 前缀，默认 "conc"
+	Limit  int64         // 最大并发数
+	Lease  time.Duration // 租约时长（最小精度为毫秒）
+
+	// WaitJitter Wait 重试前的随机抖动比例（0~1）
+	WaitJitter float64
+	// WaitStrategy Wait 被拒绝后的等待策略（可选），默认按 RetryAfter 等待并叠加 WaitJitter，见 WaitStrategy。
+	WaitStrategy WaitStrategy
+	// Wakeup Pub/Sub 唤醒器（可选）。设置后 Release / Reset 会唤醒本 key 上阻塞中的 Wait，见 Wakeup。
+	Wakeup *Wakeup
+
+	// ServerTime 为 true（默认）时脚本使用 Redis TIME 作为当前时间，
+	// 所有应用实例共享同一个时钟源；为 false 时使用本机时钟。
+	ServerTime bool
+
+	// Clock 时钟，默认 SystemClock。
+	Clock Clock
+
+	// Hooks 判定事件钩子（可选），每次放行 / 拒绝 / 出错时回调，见 Hooks。
+	Hooks Hooks
+}
+
+// NewConcurrencyLimiter 创建一个并发数限制器。
+func NewConcurrencyLimiter(
+	client *redis.Client,
+	key string,
+	opts ...ConcurrencyOption,
+) *ConcurrencyLimiter {
+
+	if client == nil {
+		panic("concurrency: redis client is nil")
+	}
+	if key == "" {
+		panic("concurrency: key is empty")
+	}
+
+	l := &ConcurrencyLimiter{
+		client:     client,
+		Key:        key,
+		Prefix:     "conc",
+		Limit:      10,
+		Lease:      30 * time.Second,
+		ServerTime: true,
+		Clock:      SystemClock,
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// leasesKey 返回保存在途租约的 ZSET key。
+func (l *ConcurrencyLimiter) leasesKey() string {
+	return fmt.Sprintf("%s:{%s}:leases", l.Prefix, l.Key)
+}
+
+// newLeaseToken 生成一个随机的租约 token。
+func newLeaseToken() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b[:]), nil
+}
+
+// Acquire 尝试获取一个并发名额，成功时返回租约 token。
+// 名额已满时返回 *LimitExceededError（匹配 ErrLimiter）。
+func (l *ConcurrencyLimiter) Acquire(ctx context.Context) (string, error) {
+	token, res, err := l.AcquireWithResult(ctx)
+	if err != nil {
+		return "", err
+	}
+	if !res.Allowed {
+		return "", newLimitExceededError(l.Key, "concurrency", res, false)
+	}
+	return token, nil
+}
+
+// AcquireWithResult 尝试获取一个并发名额，并返回剩余名额及最早租约到期的时间。
+// 被拒绝时 token 为空。
+func (l *ConcurrencyLimiter) AcquireWithResult(ctx context.Context) (string, Result, error) {
+	token, res, err := l.acquire(ctx)
+	fireHooks(ctx, l.Hooks, "concurrency", l.Key, 1, res, err)
+	return token, res, err
+}
+
+// acquire 生成租约 token 并执行获取名额的脚本。
+func (l *ConcurrencyLimiter) acquire(ctx context.Context) (string, Result, error) {
+	token, err := newLeaseToken()
+	if err != nil {
+		return "", Result{}, err
+	}
+
+	res, err := concurrencyAcquireScript.Run(
+		ctx,
+		l.client,
+		[]string{l.leasesKey()},
+		scriptNow(l.ServerTime, l.Clock),
+		l.Lease.Milliseconds(),
+		l.Limit,
+		token,
+	).Result()
+	if err != nil {
+		return "", Result{}, err
+	}
+
+	vals, ok := scriptInts(res, 3)
+	if !ok {
+		return "", Result{}, fmt.Errorf("concurrency: unexpected script result: %#v", res)
+	}
+	result := Result{
+		Allowed:    vals[0] == 1,
+		Limit:      float64(l.Limit),
+		Remaining:  float64(vals[1]),
+		RetryAfter: time.Duration(vals[2]) * time.Millisecond,
+	}
+	if !result.Allowed {
+		return "", result, nil
+	}
+	return token, result, nil
+}
+
+// Wait 阻塞直到获取一个并发名额，或 ctx 取消 / 超过 maxWait。
+// 名额被占满时按最早租约的到期时间等待；租约被提前 Release 时只有配置了 Wakeup 才会立即唤醒等待者。
+func (l *ConcurrencyLimiter) Wait(ctx context.Context, maxWait time.Duration) (string, error) {
+
+	var token string
+	err := waitForWake(ctx, l.Key, "concurrency", maxWait, waitStrategy(l.WaitStrategy, l.WaitJitter), l.Wakeup, func(ctx context.Context) (Result, error) {
+		t, res, err := l.AcquireWithResult(ctx)
+		token = t
+		return res, err
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// Release 归还租约。租约已过期被回收或重复释放时返回 ErrLeaseNotFound。
+func (l *ConcurrencyLimiter) Release(ctx context.Context, token string) error {
+	n, err := l.client.ZRem(ctx, l.leasesKey(), token).Result()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrLeaseNotFound
+	}
+	l.Wakeup.notify(ctx, l.Key)
+	return nil
+}
+
+// Extend 将仍然有效的租约从当前时刻起续期一个 Lease 时长。
+// 租约已过期或不存在时返回 ErrLeaseNotFound，调用方应视为已失去名额。
+func (l *ConcurrencyLimiter) Extend(ctx context.Context, token string) error {
+	n, err := concurrencyExtendScript.Run(
+		ctx,
+		l.client,
+		[]string{l.leasesKey()},
+		scriptNow(l.ServerTime, l.Clock),
+		l.Lease.Milliseconds(),
+		token,
+	).Int64()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrLeaseNotFound
+	}
+	return nil
+}
+
+// Reset 删除全部租约。仍在处理中的持有者随后调用 Release 会得到 ErrLeaseNotFound。
+func (l *ConcurrencyLimiter) Reset(ctx context.Context) error {
+	if err := l.client.Del(ctx, l.leasesKey()).Err(); err != nil {
+		return err
+	}
+	l.Wakeup.notify(ctx, l.Key)
+	return nil
+}
+
+// State 返回当前在途（未过期）的租约数。与 Acquire 使用同一个时钟源（ServerTime 时为 Redis TIME），
+// 避免本机时钟偏差导致统计的在途数量与脚本判定不一致。
+func (l *ConcurrencyLimiter) State(ctx context.Context) (LimiterState, error) {
+	res, err := concurrencyStateScript.Run(
+		ctx,
+		l.client,
+		[]string{l.leasesKey()},
+		scriptNow(l.ServerTime, l.Clock),
+	).Result()
+	if err != nil {
+		return LimiterState{}, err
+	}
+
+	vals, ok := scriptInts(res, 3)
+	if !ok {
+		return LimiterState{}, fmt.Errorf("concurrency: unexpected script result: %#v", res)
+	}
+	used, now := vals[0], vals[2]
+
+	next := now
+	if used >= l.Limit && vals[1] > 0 {
+		next = vals[1]
+	}
+
+	return LimiterState{
+		Level:             float64(used),
+		Remaining:         float64(max(l.Limit-used, 0)),
+		Capacity:          float64(l.Limit),
+		LastUpdated:       now,
+		NextAvailableTime: next,
+		Type:              "concurrency",
+		Key:               l.Key,
+	}, nil
+}
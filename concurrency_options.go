@@ -0,0 +1,86 @@
+package limiter
+
+import "time"
+
+// ConcurrencyOption 为并发数限制器的配置项。
+type ConcurrencyOption func(*ConcurrencyLimiter)
+
+// WithConcurrencyLimit 设置最大并发数。
+func WithConcurrencyLimit(limit int64) ConcurrencyOption {
+	return func(l *ConcurrencyLimiter) {
+		if limit > 0 {
+			l.Limit = limit
+		}
+	}
+}
+
+// WithConcurrencyLease 设置租约时长（最小精度为毫秒）。
+// 应大于单次处理的正常耗时，否则租约会在处理过程中被回收。
+func WithConcurrencyLease(d time.Duration) ConcurrencyOption {
+	return func(l *ConcurrencyLimiter) {
+		if d >= time.Millisecond {
+			l.Lease = d
+		}
+	}
+}
+
+// WithConcurrencyWaitJitter 设置 Wait 重试前的随机抖动比例，取值范围 [0, 1]。
+func WithConcurrencyWaitJitter(ratio float64) ConcurrencyOption {
+	return func(l *ConcurrencyLimiter) {
+		if ratio < 0 || ratio > 1 {
+			panic("concurrency: wait jitter must be in [0, 1]")
+		}
+		l.WaitJitter = ratio
+	}
+}
+
+// WithConcurrencyWaitStrategy 设置 Wait 被拒绝后的等待策略，例如 ExponentialWait、NewPacedWait。
+// 设置后 WaitJitter 不再生效，抖动由策略自身负责。
+func WithConcurrencyWaitStrategy(s WaitStrategy) ConcurrencyOption {
+	return func(l *ConcurrencyLimiter) {
+		l.WaitStrategy = s
+	}
+}
+
+// WithConcurrencyWakeup 设置 Pub/Sub 唤醒器：Release / Reset 释放额度后立即唤醒等待中的 Wait，
+// 不必等到 RetryAfter。多个限流器可以共享同一个 Wakeup。
+func WithConcurrencyWakeup(w *Wakeup) ConcurrencyOption {
+	return func(l *ConcurrencyLimiter) {
+		l.Wakeup = w
+	}
+}
+
+// WithConcurrencyServerTime 设置是否使用 Redis TIME 作为脚本中的当前时间，默认开启。
+// 关闭后使用本机时钟，各应用实例的时钟偏差会直接影响 refill 与窗口边界。
+func WithConcurrencyServerTime(enabled bool) ConcurrencyOption {
+	return func(l *ConcurrencyLimiter) {
+		l.ServerTime = enabled
+	}
+}
+
+// WithConcurrencyClock 设置时钟，通常用于测试中控制时间。
+// 注入时钟后脚本改用该时钟传入的时间（相当于 WithConcurrencyServerTime(false)）。
+func WithConcurrencyClock(c Clock) ConcurrencyOption {
+	return func(l *ConcurrencyLimiter) {
+		if c != nil {
+			l.Clock = c
+			l.ServerTime = false
+		}
+	}
+}
+
+// WithConcurrencyPrefix 设置 Redis key 前缀。
+func WithConcurrencyPrefix(prefix string) ConcurrencyOption {
+	return func(l *ConcurrencyLimiter) {
+		if prefix != "" {
+			l.Prefix = prefix
+		}
+	}
+}
+
+// WithConcurrencyHooks 设置判定事件钩子，用于接入日志、指标或告警。
+func WithConcurrencyHooks(h Hooks) ConcurrencyOption {
+	return func(l *ConcurrencyLimiter) {
+		l.Hooks = h
+	}
+}
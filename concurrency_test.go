@@ -0,0 +1,79 @@
+package limiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConcurrencyLimiter(t *testing.T) {
+	client := newSpecClient(t)
+	ctx := context.Background()
+
+	l := NewConcurrencyLimiter(client, "export",
+		WithConcurrencyLimit(2),
+		WithConcurrencyLease(50*time.Millisecond),
+	)
+
+	t1, err := l.Acquire(ctx)
+	assert.NoError(t, err)
+	t2, err := l.Acquire(ctx)
+	assert.NoError(t, err)
+	assert.NotEqual(t, t1, t2)
+
+	_, res, err := l.AcquireWithResult(ctx)
+	assert.NoError(t, err)
+	assert.False(t, res.Allowed)
+	assert.Greater(t, res.RetryAfter, time.Duration(0))
+
+	st, err := l.State(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, float64(2), st.Level)
+
+	// 归还后名额立即可用，重复归还报错
+	assert.NoError(t, l.Release(ctx, t1))
+	assert.ErrorIs(t, l.Release(ctx, t1), ErrLeaseNotFound)
+	t3, err := l.Acquire(ctx)
+	assert.NoError(t, err)
+
+	// 未归还的租约到期后被回收
+	assert.NoError(t, l.Extend(ctx, t3))
+	token, err := l.Wait(ctx, time.Second)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, token)
+	assert.ErrorIs(t, l.Extend(ctx, t2), ErrLeaseNotFound)
+
+	assert.NoError(t, l.Reset(ctx))
+	assert.ErrorIs(t, l.Release(ctx, token), ErrLeaseNotFound)
+}
+
+func TestConcurrencyLimiter_StateServerTime(t *testing.T) {
+	client := newSpecClient(t)
+	ctx := context.Background()
+
+	// 本机时钟比 Redis 慢 1 小时：State 与 Acquire 都使用 Redis TIME，在途数量一致
+	skewed := ClockFunc(func() time.Time { return time.Now().Add(-time.Hour) })
+	l := NewConcurrencyLimiter(client, "export",
+		WithConcurrencyLimit(2),
+		WithConcurrencyLease(time.Minute),
+		WithConcurrencyClock(skewed),
+		WithConcurrencyServerTime(true),
+	)
+	_, err := l.Acquire(ctx)
+	assert.NoError(t, err)
+	_, err = l.Acquire(ctx)
+	assert.NoError(t, err)
+
+	// 模拟持有者崩溃：一个租约已在 Redis 时间上过期，但按本机时钟仍“未到期”
+	assert.NoError(t, client.ZAdd(ctx, l.leasesKey(), &redis.Z{Score: float64(time.Now().Add(-time.Second).UnixMilli()), Member: "stale"}).Err())
+
+	st, err := l.State(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, float64(2), st.Level)
+	assert.Equal(t, float64(0), st.Remaining)
+	assert.InDelta(t, time.Now().Add(time.Minute).UnixMilli(), st.NextAvailableTime, 1000)
+	assert.InDelta(t, time.Now().UnixMilli(), st.LastUpdated, 1000)
+}
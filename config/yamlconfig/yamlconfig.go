@@ -0,0 +1,33 @@
+// Package yamlconfig 从 YAML 文件加载限流规则（limiter.LimitRule），
+// YAML 依赖只在引入本包时才会进入二进制，根包保持只依赖 go-redis。
+package yamlconfig
+
+import (
+	"os"
+
+	"gopkg.in/yaml.v3"
+
+	limiter "github.com/lifei6671/go-redis-limiter"
+)
+
+// ParseRules 解析 YAML 格式的规则文件内容，顶层可以是规则数组，也可以是带 rules 字段的对象：
+//
+//	rules:
+//	  - name: api:/v1/chat
+//	    rate: 100
+//	    capacity: 200
+//	    shards: 8
+//
+// JSON 是 YAML 的子集，因此 JSON 规则文件同样可以解析。
+func ParseRules(data []byte) ([]limiter.LimitRule, error) {
+	return limiter.ParseRulesWith(data, yaml.Unmarshal)
+}
+
+// LoadRules 读取并解析 YAML 规则文件，格式见 ParseRules。
+func LoadRules(path string) ([]limiter.LimitRule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseRules(data)
+}
@@ -0,0 +1,49 @@
+package yamlconfig
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	limiter "github.com/lifei6671/go-redis-limiter"
+)
+
+func TestParseRules(t *testing.T) {
+	data := []byte(`
+rules:
+  - name: chat
+    rate: 100
+    capacity: 200
+    shards: 4
+  - name: sms
+    algorithm: sliding_window
+    prefix: sms
+    limit: 5
+    window_ms: 60000
+    params:
+      per_second: 2
+`)
+	rules, err := ParseRules(data)
+	assert.NoError(t, err)
+	assert.Equal(t, []limiter.LimitRule{
+		{LimitConfig: limiter.LimitConfig{Name: "chat", Rate: 100, Capacity: 200}, Shards: 4},
+		{LimitConfig: limiter.LimitConfig{Name: "sms", Limit: 5, WindowMs: 60000}, Algorithm: limiter.AlgorithmSlidingWindow,
+			Prefix: "sms", Params: map[string]string{"per_second": "2"}},
+	}, rules)
+
+	// 顶层数组同样支持
+	rules, err = ParseRules([]byte("- name: export\n  limit: 5\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, []limiter.LimitRule{{LimitConfig: limiter.LimitConfig{Name: "export", Limit: 5}}}, rules)
+
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	assert.NoError(t, os.WriteFile(path, data, 0o644))
+	rules, err = LoadRules(path)
+	assert.NoError(t, err)
+	assert.Len(t, rules, 2)
+
+	_, err = ParseRules([]byte("rules: [1, 2"))
+	assert.Error(t, err)
+}
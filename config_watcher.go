@@ -0,0 +1,214 @@
+package limiter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// LimitConfig 是一条限流参数定义，通过 Redis pub/sub 以 JSON 形式下发。
+// 零值字段表示不修改。
+//
+//	{"name": "api:/v1/chat", "rate": 200, "capacity": 400}
+//	[{"name": "sms", "limit": 5, "window_ms": 60000}, ...]
+type LimitConfig struct {
+	// Name 限流器注册名
+	Name string `json:"name" yaml:"name"`
+	// Rate 速率（令牌桶为 token/sec，漏桶为泄漏速率）
+	Rate float64 `json:"rate,omitempty" yaml:"rate,omitempty"`
+	// Capacity 容量（令牌桶/漏桶）
+	Capacity float64 `json:"capacity,omitempty" yaml:"capacity,omitempty"`
+	// Limit 窗口内最大请求数（滑动窗口）
+	Limit int64 `json:"limit,omitempty" yaml:"limit,omitempty"`
+	// WindowMs 窗口大小，毫秒（滑动窗口）
+	WindowMs int64 `json:"window_ms,omitempty" yaml:"window_ms,omitempty"`
+}
+
+// Reconfigurable 是支持运行时整体替换参数的限流器。
+// Reconfigure 在一次加锁内应用 LimitConfig 中的全部非零字段，判定不会看到“只改了一半”的参数。
+type Reconfigurable interface {
+	Reconfigure(c LimitConfig) error
+}
+
+// ConfigWatcher 订阅 Redis 频道，收到 JSON 限流定义后原子地替换已注册限流器的参数，
+// 修改限额时无需重启所有服务实例。
+type ConfigWatcher struct {
+	client  *redis.Client
+	Channel string
+
+	// OnError 处理消息解析或参数应用失败（可选），默认忽略
+	OnError func(err error)
+
+	// Registry 未通过 Register 注册的 name 会在其中查找（可选），见 WithConfigWatcherRegistry
+	Registry *Registry
+
+	mu       sync.RWMutex
+	limiters map[string]Reconfigurable
+}
+
+// NewConfigWatcher 创建一个订阅 channel 的配置监听器，调用 Run 后开始生效。
+func NewConfigWatcher(client *redis.Client, channel string, opts ...ConfigWatcherOption) *ConfigWatcher {
+	if client == nil {
+		panic("config watcher: redis client is nil")
+	}
+	if channel == "" {
+		panic("config watcher: channel is empty")
+	}
+
+	w := &ConfigWatcher{
+		client:   client,
+		Channel:  channel,
+		limiters: make(map[string]Reconfigurable),
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	return w
+}
+
+// Register 以 name 注册一个限流器，同名注册会覆盖之前的限流器。
+func (w *ConfigWatcher) Register(name string, l Reconfigurable) {
+	if l == nil {
+		panic("config watcher: limiter is nil")
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.limiters[name] = l
+}
+
+// Unregister 取消注册。
+func (w *ConfigWatcher) Unregister(name string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	delete(w.limiters, name)
+}
+
+// Apply 把配置应用到已注册的限流器上（先查 Register 注册的，再查 Registry）。未注册的 name 会被忽略（可能属于其他服务）。
+// 也可以在启动时调用 Apply 加载初始配置。
+func (w *ConfigWatcher) Apply(cfgs ...LimitConfig) error {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	for _, c := range cfgs {
+		l, ok := w.limiters[c.Name]
+		if !ok {
+			l, ok = w.lookup(c.Name)
+		}
+		if !ok {
+			continue
+		}
+		if err := l.Reconfigure(c); err != nil {
+			return fmt.Errorf("config watcher: apply %q: %w", c.Name, err)
+		}
+	}
+	return nil
+}
+
+// lookup 在 Registry 中查找支持热更新的限流器。
+func (w *ConfigWatcher) lookup(name string) (Reconfigurable, bool) {
+	if w.Registry == nil {
+		return nil, false
+	}
+	e, ok := w.Registry.Lookup(name)
+	if !ok {
+		return nil, false
+	}
+	l, ok := e.limiter().(Reconfigurable)
+	return l, ok
+}
+
+// Publish 向频道发布配置，所有运行中的 ConfigWatcher 都会收到。
+func (w *ConfigWatcher) Publish(ctx context.Context, cfgs ...LimitConfig) error {
+	data, err := json.Marshal(cfgs)
+	if err != nil {
+		return err
+	}
+	return w.client.Publish(ctx, w.Channel, data).Err()
+}
+
+// Run 订阅频道并持续应用收到的配置，直到 ctx 取消。订阅失败时返回错误。
+func (w *ConfigWatcher) Run(ctx context.Context) error {
+	sub := w.client.Subscribe(ctx, w.Channel)
+	defer sub.Close()
+
+	// 等待订阅确认，保证 Run 返回 nil 之前不会漏掉配置
+	if _, err := sub.Receive(ctx); err != nil {
+		return err
+	}
+
+	ch := sub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case msg, ok := <-ch:
+			if !ok {
+				return nil
+			}
+			w.handle(msg.Payload)
+		}
+	}
+}
+
+// handle 解析一条消息（单个对象或数组）并应用。
+func (w *ConfigWatcher) handle(payload string) {
+	cfgs, err := ParseLimitConfigs([]byte(payload))
+	if err == nil {
+		err = w.Apply(cfgs...)
+	} else {
+		err = fmt.Errorf("config watcher: invalid message: %w", err)
+	}
+	if err != nil && w.OnError != nil {
+		w.OnError(err)
+	}
+}
+
+// ParseLimitConfigs 解析单个 JSON 对象或 JSON 数组形式的配置，格式与 ConfigWatcher 的消息相同。
+func ParseLimitConfigs(data []byte) ([]LimitConfig, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '[' {
+		var cfgs []LimitConfig
+		if err := json.Unmarshal(data, &cfgs); err != nil {
+			return nil, err
+		}
+		return cfgs, nil
+	}
+	var c LimitConfig
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, err
+	}
+	return []LimitConfig{c}, nil
+}
+
+// ConfigSource 是 Redis pub/sub 之外的限流参数来源，例如 etcd、Consul（见 etcdsource、consulsource 子包）。
+type ConfigSource interface {
+	// Watch 先推送一次当前的全部配置，之后每当配置变化时推送变化的部分，直到 ctx 取消（返回 nil）或出错。
+	Watch(ctx context.Context, push func(cfgs []LimitConfig)) error
+}
+
+// RunSource 从 src 持续接收配置并应用到已注册的限流器，直到 ctx 取消。可以与 Run 同时使用。
+func (w *ConfigWatcher) RunSource(ctx context.Context, src ConfigSource) error {
+	return src.Watch(ctx, func(cfgs []LimitConfig) {
+		if err := w.Apply(cfgs...); err != nil && w.OnError != nil {
+			w.OnError(err)
+		}
+	})
+}
+
+// window 返回配置中的窗口大小，未设置时为 0。
+func (c LimitConfig) window() time.Duration {
+	return time.Duration(c.WindowMs) * time.Millisecond
+}
+
+// validate 检查配置中是否有负数。
+func (c LimitConfig) validate() error {
+	if c.Rate < 0 || c.Capacity < 0 || c.Limit < 0 || c.WindowMs < 0 {
+		return fmt.Errorf("limit config: values must >= 0")
+	}
+	return nil
+}
@@ -0,0 +1,18 @@
+package limiter
+
+// ConfigWatcherOption 为配置监听器的配置项。
+type ConfigWatcherOption func(*ConfigWatcher)
+
+// WithConfigWatcherErrorHandler 设置消息解析或参数应用失败时的回调，例如记录日志。
+func WithConfigWatcherErrorHandler(fn func(err error)) ConfigWatcherOption {
+	return func(w *ConfigWatcher) {
+		w.OnError = fn
+	}
+}
+
+// WithConfigWatcherRegistry 让配置也应用到 r 中同名且支持热更新的限流器，无需再逐个 Register。
+func WithConfigWatcherRegistry(r *Registry) ConfigWatcherOption {
+	return func(w *ConfigWatcher) {
+		w.Registry = r
+	}
+}
@@ -0,0 +1,88 @@
+package limiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfigWatcher(t *testing.T) {
+	client := newSpecClient(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	tb := NewTokenBucketLimiter(client, "chat")
+	sw := NewSlidingWindowLimiter(client, "sms")
+
+	var errs []error
+	w := NewConfigWatcher(client, "limits:config", WithConfigWatcherErrorHandler(func(err error) {
+		errs = append(errs, err)
+	}))
+	w.Register("chat", tb)
+	w.Register("sms", sw)
+
+	done := make(chan error, 1)
+	go func() { done <- w.Run(ctx) }()
+
+	// 订阅建立前发布的消息会丢失，因此重复发布直到生效
+	assert.Eventually(t, func() bool {
+		_ = w.Publish(ctx,
+			LimitConfig{Name: "chat", Rate: 200, Capacity: 400},
+			LimitConfig{Name: "sms", Limit: 5, WindowMs: 60_000},
+			LimitConfig{Name: "unknown", Rate: 1},
+		)
+		rate, capacity := tb.limits()
+		limit, window, _ := sw.limits()
+		return rate == 200 && capacity == 400 && limit == 5 && window == time.Minute
+	}, 2*time.Second, 10*time.Millisecond)
+
+	// 单个对象同样支持；只修改出现的字段
+	assert.NoError(t, client.Publish(ctx, "limits:config", `{"name":"chat","rate":50}`).Err())
+	assert.Eventually(t, func() bool {
+		rate, capacity := tb.limits()
+		return rate == 50 && capacity == 400
+	}, time.Second, 10*time.Millisecond)
+
+	assert.Error(t, w.Apply(LimitConfig{Name: "chat", Rate: -1}))
+
+	cancel()
+	assert.NoError(t, <-done)
+	assert.Empty(t, errs)
+}
+
+// sourceFunc 把函数适配为 ConfigSource。
+type sourceFunc func(ctx context.Context, push func([]LimitConfig)) error
+
+func (f sourceFunc) Watch(ctx context.Context, push func([]LimitConfig)) error {
+	return f(ctx, push)
+}
+
+func TestConfigWatcher_RunSource(t *testing.T) {
+	client := newSpecClient(t)
+	tb := NewTokenBucketLimiter(client, "chat")
+
+	var errs []error
+	w := NewConfigWatcher(client, "limits:config", WithConfigWatcherErrorHandler(func(err error) {
+		errs = append(errs, err)
+	}))
+	w.Register("chat", tb)
+
+	err := w.RunSource(context.Background(), sourceFunc(func(_ context.Context, push func([]LimitConfig)) error {
+		push([]LimitConfig{{Name: "chat", Rate: 200, Capacity: 400}})
+		push([]LimitConfig{{Name: "chat", Rate: -1}})
+		return nil
+	}))
+	assert.NoError(t, err)
+	rate, capacity := tb.limits()
+	assert.Equal(t, 200.0, rate)
+	assert.Equal(t, 400.0, capacity)
+	assert.Len(t, errs, 1)
+
+	cfgs, err := ParseLimitConfigs([]byte(` [{"name": "a"}, {"name": "b", "limit": 1}] `))
+	assert.NoError(t, err)
+	assert.Equal(t, []LimitConfig{{Name: "a"}, {Name: "b", Limit: 1}}, cfgs)
+	_, err = ParseLimitConfigs([]byte(`{`))
+	assert.Error(t, err)
+}
@@ -0,0 +1,127 @@
+// Package consulsource 从 Consul KV 读取并监听限流参数，实现 limiter.ConfigSource。
+package consulsource
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+
+	limiter "github.com/lifei6671/go-redis-limiter"
+)
+
+// Source 通过阻塞查询监听 Consul KV 中 Prefix 下的所有 key，每个 key 的值为一条或一组 JSON 格式的 LimitConfig：
+//
+//	limits/sms      -> {"limit": 5, "window_ms": 60000}
+//	limits/chat     -> {"name": "api:/v1/chat", "rate": 200, "capacity": 400}
+//
+// 值中未设置 name 时使用去掉 Prefix 后的 key 作为 name。删除 key 不会撤销已经生效的配置。
+//
+//	w := limiter.NewConfigWatcher(rdb, "limiter:config")
+//	w.Register("sms", smsLimiter)
+//	go w.RunSource(ctx, consulsource.New(consulClient, "limits/"))
+type Source struct {
+	client *api.Client
+
+	// Prefix 监听的 key 前缀
+	Prefix string
+
+	// WaitTime 每次阻塞查询的最长等待时间，默认 5m
+	WaitTime time.Duration
+
+	// OnError 单个 key 的值解析失败时的回调（可选），该 key 会被忽略
+	OnError func(err error)
+}
+
+// Option 为 Consul 配置源的配置项。
+type Option func(*Source)
+
+// WithWaitTime 设置每次阻塞查询的最长等待时间，默认 5m。
+func WithWaitTime(d time.Duration) Option {
+	return func(s *Source) {
+		if d > 0 {
+			s.WaitTime = d
+		}
+	}
+}
+
+// WithErrorHandler 设置 key 的值解析失败时的回调，例如记录日志。
+func WithErrorHandler(fn func(err error)) Option {
+	return func(s *Source) {
+		s.OnError = fn
+	}
+}
+
+// New 创建监听 prefix 的 Consul 配置源。
+func New(client *api.Client, prefix string, opts ...Option) *Source {
+	if client == nil {
+		panic("consulsource: client is nil")
+	}
+	if prefix == "" {
+		panic("consulsource: prefix is empty")
+	}
+
+	s := &Source{client: client, Prefix: prefix, WaitTime: 5 * time.Minute}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Watch 实现 limiter.ConfigSource：第一次查询推送 Prefix 下的全部配置，之后每次阻塞查询返回时
+// 只推送 ModifyIndex 大于上次索引的 key。查询出错时返回该错误，调用方可以重新 Watch。
+func (s *Source) Watch(ctx context.Context, push func(cfgs []limiter.LimitConfig)) error {
+	var index uint64
+	first := true
+	for {
+		opts := (&api.QueryOptions{WaitIndex: index, WaitTime: s.WaitTime}).WithContext(ctx)
+		pairs, meta, err := s.client.KV().List(s.Prefix, opts)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		if !first && meta.LastIndex == index {
+			// 等待超时，没有变化
+			continue
+		}
+
+		var cfgs []limiter.LimitConfig
+		for _, p := range pairs {
+			if first || p.ModifyIndex > index {
+				cfgs = append(cfgs, s.parse(p.Key, p.Value)...)
+			}
+		}
+		if first || len(cfgs) > 0 {
+			push(cfgs)
+		}
+		first = false
+
+		// 索引回退（例如 Consul 集群重建）时重新从头开始阻塞查询
+		if meta.LastIndex < index {
+			index = 0
+		} else {
+			index = meta.LastIndex
+		}
+	}
+}
+
+// parse 解析一个 key 的值，未设置 name 的配置使用 key 去掉前缀后的部分。
+func (s *Source) parse(key string, value []byte) []limiter.LimitConfig {
+	cfgs, err := limiter.ParseLimitConfigs(value)
+	if err != nil {
+		if s.OnError != nil {
+			s.OnError(fmt.Errorf("consulsource: invalid value of %q: %w", key, err))
+		}
+		return nil
+	}
+	for i := range cfgs {
+		if cfgs[i].Name == "" {
+			cfgs[i].Name = strings.TrimPrefix(key, s.Prefix)
+		}
+	}
+	return cfgs
+}
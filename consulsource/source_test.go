@@ -0,0 +1,69 @@
+package consulsource
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/stretchr/testify/assert"
+
+	limiter "github.com/lifei6671/go-redis-limiter"
+)
+
+// newFakeConsul 按请求的 index 依次返回 states 中的 KV 快照，index 超出后阻塞到请求取消。
+func newFakeConsul(t *testing.T, states [][]*api.KVPair) *api.Client {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/kv/limits/", r.URL.Path)
+		index, _ := strconv.Atoi(r.URL.Query().Get("index"))
+		if index >= len(states) {
+			<-r.Context().Done()
+			return
+		}
+		w.Header().Set("X-Consul-Index", strconv.Itoa(index+1))
+		_ = json.NewEncoder(w).Encode(states[index])
+	}))
+	t.Cleanup(srv.Close)
+
+	client, err := api.NewClient(&api.Config{Address: strings.TrimPrefix(srv.URL, "http://")})
+	assert.NoError(t, err)
+	return client
+}
+
+func TestSource(t *testing.T) {
+	sms := &api.KVPair{Key: "limits/sms", Value: []byte(`{"limit": 5, "window_ms": 60000}`), ModifyIndex: 1}
+	chat := &api.KVPair{Key: "limits/chat", Value: []byte(`{"name": "api:/v1/chat", "rate": 200}`), ModifyIndex: 1}
+	client := newFakeConsul(t, [][]*api.KVPair{
+		{sms, chat},
+		{{Key: "limits/sms", Value: []byte(`{"limit": 10}`), ModifyIndex: 2}, chat},
+	})
+	s := New(client, "limits/", WithWaitTime(time.Second))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	pushed := make(chan []limiter.LimitConfig, 4)
+	done := make(chan error, 1)
+	go func() {
+		done <- s.Watch(ctx, func(cfgs []limiter.LimitConfig) { pushed <- cfgs })
+	}()
+
+	assert.Equal(t, []limiter.LimitConfig{
+		{Name: "sms", Limit: 5, WindowMs: 60000},
+		{Name: "api:/v1/chat", Rate: 200},
+	}, <-pushed)
+	// 之后只推送发生变化的 key
+	assert.Equal(t, []limiter.LimitConfig{{Name: "sms", Limit: 10}}, <-pushed)
+
+	cancel()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("Watch did not return after cancel")
+	}
+}
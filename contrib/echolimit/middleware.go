@@ -0,0 +1,24 @@
+// Package echolimit 为 Echo 提供限流中间件。
+//
+// 判定逻辑、429 响应与 RateLimit-* 头均复用 httplimit，KeyFunc 与 Option 也直接使用 httplimit 中的定义。
+package echolimit
+
+import (
+	"github.com/labstack/echo/v4"
+
+	limiter "github.com/lifei6671/go-redis-limiter"
+	"github.com/lifei6671/go-redis-limiter/httplimit"
+)
+
+// Middleware 返回 Echo 中间件，参数与行为同 httplimit.Middleware：
+// 被限流时直接写出 429 响应，不再调用后续 handler。
+//
+//	e.Use(echolimit.Middleware(l, httplimit.KeyByIP))
+func Middleware(l limiter.RateShardedLimiter, keyFunc httplimit.KeyFunc, opts ...httplimit.Option) echo.MiddlewareFunc {
+	return echo.WrapMiddleware(httplimit.Middleware(l, keyFunc, opts...))
+}
+
+// ClassifierMiddleware 与 Middleware 相同，但由 limiter.Classifier 决定 key 与消耗，见 httplimit.ClassifierMiddleware。
+func ClassifierMiddleware(l limiter.RateShardedLimiter, c limiter.Classifier, opts ...httplimit.Option) echo.MiddlewareFunc {
+	return echo.WrapMiddleware(httplimit.ClassifierMiddleware(l, c, opts...))
+}
@@ -0,0 +1,131 @@
+// Package fiberlimit 为 Fiber 提供限流中间件。
+//
+// Fiber 基于 fasthttp，无法直接复用 net/http 中间件；这里复用 httplimit 的判定（httplimit.Check）
+// 与响应头（httplimit.SetResultHeaders），429 响应的格式与 httplimit 保持一致。
+package fiberlimit
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	limiter "github.com/lifei6671/go-redis-limiter"
+	"github.com/lifei6671/go-redis-limiter/httplimit"
+)
+
+// KeyFunc 从请求中提取限流 key，返回空字符串表示该请求不限流。
+type KeyFunc func(c *fiber.Ctx) string
+
+// KeyByIP 使用客户端 IP 作为 key。部署在反向代理之后时，请配置 fiber.Config.ProxyHeader 或改用 KeyByHeader。
+func KeyByIP(c *fiber.Ctx) string {
+	return c.IP()
+}
+
+// KeyByHeader 使用指定请求头的值作为 key，例如 API Key、租户 ID。
+func KeyByHeader(name string) KeyFunc {
+	return func(c *fiber.Ctx) string {
+		return c.Get(name)
+	}
+}
+
+// KeyByRoute 使用请求方法与路由模板（例如 "GET /users/:id"）作为 key。
+func KeyByRoute(c *fiber.Ctx) string {
+	return c.Method() + " " + c.Route().Path
+}
+
+// Option 为 Fiber 中间件的配置项。
+type Option func(*config)
+
+type config struct {
+	onError  func(c *fiber.Ctx, err error) error
+	onDenied func(c *fiber.Ctx, res limiter.Result) error
+	headers  bool
+}
+
+// WithErrorHandler 设置限流器出错（例如 Redis 不可用）时的处理方式。
+// 默认 fail-open：调用 c.Next() 放行请求。如需 fail-close，可以在 handler 中返回 503。
+func WithErrorHandler(fn func(c *fiber.Ctx, err error) error) Option {
+	return func(cfg *config) {
+		if fn != nil {
+			cfg.onError = fn
+		}
+	}
+}
+
+// WithDeniedHandler 设置被限流时的响应方式，默认返回 429 和 Retry-After 头。
+func WithDeniedHandler(fn func(c *fiber.Ctx, res limiter.Result) error) Option {
+	return func(cfg *config) {
+		if fn != nil {
+			cfg.onDenied = fn
+		}
+	}
+}
+
+// WithRateLimitHeaders 设置是否在响应中写出 RateLimit-* 与 X-RateLimit-* 头，默认开启。
+func WithRateLimitHeaders(enabled bool) Option {
+	return func(cfg *config) {
+		cfg.headers = enabled
+	}
+}
+
+// Middleware 返回 Fiber 中间件，行为同 httplimit.Middleware：
+//
+//   - 使用 keyFunc 从请求中提取 key，key 为空时不限流；
+//
+//   - 被限流时返回 429 Too Many Requests 与 Retry-After 头；
+//
+//   - 无论是否放行，都会写出 RateLimit-* / X-RateLimit-* 头。
+//
+//     app.Use(fiberlimit.Middleware(l, fiberlimit.KeyByIP))
+func Middleware(l limiter.RateShardedLimiter, keyFunc KeyFunc, opts ...Option) fiber.Handler {
+	if l == nil {
+		panic("fiberlimit: limiter is nil")
+	}
+	if keyFunc == nil {
+		panic("fiberlimit: key func is nil")
+	}
+
+	cfg := &config{
+		onError: func(c *fiber.Ctx, _ error) error {
+			return c.Next()
+		},
+		onDenied: func(c *fiber.Ctx, res limiter.Result) error {
+			return WriteTooManyRequests(c, res.RetryAfter)
+		},
+		headers: true,
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	return func(c *fiber.Ctx) error {
+		key := keyFunc(c)
+		if key == "" {
+			return c.Next()
+		}
+
+		res, err := httplimit.Check(c.UserContext(), l, key, 1)
+		if err != nil {
+			return cfg.onError(c, err)
+		}
+		if cfg.headers {
+			h := make(http.Header)
+			httplimit.SetResultHeaders(h, res)
+			for k := range h {
+				c.Set(k, h.Get(k))
+			}
+		}
+		if !res.Allowed {
+			return cfg.onDenied(c, res)
+		}
+		return c.Next()
+	}
+}
+
+// WriteTooManyRequests 写出 429 响应，并设置 Retry-After 头（秒，见 httplimit.RetryAfterSeconds）。
+func WriteTooManyRequests(c *fiber.Ctx, retryAfter time.Duration) error {
+	c.Set(fiber.HeaderRetryAfter, strconv.FormatInt(httplimit.RetryAfterSeconds(retryAfter), 10))
+	return c.Status(fiber.StatusTooManyRequests).SendString(http.StatusText(http.StatusTooManyRequests))
+}
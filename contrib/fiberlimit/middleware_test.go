@@ -0,0 +1,65 @@
+package fiberlimit
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+
+	limiter "github.com/lifei6671/go-redis-limiter"
+	"github.com/lifei6671/go-redis-limiter/httplimit"
+)
+
+func TestMiddleware(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	l := limiter.NewShardedTokenBucketLimiter(client, "api", limiter.WithShardedTokenBucketCount(1),
+		limiter.WithShardedTokenBucket(limiter.WithTokenBucketRate(0.001), limiter.WithTokenBucketCapacity(1)))
+
+	app := fiber.New()
+	app.Use(Middleware(l, KeyByHeader("X-Api-Key")))
+	calls := 0
+	app.Get("/", func(c *fiber.Ctx) error {
+		calls++
+		return c.SendString("ok")
+	})
+
+	do := func(key string) *http.Response {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-Api-Key", key)
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		return resp
+	}
+
+	resp := do("a")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "1", resp.Header.Get(httplimit.HeaderLimit))
+	assert.Equal(t, "0", resp.Header.Get(httplimit.HeaderRemaining))
+
+	resp = do("a")
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	assert.NotEmpty(t, resp.Header.Get(fiber.HeaderRetryAfter))
+	assert.Equal(t, 1, calls)
+
+	assert.Equal(t, http.StatusOK, do("").StatusCode)
+	assert.Equal(t, 2, calls)
+
+	// Redis 不可用时默认放行，可以通过 WithErrorHandler 改为 fail-close
+	mr.Close()
+	assert.Equal(t, http.StatusOK, do("b").StatusCode)
+
+	app = fiber.New()
+	app.Use(Middleware(l, KeyByHeader("X-Api-Key"), WithErrorHandler(func(c *fiber.Ctx, err error) error {
+		assert.Error(t, err)
+		return c.SendStatus(fiber.StatusServiceUnavailable)
+	})))
+	app.Get("/", func(c *fiber.Ctx) error { return errors.New("unreachable") })
+	assert.Equal(t, http.StatusServiceUnavailable, do("b").StatusCode)
+}
@@ -0,0 +1,41 @@
+// Package ginlimit 为 Gin 提供限流中间件。
+//
+// 判定逻辑、429 响应与 RateLimit-* 头均复用 httplimit，KeyFunc 与 Option 也直接使用 httplimit 中的定义。
+package ginlimit
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	limiter "github.com/lifei6671/go-redis-limiter"
+	"github.com/lifei6671/go-redis-limiter/httplimit"
+)
+
+// Middleware 返回 Gin 中间件，参数与行为同 httplimit.Middleware：
+// 被限流时返回 429 并中止后续 handler。
+//
+//	r.Use(ginlimit.Middleware(l, httplimit.KeyByIP))
+func Middleware(l limiter.RateShardedLimiter, keyFunc httplimit.KeyFunc, opts ...httplimit.Option) gin.HandlerFunc {
+	return Wrap(httplimit.Middleware(l, keyFunc, opts...))
+}
+
+// ClassifierMiddleware 与 Middleware 相同，但由 limiter.Classifier 决定 key 与消耗，见 httplimit.ClassifierMiddleware。
+func ClassifierMiddleware(l limiter.RateShardedLimiter, c limiter.Classifier, opts ...httplimit.Option) gin.HandlerFunc {
+	return Wrap(httplimit.ClassifierMiddleware(l, c, opts...))
+}
+
+// Wrap 把 net/http 中间件转换为 Gin 中间件：中间件调用 next 时继续执行后续 handler，否则中止。
+func Wrap(mw func(http.Handler) http.Handler) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		next := false
+		mw(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+			next = true
+			c.Request = r
+			c.Next()
+		})).ServeHTTP(c.Writer, c.Request)
+		if !next {
+			c.Abort()
+		}
+	}
+}
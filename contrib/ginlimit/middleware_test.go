@@ -0,0 +1,58 @@
+package ginlimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+
+	limiter "github.com/lifei6671/go-redis-limiter"
+	"github.com/lifei6671/go-redis-limiter/httplimit"
+)
+
+func newLimiter(t *testing.T) limiter.RateShardedLimiter {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	return limiter.NewShardedTokenBucketLimiter(client, "api", limiter.WithShardedTokenBucketCount(1),
+		limiter.WithShardedTokenBucket(limiter.WithTokenBucketRate(0.001), limiter.WithTokenBucketCapacity(1)))
+}
+
+func TestMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Middleware(newLimiter(t), httplimit.KeyByHeader("X-Api-Key")))
+	calls := 0
+	r.GET("/", func(c *gin.Context) {
+		calls++
+		c.String(http.StatusOK, "ok")
+	})
+
+	do := func(key string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-Api-Key", key)
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do("a")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "1", w.Header().Get(httplimit.HeaderLimit))
+	assert.Equal(t, "0", w.Header().Get(httplimit.HeaderRemaining))
+
+	w = do("a")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.NotEmpty(t, w.Header().Get("Retry-After"))
+	assert.Equal(t, 1, calls)
+
+	// key 为空时不限流
+	assert.Equal(t, http.StatusOK, do("").Code)
+	assert.Equal(t, http.StatusOK, do("").Code)
+	assert.Equal(t, 3, calls)
+}
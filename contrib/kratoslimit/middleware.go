@@ -0,0 +1,95 @@
+// Package kratoslimit 为 Kratos 提供限流中间件。
+//
+// 本包不依赖 Kratos：Middleware 的类型参数取 middleware.Handler，返回值即可直接作为 middleware.Middleware 使用，
+// 同时适用于 Kratos 的 HTTP 与 gRPC 服务：
+//
+//	limit := kratoslimit.Middleware[middleware.Handler](l, func(ctx context.Context, _ any) string {
+//		if tr, ok := transport.FromServerContext(ctx); ok {
+//			return tr.Operation()
+//		}
+//		return ""
+//	}, kratoslimit.WithDeniedError(func(ctx context.Context, res limiter.Result) error {
+//		return errors.New(429, "RATELIMIT", "rate limit exceeded")
+//	}))
+//	srv := http.NewServer(http.Middleware(limit))
+package kratoslimit
+
+import (
+	"context"
+
+	limiter "github.com/lifei6671/go-redis-limiter"
+	"github.com/lifei6671/go-redis-limiter/httplimit"
+)
+
+// KeyFunc 从请求中提取限流 key，返回空字符串表示该请求不限流。
+type KeyFunc func(ctx context.Context, req any) string
+
+// Option 为 Kratos 中间件的配置项。
+type Option func(*config)
+
+type config struct {
+	onError  func(ctx context.Context, err error) error
+	onDenied func(ctx context.Context, res limiter.Result) error
+}
+
+// WithErrorHandler 设置限流器出错时的处理方式：返回 nil 表示放行，返回 error 则直接作为调用结果。
+// 默认 fail-open（放行）。
+func WithErrorHandler(fn func(ctx context.Context, err error) error) Option {
+	return func(c *config) {
+		if fn != nil {
+			c.onError = fn
+		}
+	}
+}
+
+// WithDeniedError 设置被限流时返回的错误，通常返回 Kratos 的 errors.New(429, ...) 以便编码为正确的状态码。
+// 默认返回 *limiter.LimitExceededError。
+func WithDeniedError(fn func(ctx context.Context, res limiter.Result) error) Option {
+	return func(c *config) {
+		if fn != nil {
+			c.onDenied = fn
+		}
+	}
+}
+
+// Middleware 返回 Kratos 中间件，H 为 Kratos 的 middleware.Handler。
+// 每次调用前使用 keyFunc 提取 key 并判定一次，被限流时不调用后续 handler。
+func Middleware[H ~func(context.Context, any) (any, error)](l limiter.RateShardedLimiter, keyFunc KeyFunc, opts ...Option) func(H) H {
+	if l == nil {
+		panic("kratoslimit: limiter is nil")
+	}
+	if keyFunc == nil {
+		panic("kratoslimit: key func is nil")
+	}
+
+	cfg := &config{
+		onError: func(context.Context, error) error { return nil },
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	return func(next H) H {
+		return func(ctx context.Context, req any) (any, error) {
+			key := keyFunc(ctx, req)
+			if key == "" {
+				return next(ctx, req)
+			}
+
+			res, err := httplimit.Check(ctx, l, key, 1)
+			if err != nil {
+				if err := cfg.onError(ctx, err); err != nil {
+					return nil, err
+				}
+				return next(ctx, req)
+			}
+			if !res.Allowed {
+				if cfg.onDenied != nil {
+					return nil, cfg.onDenied(ctx, res)
+				}
+				return nil, &limiter.LimitExceededError{Key: key, Remaining: res.Remaining, RetryAfter: res.RetryAfter}
+			}
+			return next(ctx, req)
+		}
+	}
+}
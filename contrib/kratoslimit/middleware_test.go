@@ -0,0 +1,61 @@
+package kratoslimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+
+	limiter "github.com/lifei6671/go-redis-limiter"
+)
+
+// handler 与 middleware 对应 Kratos 的 middleware.Handler 与 middleware.Middleware。
+type handler func(ctx context.Context, req any) (any, error)
+
+type middleware func(handler) handler
+
+func TestMiddleware(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	l := limiter.NewShardedTokenBucketLimiter(client, "api", limiter.WithShardedTokenBucketCount(1),
+		limiter.WithShardedTokenBucket(limiter.WithTokenBucketRate(0.001), limiter.WithTokenBucketCapacity(1)))
+
+	var mw middleware = Middleware[handler](l, func(_ context.Context, req any) string {
+		return req.(string)
+	})
+	h := mw(func(_ context.Context, req any) (any, error) {
+		return "ok", nil
+	})
+	ctx := context.Background()
+
+	resp, err := h(ctx, "a")
+	assert.NoError(t, err)
+	assert.Equal(t, "ok", resp)
+
+	_, err = h(ctx, "a")
+	assert.True(t, errors.Is(err, limiter.ErrLimiter))
+	var le *limiter.LimitExceededError
+	assert.True(t, errors.As(err, &le))
+	assert.Equal(t, "a", le.Key)
+	assert.Greater(t, le.RetryAfter, time.Duration(0))
+
+	// key 为空时不限流
+	_, err = h(ctx, "")
+	assert.NoError(t, err)
+
+	denied := errors.New("429")
+	h = Middleware[handler](l, func(context.Context, any) string { return "a" },
+		WithDeniedError(func(context.Context, limiter.Result) error { return denied }))(h)
+	_, err = h(ctx, "")
+	assert.ErrorIs(t, err, denied)
+
+	// Redis 不可用时默认放行
+	mr.Close()
+	_, err = mw(h)(ctx, "")
+	assert.NoError(t, err)
+}
@@ -0,0 +1,88 @@
+// Package microlimit 为 go-micro 提供限流的 HandlerWrapper。
+//
+// 本包不依赖 go-micro：HandlerWrapper 的类型参数取 server.HandlerFunc，请求类型由 keyFunc 推导，
+// 返回值即可直接作为 server.HandlerWrapper 使用：
+//
+//	srv := micro.NewService(micro.WrapHandler(
+//		microlimit.HandlerWrapper[server.HandlerFunc](l, func(_ context.Context, req server.Request) string {
+//			return req.Service() + "." + req.Endpoint()
+//		}),
+//	))
+package microlimit
+
+import (
+	"context"
+
+	limiter "github.com/lifei6671/go-redis-limiter"
+	"github.com/lifei6671/go-redis-limiter/httplimit"
+)
+
+// Option 为 go-micro HandlerWrapper 的配置项。
+type Option func(*config)
+
+type config struct {
+	onError  func(ctx context.Context, err error) error
+	onDenied func(ctx context.Context, res limiter.Result) error
+}
+
+// WithErrorHandler 设置限流器出错时的处理方式：返回 nil 表示放行，返回 error 则直接作为调用结果。
+// 默认 fail-open（放行）。
+func WithErrorHandler(fn func(ctx context.Context, err error) error) Option {
+	return func(c *config) {
+		if fn != nil {
+			c.onError = fn
+		}
+	}
+}
+
+// WithDeniedError 设置被限流时返回的错误，例如 go-micro 的 errors.New(id, detail, 429)。
+// 默认返回 *limiter.LimitExceededError。
+func WithDeniedError(fn func(ctx context.Context, res limiter.Result) error) Option {
+	return func(c *config) {
+		if fn != nil {
+			c.onDenied = fn
+		}
+	}
+}
+
+// HandlerWrapper 返回 go-micro 的 HandlerWrapper，F 为 server.HandlerFunc，R 为 server.Request。
+// 每次调用前使用 keyFunc 提取 key 并判定一次，key 为空时不限流，被限流时不调用后续 handler。
+func HandlerWrapper[F ~func(context.Context, R, any) error, R any](l limiter.RateShardedLimiter, keyFunc func(ctx context.Context, req R) string, opts ...Option) func(F) F {
+	if l == nil {
+		panic("microlimit: limiter is nil")
+	}
+	if keyFunc == nil {
+		panic("microlimit: key func is nil")
+	}
+
+	cfg := &config{
+		onError: func(context.Context, error) error { return nil },
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	return func(next F) F {
+		return func(ctx context.Context, req R, rsp any) error {
+			key := keyFunc(ctx, req)
+			if key == "" {
+				return next(ctx, req, rsp)
+			}
+
+			res, err := httplimit.Check(ctx, l, key, 1)
+			if err != nil {
+				if err := cfg.onError(ctx, err); err != nil {
+					return err
+				}
+				return next(ctx, req, rsp)
+			}
+			if !res.Allowed {
+				if cfg.onDenied != nil {
+					return cfg.onDenied(ctx, res)
+				}
+				return &limiter.LimitExceededError{Key: key, Remaining: res.Remaining, RetryAfter: res.RetryAfter}
+			}
+			return next(ctx, req, rsp)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package microlimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+
+	limiter "github.com/lifei6671/go-redis-limiter"
+)
+
+// request、handlerFunc 与 handlerWrapper 对应 go-micro 的 server.Request、server.HandlerFunc 与 server.HandlerWrapper。
+type request interface {
+	Endpoint() string
+}
+
+type handlerFunc func(ctx context.Context, req request, rsp any) error
+
+type handlerWrapper func(handlerFunc) handlerFunc
+
+type endpoint string
+
+func (e endpoint) Endpoint() string { return string(e) }
+
+func TestHandlerWrapper(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	l := limiter.NewShardedTokenBucketLimiter(client, "api", limiter.WithShardedTokenBucketCount(1),
+		limiter.WithShardedTokenBucket(limiter.WithTokenBucketRate(0.001), limiter.WithTokenBucketCapacity(1)))
+
+	var w handlerWrapper = HandlerWrapper[handlerFunc](l, func(_ context.Context, req request) string {
+		return req.Endpoint()
+	})
+	calls := 0
+	h := w(func(context.Context, request, any) error {
+		calls++
+		return nil
+	})
+	ctx := context.Background()
+
+	assert.NoError(t, h(ctx, endpoint("Greeter.Hello"), nil))
+	err := h(ctx, endpoint("Greeter.Hello"), nil)
+	assert.True(t, errors.Is(err, limiter.ErrLimiter))
+	assert.NoError(t, h(ctx, endpoint(""), nil))
+	assert.Equal(t, 2, calls)
+
+	// Redis 出错时可以 fail-close
+	mr.Close()
+	unavailable := errors.New("unavailable")
+	h = HandlerWrapper[handlerFunc](l, func(context.Context, request) string { return "x" },
+		WithErrorHandler(func(context.Context, error) error { return unavailable }))(h)
+	assert.ErrorIs(t, h(ctx, endpoint("x"), nil), unavailable)
+}
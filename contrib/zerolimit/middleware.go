@@ -0,0 +1,33 @@
+// Package zerolimit 为 go-zero 提供限流中间件。
+//
+// go-zero 的 rest.Middleware 形状为 func(http.HandlerFunc) http.HandlerFunc，因此本包不依赖 go-zero，
+// 判定逻辑、429 响应与 RateLimit-* 头均复用 httplimit。zrpc 服务直接使用 grpclimit 的拦截器即可：
+//
+//	server.AddUnaryInterceptors(grpclimit.UnaryServerInterceptor(l))
+package zerolimit
+
+import (
+	"net/http"
+
+	limiter "github.com/lifei6671/go-redis-limiter"
+	"github.com/lifei6671/go-redis-limiter/httplimit"
+)
+
+// Middleware 返回 go-zero rest 中间件，参数与行为同 httplimit.Middleware。
+//
+//	server.Use(zerolimit.Middleware(l, httplimit.KeyByIP))
+func Middleware(l limiter.RateShardedLimiter, keyFunc httplimit.KeyFunc, opts ...httplimit.Option) func(http.HandlerFunc) http.HandlerFunc {
+	return Wrap(httplimit.Middleware(l, keyFunc, opts...))
+}
+
+// ClassifierMiddleware 与 Middleware 相同，但由 limiter.Classifier 决定 key 与消耗，见 httplimit.ClassifierMiddleware。
+func ClassifierMiddleware(l limiter.RateShardedLimiter, c limiter.Classifier, opts ...httplimit.Option) func(http.HandlerFunc) http.HandlerFunc {
+	return Wrap(httplimit.ClassifierMiddleware(l, c, opts...))
+}
+
+// Wrap 把 net/http 中间件转换为 go-zero rest 中间件的形状。
+func Wrap(mw func(http.Handler) http.Handler) func(http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return mw(next).ServeHTTP
+	}
+}
@@ -0,0 +1,166 @@
+package limiter
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// DenyCacheLimiter 在本地缓存“拒绝”判定：某个 key 被拒绝且给出了 RetryAfter 时，
+// 在 RetryAfter 到期前该 key 的请求直接在本地拒绝，不再访问 Redis。
+// 适合抵御单个滥用 key 的高频重试——这些请求注定被拒绝，却会占满 Redis 的脚本调用。
+//
+// 代价是实时性：其他实例上的 Reset / 归还额度 / 调大限额不会让本地缓存提前失效，
+// 被缓存的拒绝最多持续 min(RetryAfter, MaxTTL)。本实例的 Reset 与 Invalidate 会立即清除缓存。
+type DenyCacheLimiter struct {
+	limiter RateShardedLimiter
+
+	// Size 最多缓存的 key 数量，默认 10000
+	Size int
+	// MaxTTL 单条拒绝缓存的最长时间，默认 1 分钟（RetryAfter 更长时以 MaxTTL 为准）
+	MaxTTL time.Duration
+	// MinRetryAfter RetryAfter 小于该值的拒绝不缓存（很快就会放行，缓存收益不大），默认 0（全部缓存）
+	MinRetryAfter time.Duration
+	// Clock 时钟，用于缓存过期判断，默认 SystemClock
+	Clock Clock
+
+	mu    sync.Mutex
+	cache map[string]denyEntry
+}
+
+type denyEntry struct {
+	res   Result
+	until time.Time
+}
+
+var _ RateShardedLimiter = (*DenyCacheLimiter)(nil)
+
+// NewDenyCacheLimiter 为 l 加上本地拒绝缓存。
+func NewDenyCacheLimiter(l RateShardedLimiter, opts ...DenyCacheOption) *DenyCacheLimiter {
+	if l == nil {
+		panic("deny cache: limiter is nil")
+	}
+
+	d := &DenyCacheLimiter{
+		limiter: l,
+		Size:    10000,
+		MaxTTL:  time.Minute,
+		Clock:   SystemClock,
+		cache:   make(map[string]denyEntry),
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
+}
+
+func (d *DenyCacheLimiter) Allow(ctx context.Context, key string) (bool, error) {
+	res, err := d.AllowWithResult(ctx, key)
+	return res.Allowed, err
+}
+
+// AllowWithResult 命中拒绝缓存时直接返回拒绝，RetryAfter 为距离缓存到期的时间。
+func (d *DenyCacheLimiter) AllowWithResult(ctx context.Context, key string) (Result, error) {
+	if res, ok := d.cached(key); ok {
+		return res, nil
+	}
+	res, err := d.limiter.AllowWithResult(ctx, key)
+	if err == nil && !res.Allowed {
+		d.store(key, res)
+	}
+	return res, err
+}
+
+// AllowN 命中拒绝缓存时直接拒绝（1 个许可都拿不到时 n 个同样拿不到）。
+// AllowN 不返回 RetryAfter，因此它的拒绝不会写入缓存。
+func (d *DenyCacheLimiter) AllowN(ctx context.Context, key string, n int64) (bool, error) {
+	if _, ok := d.cached(key); ok {
+		return false, nil
+	}
+	return d.limiter.AllowN(ctx, key, n)
+}
+
+// Wait 每次重试都先查询拒绝缓存，缓存的 RetryAfter 即为 sleep 时长。
+func (d *DenyCacheLimiter) Wait(ctx context.Context, key string, maxWait time.Duration) error {
+	return waitFor(ctx, key, "deny_cache", maxWait, nil, func(ctx context.Context) (Result, error) {
+		return d.AllowWithResult(ctx, key)
+	})
+}
+
+func (d *DenyCacheLimiter) State(ctx context.Context, key string) (LimiterState, error) {
+	return d.limiter.State(ctx, key)
+}
+
+// Reset 清除 key 的拒绝缓存并重置内部限流器。
+func (d *DenyCacheLimiter) Reset(ctx context.Context, key string) error {
+	d.Invalidate(key)
+	return d.limiter.Reset(ctx, key)
+}
+
+// ResetAll 清空拒绝缓存并重置内部限流器。
+func (d *DenyCacheLimiter) ResetAll(ctx context.Context) error {
+	d.mu.Lock()
+	d.cache = make(map[string]denyEntry)
+	d.mu.Unlock()
+	return d.limiter.ResetAll(ctx)
+}
+
+// Invalidate 清除 key 的拒绝缓存（只影响本实例）。
+func (d *DenyCacheLimiter) Invalidate(key string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.cache, key)
+}
+
+// Len 返回当前缓存的拒绝数量（包括已过期但尚未清理的）。
+func (d *DenyCacheLimiter) Len() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.cache)
+}
+
+// cached 查询 key 的拒绝缓存，命中时返回剩余等待时间更新后的结果。
+func (d *DenyCacheLimiter) cached(key string) (Result, bool) {
+	now := d.Clock.Now()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	e, ok := d.cache[key]
+	if !ok {
+		return Result{}, false
+	}
+	if !now.Before(e.until) {
+		delete(d.cache, key)
+		return Result{}, false
+	}
+	res := e.res
+	res.RetryAfter = e.until.Sub(now)
+	return res, true
+}
+
+// store 缓存一次带 RetryAfter 的拒绝。
+func (d *DenyCacheLimiter) store(key string, res Result) {
+	if res.RetryAfter <= 0 || res.RetryAfter < d.MinRetryAfter {
+		return
+	}
+	now := d.Clock.Now()
+	ttl := res.RetryAfter
+	if d.MaxTTL > 0 && ttl > d.MaxTTL {
+		ttl = d.MaxTTL
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(d.cache) >= d.Size {
+		for k, e := range d.cache {
+			if !now.Before(e.until) {
+				delete(d.cache, k)
+			}
+		}
+		// 仍然没有空间时不再缓存，请求照常访问 Redis
+		if len(d.cache) >= d.Size {
+			return
+		}
+	}
+	d.cache[key] = denyEntry{res: res, until: now.Add(ttl)}
+}
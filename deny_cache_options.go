@@ -0,0 +1,42 @@
+package limiter
+
+import "time"
+
+// DenyCacheOption 为本地拒绝缓存的配置项。
+type DenyCacheOption func(*DenyCacheLimiter)
+
+// WithDenyCacheSize 设置最多缓存的 key 数量。
+func WithDenyCacheSize(size int) DenyCacheOption {
+	return func(d *DenyCacheLimiter) {
+		if size > 0 {
+			d.Size = size
+		}
+	}
+}
+
+// WithDenyCacheMaxTTL 设置单条拒绝缓存的最长时间，0 表示只受 RetryAfter 限制。
+func WithDenyCacheMaxTTL(ttl time.Duration) DenyCacheOption {
+	return func(d *DenyCacheLimiter) {
+		if ttl >= 0 {
+			d.MaxTTL = ttl
+		}
+	}
+}
+
+// WithDenyCacheMinRetryAfter 设置缓存的最小 RetryAfter，更短的拒绝不缓存。
+func WithDenyCacheMinRetryAfter(d time.Duration) DenyCacheOption {
+	return func(c *DenyCacheLimiter) {
+		if d >= 0 {
+			c.MinRetryAfter = d
+		}
+	}
+}
+
+// WithDenyCacheClock 设置缓存过期判断使用的时钟，通常用于测试。
+func WithDenyCacheClock(c Clock) DenyCacheOption {
+	return func(d *DenyCacheLimiter) {
+		if c != nil {
+			d.Clock = c
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package limiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDenyCacheLimiter(t *testing.T) {
+	ctx := context.Background()
+	client := newSpecClient(t)
+	hook := &countingHook{}
+	client.AddHook(hook)
+
+	now := time.Unix(1000, 0)
+	clock := ClockFunc(func() time.Time { return now })
+	k := NewKeyedLimiter(func(key string) RateLimiter {
+		return NewTokenBucketLimiter(client, key,
+			WithTokenBucketRate(1),
+			WithTokenBucketCapacity(1),
+			WithTokenBucketTTL(time.Minute),
+			WithTokenBucketServerTime(false),
+			WithTokenBucketClock(clock),
+		)
+	})
+	d := NewDenyCacheLimiter(k, WithDenyCacheClock(clock), WithDenyCacheMaxTTL(500*time.Millisecond))
+
+	ok, err := d.Allow(ctx, "abuser")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	// 第一次拒绝访问 Redis 并写入缓存，RetryAfter 1s 被 MaxTTL 截断为 500ms
+	res, err := d.AllowWithResult(ctx, "abuser")
+	assert.NoError(t, err)
+	assert.False(t, res.Allowed)
+	assert.Equal(t, 1, d.Len())
+
+	// 缓存期内不再访问 Redis
+	calls := hook.n.Load()
+	now = now.Add(200 * time.Millisecond)
+	res, err = d.AllowWithResult(ctx, "abuser")
+	assert.NoError(t, err)
+	assert.False(t, res.Allowed)
+	assert.Equal(t, 300*time.Millisecond, res.RetryAfter)
+	ok, err = d.AllowN(ctx, "abuser", 1)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, calls, hook.n.Load())
+
+	// 其他 key 不受影响
+	ok, err = d.Allow(ctx, "other")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	// 缓存到期后重新访问 Redis
+	now = now.Add(time.Second)
+	ok, err = d.Allow(ctx, "abuser")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	// Reset 立即清除缓存
+	_, _ = d.Allow(ctx, "abuser")
+	assert.Equal(t, 1, d.Len())
+	assert.NoError(t, d.Reset(ctx, "abuser"))
+	assert.Equal(t, 0, d.Len())
+	ok, err = d.Allow(ctx, "abuser")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+}
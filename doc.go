@@ -0,0 +1,29 @@
+// Package limiter 提供基于 Redis + Lua 的分布式限流器（令牌桶、漏桶、滑动窗口等）。
+//
+// # 包布局
+//
+// 根包只包含限流算法本身及其公共类型（RateLimiter、LimiterState、错误定义等），
+// 依赖仅限标准库和 go-redis，保证任何二进制引入根包都不会带入额外的框架依赖。
+//
+// 依赖第三方框架或面向特定场景的能力放在独立的子包中，按需引入：
+//
+//	httplimit         net/http 中间件与限流响应头（middleware）
+//	grpclimit         gRPC 拦截器（middleware）
+//	contrib/...       第三方 Web/微服务框架适配（middleware）
+//	otellimit         OpenTelemetry 埋点（metrics）
+//	adminapi          运维管理 HTTP 接口（admin）
+//	etcdsource        etcd 配置源（config）
+//	consulsource      Consul 配置源（config）
+//	config/yamlconfig YAML 规则文件（config）
+//
+// 子包只能依赖根包，根包不反向依赖子包。
+//
+// 限流算法不单独拆出 algorithms 子包：算法依赖的公共类型（RateLimiter、Result、LimiterState、
+// 各 Option）与脚本、统计、钩子等内部实现紧密耦合，拆包后根包只剩数百个转发别名，
+// 却不会减少任何依赖——根包本身已经只依赖 go-redis。
+//
+// # 导入路径稳定性
+//
+// 根包中已导出的类型、函数和 Option 视为稳定 API。若某个类型日后迁移到子包，
+// 会在根包中保留同名的类型别名（type X = sub.X）和转发函数，旧代码无需修改即可编译。
+package limiter
@@ -1,8 +1,99 @@
 package limiter
 
-import "fmt"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
 
 var (
-	ErrLimiter = fmt.Errorf("rate limit exceeded")
-	ErrTimeout = fmt.Errorf("rate limited (timeout)")
+	// ErrLimiter 匹配所有被限流的错误。
+	ErrLimiter error = &sentinelError{msg: "rate limit exceeded"}
+	// ErrTimeout 匹配等待超出 maxWait（或 ctx 截止时间）的错误，同时满足 errors.Is(err, context.DeadlineExceeded)。
+	ErrTimeout error = &sentinelError{msg: "rate limited (timeout)", cause: context.DeadlineExceeded}
 )
+
+// RetryableError 由“被限流”类的错误实现：*LimitExceededError 以及 ErrLimiter、ErrTimeout 本身。
+// HTTP / gRPC 层可以据此区分两类失败：
+//   - Temporary 为 true：请求本身合法，只是暂时没有额度，应返回 429 / RESOURCE_EXHAUSTED，RetryDelay 后重试
+//   - 未实现该接口的错误（Redis 故障、熔断打开等）：限流器不可用，应返回 503 / UNAVAILABLE
+type RetryableError interface {
+	error
+	// Temporary 报告稍后重试是否可能成功
+	Temporary() bool
+	// RetryDelay 返回建议的重试等待时间，未知时为 0
+	RetryDelay() time.Duration
+}
+
+// IsRetryable 判断 err 是否为可以稍后重试的限流错误，并返回建议的重试等待时间。
+func IsRetryable(err error) (time.Duration, bool) {
+	var re RetryableError
+	if errors.As(err, &re) && re.Temporary() {
+		return re.RetryDelay(), true
+	}
+	return 0, false
+}
+
+// sentinelError 为 ErrLimiter / ErrTimeout 的类型，使哨兵值本身也实现 RetryableError。
+type sentinelError struct {
+	msg   string
+	cause error
+}
+
+func (e *sentinelError) Error() string             { return e.msg }
+func (e *sentinelError) Unwrap() error             { return e.cause }
+func (e *sentinelError) Temporary() bool           { return true }
+func (e *sentinelError) RetryDelay() time.Duration { return 0 }
+
+// LimitExceededError 表示请求被限流，并携带本次判定的额度信息，
+// 上层（例如 HTTP 中间件）可以直接据此返回 429 与 Retry-After，无需再调用 State。
+//   - errors.Is(err, ErrLimiter) 对所有 LimitExceededError 成立
+//   - Timeout 为 true 时（预计等待时间超出 maxWait）同时满足 errors.Is(err, ErrTimeout) 与 errors.Is(err, context.DeadlineExceeded)
+type LimitExceededError struct {
+	// Key 被限流的业务 key（无法确定时为空）
+	Key string
+	// Algorithm 限流算法，与 LimiterState.Type 一致，例如 "token_bucket"
+	Algorithm string
+	// Remaining 最近一次判定后剩余的额度
+	Remaining float64
+	// RetryAfter 最近一次判定给出的重试等待时间
+	RetryAfter time.Duration
+	// Timeout 是否因为等待时间超出 maxWait 而放弃
+	Timeout bool
+}
+
+var _ RetryableError = (*LimitExceededError)(nil)
+
+// newLimitExceededError 根据一次被拒绝的判定结果构造错误。
+func newLimitExceededError(key, algorithm string, res Result, timeout bool) *LimitExceededError {
+	return &LimitExceededError{
+		Key:        key,
+		Algorithm:  algorithm,
+		Remaining:  res.Remaining,
+		RetryAfter: res.RetryAfter,
+		Timeout:    timeout,
+	}
+}
+
+// Error 实现 error。
+func (e *LimitExceededError) Error() string {
+	msg := ErrLimiter.Error()
+	if e.Timeout {
+		msg = ErrTimeout.Error()
+	}
+	return fmt.Sprintf("%s: key=%q algorithm=%s remaining=%.f retry_after=%s",
+		msg, e.Key, e.Algorithm, e.Remaining, e.RetryAfter)
+}
+
+// Is 使 errors.Is(err, ErrLimiter) 以及超时场景下的 errors.Is(err, ErrTimeout)、
+// errors.Is(err, context.DeadlineExceeded) 成立。
+func (e *LimitExceededError) Is(target error) bool {
+	return target == ErrLimiter || (e.Timeout && (target == ErrTimeout || target == context.DeadlineExceeded))
+}
+
+// Temporary 实现 RetryableError，限流总是暂时的。
+func (e *LimitExceededError) Temporary() bool { return true }
+
+// RetryDelay 实现 RetryableError，返回 RetryAfter。
+func (e *LimitExceededError) RetryDelay() time.Duration { return e.RetryAfter }
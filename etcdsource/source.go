@@ -0,0 +1,116 @@
+// Package etcdsource 从 etcd 读取并监听限流参数，实现 limiter.ConfigSource。
+package etcdsource
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+
+	limiter "github.com/lifei6671/go-redis-limiter"
+)
+
+// Source 监听 etcd 中 Prefix 下的所有 key，每个 key 的值为一条或一组 JSON 格式的 LimitConfig：
+//
+//	limits/sms      -> {"limit": 5, "window_ms": 60000}
+//	limits/chat     -> {"name": "api:/v1/chat", "rate": 200, "capacity": 400}
+//
+// 值中未设置 name 时使用去掉 Prefix 后的 key 作为 name。删除 key 不会撤销已经生效的配置。
+//
+//	w := limiter.NewConfigWatcher(rdb, "limiter:config")
+//	w.Register("sms", smsLimiter)
+//	go w.RunSource(ctx, etcdsource.New(etcdClient, "limits/"))
+type Source struct {
+	client *clientv3.Client
+
+	// Prefix 监听的 key 前缀
+	Prefix string
+
+	// OnError 单个 key 的值解析失败时的回调（可选），该 key 会被忽略
+	OnError func(err error)
+}
+
+// Option 为 etcd 配置源的配置项。
+type Option func(*Source)
+
+// WithErrorHandler 设置 key 的值解析失败时的回调，例如记录日志。
+func WithErrorHandler(fn func(err error)) Option {
+	return func(s *Source) {
+		s.OnError = fn
+	}
+}
+
+// New 创建监听 prefix 的 etcd 配置源。
+func New(client *clientv3.Client, prefix string, opts ...Option) *Source {
+	if client == nil {
+		panic("etcdsource: client is nil")
+	}
+	if prefix == "" {
+		panic("etcdsource: prefix is empty")
+	}
+
+	s := &Source{client: client, Prefix: prefix}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Watch 实现 limiter.ConfigSource：先推送 Prefix 下的全部配置，再从读取时的 revision 之后开始监听变化，
+// 因此两者之间的修改不会丢失。etcd 返回错误（例如 revision 已被压缩）时返回该错误，调用方可以重新 Watch。
+func (s *Source) Watch(ctx context.Context, push func(cfgs []limiter.LimitConfig)) error {
+	resp, err := s.client.Get(ctx, s.Prefix, clientv3.WithPrefix())
+	if err != nil {
+		return err
+	}
+	var cfgs []limiter.LimitConfig
+	for _, kv := range resp.Kvs {
+		cfgs = append(cfgs, s.parse(kv.Key, kv.Value)...)
+	}
+	push(cfgs)
+
+	wctx, cancel := context.WithCancel(clientv3.WithRequireLeader(ctx))
+	defer cancel()
+	wch := s.client.Watch(wctx, s.Prefix, clientv3.WithPrefix(), clientv3.WithRev(resp.Header.Revision+1))
+	for wresp := range wch {
+		if err := wresp.Err(); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		var cfgs []limiter.LimitConfig
+		for _, ev := range wresp.Events {
+			if ev.Type == clientv3.EventTypePut {
+				cfgs = append(cfgs, s.parse(ev.Kv.Key, ev.Kv.Value)...)
+			}
+		}
+		if len(cfgs) > 0 {
+			push(cfgs)
+		}
+	}
+	if ctx.Err() != nil {
+		return nil
+	}
+	return fmt.Errorf("etcdsource: watch channel closed")
+}
+
+// parse 解析一个 key 的值，未设置 name 的配置使用 key 去掉前缀后的部分。
+func (s *Source) parse(key, value []byte) []limiter.LimitConfig {
+	name := strings.TrimPrefix(string(key), s.Prefix)
+	cfgs, err := limiter.ParseLimitConfigs(value)
+	if err != nil {
+		if s.OnError != nil {
+			s.OnError(fmt.Errorf("etcdsource: invalid value of %q: %w", key, err))
+		}
+		return nil
+	}
+	for i := range cfgs {
+		if cfgs[i].Name == "" {
+			cfgs[i].Name = name
+		}
+	}
+	return cfgs
+}
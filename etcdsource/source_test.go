@@ -0,0 +1,91 @@
+package etcdsource
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+
+	limiter "github.com/lifei6671/go-redis-limiter"
+)
+
+// fakeKV 只实现 Get，返回固定的 key。
+type fakeKV struct {
+	clientv3.KV
+	kvs []*mvccpb.KeyValue
+}
+
+func (f *fakeKV) Get(context.Context, string, ...clientv3.OpOption) (*clientv3.GetResponse, error) {
+	return &clientv3.GetResponse{Header: &etcdserverpb.ResponseHeader{Revision: 10}, Kvs: f.kvs}, nil
+}
+
+// fakeWatcher 把测试写入 ch 的事件转发给 Watch 的调用方。
+type fakeWatcher struct {
+	clientv3.Watcher
+	ch  chan clientv3.WatchResponse
+	rev int64
+}
+
+func (f *fakeWatcher) Watch(ctx context.Context, _ string, opts ...clientv3.OpOption) clientv3.WatchChan {
+	op := clientv3.OpGet("", opts...)
+	f.rev = op.Rev()
+	out := make(chan clientv3.WatchResponse)
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case resp := <-f.ch:
+				out <- resp
+			}
+		}
+	}()
+	return out
+}
+
+func TestSource(t *testing.T) {
+	kv := &fakeKV{kvs: []*mvccpb.KeyValue{
+		{Key: []byte("limits/sms"), Value: []byte(`{"limit": 5, "window_ms": 60000}`)},
+		{Key: []byte("limits/chat"), Value: []byte(`{"name": "api:/v1/chat", "rate": 200}`)},
+		{Key: []byte("limits/bad"), Value: []byte(`{`)},
+	}}
+	watcher := &fakeWatcher{ch: make(chan clientv3.WatchResponse)}
+	var errs []error
+	s := New(&clientv3.Client{KV: kv, Watcher: watcher}, "limits/", WithErrorHandler(func(err error) {
+		errs = append(errs, err)
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	pushed := make(chan []limiter.LimitConfig, 4)
+	done := make(chan error, 1)
+	go func() {
+		done <- s.Watch(ctx, func(cfgs []limiter.LimitConfig) { pushed <- cfgs })
+	}()
+
+	assert.Equal(t, []limiter.LimitConfig{
+		{Name: "sms", Limit: 5, WindowMs: 60000},
+		{Name: "api:/v1/chat", Rate: 200},
+	}, <-pushed)
+	assert.Len(t, errs, 1)
+
+	watcher.ch <- clientv3.WatchResponse{Events: []*clientv3.Event{
+		{Type: clientv3.EventTypePut, Kv: &mvccpb.KeyValue{Key: []byte("limits/sms"), Value: []byte(`{"limit": 10}`)}},
+		{Type: clientv3.EventTypeDelete, Kv: &mvccpb.KeyValue{Key: []byte("limits/chat")}},
+	}}
+	assert.Equal(t, []limiter.LimitConfig{{Name: "sms", Limit: 10}}, <-pushed)
+	// 从读取时的 revision 之后开始监听
+	assert.Equal(t, int64(11), watcher.rev)
+
+	cancel()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("Watch did not return after cancel")
+	}
+}
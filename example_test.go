@@ -15,9 +15,12 @@ func ExampleNewShardedTokenBucketLimiter() {
 		Addr:     "localhost:6379",
 		Password: "redis_password",
 	})
-	redisLimiter := limiter.NewShardedTokenBucketLimiter(client, "limiter:", 2,
-		limiter.WithTokenBucketRate(2),
-		limiter.WithTokenBucketCapacity(2),
+	redisLimiter := limiter.NewShardedTokenBucketLimiter(client, "limiter:",
+		limiter.WithShardedTokenBucketCount(2),
+		limiter.WithShardedTokenBucket(
+			limiter.WithTokenBucketRate(2),
+			limiter.WithTokenBucketCapacity(2),
+		),
 	)
 	ctx := context.Background()
 
@@ -61,9 +64,12 @@ func ExampleNewShardedSlidingWindowLimiter() {
 		Addr:     "localhost:6379",
 		Password: "redis_password",
 	})
-	redisLimiter := limiter.NewShardedSlidingWindowLimiter(client, "limiter:", 2,
-		limiter.WithSlidingWindowLimit(2),
-		limiter.WithSlidingWindowTTL(time.Microsecond),
+	redisLimiter := limiter.NewShardedSlidingWindowLimiter(client, "limiter:",
+		limiter.WithShardedSlidingWindowCount(2),
+		limiter.WithShardedSlidingWindow(
+			limiter.WithSlidingWindowLimit(2),
+			limiter.WithSlidingWindowTTL(time.Microsecond),
+		),
 	)
 	ctx := context.Background()
 
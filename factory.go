@@ -0,0 +1,361 @@
+package limiter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Factory 支持的内置算法名，用于 LimitRule.Algorithm。
+const (
+	AlgorithmTokenBucket   = "token_bucket"
+	AlgorithmLeakyBucket   = "leaky_bucket"
+	AlgorithmSlidingWindow = "sliding_window"
+	AlgorithmFixedWindow   = "fixed_window"
+)
+
+// LimitRule 是一条完整的限流规则，Factory 根据它创建限流器，可以从 JSON 规则文件（ParseRules）
+// 或 YAML 规则文件（子包 config/yamlconfig）加载：
+//
+//	rules:
+//	  - name: api:/v1/chat
+//	    algorithm: token_bucket
+//	    rate: 100
+//	    capacity: 200
+//	    shards: 8
+//	  - name: sms
+//	    algorithm: sliding_window
+//	    prefix: sms
+//	    limit: 5
+//	    window_ms: 60000
+//
+// 参数字段与 LimitConfig 相同，因此同名规则之后可以通过 ConfigWatcher 热更新。零值参数使用算法的默认值。
+type LimitRule struct {
+	LimitConfig `yaml:",inline"`
+
+	// Algorithm 算法名，默认 token_bucket
+	Algorithm string `json:"algorithm,omitempty" yaml:"algorithm,omitempty"`
+	// Key Redis 中使用的业务 key，默认与 Name 相同
+	Key string `json:"key,omitempty" yaml:"key,omitempty"`
+	// Prefix Redis key 前缀，默认使用算法自己的前缀
+	Prefix string `json:"prefix,omitempty" yaml:"prefix,omitempty"`
+	// Shards 分片数，大于 1 时创建分片限流器（fixed_window 不支持分片）
+	Shards int `json:"shards,omitempty" yaml:"shards,omitempty"`
+	// Params 算法特有的参数，供 RegisterAlgorithm 注册的自定义算法使用，内置算法忽略
+	Params map[string]string `json:"params,omitempty" yaml:"params,omitempty"`
+}
+
+// EffectiveKey 返回规则在 Redis 中使用的业务 key：Key 非空时为 Key，否则为 Name。
+func (r LimitRule) EffectiveKey() string {
+	if r.Key != "" {
+		return r.Key
+	}
+	return r.Name
+}
+
+// validate 检查规则中不依赖具体算法的字段。
+func (r LimitRule) validate() error {
+	switch {
+	case r.Name == "":
+		return fmt.Errorf("factory: rule name is empty")
+	case r.Rate < 0 || r.Capacity < 0 || r.Limit < 0 || r.WindowMs < 0 || r.Shards < 0:
+		return fmt.Errorf("factory: rule %q has negative parameters", r.Name)
+	}
+	return nil
+}
+
+// AlgorithmBuilder 根据规则创建单桶限流器。传入的规则已通过通用校验（name 非空、参数非负），
+// 算法特有的参数可以放在 LimitRule.Params 中。
+type AlgorithmBuilder func(client *redis.Client, r LimitRule) (RateLimiter, error)
+
+// ShardedAlgorithmBuilder 根据规则创建分片限流器，规则的 Shards 大于 1 时使用。
+type ShardedAlgorithmBuilder func(client *redis.Client, r LimitRule) (RateShardedLimiter, error)
+
+// algorithm 描述一种可由 Factory 创建的算法，sharded 为 nil 表示不支持分片。
+type algorithm struct {
+	single  AlgorithmBuilder
+	sharded ShardedAlgorithmBuilder
+}
+
+var (
+	algorithmsMu sync.RWMutex
+	// algorithms 已注册的算法，初始为内置算法
+	algorithms = map[string]algorithm{
+		AlgorithmTokenBucket: {
+			single: func(client *redis.Client, r LimitRule) (RateLimiter, error) {
+				return NewTokenBucketLimiter(client, r.EffectiveKey(), tokenBucketRuleOptions(r)...), nil
+			},
+			sharded: func(client *redis.Client, r LimitRule) (RateShardedLimiter, error) {
+				return NewShardedTokenBucketLimiter(client, r.EffectiveKey(), WithShardedTokenBucketCount(r.Shards),
+					WithShardedTokenBucket(tokenBucketRuleOptions(r)...)), nil
+			},
+		},
+		AlgorithmLeakyBucket: {
+			single: func(client *redis.Client, r LimitRule) (RateLimiter, error) {
+				return NewLeakyBucketLimiter(client, r.EffectiveKey(), leakyBucketRuleOptions(r)...), nil
+			},
+			sharded: func(client *redis.Client, r LimitRule) (RateShardedLimiter, error) {
+				return NewShardedLeakyBucketLimiter(client, r.EffectiveKey(), WithShardedLeakyBucketCount(r.Shards),
+					WithShardedLeakyBucket(leakyBucketRuleOptions(r)...)), nil
+			},
+		},
+		AlgorithmSlidingWindow: {
+			single: func(client *redis.Client, r LimitRule) (RateLimiter, error) {
+				return NewSlidingWindowLimiter(client, r.EffectiveKey(), slidingWindowRuleOptions(r)...), nil
+			},
+			sharded: func(client *redis.Client, r LimitRule) (RateShardedLimiter, error) {
+				return NewShardedSlidingWindowLimiter(client, r.EffectiveKey(), WithShardedSlidingWindowCount(r.Shards),
+					WithShardedSlidingWindow(slidingWindowRuleOptions(r)...)), nil
+			},
+		},
+		AlgorithmFixedWindow: {
+			single: func(client *redis.Client, r LimitRule) (RateLimiter, error) {
+				opts := []FixedWindowOption{WithFixedWindowPrefix(r.Prefix)}
+				if r.Limit > 0 {
+					opts = append(opts, WithFixedWindowLimit(r.Limit))
+				}
+				if r.WindowMs > 0 {
+					opts = append(opts, WithFixedWindowWindow(time.Duration(r.WindowMs)*time.Millisecond))
+				}
+				return NewFixedWindowLimiter(client, r.EffectiveKey(), opts...), nil
+			},
+		},
+	}
+)
+
+// RegisterAlgorithm 注册名为 name 的算法，之后规则中的 algorithm: name 会通过 builder 创建限流器。
+// 同名时覆盖（包括内置算法）。通常在 init 中调用：
+//
+//	func init() {
+//		limiter.RegisterAlgorithm("gcra", func(client *redis.Client, r limiter.LimitRule) (limiter.RateLimiter, error) {
+//			return NewGCRALimiter(client, r.EffectiveKey(), r.Rate, r.Capacity), nil
+//		})
+//	}
+func RegisterAlgorithm(name string, builder AlgorithmBuilder) {
+	if name == "" {
+		panic("factory: algorithm name is empty")
+	}
+	if builder == nil {
+		panic("factory: algorithm builder is nil")
+	}
+	algorithmsMu.Lock()
+	defer algorithmsMu.Unlock()
+	a := algorithms[name]
+	a.single = builder
+	algorithms[name] = a
+}
+
+// RegisterShardedAlgorithm 为名为 name 的算法注册分片版本，用于 Shards 大于 1 的规则。
+// 只注册了分片版本的算法不能用于 Shards 不大于 1 的规则。
+func RegisterShardedAlgorithm(name string, builder ShardedAlgorithmBuilder) {
+	if name == "" {
+		panic("factory: algorithm name is empty")
+	}
+	if builder == nil {
+		panic("factory: algorithm builder is nil")
+	}
+	algorithmsMu.Lock()
+	defer algorithmsMu.Unlock()
+	a := algorithms[name]
+	a.sharded = builder
+	algorithms[name] = a
+}
+
+// Algorithms 返回已注册的全部算法名（包括内置算法），按名称排序。
+func Algorithms() []string {
+	algorithmsMu.RLock()
+	names := make([]string, 0, len(algorithms))
+	for name := range algorithms {
+		names = append(names, name)
+	}
+	algorithmsMu.RUnlock()
+
+	sort.Strings(names)
+	return names
+}
+
+func tokenBucketRuleOptions(r LimitRule) []TokenBucketOption {
+	opts := []TokenBucketOption{WithTokenBucketPrefix(r.Prefix)}
+	if r.Rate > 0 {
+		opts = append(opts, WithTokenBucketRate(r.Rate))
+	}
+	if r.Capacity > 0 {
+		opts = append(opts, WithTokenBucketCapacity(r.Capacity))
+	}
+	return opts
+}
+
+func leakyBucketRuleOptions(r LimitRule) []LeakyBucketOption {
+	opts := []LeakyBucketOption{WithLeakyBucketPrefix(r.Prefix)}
+	if r.Rate > 0 {
+		opts = append(opts, WithLeakyBucketRate(r.Rate))
+	}
+	if r.Capacity > 0 {
+		opts = append(opts, WithLeakyBucketCapacity(r.Capacity))
+	}
+	return opts
+}
+
+func slidingWindowRuleOptions(r LimitRule) []SlidingWindowOption {
+	opts := []SlidingWindowOption{WithSlidingWindowPrefix(r.Prefix)}
+	if r.Limit > 0 {
+		opts = append(opts, WithSlidingWindowLimit(r.Limit))
+	}
+	if r.WindowMs > 0 {
+		opts = append(opts, WithSlidingWindowWindow(time.Duration(r.WindowMs)*time.Millisecond))
+	}
+	return opts
+}
+
+// Factory 根据 LimitRule 创建限流器，用于从配置文件批量构建所有限流规则。
+type Factory struct {
+	client *redis.Client
+
+	// DefaultAlgorithm 规则未指定 Algorithm 时使用的算法，默认 token_bucket
+	DefaultAlgorithm string
+}
+
+// NewFactory 创建一个使用 client 的限流器工厂。
+func NewFactory(client *redis.Client, opts ...FactoryOption) *Factory {
+	if client == nil {
+		panic("factory: redis client is nil")
+	}
+
+	f := &Factory{
+		client:           client,
+		DefaultAlgorithm: AlgorithmTokenBucket,
+	}
+	for _, opt := range opts {
+		opt(f)
+	}
+	return f
+}
+
+// algorithm 查找规则使用的算法，同时返回算法名。
+func (f *Factory) algorithm(r LimitRule) (algorithm, string, error) {
+	if err := r.validate(); err != nil {
+		return algorithm{}, "", err
+	}
+	name := r.Algorithm
+	if name == "" {
+		name = f.DefaultAlgorithm
+	}
+	algorithmsMu.RLock()
+	a, ok := algorithms[name]
+	algorithmsMu.RUnlock()
+	if !ok {
+		return algorithm{}, name, fmt.Errorf("factory: rule %q: unknown algorithm %q", r.Name, name)
+	}
+	return a, name, nil
+}
+
+// Create 根据规则创建单桶限流器。Shards 大于 1 的规则请使用 CreateSharded。
+func (f *Factory) Create(r LimitRule) (RateLimiter, error) {
+	a, name, err := f.algorithm(r)
+	if err != nil {
+		return nil, err
+	}
+	if r.Shards > 1 {
+		return nil, fmt.Errorf("factory: rule %q is sharded, use CreateSharded", r.Name)
+	}
+	if a.single == nil {
+		return nil, fmt.Errorf("factory: rule %q: algorithm %q only supports sharding", r.Name, name)
+	}
+	return a.single(f.client, r)
+}
+
+// CreateSharded 根据规则创建分片限流器，Shards 为 0 时使用分片限流器的默认分片数。
+func (f *Factory) CreateSharded(r LimitRule) (RateShardedLimiter, error) {
+	a, name, err := f.algorithm(r)
+	if err != nil {
+		return nil, err
+	}
+	if a.sharded == nil {
+		return nil, fmt.Errorf("factory: rule %q: algorithm %q does not support sharding", r.Name, name)
+	}
+	return a.sharded(f.client, r)
+}
+
+// LimiterSet 是 Factory.CreateAll 按规则名创建的一组限流器。
+type LimiterSet struct {
+	// Limiters Shards 不大于 1 的规则创建的单桶限流器
+	Limiters map[string]RateLimiter
+	// Sharded Shards 大于 1 的规则创建的分片限流器
+	Sharded map[string]RateShardedLimiter
+}
+
+// CreateAll 按规则批量创建限流器：Shards 大于 1 的规则创建分片限流器，其余创建单桶限流器。
+// 任意一条规则无效或规则名重复时返回错误，不会返回部分结果。
+func (f *Factory) CreateAll(rules []LimitRule) (*LimiterSet, error) {
+	set := &LimiterSet{
+		Limiters: make(map[string]RateLimiter),
+		Sharded:  make(map[string]RateShardedLimiter),
+	}
+	seen := make(map[string]struct{}, len(rules))
+	for _, r := range rules {
+		if _, ok := seen[r.Name]; ok {
+			return nil, fmt.Errorf("factory: duplicate rule %q", r.Name)
+		}
+		seen[r.Name] = struct{}{}
+
+		if r.Shards > 1 {
+			l, err := f.CreateSharded(r)
+			if err != nil {
+				return nil, err
+			}
+			set.Sharded[r.Name] = l
+			continue
+		}
+		l, err := f.Create(r)
+		if err != nil {
+			return nil, err
+		}
+		set.Limiters[r.Name] = l
+	}
+	return set, nil
+}
+
+// ParseRules 解析 JSON 格式的规则文件内容。
+// 顶层可以是规则数组，也可以是带 rules 字段的对象（见 LimitRule）。
+// YAML 规则文件使用子包 config/yamlconfig，根包不依赖任何 YAML 库。
+func ParseRules(data []byte) ([]LimitRule, error) {
+	return ParseRulesWith(data, json.Unmarshal)
+}
+
+// ParseRulesWith 使用 unmarshal 解析规则文件内容，用于接入 JSON 以外的格式，例如：
+//
+//	rules, err := limiter.ParseRulesWith(data, toml.Unmarshal)
+//
+// 顶层结构与 ParseRules 相同，字段名取 LimitRule 的 json / yaml tag。内容为空时返回空规则。
+func ParseRulesWith(data []byte, unmarshal func([]byte, any) error) ([]LimitRule, error) {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, nil
+	}
+
+	var rules []LimitRule
+	if err := unmarshal(data, &rules); err == nil {
+		return rules, nil
+	}
+	var file struct {
+		Rules []LimitRule `json:"rules" yaml:"rules"`
+	}
+	if err := unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("factory: parse rules: %w", err)
+	}
+	return file.Rules, nil
+}
+
+// LoadRules 读取并解析 JSON 规则文件，格式见 ParseRules。
+func LoadRules(path string) ([]LimitRule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseRules(data)
+}
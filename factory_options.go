@@ -0,0 +1,13 @@
+package limiter
+
+// FactoryOption 为限流器工厂的配置项。
+type FactoryOption func(*Factory)
+
+// WithFactoryDefaultAlgorithm 设置规则未指定 Algorithm 时使用的算法，默认 token_bucket。
+func WithFactoryDefaultAlgorithm(name string) FactoryOption {
+	return func(f *Factory) {
+		if name != "" {
+			f.DefaultAlgorithm = name
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package limiter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRules(t *testing.T) {
+	jsonRules := []byte(`{"rules": [
+		{"name": "chat", "rate": 100, "capacity": 200, "shards": 4},
+		{"name": "sms", "algorithm": "sliding_window", "prefix": "sms", "limit": 5, "window_ms": 60000}
+	]}`)
+	rules, err := ParseRules(jsonRules)
+	assert.NoError(t, err)
+	assert.Equal(t, []LimitRule{
+		{LimitConfig: LimitConfig{Name: "chat", Rate: 100, Capacity: 200}, Shards: 4},
+		{LimitConfig: LimitConfig{Name: "sms", Limit: 5, WindowMs: 60000}, Algorithm: AlgorithmSlidingWindow, Prefix: "sms"},
+	}, rules)
+
+	// 顶层数组同样支持
+	rules, err = ParseRules([]byte(`[{"name": "sms", "algorithm": "sliding_window", "limit": 5}]`))
+	assert.NoError(t, err)
+	assert.Equal(t, []LimitRule{
+		{LimitConfig: LimitConfig{Name: "sms", Limit: 5}, Algorithm: AlgorithmSlidingWindow},
+	}, rules)
+
+	path := filepath.Join(t.TempDir(), "rules.json")
+	assert.NoError(t, os.WriteFile(path, jsonRules, 0o644))
+	rules, err = LoadRules(path)
+	assert.NoError(t, err)
+	assert.Len(t, rules, 2)
+
+	rules, err = ParseRules([]byte("  \n"))
+	assert.NoError(t, err)
+	assert.Empty(t, rules)
+	_, err = ParseRules([]byte(`{"rules": [1, 2`))
+	assert.Error(t, err)
+
+	// 其他格式通过 ParseRulesWith 接入
+	var seen []byte
+	_, err = ParseRulesWith([]byte("custom"), func(data []byte, v any) error {
+		seen = data
+		return json.Unmarshal([]byte(`[{"name": "x"}]`), v)
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "custom", string(seen))
+}
+
+func TestFactory(t *testing.T) {
+	client := newSpecClient(t)
+	ctx := context.Background()
+	f := NewFactory(client)
+
+	set, err := f.CreateAll([]LimitRule{
+		{LimitConfig: LimitConfig{Name: "chat", Rate: 1, Capacity: 2}},
+		{LimitConfig: LimitConfig{Name: "upload", Rate: 1, Capacity: 2}, Algorithm: AlgorithmLeakyBucket, Key: "files"},
+		{LimitConfig: LimitConfig{Name: "sms", Limit: 1, WindowMs: 60000}, Algorithm: AlgorithmSlidingWindow, Prefix: "sms"},
+		{LimitConfig: LimitConfig{Name: "login", Limit: 3, WindowMs: 60000}, Algorithm: AlgorithmFixedWindow},
+		{LimitConfig: LimitConfig{Name: "api", Rate: 8, Capacity: 8}, Shards: 4},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, set.Limiters, 4)
+	assert.Len(t, set.Sharded, 1)
+
+	tb := set.Limiters["chat"].(*TokenBucketLimiter)
+	assert.Equal(t, 1.0, tb.Rate)
+	assert.Equal(t, 2.0, tb.Capacity)
+	assert.Equal(t, "files", set.Limiters["upload"].(*LeakyBucketLimiter).Key)
+	sw := set.Limiters["sms"].(*SingleSlidingWindowLimiter)
+	assert.Equal(t, "sms", sw.Prefix)
+	assert.Equal(t, time.Minute, sw.Window)
+
+	ok, err := sw.Allow(ctx)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = sw.Allow(ctx)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	st, err := set.Sharded["api"].State(ctx, "u1")
+	assert.NoError(t, err)
+	assert.Equal(t, 2.0, st.Capacity)
+
+	for _, rules := range [][]LimitRule{
+		{{LimitConfig: LimitConfig{Rate: 1}}},
+		{{LimitConfig: LimitConfig{Name: "a", Rate: -1}}},
+		{{LimitConfig: LimitConfig{Name: "a"}, Algorithm: "gcra"}},
+		{{LimitConfig: LimitConfig{Name: "a"}, Algorithm: AlgorithmFixedWindow, Shards: 2}},
+		{{LimitConfig: LimitConfig{Name: "a"}}, {LimitConfig: LimitConfig{Name: "a"}}},
+	} {
+		_, err := f.CreateAll(rules)
+		assert.Error(t, err, "%+v", rules)
+	}
+
+	_, err = f.Create(LimitRule{LimitConfig: LimitConfig{Name: "a"}, Shards: 2})
+	assert.Error(t, err)
+
+	f = NewFactory(client, WithFactoryDefaultAlgorithm(AlgorithmFixedWindow))
+	l, err := f.Create(LimitRule{LimitConfig: LimitConfig{Name: "a"}})
+	assert.NoError(t, err)
+	assert.IsType(t, &FixedWindowLimiter{}, l)
+}
+
+func TestRegisterAlgorithm(t *testing.T) {
+	client := newSpecClient(t)
+	t.Cleanup(func() {
+		algorithmsMu.Lock()
+		delete(algorithms, "strict_bucket")
+		algorithmsMu.Unlock()
+	})
+
+	// 自定义算法：容量固定为 1 的令牌桶，速率取自 params
+	RegisterAlgorithm("strict_bucket", func(client *redis.Client, r LimitRule) (RateLimiter, error) {
+		rate, err := strconv.ParseFloat(r.Params["per_second"], 64)
+		if err != nil {
+			return nil, fmt.Errorf("strict_bucket: invalid per_second: %w", err)
+		}
+		return NewTokenBucketLimiter(client, r.EffectiveKey(),
+			WithTokenBucketRate(rate), WithTokenBucketCapacity(1)), nil
+	})
+	assert.Contains(t, Algorithms(), "strict_bucket")
+	assert.Contains(t, Algorithms(), AlgorithmTokenBucket)
+
+	rules, err := ParseRules([]byte(`[{"name": "export", "algorithm": "strict_bucket", "params": {"per_second": "2"}}]`))
+	assert.NoError(t, err)
+	set, err := NewFactory(client).CreateAll(rules)
+	assert.NoError(t, err)
+	tb := set.Limiters["export"].(*TokenBucketLimiter)
+	assert.Equal(t, 2.0, tb.Rate)
+	assert.Equal(t, 1.0, tb.Capacity)
+
+	// builder 返回的错误原样返回；未注册分片版本的算法不支持分片
+	_, err = NewFactory(client).Create(LimitRule{LimitConfig: LimitConfig{Name: "x"}, Algorithm: "strict_bucket"})
+	assert.ErrorContains(t, err, "per_second")
+	_, err = NewFactory(client).CreateSharded(LimitRule{LimitConfig: LimitConfig{Name: "x"}, Algorithm: "strict_bucket", Shards: 2})
+	assert.Error(t, err)
+
+	assert.Panics(t, func() { RegisterAlgorithm("", nil) })
+	assert.Panics(t, func() { RegisterShardedAlgorithm("x", nil) })
+}
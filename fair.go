@@ -0,0 +1,178 @@
+package limiter
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// FairLimiter 为 Wait 提供 FIFO 公平排队。
+//
+// 普通 Wait 没有公平性：等待者各自按 RetryAfter 醒来重试，刚到的请求可能抢走
+// 一个已经等了很久的请求正要拿到的 token。FairLimiter 让每个 Wait 先在 Redis 中领取排队号：
+//   - 只有排在队首的等待者才会真正调用内部限流器，拿到许可后离队，下一位成为队首；
+//   - 其余等待者按前面排队的人数退避检查自己是否已经排到队首（PollInterval 乘以前面的人数，
+//     最长 MaxPollInterval）；配置了 Wakeup 时，每次有人离队都会立即唤醒等待者重新检查；
+//   - 排队号带有等待者的截止时间，等待者崩溃或未能正常离队时，过了截止时间会被自动清理。
+//
+// 排队在所有应用实例之间共享，因此跨实例也严格按领号顺序发放许可。
+// 注意：只有通过 FairLimiter.Wait 的请求参与排队，直接调用 Allow / AllowN 的请求不受排队约束。
+type FairLimiter struct {
+	RateLimiter
+
+	client *redis.Client
+
+	Key    string // 排队的业务 key，通常与内部限流器的 key 相同
+	Prefix string // Redis key 前缀，默认 "fifo"
+
+	// PollInterval 未排到队首时检查队列的基础间隔，实际间隔为 PollInterval 乘以前面排队的人数，默认 10ms
+	PollInterval time.Duration
+	// MaxPollInterval 未排到队首时检查队列的最长间隔，默认 1s
+	MaxPollInterval time.Duration
+	// Wakeup Pub/Sub 唤醒器（可选）。设置后每次有人离队都会唤醒本 key 上排队中的等待者，见 Wakeup。
+	Wakeup *Wakeup
+
+	// ServerTime 为 true（默认）时排队脚本使用 Redis TIME 计算排队号的截止时间与清理失联者，
+	// 所有应用实例共享同一个时钟源；为 false 时使用本机时钟。
+	ServerTime bool
+
+	// Clock 时钟，默认 SystemClock。
+	Clock Clock
+}
+
+// NewFairLimiter 在 l 之上创建 FIFO 公平排队的限流器，key 为排队使用的业务 key。
+func NewFairLimiter(client *redis.Client, key string, l RateLimiter, opts ...FairOption) *FairLimiter {
+	if client == nil {
+		panic("fair limiter: redis client is nil")
+	}
+	if key == "" {
+		panic("fair limiter: key is empty")
+	}
+	if l == nil {
+		panic("fair limiter: limiter is nil")
+	}
+
+	f := &FairLimiter{
+		RateLimiter:     l,
+		client:          client,
+		Key:             key,
+		Prefix:          "fifo",
+		PollInterval:    10 * time.Millisecond,
+		MaxPollInterval: time.Second,
+		ServerTime:      true,
+		Clock:           SystemClock,
+	}
+	for _, opt := range opts {
+		opt(f)
+	}
+	return f
+}
+
+// queueKey 返回排队号 ZSET 的 key。
+func (f *FairLimiter) queueKey() string {
+	return fmt.Sprintf("%s:{%s}:queue", f.Prefix, f.Key)
+}
+
+// leaseKey 返回记录各排队号截止时间的 ZSET key。
+func (f *FairLimiter) leaseKey() string {
+	return fmt.Sprintf("%s:{%s}:lease", f.Prefix, f.Key)
+}
+
+// seqKey 返回领号序列的 key。
+func (f *FairLimiter) seqKey() string {
+	return fmt.Sprintf("%s:{%s}:seq", f.Prefix, f.Key)
+}
+
+// fairMaxLease 为排队号的最长保留时间。
+const fairMaxLease = 24 * time.Hour
+
+// Wait 领取排队号，按领号顺序阻塞直到获得 1 个许可，或 ctx 取消 / 超过 maxWait。
+// 无论成功与否，返回前都会离队，不会阻塞后面的等待者。
+func (f *FairLimiter) Wait(ctx context.Context, maxWait time.Duration) error {
+	ticket, err := newLeaseToken()
+	if err != nil {
+		return err
+	}
+	maxWait = resolveMaxWait(ctx, maxWait)
+	deadline := time.Now().Add(maxWait)
+	// 多留 1s 余量，避免截止时间附近仍在请求内部限流器的队首被当作失联清理掉；
+	// 不限时等待（InfDuration）时排队号最多保留 fairMaxLease
+	lease := min(maxWait, fairMaxLease) + time.Second
+
+	defer func() {
+		// ctx 可能已经取消，离队使用独立的超时
+		lctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), time.Second)
+		defer cancel()
+		_ = f.leave(lctx, ticket)
+	}()
+
+	return waitForWake(ctx, f.Key, "fair", maxWait, nil, f.Wakeup, func(ctx context.Context) (Result, error) {
+		head, ahead, err := f.enqueue(ctx, ticket, lease)
+		if err != nil {
+			return Result{}, err
+		}
+		if !head {
+			return Result{RetryAfter: f.pollDelay(ahead, deadline)}, nil
+		}
+		return f.AllowWithResult(ctx)
+	})
+}
+
+// pollDelay 返回未排到队首时下一次检查前的等待时间：前面的人越多等得越久，
+// 但不超过 MaxPollInterval，也不越过 deadline（避免排队中途就被判定为等待超时）。
+func (f *FairLimiter) pollDelay(ahead int64, deadline time.Time) time.Duration {
+	d := f.MaxPollInterval
+	if ahead < int64(f.MaxPollInterval/f.PollInterval) {
+		d = f.PollInterval * time.Duration(max(ahead, 1))
+	}
+	if left := time.Until(deadline); left > 0 && d > left {
+		d = left
+	}
+	return d
+}
+
+// Queued 返回当前排队等待的人数（包含队首）。
+func (f *FairLimiter) Queued(ctx context.Context) (int64, error) {
+	return f.client.ZCard(ctx, f.queueKey()).Result()
+}
+
+// enqueue 领取（或保持）排队号，返回是否排在队首以及前面排队的人数。
+// 首次领号时排队号的截止时间为脚本中的当前时间加 lease。
+func (f *FairLimiter) enqueue(ctx context.Context, ticket string, lease time.Duration) (bool, int64, error) {
+	keys := []string{f.queueKey(), f.leaseKey(), f.seqKey()}
+	res, err := fairQueueScript.Run(ctx, f.client, keys,
+		scriptNow(f.ServerTime, f.Clock), ticket, lease.Milliseconds()).Result()
+	if err != nil {
+		return false, 0, err
+	}
+
+	vals, ok := scriptInts(res, 2)
+	if !ok {
+		return false, 0, fmt.Errorf("fair limiter: unexpected script result: %#v", res)
+	}
+	return vals[0] == 1, vals[1], nil
+}
+
+// leave 离队，并唤醒排在后面的等待者。
+func (f *FairLimiter) leave(ctx context.Context, ticket string) error {
+	_, err := f.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZRem(ctx, f.queueKey(), ticket)
+		pipe.ZRem(ctx, f.leaseKey(), ticket)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	f.Wakeup.notify(ctx, f.Key)
+	return nil
+}
+
+// Reset 清空排队队列并重置内部限流器。
+func (f *FairLimiter) Reset(ctx context.Context) error {
+	if err := f.client.Del(ctx, f.queueKey(), f.leaseKey(), f.seqKey()).Err(); err != nil {
+		return err
+	}
+	return f.RateLimiter.Reset(ctx)
+}
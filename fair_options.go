@@ -0,0 +1,60 @@
+package limiter
+
+import "time"
+
+// FairOption 为 FairLimiter 的配置项。
+type FairOption func(*FairLimiter)
+
+// WithFairPrefix 设置排队使用的 Redis key 前缀，默认 "fifo"。
+func WithFairPrefix(prefix string) FairOption {
+	return func(f *FairLimiter) {
+		if prefix != "" {
+			f.Prefix = prefix
+		}
+	}
+}
+
+// WithFairPollInterval 设置未排到队首时检查队列的基础间隔，默认 10ms。
+// 实际间隔为基础间隔乘以前面排队的人数：间隔越短，队首离队后下一位接手得越快，Redis 读压力也越大。
+func WithFairPollInterval(d time.Duration) FairOption {
+	return func(f *FairLimiter) {
+		if d < time.Millisecond {
+			panic("fair limiter: poll interval must >= 1ms")
+		}
+		f.PollInterval = d
+	}
+}
+
+// WithFairMaxPollInterval 设置未排到队首时检查队列的最长间隔，默认 1s。
+func WithFairMaxPollInterval(d time.Duration) FairOption {
+	return func(f *FairLimiter) {
+		if d < time.Millisecond {
+			panic("fair limiter: max poll interval must >= 1ms")
+		}
+		f.MaxPollInterval = d
+	}
+}
+
+// WithFairWakeup 设置 Pub/Sub 唤醒器：有人离队时立即唤醒排队中的等待者，不必等到下一次检查。
+func WithFairWakeup(w *Wakeup) FairOption {
+	return func(f *FairLimiter) {
+		f.Wakeup = w
+	}
+}
+
+// WithFairServerTime 设置排队脚本是否使用 Redis TIME 作为当前时间，默认开启。
+func WithFairServerTime(enabled bool) FairOption {
+	return func(f *FairLimiter) {
+		f.ServerTime = enabled
+	}
+}
+
+// WithFairClock 设置时钟并改用本机时钟（关闭 ServerTime），主要用于测试。
+func WithFairClock(c Clock) FairOption {
+	return func(f *FairLimiter) {
+		if c != nil {
+			f.Clock = c
+			f.ServerTime = false
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package limiter
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFairLimiter(t *testing.T) {
+	client := newSpecClient(t)
+	ctx := context.Background()
+
+	tb := NewTokenBucketLimiter(client, "fair", WithTokenBucketRate(20), WithTokenBucketCapacity(1))
+	f := NewFairLimiter(client, "fair", tb, WithFairPollInterval(2*time.Millisecond))
+	ok, _ := tb.Allow(ctx)
+	assert.True(t, ok)
+
+	// 依次排队的等待者严格按领号顺序拿到 token
+	var (
+		mu    sync.Mutex
+		order []int
+		wg    sync.WaitGroup
+	)
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, f.Wait(ctx, time.Second))
+			mu.Lock()
+			order = append(order, i)
+			mu.Unlock()
+		}()
+		assert.Eventually(t, func() bool {
+			n, _ := f.Queued(ctx)
+			return n == int64(i+1)
+		}, time.Second, time.Millisecond)
+	}
+	wg.Wait()
+	assert.Equal(t, []int{0, 1, 2}, order)
+
+	n, err := f.Queued(ctx)
+	assert.NoError(t, err)
+	assert.Zero(t, n)
+
+	// 失联等待者的排队号过了截止时间后被清理，不会阻塞队列
+	_, _, err = f.enqueue(ctx, "ghost", -time.Second)
+	assert.NoError(t, err)
+	assert.NoError(t, f.Wait(ctx, time.Second))
+
+	// 超时的等待者同样会离队
+	assert.ErrorIs(t, f.Wait(ctx, 0), ErrLimiter)
+	n, _ = f.Queued(ctx)
+	assert.Zero(t, n)
+
+	assert.NoError(t, f.Reset(ctx))
+	ok, _ = f.Allow(ctx)
+	assert.True(t, ok)
+}
+
+func TestFairLimiter_ServerTime(t *testing.T) {
+	client := newSpecClient(t)
+	ctx := context.Background()
+
+	// 两个实例本机时钟相差 1 小时，但排队号的截止时间与清理都使用 Redis TIME，
+	// 时钟快的实例不会把其他实例仍在等待的排队号当作失联清理掉
+	tb := NewTokenBucketLimiter(client, "fair-skew", WithTokenBucketRate(1), WithTokenBucketCapacity(1))
+	a := NewFairLimiter(client, "fair-skew", tb)
+	b := NewFairLimiter(client, "fair-skew", tb,
+		WithFairClock(ClockFunc(func() time.Time { return time.Now().Add(time.Hour) })), WithFairServerTime(true))
+
+	head, _, err := a.enqueue(ctx, "a", 10*time.Second)
+	assert.NoError(t, err)
+	assert.True(t, head)
+
+	head, ahead, err := b.enqueue(ctx, "b", 10*time.Second)
+	assert.NoError(t, err)
+	assert.False(t, head)
+	assert.Equal(t, int64(1), ahead)
+}
+
+func TestFairLimiter_PollDelay(t *testing.T) {
+	client := newSpecClient(t)
+	f := NewFairLimiter(client, "fair-poll", NewTokenBucketLimiter(client, "fair-poll"),
+		WithFairPollInterval(10*time.Millisecond), WithFairMaxPollInterval(100*time.Millisecond))
+	deadline := time.Now().Add(time.Minute)
+
+	// 前面的人越多检查得越慢，但不超过 MaxPollInterval，也不越过截止时间
+	assert.Equal(t, 10*time.Millisecond, f.pollDelay(1, deadline))
+	assert.Equal(t, 50*time.Millisecond, f.pollDelay(5, deadline))
+	assert.Equal(t, 100*time.Millisecond, f.pollDelay(1000, deadline))
+	assert.LessOrEqual(t, f.pollDelay(1000, time.Now().Add(20*time.Millisecond)), 20*time.Millisecond)
+}
+
+func TestFairLimiter_Wakeup(t *testing.T) {
+	client := newSpecClient(t)
+	ctx := context.Background()
+
+	// 检查间隔很长，只有离队时的唤醒才能让下一位及时拿到 token
+	w := NewWakeup(client)
+	defer w.Close()
+	tb := NewTokenBucketLimiter(client, "fair-wake", WithTokenBucketRate(1000), WithTokenBucketCapacity(1))
+	f := NewFairLimiter(client, "fair-wake", tb, WithFairWakeup(w),
+		WithFairPollInterval(time.Minute), WithFairMaxPollInterval(time.Minute))
+
+	_, _, err := f.enqueue(ctx, "holder", 10*time.Second)
+	assert.NoError(t, err)
+
+	done := make(chan error, 1)
+	go func() { done <- f.Wait(ctx, 5*time.Second) }()
+	assert.Eventually(t, func() bool {
+		n, _ := f.Queued(ctx)
+		return n == 2
+	}, time.Second, time.Millisecond)
+
+	// 等待者订阅后才离队，确保唤醒消息能被收到
+	assert.Eventually(t, func() bool {
+		m, _ := client.PubSubNumSub(ctx, w.channel("fair-wake")).Result()
+		return m[w.channel("fair-wake")] == 1
+	}, time.Second, time.Millisecond)
+
+	start := time.Now()
+	assert.NoError(t, f.leave(ctx, "holder"))
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+		assert.Less(t, time.Since(start), time.Second)
+	case <-time.After(3 * time.Second):
+		t.Fatal("waiter was not woken up")
+	}
+}
@@ -0,0 +1,263 @@
+package limiter
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// FallbackLimiter 在 Redis 故障期间降级为进程内令牌桶：
+//   - Redis 调用失败时，本次请求改由本地令牌桶判定，调用方不会看到 Redis 错误；
+//   - 连续失败达到 FailureThreshold 次后进入降级模式，不再每次访问 Redis，
+//     只每隔 ProbeInterval 探测一次（同一时刻只有一个请求去探测），探测成功后切回 Redis；
+//   - 本地令牌桶默认沿用被包装限流器的速率和容量（多实例部署时，
+//     可以通过 WithFallbackRate 设置为“全局速率 / 实例数”）。
+//
+// 降级期间只能做到近似整形，但比起全部放行或全部拒绝要安全得多。
+type FallbackLimiter struct {
+	limiter RateLimiter
+
+	// Key 被包装限流器的业务 key，用于本地状态与等待事件，默认从被包装的内置限流器读取
+	Key string
+
+	// Rate 本地令牌桶速率（token/sec）
+	Rate float64
+	// Capacity 本地令牌桶容量
+	Capacity float64
+	// FailureThreshold 连续失败多少次后进入降级模式，默认 3
+	FailureThreshold int
+	// ProbeInterval 降级期间探测 Redis 的间隔，默认 5s
+	ProbeInterval time.Duration
+	// OnStateChange 进入/退出降级模式时的回调（可选），err 为触发降级的最后一次错误
+	OnStateChange func(degraded bool, err error)
+
+	// Clock 本地令牌桶与探测间隔使用的时钟，默认 SystemClock
+	Clock Clock
+
+	mu        sync.Mutex
+	failures  int
+	degraded  bool
+	nextProbe time.Time
+	probing   bool
+	tokens    float64
+	last      time.Time
+}
+
+var _ RateLimiter = (*FallbackLimiter)(nil)
+
+// NewFallbackLimiter 为 l 包装本地降级能力。
+// 本地速率默认从 l 的配置推导（支持本包内置的单桶限流器），其他实现必须通过 WithFallbackRate 指定。
+func NewFallbackLimiter(l RateLimiter, opts ...FallbackOption) *FallbackLimiter {
+	if l == nil {
+		panic("fallback: limiter is nil")
+	}
+
+	f := &FallbackLimiter{
+		limiter:          l,
+		FailureThreshold: 3,
+		ProbeInterval:    5 * time.Second,
+		Clock:            SystemClock,
+	}
+	f.Rate, f.Capacity = mirrorRate(l)
+	f.Key = mirrorKey(l)
+
+	for _, opt := range opts {
+		opt(f)
+	}
+	if f.Rate <= 0 || f.Capacity <= 0 {
+		panic("fallback: local rate and capacity must > 0")
+	}
+
+	f.tokens = f.Capacity
+	f.last = f.Clock.Now()
+	return f
+}
+
+// mirrorRate 从内置限流器的配置推导本地令牌桶的速率和容量。
+func mirrorRate(l RateLimiter) (rate, capacity float64) {
+	switch v := l.(type) {
+	case *TokenBucketLimiter:
+		return v.Rate, v.Capacity
+	case *LeakyBucketLimiter:
+		return v.LeakRate, v.Capacity
+	case *SingleSlidingWindowLimiter:
+		return float64(v.Limit) / v.Window.Seconds(), float64(v.Limit)
+	case *OverageLimiter:
+		return float64(v.HardLimit) / v.Window.Seconds(), float64(v.HardLimit)
+	}
+	return 0, 0
+}
+
+// mirrorKey 返回内置限流器的业务 key，其他实现返回空字符串。
+func mirrorKey(l RateLimiter) string {
+	switch v := l.(type) {
+	case *TokenBucketLimiter:
+		return v.Key
+	case *LeakyBucketLimiter:
+		return v.Key
+	case *SingleSlidingWindowLimiter:
+		return v.Key
+	case *OverageLimiter:
+		return v.Key
+	}
+	return ""
+}
+
+// Degraded 返回当前是否处于降级（本地限流）模式。
+func (f *FallbackLimiter) Degraded() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.degraded
+}
+
+func (f *FallbackLimiter) Allow(ctx context.Context) (bool, error) {
+	res, err := f.AllowWithResult(ctx)
+	return res.Allowed, err
+}
+
+func (f *FallbackLimiter) AllowWithResult(ctx context.Context) (Result, error) {
+	return f.run(ctx, 1)
+}
+
+func (f *FallbackLimiter) AllowN(ctx context.Context, n int64) (bool, error) {
+	res, err := f.run(ctx, n)
+	return res.Allowed, err
+}
+
+func (f *FallbackLimiter) Wait(ctx context.Context, maxWait time.Duration) error {
+	return waitFor(ctx, f.Key, "fallback", maxWait, nil, f.AllowWithResult)
+}
+
+// State 正常时返回 Redis 中的状态；降级期间或 Redis 出错时返回本地令牌桶的状态。
+// Source 与 Degraded 一致：降级期间为 SourceLocal，否则为 SourceRedis（即使本次读取 Redis 状态失败，
+// 返回了本地令牌桶的快照，判定仍然优先走 Redis）。
+func (f *FallbackLimiter) State(ctx context.Context) (LimiterState, error) {
+	if !f.Degraded() {
+		if st, err := f.limiter.State(ctx); err == nil {
+			st.Source = SourceRedis
+			return st, nil
+		}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	source := SourceRedis
+	if f.degraded {
+		source = SourceLocal
+	}
+	now := f.Clock.Now()
+	f.refill(now)
+	return LimiterState{
+		Level:       f.tokens,
+		Remaining:   math.Floor(f.tokens),
+		Capacity:    f.Capacity,
+		Rate:        f.Rate,
+		LastUpdated: now.UnixMilli(),
+		Type:        "fallback_local",
+		Key:         f.Key,
+		Source:      source,
+	}, nil
+}
+
+// Reset 重置 Redis 中的状态，同时把本地令牌桶恢复为满桶。
+func (f *FallbackLimiter) Reset(ctx context.Context) error {
+	f.mu.Lock()
+	f.tokens = f.Capacity
+	f.last = f.Clock.Now()
+	f.mu.Unlock()
+	return f.limiter.Reset(ctx)
+}
+
+// run 优先使用 Redis 判定，失败或处于降级模式时使用本地令牌桶。
+// 降级期间到了探测时间后只放一个请求去访问 Redis，探测结束前其余请求继续走本地令牌桶。
+func (f *FallbackLimiter) run(ctx context.Context, n int64) (Result, error) {
+	if n <= 0 {
+		return Result{}, fmt.Errorf("fallback: n must > 0")
+	}
+
+	f.mu.Lock()
+	probe := f.degraded
+	if probe && (f.probing || f.Clock.Now().Before(f.nextProbe)) {
+		defer f.mu.Unlock()
+		return f.local(n), nil
+	}
+	f.probing = probe
+	f.mu.Unlock()
+
+	res, err := f.remote(ctx, n)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if probe {
+		f.probing = false
+	}
+	if err == nil {
+		f.failures = 0
+		if f.degraded {
+			f.degraded = false
+			f.notify(false, nil)
+		}
+		return res, nil
+	}
+
+	// ctx 被取消不代表 Redis 故障，直接返回
+	if ctx.Err() != nil {
+		return Result{}, err
+	}
+
+	f.failures++
+	if f.degraded || f.failures >= f.FailureThreshold {
+		f.nextProbe = f.Clock.Now().Add(f.ProbeInterval)
+		if !f.degraded {
+			f.degraded = true
+			f.notify(true, err)
+		}
+	}
+	return f.local(n), nil
+}
+
+// remote 使用被包装的限流器判定，尽量通过一次调用同时拿到 Remaining / RetryAfter / Limit。
+func (f *FallbackLimiter) remote(ctx context.Context, n int64) (Result, error) {
+	if l, ok := f.limiter.(interface {
+		AllowNWithResult(ctx context.Context, n int64) (Result, error)
+	}); ok {
+		return l.AllowNWithResult(ctx, n)
+	}
+	if n == 1 {
+		return f.limiter.AllowWithResult(ctx)
+	}
+	ok, err := f.limiter.AllowN(ctx, n)
+	return Result{Allowed: ok}, err
+}
+
+// local 使用本地令牌桶判定，调用方需持有锁。
+func (f *FallbackLimiter) local(n int64) Result {
+	f.refill(f.Clock.Now())
+
+	res := Result{Limit: f.Capacity}
+	req := float64(n)
+	if f.tokens >= req {
+		f.tokens -= req
+		res.Allowed = true
+	} else {
+		res.RetryAfter = time.Duration(math.Ceil((req-f.tokens)*1000/f.Rate)) * time.Millisecond
+	}
+	res.Remaining = math.Floor(f.tokens)
+	return res
+}
+
+// refill 按流逝时间补充本地令牌，调用方需持有锁。
+func (f *FallbackLimiter) refill(now time.Time) {
+	if elapsed := now.Sub(f.last).Seconds(); elapsed > 0 {
+		f.tokens = math.Min(f.Capacity, f.tokens+elapsed*f.Rate)
+		f.last = now
+	}
+}
+
+func (f *FallbackLimiter) notify(degraded bool, err error) {
+	if f.OnStateChange != nil {
+		f.OnStateChange(degraded, err)
+	}
+}
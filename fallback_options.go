@@ -0,0 +1,59 @@
+package limiter
+
+import "time"
+
+// FallbackOption 为降级限流器的配置项。
+type FallbackOption func(*FallbackLimiter)
+
+// WithFallbackRate 设置本地令牌桶的速率和容量，覆盖从被包装限流器推导出的值。
+func WithFallbackRate(rate, capacity float64) FallbackOption {
+	return func(f *FallbackLimiter) {
+		if rate <= 0 || capacity <= 0 {
+			panic("fallback: rate and capacity must > 0")
+		}
+		f.Rate = rate
+		f.Capacity = capacity
+	}
+}
+
+// WithFallbackKey 设置被包装限流器的业务 key，用于本地状态与等待事件；
+// 包装非内置限流器时需要通过它指定。
+func WithFallbackKey(key string) FallbackOption {
+	return func(f *FallbackLimiter) {
+		f.Key = key
+	}
+}
+
+// WithFallbackFailureThreshold 设置连续失败多少次后进入降级模式。
+func WithFallbackFailureThreshold(n int) FallbackOption {
+	return func(f *FallbackLimiter) {
+		if n > 0 {
+			f.FailureThreshold = n
+		}
+	}
+}
+
+// WithFallbackProbeInterval 设置降级期间探测 Redis 的间隔。
+func WithFallbackProbeInterval(d time.Duration) FallbackOption {
+	return func(f *FallbackLimiter) {
+		if d > 0 {
+			f.ProbeInterval = d
+		}
+	}
+}
+
+// WithFallbackOnStateChange 设置进入/退出降级模式时的回调。
+func WithFallbackOnStateChange(fn func(degraded bool, err error)) FallbackOption {
+	return func(f *FallbackLimiter) {
+		f.OnStateChange = fn
+	}
+}
+
+// WithFallbackClock 设置时钟，通常用于测试中控制本地令牌桶的 refill 与探测时间。
+func WithFallbackClock(c Clock) FallbackOption {
+	return func(f *FallbackLimiter) {
+		if c != nil {
+			f.Clock = c
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package limiter
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redismock/v8"
+	"github.com/stretchr/testify/assert"
+)
+
+// flakyLimiter 在 down 为 true 时返回错误，否则全部放行。
+type flakyLimiter struct {
+	down  bool
+	calls int
+}
+
+func (f *flakyLimiter) Allow(ctx context.Context) (bool, error) { return f.AllowN(ctx, 1) }
+
+func (f *flakyLimiter) AllowWithResult(ctx context.Context) (Result, error) {
+	ok, err := f.AllowN(ctx, 1)
+	return Result{Allowed: ok}, err
+}
+
+func (f *flakyLimiter) AllowN(context.Context, int64) (bool, error) {
+	f.calls++
+	if f.down {
+		return false, errors.New("redis down")
+	}
+	return true, nil
+}
+
+func (f *flakyLimiter) Wait(context.Context, time.Duration) error { return nil }
+
+func (f *flakyLimiter) State(context.Context) (LimiterState, error) { return LimiterState{}, nil }
+
+func (f *flakyLimiter) Reset(context.Context) error { return nil }
+
+func TestFallbackLimiter(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1000, 0)
+	remote := &flakyLimiter{down: true}
+
+	var changes []bool
+	f := NewFallbackLimiter(remote,
+		WithFallbackRate(1, 2),
+		WithFallbackFailureThreshold(2),
+		WithFallbackProbeInterval(time.Second),
+		WithFallbackOnStateChange(func(degraded bool, err error) {
+			changes = append(changes, degraded)
+		}),
+		WithFallbackClock(ClockFunc(func() time.Time { return now })),
+	)
+
+	// 判定来源与 Degraded 保持一致
+	source := func() string {
+		st, err := f.State(ctx)
+		assert.NoError(t, err)
+		return st.Source
+	}
+	assert.Equal(t, SourceRedis, source())
+
+	// 前两次失败：本地判定，第二次进入降级
+	for i := 0; i < 2; i++ {
+		ok, err := f.Allow(ctx)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+	}
+	assert.True(t, f.Degraded())
+	assert.Equal(t, SourceLocal, source())
+	assert.Equal(t, 2, remote.calls)
+
+	// 降级期间不访问 Redis，本地桶已耗尽
+	res, err := f.AllowWithResult(ctx)
+	assert.NoError(t, err)
+	assert.False(t, res.Allowed)
+	assert.Equal(t, time.Second, res.RetryAfter)
+	assert.Equal(t, 2, remote.calls)
+
+	// 探测失败：继续降级，并从本地桶补充的令牌中放行
+	now = now.Add(time.Second)
+	ok, _ := f.Allow(ctx)
+	assert.True(t, ok)
+	assert.Equal(t, 3, remote.calls)
+	assert.True(t, f.Degraded())
+
+	// Redis 恢复后，下一次探测切回
+	remote.down = false
+	now = now.Add(time.Second)
+	ok, _ = f.Allow(ctx)
+	assert.True(t, ok)
+	assert.False(t, f.Degraded())
+	assert.Equal(t, SourceRedis, source())
+	assert.Equal(t, []bool{true, false}, changes)
+}
+
+func TestFallbackLimiter_MirrorRate(t *testing.T) {
+	db, _ := redismock.NewClientMock()
+	tb := NewTokenBucketLimiter(db, "mirror", WithTokenBucketRate(10), WithTokenBucketCapacity(20))
+	f := NewFallbackLimiter(tb)
+	assert.Equal(t, 10.0, f.Rate)
+	assert.Equal(t, 20.0, f.Capacity)
+
+	assert.Panics(t, func() { NewFallbackLimiter(&flakyLimiter{}) })
+}
+
+// blockingLimiter 在 release 关闭前阻塞每一次判定，用于观察并发探测。
+type blockingLimiter struct {
+	flakyLimiter
+	entered chan struct{}
+	release chan struct{}
+}
+
+func (b *blockingLimiter) AllowWithResult(ctx context.Context) (Result, error) {
+	b.entered <- struct{}{}
+	<-b.release
+	return b.flakyLimiter.AllowWithResult(ctx)
+}
+
+func TestFallbackLimiter_SingleProbe(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1000, 0)
+	remote := &blockingLimiter{entered: make(chan struct{}, 2), release: make(chan struct{})}
+	f := NewFallbackLimiter(remote, WithFallbackRate(1, 10), WithFallbackFailureThreshold(1),
+		WithFallbackProbeInterval(time.Second), WithFallbackClock(ClockFunc(func() time.Time { return now })))
+
+	// 进入降级
+	remote.down = true
+	close(remote.release)
+	_, _ = f.Allow(ctx)
+	<-remote.entered
+	assert.True(t, f.Degraded())
+
+	// 到了探测时间：第一个请求去探测并阻塞，探测期间的其他请求走本地令牌桶
+	remote.release = make(chan struct{})
+	remote.down = false
+	now = now.Add(time.Second)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = f.Allow(ctx)
+	}()
+	<-remote.entered
+	for i := 0; i < 3; i++ {
+		ok, err := f.Allow(ctx)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+	}
+	assert.Len(t, remote.entered, 0)
+	assert.True(t, f.Degraded())
+
+	close(remote.release)
+	<-done
+	assert.False(t, f.Degraded())
+}
+
+func TestFallbackLimiter_Remote(t *testing.T) {
+	client := newSpecClient(t)
+	ctx := context.Background()
+
+	tb := NewTokenBucketLimiter(client, "fallback-remote", WithTokenBucketRate(1), WithTokenBucketCapacity(10))
+	f := NewFallbackLimiter(tb)
+
+	_, err := f.AllowN(ctx, 0)
+	assert.Error(t, err)
+
+	// n > 1 时同样透传被包装限流器的 Remaining / RetryAfter / Limit
+	res, err := f.run(ctx, 4)
+	assert.NoError(t, err)
+	assert.True(t, res.Allowed)
+	assert.Equal(t, float64(6), res.Remaining)
+	assert.Equal(t, float64(10), res.Limit)
+
+	res, err = f.run(ctx, 8)
+	assert.NoError(t, err)
+	assert.False(t, res.Allowed)
+	assert.Greater(t, res.RetryAfter, time.Duration(0))
+
+	// 降级期间的本地状态同样带上被包装限流器的 key
+	f.mu.Lock()
+	f.degraded = true
+	f.mu.Unlock()
+	st, err := f.State(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "fallback_local", st.Type)
+	assert.Equal(t, "fallback-remote", st.Key)
+}
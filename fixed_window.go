@@ -0,0 +1,219 @@
+package limiter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// FixedWindowLimiter 实现“固定窗口计数”限流器。
+// 特点：
+//   - 每个 key 只占用一个计数器，内存与性能开销最小
+//   - 窗口从第一次计数开始，过期后自动重置
+//   - 窗口边界处可能出现最多 2*Limit 的突发，对边界敏感的场景请使用滑动窗口
+type FixedWindowLimiter struct {
+	client *redis.Client
+
+	Key    string        // 业务 key
+	Prefix string        // Redis key 前缀，默认 "fw"
+	Window time.Duration // 窗口大小，例如 1 * time.Minute（最小精度为毫秒）
+	Limit  int64         // 窗口内最大允许请求数
+
+	// WaitJitter Wait 重试前的随机抖动比例（0~1）
+	WaitJitter float64
+	// WaitStrategy Wait 被拒绝后的等待策略（可选），默认按 RetryAfter 等待并叠加 WaitJitter，见 WaitStrategy。
+	WaitStrategy WaitStrategy
+
+	// Clock 时钟，默认 SystemClock。
+	Clock Clock
+
+	// StatsTTL 按 key 统计放行 / 拒绝数量时计数器的过期时间（每次写入时刷新），0 表示不统计（默认）。
+	// 计数由限流脚本原子累加到 "<Prefix>:{Key}:stats:allowed" / "denied"，通过 Stats 读取。
+	StatsTTL time.Duration
+
+	// Hooks 判定事件钩子（可选），每次放行 / 拒绝 / 出错时回调，见 Hooks。
+	Hooks Hooks
+}
+
+// NewFixedWindowLimiter 创建一个固定窗口限流器。
+func NewFixedWindowLimiter(
+	client *redis.Client,
+	key string,
+	opts ...FixedWindowOption,
+) *FixedWindowLimiter {
+
+	if client == nil {
+		panic("fixed window: redis client is nil")
+	}
+	if key == "" {
+		panic("fixed window: key is empty")
+	}
+
+	l := &FixedWindowLimiter{
+		client: client,
+		Key:    key,
+		Prefix: "fw",
+		Window: 1 * time.Minute,
+		Limit:  60,
+		Clock:  SystemClock,
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// countKey 返回窗口计数 key。
+func (l *FixedWindowLimiter) countKey() string {
+	return fmt.Sprintf("%s:{%s}:count", l.Prefix, l.Key)
+}
+
+// Allow 尝试占一个名额。
+func (l *FixedWindowLimiter) Allow(ctx context.Context) (bool, error) {
+	return l.AllowN(ctx, 1)
+}
+
+// AllowWithResult 尝试占一个名额，并返回剩余名额及距离窗口结束的时间。
+func (l *FixedWindowLimiter) AllowWithResult(ctx context.Context) (Result, error) {
+	return l.AllowNWithResult(ctx, 1)
+}
+
+// AllowN 尝试一次占用 n 个名额，不足时整体拒绝。
+func (l *FixedWindowLimiter) AllowN(ctx context.Context, n int64) (bool, error) {
+	res, err := l.AllowNWithResult(ctx, n)
+	if err != nil {
+		return false, err
+	}
+	return res.Allowed, nil
+}
+
+// AllowNWithResult 尝试一次占用 n 个名额，并返回剩余名额及距离窗口结束的时间。
+func (l *FixedWindowLimiter) AllowNWithResult(ctx context.Context, n int64) (Result, error) {
+	res, err := l.eval(ctx, n, false)
+	fireHooks(ctx, l.Hooks, "fixed_window", l.Key, n, res, err)
+	return res, err
+}
+
+// Check 判断当前窗口是否还能占用 1 个名额，但不计数。
+func (l *FixedWindowLimiter) Check(ctx context.Context) (Result, error) {
+	return l.CheckN(ctx, 1)
+}
+
+// CheckN 判断当前窗口是否还能一次占用 n 个名额，但不计数。
+// 放行时 Remaining 为当前剩余名额（未扣除 n）。
+func (l *FixedWindowLimiter) CheckN(ctx context.Context, n int64) (Result, error) {
+	return l.eval(ctx, n, true)
+}
+
+// eval 执行固定窗口脚本；dryRun 为 true 时只判定不计数。
+func (l *FixedWindowLimiter) eval(ctx context.Context, n int64, dryRun bool) (Result, error) {
+	c, err := l.call(n, dryRun)
+	if err != nil {
+		return Result{}, err
+	}
+	return c.run(ctx)
+}
+
+// allowCall 返回一次 AllowN 判定（含钩子）的脚本调用，供 AllowKeys 合并到 pipeline 中执行。
+func (l *FixedWindowLimiter) allowCall(n int64) (scriptCall, error) {
+	c, err := l.call(n, false)
+	if err != nil {
+		return scriptCall{}, err
+	}
+	return c.withHooks(l.Hooks, "fixed_window", l.Key, n), nil
+}
+
+// call 准备一次固定窗口脚本调用。
+func (l *FixedWindowLimiter) call(n int64, dryRun bool) (scriptCall, error) {
+	if n <= 0 {
+		return scriptCall{}, fmt.Errorf("fixed window: n must > 0")
+	}
+
+	keys := []string{l.countKey()}
+	args := []interface{}{l.Window.Milliseconds(), l.Limit, n}
+	if dryRun {
+		args = append(args, 1)
+	} else {
+		keys, args = appendStats(keys, args, statsBase(l.Prefix, l.Key), l.StatsTTL, n, false)
+	}
+
+	return scriptCall{
+		client: l.client,
+		script: fixedWindowScript,
+		keys:   keys,
+		args:   args,
+		parse: func(_ context.Context, res interface{}, err error) (Result, error) {
+			if err != nil {
+				return Result{}, err
+			}
+			vals, ok := scriptInts(res, 3)
+			if !ok {
+				return Result{}, fmt.Errorf("fixed window: unexpected script result: %#v", res)
+			}
+			return Result{
+				Allowed:    vals[0] == 1,
+				Limit:      float64(l.Limit),
+				Remaining:  float64(vals[1]),
+				RetryAfter: time.Duration(vals[2]) * time.Millisecond,
+			}, nil
+		},
+	}, nil
+}
+
+// Wait 阻塞直到获得一个名额，或 ctx 取消 / 超过 maxWait。
+// 被拒绝时直接等到当前窗口结束再重试。
+func (l *FixedWindowLimiter) Wait(ctx context.Context, maxWait time.Duration) error {
+	return waitFor(ctx, l.Key, "fixed_window", maxWait, waitStrategy(l.WaitStrategy, l.WaitJitter), l.AllowWithResult)
+}
+
+// Stats 返回按 key 统计的放行 / 拒绝数量，需要通过 WithFixedWindowStats 开启统计。
+func (l *FixedWindowLimiter) Stats(ctx context.Context) (LimiterStats, error) {
+	return readStats(ctx, l.client, statsBase(l.Prefix, l.Key))
+}
+
+// Reset 删除计数 key，立即开启新窗口。
+func (l *FixedWindowLimiter) Reset(ctx context.Context) error {
+	return l.client.Del(ctx, l.countKey()).Err()
+}
+
+// State 返回当前窗口的计数及窗口结束时间。
+func (l *FixedWindowLimiter) State(ctx context.Context) (LimiterState, error) {
+	now := l.Clock.Now().UnixMilli()
+
+	var used int64
+	usedStr, err := l.client.Get(ctx, l.countKey()).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return LimiterState{}, err
+	}
+	if err == nil {
+		used, err = strconv.ParseInt(usedStr, 10, 64)
+		if err != nil {
+			return LimiterState{}, fmt.Errorf("fixed window: invalid count: %v", err)
+		}
+	}
+
+	next := now
+	if used >= l.Limit {
+		ttl, err := l.client.PTTL(ctx, l.countKey()).Result()
+		if err != nil {
+			return LimiterState{}, err
+		}
+		next += max(ttl.Milliseconds(), 0)
+	}
+
+	return fillStats(ctx, l.client, statsBase(l.Prefix, l.Key), l.StatsTTL, LimiterState{
+		Level:             float64(used),
+		Remaining:         float64(max(l.Limit-used, 0)),
+		Capacity:          float64(l.Limit),
+		Rate:              float64(l.Limit) / l.Window.Seconds(),
+		LastUpdated:       now,
+		NextAvailableTime: next,
+		Type:              "fixed_window",
+		Key:               l.Key,
+		Window:            l.Window,
+	}, nil)
+}
@@ -0,0 +1,77 @@
+package limiter
+
+import "time"
+
+// FixedWindowOption 为固定窗口限流器的配置项。
+// 使用 FixedWindow 前缀，避免与其他限流器的 Option 冲突。
+type FixedWindowOption func(*FixedWindowLimiter)
+
+// WithFixedWindowWindow 设置窗口大小（最小精度为毫秒）。
+func WithFixedWindowWindow(d time.Duration) FixedWindowOption {
+	return func(l *FixedWindowLimiter) {
+		if d >= time.Millisecond {
+			l.Window = d
+		}
+	}
+}
+
+// WithFixedWindowLimit 设置窗口内允许的最大请求数。
+func WithFixedWindowLimit(limit int64) FixedWindowOption {
+	return func(l *FixedWindowLimiter) {
+		if limit > 0 {
+			l.Limit = limit
+		}
+	}
+}
+
+// WithFixedWindowWaitJitter 设置 Wait 重试前的随机抖动比例，取值范围 [0, 1]。
+func WithFixedWindowWaitJitter(ratio float64) FixedWindowOption {
+	return func(l *FixedWindowLimiter) {
+		if ratio < 0 || ratio > 1 {
+			panic("fixed window: wait jitter must be in [0, 1]")
+		}
+		l.WaitJitter = ratio
+	}
+}
+
+// WithFixedWindowWaitStrategy 设置 Wait 被拒绝后的等待策略，例如 ExponentialWait、NewPacedWait。
+// 设置后 WaitJitter 不再生效，抖动由策略自身负责。
+func WithFixedWindowWaitStrategy(s WaitStrategy) FixedWindowOption {
+	return func(l *FixedWindowLimiter) {
+		l.WaitStrategy = s
+	}
+}
+
+// WithFixedWindowClock 设置时钟，通常用于测试中控制时间。
+func WithFixedWindowClock(c Clock) FixedWindowOption {
+	return func(l *FixedWindowLimiter) {
+		if c != nil {
+			l.Clock = c
+		}
+	}
+}
+
+// WithFixedWindowPrefix 设置 Redis key 前缀。
+func WithFixedWindowPrefix(prefix string) FixedWindowOption {
+	return func(l *FixedWindowLimiter) {
+		if prefix != "" {
+			l.Prefix = prefix
+		}
+	}
+}
+
+// WithFixedWindowStats 开启按 key 统计放行 / 拒绝数量，ttl 为计数器的过期时间（每次写入时刷新）。
+func WithFixedWindowStats(ttl time.Duration) FixedWindowOption {
+	return func(l *FixedWindowLimiter) {
+		if ttl > 0 {
+			l.StatsTTL = ttl
+		}
+	}
+}
+
+// WithFixedWindowHooks 设置判定事件钩子，用于接入日志、指标或告警。
+func WithFixedWindowHooks(h Hooks) FixedWindowOption {
+	return func(l *FixedWindowLimiter) {
+		l.Hooks = h
+	}
+}
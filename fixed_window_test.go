@@ -0,0 +1,53 @@
+package limiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFixedWindowLimiter(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	ctx := context.Background()
+
+	l := NewFixedWindowLimiter(client, "login",
+		WithFixedWindowWindow(time.Minute),
+		WithFixedWindowLimit(3),
+	)
+
+	ok, err := l.AllowN(ctx, 2)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, time.Minute, mr.TTL("fw:{login}:count"))
+
+	res, err := l.AllowNWithResult(ctx, 2)
+	assert.NoError(t, err)
+	assert.False(t, res.Allowed)
+	assert.Equal(t, float64(1), res.Remaining)
+	assert.Equal(t, time.Minute, res.RetryAfter)
+
+	ok, _ = l.Allow(ctx)
+	assert.True(t, ok)
+
+	st, err := l.State(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, float64(3), st.Level)
+	assert.Equal(t, float64(0), st.Remaining)
+	assert.Greater(t, st.NextAvailableTime, st.LastUpdated)
+
+	assert.ErrorIs(t, l.Wait(ctx, 0), ErrLimiter)
+
+	// 窗口结束后计数重置
+	mr.FastForward(time.Minute)
+	ok, _ = l.AllowN(ctx, 3)
+	assert.True(t, ok)
+
+	assert.NoError(t, l.Reset(ctx))
+	assert.False(t, mr.Exists("fw:{login}:count"))
+}
@@ -0,0 +1,183 @@
+package limiter
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// FunctionLibraryPrefix 为 Redis Functions 库名的前缀，完整库名为 "<prefix>_<version>"。
+const FunctionLibraryPrefix = "goredislimiter"
+
+// scriptSources 记录每个脚本的源码，key 为 SHA1，用于生成 Redis Functions 库。
+var scriptSources = make(map[string]string)
+
+// newScript 创建脚本并记录源码。
+func newScript(src string) *redis.Script {
+	s := redis.NewScript(src)
+	scriptSources[s.Hash()] = src
+	return s
+}
+
+// functionLibrary 是由全部脚本生成的 Redis Functions 库。
+type functionLibrary struct {
+	name  string            // 库名，带版本号
+	code  string            // FUNCTION LOAD 的库代码
+	funcs map[string]string // 脚本 SHA1 -> 函数名
+}
+
+var (
+	libraryOnce sync.Once
+	library     functionLibrary
+)
+
+// loadLibrary 生成（并缓存）函数库。版本号取全部脚本源码的 SHA1 前 8 位：
+// 脚本有任何改动都会得到新的库名与函数名，新旧版本可以同时加载在 Redis 中，滚动发布期间互不影响。
+func loadLibrary() functionLibrary {
+	libraryOnce.Do(func() {
+		names := scriptNames()
+
+		h := sha1.New()
+		for _, name := range names {
+			h.Write([]byte(scripts[name].Hash()))
+		}
+		version := hex.EncodeToString(h.Sum(nil))[:8]
+
+		library = functionLibrary{
+			name:  FunctionLibraryPrefix + "_" + version,
+			funcs: make(map[string]string, len(names)),
+		}
+		var b strings.Builder
+		fmt.Fprintf(&b, "#!lua name=%s\n", library.name)
+		for _, name := range names {
+			s := scripts[name]
+			fn := fmt.Sprintf("limiter_%s_%s", name, version)
+			library.funcs[s.Hash()] = fn
+			// 函数以参数接收 KEYS / ARGV，同名后脚本正文无需修改
+			fmt.Fprintf(&b, "\nredis.register_function('%s', function(KEYS, ARGV)\n%s\nend)\n", fn, scriptSources[s.Hash()])
+		}
+		library.code = b.String()
+	})
+	return library
+}
+
+// FunctionLibrary 返回 Redis Functions 库的名称与代码（FUNCTION LOAD 的参数），
+// 便于在部署流水线中审阅或手动加载。
+func FunctionLibrary() (name, code string) {
+	lib := loadLibrary()
+	return lib.name, lib.code
+}
+
+// FunctionNames 返回全部脚本名称到 Redis Functions 函数名的映射。
+func FunctionNames() map[string]string {
+	lib := loadLibrary()
+	out := make(map[string]string, len(scripts))
+	for name, s := range scripts {
+		out[name] = lib.funcs[s.Hash()]
+	}
+	return out
+}
+
+// LoadFunctions 把全部算法注册为 Redis Functions（需要 Redis >= 7.0）。库已存在时不做任何修改。
+// client 为 *redis.ClusterClient 时会加载到所有主节点。
+//
+// 与 EVALSHA 脚本相比，函数是带名称与版本号的服务端对象，会随 RDB / AOF 持久化并复制到副本，
+// SCRIPT FLUSH 与重启后依然存在，也可以通过 FUNCTION LIST 审阅。加载后需要在客户端上安装 FunctionsHook。
+func (m *ScriptManager) LoadFunctions(ctx context.Context) error {
+	lib := loadLibrary()
+	load := func(ctx context.Context, c redis.UniversalClient) error {
+		err := c.Do(ctx, "FUNCTION", "LOAD", lib.code).Err()
+		if err != nil && !strings.Contains(err.Error(), "already exists") {
+			return fmt.Errorf("script manager: function load %s: %w", lib.name, err)
+		}
+		return nil
+	}
+
+	switch c := m.client.(type) {
+	case *redis.ClusterClient:
+		return c.ForEachMaster(ctx, func(ctx context.Context, node *redis.Client) error {
+			return load(ctx, node)
+		})
+	case redis.UniversalClient:
+		return load(ctx, c)
+	}
+	return fmt.Errorf("script manager: client %T does not support FUNCTION", m.client)
+}
+
+// DeleteFunctions 删除当前版本的函数库，库不存在时不报错。
+func (m *ScriptManager) DeleteFunctions(ctx context.Context) error {
+	lib := loadLibrary()
+	del := func(ctx context.Context, c redis.UniversalClient) error {
+		err := c.Do(ctx, "FUNCTION", "DELETE", lib.name).Err()
+		if err != nil && !strings.Contains(strings.ToLower(err.Error()), "not found") {
+			return err
+		}
+		return nil
+	}
+
+	switch c := m.client.(type) {
+	case *redis.ClusterClient:
+		return c.ForEachMaster(ctx, func(ctx context.Context, node *redis.Client) error {
+			return del(ctx, node)
+		})
+	case redis.UniversalClient:
+		return del(ctx, c)
+	}
+	return fmt.Errorf("script manager: client %T does not support FUNCTION", m.client)
+}
+
+// functionsHook 把本包脚本的 EVALSHA 改写为 FCALL，见 FunctionsHook。
+type functionsHook struct {
+	funcs map[string]string
+}
+
+// FunctionsHook 返回一个 go-redis 钩子，把本包脚本的 EVALSHA 调用改写为 FCALL 对应的函数：
+//
+//	if err := limiter.NewScriptManager(rdb).LoadFunctions(ctx); err != nil { ... }
+//	rdb.AddHook(limiter.FunctionsHook())
+//
+// 两者的参数布局相同（名称、numkeys、KEYS、ARGV），各限流器无需任何修改，pipeline 中的调用同样会被改写。
+// 其他脚本与命令原样执行。函数不存在时 FCALL 返回错误，不会像 EVALSHA 那样自动退回 EVAL，
+// 因此需要先调用 ScriptManager.LoadFunctions。
+//
+// 注意：go-redis v8 的 ClusterClient 无法从 FCALL 推断 slot，命令会依赖 MOVED 重定向才能到达正确的节点，
+// Redis Cluster 部署建议继续使用 EVALSHA。
+func FunctionsHook() redis.Hook {
+	return functionsHook{funcs: loadLibrary().funcs}
+}
+
+func (h functionsHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	h.rewrite(cmd)
+	return ctx, nil
+}
+
+func (h functionsHook) AfterProcess(context.Context, redis.Cmder) error { return nil }
+
+func (h functionsHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	for _, cmd := range cmds {
+		h.rewrite(cmd)
+	}
+	return ctx, nil
+}
+
+func (h functionsHook) AfterProcessPipeline(context.Context, []redis.Cmder) error { return nil }
+
+// rewrite 原地把 ["evalsha", sha, numkeys, ...] 改写为 ["fcall", name, numkeys, ...]。
+func (h functionsHook) rewrite(cmd redis.Cmder) {
+	if cmd.Name() != "evalsha" {
+		return
+	}
+	args := cmd.Args()
+	if len(args) < 3 {
+		return
+	}
+	sha, _ := args[1].(string)
+	if fn, ok := h.funcs[sha]; ok {
+		args[0], args[1] = "fcall", fn
+	}
+}
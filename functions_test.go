@@ -0,0 +1,89 @@
+package limiter
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	lua "github.com/yuin/gopher-lua"
+)
+
+func TestFunctionLibrary(t *testing.T) {
+	name, code := FunctionLibrary()
+	assert.True(t, strings.HasPrefix(name, FunctionLibraryPrefix+"_"))
+	assert.True(t, strings.HasPrefix(code, "#!lua name="+name+"\n"))
+
+	funcs := FunctionNames()
+	assert.Len(t, funcs, len(scripts))
+	assert.True(t, strings.HasPrefix(funcs["token_bucket"], "limiter_token_bucket_"))
+
+	// 去掉 shebang 后用 Lua 编译并执行注册，确认库代码语法正确且注册了全部函数
+	L := lua.NewState()
+	defer L.Close()
+	var registered []string
+	rt := L.NewTable()
+	L.SetField(rt, "register_function", L.NewFunction(func(L *lua.LState) int {
+		registered = append(registered, L.CheckString(1))
+		L.CheckFunction(2)
+		return 0
+	}))
+	L.SetGlobal("redis", rt)
+	assert.NoError(t, L.DoString(code[strings.Index(code, "\n"):]))
+
+	want := make([]string, 0, len(funcs))
+	for _, fn := range funcs {
+		want = append(want, fn)
+	}
+	sort.Strings(want)
+	sort.Strings(registered)
+	assert.Equal(t, want, registered)
+}
+
+// captureHook 记录命令参数后中止执行。
+type captureHook struct{ args [][]interface{} }
+
+var errCaptured = errors.New("captured")
+
+func (h *captureHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	h.args = append(h.args, append([]interface{}{}, cmd.Args()...))
+	return ctx, errCaptured
+}
+
+func (h *captureHook) AfterProcess(context.Context, redis.Cmder) error { return nil }
+
+func (h *captureHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	for _, cmd := range cmds {
+		h.args = append(h.args, append([]interface{}{}, cmd.Args()...))
+	}
+	return ctx, errCaptured
+}
+
+func (h *captureHook) AfterProcessPipeline(context.Context, []redis.Cmder) error { return nil }
+
+func TestFunctionsHook(t *testing.T) {
+	ctx := context.Background()
+	client := newSpecClient(t)
+	capture := &captureHook{}
+	client.AddHook(FunctionsHook())
+	client.AddHook(capture)
+
+	tb := NewTokenBucketLimiter(client, "fn")
+	_, err := tb.Allow(ctx)
+	assert.ErrorIs(t, err, errCaptured)
+
+	// 其他命令原样执行
+	_ = client.Get(ctx, "plain").Err()
+
+	// pipeline 中的 EVALSHA 同样被改写
+	k := NewKeyedLimiter(func(key string) RateLimiter { return NewFixedWindowLimiter(client, key) })
+	_, _ = k.AllowKeys(ctx, []string{"a"})
+
+	assert.Len(t, capture.args, 3)
+	assert.Equal(t, []interface{}{"fcall", FunctionNames()["token_bucket"], 2, tb.tokensKey(), tb.tsKey()}, capture.args[0][:5])
+	assert.Equal(t, "get", capture.args[1][0])
+	assert.Equal(t, []interface{}{"fcall", FunctionNames()["fixed_window"]}, capture.args[2][:2])
+}
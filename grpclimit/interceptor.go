@@ -0,0 +1,165 @@
+package grpclimit
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strconv"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	limiter "github.com/lifei6671/go-redis-limiter"
+)
+
+// RetryAfterKey 是被限流时写入 trailer 的 metadata key，值为建议的重试秒数。
+const RetryAfterKey = "retry-after"
+
+// Option 为 gRPC 拦截器的配置项。
+type Option func(*config)
+
+type config struct {
+	classifier limiter.Classifier
+	onError    func(ctx context.Context, err error) error
+}
+
+// WithKeyFunc 设置 key 提取函数，默认按 FullMethod 限流。
+func WithKeyFunc(fn KeyFunc) Option {
+	return func(c *config) {
+		if fn != nil {
+			c.classifier = NewClassifier("", fn)
+		}
+	}
+}
+
+// WithClassifier 使用 limiter.Classifier 决定 key 与消耗，传给 Classify 的 req 为 *Call。
+func WithClassifier(cl limiter.Classifier) Option {
+	return func(c *config) {
+		if cl != nil {
+			c.classifier = cl
+		}
+	}
+}
+
+// WithErrorHandler 设置限流器出错时的处理方式：返回 nil 表示放行，返回 error 则直接作为调用结果。
+// 默认 fail-open（放行）。
+func WithErrorHandler(fn func(ctx context.Context, err error) error) Option {
+	return func(c *config) {
+		if fn != nil {
+			c.onError = fn
+		}
+	}
+}
+
+func newConfig(opts []Option) *config {
+	cfg := &config{
+		classifier: NewClassifier("", KeyByMethod),
+		onError:    func(context.Context, error) error { return nil },
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg
+}
+
+// UnaryServerInterceptor 返回一元调用的限流拦截器。
+// 被限流时返回 codes.ResourceExhausted，错误详情中附带 RetryInfo，trailer 中附带 retry-after。
+func UnaryServerInterceptor(l limiter.RateShardedLimiter, opts ...Option) grpc.UnaryServerInterceptor {
+	if l == nil {
+		panic("grpclimit: limiter is nil")
+	}
+	cfg := newConfig(opts)
+
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if err := cfg.check(ctx, l, &Call{FullMethod: info.FullMethod, Request: req}); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor 返回流式调用的限流拦截器，在建立流时判定一次。
+func StreamServerInterceptor(l limiter.RateShardedLimiter, opts ...Option) grpc.StreamServerInterceptor {
+	if l == nil {
+		panic("grpclimit: limiter is nil")
+	}
+	cfg := newConfig(opts)
+
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := cfg.check(ss.Context(), l, &Call{FullMethod: info.FullMethod}); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
+
+// check 对一次调用做限流判定，放行返回 nil。
+func (c *config) check(ctx context.Context, l limiter.RateShardedLimiter, call *Call) error {
+	key, cost, _ := c.classifier.Classify(ctx, call)
+	if key == "" {
+		return nil
+	}
+
+	res, err := allow(ctx, l, key, cost)
+	if err != nil {
+		return c.onError(ctx, err)
+	}
+	if res.Allowed {
+		return nil
+	}
+	return ResourceExhausted(ctx, res.RetryAfter)
+}
+
+// allow 对 key 执行一次判定；消耗大于 1 时重试等待时间取自 State().NextAvailableTime。
+func allow(ctx context.Context, l limiter.RateShardedLimiter, key string, cost int64) (limiter.Result, error) {
+	if cost <= 1 {
+		return l.AllowWithResult(ctx, key)
+	}
+
+	ok, err := l.AllowN(ctx, key, cost)
+	if err != nil || ok {
+		return limiter.Result{Allowed: ok}, err
+	}
+	res := limiter.Result{}
+	if st, err := l.State(ctx, key); err == nil {
+		res.RetryAfter = max(time.Until(time.UnixMilli(st.NextAvailableTime)), 0)
+	}
+	return res, nil
+}
+
+// ResourceExhausted 构造被限流时返回的 gRPC 错误，并尽量把 retry-after 写入 trailer。
+func ResourceExhausted(ctx context.Context, retryAfter time.Duration) error {
+	seconds := max(int64(math.Ceil(retryAfter.Seconds())), 1)
+	// 不在 gRPC 服务端上下文中调用时 SetTrailer 会失败，忽略即可
+	_ = grpc.SetTrailer(ctx, metadata.Pairs(RetryAfterKey, strconv.FormatInt(seconds, 10)))
+
+	st := status.New(codes.ResourceExhausted, limiter.ErrLimiter.Error())
+	if detailed, err := st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(retryAfter)}); err == nil {
+		st = detailed
+	}
+	return st.Err()
+}
+
+// FromError 把限流器返回的错误转换为 gRPC 错误：限流错误（limiter.IsRetryable）为 codes.ResourceExhausted
+// 并附带 RetryInfo，ctx 取消 / 超时分别为 codes.Canceled / codes.DeadlineExceeded，
+// 其他错误（Redis 故障、熔断打开等，限流器不可用）为 codes.Unavailable。err 为 nil 时返回 nil。
+func FromError(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+	if retryAfter, ok := limiter.IsRetryable(err); ok {
+		return ResourceExhausted(ctx, retryAfter)
+	}
+	switch {
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	}
+	return status.Error(codes.Unavailable, err.Error())
+}
@@ -0,0 +1,156 @@
+package grpclimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	limiter "github.com/lifei6671/go-redis-limiter"
+)
+
+// fakeLimiter 按 key 计数，超过 limit 后拒绝。
+type fakeLimiter struct {
+	limit int64
+	used  map[string]int64
+	err   error
+}
+
+func (f *fakeLimiter) Allow(ctx context.Context, key string) (bool, error) {
+	return f.AllowN(ctx, key, 1)
+}
+
+func (f *fakeLimiter) AllowWithResult(ctx context.Context, key string) (limiter.Result, error) {
+	ok, err := f.AllowN(ctx, key, 1)
+	res := limiter.Result{Allowed: ok}
+	if !ok {
+		res.RetryAfter = 2 * time.Second
+	}
+	return res, err
+}
+
+func (f *fakeLimiter) AllowN(_ context.Context, key string, n int64) (bool, error) {
+	if f.err != nil {
+		return false, f.err
+	}
+	if f.used[key]+n > f.limit {
+		return false, nil
+	}
+	f.used[key] += n
+	return true, nil
+}
+
+func (f *fakeLimiter) Reset(_ context.Context, key string) error {
+	delete(f.used, key)
+	return nil
+}
+
+func (f *fakeLimiter) ResetAll(context.Context) error {
+	clear(f.used)
+	return nil
+}
+
+func (f *fakeLimiter) State(context.Context, string) (limiter.LimiterState, error) {
+	return limiter.LimiterState{}, nil
+}
+
+func (f *fakeLimiter) Wait(context.Context, string, time.Duration) error {
+	return nil
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	info := &grpc.UnaryServerInfo{FullMethod: "/pkg.Svc/Chat"}
+	handler := func(ctx context.Context, req any) (any, error) { return "ok", nil }
+
+	t.Run("Unary_deny_resource_exhausted", func(t *testing.T) {
+		l := &fakeLimiter{limit: 1, used: map[string]int64{}}
+		it := UnaryServerInterceptor(l)
+
+		resp, err := it(context.Background(), nil, info, handler)
+		assert.NoError(t, err)
+		assert.Equal(t, "ok", resp)
+
+		_, err = it(context.Background(), nil, info, handler)
+		st, _ := status.FromError(err)
+		assert.Equal(t, codes.ResourceExhausted, st.Code())
+		if assert.Len(t, st.Details(), 1) {
+			ri := st.Details()[0].(*errdetails.RetryInfo)
+			assert.Equal(t, 2*time.Second, ri.RetryDelay.AsDuration())
+		}
+		assert.Equal(t, int64(1), l.used["/pkg.Svc/Chat"])
+	})
+
+	t.Run("Unary_key_by_metadata", func(t *testing.T) {
+		l := &fakeLimiter{limit: 1, used: map[string]int64{}}
+		it := UnaryServerInterceptor(l, WithKeyFunc(KeyByMetadata("x-tenant")))
+
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-tenant", "t1"))
+		_, err := it(ctx, nil, info, handler)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), l.used["t1"])
+
+		// 没有 metadata 时不限流
+		_, err = it(context.Background(), nil, info, handler)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Unary_error_handler", func(t *testing.T) {
+		l := &fakeLimiter{err: errors.New("redis down")}
+
+		_, err := UnaryServerInterceptor(l)(context.Background(), nil, info, handler)
+		assert.NoError(t, err, "fail-open by default")
+
+		it := UnaryServerInterceptor(l, WithErrorHandler(func(ctx context.Context, err error) error {
+			return status.Error(codes.Unavailable, err.Error())
+		}))
+		_, err = it(context.Background(), nil, info, handler)
+		assert.Equal(t, codes.Unavailable, status.Code(err))
+
+		_, err = UnaryServerInterceptor(l, WithErrorHandler(FromError))(context.Background(), nil, info, handler)
+		assert.Equal(t, codes.Unavailable, status.Code(err))
+	})
+}
+
+func TestFromError(t *testing.T) {
+	ctx := context.Background()
+	assert.NoError(t, FromError(ctx, nil))
+
+	err := FromError(ctx, &limiter.LimitExceededError{Timeout: true, RetryAfter: 2 * time.Second})
+	st := status.Convert(err)
+	assert.Equal(t, codes.ResourceExhausted, st.Code())
+	assert.Len(t, st.Details(), 1)
+	assert.Equal(t, 2*time.Second, st.Details()[0].(*errdetails.RetryInfo).RetryDelay.AsDuration())
+
+	assert.Equal(t, codes.ResourceExhausted, status.Code(FromError(ctx, limiter.ErrTimeout)))
+	assert.Equal(t, codes.Canceled, status.Code(FromError(ctx, context.Canceled)))
+	assert.Equal(t, codes.DeadlineExceeded, status.Code(FromError(ctx, context.DeadlineExceeded)))
+	assert.Equal(t, codes.Unavailable, status.Code(FromError(ctx, limiter.ErrCircuitOpen)))
+}
+
+type fakeStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *fakeStream) Context() context.Context { return s.ctx }
+
+func TestStreamServerInterceptor(t *testing.T) {
+	l := &fakeLimiter{limit: 0, used: map[string]int64{}}
+	it := StreamServerInterceptor(l)
+
+	called := false
+	err := it(nil, &fakeStream{ctx: context.Background()}, &grpc.StreamServerInfo{FullMethod: "/pkg.Svc/Watch"},
+		func(srv any, stream grpc.ServerStream) error {
+			called = true
+			return nil
+		})
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.False(t, called)
+}
@@ -0,0 +1,269 @@
+package limiter
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// HierarchicalResult 是层级限流一次判定的结果。
+type HierarchicalResult struct {
+	Result
+
+	// ParentDenied 为 true 表示子桶足够、但被父桶（整体上限）拒绝
+	ParentDenied bool
+}
+
+// HierarchicalLimiter 实现“子桶嵌套在父桶之下”的两级令牌桶限流，
+// 例如每个租户 10 QPS，同时整个接口 1000 QPS：
+//   - 每次请求同时从子桶（shardKey，例如 "user:123"）与父桶（Parent，例如 "api:/chat"）扣减
+//   - 两级在同一个 Lua 脚本中原子判断，父桶拒绝时子桶不扣减
+//
+// 所有子桶与父桶共享父 key 的 hash tag，在 Redis Cluster 下落在同一个 slot，
+// 因此单个父桶下的流量会集中在一个节点上。
+type HierarchicalLimiter struct {
+	client *redis.Client
+
+	Parent string // 父桶业务 key，例如 "api:/chat"
+	Prefix string // Redis key 前缀，默认 "hier"
+
+	ChildRate      float64 // 子桶生成速率（token/sec）
+	ChildCapacity  float64 // 子桶容量
+	ParentRate     float64 // 父桶生成速率（token/sec）
+	ParentCapacity float64 // 父桶容量
+
+	TTL       time.Duration // Redis key 过期时间
+	TTLJitter float64       // 父桶与子桶的 TTL 抖动比例（0~1）
+
+	// WaitJitter Wait 重试前的随机抖动比例（0~1）
+	WaitJitter float64
+	// WaitStrategy Wait 被拒绝后的等待策略（可选），默认按 RetryAfter 等待并叠加 WaitJitter，见 WaitStrategy。
+	WaitStrategy WaitStrategy
+
+	// ServerTime 为 true（默认）时脚本使用 Redis TIME 作为当前时间，
+	// 所有应用实例共享同一个时钟源；为 false 时使用本机时钟。
+	ServerTime bool
+
+	// Clock 时钟，默认 SystemClock。
+	Clock Clock
+
+	// Hooks 判定事件钩子（可选），每次放行 / 拒绝 / 出错时回调，见 Hooks。
+	Hooks Hooks
+}
+
+var _ RateShardedLimiter = (*HierarchicalLimiter)(nil)
+
+// NewHierarchicalLimiter 创建一个两级（子桶 + 父桶）令牌桶限流器。
+// 子桶 key 在调用 Allow 等方法时通过 shardKey 传入。
+func NewHierarchicalLimiter(
+	client *redis.Client,
+	parent string,
+	opts ...HierarchicalOption,
+) *HierarchicalLimiter {
+
+	if client == nil {
+		panic("hierarchical: redis client is nil")
+	}
+	if parent == "" {
+		panic("hierarchical: parent key is empty")
+	}
+
+	l := &HierarchicalLimiter{
+		client:         client,
+		Parent:         parent,
+		Prefix:         "hier",
+		ChildRate:      10,
+		ChildCapacity:  10,
+		ParentRate:     100,
+		ParentCapacity: 100,
+		TTL:            2 * time.Second,
+		ServerTime:     true,
+		Clock:          SystemClock,
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// parentKeys 返回父桶的 tokens / ts key。
+func (l *HierarchicalLimiter) parentKeys() (string, string) {
+	base := fmt.Sprintf("%s:{%s}", l.Prefix, l.Parent)
+	return base + ":tokens", base + ":ts"
+}
+
+// childKeys 返回子桶的 tokens / ts key。
+func (l *HierarchicalLimiter) childKeys(child string) (string, string) {
+	base := fmt.Sprintf("%s:{%s}:child:%s", l.Prefix, l.Parent, child)
+	return base + ":tokens", base + ":ts"
+}
+
+// Allow 尝试为子桶 shardKey 获取一个 token。
+func (l *HierarchicalLimiter) Allow(ctx context.Context, shardKey string) (bool, error) {
+	return l.AllowN(ctx, shardKey, 1)
+}
+
+// AllowWithResult 尝试为子桶 shardKey 获取一个 token，并返回剩余额度及重试等待时间。
+func (l *HierarchicalLimiter) AllowWithResult(ctx context.Context, shardKey string) (Result, error) {
+	res, err := l.AllowNDetail(ctx, shardKey, 1)
+	if err != nil {
+		return Result{}, err
+	}
+	return res.Result, nil
+}
+
+// AllowN 尝试为子桶 shardKey 一次获取 n 个 token。
+func (l *HierarchicalLimiter) AllowN(ctx context.Context, shardKey string, n int64) (bool, error) {
+	res, err := l.AllowNDetail(ctx, shardKey, n)
+	if err != nil {
+		return false, err
+	}
+	return res.Allowed, nil
+}
+
+// AllowNDetail 尝试为子桶 shardKey 一次获取 n 个 token，并返回是否被父桶拒绝。
+//   - Limit 为子桶容量
+//   - Remaining 为子桶与父桶中较小的剩余 token 数
+//   - RetryAfter 为拒绝本次请求的那一级补足 n 个 token 所需的时间
+func (l *HierarchicalLimiter) AllowNDetail(ctx context.Context, shardKey string, n int64) (HierarchicalResult, error) {
+	res, err := l.eval(ctx, shardKey, n)
+	fireHooks(ctx, l.Hooks, "hierarchical", shardKey, n, res.Result, err)
+	return res, err
+}
+
+// eval 执行两级令牌桶脚本。
+func (l *HierarchicalLimiter) eval(ctx context.Context, shardKey string, n int64) (HierarchicalResult, error) {
+	if shardKey == "" {
+		return HierarchicalResult{}, fmt.Errorf("hierarchical: shard key is empty")
+	}
+	if n <= 0 {
+		return HierarchicalResult{}, fmt.Errorf("hierarchical: n must > 0")
+	}
+
+	childTokens, childTs := l.childKeys(shardKey)
+	parentTokens, parentTs := l.parentKeys()
+	res, err := hierarchicalScript.Run(
+		ctx,
+		l.client,
+		[]string{childTokens, childTs, parentTokens, parentTs},
+		scriptNow(l.ServerTime, l.Clock),
+		l.ChildRate,
+		l.ChildCapacity,
+		l.ParentRate,
+		l.ParentCapacity,
+		n,
+		l.TTL.Milliseconds(),
+		l.TTLJitter,
+	).Result()
+	if err != nil {
+		return HierarchicalResult{}, err
+	}
+
+	vals, ok := scriptInts(res, 4)
+	if !ok {
+		return HierarchicalResult{}, fmt.Errorf("hierarchical: unexpected script result: %#v", res)
+	}
+	return HierarchicalResult{
+		Result: Result{
+			Allowed:    vals[0] == 1,
+			Limit:      l.ChildCapacity,
+			Remaining:  float64(vals[1]),
+			RetryAfter: time.Duration(vals[2]) * time.Millisecond,
+		},
+		ParentDenied: vals[3] == 2,
+	}, nil
+}
+
+// Wait 阻塞直到子桶 shardKey 获得一个 token，或 ctx 取消 / 超过 maxWait。
+func (l *HierarchicalLimiter) Wait(ctx context.Context, shardKey string, maxWait time.Duration) error {
+	return waitFor(ctx, shardKey, "hierarchical", maxWait, waitStrategy(l.WaitStrategy, l.WaitJitter), func(ctx context.Context) (Result, error) {
+		return l.AllowWithResult(ctx, shardKey)
+	})
+}
+
+// Reset 重置子桶 shardKey，不影响父桶。
+func (l *HierarchicalLimiter) Reset(ctx context.Context, shardKey string) error {
+	tokens, ts := l.childKeys(shardKey)
+	return l.client.Del(ctx, tokens, ts).Err()
+}
+
+// ResetAll 重置父桶及其下全部子桶。子桶通过 SCAN 查找，key 数量较多时耗时较长。
+func (l *HierarchicalLimiter) ResetAll(ctx context.Context) error {
+	tokens, ts := l.parentKeys()
+	if err := l.client.Del(ctx, tokens, ts).Err(); err != nil {
+		return err
+	}
+
+	match := fmt.Sprintf("%s:{%s}:child:*", l.Prefix, l.Parent)
+	iter := l.client.Scan(ctx, 0, match, 100).Iterator()
+	for iter.Next(ctx) {
+		if err := l.client.Del(ctx, iter.Val()).Err(); err != nil {
+			return err
+		}
+	}
+	return iter.Err()
+}
+
+// State 返回子桶 shardKey 的状态，Remaining 同时受父桶剩余 token 约束。
+func (l *HierarchicalLimiter) State(ctx context.Context, shardKey string) (LimiterState, error) {
+	now := l.Clock.Now().UnixMilli()
+
+	childTokens, childTs := l.childKeys(shardKey)
+	parentTokens, parentTs := l.parentKeys()
+	vals, err := l.client.MGet(ctx, childTokens, childTs, parentTokens, parentTs).Result()
+	if err != nil {
+		return LimiterState{}, err
+	}
+
+	child, err := bucketLevel(vals[0], vals[1], l.ChildRate, l.ChildCapacity, now)
+	if err != nil {
+		return LimiterState{}, err
+	}
+	parent, err := bucketLevel(vals[2], vals[3], l.ParentRate, l.ParentCapacity, now)
+	if err != nil {
+		return LimiterState{}, err
+	}
+
+	next := now
+	if child < 1 {
+		next = max(next, now+int64((1-child)*1000/l.ChildRate))
+	}
+	if parent < 1 {
+		next = max(next, now+int64((1-parent)*1000/l.ParentRate))
+	}
+
+	return LimiterState{
+		Level:             child,
+		Remaining:         min(child, parent),
+		Capacity:          l.ChildCapacity,
+		Rate:              l.ChildRate,
+		LastUpdated:       now,
+		NextAvailableTime: next,
+		Type:              "hierarchical",
+		Key:               shardKey,
+	}, nil
+}
+
+// bucketLevel 根据 MGET 读到的 tokens / ts 计算令牌桶在 now 时刻的 token 数。
+// key 不存在时视为满桶。
+func bucketLevel(tokensVal, tsVal interface{}, rate, capacity float64, now int64) (float64, error) {
+	tokensStr, ok1 := tokensVal.(string)
+	tsStr, ok2 := tsVal.(string)
+	if !ok1 || !ok2 {
+		return capacity, nil
+	}
+
+	tokens, err := strconv.ParseFloat(tokensStr, 64)
+	if err != nil {
+		return 0, fmt.Errorf("hierarchical: invalid tokens: %v", err)
+	}
+	lastTs, err := strconv.ParseInt(tsStr, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("hierarchical: invalid ts: %v", err)
+	}
+	delta := max(now-lastTs, 0)
+	return min(tokens+float64(delta)*rate/1000, capacity), nil
+}
@@ -0,0 +1,98 @@
+package limiter
+
+import "time"
+
+// HierarchicalOption 为层级限流器的配置项。
+type HierarchicalOption func(*HierarchicalLimiter)
+
+// WithHierarchicalChildLimit 设置每个子桶的生成速率（token/sec）与容量。
+func WithHierarchicalChildLimit(rate, capacity float64) HierarchicalOption {
+	return func(l *HierarchicalLimiter) {
+		if rate <= 0 || capacity <= 0 {
+			panic("hierarchical: child rate and capacity must > 0")
+		}
+		l.ChildRate, l.ChildCapacity = rate, capacity
+	}
+}
+
+// WithHierarchicalParentLimit 设置父桶的生成速率（token/sec）与容量。
+func WithHierarchicalParentLimit(rate, capacity float64) HierarchicalOption {
+	return func(l *HierarchicalLimiter) {
+		if rate <= 0 || capacity <= 0 {
+			panic("hierarchical: parent rate and capacity must > 0")
+		}
+		l.ParentRate, l.ParentCapacity = rate, capacity
+	}
+}
+
+// WithHierarchicalTTL 设置 Redis key 的 TTL。
+func WithHierarchicalTTL(ttl time.Duration) HierarchicalOption {
+	return func(l *HierarchicalLimiter) {
+		if ttl > 0 {
+			l.TTL = ttl
+		}
+	}
+}
+
+// WithHierarchicalTTLJitter 设置父桶与子桶的 TTL 抖动比例（TTL 延长 0~ratio），取值范围 [0, 1)。
+func WithHierarchicalTTLJitter(ratio float64) HierarchicalOption {
+	return func(l *HierarchicalLimiter) {
+		if ratio < 0 || ratio >= 1 {
+			panic("hierarchical: ttl jitter must be in [0, 1)")
+		}
+		l.TTLJitter = ratio
+	}
+}
+
+// WithHierarchicalWaitJitter 设置 Wait 重试前的随机抖动比例，取值范围 [0, 1]。
+func WithHierarchicalWaitJitter(ratio float64) HierarchicalOption {
+	return func(l *HierarchicalLimiter) {
+		if ratio < 0 || ratio > 1 {
+			panic("hierarchical: wait jitter must be in [0, 1]")
+		}
+		l.WaitJitter = ratio
+	}
+}
+
+// WithHierarchicalWaitStrategy 设置 Wait 被拒绝后的等待策略，例如 ExponentialWait、NewPacedWait。
+// 设置后 WaitJitter 不再生效，抖动由策略自身负责。
+func WithHierarchicalWaitStrategy(s WaitStrategy) HierarchicalOption {
+	return func(l *HierarchicalLimiter) {
+		l.WaitStrategy = s
+	}
+}
+
+// WithHierarchicalServerTime 设置是否使用 Redis TIME 作为脚本中的当前时间，默认开启。
+// 关闭后使用本机时钟，各应用实例的时钟偏差会直接影响 refill 与窗口边界。
+func WithHierarchicalServerTime(enabled bool) HierarchicalOption {
+	return func(l *HierarchicalLimiter) {
+		l.ServerTime = enabled
+	}
+}
+
+// WithHierarchicalClock 设置时钟，通常用于测试中控制时间。
+// 注入时钟后脚本改用该时钟传入的时间（相当于 WithHierarchicalServerTime(false)）。
+func WithHierarchicalClock(c Clock) HierarchicalOption {
+	return func(l *HierarchicalLimiter) {
+		if c != nil {
+			l.Clock = c
+			l.ServerTime = false
+		}
+	}
+}
+
+// WithHierarchicalPrefix 设置 Redis key 前缀。
+func WithHierarchicalPrefix(prefix string) HierarchicalOption {
+	return func(l *HierarchicalLimiter) {
+		if prefix != "" {
+			l.Prefix = prefix
+		}
+	}
+}
+
+// WithHierarchicalHooks 设置判定事件钩子，用于接入日志、指标或告警。
+func WithHierarchicalHooks(h Hooks) HierarchicalOption {
+	return func(l *HierarchicalLimiter) {
+		l.Hooks = h
+	}
+}
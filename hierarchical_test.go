@@ -0,0 +1,79 @@
+package limiter
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHierarchicalLimiter(t *testing.T) {
+	client := newSpecClient(t)
+	ctx := context.Background()
+
+	l := NewHierarchicalLimiter(client, "api:/chat",
+		WithHierarchicalChildLimit(0.001, 2),
+		WithHierarchicalParentLimit(0.001, 3),
+		WithHierarchicalTTL(time.Minute),
+	)
+
+	// 子桶上限
+	res, err := l.AllowNDetail(ctx, "user:1", 2)
+	assert.NoError(t, err)
+	assert.True(t, res.Allowed)
+	res, err = l.AllowNDetail(ctx, "user:1", 1)
+	assert.NoError(t, err)
+	assert.False(t, res.Allowed)
+	assert.False(t, res.ParentDenied)
+
+	// 父桶只剩 1 个 token：user:2 请求 2 个被父桶拒绝，且子桶不扣减
+	res, err = l.AllowNDetail(ctx, "user:2", 2)
+	assert.NoError(t, err)
+	assert.False(t, res.Allowed)
+	assert.True(t, res.ParentDenied)
+	assert.Greater(t, res.RetryAfter, time.Duration(0))
+
+	st, err := l.State(ctx, "user:2")
+	assert.NoError(t, err)
+	assert.InDelta(t, 2, st.Level, 0.1)
+	assert.InDelta(t, 1, st.Remaining, 0.1)
+
+	ok, err := l.Allow(ctx, "user:2")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	// 重置子桶不影响父桶
+	assert.NoError(t, l.Reset(ctx, "user:1"))
+	ok, _ = l.Allow(ctx, "user:1")
+	assert.False(t, ok)
+
+	assert.NoError(t, l.ResetAll(ctx))
+	keys, _ := client.Keys(ctx, "*").Result()
+	assert.Empty(t, keys)
+}
+
+func TestHierarchicalLimiter_TTLJitter(t *testing.T) {
+	client := newSpecClient(t)
+	ctx := context.Background()
+
+	// 父桶与子桶的 TTL 都加抖动：不同父 key 的 TTL 被打散，且只延长不缩短
+	seen := make(map[time.Duration]struct{})
+	for i := 0; i < 20; i++ {
+		l := NewHierarchicalLimiter(client, fmt.Sprintf("api:%d", i),
+			WithHierarchicalTTL(time.Minute),
+			WithHierarchicalTTLJitter(0.5),
+		)
+		_, err := l.AllowN(ctx, "user:1", 1)
+		assert.NoError(t, err)
+
+		parentKey, _ := l.parentKeys()
+		pttl, err := client.PTTL(ctx, parentKey).Result()
+		assert.NoError(t, err)
+		assert.GreaterOrEqual(t, pttl, time.Minute)
+		assert.LessOrEqual(t, pttl, 90*time.Second)
+		seen[pttl] = struct{}{}
+	}
+	assert.Greater(t, len(seen), 1)
+}
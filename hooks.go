@@ -0,0 +1,131 @@
+package limiter
+
+import "context"
+
+// HookEvent 描述一次限流判定，作为参数传给 Hooks 的回调。
+type HookEvent struct {
+	Key       string // 业务 key；层级限流为子桶 shardKey
+	Algorithm string // 算法名，与 LimitExceededError.Algorithm 一致，例如 "token_bucket"
+	N         int64  // 本次申请的许可数
+	Result    Result // 判定结果；OnError 时为零值
+}
+
+// Hooks 是限流判定的事件钩子，用于接入日志、指标或告警，而无需逐个包装限流器。
+//
+// 回调在调用方的 goroutine 中同步执行，耗时操作请自行异步化。
+// 只有会消耗名额的调用（Allow / Wait 等）才触发回调，预检（Check）不触发；
+// Wait 的每次重试都会触发一次。
+type Hooks interface {
+	// OnAllow 在请求被放行时调用。
+	OnAllow(ctx context.Context, e HookEvent)
+	// OnDeny 在请求被拒绝时调用。
+	OnDeny(ctx context.Context, e HookEvent)
+	// OnError 在判定出错（参数错误、Redis 不可用、脚本返回异常等）时调用。
+	OnError(ctx context.Context, e HookEvent, err error)
+}
+
+// HookFuncs 用函数实现 Hooks，未设置的回调直接忽略。
+type HookFuncs struct {
+	Allow func(ctx context.Context, e HookEvent)
+	Deny  func(ctx context.Context, e HookEvent)
+	Error func(ctx context.Context, e HookEvent, err error)
+}
+
+var _ Hooks = HookFuncs{}
+
+// OnAllow 实现 Hooks。
+func (h HookFuncs) OnAllow(ctx context.Context, e HookEvent) {
+	if h.Allow != nil {
+		h.Allow(ctx, e)
+	}
+}
+
+// OnDeny 实现 Hooks。
+func (h HookFuncs) OnDeny(ctx context.Context, e HookEvent) {
+	if h.Deny != nil {
+		h.Deny(ctx, e)
+	}
+}
+
+// OnError 实现 Hooks。
+func (h HookFuncs) OnError(ctx context.Context, e HookEvent, err error) {
+	if h.Error != nil {
+		h.Error(ctx, e, err)
+	}
+}
+
+// fireHooks 按判定结果触发对应的回调，h 为 nil 时什么都不做。
+func fireHooks(ctx context.Context, h Hooks, algorithm, key string, n int64, res Result, err error) {
+	if h == nil {
+		return
+	}
+	e := HookEvent{Key: key, Algorithm: algorithm, N: n, Result: res}
+	switch {
+	case err != nil:
+		h.OnError(ctx, e, err)
+	case res.Allowed:
+		h.OnAllow(ctx, e)
+	default:
+		h.OnDeny(ctx, e)
+	}
+}
+
+// SoftLimitHooks 为 Hooks 加上软上限告警：放行后已用额度达到 Limit 的 Ratio 时，
+// 除了原有的 OnAllow 之外再调用 OnSoftLimit，在客户开始收到 429 之前提前发现。
+//
+// 已用额度按 Result.Limit - Result.Remaining 计算，适用于所有返回 Limit 的限流器；
+// Limit 为 0（例如白名单直接放行）时不判断。越过软上限后每次放行都会调用 OnSoftLimit，
+// 需要“只告警一次”时请在回调中自行去重。
+type SoftLimitHooks struct {
+	// Next 被包装的钩子（可选），所有事件照常转发
+	Next Hooks
+	// Ratio 软上限占 Limit 的比例，取值范围 (0, 1]
+	Ratio float64
+	// OnSoftLimit 放行的请求越过软上限时的回调
+	OnSoftLimit func(ctx context.Context, e HookEvent)
+}
+
+var _ Hooks = (*SoftLimitHooks)(nil)
+
+// NewSoftLimitHooks 创建软上限告警钩子，ratio 为软上限占 Limit 的比例，next 可以为 nil。
+func NewSoftLimitHooks(ratio float64, onSoftLimit func(ctx context.Context, e HookEvent), next Hooks) *SoftLimitHooks {
+	if ratio <= 0 || ratio > 1 {
+		panic("soft limit: ratio must be in (0, 1]")
+	}
+	if onSoftLimit == nil {
+		panic("soft limit: callback is nil")
+	}
+	return &SoftLimitHooks{Next: next, Ratio: ratio, OnSoftLimit: onSoftLimit}
+}
+
+// OnAllow 实现 Hooks。
+func (h *SoftLimitHooks) OnAllow(ctx context.Context, e HookEvent) {
+	if h.Next != nil {
+		h.Next.OnAllow(ctx, e)
+	}
+	if h.OverSoftLimit(e.Result) {
+		h.OnSoftLimit(ctx, e)
+	}
+}
+
+// OnDeny 实现 Hooks。
+func (h *SoftLimitHooks) OnDeny(ctx context.Context, e HookEvent) {
+	if h.Next != nil {
+		h.Next.OnDeny(ctx, e)
+	}
+}
+
+// OnError 实现 Hooks。
+func (h *SoftLimitHooks) OnError(ctx context.Context, e HookEvent, err error) {
+	if h.Next != nil {
+		h.Next.OnError(ctx, e, err)
+	}
+}
+
+// OverSoftLimit 判断放行后的结果是否已越过软上限。
+func (h *SoftLimitHooks) OverSoftLimit(res Result) bool {
+	if !res.Allowed || res.Limit <= 0 {
+		return false
+	}
+	return res.Limit-res.Remaining >= res.Limit*h.Ratio
+}
@@ -0,0 +1,150 @@
+package limiter
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/go-redis/redismock/v8"
+	"github.com/stretchr/testify/assert"
+)
+
+type recordedHooks struct {
+	mu     sync.Mutex
+	events []string
+	errs   []error
+	last   HookEvent
+}
+
+func (r *recordedHooks) record(kind string, e HookEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, kind)
+	r.last = e
+}
+
+func (r *recordedHooks) hooks() Hooks {
+	return HookFuncs{
+		Allow: func(_ context.Context, e HookEvent) { r.record("allow", e) },
+		Deny:  func(_ context.Context, e HookEvent) { r.record("deny", e) },
+		Error: func(_ context.Context, e HookEvent, err error) {
+			r.record("error", e)
+			r.errs = append(r.errs, err)
+		},
+	}
+}
+
+func TestHooks(t *testing.T) {
+	client := newSpecClient(t)
+	ctx := context.Background()
+
+	newCases := func(h Hooks) map[string]checker {
+		return map[string]checker{
+			"token_bucket": NewTokenBucketLimiter(client, "hooks",
+				WithTokenBucketRate(0.001), WithTokenBucketCapacity(2), WithTokenBucketHooks(h)),
+			"leaky_bucket": NewLeakyBucketLimiter(client, "hooks",
+				WithLeakyBucketRate(0.001), WithLeakyBucketCapacity(2), WithLeakyBucketHooks(h)),
+			"sliding_window": NewSlidingWindowLimiter(client, "hooks",
+				WithSlidingWindowLimit(2), WithSlidingWindowHooks(h)),
+			"sliding_window_counter": NewSlidingWindowCounterLimiter(client, "hooks",
+				WithSlidingWindowCounterLimit(2), WithSlidingWindowCounterHooks(h)),
+			"fixed_window": NewFixedWindowLimiter(client, "hooks",
+				WithFixedWindowLimit(2), WithFixedWindowWindow(time.Minute), WithFixedWindowHooks(h)),
+			"quota": NewQuotaLimiter(client, "hooks", WithQuotaLimit(2), WithQuotaHooks(h)),
+		}
+	}
+
+	rec := &recordedHooks{}
+	for name, l := range newCases(rec.hooks()) {
+		t.Run(name, func(t *testing.T) {
+			rec.events = nil
+
+			_, err := l.CheckN(ctx, 1)
+			assert.NoError(t, err)
+			assert.Empty(t, rec.events, "预检不触发回调")
+
+			_, err = l.AllowNWithResult(ctx, 2)
+			assert.NoError(t, err)
+			assert.Equal(t, "hooks", rec.last.Key)
+			assert.Equal(t, name, rec.last.Algorithm)
+			assert.Equal(t, int64(2), rec.last.N)
+			assert.True(t, rec.last.Result.Allowed)
+
+			res, err := l.AllowNWithResult(ctx, 1)
+			assert.NoError(t, err)
+			assert.False(t, res.Allowed)
+			assert.Equal(t, res, rec.last.Result)
+			assert.Equal(t, []string{"allow", "deny"}, rec.events)
+		})
+	}
+}
+
+func TestHooks_Error(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer db.Close()
+	ctx := context.Background()
+
+	rec := &recordedHooks{}
+	l := NewFixedWindowLimiter(db, "hooks", WithFixedWindowLimit(2), WithFixedWindowHooks(rec.hooks()))
+
+	mock.ExpectEvalSha(fixedWindowScript.Hash(), []string{"fw:{hooks}:count"},
+		int64(60_000), int64(2), int64(1)).SetErr(redis.ErrClosed)
+
+	ok, err := l.Allow(ctx)
+	assert.ErrorIs(t, err, redis.ErrClosed)
+	assert.False(t, ok)
+	assert.Equal(t, []string{"error"}, rec.events)
+	assert.ErrorIs(t, rec.errs[0], redis.ErrClosed)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestHooks_Composite(t *testing.T) {
+	client := newSpecClient(t)
+	ctx := context.Background()
+
+	var denied []HookEvent
+	h := HookFuncs{Deny: func(_ context.Context, e HookEvent) { denied = append(denied, e) }}
+
+	hl := NewHierarchicalLimiter(client, "api", WithHierarchicalHooks(h))
+	ok, err := hl.AllowN(ctx, "u1", 100)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	cl := NewConcurrencyLimiter(client, "jobs", WithConcurrencyLimit(1), WithConcurrencyHooks(h))
+	_, err = cl.Acquire(ctx)
+	assert.NoError(t, err)
+	_, err = cl.Acquire(ctx)
+	assert.ErrorIs(t, err, ErrLimiter)
+
+	if assert.Len(t, denied, 2) {
+		assert.Equal(t, HookEvent{Key: "u1", Algorithm: "hierarchical", N: 100, Result: denied[0].Result}, denied[0])
+		assert.Equal(t, "concurrency", denied[1].Algorithm)
+		assert.Equal(t, int64(1), denied[1].N)
+	}
+}
+
+func TestSoftLimitHooks(t *testing.T) {
+	client := newSpecClient(t)
+	ctx := context.Background()
+
+	rec := &recordedHooks{}
+	var warned []float64
+	h := NewSoftLimitHooks(0.8, func(_ context.Context, e HookEvent) {
+		warned = append(warned, e.Result.Remaining)
+	}, rec.hooks())
+
+	l := NewFixedWindowLimiter(client, "soft", WithFixedWindowLimit(5), WithFixedWindowHooks(h))
+	for i := 0; i < 6; i++ {
+		_, err := l.Allow(ctx)
+		assert.NoError(t, err)
+	}
+
+	// 第 4、5 次放行越过 80% 软上限，第 6 次被拒绝不告警
+	assert.Equal(t, []float64{1, 0}, warned)
+	assert.Equal(t, []string{"allow", "allow", "allow", "allow", "allow", "deny"}, rec.events)
+
+	assert.False(t, h.OverSoftLimit(Result{Allowed: true}))
+	assert.Panics(t, func() { NewSoftLimitHooks(0, func(context.Context, HookEvent) {}, nil) })
+}
@@ -0,0 +1,219 @@
+package limiter
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// HotKey 是 TopKeys 返回的一个热点 key。
+type HotKey struct {
+	Key string
+	// Count 最近 Window*Windows 内的（估算）次数，采样时按 1/SampleRate 放大
+	Count int64
+}
+
+// HotKeyTracker 统计最常被拒绝 / 放行的业务 key，用于找出“哪些租户撞限流最狠”。
+//
+// 它实现了 Hooks，通过 With*Hooks 挂到任意限流器上即可：
+//   - 判定事件按 SampleRate 采样后先在本地计数，每 FlushInterval 用一次 pipeline 批量 ZINCRBY 到 Redis；
+//   - 计数按 Window 分桶存放在 "hotkeys:{denied}:<桶起点毫秒>" 等有序集合中，过期后自动淘汰；
+//   - TopKeys 合并最近 Windows 个桶，返回次数最多的前 n 个 key。
+//
+// 多个实例共享同一组有序集合，因此看到的是全局热点。使用完毕后需调用 Close 停止后台协程并刷出剩余计数。
+type HotKeyTracker struct {
+	client *redis.Client
+
+	// Prefix Redis key 前缀，默认 "hotkeys"
+	Prefix string
+	// Window 每个计数桶的时长，默认 1 分钟
+	Window time.Duration
+	// Windows TopKeys 合并的桶数，默认 5，即统计最近 5 分钟
+	Windows int
+	// SampleRate 采样比例（0~1]，默认 1（全部记录）。流量很大时可以调低以减少本地计数开销
+	SampleRate float64
+	// FlushInterval 本地计数刷到 Redis 的间隔，默认 1s
+	FlushInterval time.Duration
+	// OnFlushError 刷新失败时的回调（可选），默认忽略；失败的这批计数会被丢弃
+	OnFlushError func(err error)
+	// Clock 时钟，默认 SystemClock
+	Clock Clock
+
+	mu     sync.Mutex
+	counts map[string]map[string]float64 // outcome -> key -> 次数
+
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+var _ Hooks = (*HotKeyTracker)(nil)
+
+// NewHotKeyTracker 创建热点 key 统计器，并启动后台刷新协程。
+func NewHotKeyTracker(client *redis.Client, opts ...HotKeyOption) *HotKeyTracker {
+	if client == nil {
+		panic("hot key tracker: redis client is nil")
+	}
+
+	t := &HotKeyTracker{
+		client:        client,
+		Prefix:        "hotkeys",
+		Window:        time.Minute,
+		Windows:       5,
+		SampleRate:    1,
+		FlushInterval: time.Second,
+		Clock:         SystemClock,
+		counts:        make(map[string]map[string]float64),
+		stop:          make(chan struct{}),
+		done:          make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	go t.flushLoop()
+	return t
+}
+
+// bucketKey 返回 outcome（"allowed" / "denied"）在 start 桶的有序集合 key。
+// 所有桶共享 {outcome} hash tag，保证 TopKeys 的 ZUNION 在 Redis Cluster 中落在同一个 slot。
+func (t *HotKeyTracker) bucketKey(outcome string, start int64) string {
+	return fmt.Sprintf("%s:{%s}:%d", t.Prefix, outcome, start)
+}
+
+// bucketStart 返回 now 所在桶的起点（毫秒）。
+func (t *HotKeyTracker) bucketStart(now time.Time) int64 {
+	w := t.Window.Milliseconds()
+	return now.UnixMilli() / w * w
+}
+
+// OnAllow 实现 Hooks，记录一次放行。
+func (t *HotKeyTracker) OnAllow(_ context.Context, e HookEvent) {
+	t.record("allowed", e.Key)
+}
+
+// OnDeny 实现 Hooks，记录一次拒绝。
+func (t *HotKeyTracker) OnDeny(_ context.Context, e HookEvent) {
+	t.record("denied", e.Key)
+}
+
+// OnError 实现 Hooks，出错的判定不计入统计。
+func (t *HotKeyTracker) OnError(context.Context, HookEvent, error) {}
+
+// record 按采样比例在本地累加一次计数。
+func (t *HotKeyTracker) record(outcome, key string) {
+	if t.SampleRate < 1 && rand.Float64() >= t.SampleRate {
+		return
+	}
+	t.mu.Lock()
+	m, ok := t.counts[outcome]
+	if !ok {
+		m = make(map[string]float64)
+		t.counts[outcome] = m
+	}
+	m[key] += 1 / t.SampleRate
+	t.mu.Unlock()
+}
+
+// Flush 立即把本地累计的计数写入 Redis。
+func (t *HotKeyTracker) Flush(ctx context.Context) error {
+	t.mu.Lock()
+	counts := t.counts
+	t.counts = make(map[string]map[string]float64)
+	t.mu.Unlock()
+
+	if len(counts) == 0 {
+		return nil
+	}
+
+	start := t.bucketStart(t.Clock.Now())
+	// 桶在离开 TopKeys 的统计范围后过期
+	expireAt := time.UnixMilli(start).Add(t.Window * time.Duration(t.Windows))
+	_, err := t.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for outcome, m := range counts {
+			key := t.bucketKey(outcome, start)
+			for member, n := range m {
+				pipe.ZIncrBy(ctx, key, n, member)
+			}
+			pipe.PExpireAt(ctx, key, expireAt)
+		}
+		return nil
+	})
+	return err
+}
+
+// TopKeys 返回最近 Window*Windows 内被拒绝次数最多的前 n 个 key，按次数从大到小排序。
+// 只包含已经刷到 Redis 的计数。
+func (t *HotKeyTracker) TopKeys(ctx context.Context, n int) ([]HotKey, error) {
+	return t.top(ctx, "denied", n)
+}
+
+// TopAllowedKeys 返回最近 Window*Windows 内放行次数最多的前 n 个 key，即流量最大的 key。
+func (t *HotKeyTracker) TopAllowedKeys(ctx context.Context, n int) ([]HotKey, error) {
+	return t.top(ctx, "allowed", n)
+}
+
+func (t *HotKeyTracker) top(ctx context.Context, outcome string, n int) ([]HotKey, error) {
+	if n <= 0 {
+		return nil, fmt.Errorf("hot key tracker: n must > 0")
+	}
+
+	start := t.bucketStart(t.Clock.Now())
+	keys := make([]string, 0, t.Windows)
+	for i := 0; i < t.Windows; i++ {
+		keys = append(keys, t.bucketKey(outcome, start-int64(i)*t.Window.Milliseconds()))
+	}
+
+	zs, err := t.client.ZUnionWithScores(ctx, redis.ZStore{Keys: keys}).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(zs, func(i, j int) bool {
+		if zs[i].Score != zs[j].Score {
+			return zs[i].Score > zs[j].Score
+		}
+		return fmt.Sprint(zs[i].Member) < fmt.Sprint(zs[j].Member)
+	})
+	out := make([]HotKey, 0, min(n, len(zs)))
+	for _, z := range zs[:min(n, len(zs))] {
+		out = append(out, HotKey{Key: fmt.Sprint(z.Member), Count: int64(math.Round(z.Score))})
+	}
+	return out, nil
+}
+
+// Close 停止后台刷新协程，并把剩余的本地计数刷到 Redis。
+func (t *HotKeyTracker) Close(ctx context.Context) error {
+	t.closeOnce.Do(func() {
+		close(t.stop)
+	})
+	<-t.done
+	return t.Flush(ctx)
+}
+
+// flushLoop 每 FlushInterval 把本地计数刷到 Redis。
+func (t *HotKeyTracker) flushLoop() {
+	defer close(t.done)
+
+	ticker := time.NewTicker(t.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-t.stop:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), t.FlushInterval)
+			if err := t.Flush(ctx); err != nil && t.OnFlushError != nil {
+				t.OnFlushError(fmt.Errorf("hot key tracker: flush: %w", err))
+			}
+			cancel()
+		}
+	}
+}
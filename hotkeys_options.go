@@ -0,0 +1,61 @@
+package limiter
+
+import "time"
+
+// HotKeyOption 为热点 key 统计器的配置项。
+type HotKeyOption func(*HotKeyTracker)
+
+// WithHotKeyPrefix 设置 Redis key 前缀。
+func WithHotKeyPrefix(prefix string) HotKeyOption {
+	return func(t *HotKeyTracker) {
+		if prefix != "" {
+			t.Prefix = prefix
+		}
+	}
+}
+
+// WithHotKeyWindow 设置计数桶的时长与 TopKeys 合并的桶数，统计范围为 window*windows。
+func WithHotKeyWindow(window time.Duration, windows int) HotKeyOption {
+	return func(t *HotKeyTracker) {
+		if window < time.Millisecond || windows <= 0 {
+			panic("hot key tracker: window must >= 1ms and windows must > 0")
+		}
+		t.Window = window
+		t.Windows = windows
+	}
+}
+
+// WithHotKeySampleRate 设置采样比例，取值范围 (0, 1]。
+func WithHotKeySampleRate(rate float64) HotKeyOption {
+	return func(t *HotKeyTracker) {
+		if rate <= 0 || rate > 1 {
+			panic("hot key tracker: sample rate must be in (0, 1]")
+		}
+		t.SampleRate = rate
+	}
+}
+
+// WithHotKeyFlushInterval 设置本地计数刷到 Redis 的间隔。
+func WithHotKeyFlushInterval(d time.Duration) HotKeyOption {
+	return func(t *HotKeyTracker) {
+		if d > 0 {
+			t.FlushInterval = d
+		}
+	}
+}
+
+// WithHotKeyErrorHandler 设置刷新失败时的回调。
+func WithHotKeyErrorHandler(fn func(err error)) HotKeyOption {
+	return func(t *HotKeyTracker) {
+		t.OnFlushError = fn
+	}
+}
+
+// WithHotKeyClock 设置时钟，通常用于测试中控制时间。
+func WithHotKeyClock(c Clock) HotKeyOption {
+	return func(t *HotKeyTracker) {
+		if c != nil {
+			t.Clock = c
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package limiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHotKeyTracker(t *testing.T) {
+	client := newSpecClient(t)
+	ctx := context.Background()
+
+	// 计数桶通过 PEXPIREAT 过期，时钟需要与 Redis 大致一致
+	now := time.Now().Truncate(time.Minute)
+	tracker := NewHotKeyTracker(client,
+		WithHotKeyWindow(time.Minute, 2),
+		WithHotKeyFlushInterval(time.Hour),
+		WithHotKeyClock(ClockFunc(func() time.Time { return now })))
+	defer tracker.Close(ctx)
+
+	for _, tenant := range []string{"a", "b", "c"} {
+		fw := NewFixedWindowLimiter(client, "tenant:"+tenant,
+			WithFixedWindowLimit(1), WithFixedWindowWindow(time.Hour), WithFixedWindowHooks(tracker))
+		n := map[string]int{"a": 3, "b": 6, "c": 1}[tenant]
+		for i := 0; i < n; i++ {
+			_, err := fw.Allow(ctx)
+			assert.NoError(t, err)
+		}
+	}
+
+	// 未刷新前 Redis 中没有计数
+	top, err := tracker.TopKeys(ctx, 10)
+	assert.NoError(t, err)
+	assert.Empty(t, top)
+
+	assert.NoError(t, tracker.Flush(ctx))
+	top, err = tracker.TopKeys(ctx, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []HotKey{{Key: "tenant:b", Count: 5}, {Key: "tenant:a", Count: 2}}, top)
+
+	allowed, err := tracker.TopAllowedKeys(ctx, 10)
+	assert.NoError(t, err)
+	assert.Len(t, allowed, 3)
+
+	// 下一个桶的计数与上一个桶合并
+	now = now.Add(time.Minute)
+	tracker.OnDeny(ctx, HookEvent{Key: "tenant:c"})
+	tracker.OnDeny(ctx, HookEvent{Key: "tenant:a"})
+	assert.NoError(t, tracker.Flush(ctx))
+	top, err = tracker.TopKeys(ctx, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, []HotKey{{Key: "tenant:b", Count: 5}, {Key: "tenant:a", Count: 3}, {Key: "tenant:c", Count: 1}}, top)
+
+	// 超出 Window*Windows 的桶不再统计
+	now = now.Add(time.Minute)
+	top, err = tracker.TopKeys(ctx, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, []HotKey{{Key: "tenant:a", Count: 1}, {Key: "tenant:c", Count: 1}}, top)
+
+	_, err = tracker.TopKeys(ctx, 0)
+	assert.Error(t, err)
+}
@@ -0,0 +1,97 @@
+// Package httplimit 提供 net/http 限流中间件、对外请求限速的 RoundTripper 以及请求分类（Classifier）等能力。
+package httplimit
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+
+	limiter "github.com/lifei6671/go-redis-limiter"
+)
+
+// KeyFunc 从 HTTP 请求中提取限流 key，返回空字符串表示该请求不限流。
+type KeyFunc func(r *http.Request) string
+
+// KeyByIP 使用客户端 IP（RemoteAddr 的 host 部分）作为 key。
+// 部署在反向代理之后时，请改用 KeyByHeader("X-Real-IP") 等方式。
+func KeyByIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// KeyByHeader 使用指定请求头的值作为 key，例如 API Key、租户 ID。
+func KeyByHeader(name string) KeyFunc {
+	return func(r *http.Request) string {
+		return r.Header.Get(name)
+	}
+}
+
+// KeyByHost 使用请求的目标主机（含端口）作为 key，适合给对外请求按第三方服务限速（见 NewThrottledTransport）。
+func KeyByHost(r *http.Request) string {
+	return r.URL.Host
+}
+
+// KeyByRoute 使用 "METHOD path" 作为 key，适合按接口做全局限流。
+func KeyByRoute(r *http.Request) string {
+	return r.Method + " " + r.URL.Path
+}
+
+// Classifier 是 HTTP 请求的内置 limiter.Classifier 实现。
+type Classifier struct {
+	// Key 提取限流 key，必填
+	Key KeyFunc
+	// Cost 计算请求消耗，为空时每个请求消耗 1
+	Cost func(r *http.Request) int64
+	// Rule 计算规则名称，为空时使用 Name
+	Rule func(r *http.Request) string
+	// Name 默认规则名称
+	Name string
+}
+
+var _ limiter.Classifier = Classifier{}
+
+// NewClassifier 创建一个按 key 提取函数分类、每个请求消耗 1 的 HTTP Classifier。
+func NewClassifier(name string, key KeyFunc) Classifier {
+	if key == nil {
+		panic("httplimit: key func is nil")
+	}
+	return Classifier{Key: key, Name: name}
+}
+
+// Classify 实现 limiter.Classifier。req 不是 *http.Request 时返回空 key。
+func (c Classifier) Classify(_ context.Context, req any) (string, int64, string) {
+	r, ok := req.(*http.Request)
+	if !ok || c.Key == nil {
+		return "", 0, ""
+	}
+	key := c.Key(r)
+	if key == "" {
+		return "", 0, ""
+	}
+
+	cost := int64(1)
+	if c.Cost != nil {
+		cost = c.Cost(r)
+	}
+	rule := c.Name
+	if c.Rule != nil {
+		rule = c.Rule(r)
+	}
+	return key, cost, rule
+}
+
+// PathPrefix 返回一个仅对指定路径前缀生效的 Classifier，其余请求返回空 key。
+// 可配合 limiter.FirstMatch 为不同路由配置不同的规则。
+func PathPrefix(prefix string, c Classifier) limiter.Classifier {
+	return limiter.ClassifierFunc(func(ctx context.Context, req any) (string, int64, string) {
+		r, ok := req.(*http.Request)
+		if !ok || !strings.HasPrefix(r.URL.Path, prefix) {
+			return "", 0, ""
+		}
+		return c.Classify(ctx, r)
+	})
+}
@@ -0,0 +1,56 @@
+package httplimit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	limiter "github.com/lifei6671/go-redis-limiter"
+)
+
+func TestClassifier_Classify(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Classifier_ip", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/v1/chat", nil)
+		r.RemoteAddr = "10.0.0.1:5678"
+
+		key, cost, rule := NewClassifier("ip", KeyByIP).Classify(ctx, r)
+		assert.Equal(t, "10.0.0.1", key)
+		assert.Equal(t, int64(1), cost)
+		assert.Equal(t, "ip", rule)
+	})
+
+	t.Run("Classifier_not_http", func(t *testing.T) {
+		key, _, _ := NewClassifier("ip", KeyByIP).Classify(ctx, "not a request")
+		assert.Equal(t, "", key)
+	})
+
+	t.Run("Classifier_first_match", func(t *testing.T) {
+		upload := Classifier{
+			Key:  KeyByHeader("X-Api-Key"),
+			Cost: func(*http.Request) int64 { return 10 },
+			Name: "upload",
+		}
+		c := limiter.FirstMatch(
+			PathPrefix("/v1/upload", upload),
+			NewClassifier("route", KeyByRoute),
+		)
+
+		r := httptest.NewRequest(http.MethodPost, "/v1/upload/file", nil)
+		r.Header.Set("X-Api-Key", "k1")
+		key, cost, rule := c.Classify(ctx, r)
+		assert.Equal(t, "k1", key)
+		assert.Equal(t, int64(10), cost)
+		assert.Equal(t, "upload", rule)
+
+		r = httptest.NewRequest(http.MethodGet, "/v1/chat", nil)
+		key, cost, rule = c.Classify(ctx, r)
+		assert.Equal(t, "GET /v1/chat", key)
+		assert.Equal(t, int64(1), cost)
+		assert.Equal(t, "route", rule)
+	})
+}
@@ -0,0 +1,54 @@
+package httplimit
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	limiter "github.com/lifei6671/go-redis-limiter"
+)
+
+// 标准（IETF draft-ietf-httpapi-ratelimit-headers）与传统的限流响应头。
+const (
+	HeaderLimit     = "RateLimit-Limit"
+	HeaderRemaining = "RateLimit-Remaining"
+	HeaderReset     = "RateLimit-Reset"
+
+	HeaderXLimit     = "X-RateLimit-Limit"
+	HeaderXRemaining = "X-RateLimit-Remaining"
+	HeaderXReset     = "X-RateLimit-Reset"
+)
+
+// SetStateHeaders 根据 LimiterState 设置限流响应头：
+//   - RateLimit-Limit / X-RateLimit-Limit：Capacity
+//   - RateLimit-Remaining / X-RateLimit-Remaining：Remaining（向下取整）
+//   - RateLimit-Reset：距离 NextAvailableTime 的秒数（向上取整，已可用时为 0）
+//   - X-RateLimit-Reset：NextAvailableTime 对应的 Unix 时间戳（秒），与 GitHub 等 API 的惯例一致
+func SetStateHeaders(h http.Header, st limiter.LimiterState) {
+	next := time.UnixMilli(st.NextAvailableTime)
+	setHeaders(h, st.Capacity, st.Remaining, max(time.Until(next), 0))
+}
+
+// SetResultHeaders 根据一次判定的 Result 设置限流响应头，字段含义同 SetStateHeaders，
+// 重置时间取 RetryAfter。Limit 为 0（限流器未返回额度信息）时不设置任何头。
+func SetResultHeaders(h http.Header, res limiter.Result) {
+	if res.Limit <= 0 {
+		return
+	}
+	setHeaders(h, res.Limit, res.Remaining, res.RetryAfter)
+}
+
+func setHeaders(h http.Header, limit, remaining float64, reset time.Duration) {
+	limitStr := strconv.FormatInt(int64(limit), 10)
+	remainingStr := strconv.FormatInt(int64(max(math.Floor(remaining), 0)), 10)
+	resetSec := int64(math.Ceil(max(reset, 0).Seconds()))
+
+	h.Set(HeaderLimit, limitStr)
+	h.Set(HeaderRemaining, remainingStr)
+	h.Set(HeaderReset, strconv.FormatInt(resetSec, 10))
+
+	h.Set(HeaderXLimit, limitStr)
+	h.Set(HeaderXRemaining, remainingStr)
+	h.Set(HeaderXReset, strconv.FormatInt(time.Now().Add(reset).Unix(), 10))
+}
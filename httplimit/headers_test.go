@@ -0,0 +1,43 @@
+package httplimit
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	limiter "github.com/lifei6671/go-redis-limiter"
+)
+
+func TestSetStateHeaders(t *testing.T) {
+	next := time.Now().Add(2500 * time.Millisecond)
+	h := http.Header{}
+	SetStateHeaders(h, limiter.LimiterState{
+		Capacity:          100,
+		Remaining:         41.7,
+		NextAvailableTime: next.UnixMilli(),
+	})
+
+	assert.Equal(t, "100", h.Get(HeaderLimit))
+	assert.Equal(t, "41", h.Get(HeaderRemaining))
+	assert.Equal(t, "3", h.Get(HeaderReset))
+	assert.Equal(t, "100", h.Get(HeaderXLimit))
+	assert.Equal(t, "41", h.Get(HeaderXRemaining))
+
+	reset, err := strconv.ParseInt(h.Get(HeaderXReset), 10, 64)
+	assert.NoError(t, err)
+	assert.InDelta(t, next.Unix(), reset, 1)
+}
+
+func TestSetResultHeaders(t *testing.T) {
+	h := http.Header{}
+	SetResultHeaders(h, limiter.Result{Allowed: true})
+	assert.Empty(t, h)
+
+	SetResultHeaders(h, limiter.Result{Allowed: true, Limit: 10, Remaining: 9})
+	assert.Equal(t, "10", h.Get(HeaderLimit))
+	assert.Equal(t, "9", h.Get(HeaderRemaining))
+	assert.Equal(t, "0", h.Get(HeaderReset))
+}
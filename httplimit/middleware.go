@@ -0,0 +1,170 @@
+package httplimit
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	limiter "github.com/lifei6671/go-redis-limiter"
+)
+
+// Option 为 HTTP 中间件的配置项。
+type Option func(*config)
+
+type config struct {
+	onError  func(w http.ResponseWriter, r *http.Request, next http.Handler, err error)
+	onDenied func(w http.ResponseWriter, r *http.Request, res limiter.Result)
+	headers  bool
+}
+
+// WithErrorHandler 设置限流器出错（例如 Redis 不可用）时的处理方式。
+// 默认 fail-open：直接放行请求。如需 fail-close，可以在 handler 中返回 503。
+func WithErrorHandler(fn func(w http.ResponseWriter, r *http.Request, next http.Handler, err error)) Option {
+	return func(c *config) {
+		if fn != nil {
+			c.onError = fn
+		}
+	}
+}
+
+// WithDeniedHandler 设置被限流时的响应方式，默认返回 429 和 Retry-After 头。
+func WithDeniedHandler(fn func(w http.ResponseWriter, r *http.Request, res limiter.Result)) Option {
+	return func(c *config) {
+		if fn != nil {
+			c.onDenied = fn
+		}
+	}
+}
+
+// WithRateLimitHeaders 设置是否在响应中写出 RateLimit-* 与 X-RateLimit-* 头，默认开启。
+func WithRateLimitHeaders(enabled bool) Option {
+	return func(c *config) {
+		c.headers = enabled
+	}
+}
+
+// Middleware 返回一个 net/http 中间件：
+//   - 使用 keyFunc 从请求中提取 key（IP、请求头、路由等），key 为空时不限流；
+//   - 调用 l.AllowWithResult 判定；
+//   - 被限流时返回 429 Too Many Requests，并根据重试等待时间设置 Retry-After 头（秒）；
+//   - 无论是否放行，都会写出 RateLimit-* / X-RateLimit-* 头（见 SetResultHeaders）。
+func Middleware(l limiter.RateShardedLimiter, keyFunc KeyFunc, opts ...Option) func(http.Handler) http.Handler {
+	if keyFunc == nil {
+		panic("httplimit: key func is nil")
+	}
+	return ClassifierMiddleware(l, NewClassifier("", keyFunc), opts...)
+}
+
+// ClassifierMiddleware 与 Middleware 相同，但由 limiter.Classifier 决定 key 与消耗。
+// 消耗大于 1 时使用 AllowN 判定，重试等待时间取自 State().NextAvailableTime。
+func ClassifierMiddleware(l limiter.RateShardedLimiter, c limiter.Classifier, opts ...Option) func(http.Handler) http.Handler {
+	if l == nil {
+		panic("httplimit: limiter is nil")
+	}
+	if c == nil {
+		panic("httplimit: classifier is nil")
+	}
+
+	cfg := &config{
+		onError: func(w http.ResponseWriter, r *http.Request, next http.Handler, _ error) {
+			next.ServeHTTP(w, r)
+		},
+		onDenied: func(w http.ResponseWriter, _ *http.Request, res limiter.Result) {
+			WriteTooManyRequests(w, res.RetryAfter)
+		},
+		headers: true,
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			key, cost, _ := c.Classify(ctx, r)
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			res, err := Check(ctx, l, key, cost)
+			if err != nil {
+				cfg.onError(w, r, next, err)
+				return
+			}
+			if cfg.headers {
+				SetResultHeaders(w.Header(), res)
+			}
+			if !res.Allowed {
+				cfg.onDenied(w, r, res)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Check 对 key 执行一次判定，返回带重试等待时间的结果。消耗大于 1 时使用 AllowN 判定，
+// 重试等待时间取自 State().NextAvailableTime。供 Gin / Echo / Fiber 等框架的适配复用。
+func Check(ctx context.Context, l limiter.RateShardedLimiter, key string, cost int64) (limiter.Result, error) {
+	if cost <= 1 {
+		return l.AllowWithResult(ctx, key)
+	}
+
+	ok, err := l.AllowN(ctx, key, cost)
+	if err != nil || ok {
+		return limiter.Result{Allowed: ok}, err
+	}
+	res := limiter.Result{}
+	if st, err := l.State(ctx, key); err == nil {
+		res.Limit = st.Capacity
+		res.Remaining = st.Remaining
+		res.RetryAfter = max(time.Until(time.UnixMilli(st.NextAvailableTime)), 0)
+	}
+	return res, nil
+}
+
+// RetryAfterSeconds 将等待时长转换为 Retry-After 头使用的整秒数（向上取整，最小为 1）。
+func RetryAfterSeconds(d time.Duration) int64 {
+	return max(int64(math.Ceil(d.Seconds())), 1)
+}
+
+// WriteTooManyRequests 写出 429 响应，并设置 Retry-After 头。
+func WriteTooManyRequests(w http.ResponseWriter, retryAfter time.Duration) {
+	w.Header().Set("Retry-After", strconv.FormatInt(RetryAfterSeconds(retryAfter), 10))
+	http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+}
+
+// WriteLimitError 在 err 为限流错误（limiter.IsRetryable）时写出 429 响应并返回 true：
+// err 为 *limiter.LimitExceededError 时 Retry-After 与 RateLimit-* 头取自错误中携带的额度信息，
+// 只包装了 ErrLimiter / ErrTimeout 时只设置 Retry-After。
+// 其他错误不做处理，返回 false。适合在 handler 中处理 Wait / Acquire 等返回的错误。
+func WriteLimitError(w http.ResponseWriter, err error) bool {
+	retryAfter, ok := limiter.IsRetryable(err)
+	if !ok {
+		return false
+	}
+	var le *limiter.LimitExceededError
+	if errors.As(err, &le) {
+		h := w.Header()
+		h.Set(HeaderRemaining, strconv.FormatInt(int64(max(math.Floor(le.Remaining), 0)), 10))
+		h.Set(HeaderReset, strconv.FormatInt(RetryAfterSeconds(le.RetryAfter), 10))
+	}
+	WriteTooManyRequests(w, retryAfter)
+	return true
+}
+
+// StatusCode 返回 err 对应的 HTTP 状态码：nil 为 200，限流错误为 429，
+// 其他错误（Redis 故障、熔断打开等，限流器不可用）为 503。
+func StatusCode(err error) int {
+	if err == nil {
+		return http.StatusOK
+	}
+	if _, ok := limiter.IsRetryable(err); ok {
+		return http.StatusTooManyRequests
+	}
+	return http.StatusServiceUnavailable
+}
@@ -0,0 +1,158 @@
+package httplimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	limiter "github.com/lifei6671/go-redis-limiter"
+)
+
+// fakeLimiter 按 key 计数，超过 limit 后拒绝。
+type fakeLimiter struct {
+	limit int64
+	used  map[string]int64
+	err   error
+}
+
+func (f *fakeLimiter) Allow(ctx context.Context, key string) (bool, error) {
+	return f.AllowN(ctx, key, 1)
+}
+
+func (f *fakeLimiter) AllowWithResult(ctx context.Context, key string) (limiter.Result, error) {
+	ok, err := f.AllowN(ctx, key, 1)
+	res := limiter.Result{Allowed: ok, Limit: float64(f.limit), Remaining: float64(f.limit - f.used[key])}
+	if !ok {
+		res.RetryAfter = 1500 * time.Millisecond
+	}
+	return res, err
+}
+
+func (f *fakeLimiter) AllowN(_ context.Context, key string, n int64) (bool, error) {
+	if f.err != nil {
+		return false, f.err
+	}
+	if f.used[key]+n > f.limit {
+		return false, nil
+	}
+	f.used[key] += n
+	return true, nil
+}
+
+func (f *fakeLimiter) Reset(_ context.Context, key string) error {
+	delete(f.used, key)
+	return nil
+}
+
+func (f *fakeLimiter) ResetAll(context.Context) error {
+	clear(f.used)
+	return nil
+}
+
+func (f *fakeLimiter) State(_ context.Context, key string) (limiter.LimiterState, error) {
+	return limiter.LimiterState{
+		Capacity:          float64(f.limit),
+		Remaining:         float64(f.limit - f.used[key]),
+		NextAvailableTime: time.Now().Add(3 * time.Second).UnixMilli(),
+	}, nil
+}
+
+func (f *fakeLimiter) Wait(context.Context, string, time.Duration) error {
+	return nil
+}
+
+func TestMiddleware(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	t.Run("Middleware_deny_with_retry_after", func(t *testing.T) {
+		l := &fakeLimiter{limit: 1, used: map[string]int64{}}
+		h := Middleware(l, KeyByHeader("X-User"))(ok)
+
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("X-User", "u1")
+
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		w = httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		assert.Equal(t, "2", w.Header().Get("Retry-After"))
+		assert.Equal(t, "1", w.Header().Get(HeaderLimit))
+		assert.Equal(t, "0", w.Header().Get(HeaderRemaining))
+		assert.Equal(t, "2", w.Header().Get(HeaderReset))
+	})
+
+	t.Run("Middleware_without_headers", func(t *testing.T) {
+		l := &fakeLimiter{limit: 1, used: map[string]int64{}}
+		h := Middleware(l, KeyByRoute, WithRateLimitHeaders(false))(ok)
+
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, w.Header().Get(HeaderLimit))
+	})
+
+	t.Run("Middleware_empty_key_passthrough", func(t *testing.T) {
+		l := &fakeLimiter{limit: 0, used: map[string]int64{}}
+		h := Middleware(l, KeyByHeader("X-User"))(ok)
+
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("Middleware_fail_open", func(t *testing.T) {
+		l := &fakeLimiter{limit: 0, used: map[string]int64{}, err: errors.New("redis down")}
+		h := Middleware(l, KeyByRoute)(ok)
+
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("Middleware_cost_uses_state", func(t *testing.T) {
+		l := &fakeLimiter{limit: 5, used: map[string]int64{}}
+		c := Classifier{Key: KeyByRoute, Cost: func(*http.Request) int64 { return 10 }}
+		h := ClassifierMiddleware(l, c)(ok)
+
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		assert.Equal(t, "3", w.Header().Get("Retry-After"))
+	})
+}
+
+func TestWriteLimitError(t *testing.T) {
+	w := httptest.NewRecorder()
+	assert.False(t, WriteLimitError(w, errors.New("redis down")))
+
+	err := fmt.Errorf("wait: %w", &limiter.LimitExceededError{Remaining: 0, RetryAfter: 2500 * time.Millisecond})
+	assert.True(t, WriteLimitError(w, err))
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "3", w.Header().Get("Retry-After"))
+	assert.Equal(t, "0", w.Header().Get(HeaderRemaining))
+
+	w = httptest.NewRecorder()
+	assert.True(t, WriteLimitError(w, fmt.Errorf("wait: %w", limiter.ErrTimeout)))
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "1", w.Header().Get("Retry-After"))
+	assert.Empty(t, w.Header().Get(HeaderRemaining))
+}
+
+func TestStatusCode(t *testing.T) {
+	assert.Equal(t, http.StatusOK, StatusCode(nil))
+	assert.Equal(t, http.StatusTooManyRequests, StatusCode(&limiter.LimitExceededError{Timeout: true}))
+	assert.Equal(t, http.StatusTooManyRequests, StatusCode(limiter.ErrLimiter))
+	assert.Equal(t, http.StatusServiceUnavailable, StatusCode(errors.New("redis down")))
+	assert.Equal(t, http.StatusServiceUnavailable, StatusCode(limiter.ErrCircuitOpen))
+}
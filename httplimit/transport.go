@@ -0,0 +1,97 @@
+package httplimit
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	limiter "github.com/lifei6671/go-redis-limiter"
+)
+
+// TransportOption 为 ThrottledTransport 的配置项。
+type TransportOption func(*ThrottledTransport)
+
+// WithTransportMaxWait 设置每个请求最多等待多久，默认 30s。
+// 请求的 ctx 带有更早的截止时间时以 ctx 为准。
+func WithTransportMaxWait(d time.Duration) TransportOption {
+	return func(t *ThrottledTransport) {
+		if d >= 0 {
+			t.maxWait = d
+		}
+	}
+}
+
+// WithTransportErrorHandler 设置限流器出错（例如 Redis 不可用）时的处理方式：
+// 返回 nil 表示继续发送请求，返回非 nil 错误则直接作为 RoundTrip 的错误返回。
+// 默认 fail-open：继续发送请求。
+func WithTransportErrorHandler(fn func(r *http.Request, err error) error) TransportOption {
+	return func(t *ThrottledTransport) {
+		if fn != nil {
+			t.onError = fn
+		}
+	}
+}
+
+// ThrottledTransport 是对外发请求限速的 http.RoundTripper，见 NewThrottledTransport。
+type ThrottledTransport struct {
+	base    http.RoundTripper
+	l       limiter.RateShardedLimiter
+	keyFunc KeyFunc
+
+	maxWait time.Duration
+	onError func(r *http.Request, err error) error
+}
+
+// NewThrottledTransport 返回一个在每次发送请求前调用 l.Wait 的 http.RoundTripper，
+// 用于在所有实例之间共同遵守第三方 API 的限额：
+//   - 使用 keyFunc 从请求中提取 key（例如 KeyByHost、KeyByHeader("Authorization")），key 为空时不限速；
+//   - 等待超过 maxWait 或请求的 ctx 截止时间时不发送请求，返回 *limiter.LimitExceededError
+//     （http.Client 会把它包装进 *url.Error，仍可用 errors.Is(err, limiter.ErrLimiter) 判断）；
+//   - base 为 nil 时使用 http.DefaultTransport。
+func NewThrottledTransport(base http.RoundTripper, l limiter.RateShardedLimiter, keyFunc KeyFunc, opts ...TransportOption) *ThrottledTransport {
+	if l == nil {
+		panic("httplimit: limiter is nil")
+	}
+	if keyFunc == nil {
+		panic("httplimit: key func is nil")
+	}
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	t := &ThrottledTransport{
+		base:    base,
+		l:       l,
+		keyFunc: keyFunc,
+		maxWait: 30 * time.Second,
+		onError: func(*http.Request, error) error { return nil },
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+// RoundTrip 实现 http.RoundTripper。
+func (t *ThrottledTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	key := t.keyFunc(r)
+	if key == "" {
+		return t.base.RoundTrip(r)
+	}
+
+	ctx := r.Context()
+	maxWait := t.maxWait
+	if deadline, ok := ctx.Deadline(); ok {
+		maxWait = min(maxWait, time.Until(deadline))
+	}
+
+	if err := t.l.Wait(ctx, key, maxWait); err != nil {
+		if errors.Is(err, limiter.ErrLimiter) || ctx.Err() != nil {
+			return nil, err
+		}
+		if err := t.onError(r, err); err != nil {
+			return nil, err
+		}
+	}
+	return t.base.RoundTrip(r)
+}
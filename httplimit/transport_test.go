@@ -0,0 +1,87 @@
+package httplimit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	limiter "github.com/lifei6671/go-redis-limiter"
+)
+
+// waitLimiter 记录 Wait 的调用，并按 err 返回。
+type waitLimiter struct {
+	fakeLimiter
+	keys     []string
+	maxWaits []time.Duration
+	err      error
+}
+
+func (w *waitLimiter) Wait(_ context.Context, key string, maxWait time.Duration) error {
+	w.keys = append(w.keys, key)
+	w.maxWaits = append(w.maxWaits, maxWait)
+	return w.err
+}
+
+func TestThrottledTransport(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	t.Run("ThrottledTransport_wait_by_host", func(t *testing.T) {
+		l := &waitLimiter{}
+		client := &http.Client{Transport: NewThrottledTransport(nil, l, KeyByHost, WithTransportMaxWait(time.Second))}
+
+		resp, err := client.Get(srv.URL)
+		assert.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, []string{srv.Listener.Addr().String()}, l.keys)
+		assert.Equal(t, time.Second, l.maxWaits[0])
+
+		// ctx 的截止时间更早时以 ctx 为准
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+		resp, err = client.Do(req)
+		assert.NoError(t, err)
+		resp.Body.Close()
+		assert.LessOrEqual(t, l.maxWaits[1], 100*time.Millisecond)
+	})
+
+	t.Run("ThrottledTransport_limited", func(t *testing.T) {
+		l := &waitLimiter{err: &limiter.LimitExceededError{Key: "k", Timeout: true}}
+		client := &http.Client{Transport: NewThrottledTransport(nil, l, KeyByHost)}
+
+		_, err := client.Get(srv.URL)
+		assert.ErrorIs(t, err, limiter.ErrLimiter)
+		assert.ErrorIs(t, err, limiter.ErrTimeout)
+	})
+
+	t.Run("ThrottledTransport_error_handler", func(t *testing.T) {
+		l := &waitLimiter{err: errors.New("redis down")}
+
+		// 默认 fail-open
+		resp, err := (&http.Client{Transport: NewThrottledTransport(nil, l, KeyByHost)}).Get(srv.URL)
+		assert.NoError(t, err)
+		resp.Body.Close()
+
+		failClose := NewThrottledTransport(nil, l, KeyByHost,
+			WithTransportErrorHandler(func(_ *http.Request, err error) error { return err }))
+		_, err = (&http.Client{Transport: failClose}).Get(srv.URL)
+		assert.ErrorContains(t, err, "redis down")
+	})
+
+	t.Run("ThrottledTransport_empty_key", func(t *testing.T) {
+		l := &waitLimiter{}
+		tr := NewThrottledTransport(nil, l, KeyByHeader("X-Api-Key"))
+		resp, err := (&http.Client{Transport: tr}).Get(srv.URL)
+		assert.NoError(t, err)
+		resp.Body.Close()
+		assert.Empty(t, l.keys)
+	})
+}
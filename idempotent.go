@@ -0,0 +1,68 @@
+package limiter
+
+import (
+	"fmt"
+	"time"
+)
+
+// withIdempotency 为限流脚本加上按请求 ID 去重的能力，开启时调用方在参数末尾（统计参数之后）追加：
+//   - KEYS：idemKey（记录该请求 ID 的判定结果）
+//   - ARGV："idem:<ttlMs>"
+//
+// 包装后的脚本先把这些参数从 KEYS / ARGV 中移除，原脚本看到的参数布局保持不变：
+//   - idemKey 存在：说明同一个请求 ID 已经被放行过，直接返回记录的判定，不再执行原脚本（也不计入统计）
+//   - idemKey 不存在：执行原脚本，放行时把返回值记录到 idemKey 并设置 ttlMs 过期
+//
+// 被拒绝的判定不记录：拒绝没有扣减任何额度，同一个请求在 RetryAfter 之后重试应当重新判定。
+// 开启时返回值末尾追加一个元素，1 表示命中了之前的判定；未追加参数时脚本行为与原脚本完全相同。
+func withIdempotency(body string) string {
+	return `
+local idemKey, idemTTL
+do
+  local m = ARGV[#ARGV]
+  if type(m) == "string" and string.sub(m, 1, 5) == "idem:" then
+    idemTTL = tonumber(string.sub(m, 6))
+    ARGV[#ARGV] = nil
+    idemKey = table.remove(KEYS)
+  end
+end
+
+local function idempotentBody()
+` + body + `
+end
+
+if idemKey then
+  local prev = redis.call("GET", idemKey)
+  if prev then
+    local res = {}
+    for v in string.gmatch(prev, "[^,]+") do
+      res[#res + 1] = tonumber(v)
+    end
+    res[#res + 1] = 1
+    return res
+  end
+end
+
+local res = idempotentBody()
+if idemKey then
+  if tonumber(res[1]) > 0 then
+    redis.call("SET", idemKey, table.concat(res, ","), "PX", idemTTL)
+  end
+  res[#res + 1] = 0
+end
+return res
+`
+}
+
+// idempotencyKey 返回记录请求 ID 判定结果的 key，形如 "tbucket:{key}:req:<requestID>"。
+// 与限流状态共用 {key} 作为 hash tag，保证 Redis Cluster 中脚本访问的 key 落在同一 slot。
+func idempotencyKey(prefix, key, requestID string) string {
+	return fmt.Sprintf("%s:{%s}:req:%s", prefix, key, requestID)
+}
+
+// appendIdempotency 把去重参数追加到脚本的 KEYS / ARGV 末尾，见 withIdempotency。
+func appendIdempotency(keys []string, args []interface{}, idemKey string, ttl time.Duration) ([]string, []interface{}) {
+	keys = append(keys, idemKey)
+	args = append(args, fmt.Sprintf("idem:%d", ttl.Milliseconds()))
+	return keys, args
+}
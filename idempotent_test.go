@@ -0,0 +1,68 @@
+package limiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenBucket_AllowIdempotent(t *testing.T) {
+	client := newSpecClient(t)
+	ctx := context.Background()
+
+	tb := NewTokenBucketLimiter(client, "consumer", WithTokenBucketRate(0.001), WithTokenBucketCapacity(2),
+		WithTokenBucketStats(time.Hour), WithTokenBucketOverrides("limits"))
+
+	res, err := tb.AllowIdempotent(ctx, "msg-1")
+	assert.NoError(t, err)
+	assert.Equal(t, Result{Allowed: true, Limit: 2, Remaining: 1}, res)
+
+	// 同一条消息重试：返回原来的判定，不再扣减
+	for i := 0; i < 3; i++ {
+		res, err = tb.AllowIdempotent(ctx, "msg-1")
+		assert.NoError(t, err)
+		assert.Equal(t, Result{Allowed: true, Limit: 2, Remaining: 1, Duplicate: true}, res)
+	}
+
+	res, err = tb.AllowIdempotent(ctx, "msg-2")
+	assert.NoError(t, err)
+	assert.True(t, res.Allowed)
+	assert.False(t, res.Duplicate)
+	assert.Equal(t, float64(0), res.Remaining)
+
+	// 被拒绝的请求不记录，重试时重新判定
+	res, err = tb.AllowIdempotent(ctx, "msg-3")
+	assert.NoError(t, err)
+	assert.False(t, res.Allowed)
+	assert.NoError(t, tb.Reset(ctx))
+	res, err = tb.AllowIdempotent(ctx, "msg-3")
+	assert.NoError(t, err)
+	assert.True(t, res.Allowed)
+	assert.False(t, res.Duplicate)
+
+	// 重复的调用不计入统计
+	stats, err := tb.Stats(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, LimiterStats{Allowed: 3, Denied: 1}, stats)
+
+	_, err = tb.AllowIdempotent(ctx, "")
+	assert.Error(t, err)
+}
+
+func TestTokenBucket_AllowIdempotentTTL(t *testing.T) {
+	client := newSpecClient(t)
+	ctx := context.Background()
+
+	tb := NewTokenBucketLimiter(client, "consumer", WithTokenBucketRate(0.001), WithTokenBucketCapacity(5),
+		WithTokenBucketIdempotencyTTL(time.Minute))
+
+	_, err := tb.AllowNIdempotent(ctx, "msg-1", 2)
+	assert.NoError(t, err)
+
+	ttl, err := client.PTTL(ctx, "tbucket:{consumer}:req:msg-1").Result()
+	assert.NoError(t, err)
+	assert.Greater(t, ttl, time.Duration(0))
+	assert.LessOrEqual(t, ttl, time.Minute)
+}
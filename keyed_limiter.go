@@ -0,0 +1,143 @@
+package limiter
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// KeyedLimiter 按 key 懒创建并缓存 RateLimiter，适用于“每个用户 / 每个 IP 一个限流器”的场景。
+//   - 第一次访问某个 key 时调用 factory 创建限流器；
+//   - 缓存使用 LRU 淘汰，最多保留 Size 个限流器，内存占用有上界；
+//   - 限流状态本身保存在 Redis 中，被淘汰的 key 再次访问时重新创建即可，不会丢失计数。
+//
+// KeyedLimiter 实现了 RateShardedLimiter，可直接用于 httplimit / grpclimit 中间件。
+type KeyedLimiter struct {
+	factory func(key string) RateLimiter
+
+	// Size 最多缓存的限流器数量，默认 10000
+	Size int
+	// OnEvict 限流器被淘汰时的回调（可选），例如关闭 BatchedTokenBucketLimiter
+	OnEvict func(key string, l RateLimiter)
+
+	mu    sync.Mutex
+	ll    *list.List // 最近使用的在队头
+	items map[string]*list.Element
+}
+
+type keyedEntry struct {
+	key     string
+	limiter RateLimiter
+}
+
+var _ RateShardedLimiter = (*KeyedLimiter)(nil)
+
+// NewKeyedLimiter 创建一个按 key 缓存限流器的管理器。
+func NewKeyedLimiter(factory func(key string) RateLimiter, opts ...KeyedLimiterOption) *KeyedLimiter {
+	if factory == nil {
+		panic("keyed limiter: factory is nil")
+	}
+
+	k := &KeyedLimiter{
+		factory: factory,
+		Size:    10000,
+		ll:      list.New(),
+		items:   make(map[string]*list.Element),
+	}
+	for _, opt := range opts {
+		opt(k)
+	}
+	return k
+}
+
+// Get 返回 key 对应的限流器，不存在时创建，并标记为最近使用。
+func (k *KeyedLimiter) Get(key string) RateLimiter {
+	k.mu.Lock()
+	if el, ok := k.items[key]; ok {
+		k.ll.MoveToFront(el)
+		k.mu.Unlock()
+		return el.Value.(*keyedEntry).limiter
+	}
+
+	l := k.factory(key)
+	k.items[key] = k.ll.PushFront(&keyedEntry{key: key, limiter: l})
+
+	var evicted []*keyedEntry
+	for k.ll.Len() > k.Size {
+		el := k.ll.Back()
+		k.ll.Remove(el)
+		e := el.Value.(*keyedEntry)
+		delete(k.items, e.key)
+		evicted = append(evicted, e)
+	}
+	k.mu.Unlock()
+
+	// 回调可能比较慢（例如访问 Redis），放在锁外执行
+	if k.OnEvict != nil {
+		for _, e := range evicted {
+			k.OnEvict(e.key, e.limiter)
+		}
+	}
+	return l
+}
+
+// Len 返回当前缓存的限流器数量。
+func (k *KeyedLimiter) Len() int {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return k.ll.Len()
+}
+
+func (k *KeyedLimiter) Allow(ctx context.Context, key string) (bool, error) {
+	return k.Get(key).Allow(ctx)
+}
+
+func (k *KeyedLimiter) AllowWithResult(ctx context.Context, key string) (Result, error) {
+	return k.Get(key).AllowWithResult(ctx)
+}
+
+func (k *KeyedLimiter) AllowN(ctx context.Context, key string, n int64) (bool, error) {
+	return k.Get(key).AllowN(ctx, n)
+}
+
+// AllowKeys 对每个 key 各获取 1 个许可，返回与 keys 一一对应的结果。
+// 令牌桶、漏桶、滑动窗口与固定窗口的脚本调用会合并到一个 pipeline 中（每个 Redis 客户端一次往返），
+// 适合每个周期检查成百上千个租户的批处理任务；其他限流器（例如包装器）逐个判定。
+// 单个 key 出错不影响其他 key：err 汇总全部出错 key 的错误，各自的错误见 KeyResult.Err。
+func (k *KeyedLimiter) AllowKeys(ctx context.Context, keys []string) ([]KeyResult, error) {
+	limiters := make([]RateLimiter, len(keys))
+	for i, key := range keys {
+		limiters[i] = k.Get(key)
+	}
+	return allowAll(ctx, keys, limiters)
+}
+
+func (k *KeyedLimiter) Wait(ctx context.Context, key string, maxWait time.Duration) error {
+	return k.Get(key).Wait(ctx, maxWait)
+}
+
+func (k *KeyedLimiter) State(ctx context.Context, key string) (LimiterState, error) {
+	return k.Get(key).State(ctx)
+}
+
+func (k *KeyedLimiter) Reset(ctx context.Context, key string) error {
+	return k.Get(key).Reset(ctx)
+}
+
+// ResetAll 重置当前缓存中的全部限流器。已被淘汰的 key 不在其中，需要单独调用 Reset。
+func (k *KeyedLimiter) ResetAll(ctx context.Context) error {
+	k.mu.Lock()
+	limiters := make([]RateLimiter, 0, k.ll.Len())
+	for el := k.ll.Front(); el != nil; el = el.Next() {
+		limiters = append(limiters, el.Value.(*keyedEntry).limiter)
+	}
+	k.mu.Unlock()
+
+	for _, l := range limiters {
+		if err := l.Reset(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,20 @@
+package limiter
+
+// KeyedLimiterOption 为按 key 缓存限流器的配置项。
+type KeyedLimiterOption func(*KeyedLimiter)
+
+// WithKeyedLimiterSize 设置最多缓存的限流器数量。
+func WithKeyedLimiterSize(n int) KeyedLimiterOption {
+	return func(k *KeyedLimiter) {
+		if n > 0 {
+			k.Size = n
+		}
+	}
+}
+
+// WithKeyedLimiterOnEvict 设置限流器被 LRU 淘汰时的回调。
+func WithKeyedLimiterOnEvict(fn func(key string, l RateLimiter)) KeyedLimiterOption {
+	return func(k *KeyedLimiter) {
+		k.OnEvict = fn
+	}
+}
@@ -0,0 +1,49 @@
+package limiter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyedLimiter(t *testing.T) {
+	client := newSpecClient(t)
+	ctx := context.Background()
+
+	created := map[string]int{}
+	var evicted []string
+	k := NewKeyedLimiter(func(key string) RateLimiter {
+		created[key]++
+		return NewTokenBucketLimiter(client, "user:"+key,
+			WithTokenBucketRate(0.001), WithTokenBucketCapacity(1))
+	},
+		WithKeyedLimiterSize(2),
+		WithKeyedLimiterOnEvict(func(key string, _ RateLimiter) {
+			evicted = append(evicted, key)
+		}),
+	)
+
+	ok, _ := k.Allow(ctx, "a")
+	assert.True(t, ok)
+	ok, _ = k.Allow(ctx, "a")
+	assert.False(t, ok)
+	ok, _ = k.Allow(ctx, "b")
+	assert.True(t, ok)
+
+	// 访问 a 使其成为最近使用，插入 c 时淘汰 b
+	k.Get("a")
+	k.Get("c")
+	assert.Equal(t, 2, k.Len())
+	assert.Equal(t, []string{"b"}, evicted)
+
+	// 被淘汰的 key 重新创建，计数仍保存在 Redis 中
+	ok, _ = k.Allow(ctx, "b")
+	assert.False(t, ok)
+	assert.Equal(t, 2, created["b"])
+	assert.Equal(t, 1, created["a"])
+
+	assert.NoError(t, k.Reset(ctx, "b"))
+	ok, _ = k.Allow(ctx, "b")
+	assert.True(t, ok)
+}
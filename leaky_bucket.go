@@ -2,9 +2,9 @@ package limiter
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/go-redis/redis/v8"
@@ -18,6 +18,9 @@ import (
 type LeakyBucketLimiter struct {
 	client *redis.Client
 
+	// mu 保护 LeakRate/Capacity 的运行时修改，创建后请通过 SetRate/SetCapacity 修改
+	mu sync.RWMutex
+
 	Key    string // 业务维度限流 key，例如 "api:/v1/login"、"user:123"
 	Prefix string // Redis key 前缀，默认 "lb"
 	// LeakRate 泄漏速率：单位/秒（例如每秒“漏掉”多少请求）
@@ -26,6 +29,39 @@ type LeakyBucketLimiter struct {
 	Capacity float64
 	// TTL Redis key 过期时间：建议 >= “等价时间窗口”的 2 倍
 	TTL time.Duration
+	// TTLJitter TTL 抖动比例（0~1），用于打散大量 key 的过期时间，默认 0（不抖动）
+	TTLJitter float64
+	// WaitJitter Wait 重试前的随机抖动比例（0~1）
+	WaitJitter float64
+	// WaitStrategy Wait 被拒绝后的等待策略（可选），默认按 RetryAfter 等待并叠加 WaitJitter，见 WaitStrategy。
+	WaitStrategy WaitStrategy
+	// Wakeup Pub/Sub 唤醒器（可选）。设置后 ReturnN / Reset 会唤醒本 key 上阻塞中的 Wait，见 Wakeup。
+	Wakeup *Wakeup
+
+	// ClockSkewThreshold 时钟回拨告警阈值，超过后触发 OnClockSkew
+	ClockSkewThreshold time.Duration
+	// OnClockSkew 时钟回拨回调（可选），回拨的时间总会被脚本钳制到已记录的最大时间戳
+	OnClockSkew func(key string, skew time.Duration)
+
+	// OverridePrefix 按 key 覆盖配置的 hash 前缀，为空表示不开启覆盖模式
+	OverridePrefix string
+
+	// ServerTime 为 true（默认）时脚本使用 Redis TIME 作为当前时间，
+	// 所有应用实例共享同一个时钟源；为 false 时使用本机时钟。
+	ServerTime bool
+
+	// Clock 时钟，默认 SystemClock。
+	Clock Clock
+
+	// Storage 状态的存储方式，默认 StorageString。
+	Storage StorageMode
+
+	// StatsTTL 按 key 统计放行 / 拒绝数量时计数器的过期时间（每次写入时刷新），0 表示不统计（默认）。
+	// 计数由限流脚本原子累加到 "<Prefix>:{Key}:stats:allowed" / "denied"，通过 Stats 读取。
+	StatsTTL time.Duration
+
+	// Hooks 判定事件钩子（可选），每次放行 / 拒绝 / 出错时回调，见 Hooks。
+	Hooks Hooks
 }
 
 // NewLeakyBucketLimiter 创建一个“单桶”的漏桶限流器。
@@ -45,12 +81,14 @@ func NewLeakyBucketLimiter(
 	}
 
 	l := &LeakyBucketLimiter{
-		client:   client,
-		Key:      key,
-		Prefix:   "lb",
-		LeakRate: 100,             // 默认每秒泄漏100单位
-		Capacity: 100,             // 默认桶容量100
-		TTL:      2 * time.Second, // 默认TTL
+		client:     client,
+		Key:        key,
+		Prefix:     "lb",
+		LeakRate:   100,             // 默认每秒泄漏100单位
+		Capacity:   100,             // 默认桶容量100
+		TTL:        2 * time.Second, // 默认TTL
+		ServerTime: true,
+		Clock:      SystemClock,
 	}
 
 	for _, opt := range opts {
@@ -70,84 +108,218 @@ func (l *LeakyBucketLimiter) tsKey() string {
 	return fmt.Sprintf("%s:{%s}:ts", l.Prefix, l.Key)
 }
 
+// stateKey 返回 hash 存储模式下保存 level / ts 两个字段的 key。
+func (l *LeakyBucketLimiter) stateKey() string {
+	return fmt.Sprintf("%s:{%s}:state", l.Prefix, l.Key)
+}
+
+// stateKeys 返回脚本的 KEYS[1] / KEYS[2]。
+// hash 存储模式下两者为同一个 key，脚本据此改用 hash 字段读写。
+func (l *LeakyBucketLimiter) stateKeys() (string, string) {
+	if l.Storage == StorageHash {
+		return l.stateKey(), l.stateKey()
+	}
+	return l.bucketKey(), l.tsKey()
+}
+
+// overrideKey 返回按 key 覆盖配置的 hash key。
+func (l *LeakyBucketLimiter) overrideKey() string {
+	return overrideKey(l.OverridePrefix, l.Key)
+}
+
 // Allow 尝试获取一个“许可”(1单位)，返回是否允许。
 func (l *LeakyBucketLimiter) Allow(ctx context.Context) (bool, error) {
 	return l.AllowN(ctx, 1)
 }
 
+// AllowWithResult 尝试获取一个许可，并返回剩余空间及重试等待时间。
+func (l *LeakyBucketLimiter) AllowWithResult(ctx context.Context) (Result, error) {
+	return l.AllowNWithResult(ctx, 1)
+}
+
 // AllowN 尝试获取 n 个许可。
 // 对漏桶来说，相当于往桶里加 n 单位的水。
 func (l *LeakyBucketLimiter) AllowN(ctx context.Context, n int64) (bool, error) {
-	if n <= 0 {
-		return false, fmt.Errorf("leaky bucket: n must > 0")
+	res, err := l.AllowNWithResult(ctx, n)
+	if err != nil {
+		return false, err
 	}
+	return res.Allowed, nil
+}
 
-	nowMs := float64(time.Now().UnixNano() / 1e6)
-	ttlMs := l.TTL.Milliseconds()
+// AllowNWithResult 尝试获取 n 个许可，并返回剩余空间及重试等待时间。
+func (l *LeakyBucketLimiter) AllowNWithResult(ctx context.Context, n int64) (Result, error) {
+	admitted, res, err := l.run(ctx, n, false, false)
+	res.Allowed = admitted == 1
+	fireHooks(ctx, l.Hooks, "leaky_bucket", l.Key, n, res, err)
+	if err != nil {
+		return Result{}, err
+	}
+	return res, nil
+}
 
-	res, err := leakyBucketScript.Run(
-		ctx,
-		l.client,
-		[]string{l.bucketKey(), l.tsKey()},
-		nowMs,
-		l.LeakRate,
-		l.Capacity,
-		float64(n),
-		ttlMs,
-	).Result()
+// Check 判断当前是否能放入 1 单位的水，但不写入。
+func (l *LeakyBucketLimiter) Check(ctx context.Context) (Result, error) {
+	return l.CheckN(ctx, 1)
+}
+
+// CheckN 判断当前是否能放入 n 单位的水，但不写入。
+// 脚本同样会按流逝时间漏水并完成判定，只是不回写水位；
+// 放行时 Remaining 为当前剩余空间（未扣除 n）。
+func (l *LeakyBucketLimiter) CheckN(ctx context.Context, n int64) (Result, error) {
+	admitted, res, err := l.run(ctx, n, false, true)
 	if err != nil {
-		return false, err
+		return Result{}, err
 	}
+	res.Allowed = admitted == 1
+	return res, nil
+}
 
-	switch v := res.(type) {
-	case int64:
-		return v == 1, nil
-	case int:
-		return int64(v) == 1, nil
-	default:
-		return false, fmt.Errorf("unexpected script result: %#v", res)
+// AllowUpToN 尝试获取最多 n 个许可，返回实际获取的数量（部分放行）。
+// 与 AllowN 的“全部或全不”不同，桶中剩余空间不足 n 时会尽量放入能容纳的部分，
+// 适合流式写入等“部分推进优于整批反复被拒”的场景。返回 0 表示桶已满。
+func (l *LeakyBucketLimiter) AllowUpToN(ctx context.Context, n int64) (int64, error) {
+	admitted, res, err := l.run(ctx, n, true, false)
+	fireHooks(ctx, l.Hooks, "leaky_bucket", l.Key, n, res, err)
+	return admitted, err
+}
+
+// run 执行漏桶脚本，返回脚本的 result 字段（是否放行 / 实际放入数量）以及剩余空间等信息。
+// dryRun 为 true 时只判定不写入。
+func (l *LeakyBucketLimiter) run(ctx context.Context, n int64, partial, dryRun bool) (int64, Result, error) {
+	if n <= 0 {
+		return 0, Result{}, fmt.Errorf("leaky bucket: n must > 0")
 	}
+	keys, args, parse := l.prepare(n, partial, dryRun)
+	res, err := leakyBucketScript.Run(ctx, l.client, keys, args...).Result()
+	if err != nil {
+		return 0, Result{}, err
+	}
+	return parse(res)
 }
 
-// Wait 会阻塞直到成功获取一个许可或 ctx 超时/取消。
-// 对漏桶来说，Wait 的语义是“等到桶里腾出空间为止”。
-func (l *LeakyBucketLimiter) Wait(ctx context.Context, maxWait time.Duration) error {
-	if maxWait <= 0 {
-		maxWait = 0
+// allowCall 返回一次 AllowN 判定（含钩子）的脚本调用，供 AllowKeys 合并到 pipeline 中执行。
+func (l *LeakyBucketLimiter) allowCall(n int64) (scriptCall, error) {
+	if n <= 0 {
+		return scriptCall{}, fmt.Errorf("leaky bucket: n must > 0")
+	}
+	keys, args, parse := l.prepare(n, false, false)
+	c := scriptCall{
+		client: l.client,
+		script: leakyBucketScript,
+		keys:   keys,
+		args:   args,
+		parse: func(_ context.Context, res interface{}, err error) (Result, error) {
+			if err != nil {
+				return Result{}, err
+			}
+			_, r, err := parse(res)
+			return r, err
+		},
 	}
-	deadline := time.Now().Add(maxWait)
+	return c.withHooks(l.Hooks, "leaky_bucket", l.Key, n), nil
+}
 
-	timer := time.NewTimer(time.Second)
-	defer timer.Stop()
+// prepare 准备漏桶脚本的 KEYS / ARGV，返回的 parse 用于解析脚本返回值。
+func (l *LeakyBucketLimiter) prepare(n int64, partial, dryRun bool) ([]string, []interface{}, func(res interface{}) (int64, Result, error)) {
+	rate, capacity := l.limits()
+	nowMs := float64(scriptNow(l.ServerTime, l.Clock))
+	ttlMs := l.TTL.Milliseconds()
+	partialArg := 0
+	if partial {
+		partialArg = 1
+	}
 
-	for {
-		ok, err := l.Allow(ctx)
-		if err != nil {
-			return err
-		}
-		if ok {
-			return nil
-		}
+	valueKey, tsKey := l.stateKeys()
+	keys := []string{valueKey, tsKey}
+	if l.OverridePrefix != "" {
+		keys = append(keys, l.overrideKey())
+	}
 
-		// 被限流时，简单 sleep 一小段时间，再重试。
-		// 若要更精细，可以结合 State() 中的 NextAvailableTime 计算 sleep 时长。
-		now := time.Now()
-		if now.After(deadline) {
-			return ErrTimeout
+	args := []interface{}{nowMs, rate, capacity, float64(n), ttlMs, l.TTLJitter, partialArg}
+	nkeys := len(keys)
+	if dryRun {
+		args = append(args, 1)
+	} else {
+		keys, args = appendStats(keys, args, statsBase(l.Prefix, l.Key), l.StatsTTL, n, partial)
+	}
+
+	return keys, args, func(res interface{}) (int64, Result, error) {
+		vals, ok := scriptInts(res, nkeys+2)
+		if !ok {
+			return 0, Result{}, fmt.Errorf("leaky bucket: unexpected script result: %#v", res)
 		}
-		sleep := 10 * time.Millisecond
-		remain := time.Until(deadline)
-		if sleep > remain {
-			sleep = remain
+		capacity := capacity
+		if len(vals) > 4 {
+			capacity = float64(vals[4])
 		}
-		timer.Reset(sleep)
+		l.reportClockSkew(vals[3])
+		return vals[0], Result{
+			Allowed:    vals[0] > 0,
+			Limit:      capacity,
+			Remaining:  float64(vals[1]),
+			RetryAfter: time.Duration(vals[2]) * time.Millisecond,
+		}, nil
+	}
+}
+
+// ReturnN 把水位降低 n 单位（不低于 0），用于已放行的请求提前中止时归还空间。
+// 桶不存在（已过期或被 Reset）时视为空桶，不做任何修改。
+func (l *LeakyBucketLimiter) ReturnN(ctx context.Context, n int64) error {
+	if n <= 0 {
+		return fmt.Errorf("leaky bucket: n must > 0")
+	}
+	valueKey, tsKey := l.stateKeys()
+	if err := leakyBucketRefundScript.Run(ctx, l.client, []string{valueKey, tsKey}, n).Err(); err != nil {
+		return err
+	}
+	l.Wakeup.notify(ctx, l.Key)
+	return nil
+}
+
+// reportClockSkew 在回拨量超过阈值时触发 OnClockSkew 回调。
+func (l *LeakyBucketLimiter) reportClockSkew(skewMs int64) {
+	if l.OnClockSkew == nil || skewMs <= 0 {
+		return
+	}
+	skew := time.Duration(skewMs) * time.Millisecond
+	if skew > l.ClockSkewThreshold {
+		l.OnClockSkew(l.Key, skew)
+	}
+}
+
+// Wait 会阻塞直到成功获取一个许可或 ctx 超时/取消。
+// 对漏桶来说，Wait 的语义是“等到桶里腾出空间为止”，等待时长由脚本按泄漏速率精确计算。
+func (l *LeakyBucketLimiter) Wait(ctx context.Context, maxWait time.Duration) error {
+	return waitForWake(ctx, l.Key, "leaky_bucket", maxWait, waitStrategy(l.WaitStrategy, l.WaitJitter), l.Wakeup, l.AllowWithResult)
+}
 
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		case <-timer.C:
+// WaitN 阻塞直到桶内腾出 n 个单位的空间并一次性放入，或 ctx 取消 / 超过 maxWait。
+// 每次被拒绝时按脚本根据缺口与泄漏速率算出的等待时间 sleep，而不是循环调用 AllowN。
+// n 超过桶容量时永远无法满足，直接返回错误。
+func (l *LeakyBucketLimiter) WaitN(ctx context.Context, n int64, maxWait time.Duration) error {
+	return waitForWake(ctx, l.Key, "leaky_bucket", maxWait, waitStrategy(l.WaitStrategy, l.WaitJitter), l.Wakeup, func(ctx context.Context) (Result, error) {
+		res, err := l.AllowNWithResult(ctx, n)
+		if err == nil && !res.Allowed && float64(n) > res.Limit {
+			return Result{}, fmt.Errorf("leaky bucket: n must <= capacity")
 		}
+		return res, err
+	})
+}
+
+// Stats 返回按 key 统计的放行 / 拒绝数量，需要通过 WithLeakyBucketStats 开启统计。
+func (l *LeakyBucketLimiter) Stats(ctx context.Context) (LimiterStats, error) {
+	return readStats(ctx, l.client, statsBase(l.Prefix, l.Key))
+}
+
+// Reset 删除水位和时间戳 key，漏桶回到空桶状态。
+func (l *LeakyBucketLimiter) Reset(ctx context.Context) error {
+	valueKey, tsKey := l.stateKeys()
+	if err := deleteBucket(ctx, l.client, valueKey, tsKey); err != nil {
+		return err
 	}
+	l.Wakeup.notify(ctx, l.Key)
+	return nil
 }
 
 // State 返回当前漏桶的状态，用于监控 / Debug。
@@ -163,67 +335,85 @@ func (l *LeakyBucketLimiter) Wait(ctx context.Context, maxWait time.Duration) er
 // Type             -> "leaky_bucket"
 // Key              -> 限流 key
 func (l *LeakyBucketLimiter) State(ctx context.Context) (LimiterState, error) {
-	levelStr, err := l.client.Get(ctx, l.bucketKey()).Result()
-	if errors.Is(err, redis.Nil) {
-		// 桶从未使用过，视为初始状态：水位0
-		now := time.Now().UnixMilli()
-		return LimiterState{
-			Level:             0,
-			Remaining:         l.Capacity,
-			Capacity:          l.Capacity,
-			Rate:              l.LeakRate,
-			LastUpdated:       now,
-			NextAvailableTime: now,
-			Type:              "leaky_bucket",
-			Key:               l.Key,
-		}, nil
-	} else if err != nil {
+	keys, args := l.stateArgs()
+	return l.stateFrom(parseBucketSnapshot(bucketStateScript.Run(ctx, l.client, keys, args...).Result()))
+}
+
+// queueState 把 State 的读取脚本排进 pipeline，返回的函数需在 Exec 之后调用。
+// pipeline 中使用 EVAL 而不是 EVALSHA，不会因为脚本尚未加载而失败。
+func (l *LeakyBucketLimiter) queueState(ctx context.Context, pipe redis.Pipeliner) func() (LimiterState, error) {
+	keys, args := l.stateArgs()
+	cmd := bucketStateScript.Eval(ctx, pipe, keys, args...)
+	return func() (LimiterState, error) {
+		return l.stateFrom(parseBucketSnapshot(cmd.Result()))
+	}
+}
+
+// stateArgs 返回读取状态快照的脚本参数。
+func (l *LeakyBucketLimiter) stateArgs() ([]string, []interface{}) {
+	valueKey, tsKey := l.stateKeys()
+	var overrideKey, statsBaseKey string
+	if l.OverridePrefix != "" {
+		overrideKey = l.overrideKey()
+	}
+	if l.StatsTTL > 0 {
+		statsBaseKey = statsBase(l.Prefix, l.Key)
+	}
+	return bucketStateArgs(valueKey, tsKey, "level", overrideKey, statsBaseKey, scriptNow(l.ServerTime, l.Clock))
+}
+
+// stateFrom 根据读出的快照与脚本返回的当前时间模拟一次泄漏，计算当前状态。
+// 与限流脚本一致：level 不存在时视为空桶，ts 不存在时视为当前时间。
+func (l *LeakyBucketLimiter) stateFrom(snap bucketSnapshot, err error) (LimiterState, error) {
+	if err != nil {
 		return LimiterState{}, err
 	}
+	rate, capacity := snap.override.apply(l.limits())
 
-	tsStr, err := l.client.Get(ctx, l.tsKey()).Result()
-	if errors.Is(err, redis.Nil) {
-		// 状态不完整，兜底为初始状态
-		now := time.Now().UnixMilli()
+	if snap.value == "" {
+		// 桶从未使用过，视为初始状态：水位0
+		now := snap.now
 		return LimiterState{
 			Level:             0,
-			Remaining:         l.Capacity,
-			Capacity:          l.Capacity,
-			Rate:              l.LeakRate,
+			Remaining:         capacity,
+			Capacity:          capacity,
+			Rate:              rate,
 			LastUpdated:       now,
 			NextAvailableTime: now,
 			Type:              "leaky_bucket",
 			Key:               l.Key,
+			AllowedTotal:      snap.stats.Allowed,
+			DeniedTotal:       snap.stats.Denied,
 		}, nil
-	} else if err != nil {
-		return LimiterState{}, err
 	}
 
-	level, err := strconv.ParseFloat(levelStr, 64)
+	level, err := strconv.ParseFloat(snap.value, 64)
 	if err != nil {
 		return LimiterState{}, fmt.Errorf("leaky bucket: invalid level value: %v", err)
 	}
 
-	lastTs, err := strconv.ParseInt(tsStr, 10, 64)
-	if err != nil {
-		return LimiterState{}, fmt.Errorf("leaky bucket: invalid ts value: %v", err)
+	now := time.UnixMilli(snap.now)
+	nowMs := snap.now
+	lastTs := nowMs
+	if snap.ts != "" {
+		lastTs, err = strconv.ParseInt(snap.ts, 10, 64)
+		if err != nil {
+			return LimiterState{}, fmt.Errorf("leaky bucket: invalid ts value: %v", err)
+		}
 	}
-
-	now := time.Now()
-	nowMs := now.UnixNano() / 1e6
 	deltaMs := float64(nowMs - lastTs)
 	if deltaMs < 0 {
 		deltaMs = 0
 	}
 
-	// 在本地模拟一次泄漏，得到“当前真实水位”
-	leak := (deltaMs * l.LeakRate) / 1000
+	// 模拟一次泄漏，得到“当前真实水位”
+	leak := (deltaMs * rate) / 1000
 	realLevel := level - leak
 	if realLevel < 0 {
 		realLevel = 0
 	}
 
-	remaining := l.Capacity - realLevel
+	remaining := capacity - realLevel
 	if remaining < 0 {
 		remaining = 0
 	}
@@ -232,12 +422,12 @@ func (l *LeakyBucketLimiter) State(ctx context.Context) (LimiterState, error) {
 	// 若 realLevel < Capacity，则现在就能放行；
 	// 若 realLevel >= Capacity，则需要等到 realLevel - Capacity 泄掉为止。
 	var next time.Time
-	if realLevel < l.Capacity {
+	if realLevel < capacity {
 		next = now
 	} else {
-		needLeak := realLevel - l.Capacity
+		needLeak := realLevel - capacity
 		// needLeak / leakRate 得到需要的秒数
-		waitSec := needLeak / l.LeakRate
+		waitSec := needLeak / rate
 		if waitSec < 0 {
 			waitSec = 0
 		}
@@ -247,11 +437,82 @@ func (l *LeakyBucketLimiter) State(ctx context.Context) (LimiterState, error) {
 	return LimiterState{
 		Level:             realLevel,
 		Remaining:         remaining,
-		Capacity:          l.Capacity,
-		Rate:              l.LeakRate,
+		Capacity:          capacity,
+		Rate:              rate,
 		LastUpdated:       lastTs,
 		NextAvailableTime: next.UnixMilli(),
 		Type:              "leaky_bucket",
 		Key:               l.Key,
+		AllowedTotal:      snap.stats.Allowed,
+		DeniedTotal:       snap.stats.Denied,
 	}, nil
 }
+
+// limits 返回当前生效的泄漏速率和容量快照。
+func (l *LeakyBucketLimiter) limits() (rate, capacity float64) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.LeakRate, l.Capacity
+}
+
+// SetRate 在运行时修改泄漏速率（单位/秒），并发安全。
+func (l *LeakyBucketLimiter) SetRate(rate float64) error {
+	if rate <= 0 {
+		return fmt.Errorf("leaky bucket: leak rate must > 0")
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.LeakRate = rate
+	return nil
+}
+
+// SetCapacity 在运行时修改桶容量，并发安全。
+func (l *LeakyBucketLimiter) SetCapacity(capacity float64) error {
+	if capacity <= 0 {
+		return fmt.Errorf("leaky bucket: capacity must > 0")
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.Capacity = capacity
+	return nil
+}
+
+// SetOverride 为当前 key 写入覆盖配置（Rate 对应泄漏速率），需要先开启覆盖模式。
+func (l *LeakyBucketLimiter) SetOverride(ctx context.Context, o LimitOverride) error {
+	if l.OverridePrefix == "" {
+		return ErrOverrideDisabled
+	}
+	return setOverride(ctx, l.client, l.overrideKey(), o)
+}
+
+// ClearOverride 删除当前 key 的覆盖配置。
+func (l *LeakyBucketLimiter) ClearOverride(ctx context.Context) error {
+	if l.OverridePrefix == "" {
+		return ErrOverrideDisabled
+	}
+	return l.client.Del(ctx, l.overrideKey()).Err()
+}
+
+// GetOverride 读取当前 key 的覆盖配置，不存在时 ok 为 false。
+func (l *LeakyBucketLimiter) GetOverride(ctx context.Context) (o LimitOverride, ok bool, err error) {
+	if l.OverridePrefix == "" {
+		return LimitOverride{}, false, ErrOverrideDisabled
+	}
+	return getOverride(ctx, l.client, l.overrideKey())
+}
+
+// Reconfigure 在一次加锁内同时替换泄漏速率和容量，实现 Reconfigurable。
+func (l *LeakyBucketLimiter) Reconfigure(c LimitConfig) error {
+	if err := c.validate(); err != nil {
+		return err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if c.Rate > 0 {
+		l.LeakRate = c.Rate
+	}
+	if c.Capacity > 0 {
+		l.Capacity = c.Capacity
+	}
+	return nil
+}
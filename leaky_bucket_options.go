@@ -36,6 +36,79 @@ func WithLeakyBucketTTL(ttl time.Duration) LeakyBucketOption {
 	}
 }
 
+// WithLeakyBucketTTLJitter 设置 TTL 抖动比例（TTL 延长 0~ratio），取值范围 [0, 1)。
+func WithLeakyBucketTTLJitter(ratio float64) LeakyBucketOption {
+	return func(l *LeakyBucketLimiter) {
+		if ratio < 0 || ratio >= 1 {
+			panic("leaky bucket: ttl jitter must be in [0, 1)")
+		}
+		l.TTLJitter = ratio
+	}
+}
+
+// WithLeakyBucketClockSkew 设置时钟回拨告警阈值与回调。
+// 默认使用 Redis TIME 时不会出现回拨，仅在 WithLeakyBucketServerTime(false) 时有意义。
+func WithLeakyBucketClockSkew(threshold time.Duration, fn func(key string, skew time.Duration)) LeakyBucketOption {
+	return func(l *LeakyBucketLimiter) {
+		l.ClockSkewThreshold = max(threshold, 0)
+		l.OnClockSkew = fn
+	}
+}
+
+// WithLeakyBucketWaitJitter 设置 Wait 重试前的随机抖动比例，取值范围 [0, 1]。
+// 大量 goroutine 等待同一个 key 时，抖动可以把它们的重试时间错开。
+func WithLeakyBucketWaitJitter(ratio float64) LeakyBucketOption {
+	return func(l *LeakyBucketLimiter) {
+		if ratio < 0 || ratio > 1 {
+			panic("leaky bucket: wait jitter must be in [0, 1]")
+		}
+		l.WaitJitter = ratio
+	}
+}
+
+// WithLeakyBucketWaitStrategy 设置 Wait 被拒绝后的等待策略，例如 ExponentialWait、NewPacedWait。
+// 设置后 WaitJitter 不再生效，抖动由策略自身负责。
+func WithLeakyBucketWaitStrategy(s WaitStrategy) LeakyBucketOption {
+	return func(l *LeakyBucketLimiter) {
+		l.WaitStrategy = s
+	}
+}
+
+// WithLeakyBucketWakeup 设置 Pub/Sub 唤醒器：ReturnN / Reset 释放额度后立即唤醒等待中的 Wait，
+// 不必等到 RetryAfter。多个限流器可以共享同一个 Wakeup。
+func WithLeakyBucketWakeup(w *Wakeup) LeakyBucketOption {
+	return func(l *LeakyBucketLimiter) {
+		l.Wakeup = w
+	}
+}
+
+// WithLeakyBucketServerTime 设置是否使用 Redis TIME 作为脚本中的当前时间，默认开启。
+// 关闭后使用本机时钟，各应用实例的时钟偏差会直接影响 refill 与窗口边界。
+func WithLeakyBucketServerTime(enabled bool) LeakyBucketOption {
+	return func(l *LeakyBucketLimiter) {
+		l.ServerTime = enabled
+	}
+}
+
+// WithLeakyBucketClock 设置时钟，通常用于测试中控制时间。
+// 注入时钟后脚本改用该时钟传入的时间（相当于 WithLeakyBucketServerTime(false)）。
+func WithLeakyBucketClock(c Clock) LeakyBucketOption {
+	return func(l *LeakyBucketLimiter) {
+		if c != nil {
+			l.Clock = c
+			l.ServerTime = false
+		}
+	}
+}
+
+// WithLeakyBucketStorage 设置状态的存储方式，参见 StorageString / StorageHash。
+// 切换存储方式后，旧方式下的状态不会被迁移，相当于一次 Reset。
+func WithLeakyBucketStorage(mode StorageMode) LeakyBucketOption {
+	return func(l *LeakyBucketLimiter) {
+		l.Storage = mode
+	}
+}
+
 // WithLeakyBucketPrefix 设置 Redis key 前缀。
 func WithLeakyBucketPrefix(prefix string) LeakyBucketOption {
 	return func(l *LeakyBucketLimiter) {
@@ -52,3 +125,30 @@ func WithLeakyBucketCustom(fn func(*LeakyBucketLimiter)) LeakyBucketOption {
 		fn(l)
 	}
 }
+
+// WithLeakyBucketOverrides 开启按 key 覆盖模式：脚本会优先读取 "<prefix>:{key}" hash 中的 rate / capacity。
+// prefix 为空时使用默认前缀 "limits"。
+func WithLeakyBucketOverrides(prefix string) LeakyBucketOption {
+	return func(l *LeakyBucketLimiter) {
+		if prefix == "" {
+			prefix = "limits"
+		}
+		l.OverridePrefix = prefix
+	}
+}
+
+// WithLeakyBucketStats 开启按 key 统计放行 / 拒绝数量，ttl 为计数器的过期时间（每次写入时刷新）。
+func WithLeakyBucketStats(ttl time.Duration) LeakyBucketOption {
+	return func(l *LeakyBucketLimiter) {
+		if ttl > 0 {
+			l.StatsTTL = ttl
+		}
+	}
+}
+
+// WithLeakyBucketHooks 设置判定事件钩子，用于接入日志、指标或告警。
+func WithLeakyBucketHooks(h Hooks) LeakyBucketOption {
+	return func(l *LeakyBucketLimiter) {
+		l.Hooks = h
+	}
+}
@@ -0,0 +1,104 @@
+// Package limitergroup 把 errgroup 与限流器结合：每个任务启动前先获取一个许可，任务结束后归还，
+// 适合批处理任务按全局速率或全局并发数调用下游。
+package limitergroup
+
+import (
+	"context"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+
+	limiter "github.com/lifei6671/go-redis-limiter"
+)
+
+// Permit 获取一个许可，返回任务结束后调用的归还函数。
+// ctx 带截止时间时最多等待到截止时间，否则一直等待到 ctx 取消。
+type Permit func(ctx context.Context) (release func(), err error)
+
+// Rate 按速率发放许可：启动任务前调用 l.Wait，任务结束后不需要归还。
+func Rate(l limiter.RateLimiter) Permit {
+	if l == nil {
+		panic("limitergroup: limiter is nil")
+	}
+	return func(ctx context.Context) (func(), error) {
+		if err := l.Wait(ctx, limiter.UntilDeadline); err != nil {
+			return nil, err
+		}
+		return func() {}, nil
+	}
+}
+
+// Concurrency 按并发数发放许可：启动任务前获取租约，任务结束后 Release。
+// 任务运行时间可能超过租约时长时，请把 Lease 设置得足够长，否则租约过期后名额会被提前回收。
+func Concurrency(l *limiter.ConcurrencyLimiter) Permit {
+	if l == nil {
+		panic("limitergroup: limiter is nil")
+	}
+	return func(ctx context.Context) (func(), error) {
+		token, err := l.Wait(ctx, limiter.UntilDeadline)
+		if err != nil {
+			return nil, err
+		}
+		return func() {
+			// 任务可能因 ctx 取消而结束，归还使用独立的超时；归还失败时名额在租约到期后自动回收
+			rctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), time.Second)
+			defer cancel()
+			_ = l.Release(rctx, token)
+		}, nil
+	}
+}
+
+// Group 是带限流的 errgroup.Group：Go 在启动任务前阻塞获取许可，因此也能为生产者提供背压。
+//
+//	g, ctx := limitergroup.WithContext(ctx, limitergroup.Concurrency(conc))
+//	for _, item := range items {
+//		g.Go(func() error { return process(ctx, item) })
+//	}
+//	err := g.Wait()
+type Group struct {
+	eg     *errgroup.Group
+	ctx    context.Context
+	permit Permit
+}
+
+// New 创建一个限流的 Group，获取许可使用 context.Background()。
+func New(permit Permit) *Group {
+	if permit == nil {
+		panic("limitergroup: permit is nil")
+	}
+	return &Group{eg: &errgroup.Group{}, ctx: context.Background(), permit: permit}
+}
+
+// WithContext 与 errgroup.WithContext 相同：返回的 ctx 在第一个任务出错或 Wait 返回时取消，
+// 还在等待许可的 Go 也会随之放弃。
+func WithContext(ctx context.Context, permit Permit) (*Group, context.Context) {
+	if permit == nil {
+		panic("limitergroup: permit is nil")
+	}
+	eg, ctx := errgroup.WithContext(ctx)
+	return &Group{eg: eg, ctx: ctx, permit: permit}, ctx
+}
+
+// Go 获取一个许可后在新的 goroutine 中运行 f，f 返回后归还许可。
+// 获取许可失败（超时、ctx 取消、Redis 出错）时不会运行 f，错误记为该任务的错误，由 Wait 返回。
+func (g *Group) Go(f func() error) {
+	release, err := g.permit(g.ctx)
+	if err != nil {
+		g.eg.Go(func() error { return err })
+		return
+	}
+	g.eg.Go(func() error {
+		defer release()
+		return f()
+	})
+}
+
+// SetLimit 限制本进程内同时运行的任务数，见 errgroup.Group.SetLimit。
+func (g *Group) SetLimit(n int) {
+	g.eg.SetLimit(n)
+}
+
+// Wait 等待所有任务结束，返回第一个非 nil 的错误。
+func (g *Group) Wait() error {
+	return g.eg.Wait()
+}
@@ -0,0 +1,94 @@
+package limitergroup
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+
+	limiter "github.com/lifei6671/go-redis-limiter"
+)
+
+func newClient(t *testing.T) *redis.Client {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	return client
+}
+
+func TestGroup_Concurrency(t *testing.T) {
+	client := newClient(t)
+	conc := limiter.NewConcurrencyLimiter(client, "jobs",
+		limiter.WithConcurrencyLimit(2), limiter.WithConcurrencyWaitStrategy(limiter.FixedWait{Interval: 5 * time.Millisecond}))
+
+	g := New(Concurrency(conc))
+	var running, peak atomic.Int64
+	for i := 0; i < 6; i++ {
+		g.Go(func() error {
+			n := running.Add(1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			time.Sleep(20 * time.Millisecond)
+			running.Add(-1)
+			return nil
+		})
+	}
+	assert.NoError(t, g.Wait())
+	assert.Equal(t, int64(2), peak.Load())
+
+	// 所有任务结束后许可全部归还
+	st, err := conc.State(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, float64(2), st.Remaining)
+}
+
+func TestGroup_Rate(t *testing.T) {
+	client := newClient(t)
+	tb := limiter.NewTokenBucketLimiter(client, "jobs",
+		limiter.WithTokenBucketRate(0.001), limiter.WithTokenBucketCapacity(2))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	g, _ := WithContext(ctx, Rate(tb))
+
+	var ran atomic.Int64
+	for i := 0; i < 3; i++ {
+		g.Go(func() error {
+			ran.Add(1)
+			return nil
+		})
+	}
+	// 第 3 个任务等不到令牌，不会运行，获取许可的错误由 Wait 返回
+	err := g.Wait()
+	assert.Error(t, err)
+	assert.Equal(t, int64(2), ran.Load())
+}
+
+func TestGroup_ErrorCancels(t *testing.T) {
+	client := newClient(t)
+	conc := limiter.NewConcurrencyLimiter(client, "jobs", limiter.WithConcurrencyLimit(1))
+
+	boom := errors.New("boom")
+	g, ctx := WithContext(context.Background(), Concurrency(conc))
+	g.Go(func() error { return boom })
+	g.Go(func() error { return nil })
+	// Wait 返回第一个任务的错误，并取消派生的 ctx
+	assert.ErrorIs(t, g.Wait(), boom)
+	assert.Error(t, ctx.Err())
+}
+
+func TestNew_Panics(t *testing.T) {
+	assert.Panics(t, func() { New(nil) })
+	assert.Panics(t, func() { Rate(nil) })
+	assert.Panics(t, func() { Concurrency(nil) })
+}
@@ -0,0 +1,204 @@
+package limiter
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// MultiSpec 是 MultiAllow 中的一项：从 Limiter 中消耗 N 个许可。
+type MultiSpec struct {
+	// Limiter 目前支持 *TokenBucketLimiter、*LeakyBucketLimiter、
+	// *FixedWindowLimiter 与 *SingleSlidingWindowLimiter
+	Limiter RateLimiter
+	// N 消耗的许可数，0 视为 1
+	N int64
+}
+
+// MultiResult 是 MultiAllow 一次判定的结果。
+type MultiResult struct {
+	// Allowed 是否全部放行
+	Allowed bool
+	// DeniedBy 被拒绝时为需要等待最久的那一项在 specs 中的下标，放行时为 -1
+	DeniedBy int
+	// RetryAfter 被拒绝时所有不满足的项中最长的等待时间，放行时为 0
+	RetryAfter time.Duration
+	// Results 与 specs 一一对应的判定结果；整体被拒绝时，满足条件的项 Allowed 为 true 但并未扣减
+	Results []Result
+}
+
+// MultiAllow 在一个 Lua 脚本中原子地从多个限流器消耗许可，要么全部扣减，要么全部不扣减。
+// 典型场景是同时施加“按用户 + 按 IP + 按接口”三个限流器：逐个调用 Allow 时，
+// 前面的限流器已经扣减、后面的拒绝，会造成额度被白白消耗。
+//
+// 判定结束后按各限流器自身的配置触发 Hooks 并累加统计计数器（StatsTTL > 0 时）：
+// 整体放行时每一项都记为放行，整体拒绝时每一项都记为拒绝（没有任何一项被扣减）。
+//
+// 所有限流器必须使用同一个 Redis 客户端，且不能重复。各限流器的 key 使用各自的 hash tag，
+// 在 Redis Cluster 中通常分布在不同 slot，脚本会报 CROSSSLOT，因此只适用于单机 / 主从 / 哨兵部署。
+func MultiAllow(ctx context.Context, specs ...MultiSpec) (MultiResult, error) {
+	if len(specs) == 0 {
+		return MultiResult{}, fmt.Errorf("multi: specs are empty")
+	}
+
+	var (
+		client  *redis.Client
+		members = make([]multiMember, len(specs))
+		keys    []string
+		args    = []interface{}{len(specs)}
+		seen    = make(map[string]struct{}, len(specs))
+	)
+	for i, spec := range specs {
+		n := spec.N
+		if n == 0 {
+			n = 1
+		}
+		if n < 0 {
+			return MultiResult{}, fmt.Errorf("multi: spec %d: n must > 0", i)
+		}
+
+		c, algo, k, a, err := multiEncode(spec.Limiter)
+		if err != nil {
+			return MultiResult{}, fmt.Errorf("multi: spec %d: %w", i, err)
+		}
+		if client == nil {
+			client = c
+		} else if c != client {
+			return MultiResult{}, fmt.Errorf("multi: spec %d: limiters must share the same redis client", i)
+		}
+		if _, ok := seen[k[0]]; ok {
+			return MultiResult{}, fmt.Errorf("multi: spec %d: duplicate limiter", i)
+		}
+		seen[k[0]] = struct{}{}
+
+		members[i] = multiObserve(spec.Limiter, n)
+
+		keys = append(keys, k...)
+		args = append(args, algo, n, len(k), len(a))
+		args = append(args, a...)
+	}
+
+	res, err := multiScript.Run(ctx, client, keys, args...).Result()
+	if err != nil {
+		for _, m := range members {
+			fireHooks(ctx, m.hooks, m.algorithm, m.key, m.n, Result{}, err)
+		}
+		return MultiResult{}, err
+	}
+
+	vals, ok := scriptInts(res, 3+4*len(specs))
+	if !ok || vals[1] >= int64(len(specs)) {
+		return MultiResult{}, fmt.Errorf("multi: unexpected script result: %#v", res)
+	}
+
+	out := MultiResult{
+		Allowed:    vals[0] == 1,
+		DeniedBy:   int(vals[1]),
+		RetryAfter: time.Duration(vals[2]) * time.Millisecond,
+		Results:    make([]Result, len(specs)),
+	}
+	for i := range specs {
+		v := vals[3+4*i:]
+		out.Results[i] = Result{
+			Allowed:    v[0] == 1,
+			Remaining:  float64(v[1]),
+			RetryAfter: time.Duration(v[2]) * time.Millisecond,
+			Limit:      float64(v[3]),
+		}
+	}
+	multiRecord(ctx, client, members, out)
+	return out, nil
+}
+
+// multiMember 是 MultiAllow 中一项的钩子与统计配置。
+type multiMember struct {
+	hooks     Hooks
+	algorithm string
+	key       string
+	statsBase string
+	statsTTL  time.Duration
+	n         int64
+}
+
+// multiObserve 取出限流器的钩子与统计配置，l 必须已经通过 multiEncode 的检查。
+func multiObserve(l RateLimiter, n int64) multiMember {
+	switch v := l.(type) {
+	case *TokenBucketLimiter:
+		return multiMember{v.Hooks, "token_bucket", v.Key, statsBase(v.Prefix, v.Key), v.StatsTTL, n}
+	case *LeakyBucketLimiter:
+		return multiMember{v.Hooks, "leaky_bucket", v.Key, statsBase(v.Prefix, v.Key), v.StatsTTL, n}
+	case *FixedWindowLimiter:
+		return multiMember{v.Hooks, "fixed_window", v.Key, statsBase(v.Prefix, v.Key), v.StatsTTL, n}
+	case *SingleSlidingWindowLimiter:
+		return multiMember{v.Hooks, "sliding_window", v.Key, statsBase(v.Prefix, v.Key), v.StatsTTL, n}
+	}
+	return multiMember{n: n}
+}
+
+// multiRecord 按整体判定结果为每一项累加统计计数器并触发钩子。
+// 统计只用于观测，写入失败不影响已经完成的判定。
+func multiRecord(ctx context.Context, client *redis.Client, members []multiMember, out MultiResult) {
+	for _, m := range members {
+		if m.statsTTL <= 0 {
+			continue
+		}
+		allowed, denied := m.n, int64(0)
+		if !out.Allowed {
+			allowed, denied = 0, m.n
+		}
+		_ = recordStats(ctx, client, m.statsBase, m.statsTTL, allowed, denied)
+	}
+	for i, m := range members {
+		res := out.Results[i]
+		res.Allowed = out.Allowed
+		fireHooks(ctx, m.hooks, m.algorithm, m.key, m.n, res, nil)
+	}
+}
+
+// multiEncode 把限流器编码为 multiScript 的一段 KEYS / ARGV。
+func multiEncode(l RateLimiter) (*redis.Client, string, []string, []interface{}, error) {
+	switch v := l.(type) {
+	case *TokenBucketLimiter:
+		if v.WarmUp > 0 {
+			return nil, "", nil, nil, fmt.Errorf("token bucket warm-up is not supported")
+		}
+		if v.InitialTokens >= 0 {
+			return nil, "", nil, nil, fmt.Errorf("token bucket initial tokens is not supported")
+		}
+		if v.MaxDebt > 0 {
+			return nil, "", nil, nil, fmt.Errorf("token bucket debt is not supported")
+		}
+		if v.MinInterval > 0 {
+			return nil, "", nil, nil, fmt.Errorf("token bucket min interval is not supported")
+		}
+		rate, capacity := v.limits()
+		valueKey, tsKey := v.stateKeys()
+		keys := []string{valueKey, tsKey}
+		if v.OverridePrefix != "" {
+			keys = append(keys, v.overrideKey())
+		}
+		args := []interface{}{scriptNow(v.ServerTime, v.Clock), rate, capacity, v.TTL.Milliseconds(), v.TTLJitter}
+		return v.client, "tb", keys, args, nil
+	case *LeakyBucketLimiter:
+		rate, capacity := v.limits()
+		valueKey, tsKey := v.stateKeys()
+		keys := []string{valueKey, tsKey}
+		if v.OverridePrefix != "" {
+			keys = append(keys, v.overrideKey())
+		}
+		args := []interface{}{scriptNow(v.ServerTime, v.Clock), rate, capacity, v.TTL.Milliseconds(), v.TTLJitter}
+		return v.client, "lb", keys, args, nil
+	case *FixedWindowLimiter:
+		return v.client, "fw", []string{v.countKey()}, []interface{}{v.Window.Milliseconds(), v.Limit}, nil
+	case *SingleSlidingWindowLimiter:
+		limit, window, ttl := v.limits()
+		args := []interface{}{scriptNow(v.ServerTime, v.Clock), window.Milliseconds(), limit, ttl.Milliseconds(), v.TTLJitter}
+		return v.client, "sw", []string{v.logKey(), v.seqKey()}, args, nil
+	case nil:
+		return nil, "", nil, nil, fmt.Errorf("limiter is nil")
+	default:
+		return nil, "", nil, nil, fmt.Errorf("unsupported limiter %T", l)
+	}
+}
@@ -0,0 +1,103 @@
+package limiter
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMultiAllow(t *testing.T) {
+	client := newSpecClient(t)
+	ctx := context.Background()
+
+	user := NewTokenBucketLimiter(client, "user:1", WithTokenBucketRate(0.001), WithTokenBucketCapacity(5))
+	ip := NewSlidingWindowLimiter(client, "ip:1", WithSlidingWindowLimit(3))
+	api := NewFixedWindowLimiter(client, "api", WithFixedWindowLimit(10), WithFixedWindowWindow(time.Minute))
+	lb := NewLeakyBucketLimiter(client, "lb", WithLeakyBucketRate(0.001), WithLeakyBucketCapacity(8),
+		WithLeakyBucketStorage(StorageHash))
+
+	specs := []MultiSpec{{Limiter: user, N: 2}, {Limiter: ip}, {Limiter: api}, {Limiter: lb, N: 2}}
+	for i := 0; i < 2; i++ {
+		res, err := MultiAllow(ctx, specs...)
+		assert.NoError(t, err)
+		assert.True(t, res.Allowed)
+		assert.Equal(t, -1, res.DeniedBy)
+	}
+
+	// 用户令牌桶只剩 1 个：整体拒绝，其余限流器都不扣减
+	res, err := MultiAllow(ctx, specs...)
+	assert.NoError(t, err)
+	assert.False(t, res.Allowed)
+	assert.Equal(t, 0, res.DeniedBy)
+	assert.Greater(t, res.RetryAfter, time.Duration(0))
+	assert.False(t, res.Results[0].Allowed)
+	assert.Equal(t, float64(1), res.Results[0].Remaining)
+	assert.True(t, res.Results[1].Allowed)
+	assert.Equal(t, float64(1), res.Results[1].Remaining)
+	assert.Equal(t, float64(8), res.Results[2].Remaining)
+	assert.Equal(t, float64(4), res.Results[3].Remaining)
+
+	for _, l := range []checker{ip, api, lb} {
+		r, err := l.CheckN(ctx, 1)
+		assert.NoError(t, err)
+		assert.True(t, r.Allowed)
+	}
+	r, err := ip.CheckN(ctx, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, float64(1), r.Remaining)
+
+	// 只要用户限流器就能放行
+	res, err = MultiAllow(ctx, MultiSpec{Limiter: user})
+	assert.NoError(t, err)
+	assert.True(t, res.Allowed)
+	assert.Equal(t, float64(0), res.Results[0].Remaining)
+	assert.Equal(t, float64(5), res.Results[0].Limit)
+
+	_, err = MultiAllow(ctx)
+	assert.Error(t, err)
+	_, err = MultiAllow(ctx, MultiSpec{Limiter: ip}, MultiSpec{Limiter: ip})
+	assert.Error(t, err)
+	_, err = MultiAllow(ctx, MultiSpec{Limiter: NewCompositeLimiter(client, "c", []CompositeRule{{Name: "s", Window: time.Second, Limit: 1}})})
+	assert.Error(t, err)
+	_, err = MultiAllow(ctx, MultiSpec{Limiter: api}, MultiSpec{Limiter: NewFixedWindowLimiter(newSpecClient(t), "other")})
+	assert.Error(t, err)
+	_, err = MultiAllow(ctx, MultiSpec{Limiter: NewTokenBucketLimiter(client, "initial", WithTokenBucketInitialTokens(0))})
+	assert.ErrorContains(t, err, "not supported")
+	_, err = MultiAllow(ctx, MultiSpec{Limiter: NewTokenBucketLimiter(client, "debt", WithTokenBucketDebt(1))})
+	assert.ErrorContains(t, err, "not supported")
+	_, err = MultiAllow(ctx, MultiSpec{Limiter: NewTokenBucketLimiter(client, "gap", WithTokenBucketMinInterval(time.Second))})
+	assert.ErrorContains(t, err, "not supported")
+}
+
+func TestMultiAllow_HooksStats(t *testing.T) {
+	client := newSpecClient(t)
+	ctx := context.Background()
+
+	var allowed, denied atomic.Int64
+	hooks := HookFuncs{
+		Allow: func(context.Context, HookEvent) { allowed.Add(1) },
+		Deny:  func(context.Context, HookEvent) { denied.Add(1) },
+	}
+	user := NewTokenBucketLimiter(client, "user:1", WithTokenBucketRate(0.001), WithTokenBucketCapacity(2),
+		WithTokenBucketStats(time.Minute), WithTokenBucketHooks(hooks))
+	api := NewFixedWindowLimiter(client, "api", WithFixedWindowLimit(10), WithFixedWindowWindow(time.Minute),
+		WithFixedWindowStats(time.Minute), WithFixedWindowHooks(hooks))
+
+	// 两次整体放行、一次整体拒绝：每一项都按整体结果统计并触发钩子
+	for i := 0; i < 3; i++ {
+		_, err := MultiAllow(ctx, MultiSpec{Limiter: user}, MultiSpec{Limiter: api, N: 2})
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, int64(4), allowed.Load())
+	assert.Equal(t, int64(2), denied.Load())
+
+	st, err := user.Stats(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, LimiterStats{Allowed: 2, Denied: 1}, st)
+	st, err = api.Stats(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, LimiterStats{Allowed: 4, Denied: 2}, st)
+}
@@ -0,0 +1,218 @@
+package limiter
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Namespace 把一组限流器隔离在同一个命名空间下（例如 "prod:tenantA"），用于多租户隔离，
+// 或让 staging / prod 共用一个 Redis：
+//   - 命名空间内创建的限流器，业务 key 自动加上 "<Name>:" 前缀
+//   - Defaults 是命名空间的默认规则，规则中为零值的字段使用这里的值
+//   - Keys / Reset 枚举或删除命名空间下的全部限流状态
+//
+// 命名空间前缀位于 Redis key 的 hash tag 内（prefix:{<Name>:key}:suffix），
+// 因此可以按 hash tag 扫描到命名空间下各类限流器（包括统计计数器）的全部 key。
+type Namespace struct {
+	client  *redis.Client
+	factory *Factory
+
+	// Name 命名空间名，例如 "prod:tenantA"
+	Name string
+	// Defaults 命名空间的默认规则，规则中为零值的字段（Algorithm、Prefix、Shards、Params 以及各参数）使用这里的值
+	Defaults LimitRule
+	// ScanCount Keys / Reset 每次 SCAN 的 COUNT 提示值，默认 100
+	ScanCount int64
+}
+
+// NewNamespace 创建名为 name 的命名空间。
+func NewNamespace(client *redis.Client, name string, opts ...NamespaceOption) *Namespace {
+	if client == nil {
+		panic("namespace: redis client is nil")
+	}
+	if name == "" {
+		panic("namespace: name is empty")
+	}
+
+	ns := &Namespace{
+		client:    client,
+		factory:   NewFactory(client),
+		Name:      name,
+		ScanCount: 100,
+	}
+	for _, opt := range opts {
+		opt(ns)
+	}
+	return ns
+}
+
+// Sub 返回名为 "<Name>:<name>" 的子命名空间，继承默认规则。
+// 例如 NewNamespace(rdb, "prod").Sub("tenantA") 的前缀为 "prod:tenantA:"，重置 "prod" 时一并删除。
+func (ns *Namespace) Sub(name string) *Namespace {
+	if name == "" {
+		panic("namespace: name is empty")
+	}
+	sub := *ns
+	sub.Name = ns.Name + ":" + name
+	return &sub
+}
+
+// Key 返回加上命名空间前缀后的业务 key。
+func (ns *Namespace) Key(key string) string {
+	return ns.Name + ":" + key
+}
+
+// Rule 返回应用了命名空间前缀与默认规则后的规则，规则名保持不变。
+func (ns *Namespace) Rule(r LimitRule) LimitRule {
+	d := ns.Defaults
+	if r.Algorithm == "" {
+		r.Algorithm = d.Algorithm
+	}
+	if r.Prefix == "" {
+		r.Prefix = d.Prefix
+	}
+	if r.Shards == 0 {
+		r.Shards = d.Shards
+	}
+	if r.Params == nil {
+		r.Params = d.Params
+	}
+	if r.Rate == 0 {
+		r.Rate = d.Rate
+	}
+	if r.Capacity == 0 {
+		r.Capacity = d.Capacity
+	}
+	if r.Limit == 0 {
+		r.Limit = d.Limit
+	}
+	if r.WindowMs == 0 {
+		r.WindowMs = d.WindowMs
+	}
+	r.Key = ns.Key(r.EffectiveKey())
+	return r
+}
+
+// Create 在命名空间内按规则创建单桶限流器，见 Factory.Create。
+func (ns *Namespace) Create(r LimitRule) (RateLimiter, error) {
+	return ns.factory.Create(ns.Rule(r))
+}
+
+// CreateSharded 在命名空间内按规则创建分片限流器，见 Factory.CreateSharded。
+func (ns *Namespace) CreateSharded(r LimitRule) (RateShardedLimiter, error) {
+	return ns.factory.CreateSharded(ns.Rule(r))
+}
+
+// CreateAll 在命名空间内按规则批量创建限流器，见 Factory.CreateAll。
+func (ns *Namespace) CreateAll(rules []LimitRule) (*LimiterSet, error) {
+	scoped := make([]LimitRule, len(rules))
+	for i, r := range rules {
+		scoped[i] = ns.Rule(r)
+	}
+	return ns.factory.CreateAll(scoped)
+}
+
+// Wrap 把 l 的 shardKey 加上命名空间前缀，适用于 KeyedLimiter 等按请求 key 懒创建限流器的场景。
+func (ns *Namespace) Wrap(l RateShardedLimiter) RateShardedLimiter {
+	if l == nil {
+		panic("namespace: limiter is nil")
+	}
+	return &namespacedLimiter{ns: ns, l: l}
+}
+
+// Keys 枚举命名空间（含子命名空间）下当前存在的限流 key，见 ScanKeys。
+func (ns *Namespace) Keys(ctx context.Context) ([]KeyInfo, error) {
+	s := NewKeyScanner(ns.client, WithKeyScannerCount(ns.ScanCount))
+	return s.ScanKeys(ctx, escapeGlob(ns.Name)+":*")
+}
+
+// Reset 删除命名空间（含子命名空间）下的全部限流状态，返回删除的 Redis key 数量。
+// SCAN 会遍历整个 keyspace，key 数量很多时耗时较长；删除按批次通过 pipeline 执行 UNLINK。
+func (ns *Namespace) Reset(ctx context.Context) (int64, error) {
+	var deleted int64
+	flush := func(keys []string) error {
+		if len(keys) == 0 {
+			return nil
+		}
+		// 各 key 的 hash tag 不同，逐个 UNLINK，兼容 Redis Cluster
+		cmds, err := ns.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for _, k := range keys {
+				pipe.Unlink(ctx, k)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, cmd := range cmds {
+			deleted += cmd.(*redis.IntCmd).Val()
+		}
+		return nil
+	}
+
+	var batch []string
+	iter := ns.client.Scan(ctx, 0, "*{"+escapeGlob(ns.Name)+":*", ns.ScanCount).Iterator()
+	for iter.Next(ctx) {
+		batch = append(batch, iter.Val())
+		if int64(len(batch)) >= ns.ScanCount {
+			if err := flush(batch); err != nil {
+				return deleted, err
+			}
+			batch = batch[:0]
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return deleted, err
+	}
+	return deleted, flush(batch)
+}
+
+// escapeGlob 转义 Redis glob 语法中的特殊字符。
+func escapeGlob(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch r {
+		case '*', '?', '[', ']', '\\':
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// namespacedLimiter 为 shardKey 加上命名空间前缀。
+type namespacedLimiter struct {
+	ns *Namespace
+	l  RateShardedLimiter
+}
+
+func (n *namespacedLimiter) Allow(ctx context.Context, key string) (bool, error) {
+	return n.l.Allow(ctx, n.ns.Key(key))
+}
+
+func (n *namespacedLimiter) AllowWithResult(ctx context.Context, key string) (Result, error) {
+	return n.l.AllowWithResult(ctx, n.ns.Key(key))
+}
+
+func (n *namespacedLimiter) AllowN(ctx context.Context, key string, count int64) (bool, error) {
+	return n.l.AllowN(ctx, n.ns.Key(key), count)
+}
+
+func (n *namespacedLimiter) State(ctx context.Context, key string) (LimiterState, error) {
+	return n.l.State(ctx, n.ns.Key(key))
+}
+
+func (n *namespacedLimiter) Wait(ctx context.Context, key string, maxWait time.Duration) error {
+	return n.l.Wait(ctx, n.ns.Key(key), maxWait)
+}
+
+func (n *namespacedLimiter) Reset(ctx context.Context, key string) error {
+	return n.l.Reset(ctx, n.ns.Key(key))
+}
+
+func (n *namespacedLimiter) ResetAll(ctx context.Context) error {
+	return n.l.ResetAll(ctx)
+}
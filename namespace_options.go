@@ -0,0 +1,20 @@
+package limiter
+
+// NamespaceOption 为命名空间的配置项。
+type NamespaceOption func(*Namespace)
+
+// WithNamespaceDefaults 设置命名空间的默认规则，规则中为零值的字段使用这里的值。
+func WithNamespaceDefaults(r LimitRule) NamespaceOption {
+	return func(ns *Namespace) {
+		ns.Defaults = r
+	}
+}
+
+// WithNamespaceScanCount 设置 Keys / Reset 每次 SCAN 的 COUNT 提示值。
+func WithNamespaceScanCount(n int64) NamespaceOption {
+	return func(ns *Namespace) {
+		if n > 0 {
+			ns.ScanCount = n
+		}
+	}
+}
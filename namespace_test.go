@@ -0,0 +1,78 @@
+package limiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNamespace(t *testing.T) {
+	client := newSpecClient(t)
+	ctx := context.Background()
+
+	prod := NewNamespace(client, "prod", WithNamespaceDefaults(LimitRule{
+		Algorithm:   AlgorithmFixedWindow,
+		LimitConfig: LimitConfig{Limit: 2, WindowMs: 60000},
+	}), WithNamespaceScanCount(2))
+	tenantA := prod.Sub("tenantA")
+	tenantB := prod.Sub("tenantB")
+	staging := NewNamespace(client, "staging", WithNamespaceDefaults(prod.Defaults))
+
+	assert.Equal(t, "prod:tenantA:api", tenantA.Key("api"))
+	r := tenantA.Rule(LimitRule{LimitConfig: LimitConfig{Name: "api", Limit: 3}})
+	assert.Equal(t, "prod:tenantA:api", r.Key)
+	assert.Equal(t, AlgorithmFixedWindow, r.Algorithm)
+	assert.Equal(t, int64(3), r.Limit)
+	assert.Equal(t, int64(60000), r.WindowMs)
+
+	// 同名规则在不同命名空间下互不影响
+	allowed := func(ns *Namespace) int {
+		l, err := ns.Create(LimitRule{LimitConfig: LimitConfig{Name: "api"}})
+		if !assert.NoError(t, err) {
+			return 0
+		}
+		n := 0
+		for i := 0; i < 3; i++ {
+			if ok, _ := l.Allow(ctx); ok {
+				n++
+			}
+		}
+		return n
+	}
+	assert.Equal(t, 2, allowed(tenantA))
+	assert.Equal(t, 2, allowed(tenantB))
+	assert.Equal(t, 2, allowed(staging))
+
+	// 按请求 key 懒创建的限流器同样加上前缀
+	keyed := tenantA.Wrap(NewKeyedLimiter(func(key string) RateLimiter {
+		return NewTokenBucketLimiter(client, key, WithTokenBucketStats(time.Hour))
+	}))
+	ok, err := keyed.Allow(ctx, "user:1")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	keys, err := tenantA.Keys(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []KeyInfo{
+		{Key: "prod:tenantA:api", Type: "fixed_window", Shard: -1},
+		{Key: "prod:tenantA:user:1", Type: "token_bucket", Shard: -1},
+	}, keys)
+
+	// 重置 prod 会删除所有租户（包括统计计数器），staging 不受影响：
+	// 两个租户的 fw 计数器，tokens / ts 与 stats allowed 计数器
+	n, err := prod.Reset(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), n)
+	keys, err = prod.Keys(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, keys)
+	keys, err = staging.Keys(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, keys, 1)
+}
+
+func TestEscapeGlob(t *testing.T) {
+	assert.Equal(t, `a\*b\?c\[d\]\\`, escapeGlob(`a*b?c[d]\`))
+}
@@ -0,0 +1,94 @@
+package limiter
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// OnceLimiter 保证同一个 key 在每个周期内全集群只有一个实例执行某个动作，
+// 例如多实例部署时对定时任务去重、定期刷新缓存。
+//
+// 周期按 interval 对齐到 Unix 纪元（now / interval 取整），
+// 各实例的触发时间略有偏差时仍落在同一个周期内，只有最先到达的实例拿到执行权；
+// 因此 interval 为 24h 时周期以 UTC 零点为界。
+//
+// 执行权记录在 "once:{key}" 中（value 为周期序号），周期结束时自动过期。
+type OnceLimiter struct {
+	client *redis.Client
+
+	// Prefix Redis key 前缀，默认 "once"
+	Prefix string
+
+	// ServerTime 为 true（默认）时脚本使用 Redis TIME 作为当前时间，
+	// 所有应用实例共享同一个时钟源；为 false 时使用本机时钟。
+	ServerTime bool
+
+	// Clock 时钟，默认 SystemClock。
+	Clock Clock
+}
+
+// NewOnceLimiter 创建周期内只执行一次的分布式调度锁。
+func NewOnceLimiter(client *redis.Client, opts ...OnceOption) *OnceLimiter {
+	if client == nil {
+		panic("once: redis client is nil")
+	}
+
+	o := &OnceLimiter{
+		client:     client,
+		Prefix:     "once",
+		ServerTime: true,
+		Clock:      SystemClock,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// onceKey 返回记录执行权的 key。
+func (o *OnceLimiter) onceKey(key string) string {
+	return fmt.Sprintf("%s:{%s}", o.Prefix, key)
+}
+
+// AllowOnce 判断当前实例能否在本周期内执行 key 对应的动作，
+// 同一个周期内全集群只有第一次调用返回 true。
+func (o *OnceLimiter) AllowOnce(ctx context.Context, key string, interval time.Duration) (bool, error) {
+	res, err := o.AllowOnceResult(ctx, key, interval)
+	return res.Allowed, err
+}
+
+// AllowOnceResult 与 AllowOnce 相同，但返回完整的 Result：
+// RetryAfter 为距离下一个周期开始的时长，无论本次是否拿到执行权。
+func (o *OnceLimiter) AllowOnceResult(ctx context.Context, key string, interval time.Duration) (Result, error) {
+	if interval < time.Millisecond {
+		return Result{}, fmt.Errorf("once: interval must >= 1ms")
+	}
+
+	res, err := onceScript.Run(ctx, o.client,
+		[]string{o.onceKey(key)},
+		scriptNow(o.ServerTime, o.Clock),
+		interval.Milliseconds(),
+	).Result()
+	if err != nil {
+		return Result{}, err
+	}
+
+	vals, ok := scriptInts(res, 2)
+	if !ok {
+		return Result{}, fmt.Errorf("once: unexpected script result: %#v", res)
+	}
+	return Result{
+		Allowed:    vals[0] == 1,
+		Limit:      1,
+		RetryAfter: time.Duration(vals[1]) * time.Millisecond,
+	}, nil
+}
+
+// Reset 清除 key 在本周期的执行记录，之后的第一次 AllowOnce 重新拿到执行权，
+// 通常用于拿到执行权的实例执行失败、希望其他实例重试。
+func (o *OnceLimiter) Reset(ctx context.Context, key string) error {
+	return o.client.Del(ctx, o.onceKey(key)).Err()
+}
@@ -0,0 +1,32 @@
+package limiter
+
+// OnceOption 为周期调度锁的配置项。
+type OnceOption func(*OnceLimiter)
+
+// WithOncePrefix 设置 Redis key 前缀。
+func WithOncePrefix(prefix string) OnceOption {
+	return func(o *OnceLimiter) {
+		if prefix != "" {
+			o.Prefix = prefix
+		}
+	}
+}
+
+// WithOnceServerTime 设置是否使用 Redis TIME 作为脚本中的当前时间，默认开启。
+// 关闭后使用本机时钟，各应用实例的时钟偏差会直接影响周期边界。
+func WithOnceServerTime(enabled bool) OnceOption {
+	return func(o *OnceLimiter) {
+		o.ServerTime = enabled
+	}
+}
+
+// WithOnceClock 设置时钟，通常用于测试中控制时间。
+// 注入时钟后脚本改用该时钟传入的时间（相当于 WithOnceServerTime(false)）。
+func WithOnceClock(c Clock) OnceOption {
+	return func(o *OnceLimiter) {
+		if c != nil {
+			o.Clock = c
+			o.ServerTime = false
+		}
+	}
+}
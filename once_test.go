@@ -0,0 +1,72 @@
+package limiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOnceLimiter_AllowOnce(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	ctx := context.Background()
+
+	now := time.UnixMilli(10*60*1000 + 15*1000) // 第 10 分钟的第 15 秒
+	clock := ClockFunc(func() time.Time { return now })
+	a := NewOnceLimiter(client, WithOnceClock(clock))
+	b := NewOnceLimiter(client, WithOnceClock(clock))
+
+	res, err := a.AllowOnceResult(ctx, "refresh", time.Minute)
+	assert.NoError(t, err)
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 45*time.Second, res.RetryAfter)
+
+	// 同一个周期内其他实例（以及自己）都拿不到执行权
+	ok, err := b.AllowOnce(ctx, "refresh", time.Minute)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	now = now.Add(40 * time.Second)
+	ok, err = a.AllowOnce(ctx, "refresh", time.Minute)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	// 不同 key 互不影响
+	ok, err = b.AllowOnce(ctx, "report", time.Minute)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	// 进入下一个周期后重新开放
+	now = now.Add(5 * time.Second)
+	ok, err = b.AllowOnce(ctx, "refresh", time.Minute)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	// Reset 后本周期可以重新执行
+	assert.NoError(t, b.Reset(ctx, "refresh"))
+	ok, err = a.AllowOnce(ctx, "refresh", time.Minute)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	_, err = a.AllowOnce(ctx, "refresh", 0)
+	assert.Error(t, err)
+}
+
+func TestOnceLimiter_Expire(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	ctx := context.Background()
+
+	o := NewOnceLimiter(client, WithOncePrefix("cron"))
+	ok, err := o.AllowOnce(ctx, "job", time.Hour)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	assert.True(t, mr.Exists("cron:{job}"))
+	ttl := mr.TTL("cron:{job}")
+	assert.Greater(t, ttl, time.Duration(0))
+	assert.LessOrEqual(t, ttl, time.Hour)
+}
@@ -0,0 +1,31 @@
+package otellimit
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/metric"
+
+	limiter "github.com/lifei6671/go-redis-limiter"
+)
+
+// SoftLimitHooks 返回软上限告警钩子（见 limiter.SoftLimitHooks）：
+// 放行的请求越过软上限时累加 "ratelimit.soft_limit" 计数器（按算法区分，不携带 key），
+// 其余事件转发给 next（可以为 nil）。
+func SoftLimitHooks(ratio float64, next limiter.Hooks, opts ...Option) *limiter.SoftLimitHooks {
+	cfg := &config{mp: otel.GetMeterProvider()}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	counter, err := cfg.mp.Meter(instrumentationName).Int64Counter("ratelimit.soft_limit",
+		metric.WithDescription("Number of allowed requests over the soft limit"),
+		metric.WithUnit("{request}"))
+	if err != nil {
+		otel.Handle(err)
+	}
+
+	return limiter.NewSoftLimitHooks(ratio, func(ctx context.Context, e limiter.HookEvent) {
+		counter.Add(ctx, 1, metric.WithAttributes(AlgorithmAttr.String(e.Algorithm)), metric.WithAttributes(cfg.attrs...))
+	}, next)
+}
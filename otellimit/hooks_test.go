@@ -0,0 +1,42 @@
+package otellimit
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+
+	limiter "github.com/lifei6671/go-redis-limiter"
+)
+
+func TestSoftLimitHooks(t *testing.T) {
+	ctx := context.Background()
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	h := SoftLimitHooks(0.5, nil, WithMeterProvider(mp))
+	for _, remaining := range []float64{3, 2, 1, 0} {
+		h.OnAllow(ctx, limiter.HookEvent{Key: "user:1", Algorithm: "token_bucket",
+			Result: limiter.Result{Allowed: true, Limit: 4, Remaining: remaining}})
+	}
+	h.OnDeny(ctx, limiter.HookEvent{Algorithm: "token_bucket", Result: limiter.Result{Limit: 4}})
+
+	var rm metricdata.ResourceMetrics
+	assert.NoError(t, reader.Collect(ctx, &rm))
+	var total int64
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name != "ratelimit.soft_limit" {
+				continue
+			}
+			for _, dp := range m.Data.(metricdata.Sum[int64]).DataPoints {
+				v, _ := dp.Attributes.Value(AlgorithmAttr)
+				assert.Equal(t, "token_bucket", v.AsString())
+				total += dp.Value
+			}
+		}
+	}
+	assert.Equal(t, int64(3), total)
+}
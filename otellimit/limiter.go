@@ -0,0 +1,128 @@
+package otellimit
+
+import (
+	"context"
+	"time"
+
+	limiter "github.com/lifei6671/go-redis-limiter"
+)
+
+// tracedLimiter 为单桶限流器加上埋点。
+type tracedLimiter struct {
+	l   limiter.RateLimiter
+	key string
+	in  *instruments
+}
+
+// Wrap 为单桶限流器加上 OpenTelemetry 埋点。
+// key 为该限流器的业务 key，algorithm 为算法名（例如 "token_bucket"），两者会作为 span 属性。
+func Wrap(l limiter.RateLimiter, key, algorithm string, opts ...Option) limiter.RateLimiter {
+	if l == nil {
+		panic("otellimit: limiter is nil")
+	}
+	return &tracedLimiter{l: l, key: key, in: newInstruments(algorithm, opts)}
+}
+
+// Allow 通过 AllowWithResult 判定，以便在 span 上记录剩余额度。
+func (t *tracedLimiter) Allow(ctx context.Context) (bool, error) {
+	res, err := t.AllowWithResult(ctx)
+	return res.Allowed, err
+}
+
+func (t *tracedLimiter) AllowWithResult(ctx context.Context) (limiter.Result, error) {
+	ctx, end := t.in.start(ctx, "allow", t.key)
+	res, err := t.l.AllowWithResult(ctx)
+	end(&res.Allowed, &res.Remaining, err)
+	return res, err
+}
+
+func (t *tracedLimiter) AllowN(ctx context.Context, n int64) (bool, error) {
+	ctx, end := t.in.start(ctx, "allow_n", t.key)
+	ok, err := t.l.AllowN(ctx, n)
+	end(&ok, nil, err)
+	return ok, err
+}
+
+func (t *tracedLimiter) Wait(ctx context.Context, maxWait time.Duration) error {
+	ctx, end := t.in.start(ctx, "wait", t.key)
+	err := t.l.Wait(ctx, maxWait)
+	ok, spanErr := waitOutcome(err)
+	end(&ok, nil, spanErr)
+	return err
+}
+
+func (t *tracedLimiter) State(ctx context.Context) (limiter.LimiterState, error) {
+	ctx, end := t.in.start(ctx, "state", t.key)
+	st, err := t.l.State(ctx)
+	end(nil, &st.Remaining, err)
+	return st, err
+}
+
+func (t *tracedLimiter) Reset(ctx context.Context) error {
+	ctx, end := t.in.start(ctx, "reset", t.key)
+	err := t.l.Reset(ctx)
+	end(nil, nil, err)
+	return err
+}
+
+// tracedShardedLimiter 为分片限流器加上埋点。
+type tracedShardedLimiter struct {
+	l  limiter.RateShardedLimiter
+	in *instruments
+}
+
+// WrapSharded 为分片限流器加上 OpenTelemetry 埋点，shardKey 会作为 span 的 key 属性。
+func WrapSharded(l limiter.RateShardedLimiter, algorithm string, opts ...Option) limiter.RateShardedLimiter {
+	if l == nil {
+		panic("otellimit: limiter is nil")
+	}
+	return &tracedShardedLimiter{l: l, in: newInstruments(algorithm, opts)}
+}
+
+func (t *tracedShardedLimiter) Allow(ctx context.Context, shardKey string) (bool, error) {
+	res, err := t.AllowWithResult(ctx, shardKey)
+	return res.Allowed, err
+}
+
+func (t *tracedShardedLimiter) AllowWithResult(ctx context.Context, shardKey string) (limiter.Result, error) {
+	ctx, end := t.in.start(ctx, "allow", shardKey)
+	res, err := t.l.AllowWithResult(ctx, shardKey)
+	end(&res.Allowed, &res.Remaining, err)
+	return res, err
+}
+
+func (t *tracedShardedLimiter) AllowN(ctx context.Context, shardKey string, n int64) (bool, error) {
+	ctx, end := t.in.start(ctx, "allow_n", shardKey)
+	ok, err := t.l.AllowN(ctx, shardKey, n)
+	end(&ok, nil, err)
+	return ok, err
+}
+
+func (t *tracedShardedLimiter) Wait(ctx context.Context, shardKey string, maxWait time.Duration) error {
+	ctx, end := t.in.start(ctx, "wait", shardKey)
+	err := t.l.Wait(ctx, shardKey, maxWait)
+	ok, spanErr := waitOutcome(err)
+	end(&ok, nil, spanErr)
+	return err
+}
+
+func (t *tracedShardedLimiter) State(ctx context.Context, shardKey string) (limiter.LimiterState, error) {
+	ctx, end := t.in.start(ctx, "state", shardKey)
+	st, err := t.l.State(ctx, shardKey)
+	end(nil, &st.Remaining, err)
+	return st, err
+}
+
+func (t *tracedShardedLimiter) Reset(ctx context.Context, shardKey string) error {
+	ctx, end := t.in.start(ctx, "reset", shardKey)
+	err := t.l.Reset(ctx, shardKey)
+	end(nil, nil, err)
+	return err
+}
+
+func (t *tracedShardedLimiter) ResetAll(ctx context.Context) error {
+	ctx, end := t.in.start(ctx, "reset_all", "")
+	err := t.l.ResetAll(ctx)
+	end(nil, nil, err)
+	return err
+}
@@ -0,0 +1,102 @@
+package otellimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	limiter "github.com/lifei6671/go-redis-limiter"
+)
+
+// fakeLimiter 放行前 limit 次请求。
+type fakeLimiter struct {
+	limit int64
+	used  int64
+}
+
+func (f *fakeLimiter) Allow(ctx context.Context) (bool, error) {
+	return f.AllowN(ctx, 1)
+}
+
+func (f *fakeLimiter) AllowWithResult(ctx context.Context) (limiter.Result, error) {
+	ok, err := f.AllowN(ctx, 1)
+	return limiter.Result{Allowed: ok, Limit: float64(f.limit), Remaining: float64(f.limit - f.used)}, err
+}
+
+func (f *fakeLimiter) AllowN(_ context.Context, n int64) (bool, error) {
+	if f.used+n > f.limit {
+		return false, nil
+	}
+	f.used += n
+	return true, nil
+}
+
+func (f *fakeLimiter) Wait(ctx context.Context, _ time.Duration) error {
+	if ok, _ := f.Allow(ctx); !ok {
+		return limiter.ErrTimeout
+	}
+	return nil
+}
+
+func (f *fakeLimiter) Reset(context.Context) error {
+	f.used = 0
+	return nil
+}
+
+func (f *fakeLimiter) State(context.Context) (limiter.LimiterState, error) {
+	return limiter.LimiterState{Remaining: float64(f.limit - f.used)}, nil
+}
+
+func TestWrap(t *testing.T) {
+	ctx := context.Background()
+	sr := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	l := Wrap(&fakeLimiter{limit: 1}, "user:1", "token_bucket",
+		WithTracerProvider(tp), WithMeterProvider(mp))
+
+	ok, err := l.Allow(ctx)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.ErrorIs(t, l.Wait(ctx, time.Second), limiter.ErrTimeout)
+
+	spans := sr.Ended()
+	if assert.Len(t, spans, 2) {
+		assert.Equal(t, "ratelimit.allow", spans[0].Name())
+		assert.Contains(t, spans[0].Attributes(), KeyAttr.String("user:1"))
+		assert.Contains(t, spans[0].Attributes(), AlgorithmAttr.String("token_bucket"))
+		assert.Contains(t, spans[0].Attributes(), AllowedAttr.Bool(true))
+		assert.Contains(t, spans[0].Attributes(), RemainingAttr.Float64(0))
+
+		// 超时属于限流结果，而不是错误
+		assert.Equal(t, "ratelimit.wait", spans[1].Name())
+		assert.Contains(t, spans[1].Attributes(), AllowedAttr.Bool(false))
+		assert.Empty(t, spans[1].Events())
+	}
+
+	var rm metricdata.ResourceMetrics
+	assert.NoError(t, reader.Collect(ctx, &rm))
+	counts := map[bool]int64{}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name != "ratelimit.decisions" {
+				continue
+			}
+			for _, dp := range m.Data.(metricdata.Sum[int64]).DataPoints {
+				v, _ := dp.Attributes.Value(AllowedAttr)
+				counts[v.AsBool()] += dp.Value
+				_, hasKey := dp.Attributes.Value(KeyAttr)
+				assert.False(t, hasKey, "key must not be a metric attribute")
+			}
+		}
+	}
+	assert.Equal(t, map[bool]int64{true: 1, false: 1}, counts)
+}
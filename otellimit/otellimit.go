@@ -0,0 +1,143 @@
+// Package otellimit 为限流器提供 OpenTelemetry 埋点：
+// 在 Allow/Wait/State 外层创建 span，并通过 OTel metrics API 上报判定次数与耗时。
+package otellimit
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+
+	limiter "github.com/lifei6671/go-redis-limiter"
+)
+
+const instrumentationName = "github.com/lifei6671/go-redis-limiter/otellimit"
+
+// span 与 metric 使用的属性名。
+const (
+	KeyAttr       = attribute.Key("ratelimit.key")
+	AlgorithmAttr = attribute.Key("ratelimit.algorithm")
+	AllowedAttr   = attribute.Key("ratelimit.allowed")
+	RemainingAttr = attribute.Key("ratelimit.remaining")
+	OperationAttr = attribute.Key("ratelimit.operation")
+)
+
+// Option 为埋点的配置项。
+type Option func(*config)
+
+type config struct {
+	tp    trace.TracerProvider
+	mp    metric.MeterProvider
+	attrs []attribute.KeyValue
+}
+
+// WithTracerProvider 指定 TracerProvider，默认使用 otel.GetTracerProvider()。
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(c *config) {
+		if tp != nil {
+			c.tp = tp
+		}
+	}
+}
+
+// WithMeterProvider 指定 MeterProvider，默认使用 otel.GetMeterProvider()。
+func WithMeterProvider(mp metric.MeterProvider) Option {
+	return func(c *config) {
+		if mp != nil {
+			c.mp = mp
+		}
+	}
+}
+
+// WithAttributes 为所有 span 和 metric 追加固定属性，例如服务名、规则名。
+func WithAttributes(attrs ...attribute.KeyValue) Option {
+	return func(c *config) {
+		c.attrs = append(c.attrs, attrs...)
+	}
+}
+
+// instruments 保存 tracer 与 metric 仪表。
+type instruments struct {
+	tracer    trace.Tracer
+	decisions metric.Int64Counter
+	duration  metric.Float64Histogram
+	algorithm string
+	attrs     []attribute.KeyValue
+}
+
+func newInstruments(algorithm string, opts []Option) *instruments {
+	cfg := &config{
+		tp: otel.GetTracerProvider(),
+		mp: otel.GetMeterProvider(),
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	meter := cfg.mp.Meter(instrumentationName)
+	// 创建仪表失败时 OTel 会返回可用的 no-op 实现，这里交给全局 ErrorHandler 处理即可
+	decisions, err := meter.Int64Counter("ratelimit.decisions",
+		metric.WithDescription("Number of rate limit decisions"),
+		metric.WithUnit("{decision}"))
+	if err != nil {
+		otel.Handle(err)
+	}
+	duration, err := meter.Float64Histogram("ratelimit.duration",
+		metric.WithDescription("Duration of rate limiter operations"),
+		metric.WithUnit("s"))
+	if err != nil {
+		otel.Handle(err)
+	}
+
+	return &instruments{
+		tracer:    cfg.tp.Tracer(instrumentationName),
+		decisions: decisions,
+		duration:  duration,
+		algorithm: algorithm,
+		attrs:     append([]attribute.KeyValue{AlgorithmAttr.String(algorithm)}, cfg.attrs...),
+	}
+}
+
+// start 开启一个 span，返回结束时需要调用的 end 函数。
+// metric 上不携带 key，避免高基数；key 只记录在 span 上。
+func (in *instruments) start(ctx context.Context, op, key string) (context.Context, func(allowed *bool, remaining *float64, err error)) {
+	begin := time.Now()
+	ctx, span := in.tracer.Start(ctx, "ratelimit."+op,
+		trace.WithSpanKind(trace.SpanKindInternal),
+		trace.WithAttributes(in.attrs...),
+		trace.WithAttributes(KeyAttr.String(key)),
+	)
+
+	return ctx, func(allowed *bool, remaining *float64, err error) {
+		attrs := append([]attribute.KeyValue{OperationAttr.String(op)}, in.attrs...)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		if allowed != nil {
+			span.SetAttributes(AllowedAttr.Bool(*allowed))
+			in.decisions.Add(ctx, 1, metric.WithAttributes(append(attrs, AllowedAttr.Bool(*allowed))...))
+		}
+		if remaining != nil {
+			span.SetAttributes(RemainingAttr.Float64(*remaining))
+		}
+		in.duration.Record(ctx, time.Since(begin).Seconds(), metric.WithAttributes(attrs...))
+		span.End()
+	}
+}
+
+// waitOutcome 把 Wait 的返回值拆成判定结果和真正的错误：超时/被限流不视为 span 错误。
+func waitOutcome(err error) (bool, error) {
+	if err == nil {
+		return true, nil
+	}
+	if errors.Is(err, limiter.ErrLimiter) || errors.Is(err, limiter.ErrTimeout) {
+		return false, nil
+	}
+	return false, err
+}
@@ -0,0 +1,232 @@
+package limiter
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// OverageResult 是两段式限流一次判定的结果。
+type OverageResult struct {
+	// Allowed 是否放行（只有超过硬上限才会被拒绝）
+	Allowed bool
+	// Flagged 本次请求是否落在软上限与硬上限之间（超额部分），
+	// 调用方通常据此记录超额计费。
+	Flagged bool
+	// Used 当前窗口内已使用的数量（包含本次请求）
+	Used int64
+}
+
+// OverageLimiter 实现“软上限记录、硬上限拦截”的两段式固定窗口限流：
+//   - 用量 <= SoftLimit：正常放行
+//   - SoftLimit < 用量 <= HardLimit：放行，但标记为超额（用于超额计费）
+//   - 用量 > HardLimit：拒绝
+//
+// 两个阈值在同一个 Lua 脚本中原子判断，不会出现“先查后扣”的竞态。
+type OverageLimiter struct {
+	client *redis.Client
+
+	Key    string        // 业务 key，例如 "tenant:42:api"
+	Prefix string        // Redis key 前缀，默认 "ovg"
+	Window time.Duration // 计数窗口大小，例如 24 * time.Hour
+
+	SoftLimit int64 // 软上限：超过后仍放行，但标记超额
+	HardLimit int64 // 硬上限：超过后拒绝
+
+	// ServerTime 为 true（默认）时脚本使用 Redis TIME 计算窗口起点与计数 key，
+	// 所有应用实例共享同一个时钟源；为 false 时使用本机时钟。
+	ServerTime bool
+
+	// Clock 时钟，默认 SystemClock。
+	Clock Clock
+
+	// Hooks 判定事件钩子（可选），每次放行 / 拒绝 / 出错时回调，见 Hooks。
+	Hooks Hooks
+}
+
+// NewOverageLimiter 创建一个两段式（软/硬上限）限流器。
+func NewOverageLimiter(
+	client *redis.Client,
+	key string,
+	opts ...OverageOption,
+) *OverageLimiter {
+
+	if client == nil {
+		panic("overage: redis client is nil")
+	}
+	if key == "" {
+		panic("overage: key is empty")
+	}
+
+	l := &OverageLimiter{
+		client:     client,
+		Key:        key,
+		Prefix:     "ovg",
+		Window:     time.Hour,
+		SoftLimit:  1000,
+		HardLimit:  1200,
+		ServerTime: true,
+		Clock:      SystemClock,
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	if l.SoftLimit > l.HardLimit {
+		panic("overage: soft limit must <= hard limit")
+	}
+	return l
+}
+
+// baseKey 返回计数 key 的公共前缀，脚本在其后追加窗口起点得到当前窗口的计数 key。
+func (l *OverageLimiter) baseKey() string {
+	return fmt.Sprintf("%s:{%s}", l.Prefix, l.Key)
+}
+
+// countKey 返回某个窗口对应的计数 key，与脚本中的拼接方式一致。
+func (l *OverageLimiter) countKey(windowStart int64) string {
+	return fmt.Sprintf("%s:%d", l.baseKey(), windowStart)
+}
+
+// Allow 尝试通过 1 个请求，只有超过硬上限才返回 false。
+func (l *OverageLimiter) Allow(ctx context.Context) (bool, error) {
+	return l.AllowN(ctx, 1)
+}
+
+// AllowN 尝试一次通过 n 个请求，只有超过硬上限才返回 false。
+// 如需知道是否处于超额区间，请使用 AllowNWithOverage。
+func (l *OverageLimiter) AllowN(ctx context.Context, n int64) (bool, error) {
+	res, err := l.AllowNWithOverage(ctx, n)
+	if err != nil {
+		return false, err
+	}
+	return res.Allowed, nil
+}
+
+// AllowWithResult 尝试通过 1 个请求，并返回硬上限下的剩余额度及重试等待时间。
+func (l *OverageLimiter) AllowWithResult(ctx context.Context) (Result, error) {
+	return l.AllowNWithResult(ctx, 1)
+}
+
+// AllowNWithResult 尝试一次通过 n 个请求，并返回硬上限下的剩余额度及重试等待时间。
+// 被拒绝时 RetryAfter 为距离下一个窗口开始的时长。
+func (l *OverageLimiter) AllowNWithResult(ctx context.Context, n int64) (Result, error) {
+	_, res, err := l.allow(ctx, n)
+	return res, err
+}
+
+// AllowNWithOverage 尝试一次通过 n 个请求，并返回是否处于超额区间。
+func (l *OverageLimiter) AllowNWithOverage(ctx context.Context, n int64) (OverageResult, error) {
+	out, _, err := l.allow(ctx, n)
+	return out, err
+}
+
+// allow 执行一次判定，同时返回两段式结果与硬上限下的 Result，并触发钩子。
+func (l *OverageLimiter) allow(ctx context.Context, n int64) (OverageResult, Result, error) {
+	var (
+		out OverageResult
+		res Result
+		err error
+	)
+	if n <= 0 {
+		err = fmt.Errorf("overage: n must > 0")
+	} else {
+		var w overageWindow
+		out, w, err = l.eval(ctx, n, "")
+		if err == nil {
+			res = Result{
+				Allowed:   out.Allowed,
+				Limit:     float64(l.HardLimit),
+				Remaining: float64(max(l.HardLimit-out.Used, 0)),
+			}
+			if !out.Allowed {
+				res.RetryAfter = time.Duration(w.end(l.Window)-w.now) * time.Millisecond
+			}
+		}
+	}
+	fireHooks(ctx, l.Hooks, "overage", l.Key, n, res, err)
+	return out, res, err
+}
+
+// overageWindow 为脚本返回的窗口起点与脚本使用的当前时间（毫秒）。
+type overageWindow struct {
+	start, now int64
+}
+
+// end 返回窗口结束（下一个窗口开始）的时间，毫秒。
+func (w overageWindow) end(window time.Duration) int64 {
+	return w.start + window.Milliseconds()
+}
+
+// eval 执行两段式限流脚本，op 为空时判定并计数，"state" 只读取用量，"reset" 删除当前窗口的计数。
+func (l *OverageLimiter) eval(ctx context.Context, n int64, op string) (OverageResult, overageWindow, error) {
+	args := []interface{}{scriptNow(l.ServerTime, l.Clock), l.Window.Milliseconds(), l.SoftLimit, l.HardLimit, n}
+	if op != "" {
+		args = append(args, op)
+	}
+	res, err := overageScript.Run(ctx, l.client, []string{l.baseKey()}, args...).Result()
+	if err != nil {
+		return OverageResult{}, overageWindow{}, err
+	}
+
+	vals, ok := scriptInts(res, 5)
+	if !ok {
+		return OverageResult{}, overageWindow{}, fmt.Errorf("overage: unexpected script result: %#v", res)
+	}
+
+	return OverageResult{
+		Allowed: vals[0] == 1,
+		Flagged: vals[2] == 1,
+		Used:    vals[1],
+	}, overageWindow{start: vals[3], now: vals[4]}, nil
+}
+
+// Wait 阻塞直到请求被放行（即低于硬上限），或 ctx 取消 / 超过 maxWait。
+// 固定窗口只有在窗口切换时才会释放额度，因此被拒绝后直接等到下一个窗口起点再重试。
+func (l *OverageLimiter) Wait(ctx context.Context, maxWait time.Duration) error {
+	return waitFor(ctx, l.Key, "overage", maxWait, nil, l.AllowWithResult)
+}
+
+// Reset 清空当前窗口的计数（历史窗口的 key 会自然过期）。当前窗口与 Allow 一样由脚本中的时间确定。
+func (l *OverageLimiter) Reset(ctx context.Context) error {
+	_, _, err := l.eval(ctx, 0, "reset")
+	return err
+}
+
+// State 返回当前窗口的用量，同时报告软上限（SoftLimit）与硬上限（Capacity）。
+// 用量通过与 Allow 相同的脚本读取，窗口起点同样由脚本中的时间确定。
+func (l *OverageLimiter) State(ctx context.Context) (LimiterState, error) {
+	out, w, err := l.eval(ctx, 0, "state")
+	if err != nil {
+		return LimiterState{}, err
+	}
+	used := out.Used
+
+	remaining := float64(l.HardLimit - used)
+	if remaining < 0 {
+		remaining = 0
+	}
+	overage := float64(used - l.SoftLimit)
+	if overage < 0 {
+		overage = 0
+	}
+
+	next := w.now
+	if used >= l.HardLimit {
+		next = w.end(l.Window)
+	}
+
+	return LimiterState{
+		Level:             float64(used),
+		Remaining:         remaining,
+		Capacity:          float64(l.HardLimit),
+		SoftLimit:         float64(l.SoftLimit),
+		Overage:           overage,
+		Rate:              float64(l.HardLimit) / l.Window.Seconds(),
+		LastUpdated:       w.now,
+		NextAvailableTime: next,
+		Type:              "overage",
+		Key:               l.Key,
+	}, nil
+}
@@ -0,0 +1,69 @@
+package limiter
+
+import "time"
+
+// OverageOption 为两段式（软/硬上限）限流器的配置项。
+// 所有函数名均以 Overage 前缀开头，避免与其他限流器的 Option 冲突。
+type OverageOption func(*OverageLimiter)
+
+// WithOverageWindow 设置计数窗口大小（最小精度为毫秒）。
+func WithOverageWindow(d time.Duration) OverageOption {
+	return func(l *OverageLimiter) {
+		if d >= time.Millisecond {
+			l.Window = d
+		}
+	}
+}
+
+// WithOverageLimits 同时设置软上限与硬上限，要求 0 < soft <= hard。
+func WithOverageLimits(soft, hard int64) OverageOption {
+	return func(l *OverageLimiter) {
+		if soft <= 0 || hard <= 0 || soft > hard {
+			panic("overage: limits must satisfy 0 < soft <= hard")
+		}
+		l.SoftLimit = soft
+		l.HardLimit = hard
+	}
+}
+
+// WithOverageServerTime 设置是否使用 Redis TIME 计算窗口起点，默认开启。
+// 关闭后使用本机时钟，各应用实例的时钟偏差会把同一个窗口拆成不同的计数 key。
+func WithOverageServerTime(enabled bool) OverageOption {
+	return func(l *OverageLimiter) {
+		l.ServerTime = enabled
+	}
+}
+
+// WithOverageClock 设置时钟，通常用于测试中控制时间。
+// 注入时钟后脚本改用该时钟传入的时间（相当于 WithOverageServerTime(false)）。
+func WithOverageClock(c Clock) OverageOption {
+	return func(l *OverageLimiter) {
+		if c != nil {
+			l.Clock = c
+			l.ServerTime = false
+		}
+	}
+}
+
+// WithOveragePrefix 设置 Redis key 前缀。
+func WithOveragePrefix(prefix string) OverageOption {
+	return func(l *OverageLimiter) {
+		if prefix != "" {
+			l.Prefix = prefix
+		}
+	}
+}
+
+// WithOverageCustom 提供一个自定义扩展入口。
+func WithOverageCustom(fn func(*OverageLimiter)) OverageOption {
+	return func(l *OverageLimiter) {
+		fn(l)
+	}
+}
+
+// WithOverageHooks 设置判定事件钩子，用于接入日志、指标或告警。
+func WithOverageHooks(h Hooks) OverageOption {
+	return func(l *OverageLimiter) {
+		l.Hooks = h
+	}
+}
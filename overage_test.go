@@ -0,0 +1,136 @@
+package limiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/go-redis/redismock/v8"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOverageLimiter_AllowNWithOverage(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer db.Close()
+	ctx := context.Background()
+
+	l := NewOverageLimiter(
+		db,
+		"tenant",
+		WithOverageWindow(time.Hour),
+		WithOverageLimits(10, 20),
+	)
+	// 默认使用 Redis TIME：nowMs 传 0，窗口起点与当前时间由脚本返回
+	args := []interface{}{int64(0), int64(3_600_000), int64(10), int64(20), int64(1)}
+	window := []interface{}{int64(7_200_000), int64(10_000_000)}
+
+	t.Run("Overage_Allow_ok", func(t *testing.T) {
+		mock.ExpectEvalSha(overageScript.Hash(), []string{"ovg:{tenant}"}, args...).
+			SetVal(append([]interface{}{int64(1), int64(5), int64(0)}, window...))
+
+		res, err := l.AllowNWithOverage(ctx, 1)
+		assert.NoError(t, err)
+		assert.True(t, res.Allowed)
+		assert.False(t, res.Flagged)
+		assert.Equal(t, int64(5), res.Used)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Overage_Allow_flagged", func(t *testing.T) {
+		mock.ExpectEvalSha(overageScript.Hash(), []string{"ovg:{tenant}"}, args...).
+			SetVal(append([]interface{}{int64(1), int64(15), int64(1)}, window...))
+
+		res, err := l.AllowNWithOverage(ctx, 1)
+		assert.NoError(t, err)
+		assert.True(t, res.Allowed)
+		assert.True(t, res.Flagged)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Overage_Allow_hard_denied", func(t *testing.T) {
+		mock.ExpectEvalSha(overageScript.Hash(), []string{"ovg:{tenant}"}, args...).
+			SetVal(append([]interface{}{int64(0), int64(20), int64(1)}, window...))
+
+		res, err := l.AllowWithResult(ctx)
+		assert.NoError(t, err)
+		assert.False(t, res.Allowed)
+		// 距离窗口结束：7_200_000 + 3_600_000 - 10_000_000
+		assert.Equal(t, 800*time.Second, res.RetryAfter)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Overage_Allow_err", func(t *testing.T) {
+		mock.ExpectEvalSha(overageScript.Hash(), []string{"ovg:{tenant}"}, args...).
+			SetErr(redis.ErrClosed)
+
+		_, err := l.AllowNWithOverage(ctx, 1)
+		assert.ErrorIs(t, err, redis.ErrClosed)
+	})
+}
+
+func TestOverageLimiter_State(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer db.Close()
+	ctx := context.Background()
+
+	l := NewOverageLimiter(
+		db,
+		"tenant",
+		WithOverageWindow(time.Hour),
+		WithOverageLimits(10, 20),
+	)
+
+	mock.ExpectEvalSha(overageScript.Hash(), []string{"ovg:{tenant}"},
+		int64(0), int64(3_600_000), int64(10), int64(20), int64(0), "state").
+		SetVal([]interface{}{int64(1), int64(15), int64(1), int64(7_200_000), int64(10_000_000)})
+
+	s, err := l.State(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, float64(15), s.Level)
+	assert.Equal(t, float64(10), s.SoftLimit)
+	assert.Equal(t, float64(20), s.Capacity)
+	assert.Equal(t, float64(5), s.Overage)
+	assert.Equal(t, float64(5), s.Remaining)
+	assert.Equal(t, int64(10_000_000), s.LastUpdated)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestOverageLimiter_ServerTime(t *testing.T) {
+	client := newSpecClient(t)
+	ctx := context.Background()
+
+	// 两个实例的本机时钟相差 2 小时，但都使用 Redis TIME：计数落在同一个窗口 key 上
+	skewed := ClockFunc(func() time.Time { return time.Now().Add(2 * time.Hour) })
+	a := NewOverageLimiter(client, "tenant", WithOverageWindow(time.Hour), WithOverageLimits(2, 3))
+	b := NewOverageLimiter(client, "tenant", WithOverageWindow(time.Hour), WithOverageLimits(2, 3),
+		WithOverageClock(skewed), WithOverageServerTime(true))
+
+	for _, l := range []*OverageLimiter{a, b, a} {
+		ok, err := l.Allow(ctx)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+	}
+	res, err := b.AllowNWithOverage(ctx, 1)
+	assert.NoError(t, err)
+	assert.False(t, res.Allowed)
+
+	st, err := b.State(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, float64(3), st.Level)
+
+	assert.NoError(t, a.Reset(ctx))
+	st, err = b.State(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, float64(0), st.Level)
+
+	// 使用本机时钟时，各实例按各自的时钟计算窗口
+	local := NewOverageLimiter(client, "tenant", WithOverageWindow(time.Hour), WithOverageLimits(2, 3), WithOverageClock(skewed))
+	assert.False(t, local.ServerTime)
+	ok, err := local.Allow(ctx)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	start := skewed.Now().UnixMilli() / 3_600_000 * 3_600_000
+	n, _ := client.Exists(ctx, local.countKey(start)).Result()
+	assert.Equal(t, int64(1), n)
+}
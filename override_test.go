@@ -0,0 +1,73 @@
+package limiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenBucket_Override(t *testing.T) {
+	client := newSpecClient(t)
+	ctx := context.Background()
+
+	tb := NewTokenBucketLimiter(client, "whale",
+		WithTokenBucketRate(0.001),
+		WithTokenBucketCapacity(2),
+		WithTokenBucketTTL(time.Minute),
+		WithTokenBucketOverrides(""),
+	)
+
+	_, ok, err := tb.GetOverride(ctx)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	assert.NoError(t, tb.SetOverride(ctx, LimitOverride{Capacity: 5}))
+	o, ok, err := tb.GetOverride(ctx)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, LimitOverride{Capacity: 5}, o)
+	assert.Equal(t, int64(1), client.Exists(ctx, "limits:{whale}").Val())
+
+	// 覆盖后容量为 5，默认的 2 不再生效
+	for i := 0; i < 5; i++ {
+		res, err := tb.AllowWithResult(ctx)
+		assert.NoError(t, err)
+		assert.True(t, res.Allowed, "request %d", i)
+		assert.Equal(t, float64(5), res.Limit)
+	}
+	ok, _ = tb.Allow(ctx)
+	assert.False(t, ok)
+
+	st, err := tb.State(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, float64(5), st.Capacity)
+
+	// 清除后恢复默认容量
+	assert.NoError(t, tb.ClearOverride(ctx))
+	assert.NoError(t, tb.Reset(ctx))
+	res, err := tb.AllowNWithResult(ctx, 2)
+	assert.NoError(t, err)
+	assert.True(t, res.Allowed)
+	assert.Equal(t, float64(2), res.Limit)
+
+	plain := NewTokenBucketLimiter(client, "plain")
+	assert.ErrorIs(t, plain.SetOverride(ctx, LimitOverride{Rate: 1}), ErrOverrideDisabled)
+}
+
+func TestLeakyBucket_Override(t *testing.T) {
+	client := newSpecClient(t)
+	ctx := context.Background()
+
+	lb := NewLeakyBucketLimiter(client, "whale",
+		WithLeakyBucketRate(0.001),
+		WithLeakyBucketCapacity(1),
+		WithLeakyBucketOverrides("ovr"),
+	)
+	assert.NoError(t, lb.SetOverride(ctx, LimitOverride{Capacity: 3}))
+
+	n, err := lb.AllowUpToN(ctx, 5)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), n)
+}
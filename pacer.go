@@ -0,0 +1,153 @@
+package limiter
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Pacer 在漏桶之上按泄漏速率匀速产生“节拍”，用于严格匀速的外发投递（邮件、推送、第三方 API 调用等）：
+//
+//	lb := limiter.NewLeakyBucketLimiter(rdb, "mail:outbound",
+//		limiter.WithLeakyBucketRate(50), limiter.WithLeakyBucketCapacity(1))
+//	p := limiter.NewPacer(lb, limiter.WithPacerHandler(func(ctx context.Context) {
+//		sendNext(ctx)
+//	}))
+//	_ = p.Start()
+//	defer p.Stop(context.Background())
+//
+// 每个节拍对应漏桶中放入的 1 单位：设置了 Handler 时在后台协程中依次调用 Handler，
+// 否则通过 C() 返回的通道投递放行时间。节奏由 Redis 中的漏桶统一协调，
+// 同一个 key 上所有实例的 Pacer 合起来按 LeakRate 推进；漏桶容量为 1 时相邻节拍严格间隔 1/LeakRate，
+// 容量更大时允许积攒的突发即为容量。
+//
+// Redis 出错时通过 OnError 上报，间隔 ErrorBackoff 后重试，不会产生节拍。
+type Pacer struct {
+	lb *LeakyBucketLimiter
+
+	// Handler 每个节拍调用一次（可选），调用结束后才会申请下一个节拍。
+	// 参数 ctx 只在 Stop 的 ctx 到期（强制停止）时取消。未设置时节拍通过 C() 投递。
+	Handler func(ctx context.Context)
+	// ErrorBackoff Redis 出错后重试的间隔，默认 100ms
+	ErrorBackoff time.Duration
+	// OnError Redis 出错时的回调（可选）
+	OnError func(err error)
+
+	c chan time.Time
+
+	mu     sync.Mutex
+	stop   context.CancelFunc // 停止申请新的节拍
+	cancel context.CancelFunc // 取消进行中的 Handler
+	done   chan struct{}
+}
+
+// NewPacer 在漏桶 lb 之上创建 Pacer，需要调用 Start 才会开始产生节拍。
+func NewPacer(lb *LeakyBucketLimiter, opts ...PacerOption) *Pacer {
+	if lb == nil {
+		panic("pacer: leaky bucket is nil")
+	}
+
+	p := &Pacer{
+		lb:           lb,
+		ErrorBackoff: 100 * time.Millisecond,
+		c:            make(chan time.Time),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// C 返回投递节拍的通道，每收到一个值表示可以处理一个单位，值为放行时间。
+// 通道无缓冲且不会被关闭（与 time.Ticker 相同）；设置了 Handler 时不会有任何值。
+func (p *Pacer) C() <-chan time.Time {
+	return p.c
+}
+
+// Start 启动后台协程开始产生节拍。Pacer 正在运行（包括 Stop 之后尚未完全退出）时返回错误；
+// 完全停止后可以再次 Start。
+func (p *Pacer) Start() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.done != nil {
+		select {
+		case <-p.done:
+		default:
+			return fmt.Errorf("pacer: already running")
+		}
+	}
+
+	runCtx, cancel := context.WithCancel(context.Background())
+	acquireCtx, stop := context.WithCancel(runCtx)
+	p.stop, p.cancel = stop, cancel
+	p.done = make(chan struct{})
+
+	go p.loop(runCtx, acquireCtx, p.done)
+	return nil
+}
+
+// Stop 优雅停止：不再申请新的节拍，等待进行中的 Handler 返回。
+// 已经申请到但尚未被 C() 的接收方取走的节拍会归还给漏桶。
+// ctx 到期时取消 Handler 的 ctx 并立即返回 ctx.Err()，后台协程在 Handler 返回后退出。
+// 未启动或已停止时直接返回 nil。
+func (p *Pacer) Stop(ctx context.Context) error {
+	p.mu.Lock()
+	stop, cancel, done := p.stop, p.cancel, p.done
+	p.mu.Unlock()
+
+	if done == nil {
+		return nil
+	}
+	stop()
+	select {
+	case <-done:
+		cancel()
+		return nil
+	case <-ctx.Done():
+		cancel()
+		return ctx.Err()
+	}
+}
+
+// loop 反复向漏桶申请 1 单位并产生节拍，直到 acquireCtx 被取消。
+func (p *Pacer) loop(runCtx, acquireCtx context.Context, done chan struct{}) {
+	defer close(done)
+
+	for {
+		if err := p.lb.Wait(acquireCtx, UntilDeadline); err != nil {
+			if acquireCtx.Err() != nil {
+				return
+			}
+			if p.OnError != nil {
+				p.OnError(fmt.Errorf("pacer: %w", err))
+			}
+			timer := time.NewTimer(p.ErrorBackoff)
+			select {
+			case <-acquireCtx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+			continue
+		}
+
+		if p.Handler != nil {
+			p.Handler(runCtx)
+			continue
+		}
+
+		select {
+		case p.c <- p.lb.Clock.Now():
+		case <-acquireCtx.Done():
+			// 节拍没有被取走，把占用的 1 单位归还给漏桶，避免其他实例白白等待
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			if err := p.lb.ReturnN(ctx, 1); err != nil && p.OnError != nil {
+				p.OnError(fmt.Errorf("pacer: return: %w", err))
+			}
+			cancel()
+			return
+		}
+	}
+}
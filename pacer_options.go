@@ -0,0 +1,32 @@
+package limiter
+
+import (
+	"context"
+	"time"
+)
+
+// PacerOption 为 Pacer 的配置项。
+type PacerOption func(*Pacer)
+
+// WithPacerHandler 设置每个节拍调用的函数，设置后节拍不再通过 C() 投递。
+func WithPacerHandler(fn func(ctx context.Context)) PacerOption {
+	return func(p *Pacer) {
+		p.Handler = fn
+	}
+}
+
+// WithPacerErrorBackoff 设置 Redis 出错后重试的间隔，默认 100ms。
+func WithPacerErrorBackoff(d time.Duration) PacerOption {
+	return func(p *Pacer) {
+		if d > 0 {
+			p.ErrorBackoff = d
+		}
+	}
+}
+
+// WithPacerErrorHandler 设置 Redis 出错时的回调。
+func WithPacerErrorHandler(fn func(err error)) PacerOption {
+	return func(p *Pacer) {
+		p.OnError = fn
+	}
+}
@@ -0,0 +1,80 @@
+package limiter
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPacer_Channel(t *testing.T) {
+	client := newSpecClient(t)
+	ctx := context.Background()
+
+	lb := NewLeakyBucketLimiter(client, "pacer", WithLeakyBucketRate(50), WithLeakyBucketCapacity(1))
+	p := NewPacer(lb)
+	assert.NoError(t, p.Start())
+	assert.Error(t, p.Start())
+
+	prev := <-p.C()
+	for i := 0; i < 3; i++ {
+		now := <-p.C()
+		assert.GreaterOrEqual(t, now.Sub(prev), 15*time.Millisecond)
+		prev = now
+	}
+
+	assert.NoError(t, p.Stop(ctx))
+	assert.NoError(t, p.Stop(ctx))
+
+	// 停止时未被取走的节拍归还给漏桶：t=100ms 时申请到第二个节拍后一直没有接收方
+	slow := NewLeakyBucketLimiter(client, "pacer:return", WithLeakyBucketRate(10), WithLeakyBucketCapacity(1))
+	ps := NewPacer(slow)
+	assert.NoError(t, ps.Start())
+	<-ps.C()
+	time.Sleep(150 * time.Millisecond)
+	assert.NoError(t, ps.Stop(ctx))
+	ok, err := slow.Allow(ctx)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	// 停止后可以再次启动
+	assert.NoError(t, p.Start())
+	select {
+	case <-p.C():
+	case <-time.After(time.Second):
+		t.Fatal("no tick after restart")
+	}
+	assert.NoError(t, p.Stop(ctx))
+}
+
+func TestPacer_Handler(t *testing.T) {
+	client := newSpecClient(t)
+	ctx := context.Background()
+
+	// 两个实例共享同一个漏桶，合起来按 LeakRate 推进
+	var n atomic.Int64
+	handler := WithPacerHandler(func(context.Context) { n.Add(1) })
+	p1 := NewPacer(NewLeakyBucketLimiter(client, "pacer", WithLeakyBucketRate(50), WithLeakyBucketCapacity(1)), handler)
+	p2 := NewPacer(NewLeakyBucketLimiter(client, "pacer", WithLeakyBucketRate(50), WithLeakyBucketCapacity(1)), handler)
+	assert.NoError(t, p1.Start())
+	assert.NoError(t, p2.Start())
+	time.Sleep(200 * time.Millisecond)
+	assert.NoError(t, p1.Stop(ctx))
+	assert.NoError(t, p2.Stop(ctx))
+	assert.InDelta(t, 11, n.Load(), 4)
+
+	// 优雅停止等待进行中的 Handler；Stop 的 ctx 到期时取消 Handler
+	started := make(chan struct{})
+	slow := NewPacer(NewLeakyBucketLimiter(client, "slow"), WithPacerHandler(func(ctx context.Context) {
+		close(started)
+		<-ctx.Done()
+	}))
+	assert.NoError(t, slow.Start())
+	<-started
+	sctx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, slow.Stop(sctx), context.DeadlineExceeded)
+	assert.NoError(t, slow.Stop(ctx))
+}
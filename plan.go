@@ -0,0 +1,182 @@
+package limiter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// PlanResolver 返回 key 所属的套餐（例如 "free" / "pro" / "enterprise"），
+// 返回空字符串表示未知，按 PlanLimiter.DefaultPlan 处理。
+type PlanResolver func(ctx context.Context, key string) (string, error)
+
+// RedisPlanResolver 从 Redis hash 中读取套餐：field 为 key，value 为套餐名。
+// 不存在的 key 返回空字符串。
+func RedisPlanResolver(client *redis.Client, hashKey string) PlanResolver {
+	if client == nil {
+		panic("plan: redis client is nil")
+	}
+	return func(ctx context.Context, key string) (string, error) {
+		plan, err := client.HGet(ctx, hashKey, key).Result()
+		if errors.Is(err, redis.Nil) {
+			return "", nil
+		}
+		return plan, err
+	}
+}
+
+// PlanLimiter 按 key 所属的套餐选择限流器，是 SaaS 分级限额的常见做法：
+//   - 每个套餐对应一个 RateShardedLimiter（例如由 Factory.CreateSharded 按规则创建）
+//   - key 的套餐通过 PlanResolver 查询（业务回调或 RedisPlanResolver），结果在本地缓存 CacheTTL
+//   - 查询失败、套餐为空或未配置时使用 DefaultPlan，失败通过 OnError 上报，不会放大成请求失败
+//
+// PlanLimiter 实现了 RateShardedLimiter，可直接用于 httplimit / grpclimit 中间件。
+type PlanLimiter struct {
+	plans   map[string]RateShardedLimiter
+	resolve PlanResolver
+
+	// DefaultPlan 套餐未知时使用的套餐，默认 "free"，必须存在于 plans 中
+	DefaultPlan string
+	// CacheTTL 套餐查询结果的本地缓存时长，默认 1 分钟；套餐变更后最多延迟这么久生效，或调用 Invalidate
+	CacheTTL time.Duration
+	// CacheSize 最多缓存的 key 数量，默认 10000
+	CacheSize int
+	// OnError 套餐查询失败时的回调（可选），默认忽略
+	OnError func(key string, err error)
+	// Clock 时钟，用于缓存过期判断，默认 SystemClock
+	Clock Clock
+
+	mu    sync.Mutex
+	cache map[string]planEntry
+}
+
+type planEntry struct {
+	plan    string
+	expires time.Time
+}
+
+var _ RateShardedLimiter = (*PlanLimiter)(nil)
+
+// NewPlanLimiter 创建按套餐选择限流器的 PlanLimiter，plans 为套餐名到限流器的映射。
+func NewPlanLimiter(resolve PlanResolver, plans map[string]RateShardedLimiter, opts ...PlanOption) *PlanLimiter {
+	if resolve == nil {
+		panic("plan: resolver is nil")
+	}
+	if len(plans) == 0 {
+		panic("plan: plans is empty")
+	}
+
+	p := &PlanLimiter{
+		plans:       make(map[string]RateShardedLimiter, len(plans)),
+		resolve:     resolve,
+		DefaultPlan: "free",
+		CacheTTL:    time.Minute,
+		CacheSize:   10000,
+		Clock:       SystemClock,
+		cache:       make(map[string]planEntry),
+	}
+	for name, l := range plans {
+		if l == nil {
+			panic(fmt.Sprintf("plan: limiter of plan %q is nil", name))
+		}
+		p.plans[name] = l
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	if _, ok := p.plans[p.DefaultPlan]; !ok {
+		panic(fmt.Sprintf("plan: default plan %q is not configured", p.DefaultPlan))
+	}
+	return p
+}
+
+// Plan 返回 key 当前生效的套餐，优先使用本地缓存。
+func (p *PlanLimiter) Plan(ctx context.Context, key string) string {
+	now := p.Clock.Now()
+
+	p.mu.Lock()
+	e, ok := p.cache[key]
+	p.mu.Unlock()
+	if ok && now.Before(e.expires) {
+		return e.plan
+	}
+
+	plan, err := p.resolve(ctx, key)
+	if err != nil {
+		if p.OnError != nil {
+			p.OnError(key, fmt.Errorf("plan: resolve: %w", err))
+		}
+		// 查询失败不缓存，下一次请求重新查询
+		return p.DefaultPlan
+	}
+	if _, ok := p.plans[plan]; !ok {
+		plan = p.DefaultPlan
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.cache) >= p.CacheSize {
+		for k, e := range p.cache {
+			if !now.Before(e.expires) {
+				delete(p.cache, k)
+			}
+		}
+		// 仍然没有空间时整体清空，缓存只是为了减少查询，丢弃的代价很小
+		if len(p.cache) >= p.CacheSize {
+			p.cache = make(map[string]planEntry)
+		}
+	}
+	p.cache[key] = planEntry{plan: plan, expires: now.Add(p.CacheTTL)}
+	return plan
+}
+
+// Invalidate 清除 key 的套餐缓存，套餐变更后立即生效（只影响本实例）。
+func (p *PlanLimiter) Invalidate(key string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.cache, key)
+}
+
+// Limiter 返回 key 当前套餐对应的限流器。
+func (p *PlanLimiter) Limiter(ctx context.Context, key string) RateShardedLimiter {
+	return p.plans[p.Plan(ctx, key)]
+}
+
+func (p *PlanLimiter) Allow(ctx context.Context, key string) (bool, error) {
+	return p.Limiter(ctx, key).Allow(ctx, key)
+}
+
+func (p *PlanLimiter) AllowWithResult(ctx context.Context, key string) (Result, error) {
+	return p.Limiter(ctx, key).AllowWithResult(ctx, key)
+}
+
+func (p *PlanLimiter) AllowN(ctx context.Context, key string, n int64) (bool, error) {
+	return p.Limiter(ctx, key).AllowN(ctx, key, n)
+}
+
+func (p *PlanLimiter) State(ctx context.Context, key string) (LimiterState, error) {
+	return p.Limiter(ctx, key).State(ctx, key)
+}
+
+func (p *PlanLimiter) Wait(ctx context.Context, key string, maxWait time.Duration) error {
+	return p.Limiter(ctx, key).Wait(ctx, key, maxWait)
+}
+
+func (p *PlanLimiter) Reset(ctx context.Context, key string) error {
+	return p.Limiter(ctx, key).Reset(ctx, key)
+}
+
+// ResetAll 重置所有套餐限流器的状态。
+func (p *PlanLimiter) ResetAll(ctx context.Context) error {
+	var errs []error
+	for _, l := range p.plans {
+		if err := l.ResetAll(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,43 @@
+package limiter
+
+import "time"
+
+// PlanOption 为按套餐限流的配置项。
+type PlanOption func(*PlanLimiter)
+
+// WithPlanDefault 设置套餐未知或查询失败时使用的套餐。
+func WithPlanDefault(plan string) PlanOption {
+	return func(p *PlanLimiter) {
+		if plan != "" {
+			p.DefaultPlan = plan
+		}
+	}
+}
+
+// WithPlanCache 设置套餐查询结果的本地缓存时长与最多缓存的 key 数量。
+func WithPlanCache(ttl time.Duration, size int) PlanOption {
+	return func(p *PlanLimiter) {
+		if ttl >= 0 {
+			p.CacheTTL = ttl
+		}
+		if size > 0 {
+			p.CacheSize = size
+		}
+	}
+}
+
+// WithPlanErrorHandler 设置套餐查询失败时的回调，例如记录日志。
+func WithPlanErrorHandler(fn func(key string, err error)) PlanOption {
+	return func(p *PlanLimiter) {
+		p.OnError = fn
+	}
+}
+
+// WithPlanClock 设置缓存过期判断使用的时钟，通常用于测试。
+func WithPlanClock(c Clock) PlanOption {
+	return func(p *PlanLimiter) {
+		if c != nil {
+			p.Clock = c
+		}
+	}
+}
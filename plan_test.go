@@ -0,0 +1,83 @@
+package limiter
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlanLimiter(t *testing.T) {
+	client := newSpecClient(t)
+	ctx := context.Background()
+
+	perPlan := func(limit int64) RateShardedLimiter {
+		return NewKeyedLimiter(func(key string) RateLimiter {
+			return NewFixedWindowLimiter(client, key, WithFixedWindowLimit(limit), WithFixedWindowWindow(time.Hour))
+		})
+	}
+	plans := map[string]RateShardedLimiter{"free": perPlan(1), "pro": perPlan(3)}
+
+	assert.NoError(t, client.HSet(ctx, "plans", "tenant:pro", "pro", "tenant:odd", "legacy").Err())
+	now := time.Now()
+	var errs []error
+	p := NewPlanLimiter(RedisPlanResolver(client, "plans"), plans,
+		WithPlanClock(ClockFunc(func() time.Time { return now })),
+		WithPlanErrorHandler(func(_ string, err error) { errs = append(errs, err) }))
+
+	allowed := func(key string, times int) int {
+		n := 0
+		for i := 0; i < times; i++ {
+			ok, err := p.Allow(ctx, key)
+			assert.NoError(t, err)
+			if ok {
+				n++
+			}
+		}
+		return n
+	}
+	assert.Equal(t, 3, allowed("tenant:pro", 5))
+	assert.Equal(t, 1, allowed("tenant:free", 5))
+	// 未配置的套餐按默认套餐处理
+	assert.Equal(t, "free", p.Plan(ctx, "tenant:odd"))
+
+	// 套餐变更在缓存过期（或 Invalidate）后生效
+	assert.NoError(t, client.HSet(ctx, "plans", "tenant:free", "pro").Err())
+	assert.Equal(t, "free", p.Plan(ctx, "tenant:free"))
+	now = now.Add(time.Minute)
+	assert.Equal(t, "pro", p.Plan(ctx, "tenant:free"))
+
+	assert.NoError(t, client.HSet(ctx, "plans", "tenant:free", "free").Err())
+	p.Invalidate("tenant:free")
+	assert.Equal(t, "free", p.Plan(ctx, "tenant:free"))
+	assert.NoError(t, p.ResetAll(ctx))
+	assert.Empty(t, errs)
+}
+
+func TestPlanLimiter_ResolveError(t *testing.T) {
+	client := newSpecClient(t)
+	ctx := context.Background()
+
+	calls := 0
+	var errs []error
+	p := NewPlanLimiter(func(context.Context, string) (string, error) {
+		calls++
+		return "", errors.New("billing unavailable")
+	}, map[string]RateShardedLimiter{
+		"basic": NewShardedTokenBucketLimiter(client, "basic", WithShardedTokenBucketCount(1)),
+	}, WithPlanDefault("basic"), WithPlanErrorHandler(func(_ string, err error) { errs = append(errs, err) }))
+
+	ok, err := p.Allow(ctx, "tenant:1")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "basic", p.Plan(ctx, "tenant:1"))
+	// 查询失败不缓存
+	assert.Equal(t, 2, calls)
+	assert.Len(t, errs, 2)
+
+	assert.Panics(t, func() {
+		NewPlanLimiter(RedisPlanResolver(client, "plans"), map[string]RateShardedLimiter{"pro": p})
+	})
+}
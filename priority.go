@@ -0,0 +1,129 @@
+package limiter
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Priority 请求的优先级。
+type Priority int
+
+const (
+	// PriorityLow 低优先级，例如后台任务、批量同步。
+	PriorityLow Priority = iota
+	// PriorityNormal 普通优先级。
+	PriorityNormal
+	// PriorityHigh 高优先级，例如用户交互请求。
+	PriorityHigh
+)
+
+// String 返回优先级名称。
+func (p Priority) String() string {
+	switch p {
+	case PriorityLow:
+		return "low"
+	case PriorityNormal:
+		return "normal"
+	case PriorityHigh:
+		return "high"
+	default:
+		return fmt.Sprintf("priority(%d)", int(p))
+	}
+}
+
+// PriorityLimiter 在同一个令牌桶上按优先级预留容量：
+// 低优先级请求不能动用为更高优先级预留的那部分 token，
+// 因此后台流量再大也不会把交互流量饿死。
+//
+// 预留在令牌桶 Lua 脚本内判定：优先级为 p 的请求必须在扣减后仍保留
+// 所有高于 p 的优先级的预留量之和，否则拒绝。高优先级可以使用整个桶。
+type PriorityLimiter struct {
+	tb *TokenBucketLimiter
+
+	// HighReserve 只有高优先级可以使用的容量比例，默认 0.2
+	HighReserve float64
+	// NormalReserve 高优先级与普通优先级可以使用、低优先级不能使用的容量比例，默认 0.1
+	NormalReserve float64
+}
+
+// NewPriorityLimiter 在令牌桶 tb 上创建按优先级预留容量的限流器。
+func NewPriorityLimiter(tb *TokenBucketLimiter, opts ...PriorityOption) *PriorityLimiter {
+	if tb == nil {
+		panic("priority: token bucket is nil")
+	}
+
+	p := &PriorityLimiter{
+		tb:            tb,
+		HighReserve:   0.2,
+		NormalReserve: 0.1,
+	}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	if p.HighReserve < 0 || p.NormalReserve < 0 || p.HighReserve+p.NormalReserve >= 1 {
+		panic("priority: reserves must >= 0 and sum < 1")
+	}
+	return p
+}
+
+// usable 返回优先级 pr 可以使用的容量比例。
+func (p *PriorityLimiter) usable(pr Priority) (float64, error) {
+	switch pr {
+	case PriorityHigh:
+		return 1, nil
+	case PriorityNormal:
+		return 1 - p.HighReserve, nil
+	case PriorityLow:
+		return 1 - p.HighReserve - p.NormalReserve, nil
+	default:
+		return 0, fmt.Errorf("priority: unknown priority %d", int(pr))
+	}
+}
+
+// Allow 以优先级 pr 尝试获取 1 个 token。
+func (p *PriorityLimiter) Allow(ctx context.Context, pr Priority) (bool, error) {
+	return p.AllowN(ctx, pr, 1)
+}
+
+// AllowN 以优先级 pr 尝试一次获取 n 个 token。
+func (p *PriorityLimiter) AllowN(ctx context.Context, pr Priority, n int64) (bool, error) {
+	res, err := p.AllowNWithResult(ctx, pr, n)
+	if err != nil {
+		return false, err
+	}
+	return res.Allowed, nil
+}
+
+// AllowNWithResult 以优先级 pr 尝试一次获取 n 个 token，并返回剩余 token 数及重试等待时间。
+// Remaining 为桶内剩余的全部 token 数（包含为更高优先级预留的部分）；
+// RetryAfter 为补足到该优先级可用所需的等待时间。
+func (p *PriorityLimiter) AllowNWithResult(ctx context.Context, pr Priority, n int64) (Result, error) {
+	if n <= 0 {
+		return Result{}, fmt.Errorf("priority: n must > 0")
+	}
+	ratio, err := p.usable(pr)
+	if err != nil {
+		return Result{}, err
+	}
+	return p.tb.lendN(ctx, n, ratio)
+}
+
+// Wait 以优先级 pr 阻塞直到获取 1 个 token，或 ctx 取消 / 超过 maxWait。
+func (p *PriorityLimiter) Wait(ctx context.Context, pr Priority, maxWait time.Duration) error {
+	return waitForWake(ctx, p.tb.Key, "priority", maxWait, waitStrategy(p.tb.WaitStrategy, p.tb.WaitJitter), p.tb.Wakeup, func(ctx context.Context) (Result, error) {
+		return p.AllowNWithResult(ctx, pr, 1)
+	})
+}
+
+// State 返回底层令牌桶的状态。
+func (p *PriorityLimiter) State(ctx context.Context) (LimiterState, error) {
+	return p.tb.State(ctx)
+}
+
+// Reset 重置底层令牌桶。
+func (p *PriorityLimiter) Reset(ctx context.Context) error {
+	return p.tb.Reset(ctx)
+}
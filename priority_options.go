@@ -0,0 +1,19 @@
+package limiter
+
+// PriorityOption 为优先级限流器的配置项。
+type PriorityOption func(*PriorityLimiter)
+
+// WithPriorityReserve 设置为 pr 及更高优先级预留的容量比例（0~1）。
+// 只能为 PriorityHigh / PriorityNormal 设置预留，低优先级没有预留。
+func WithPriorityReserve(pr Priority, fraction float64) PriorityOption {
+	return func(p *PriorityLimiter) {
+		switch pr {
+		case PriorityHigh:
+			p.HighReserve = fraction
+		case PriorityNormal:
+			p.NormalReserve = fraction
+		default:
+			panic("priority: only high and normal priority can reserve capacity")
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package limiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPriorityLimiter(t *testing.T) {
+	client := newSpecClient(t)
+	ctx := context.Background()
+
+	tb := NewTokenBucketLimiter(client, "prio", WithTokenBucketRate(0.001), WithTokenBucketCapacity(10))
+	p := NewPriorityLimiter(tb)
+
+	// 低优先级最多用到 7 个，为普通 / 高优先级保留 3 个
+	ok, err := p.AllowN(ctx, PriorityLow, 7)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	res, err := p.AllowNWithResult(ctx, PriorityLow, 1)
+	assert.NoError(t, err)
+	assert.False(t, res.Allowed)
+	assert.Equal(t, float64(3), res.Remaining)
+	assert.Greater(t, res.RetryAfter, time.Duration(0))
+
+	// 普通优先级还能用 1 个，为高优先级保留 2 个
+	ok, err = p.Allow(ctx, PriorityNormal)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = p.Allow(ctx, PriorityNormal)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	// 高优先级可以用完整个桶
+	ok, err = p.AllowN(ctx, PriorityHigh, 2)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = p.Allow(ctx, PriorityHigh)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	_, err = p.AllowN(ctx, Priority(9), 1)
+	assert.Error(t, err)
+	_, err = p.AllowN(ctx, PriorityHigh, 0)
+	assert.Error(t, err)
+
+	assert.Panics(t, func() {
+		NewPriorityLimiter(tb, WithPriorityReserve(PriorityHigh, 0.6), WithPriorityReserve(PriorityNormal, 0.4))
+	})
+	assert.Panics(t, func() { WithPriorityReserve(PriorityLow, 0.1)(p) })
+}
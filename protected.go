@@ -0,0 +1,261 @@
+package limiter
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen 表示熔断器处于打开状态，本次调用没有访问 Redis（FailError 策略）。
+var ErrCircuitOpen = errors.New("limiter: circuit breaker is open")
+
+// FailPolicy 是熔断期间（不访问 Redis 时）的判定策略。
+type FailPolicy int
+
+const (
+	// FailOpen 熔断期间全部放行（默认），优先保证业务可用。
+	FailOpen FailPolicy = iota
+	// FailClosed 熔断期间全部拒绝，RetryAfter 为距离下一次探测的时间。
+	FailClosed
+	// FailError 熔断期间返回 ErrCircuitOpen，由调用方自行处理。
+	FailError
+)
+
+// CircuitState 是熔断器的状态。
+type CircuitState int
+
+const (
+	// CircuitClosed 正常访问 Redis。
+	CircuitClosed CircuitState = iota
+	// CircuitOpen 熔断中，不访问 Redis，按 FailPolicy 判定。
+	CircuitOpen
+	// CircuitHalfOpen 熔断时长已过，放一个探测请求访问 Redis，成功足够次数后恢复。
+	CircuitHalfOpen
+)
+
+// String 返回状态名。
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+// ProtectedLimiter 在限流器的 Redis 调用外层加上熔断器，避免 Redis 变慢时拖慢每一次 Allow：
+//   - 连续 FailureThreshold 次调用出错或耗时超过 SlowThreshold 后熔断（打开）
+//   - 熔断期间不访问 Redis，按 Policy 在本地直接判定
+//   - 熔断 OpenDuration 后进入半开状态，同一时刻只放一个探测请求访问 Redis，
+//     连续 HalfOpenSuccesses 次成功后恢复，探测失败则重新熔断
+//
+// 与 FallbackLimiter 的区别：FallbackLimiter 在故障期间用本地令牌桶近似限流，
+// ProtectedLimiter 只负责快速失败，两者可以组合使用。
+type ProtectedLimiter struct {
+	limiter RateLimiter
+
+	// Policy 熔断期间的判定策略，默认 FailOpen
+	Policy FailPolicy
+	// FailureThreshold 连续失败（出错或慢调用）多少次后熔断，默认 5
+	FailureThreshold int
+	// SlowThreshold 单次调用耗时超过该值视为失败，0 表示不按耗时判断，默认 100ms
+	SlowThreshold time.Duration
+	// OpenDuration 熔断后多久进入半开状态，默认 5s
+	OpenDuration time.Duration
+	// HalfOpenSuccesses 半开状态下连续成功多少次后恢复，默认 1
+	HalfOpenSuccesses int
+	// OnStateChange 熔断器状态变化时的回调（可选），err 为触发熔断的最后一次错误（慢调用时为 nil）
+	OnStateChange func(from, to CircuitState, err error)
+
+	// Clock 时钟，用于熔断时长与耗时统计，默认 SystemClock
+	Clock Clock
+
+	mu        sync.Mutex
+	state     CircuitState
+	failures  int
+	successes int
+	openUntil time.Time
+	probing   bool
+}
+
+var _ RateLimiter = (*ProtectedLimiter)(nil)
+
+// NewProtectedLimiter 为 l 的 Redis 调用加上熔断器。
+func NewProtectedLimiter(l RateLimiter, opts ...ProtectedOption) *ProtectedLimiter {
+	if l == nil {
+		panic("protected: limiter is nil")
+	}
+
+	p := &ProtectedLimiter{
+		limiter:           l,
+		FailureThreshold:  5,
+		SlowThreshold:     100 * time.Millisecond,
+		OpenDuration:      5 * time.Second,
+		HalfOpenSuccesses: 1,
+		Clock:             SystemClock,
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// CircuitState 返回熔断器当前的状态。
+func (p *ProtectedLimiter) CircuitState() CircuitState {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.state == CircuitOpen && !p.Clock.Now().Before(p.openUntil) {
+		return CircuitHalfOpen
+	}
+	return p.state
+}
+
+func (p *ProtectedLimiter) Allow(ctx context.Context) (bool, error) {
+	res, err := p.AllowWithResult(ctx)
+	return res.Allowed, err
+}
+
+func (p *ProtectedLimiter) AllowWithResult(ctx context.Context) (Result, error) {
+	return p.call(ctx, func(ctx context.Context) (Result, error) {
+		return p.limiter.AllowWithResult(ctx)
+	})
+}
+
+func (p *ProtectedLimiter) AllowN(ctx context.Context, n int64) (bool, error) {
+	res, err := p.call(ctx, func(ctx context.Context) (Result, error) {
+		ok, err := p.limiter.AllowN(ctx, n)
+		return Result{Allowed: ok}, err
+	})
+	return res.Allowed, err
+}
+
+// Wait 每次重试都经过熔断器：熔断期间 FailOpen 立即返回，FailClosed 等到下一次探测，FailError 返回 ErrCircuitOpen。
+func (p *ProtectedLimiter) Wait(ctx context.Context, maxWait time.Duration) error {
+	return waitFor(ctx, "", "protected", maxWait, nil, p.AllowWithResult)
+}
+
+// State 熔断期间返回 ErrCircuitOpen，不访问 Redis。
+func (p *ProtectedLimiter) State(ctx context.Context) (LimiterState, error) {
+	var st LimiterState
+	_, err := p.guard(ctx, func(ctx context.Context) error {
+		var err error
+		st, err = p.limiter.State(ctx)
+		return err
+	})
+	return st, err
+}
+
+// Reset 总是访问 Redis，不受熔断器影响（通常由运维手动调用）。
+func (p *ProtectedLimiter) Reset(ctx context.Context) error {
+	return p.limiter.Reset(ctx)
+}
+
+// call 经过熔断器执行一次判定，熔断期间按 Policy 在本地判定。
+func (p *ProtectedLimiter) call(ctx context.Context, fn func(ctx context.Context) (Result, error)) (Result, error) {
+	var res Result
+	wait, err := p.guard(ctx, func(ctx context.Context) error {
+		var err error
+		res, err = fn(ctx)
+		return err
+	})
+	if !errors.Is(err, ErrCircuitOpen) || wait < 0 {
+		return res, err
+	}
+
+	switch p.Policy {
+	case FailClosed:
+		return Result{RetryAfter: wait}, nil
+	case FailError:
+		return Result{}, err
+	}
+	return Result{Allowed: true}, nil
+}
+
+// guard 在熔断器允许时执行 fn 并记录结果；不允许时返回 ErrCircuitOpen 与距离下一次探测的时间。
+// fn 被执行时返回的等待时间为 -1。
+func (p *ProtectedLimiter) guard(ctx context.Context, fn func(ctx context.Context) error) (time.Duration, error) {
+	wait, ok := p.acquire()
+	if !ok {
+		return wait, ErrCircuitOpen
+	}
+
+	start := p.Clock.Now()
+	err := fn(ctx)
+	slow := p.SlowThreshold > 0 && p.Clock.Now().Sub(start) > p.SlowThreshold
+
+	// ctx 被取消不代表 Redis 故障，不计入失败
+	canceled := err != nil && ctx.Err() != nil
+	p.record(err == nil && !slow, canceled, err)
+	return -1, err
+}
+
+// acquire 判断本次调用能否访问 Redis，半开状态下同一时刻只放行一个探测请求。
+func (p *ProtectedLimiter) acquire() (time.Duration, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	switch p.state {
+	case CircuitClosed:
+		return 0, true
+	case CircuitOpen:
+		now := p.Clock.Now()
+		if now.Before(p.openUntil) {
+			return p.openUntil.Sub(now), false
+		}
+		p.setState(CircuitHalfOpen, nil)
+	}
+	if p.probing {
+		// 探测请求最多 SlowThreshold 就能判定成败，不必再等一个完整的熔断时长
+		if p.SlowThreshold > 0 {
+			return p.SlowThreshold, false
+		}
+		return p.OpenDuration, false
+	}
+	p.probing = true
+	return 0, true
+}
+
+// record 记录一次调用的结果并推进状态。
+func (p *ProtectedLimiter) record(ok, canceled bool, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	halfOpen := p.state == CircuitHalfOpen
+	if halfOpen {
+		p.probing = false
+	}
+	if canceled {
+		return
+	}
+
+	if ok {
+		p.failures = 0
+		if halfOpen {
+			p.successes++
+			if p.successes >= p.HalfOpenSuccesses {
+				p.setState(CircuitClosed, nil)
+			}
+		}
+		return
+	}
+
+	p.failures++
+	if halfOpen || p.failures >= p.FailureThreshold {
+		p.openUntil = p.Clock.Now().Add(p.OpenDuration)
+		p.setState(CircuitOpen, err)
+	}
+}
+
+// setState 切换状态并触发回调，调用方需持有锁。
+func (p *ProtectedLimiter) setState(to CircuitState, err error) {
+	from := p.state
+	p.state = to
+	p.failures, p.successes = 0, 0
+	if from != to && p.OnStateChange != nil {
+		p.OnStateChange(from, to, err)
+	}
+}
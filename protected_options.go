@@ -0,0 +1,65 @@
+package limiter
+
+import "time"
+
+// ProtectedOption 为熔断保护限流器的配置项。
+type ProtectedOption func(*ProtectedLimiter)
+
+// WithProtectedPolicy 设置熔断期间的判定策略：FailOpen（默认）、FailClosed 或 FailError。
+func WithProtectedPolicy(policy FailPolicy) ProtectedOption {
+	return func(p *ProtectedLimiter) {
+		p.Policy = policy
+	}
+}
+
+// WithProtectedFailureThreshold 设置连续失败多少次后熔断。
+func WithProtectedFailureThreshold(n int) ProtectedOption {
+	return func(p *ProtectedLimiter) {
+		if n > 0 {
+			p.FailureThreshold = n
+		}
+	}
+}
+
+// WithProtectedSlowThreshold 设置慢调用阈值，单次调用耗时超过该值视为失败；0 表示不按耗时判断。
+func WithProtectedSlowThreshold(d time.Duration) ProtectedOption {
+	return func(p *ProtectedLimiter) {
+		if d >= 0 {
+			p.SlowThreshold = d
+		}
+	}
+}
+
+// WithProtectedOpenDuration 设置熔断后多久进入半开状态。
+func WithProtectedOpenDuration(d time.Duration) ProtectedOption {
+	return func(p *ProtectedLimiter) {
+		if d > 0 {
+			p.OpenDuration = d
+		}
+	}
+}
+
+// WithProtectedHalfOpenSuccesses 设置半开状态下连续成功多少次后恢复。
+func WithProtectedHalfOpenSuccesses(n int) ProtectedOption {
+	return func(p *ProtectedLimiter) {
+		if n > 0 {
+			p.HalfOpenSuccesses = n
+		}
+	}
+}
+
+// WithProtectedOnStateChange 设置熔断器状态变化时的回调，例如告警。
+func WithProtectedOnStateChange(fn func(from, to CircuitState, err error)) ProtectedOption {
+	return func(p *ProtectedLimiter) {
+		p.OnStateChange = fn
+	}
+}
+
+// WithProtectedClock 设置时钟，通常用于测试中控制熔断时长与耗时。
+func WithProtectedClock(c Clock) ProtectedOption {
+	return func(p *ProtectedLimiter) {
+		if c != nil {
+			p.Clock = c
+		}
+	}
+}
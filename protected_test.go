@@ -0,0 +1,110 @@
+package limiter
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// slowLimiter 每次调用把时钟拨快 delay，模拟 Redis 变慢。
+type slowLimiter struct {
+	flakyLimiter
+	now   *time.Time
+	delay time.Duration
+}
+
+func (s *slowLimiter) AllowWithResult(ctx context.Context) (Result, error) {
+	*s.now = s.now.Add(s.delay)
+	return s.flakyLimiter.AllowWithResult(ctx)
+}
+
+func TestProtectedLimiter(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1000, 0)
+	remote := &flakyLimiter{down: true}
+
+	var changes []string
+	p := NewProtectedLimiter(remote,
+		WithProtectedFailureThreshold(2),
+		WithProtectedOpenDuration(time.Second),
+		WithProtectedHalfOpenSuccesses(2),
+		WithProtectedOnStateChange(func(from, to CircuitState, err error) {
+			changes = append(changes, from.String()+"->"+to.String())
+		}),
+		WithProtectedClock(ClockFunc(func() time.Time { return now })),
+	)
+
+	// 熔断前错误原样返回
+	for i := 0; i < 2; i++ {
+		_, err := p.Allow(ctx)
+		assert.Error(t, err)
+	}
+	assert.Equal(t, CircuitOpen, p.CircuitState())
+
+	// 熔断期间不访问 Redis，默认放行
+	ok, err := p.Allow(ctx)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, 2, remote.calls)
+	_, err = p.State(ctx)
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+
+	// 半开探测失败，重新熔断
+	now = now.Add(time.Second)
+	assert.Equal(t, CircuitHalfOpen, p.CircuitState())
+	_, err = p.Allow(ctx)
+	assert.Error(t, err)
+	assert.Equal(t, 3, remote.calls)
+	assert.Equal(t, CircuitOpen, p.CircuitState())
+
+	// Redis 恢复后连续两次探测成功才关闭
+	remote.down = false
+	now = now.Add(time.Second)
+	for i := 0; i < 2; i++ {
+		ok, err = p.Allow(ctx)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+	}
+	assert.Equal(t, CircuitClosed, p.CircuitState())
+	assert.Equal(t, []string{
+		"closed->open", "open->half-open", "half-open->open", "open->half-open", "half-open->closed",
+	}, changes)
+}
+
+func TestProtectedLimiter_Policy(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1000, 0)
+	clock := ClockFunc(func() time.Time { return now })
+
+	// 慢调用同样计入失败
+	remote := &slowLimiter{now: &now, delay: 200 * time.Millisecond}
+	closed := NewProtectedLimiter(remote, WithProtectedPolicy(FailClosed),
+		WithProtectedFailureThreshold(1), WithProtectedOpenDuration(time.Second), WithProtectedClock(clock))
+	ok, err := closed.Allow(ctx)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, CircuitOpen, closed.CircuitState())
+
+	res, err := closed.AllowWithResult(ctx)
+	assert.NoError(t, err)
+	assert.False(t, res.Allowed)
+	assert.Equal(t, time.Second, res.RetryAfter)
+	assert.ErrorIs(t, closed.Wait(ctx, 0), ErrLimiter)
+
+	failErr := NewProtectedLimiter(&flakyLimiter{down: true}, WithProtectedPolicy(FailError),
+		WithProtectedFailureThreshold(1), WithProtectedClock(clock))
+	_, err = failErr.Allow(ctx)
+	assert.False(t, errors.Is(err, ErrCircuitOpen))
+	_, err = failErr.Allow(ctx)
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+
+	// ctx 取消不计入失败
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	p := NewProtectedLimiter(&flakyLimiter{down: true}, WithProtectedFailureThreshold(1), WithProtectedClock(clock))
+	_, _ = p.Allow(canceled)
+	assert.Equal(t, CircuitClosed, p.CircuitState())
+}
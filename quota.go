@@ -0,0 +1,351 @@
+package limiter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// ErrQuotaInsufficient 表示 Revoke / Transfer 要扣减的额度超过了当前周期的剩余额度，此时不做任何修改。
+var ErrQuotaInsufficient = errors.New("quota: insufficient remaining quota")
+
+// QuotaPeriod 配额的自然周期。
+type QuotaPeriod int
+
+const (
+	// QuotaDaily 自然日，从当地 0 点开始。
+	QuotaDaily QuotaPeriod = iota
+	// QuotaWeekly 自然周，从当地周一 0 点开始。
+	QuotaWeekly
+	// QuotaMonthly 自然月，从当地 1 日 0 点开始。
+	QuotaMonthly
+)
+
+// String 返回周期名称。
+func (p QuotaPeriod) String() string {
+	switch p {
+	case QuotaDaily:
+		return "daily"
+	case QuotaWeekly:
+		return "weekly"
+	case QuotaMonthly:
+		return "monthly"
+	default:
+		return fmt.Sprintf("quota_period(%d)", int(p))
+	}
+}
+
+// bounds 返回 t 所在周期在 loc 时区下的起止时间 [start, end)。
+func (p QuotaPeriod) bounds(t time.Time, loc *time.Location) (time.Time, time.Time) {
+	t = t.In(loc)
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+	switch p {
+	case QuotaWeekly:
+		start := day.AddDate(0, 0, -((int(t.Weekday()) + 6) % 7))
+		return start, start.AddDate(0, 0, 7)
+	case QuotaMonthly:
+		start := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, loc)
+		return start, start.AddDate(0, 1, 0)
+	default:
+		return day, day.AddDate(0, 0, 1)
+	}
+}
+
+// QuotaLimiter 实现按自然周期对齐的配额，例如“每个自然日（Asia/Shanghai）10000 次调用”。
+// 与固定窗口不同，周期不从第一次计数开始，而是对齐到日历上的天 / 周 / 月，
+// 所有实例在同一时刻切换到新周期。
+//
+// 每个周期使用一个独立的 hash key（"quota:{key}:20260102"，后缀为周期开始日期），
+// 周期结束后再保留 Retention 时长，便于对账。
+// 周期按 Clock 的时间计算，各实例之间的时钟偏差会体现为周期切换时刻的偏差。
+type QuotaLimiter struct {
+	client *redis.Client
+
+	Key    string      // 业务 key
+	Prefix string      // Redis key 前缀，默认 "quota"
+	Limit  int64       // 每个周期的配额
+	Period QuotaPeriod // 周期，默认 QuotaDaily
+
+	// Location 周期对齐使用的时区，默认 time.UTC，避免不同时区的实例算出不同的周期
+	Location *time.Location
+
+	// Retention 周期结束后计数 key 的保留时长，默认 24h。
+	// 开启结转时上个周期的 key 需要保留到本周期第一次计数，Retention 不宜过短。
+	Retention time.Duration
+
+	// RolloverMax 每个周期最多从上个周期结转的未用额度，0 表示不结转（默认）。
+	// 结转额度在本周期第一次计数时由脚本原子计算并固定下来，只结转一个周期，不会无限累积超过该值。
+	RolloverMax int64
+
+	// WaitJitter Wait 重试前的随机抖动比例（0~1）
+	WaitJitter float64
+	// WaitStrategy Wait 被拒绝后的等待策略（可选），默认按 RetryAfter 等待并叠加 WaitJitter，见 WaitStrategy。
+	WaitStrategy WaitStrategy
+
+	// Clock 时钟，默认 SystemClock。
+	Clock Clock
+
+	// Hooks 判定事件钩子（可选），每次放行 / 拒绝 / 出错时回调，见 Hooks。
+	Hooks Hooks
+}
+
+// NewQuotaLimiter 创建一个按自然周期对齐的配额限流器。
+func NewQuotaLimiter(
+	client *redis.Client,
+	key string,
+	opts ...QuotaOption,
+) *QuotaLimiter {
+
+	if client == nil {
+		panic("quota: redis client is nil")
+	}
+	if key == "" {
+		panic("quota: key is empty")
+	}
+
+	l := &QuotaLimiter{
+		client:    client,
+		Key:       key,
+		Prefix:    "quota",
+		Limit:     10000,
+		Period:    QuotaDaily,
+		Location:  time.UTC,
+		Retention: 24 * time.Hour,
+		Clock:     SystemClock,
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// period 返回当前周期的计数 key 及起止时间。
+func (l *QuotaLimiter) period() (string, time.Time, time.Time) {
+	start, end := l.Period.bounds(l.Clock.Now(), l.Location)
+	return l.periodKey(start), start, end
+}
+
+// periodKey 返回以 start 开始的周期的计数 key。
+func (l *QuotaLimiter) periodKey(start time.Time) string {
+	return l.periodKeyOf(l.Key, start)
+}
+
+// periodKeyOf 返回业务 key 以 start 开始的周期的计数 key。
+func (l *QuotaLimiter) periodKeyOf(key string, start time.Time) string {
+	return fmt.Sprintf("%s:{%s}:%s", l.Prefix, key, start.Format("20060102"))
+}
+
+// Allow 尝试占用 1 个配额。
+func (l *QuotaLimiter) Allow(ctx context.Context) (bool, error) {
+	return l.AllowN(ctx, 1)
+}
+
+// AllowWithResult 尝试占用 1 个配额，并返回剩余配额及距离周期结束的时间。
+func (l *QuotaLimiter) AllowWithResult(ctx context.Context) (Result, error) {
+	return l.AllowNWithResult(ctx, 1)
+}
+
+// AllowN 尝试一次占用 n 个配额，不足时整体拒绝。
+func (l *QuotaLimiter) AllowN(ctx context.Context, n int64) (bool, error) {
+	res, err := l.AllowNWithResult(ctx, n)
+	if err != nil {
+		return false, err
+	}
+	return res.Allowed, nil
+}
+
+// AllowNWithResult 尝试一次占用 n 个配额。
+// 被拒绝时 RetryAfter 为距离当前周期结束的时间。
+func (l *QuotaLimiter) AllowNWithResult(ctx context.Context, n int64) (Result, error) {
+	res, err := l.eval(ctx, n, false)
+	fireHooks(ctx, l.Hooks, "quota", l.Key, n, res, err)
+	return res, err
+}
+
+// Check 判断当前周期是否还能占用 1 个配额，但不计数。
+func (l *QuotaLimiter) Check(ctx context.Context) (Result, error) {
+	return l.CheckN(ctx, 1)
+}
+
+// CheckN 判断当前周期是否还能一次占用 n 个配额，但不计数。
+// 放行时 Remaining 为当前剩余配额（未扣除 n）。
+func (l *QuotaLimiter) CheckN(ctx context.Context, n int64) (Result, error) {
+	return l.eval(ctx, n, true)
+}
+
+// eval 校验 n 后执行配额脚本；dryRun 为 true 时只判定不计数。
+func (l *QuotaLimiter) eval(ctx context.Context, n int64, dryRun bool) (Result, error) {
+	if n <= 0 {
+		return Result{}, fmt.Errorf("quota: n must > 0")
+	}
+	res, _, err := l.run(ctx, n, dryRun)
+	return res, err
+}
+
+// run 执行配额脚本，额外返回本周期的已用量。n 为 0 时只查询。
+func (l *QuotaLimiter) run(ctx context.Context, n int64, dryRun bool) (Result, int64, error) {
+	key, start, end := l.period()
+	keys := []string{key}
+	args := []interface{}{l.Limit, n, end.Add(l.Retention).UnixMilli()}
+	if dryRun {
+		args = append(args, 1)
+	}
+	if l.RolloverMax > 0 {
+		prev, _ := l.Period.bounds(start.Add(-time.Nanosecond), l.Location)
+		keys = append(keys, l.periodKey(prev))
+		if !dryRun {
+			args = append(args, 0)
+		}
+		args = append(args, l.RolloverMax)
+	}
+
+	res, err := quotaScript.Run(ctx, l.client, keys, args...).Result()
+	if err != nil {
+		return Result{}, 0, err
+	}
+
+	vals, ok := scriptInts(res, 4)
+	if !ok {
+		return Result{}, 0, fmt.Errorf("quota: unexpected script result: %#v", res)
+	}
+
+	r := Result{
+		Allowed:   vals[0] == 1,
+		Limit:     float64(vals[2]),
+		Remaining: float64(vals[1]),
+	}
+	if !r.Allowed {
+		r.RetryAfter = max(end.Sub(l.Clock.Now()), 0)
+	}
+	return r, vals[3], nil
+}
+
+// Wait 阻塞直到获得 1 个配额，或 ctx 取消 / 超过 maxWait。
+// 配额用尽时需要等到下一个周期，通常应直接拒绝而不是等待。
+func (l *QuotaLimiter) Wait(ctx context.Context, maxWait time.Duration) error {
+	return waitFor(ctx, l.Key, "quota", maxWait, waitStrategy(l.WaitStrategy, l.WaitJitter), l.AllowWithResult)
+}
+
+// Remaining 返回当前周期剩余的配额（包含追加与结转的额度）。
+func (l *QuotaLimiter) Remaining(ctx context.Context) (int64, error) {
+	res, _, err := l.run(ctx, 0, true)
+	if err != nil {
+		return 0, err
+	}
+	return int64(res.Remaining), nil
+}
+
+// ResetAt 返回当前周期结束（配额重置）的时间，时区为 Location。
+func (l *QuotaLimiter) ResetAt() time.Time {
+	_, _, end := l.period()
+	return end
+}
+
+// TopUp 为当前周期追加 n 个配额，只在本周期内有效。
+func (l *QuotaLimiter) TopUp(ctx context.Context, n int64) error {
+	if n <= 0 {
+		return fmt.Errorf("quota: n must > 0")
+	}
+
+	key, _, end := l.period()
+	_, err := l.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HIncrBy(ctx, key, "bonus", n)
+		pipe.PExpireAt(ctx, key, end.Add(l.Retention))
+		return nil
+	})
+	return err
+}
+
+// Grant 为 key 的当前周期追加 amount 个额度（例如客户补偿），只在本周期内有效，返回追加后的剩余额度。
+// key 与本限流器共用 Prefix / Limit / Period / Location 配置，可以是本限流器之外的业务 key。
+func (l *QuotaLimiter) Grant(ctx context.Context, key string, amount int64) (int64, error) {
+	if amount <= 0 {
+		return 0, fmt.Errorf("quota: amount must > 0")
+	}
+	return l.adjust(ctx, key, "", amount)
+}
+
+// Revoke 从 key 的当前周期收回 amount 个额度（例如周期中途降级套餐），返回收回后的剩余额度。
+// 剩余额度不足 amount 时返回 ErrQuotaInsufficient，不做任何修改。
+func (l *QuotaLimiter) Revoke(ctx context.Context, key string, amount int64) (int64, error) {
+	if amount <= 0 {
+		return 0, fmt.Errorf("quota: amount must > 0")
+	}
+	return l.adjust(ctx, key, "", -amount)
+}
+
+// Transfer 把 from 当前周期的 amount 个剩余额度原子地转给 to，返回 from 转出后的剩余额度。
+// from 的剩余额度不足 amount 时返回 ErrQuotaInsufficient，两边都不做修改。
+// 两个 key 在同一个脚本中修改，Redis Cluster 下它们不在同一个 slot 时返回 CROSSSLOT 错误。
+func (l *QuotaLimiter) Transfer(ctx context.Context, from, to string, amount int64) (int64, error) {
+	if amount <= 0 {
+		return 0, fmt.Errorf("quota: amount must > 0")
+	}
+	if from == to {
+		return 0, fmt.Errorf("quota: transfer to the same key")
+	}
+	return l.adjust(ctx, from, to, -amount)
+}
+
+// adjust 执行额度调整脚本：key 的额度变化 delta，to 非空时 to 的额度反向变化。
+func (l *QuotaLimiter) adjust(ctx context.Context, key, to string, delta int64) (int64, error) {
+	if key == "" {
+		return 0, fmt.Errorf("quota: key is empty")
+	}
+
+	start, end := l.Period.bounds(l.Clock.Now(), l.Location)
+	keys := []string{l.periodKeyOf(key, start)}
+	if to != "" {
+		keys = append(keys, l.periodKeyOf(to, start))
+	}
+
+	res, err := quotaAdjustScript.Run(ctx, l.client, keys, l.Limit, delta, end.Add(l.Retention).UnixMilli()).Result()
+	if err != nil {
+		return 0, err
+	}
+	vals, ok := scriptInts(res, 2)
+	if !ok {
+		return 0, fmt.Errorf("quota: unexpected script result: %#v", res)
+	}
+	if vals[0] != 1 {
+		return vals[1], ErrQuotaInsufficient
+	}
+	return vals[1], nil
+}
+
+// Reset 删除当前周期的计数与追加额度，本周期的配额回到 Limit。
+func (l *QuotaLimiter) Reset(ctx context.Context) error {
+	key, _, _ := l.period()
+	return l.client.Del(ctx, key).Err()
+}
+
+// State 返回当前周期的用量。
+// Capacity 为本周期的总额度（Limit + 追加与结转的额度），NextAvailableTime 在配额用尽时为周期结束时间。
+func (l *QuotaLimiter) State(ctx context.Context) (LimiterState, error) {
+	res, used, err := l.run(ctx, 0, true)
+	if err != nil {
+		return LimiterState{}, err
+	}
+
+	now := l.Clock.Now()
+	start, end := l.Period.bounds(now, l.Location)
+	next := now.UnixMilli()
+	if res.Remaining <= 0 {
+		next = end.UnixMilli()
+	}
+
+	return LimiterState{
+		Level:             float64(used),
+		Remaining:         res.Remaining,
+		Capacity:          res.Limit,
+		Rate:              float64(l.Limit) / end.Sub(start).Seconds(),
+		LastUpdated:       now.UnixMilli(),
+		NextAvailableTime: next,
+		Type:              "quota",
+		Key:               l.Key,
+		Window:            end.Sub(start),
+	}, nil
+}
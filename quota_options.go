@@ -0,0 +1,96 @@
+package limiter
+
+import "time"
+
+// QuotaOption 为配额限流器的配置项。
+type QuotaOption func(*QuotaLimiter)
+
+// WithQuotaLimit 设置每个周期的配额。
+func WithQuotaLimit(limit int64) QuotaOption {
+	return func(l *QuotaLimiter) {
+		if limit > 0 {
+			l.Limit = limit
+		}
+	}
+}
+
+// WithQuotaPeriod 设置周期，参见 QuotaDaily / QuotaWeekly / QuotaMonthly。
+func WithQuotaPeriod(p QuotaPeriod) QuotaOption {
+	return func(l *QuotaLimiter) {
+		if p < QuotaDaily || p > QuotaMonthly {
+			panic("quota: unknown period")
+		}
+		l.Period = p
+	}
+}
+
+// WithQuotaLocation 设置周期对齐使用的时区，例如 time.LoadLocation("Asia/Shanghai")。
+func WithQuotaLocation(loc *time.Location) QuotaOption {
+	return func(l *QuotaLimiter) {
+		if loc != nil {
+			l.Location = loc
+		}
+	}
+}
+
+// WithQuotaRetention 设置周期结束后计数 key 的保留时长。
+func WithQuotaRetention(d time.Duration) QuotaOption {
+	return func(l *QuotaLimiter) {
+		if d >= 0 {
+			l.Retention = d
+		}
+	}
+}
+
+// WithQuotaPrefix 设置 Redis key 的前缀。
+func WithQuotaPrefix(prefix string) QuotaOption {
+	return func(l *QuotaLimiter) {
+		if prefix != "" {
+			l.Prefix = prefix
+		}
+	}
+}
+
+// WithQuotaWaitJitter 设置 Wait 重试前的随机抖动比例，取值范围 [0, 1]。
+func WithQuotaWaitJitter(ratio float64) QuotaOption {
+	return func(l *QuotaLimiter) {
+		if ratio < 0 || ratio > 1 {
+			panic("quota: wait jitter must be in [0, 1]")
+		}
+		l.WaitJitter = ratio
+	}
+}
+
+// WithQuotaWaitStrategy 设置 Wait 被拒绝后的等待策略，例如 ExponentialWait、NewPacedWait。
+// 设置后 WaitJitter 不再生效，抖动由策略自身负责。
+func WithQuotaWaitStrategy(s WaitStrategy) QuotaOption {
+	return func(l *QuotaLimiter) {
+		l.WaitStrategy = s
+	}
+}
+
+// WithQuotaClock 注入自定义时钟，周期按该时钟的时间计算。
+func WithQuotaClock(c Clock) QuotaOption {
+	return func(l *QuotaLimiter) {
+		if c != nil {
+			l.Clock = c
+		}
+	}
+}
+
+// WithQuotaRollover 开启未用额度结转：每个周期最多把上个周期未用完的 n 个配额带入本周期。
+func WithQuotaRollover(n int64) QuotaOption {
+	return func(l *QuotaLimiter) {
+		if n < 0 {
+			panic("quota: rollover must >= 0")
+		}
+		l.RolloverMax = n
+	}
+}
+
+// WithQuotaHooks 设置判定事件钩子，用于接入日志、指标或告警。
+func WithQuotaHooks(h Hooks) QuotaOption {
+	return func(l *QuotaLimiter) {
+		l.Hooks = h
+	}
+}
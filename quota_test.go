@@ -0,0 +1,199 @@
+package limiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuotaPeriod_Bounds(t *testing.T) {
+	loc := time.FixedZone("CST", 8*3600)
+	// 2026-01-07 是周三；UTC 16:30 已是东八区的 01-08 00:30
+	now := time.Date(2026, 1, 7, 16, 30, 0, 0, time.UTC)
+
+	cases := []struct {
+		period     QuotaPeriod
+		start, end time.Time
+	}{
+		{QuotaDaily, time.Date(2026, 1, 8, 0, 0, 0, 0, loc), time.Date(2026, 1, 9, 0, 0, 0, 0, loc)},
+		{QuotaWeekly, time.Date(2026, 1, 5, 0, 0, 0, 0, loc), time.Date(2026, 1, 12, 0, 0, 0, 0, loc)},
+		{QuotaMonthly, time.Date(2026, 1, 1, 0, 0, 0, 0, loc), time.Date(2026, 2, 1, 0, 0, 0, 0, loc)},
+	}
+	for _, c := range cases {
+		t.Run(c.period.String(), func(t *testing.T) {
+			start, end := c.period.bounds(now, loc)
+			assert.True(t, c.start.Equal(start), "start %v", start)
+			assert.True(t, c.end.Equal(end), "end %v", end)
+		})
+	}
+}
+
+func TestQuotaLimiter(t *testing.T) {
+	loc := time.FixedZone("CST", 8*3600)
+	now := time.Date(2026, 1, 8, 23, 0, 0, 0, loc)
+
+	mr := miniredis.RunT(t)
+	mr.SetTime(now)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	ctx := context.Background()
+
+	q := NewQuotaLimiter(client, "api", WithQuotaLimit(5), WithQuotaLocation(loc),
+		WithQuotaClock(ClockFunc(func() time.Time { return now })))
+	assert.True(t, q.ResetAt().Equal(time.Date(2026, 1, 9, 0, 0, 0, 0, loc)))
+
+	ok, err := q.AllowN(ctx, 5)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	res, err := q.AllowWithResult(ctx)
+	assert.NoError(t, err)
+	assert.False(t, res.Allowed)
+	assert.Equal(t, time.Hour, res.RetryAfter)
+
+	// 管理员追加额度，只在本周期有效
+	assert.NoError(t, q.TopUp(ctx, 2))
+	remaining, err := q.Remaining(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), remaining)
+
+	res, err = q.AllowWithResult(ctx)
+	assert.NoError(t, err)
+	assert.True(t, res.Allowed)
+	assert.Equal(t, float64(7), res.Limit)
+	assert.Equal(t, float64(1), res.Remaining)
+
+	st, err := q.State(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, float64(6), st.Level)
+	assert.Equal(t, float64(7), st.Capacity)
+
+	// key 在周期结束后再保留 Retention
+	ttl := mr.TTL("quota:{api}:20260108")
+	assert.Equal(t, 25*time.Hour, ttl)
+
+	// 跨过当地 0 点进入新周期
+	now = now.Add(time.Hour)
+	remaining, err = q.Remaining(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), remaining)
+
+	ok, err = q.Allow(ctx)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.True(t, mr.Exists("quota:{api}:20260109"))
+
+	assert.NoError(t, q.Reset(ctx))
+	remaining, err = q.Remaining(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), remaining)
+
+	assert.Error(t, q.TopUp(ctx, 0))
+	_, err = q.AllowN(ctx, 0)
+	assert.Error(t, err)
+}
+
+func TestQuotaLimiter_Rollover(t *testing.T) {
+	now := time.Date(2026, 1, 8, 12, 0, 0, 0, time.UTC)
+
+	mr := miniredis.RunT(t)
+	mr.SetTime(now)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	ctx := context.Background()
+
+	q := NewQuotaLimiter(client, "plan", WithQuotaLimit(5), WithQuotaRollover(3),
+		WithQuotaClock(ClockFunc(func() time.Time { return now })))
+
+	// 上个周期不存在：不结转
+	remaining, err := q.Remaining(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), remaining)
+
+	ok, err := q.Allow(ctx)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	// 次日：昨日剩 4 个，最多结转 3 个
+	now = now.Add(24 * time.Hour)
+	remaining, err = q.Remaining(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(8), remaining)
+
+	res, err := q.AllowNWithResult(ctx, 8)
+	assert.NoError(t, err)
+	assert.True(t, res.Allowed)
+	assert.Equal(t, float64(8), res.Limit)
+
+	// 结转额度在第一次计数时固定，不受上个周期后续变化影响
+	v, err := client.HGet(ctx, "quota:{plan}:20260109", "carry").Int64()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), v)
+
+	// 第三天：前一天全部用完，没有可结转的额度
+	now = now.Add(24 * time.Hour)
+	st, err := q.State(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, float64(5), st.Capacity)
+	assert.Equal(t, float64(0), st.Level)
+}
+
+func TestQuotaLimiter_Adjust(t *testing.T) {
+	now := time.Date(2026, 1, 8, 12, 0, 0, 0, time.UTC)
+
+	mr := miniredis.RunT(t)
+	mr.SetTime(now)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	ctx := context.Background()
+
+	q := NewQuotaLimiter(client, "tenant:a", WithQuotaLimit(5),
+		WithQuotaClock(ClockFunc(func() time.Time { return now })))
+	b := NewQuotaLimiter(client, "tenant:b", WithQuotaLimit(5),
+		WithQuotaClock(ClockFunc(func() time.Time { return now })))
+
+	ok, err := q.AllowN(ctx, 2)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	// 补偿额度只在本周期有效
+	left, err := q.Grant(ctx, "tenant:a", 4)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(7), left)
+
+	left, err = q.Revoke(ctx, "tenant:a", 3)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4), left)
+
+	// 剩余额度不足时整体失败，不做修改
+	left, err = q.Revoke(ctx, "tenant:a", 5)
+	assert.ErrorIs(t, err, ErrQuotaInsufficient)
+	assert.Equal(t, int64(4), left)
+
+	// 转给另一个 key：两边在同一个脚本中修改
+	left, err = q.Transfer(ctx, "tenant:a", "tenant:b", 3)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), left)
+	remaining, err := b.Remaining(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(8), remaining)
+
+	_, err = q.Transfer(ctx, "tenant:a", "tenant:b", 2)
+	assert.ErrorIs(t, err, ErrQuotaInsufficient)
+	remaining, err = b.Remaining(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(8), remaining)
+	assert.Equal(t, 36*time.Hour, mr.TTL("quota:{tenant:b}:20260108"))
+
+	_, err = q.Grant(ctx, "tenant:a", 0)
+	assert.Error(t, err)
+	_, err = q.Transfer(ctx, "tenant:a", "tenant:a", 1)
+	assert.Error(t, err)
+
+	// 下一个周期不受影响
+	now = now.Add(24 * time.Hour)
+	remaining, err = b.Remaining(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), remaining)
+}
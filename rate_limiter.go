@@ -16,18 +16,54 @@ type RateLimiter interface {
 	// error 通常表示后端（例如 Redis）异常，由上层决定是 fail-open 还是 fail-close。
 	Allow(ctx context.Context) (bool, error)
 
+	// AllowWithResult 与 Allow 相同，但同时返回剩余额度和重试等待时间。
+	// 这些信息与判定结果由同一次脚本调用原子返回，无需再调用 State。
+	AllowWithResult(ctx context.Context) (Result, error)
+
 	// AllowN 尝试一次性获取 n 个许可。
-	// 对于令牌桶/漏桶非常有用（批量扣减），滑动窗口等不一定支持 n>1。
+	// 对于批量操作非常有用（例如一次扣减 n 个 token、一次发送 n 条短信），要么全部通过，要么全部拒绝。
 	AllowN(ctx context.Context, n int64) (bool, error)
 
 	// Wait 阻塞直到成功获取 1 个许可，或者 ctx 超时/取消。
 	// 适合节流场景，例如严格“匀速”处理任务队列。
+	// maxWait 为 0 表示不等待，被拒绝时立即返回；传入 UntilDeadline 时等待预算取自 ctx 的截止时间。
 	Wait(ctx context.Context, maxWait time.Duration) error
 
 	// State 返回限流器当前状态，用于监控和调试。
 	State(ctx context.Context) (LimiterState, error)
+
+	// Reset 原子地删除该限流器在 Redis 中的全部状态，使其回到初始（满额）状态。
+	// 常用于误限流后人工解封某个用户。
+	Reset(ctx context.Context) error
+}
+
+// Result 是一次限流判定的完整结果。
+type Result struct {
+	// Allowed 是否放行
+	Allowed bool
+
+	// Limit 限流上限（令牌桶/漏桶为容量，滑动窗口为窗口内最大请求数）
+	Limit float64
+
+	// Remaining 判定之后剩余的额度（向下取整）
+	Remaining float64
+
+	// RetryAfter 被拒绝时，距离同样的请求可能被放行还需等待的时长；放行时为 0。
+	RetryAfter time.Duration
+
+	// Duplicate 按请求 ID 去重时（如 AllowIdempotent），本次命中了同一请求 ID 之前的放行判定，
+	// 返回的是当时记录的结果，没有再次扣减额度。
+	Duplicate bool
 }
 
+// 判定来源，见 LimiterState.Source。
+const (
+	// SourceRedis 由 Redis 中的共享状态判定
+	SourceRedis = "redis"
+	// SourceLocal 由进程内的本地状态判定（例如 FallbackLimiter 降级期间）
+	SourceLocal = "local"
+)
+
 // LimiterState 为各类限流器提供了一个尽量通用的状态结构。
 // 各字段的含义可能会因具体算法略有区别，但整体语义保持一致。
 type LimiterState struct {
@@ -46,6 +82,13 @@ type LimiterState struct {
 	//  - 滑动窗口：窗口内允许的最大请求数
 	Capacity float64
 
+	// SoftLimit 软上限（仅两段式限流器使用，其他限流器为 0）：
+	// 超过后仍然放行，但会被标记为超额。
+	SoftLimit float64
+
+	// Overage 超过软上限的用量（仅两段式限流器使用）。
+	Overage float64
+
 	// Rate 速率：
 	//  - 令牌桶：token 生成速率（token/sec）
 	//  - 滑动窗口：每秒平均允许请求数（Limit / Window）
@@ -64,10 +107,24 @@ type LimiterState struct {
 
 	// Key 该限流器的业务 key（例如 "api:/v1/login"、"user:123"）
 	Key string
+
+	// Window 窗口大小（仅固定窗口 / 滑动窗口 / 配额等按窗口计数的限流器使用，令牌桶与漏桶为 0）。
+	Window time.Duration
+
+	// Shard 该状态所属的分片序号（仅分片限流器的 State / GlobalState 填写，其他限流器为 0）。
+	Shard int
+
+	// AllowedTotal / DeniedTotal 按 key 累计的放行 / 拒绝许可数，
+	// 仅在通过 With*Stats 开启统计时填写，与 Stats 返回的计数相同。
+	AllowedTotal int64
+	DeniedTotal  int64
+
+	// Source 当前的判定来源（SourceRedis / SourceLocal），仅带降级能力的限流器（FallbackLimiter）填写，其他限流器为空。
+	Source string
 }
 
 func (s LimiterState) String() string {
-	return fmt.Sprintf("level=%.f; remaining=%.f; capactity=%.f; rate=%.f; last_updated=%d; next_available_time=%d; type=%s;key=%s",
+	return fmt.Sprintf("level=%.f; remaining=%.f; capactity=%.f; rate=%.f; last_updated=%d; next_available_time=%d; type=%s;key=%s; window=%s; shard=%d; allowed_total=%d; denied_total=%d; source=%s",
 		s.Level,
 		s.Remaining,
 		s.Capacity,
@@ -76,13 +133,23 @@ func (s LimiterState) String() string {
 		s.NextAvailableTime,
 		s.Type,
 		s.Key,
+		s.Window,
+		s.Shard,
+		s.AllowedTotal,
+		s.DeniedTotal,
+		s.Source,
 	)
 }
 
 // RateShardedLimiter 支持分片的限流器接口
 type RateShardedLimiter interface {
 	Allow(ctx context.Context, shardKey string) (bool, error)
+	AllowWithResult(ctx context.Context, shardKey string) (Result, error)
 	AllowN(ctx context.Context, shardKey string, n int64) (bool, error)
 	State(ctx context.Context, shardKey string) (LimiterState, error)
 	Wait(ctx context.Context, shardKey string, maxWait time.Duration) error
+	// Reset 重置 shardKey 所在分片的状态
+	Reset(ctx context.Context, shardKey string) error
+	// ResetAll 重置所有分片的状态
+	ResetAll(ctx context.Context) error
 }
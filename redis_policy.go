@@ -0,0 +1,159 @@
+package limiter
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// RedisPolicyLimiter 为限流器的每一次 Redis 调用加上单次超时与重试：
+//   - Timeout 限制单次调用的耗时，一条慢命令不会占住请求线程直到调用方 ctx 的截止时间
+//   - 单次调用出现瞬时错误（超时、连接被重置、MOVED / TRYAGAIN 等）时按 Backoff 退避后重试，最多 Retries 次
+//
+// 注意：判定脚本在超时或连接断开时可能已经在 Redis 中执行，重试会再扣减一次额度，
+// 因此最坏情况下一次 Allow 最多多扣 Retries 次。对额度要求严格时应关闭重试，
+// 或配合 TokenBucketLimiter.AllowIdempotent 使用。
+type RedisPolicyLimiter struct {
+	limiter RateLimiter
+
+	// Timeout 单次 Redis 调用的超时时间，0 表示只受调用方 ctx 限制（默认）
+	Timeout time.Duration
+	// Retries 瞬时错误的最大重试次数，0 表示不重试（默认）
+	Retries int
+	// Backoff 第一次重试前的等待时间，之后每次翻倍，默认 10ms
+	Backoff time.Duration
+	// Retryable 判断错误是否可以重试，默认见 IsTransientRedisError
+	Retryable func(err error) bool
+}
+
+var _ RateLimiter = (*RedisPolicyLimiter)(nil)
+
+// NewRedisPolicyLimiter 为 l 的 Redis 调用加上单次超时与重试。
+func NewRedisPolicyLimiter(l RateLimiter, opts ...RedisPolicyOption) *RedisPolicyLimiter {
+	if l == nil {
+		panic("redis policy: limiter is nil")
+	}
+
+	r := &RedisPolicyLimiter{
+		limiter:   l,
+		Backoff:   10 * time.Millisecond,
+		Retryable: IsTransientRedisError,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+func (r *RedisPolicyLimiter) Allow(ctx context.Context) (bool, error) {
+	res, err := r.AllowWithResult(ctx)
+	return res.Allowed, err
+}
+
+func (r *RedisPolicyLimiter) AllowWithResult(ctx context.Context) (Result, error) {
+	var res Result
+	err := r.do(ctx, func(ctx context.Context) error {
+		var err error
+		res, err = r.limiter.AllowWithResult(ctx)
+		return err
+	})
+	return res, err
+}
+
+func (r *RedisPolicyLimiter) AllowN(ctx context.Context, n int64) (bool, error) {
+	var ok bool
+	err := r.do(ctx, func(ctx context.Context) error {
+		var err error
+		ok, err = r.limiter.AllowN(ctx, n)
+		return err
+	})
+	return ok, err
+}
+
+// Wait 每次重试判定都单独应用超时与重试策略，sleep 不受 Timeout 限制。
+func (r *RedisPolicyLimiter) Wait(ctx context.Context, maxWait time.Duration) error {
+	return waitFor(ctx, "", "redis_policy", maxWait, nil, r.AllowWithResult)
+}
+
+func (r *RedisPolicyLimiter) State(ctx context.Context) (LimiterState, error) {
+	var st LimiterState
+	err := r.do(ctx, func(ctx context.Context) error {
+		var err error
+		st, err = r.limiter.State(ctx)
+		return err
+	})
+	return st, err
+}
+
+func (r *RedisPolicyLimiter) Reset(ctx context.Context) error {
+	return r.do(ctx, r.limiter.Reset)
+}
+
+// do 按超时与重试策略执行 fn。调用方 ctx 结束后不再重试，直接返回最后一次的错误。
+func (r *RedisPolicyLimiter) do(ctx context.Context, fn func(ctx context.Context) error) error {
+	backoff := r.Backoff
+	timer := time.NewTimer(time.Second)
+	defer timer.Stop()
+
+	for attempt := 0; ; attempt++ {
+		err := r.attempt(ctx, fn)
+		if err == nil || attempt >= r.Retries || ctx.Err() != nil || !r.Retryable(err) {
+			return err
+		}
+
+		timer.Reset(backoff)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-timer.C:
+		}
+		backoff *= 2
+	}
+}
+
+// attempt 在 Timeout 限制下执行一次 fn。
+func (r *RedisPolicyLimiter) attempt(ctx context.Context, fn func(ctx context.Context) error) error {
+	if r.Timeout <= 0 {
+		return fn(ctx)
+	}
+	ctx, cancel := context.WithTimeout(ctx, r.Timeout)
+	defer cancel()
+	return fn(ctx)
+}
+
+// transientRedisErrors 为可以重试的 Redis 错误回复前缀：
+// 集群迁移 / 故障转移期间的重定向与暂不可用，以及实例加载数据、只读副本等短暂状态。
+var transientRedisErrors = []string{"MOVED ", "ASK ", "TRYAGAIN", "CLUSTERDOWN", "LOADING", "READONLY", "MASTERDOWN"}
+
+// IsTransientRedisError 判断 err 是否为可以重试的瞬时错误：
+// 单次调用超时、网络超时、连接被重置 / 拒绝 / 意外关闭，以及 MOVED、ASK、TRYAGAIN 等错误回复。
+// ctx 被取消、redis.Nil 以及限流错误都不属于瞬时错误。
+func IsTransientRedisError(err error) bool {
+	switch {
+	case err == nil, errors.Is(err, context.Canceled), errors.Is(err, ErrLimiter):
+		return false
+	case errors.Is(err, context.DeadlineExceeded),
+		errors.Is(err, io.EOF),
+		errors.Is(err, io.ErrUnexpectedEOF),
+		errors.Is(err, syscall.ECONNRESET),
+		errors.Is(err, syscall.ECONNREFUSED),
+		errors.Is(err, syscall.EPIPE):
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	msg := err.Error()
+	for _, prefix := range transientRedisErrors {
+		if strings.HasPrefix(msg, prefix) {
+			return true
+		}
+	}
+	return false
+}
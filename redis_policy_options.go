@@ -0,0 +1,36 @@
+package limiter
+
+import "time"
+
+// RedisPolicyOption 为 Redis 调用超时与重试策略的配置项。
+type RedisPolicyOption func(*RedisPolicyLimiter)
+
+// WithRedisTimeout 设置单次 Redis 调用的超时时间，0 表示只受调用方 ctx 限制。
+func WithRedisTimeout(d time.Duration) RedisPolicyOption {
+	return func(r *RedisPolicyLimiter) {
+		if d >= 0 {
+			r.Timeout = d
+		}
+	}
+}
+
+// WithRedisRetry 设置瞬时错误的最大重试次数 n 与第一次重试前的等待时间 backoff（之后每次翻倍）。
+func WithRedisRetry(n int, backoff time.Duration) RedisPolicyOption {
+	return func(r *RedisPolicyLimiter) {
+		if n >= 0 {
+			r.Retries = n
+		}
+		if backoff > 0 {
+			r.Backoff = backoff
+		}
+	}
+}
+
+// WithRedisRetryable 自定义可重试错误的判断，默认为 IsTransientRedisError。
+func WithRedisRetryable(fn func(err error) bool) RedisPolicyOption {
+	return func(r *RedisPolicyLimiter) {
+		if fn != nil {
+			r.Retryable = fn
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package limiter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+)
+
+// scriptedLimiter 依次返回 errs 中的错误，用完后全部放行；hang 为 true 时阻塞到 ctx 结束。
+type scriptedLimiter struct {
+	flakyLimiter
+	errs []error
+	hang bool
+}
+
+func (s *scriptedLimiter) AllowWithResult(ctx context.Context) (Result, error) {
+	s.calls++
+	if s.hang {
+		<-ctx.Done()
+		return Result{}, ctx.Err()
+	}
+	if len(s.errs) > 0 {
+		err := s.errs[0]
+		s.errs = s.errs[1:]
+		return Result{}, err
+	}
+	return Result{Allowed: true}, nil
+}
+
+func TestRedisPolicyLimiter(t *testing.T) {
+	ctx := context.Background()
+
+	// 瞬时错误重试后成功
+	inner := &scriptedLimiter{errs: []error{io.EOF, fmt.Errorf("dial: %w", syscall.ECONNRESET), errors.New("TRYAGAIN Multiple keys request during rehashing of slot")}}
+	r := NewRedisPolicyLimiter(inner, WithRedisRetry(3, time.Millisecond))
+	ok, err := r.Allow(ctx)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, 4, inner.calls)
+
+	// 超过重试次数返回最后一次的错误
+	inner = &scriptedLimiter{errs: []error{io.EOF, io.EOF, io.ErrUnexpectedEOF}}
+	r = NewRedisPolicyLimiter(inner, WithRedisRetry(2, time.Millisecond))
+	_, err = r.Allow(ctx)
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	assert.Equal(t, 3, inner.calls)
+
+	// 非瞬时错误不重试
+	inner = &scriptedLimiter{errs: []error{errors.New("ERR wrong number of arguments")}}
+	r = NewRedisPolicyLimiter(inner, WithRedisRetry(3, time.Millisecond))
+	_, err = r.Allow(ctx)
+	assert.Error(t, err)
+	assert.Equal(t, 1, inner.calls)
+
+	// 单次超时：每次调用最多阻塞 Timeout，超时后重试
+	inner = &scriptedLimiter{hang: true}
+	r = NewRedisPolicyLimiter(inner, WithRedisTimeout(20*time.Millisecond), WithRedisRetry(1, time.Millisecond))
+	start := time.Now()
+	_, err = r.Allow(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, 2, inner.calls)
+	assert.Less(t, time.Since(start), time.Second)
+
+	// 调用方 ctx 结束后不再重试
+	inner = &scriptedLimiter{hang: true}
+	r = NewRedisPolicyLimiter(inner, WithRedisTimeout(time.Second), WithRedisRetry(5, time.Millisecond))
+	cctx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	_, err = r.Allow(cctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, 1, inner.calls)
+}
+
+func TestRedisPolicyLimiter_Redis(t *testing.T) {
+	ctx := context.Background()
+	client := newSpecClient(t)
+
+	tb := NewTokenBucketLimiter(client, "policy", WithTokenBucketRate(1), WithTokenBucketCapacity(1))
+	r := NewRedisPolicyLimiter(tb, WithRedisTimeout(time.Second), WithRedisRetry(2, time.Millisecond))
+
+	ok, err := r.Allow(ctx)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = r.Allow(ctx)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	st, err := r.State(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, float64(1), st.Capacity)
+	assert.NoError(t, r.Reset(ctx))
+}
+
+func TestIsTransientRedisError(t *testing.T) {
+	for _, err := range []error{
+		io.EOF,
+		context.DeadlineExceeded,
+		syscall.ECONNREFUSED,
+		errors.New("MOVED 3999 127.0.0.1:6381"),
+		errors.New("CLUSTERDOWN The cluster is down"),
+		errors.New("LOADING Redis is loading the dataset in memory"),
+	} {
+		assert.True(t, IsTransientRedisError(err), err.Error())
+	}
+	for _, err := range []error{
+		nil,
+		redis.Nil,
+		context.Canceled,
+		ErrLimiter,
+		errors.New("NOSCRIPT No matching script"),
+	} {
+		assert.False(t, IsTransientRedisError(err), fmt.Sprint(err))
+	}
+}
@@ -0,0 +1,59 @@
+package limiter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type returner interface {
+	checker
+	ReturnN(ctx context.Context, n int64) error
+	Reset(ctx context.Context) error
+}
+
+func TestReturnN(t *testing.T) {
+	client := newSpecClient(t)
+	ctx := context.Background()
+
+	for _, mode := range []StorageMode{StorageString, StorageHash} {
+		cases := map[string]returner{
+			"token_bucket": NewTokenBucketLimiter(client, "refund",
+				WithTokenBucketRate(0.001), WithTokenBucketCapacity(10), WithTokenBucketStorage(mode)),
+			"leaky_bucket": NewLeakyBucketLimiter(client, "refund",
+				WithLeakyBucketRate(0.001), WithLeakyBucketCapacity(10), WithLeakyBucketStorage(mode)),
+		}
+
+		for name, l := range cases {
+			t.Run(name, func(t *testing.T) {
+				defer l.Reset(ctx)
+
+				res, err := l.AllowNWithResult(ctx, 6)
+				assert.NoError(t, err)
+				assert.Equal(t, float64(4), res.Remaining)
+
+				assert.NoError(t, l.ReturnN(ctx, 3))
+				res, err = l.CheckN(ctx, 1)
+				assert.NoError(t, err)
+				assert.Equal(t, float64(7), res.Remaining)
+
+				// 归还量超过已消耗量时按容量截断
+				assert.NoError(t, l.ReturnN(ctx, 100))
+				res, err = l.CheckN(ctx, 1)
+				assert.NoError(t, err)
+				assert.Equal(t, float64(10), res.Remaining)
+
+				assert.Error(t, l.ReturnN(ctx, 0))
+
+				// 桶不存在时不创建任何 key
+				assert.NoError(t, l.Reset(ctx))
+				assert.NoError(t, l.ReturnN(ctx, 1))
+				n, err := client.Exists(ctx, "tbucket:{refund}:tokens", "tbucket:{refund}:state",
+					"lb:{refund}:bucket", "lb:{refund}:state").Result()
+				assert.NoError(t, err)
+				assert.Equal(t, int64(0), n)
+			})
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package limiter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// RegistryEntry 是 Registry 中的一个限流器，Limiter 与 Sharded 二选一。
+type RegistryEntry struct {
+	Name    string
+	Limiter RateLimiter
+	Sharded RateShardedLimiter
+}
+
+// limiter 返回条目中实际的限流器。
+func (e RegistryEntry) limiter() any {
+	if e.Sharded != nil {
+		return e.Sharded
+	}
+	return e.Limiter
+}
+
+// Registry 按名称管理进程内存活的限流器，是运维接口（adminapi）、配置热更新（ConfigWatcher）
+// 与指标导出查找限流器的统一入口。
+//
+// DefaultRegistry 是进程级的默认实例；需要隔离时（例如测试、同一进程内的多个服务）
+// 使用 NewRegistry 创建独立的 Registry，并通过 WithConfigWatcherRegistry、adminapi.WithRegistry 注入。
+type Registry struct {
+	mu      sync.RWMutex
+	entries map[string]RegistryEntry
+}
+
+// DefaultRegistry 是进程级的默认 Registry。
+var DefaultRegistry = NewRegistry()
+
+// NewRegistry 创建一个空的 Registry。
+func NewRegistry() *Registry {
+	return &Registry{entries: make(map[string]RegistryEntry)}
+}
+
+// Register 以 name 注册一个单桶限流器，同名时覆盖。
+func (r *Registry) Register(name string, l RateLimiter) {
+	if l == nil {
+		panic("registry: limiter is nil")
+	}
+	r.set(RegistryEntry{Name: name, Limiter: l})
+}
+
+// RegisterSharded 以 name 注册一个分片限流器，同名时覆盖。
+func (r *Registry) RegisterSharded(name string, l RateShardedLimiter) {
+	if l == nil {
+		panic("registry: limiter is nil")
+	}
+	r.set(RegistryEntry{Name: name, Sharded: l})
+}
+
+// RegisterSet 注册 Factory.CreateAll 创建的全部限流器，名称为规则名。
+func (r *Registry) RegisterSet(set *LimiterSet) {
+	for name, l := range set.Limiters {
+		r.Register(name, l)
+	}
+	for name, l := range set.Sharded {
+		r.RegisterSharded(name, l)
+	}
+}
+
+func (r *Registry) set(e RegistryEntry) {
+	if e.Name == "" {
+		panic("registry: name is empty")
+	}
+	r.mu.Lock()
+	r.entries[e.Name] = e
+	r.mu.Unlock()
+}
+
+// Unregister 移除名为 name 的限流器，不会关闭它。
+func (r *Registry) Unregister(name string) {
+	r.mu.Lock()
+	delete(r.entries, name)
+	r.mu.Unlock()
+}
+
+// Lookup 返回名为 name 的条目。
+func (r *Registry) Lookup(name string) (RegistryEntry, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	e, ok := r.entries[name]
+	return e, ok
+}
+
+// Get 返回名为 name 的单桶限流器，不存在或是分片限流器时返回 false。
+func (r *Registry) Get(name string) (RateLimiter, bool) {
+	e, ok := r.Lookup(name)
+	return e.Limiter, ok && e.Limiter != nil
+}
+
+// GetSharded 返回名为 name 的分片限流器，不存在或是单桶限流器时返回 false。
+func (r *Registry) GetSharded(name string) (RateShardedLimiter, bool) {
+	e, ok := r.Lookup(name)
+	return e.Sharded, ok && e.Sharded != nil
+}
+
+// Entries 按名称排序返回当前注册的全部条目的快照，例如定期导出每个限流器的 State。
+func (r *Registry) Entries() []RegistryEntry {
+	r.mu.RLock()
+	list := make([]RegistryEntry, 0, len(r.entries))
+	for _, e := range r.entries {
+		list = append(list, e)
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// Len 返回注册的限流器数量。
+func (r *Registry) Len() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.entries)
+}
+
+// CloseAll 关闭并移除全部限流器，用于进程优雅退出。
+// 实现了 Close(ctx) error 或 Close() error 的限流器（例如 BatchedTokenBucketLimiter 会归还预取的额度）会被关闭，
+// 其余的只从 Registry 中移除。返回所有关闭失败的错误。
+func (r *Registry) CloseAll(ctx context.Context) error {
+	r.mu.Lock()
+	entries := r.entries
+	r.entries = make(map[string]RegistryEntry)
+	r.mu.Unlock()
+
+	names := make([]string, 0, len(entries))
+	for name := range entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var errs []error
+	for _, name := range names {
+		var err error
+		e := entries[name]
+		switch c := e.limiter().(type) {
+		case interface {
+			Close(ctx context.Context) error
+		}:
+			err = c.Close(ctx)
+		case interface{ Close() error }:
+			err = c.Close()
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("registry: close %q: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,72 @@
+package limiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistry(t *testing.T) {
+	ctx := context.Background()
+	client := newSpecClient(t)
+	r := NewRegistry()
+
+	tb := NewTokenBucketLimiter(client, "batch", WithTokenBucketRate(0.001), WithTokenBucketCapacity(100))
+	b := NewBatchedTokenBucketLimiter(tb, WithBatchSize(10), WithBatchFlushInterval(time.Hour))
+	sharded := NewShardedTokenBucketLimiter(client, "api")
+	r.Register("batch", b)
+	r.RegisterSharded("api", sharded)
+
+	l, ok := r.Get("batch")
+	assert.True(t, ok)
+	assert.Same(t, b, l)
+	_, ok = r.Get("api")
+	assert.False(t, ok)
+	s, ok := r.GetSharded("api")
+	assert.True(t, ok)
+	assert.Same(t, sharded, s)
+	_, ok = r.Get("missing")
+	assert.False(t, ok)
+
+	entries := r.Entries()
+	assert.Len(t, entries, 2)
+	assert.Equal(t, "api", entries[0].Name)
+	assert.Equal(t, "batch", entries[1].Name)
+
+	// CloseAll 关闭批量令牌桶，归还本地预取的额度
+	ok, err := b.Allow(ctx)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.InDelta(t, 90, getFloat(t, client, tb.tokensKey()), 0.01)
+	assert.NoError(t, r.CloseAll(ctx))
+	assert.InDelta(t, 99, getFloat(t, client, tb.tokensKey()), 0.01)
+	assert.Equal(t, 0, r.Len())
+
+	set, err := NewFactory(client).CreateAll([]LimitRule{
+		{LimitConfig: LimitConfig{Name: "chat"}},
+		{LimitConfig: LimitConfig{Name: "users"}, Shards: 2},
+	})
+	assert.NoError(t, err)
+	r.RegisterSet(set)
+	assert.Equal(t, 2, r.Len())
+	r.Unregister("chat")
+	assert.Equal(t, 1, r.Len())
+
+	assert.Panics(t, func() { r.Register("", tb) })
+	assert.Panics(t, func() { r.Register("x", nil) })
+}
+
+func TestConfigWatcher_Registry(t *testing.T) {
+	client := newSpecClient(t)
+	r := NewRegistry()
+	tb := NewTokenBucketLimiter(client, "chat")
+	r.Register("chat", tb)
+
+	w := NewConfigWatcher(client, "limits:config", WithConfigWatcherRegistry(r))
+	assert.NoError(t, w.Apply(LimitConfig{Name: "chat", Rate: 200, Capacity: 400}, LimitConfig{Name: "unknown", Rate: 1}))
+	rate, capacity := tb.limits()
+	assert.Equal(t, 200.0, rate)
+	assert.Equal(t, 400.0, capacity)
+}
@@ -0,0 +1,127 @@
+package limiter
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// RollupEntry 是 Rollup 报表中的一行：某个分组下所有 key 的聚合状态。
+type RollupEntry struct {
+	// Group 分组名称（由 groupFn 返回）
+	Group string
+	// Keys 该分组下的限流 key 数量
+	Keys int
+	// Used 已使用量之和（Capacity - Remaining）
+	Used float64
+	// Capacity 容量之和
+	Capacity float64
+	// Utilization 利用率 = Used / Capacity
+	Utilization float64
+}
+
+// Reporter 用于扫描 Redis 中某一类限流器的 key，并按分组聚合它们的 State。
+// 典型问题：“哪些租户在消耗我们的共享容量？”
+type Reporter struct {
+	client *redis.Client
+
+	// Prefix 限流器的 Redis key 前缀，例如 "tbucket"
+	Prefix string
+	// ScanCount 每次 SCAN 的 COUNT 提示值，默认 100
+	ScanCount int64
+
+	newLimiter func(key string) RateLimiter
+}
+
+// NewReporter 创建一个报表器。
+//   - prefix:     限流器的 Redis key 前缀（与 With*Prefix 保持一致）
+//   - newLimiter: 根据业务 key 构建限流器，用于读取 State（配置应与线上一致）
+func NewReporter(client *redis.Client, prefix string, newLimiter func(key string) RateLimiter) *Reporter {
+	if client == nil {
+		panic("reporter: redis client is nil")
+	}
+	if prefix == "" {
+		panic("reporter: prefix is empty")
+	}
+	if newLimiter == nil {
+		panic("reporter: newLimiter is nil")
+	}
+	return &Reporter{
+		client:     client,
+		Prefix:     prefix,
+		ScanCount:  100,
+		newLimiter: newLimiter,
+	}
+}
+
+// scanKeys 通过只登记了 Prefix 的 KeyScanner 找出业务 key 匹配 pattern 的所有限流 key，返回去重后的业务 key。
+// 分片 key 按默认模板还原为完整的分片业务 key，与 newLimiter 的参数保持一致。
+func (r *Reporter) scanKeys(ctx context.Context, pattern string) ([]string, error) {
+	s := &KeyScanner{
+		client:    r.client,
+		prefixes:  map[string]string{r.Prefix: r.Prefix},
+		ScanCount: r.ScanCount,
+	}
+	infos, err := s.ScanKeys(ctx, pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(infos))
+	for _, info := range infos {
+		key := info.Key
+		if info.Shard >= 0 {
+			key = fmt.Sprintf("%s:shard:%d", info.Key, info.Shard)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// Rollup 扫描业务 key 匹配 pattern（Redis glob 语法）的限流器，
+// 读取每个 key 的 State，并按 groupFn 返回的分组聚合，结果按 Used 从大到小排序。
+// groupFn 为 nil 时，每个 key 单独成组。
+func (r *Reporter) Rollup(ctx context.Context, pattern string, groupFn func(key string) string) ([]RollupEntry, error) {
+	keys, err := r.scanKeys(ctx, pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make(map[string]*RollupEntry)
+	for _, key := range keys {
+		state, err := r.newLimiter(key).State(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("reporter: state of %q: %w", key, err)
+		}
+
+		group := key
+		if groupFn != nil {
+			group = groupFn(key)
+		}
+		entry, ok := groups[group]
+		if !ok {
+			entry = &RollupEntry{Group: group}
+			groups[group] = entry
+		}
+		entry.Keys++
+		entry.Used += max(state.Capacity-state.Remaining, 0)
+		entry.Capacity += state.Capacity
+	}
+
+	report := make([]RollupEntry, 0, len(groups))
+	for _, entry := range groups {
+		if entry.Capacity > 0 {
+			entry.Utilization = entry.Used / entry.Capacity
+		}
+		report = append(report, *entry)
+	}
+	sort.Slice(report, func(i, j int) bool {
+		if report[i].Used != report[j].Used {
+			return report[i].Used > report[j].Used
+		}
+		return report[i].Group < report[j].Group
+	})
+	return report, nil
+}
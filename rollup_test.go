@@ -0,0 +1,55 @@
+package limiter
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReporter_Rollup(t *testing.T) {
+	client := newSpecClient(t)
+	ctx := context.Background()
+
+	newLimiter := func(key string) RateLimiter {
+		return NewLeakyBucketLimiter(client, key,
+			WithLeakyBucketRate(0.001),
+			WithLeakyBucketCapacity(10),
+		)
+	}
+	consume := func(key string, n int64) {
+		ok, err := newLimiter(key).(*LeakyBucketLimiter).AllowN(ctx, n)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+	}
+	consume("tenant:a:user:1", 2)
+	consume("tenant:a:user:2", 3)
+	consume("tenant:b:user:1", 8)
+	consume("other:1", 1)
+
+	r := NewReporter(client, "lb", newLimiter)
+	report, err := r.Rollup(ctx, "tenant:*", func(key string) string {
+		return strings.Join(strings.SplitN(key, ":", 3)[:2], ":")
+	})
+	assert.NoError(t, err)
+	if assert.Len(t, report, 2) {
+		assert.Equal(t, "tenant:b", report[0].Group)
+		assert.Equal(t, 1, report[0].Keys)
+		assert.InDelta(t, 8, report[0].Used, 0.1)
+
+		assert.Equal(t, "tenant:a", report[1].Group)
+		assert.Equal(t, 2, report[1].Keys)
+		assert.InDelta(t, 5, report[1].Used, 0.1)
+		assert.InDelta(t, 0.25, report[1].Utilization, 0.01)
+	}
+
+	// 分片 key 按完整的分片业务 key 读取 State
+	consume("sharded:shard:1", 4)
+	report, err = r.Rollup(ctx, "sharded*", nil)
+	assert.NoError(t, err)
+	if assert.Len(t, report, 1) {
+		assert.Equal(t, "sharded:shard:1", report[0].Group)
+		assert.InDelta(t, 4, report[0].Used, 0.1)
+	}
+}
@@ -0,0 +1,286 @@
+package limiter
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// RuleAction 是黑白名单的匹配结果。
+type RuleAction int
+
+const (
+	// RuleNone 不在任何名单中，交给被包装的限流器判定。
+	RuleNone RuleAction = iota
+	// RuleAllow 命中白名单，直接放行，不访问 Redis。
+	RuleAllow
+	// RuleDeny 命中黑名单，直接拒绝。
+	RuleDeny
+)
+
+// ruleSet 是一份解析后的名单。
+type ruleSet struct {
+	exact    map[string]struct{}
+	prefixes []string
+	nets     []*net.IPNet
+}
+
+// parseRuleSet 解析名单中的规则：
+//   - "a.b.c.d/n"、"::1/128" 等 CIDR：匹配落在网段内的 IP key
+//   - 以 "*" 结尾：前缀匹配，例如 "internal:*"
+//   - 其他：精确匹配
+func parseRuleSet(patterns []string) (ruleSet, error) {
+	rs := ruleSet{exact: make(map[string]struct{}, len(patterns))}
+	for _, p := range patterns {
+		switch {
+		case strings.Contains(p, "/"):
+			_, ipNet, err := net.ParseCIDR(p)
+			if err != nil {
+				return ruleSet{}, fmt.Errorf("rule limiter: invalid cidr %q: %v", p, err)
+			}
+			rs.nets = append(rs.nets, ipNet)
+		case strings.HasSuffix(p, "*"):
+			rs.prefixes = append(rs.prefixes, strings.TrimSuffix(p, "*"))
+		default:
+			rs.exact[p] = struct{}{}
+		}
+	}
+	return rs, nil
+}
+
+// match 判断 key 是否命中名单。只有 key 本身是合法 IP 时才会进行 CIDR 匹配。
+func (rs ruleSet) match(key string) bool {
+	if _, ok := rs.exact[key]; ok {
+		return true
+	}
+	for _, p := range rs.prefixes {
+		if strings.HasPrefix(key, p) {
+			return true
+		}
+	}
+	if len(rs.nets) > 0 {
+		if ip := net.ParseIP(key); ip != nil {
+			for _, n := range rs.nets {
+				if n.Contains(ip) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// RuleLimiter 在按 key 限流之前先匹配黑白名单：
+//   - 命中白名单的 key 永远放行，完全不访问 Redis（例如内部服务、监控探针）
+//   - 命中黑名单的 key 永远拒绝（同时命中时黑名单优先）
+//   - 其余 key 交给被包装的限流器判定
+//
+// 名单存放在 Redis 的两个 set 中（"<Prefix>:allow" / "<Prefix>:deny"），支持精确 key、
+// 前缀（以 "*" 结尾）与 CIDR（用于 IP key）三种写法。各实例在本地缓存名单，
+// 每隔 RefreshInterval 从 Redis 重新加载一次；加载失败时继续使用旧名单。
+//
+// RuleLimiter 实现了 RateShardedLimiter，可直接用于 httplimit / grpclimit 中间件。
+type RuleLimiter struct {
+	client  *redis.Client
+	limiter RateShardedLimiter
+
+	// Prefix 名单的 Redis key 前缀，默认 "rules"
+	Prefix string
+	// RefreshInterval 本地缓存的刷新间隔，默认 10s
+	RefreshInterval time.Duration
+	// OnError 名单加载失败时的回调（可选），默认忽略
+	OnError func(err error)
+
+	mu       sync.RWMutex
+	allow    ruleSet
+	deny     ruleSet
+	loadedAt time.Time
+
+	// refreshing 保证同一时刻只有一个调用方在刷新名单
+	refreshing sync.Mutex
+}
+
+var _ RateShardedLimiter = (*RuleLimiter)(nil)
+
+// NewRuleLimiter 为 l 包装一层黑白名单。
+func NewRuleLimiter(client *redis.Client, l RateShardedLimiter, opts ...RuleLimiterOption) *RuleLimiter {
+	if client == nil {
+		panic("rule limiter: redis client is nil")
+	}
+	if l == nil {
+		panic("rule limiter: limiter is nil")
+	}
+
+	r := &RuleLimiter{
+		client:          client,
+		limiter:         l,
+		Prefix:          "rules",
+		RefreshInterval: 10 * time.Second,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// allowKey 返回白名单 set 的 key。
+func (r *RuleLimiter) allowKey() string {
+	return r.Prefix + ":allow"
+}
+
+// denyKey 返回黑名单 set 的 key。
+func (r *RuleLimiter) denyKey() string {
+	return r.Prefix + ":deny"
+}
+
+// AddAllow 把规则加入白名单，本实例立即生效，其他实例在下一次刷新后生效。
+func (r *RuleLimiter) AddAllow(ctx context.Context, patterns ...string) error {
+	return r.update(ctx, r.allowKey(), true, patterns)
+}
+
+// RemoveAllow 从白名单中删除规则。
+func (r *RuleLimiter) RemoveAllow(ctx context.Context, patterns ...string) error {
+	return r.update(ctx, r.allowKey(), false, patterns)
+}
+
+// AddDeny 把规则加入黑名单，本实例立即生效，其他实例在下一次刷新后生效。
+func (r *RuleLimiter) AddDeny(ctx context.Context, patterns ...string) error {
+	return r.update(ctx, r.denyKey(), true, patterns)
+}
+
+// RemoveDeny 从黑名单中删除规则。
+func (r *RuleLimiter) RemoveDeny(ctx context.Context, patterns ...string) error {
+	return r.update(ctx, r.denyKey(), false, patterns)
+}
+
+// update 写入（或删除）名单中的规则，并重新加载本地缓存。
+func (r *RuleLimiter) update(ctx context.Context, key string, add bool, patterns []string) error {
+	if len(patterns) == 0 {
+		return nil
+	}
+	if add {
+		if _, err := parseRuleSet(patterns); err != nil {
+			return err
+		}
+	}
+
+	members := make([]interface{}, len(patterns))
+	for i, p := range patterns {
+		members[i] = p
+	}
+	var err error
+	if add {
+		err = r.client.SAdd(ctx, key, members...).Err()
+	} else {
+		err = r.client.SRem(ctx, key, members...).Err()
+	}
+	if err != nil {
+		return err
+	}
+	return r.Refresh(ctx)
+}
+
+// Refresh 立即从 Redis 重新加载名单。
+func (r *RuleLimiter) Refresh(ctx context.Context) error {
+	var allowCmd, denyCmd *redis.StringSliceCmd
+	_, err := r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		allowCmd = pipe.SMembers(ctx, r.allowKey())
+		denyCmd = pipe.SMembers(ctx, r.denyKey())
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	allow, err := parseRuleSet(allowCmd.Val())
+	if err != nil {
+		return err
+	}
+	deny, err := parseRuleSet(denyCmd.Val())
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	r.allow, r.deny, r.loadedAt = allow, deny, time.Now()
+	r.mu.Unlock()
+	return nil
+}
+
+// Match 返回 key 命中的名单，本地缓存过期时先刷新。
+func (r *RuleLimiter) Match(ctx context.Context, key string) RuleAction {
+	r.mu.RLock()
+	stale := time.Since(r.loadedAt) >= r.RefreshInterval
+	r.mu.RUnlock()
+
+	// 缓存过期时由一个调用方刷新，其他调用方继续使用旧名单
+	if stale && r.refreshing.TryLock() {
+		if err := r.Refresh(ctx); err != nil && r.OnError != nil {
+			r.OnError(fmt.Errorf("rule limiter: refresh: %w", err))
+		}
+		r.refreshing.Unlock()
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	switch {
+	case r.deny.match(key):
+		return RuleDeny
+	case r.allow.match(key):
+		return RuleAllow
+	default:
+		return RuleNone
+	}
+}
+
+func (r *RuleLimiter) Allow(ctx context.Context, key string) (bool, error) {
+	return r.AllowN(ctx, key, 1)
+}
+
+func (r *RuleLimiter) AllowWithResult(ctx context.Context, key string) (Result, error) {
+	switch r.Match(ctx, key) {
+	case RuleAllow:
+		return Result{Allowed: true}, nil
+	case RuleDeny:
+		return Result{}, nil
+	}
+	return r.limiter.AllowWithResult(ctx, key)
+}
+
+func (r *RuleLimiter) AllowN(ctx context.Context, key string, n int64) (bool, error) {
+	switch r.Match(ctx, key) {
+	case RuleAllow:
+		return true, nil
+	case RuleDeny:
+		return false, nil
+	}
+	return r.limiter.AllowN(ctx, key, n)
+}
+
+// Wait 命中黑名单时立即返回 ErrLimiter，命中白名单时立即返回。
+func (r *RuleLimiter) Wait(ctx context.Context, key string, maxWait time.Duration) error {
+	switch r.Match(ctx, key) {
+	case RuleAllow:
+		return nil
+	case RuleDeny:
+		return newLimitExceededError(key, "rule", Result{}, false)
+	}
+	return r.limiter.Wait(ctx, key, maxWait)
+}
+
+func (r *RuleLimiter) State(ctx context.Context, key string) (LimiterState, error) {
+	return r.limiter.State(ctx, key)
+}
+
+func (r *RuleLimiter) Reset(ctx context.Context, key string) error {
+	return r.limiter.Reset(ctx, key)
+}
+
+func (r *RuleLimiter) ResetAll(ctx context.Context) error {
+	return r.limiter.ResetAll(ctx)
+}
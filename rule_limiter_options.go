@@ -0,0 +1,31 @@
+package limiter
+
+import "time"
+
+// RuleLimiterOption 为黑白名单限流器的配置项。
+type RuleLimiterOption func(*RuleLimiter)
+
+// WithRuleLimiterPrefix 设置名单的 Redis key 前缀。
+func WithRuleLimiterPrefix(prefix string) RuleLimiterOption {
+	return func(r *RuleLimiter) {
+		if prefix != "" {
+			r.Prefix = prefix
+		}
+	}
+}
+
+// WithRuleLimiterRefreshInterval 设置本地缓存的刷新间隔。
+func WithRuleLimiterRefreshInterval(d time.Duration) RuleLimiterOption {
+	return func(r *RuleLimiter) {
+		if d > 0 {
+			r.RefreshInterval = d
+		}
+	}
+}
+
+// WithRuleLimiterErrorHandler 设置名单加载失败时的回调，例如记录日志。
+func WithRuleLimiterErrorHandler(fn func(err error)) RuleLimiterOption {
+	return func(r *RuleLimiter) {
+		r.OnError = fn
+	}
+}
@@ -0,0 +1,61 @@
+package limiter
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRuleLimiter(t *testing.T) {
+	client := newSpecClient(t)
+	ctx := context.Background()
+
+	keyed := NewKeyedLimiter(func(key string) RateLimiter {
+		return NewFixedWindowLimiter(client, key, WithFixedWindowLimit(1), WithFixedWindowWindow(time.Minute))
+	})
+	r := NewRuleLimiter(client, keyed, WithRuleLimiterRefreshInterval(time.Hour))
+
+	assert.NoError(t, r.AddAllow(ctx, "internal:*", "10.0.0.0/8"))
+	assert.NoError(t, r.AddDeny(ctx, "bad", "10.1.0.0/16"))
+	assert.Error(t, r.AddAllow(ctx, "10.0.0.0/99"))
+
+	assert.Equal(t, RuleAllow, r.Match(ctx, "internal:probe"))
+	assert.Equal(t, RuleAllow, r.Match(ctx, "10.2.3.4"))
+	assert.Equal(t, RuleDeny, r.Match(ctx, "10.1.3.4"))
+	assert.Equal(t, RuleDeny, r.Match(ctx, "bad"))
+	assert.Equal(t, RuleNone, r.Match(ctx, "user:1"))
+
+	// 白名单不受限额影响，也不在 Redis 中留下计数
+	for i := 0; i < 3; i++ {
+		ok, err := r.Allow(ctx, "internal:probe")
+		assert.NoError(t, err)
+		assert.True(t, ok)
+	}
+	n, err := client.Exists(ctx, "fw:{internal:probe}:count").Result()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), n)
+
+	ok, err := r.Allow(ctx, "bad")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	assert.True(t, errors.Is(r.Wait(ctx, "bad", time.Second), ErrLimiter))
+
+	ok, err = r.Allow(ctx, "user:1")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = r.Allow(ctx, "user:1")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	// 其他实例写入的名单在刷新后生效
+	assert.NoError(t, client.SAdd(ctx, "rules:allow", "user:1").Err())
+	assert.Equal(t, RuleNone, r.Match(ctx, "user:1"))
+	assert.NoError(t, r.Refresh(ctx))
+	assert.Equal(t, RuleAllow, r.Match(ctx, "user:1"))
+
+	assert.NoError(t, r.RemoveDeny(ctx, "bad"))
+	assert.Equal(t, RuleNone, r.Match(ctx, "bad"))
+}
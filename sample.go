@@ -0,0 +1,139 @@
+package limiter
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// SampleLimiter 按比例放行每个 key 的请求，用于功能灰度发布与按比例削峰，
+// 而不是按绝对速率限流：
+//   - Allow：每个请求按概率独立判定，适合削峰（例如高峰期只放行 30% 的推荐请求）
+//   - AllowID：按 id 的哈希确定性判定，同一个 id 的结果在所有实例上一致，
+//     且比例调大时已放行的 id 仍然放行，适合按用户灰度
+//
+// 各 key 的放行比例（百分比，0~100）存放在 Redis 的 hash 中（"<Prefix>"，field 为 key），
+// 可随时通过 SetPercent 调整。各实例在本地缓存比例，每隔 RefreshInterval 从 Redis 重新加载一次，
+// 判定本身不访问 Redis；加载失败时继续使用旧比例。
+type SampleLimiter struct {
+	client *redis.Client
+
+	// Prefix 保存放行比例的 hash key，默认 "sample"
+	Prefix string
+	// DefaultPercent 未设置比例的 key 的放行比例，默认 100（全部放行）
+	DefaultPercent float64
+	// RefreshInterval 本地缓存的刷新间隔，默认 10s
+	RefreshInterval time.Duration
+	// OnError 比例加载失败时的回调（可选），默认忽略
+	OnError func(err error)
+
+	mu       sync.RWMutex
+	percents map[string]float64
+	loadedAt time.Time
+
+	// refreshing 保证同一时刻只有一个调用方在刷新比例
+	refreshing sync.Mutex
+}
+
+// NewSampleLimiter 创建一个按比例放行的限流器。
+func NewSampleLimiter(client *redis.Client, opts ...SampleOption) *SampleLimiter {
+	if client == nil {
+		panic("sample: redis client is nil")
+	}
+
+	s := &SampleLimiter{
+		client:          client,
+		Prefix:          "sample",
+		DefaultPercent:  100,
+		RefreshInterval: 10 * time.Second,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// SetPercent 设置 key 的放行比例（0~100），本实例立即生效，其他实例在下一次刷新后生效。
+func (s *SampleLimiter) SetPercent(ctx context.Context, key string, percent float64) error {
+	if percent < 0 || percent > 100 {
+		return fmt.Errorf("sample: percent must be in [0, 100]")
+	}
+	if err := s.client.HSet(ctx, s.Prefix, key, percent).Err(); err != nil {
+		return err
+	}
+	return s.Refresh(ctx)
+}
+
+// RemovePercent 删除 key 的放行比例，之后该 key 按 DefaultPercent 放行。
+func (s *SampleLimiter) RemovePercent(ctx context.Context, key string) error {
+	if err := s.client.HDel(ctx, s.Prefix, key).Err(); err != nil {
+		return err
+	}
+	return s.Refresh(ctx)
+}
+
+// Refresh 立即从 Redis 重新加载所有 key 的放行比例。
+func (s *SampleLimiter) Refresh(ctx context.Context) error {
+	vals, err := s.client.HGetAll(ctx, s.Prefix).Result()
+	if err != nil {
+		return err
+	}
+
+	percents := make(map[string]float64, len(vals))
+	for k, v := range vals {
+		p, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return fmt.Errorf("sample: invalid percent of %q: %v", k, err)
+		}
+		percents[k] = p
+	}
+
+	s.mu.Lock()
+	s.percents, s.loadedAt = percents, time.Now()
+	s.mu.Unlock()
+	return nil
+}
+
+// Percent 返回 key 当前生效的放行比例，本地缓存过期时先刷新。
+func (s *SampleLimiter) Percent(ctx context.Context, key string) float64 {
+	s.mu.RLock()
+	stale := time.Since(s.loadedAt) >= s.RefreshInterval
+	s.mu.RUnlock()
+
+	// 缓存过期时由一个调用方刷新，其他调用方继续使用旧比例
+	if stale && s.refreshing.TryLock() {
+		if err := s.Refresh(ctx); err != nil && s.OnError != nil {
+			s.OnError(fmt.Errorf("sample: refresh: %w", err))
+		}
+		s.refreshing.Unlock()
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if p, ok := s.percents[key]; ok {
+		return p
+	}
+	return s.DefaultPercent
+}
+
+// Allow 按 key 的放行比例随机判定本次请求是否放行。
+func (s *SampleLimiter) Allow(ctx context.Context, key string) bool {
+	return rand.Float64()*100 < s.Percent(ctx, key)
+}
+
+// AllowID 按 key 与 id 的哈希确定性地判定 id 是否在放行比例内：
+// 同一个 id 在所有实例上的结果一致，比例调大时已放行的 id 仍然放行。
+func (s *SampleLimiter) AllowID(ctx context.Context, key, id string) bool {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(key))
+	_, _ = h.Write([]byte{0})
+	_, _ = h.Write([]byte(id))
+	// 以万分之一为粒度，支持 0.01% 的灰度比例
+	return float64(h.Sum64()%10000) < s.Percent(ctx, key)*100
+}
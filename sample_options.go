@@ -0,0 +1,41 @@
+package limiter
+
+import "time"
+
+// SampleOption 为按比例放行限流器的配置项。
+type SampleOption func(*SampleLimiter)
+
+// WithSamplePrefix 设置保存放行比例的 hash key。
+func WithSamplePrefix(prefix string) SampleOption {
+	return func(s *SampleLimiter) {
+		if prefix != "" {
+			s.Prefix = prefix
+		}
+	}
+}
+
+// WithSampleDefaultPercent 设置未配置比例的 key 的放行比例（0~100）。
+func WithSampleDefaultPercent(percent float64) SampleOption {
+	return func(s *SampleLimiter) {
+		if percent < 0 || percent > 100 {
+			panic("sample: percent must be in [0, 100]")
+		}
+		s.DefaultPercent = percent
+	}
+}
+
+// WithSampleRefreshInterval 设置本地缓存的刷新间隔。
+func WithSampleRefreshInterval(d time.Duration) SampleOption {
+	return func(s *SampleLimiter) {
+		if d > 0 {
+			s.RefreshInterval = d
+		}
+	}
+}
+
+// WithSampleErrorHandler 设置比例加载失败时的回调，例如记录日志。
+func WithSampleErrorHandler(fn func(err error)) SampleOption {
+	return func(s *SampleLimiter) {
+		s.OnError = fn
+	}
+}
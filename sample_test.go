@@ -0,0 +1,69 @@
+package limiter
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSampleLimiter(t *testing.T) {
+	client := newSpecClient(t)
+	ctx := context.Background()
+
+	s := NewSampleLimiter(client)
+	assert.True(t, s.Allow(ctx, "feature:new-ui"))
+	assert.Equal(t, float64(100), s.Percent(ctx, "feature:new-ui"))
+
+	assert.NoError(t, s.SetPercent(ctx, "feature:new-ui", 0))
+	assert.False(t, s.Allow(ctx, "feature:new-ui"))
+	assert.False(t, s.AllowID(ctx, "feature:new-ui", "user:1"))
+
+	// 确定性判定：同一个 id 结果稳定，且比例调大后已放行的 id 仍然放行
+	assert.NoError(t, s.SetPercent(ctx, "feature:new-ui", 20))
+	var admitted []string
+	for i := 0; i < 1000; i++ {
+		id := fmt.Sprintf("user:%d", i)
+		if s.AllowID(ctx, "feature:new-ui", id) {
+			assert.True(t, s.AllowID(ctx, "feature:new-ui", id))
+			admitted = append(admitted, id)
+		}
+	}
+	assert.InDelta(t, 200, len(admitted), 50)
+
+	assert.NoError(t, s.SetPercent(ctx, "feature:new-ui", 50))
+	for _, id := range admitted {
+		assert.True(t, s.AllowID(ctx, "feature:new-ui", id))
+	}
+
+	// 随机判定按比例放行
+	n := 0
+	for i := 0; i < 2000; i++ {
+		if s.Allow(ctx, "feature:new-ui") {
+			n++
+		}
+	}
+	assert.InDelta(t, 1000, n, 150)
+
+	assert.NoError(t, s.RemovePercent(ctx, "feature:new-ui"))
+	assert.Equal(t, float64(100), s.Percent(ctx, "feature:new-ui"))
+	assert.Error(t, s.SetPercent(ctx, "feature:new-ui", 101))
+}
+
+func TestSampleLimiter_Refresh(t *testing.T) {
+	client := newSpecClient(t)
+	ctx := context.Background()
+
+	a := NewSampleLimiter(client, WithSampleRefreshInterval(time.Hour))
+	b := NewSampleLimiter(client, WithSampleDefaultPercent(0))
+	assert.False(t, b.Allow(ctx, "shed"))
+
+	// 其他实例在刷新后看到新比例
+	assert.True(t, a.Allow(ctx, "shed"))
+	assert.NoError(t, b.SetPercent(ctx, "shed", 10))
+	assert.Equal(t, float64(100), a.Percent(ctx, "shed"))
+	assert.NoError(t, a.Refresh(ctx))
+	assert.Equal(t, float64(10), a.Percent(ctx, "shed"))
+}
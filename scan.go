@@ -0,0 +1,151 @@
+package limiter
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// KeyInfo 是 ScanKeys 找到的一个限流 key。
+type KeyInfo struct {
+	// Key 业务 key；分片 key 为去掉分片后缀的全局 key，层级限流为父 key
+	Key string
+	// Type 限流器类型，与 LimiterState.Type 一致，例如 "token_bucket"
+	Type string
+	// Shard 分片序号，非分片 key 为 -1
+	Shard int
+}
+
+// defaultKeyPrefixes 是各限流器默认的 Redis key 前缀与类型的对应关系。
+var defaultKeyPrefixes = map[string]string{
+	"tbucket": "token_bucket",
+	"lb":      "leaky_bucket",
+	"sw":      "sliding_window",
+	"swc":     "sliding_window_counter",
+	"fw":      "fixed_window",
+	"conc":    "concurrency",
+	"cmp":     "composite",
+	"hier":    "hierarchical",
+	"ovg":     "overage",
+	"quota":   "quota",
+	"ban":     "ban",
+}
+
+// shardSuffix 匹配默认分片 key 模板 "%s:shard:%d" 生成的后缀。
+var shardSuffix = regexp.MustCompile(`^(.+):shard:(\d+)$`)
+
+// KeyScanner 使用 SCAN 枚举 Redis 中当前存在的限流 key，用于运维排查“哪些 key 正在被限流”。
+//
+// 所有限流器的 Redis key 都形如 prefix:{key}:suffix，KeyScanner 按前缀识别类型、
+// 按 hash tag 解析业务 key，同一业务 key 的多个 Redis key 只返回一次。
+// 分片 key 只识别默认模板 "<key>:shard:<i>"。
+type KeyScanner struct {
+	client *redis.Client
+
+	// prefixes Redis key 前缀到限流器类型的映射，默认见 defaultKeyPrefixes
+	prefixes map[string]string
+
+	// ScanCount 每次 SCAN 的 COUNT 提示值，默认 100
+	ScanCount int64
+}
+
+// NewKeyScanner 创建一个 key 扫描器。使用了自定义前缀（With*Prefix）的限流器
+// 需要通过 WithKeyScannerPrefix 登记，否则不会被识别。
+func NewKeyScanner(client *redis.Client, opts ...KeyScannerOption) *KeyScanner {
+	if client == nil {
+		panic("key scanner: redis client is nil")
+	}
+
+	s := &KeyScanner{
+		client:    client,
+		prefixes:  make(map[string]string, len(defaultKeyPrefixes)),
+		ScanCount: 100,
+	}
+	for prefix, typ := range defaultKeyPrefixes {
+		s.prefixes[prefix] = typ
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// ScanKeys 使用默认前缀扫描业务 key 匹配 match（Redis glob 语法，空串等同 "*"）的限流 key。
+func ScanKeys(ctx context.Context, client *redis.Client, match string) ([]KeyInfo, error) {
+	return NewKeyScanner(client).ScanKeys(ctx, match)
+}
+
+// ScanKeys 扫描业务 key 匹配 match（Redis glob 语法，空串等同 "*"）的限流 key，
+// 结果按 Type、Key、Shard 排序。SCAN 会遍历整个 keyspace，key 数量很多时耗时较长。
+func (s *KeyScanner) ScanKeys(ctx context.Context, match string) ([]KeyInfo, error) {
+	if match == "" {
+		match = "*"
+	}
+
+	seen := make(map[KeyInfo]struct{})
+	var keys []KeyInfo
+
+	iter := s.client.Scan(ctx, 0, s.pattern(match), s.ScanCount).Iterator()
+	for iter.Next(ctx) {
+		info, ok := s.parse(iter.Val())
+		if !ok {
+			continue
+		}
+		if _, dup := seen[info]; dup {
+			continue
+		}
+		seen[info] = struct{}{}
+		keys = append(keys, info)
+	}
+	if err := iter.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		if a.Key != b.Key {
+			return a.Key < b.Key
+		}
+		return a.Shard < b.Shard
+	})
+	return keys, nil
+}
+
+// pattern 返回 SCAN 的 MATCH 参数；只登记了一个前缀时把前缀写进模式，减少服务端返回的无关 key。
+func (s *KeyScanner) pattern(match string) string {
+	if len(s.prefixes) == 1 {
+		for prefix := range s.prefixes {
+			return fmt.Sprintf("%s:{%s}:*", prefix, match)
+		}
+	}
+	return fmt.Sprintf("*:{%s}:*", match)
+}
+
+// parse 把 prefix:{key}:suffix 形式的 Redis key 解析为 KeyInfo，前缀未登记时返回 false。
+func (s *KeyScanner) parse(redisKey string) (KeyInfo, bool) {
+	start := strings.Index(redisKey, ":{")
+	end := strings.LastIndex(redisKey, "}:")
+	if start <= 0 || end < start+2 {
+		return KeyInfo{}, false
+	}
+	typ, ok := s.prefixes[redisKey[:start]]
+	if !ok {
+		return KeyInfo{}, false
+	}
+
+	info := KeyInfo{Key: redisKey[start+2 : end], Type: typ, Shard: -1}
+	if m := shardSuffix.FindStringSubmatch(info.Key); m != nil {
+		if shard, err := strconv.Atoi(m[2]); err == nil {
+			info.Key, info.Shard = m[1], shard
+		}
+	}
+	return info, true
+}
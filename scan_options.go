@@ -0,0 +1,24 @@
+package limiter
+
+// KeyScannerOption 为 key 扫描器的配置项。
+type KeyScannerOption func(*KeyScanner)
+
+// WithKeyScannerPrefix 登记一个自定义的 Redis key 前缀及其限流器类型，
+// 例如 WithKeyScannerPrefix("login", "sliding_window")。
+func WithKeyScannerPrefix(prefix, typ string) KeyScannerOption {
+	return func(s *KeyScanner) {
+		if prefix == "" || typ == "" {
+			panic("key scanner: prefix and type must not be empty")
+		}
+		s.prefixes[prefix] = typ
+	}
+}
+
+// WithKeyScannerCount 设置每次 SCAN 的 COUNT 提示值。
+func WithKeyScannerCount(n int64) KeyScannerOption {
+	return func(s *KeyScanner) {
+		if n > 0 {
+			s.ScanCount = n
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package limiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScanKeys(t *testing.T) {
+	client := newSpecClient(t)
+	ctx := context.Background()
+
+	_, _ = NewTokenBucketLimiter(client, "user:1").Allow(ctx)
+	_, _ = NewTokenBucketLimiter(client, "user:2", WithTokenBucketStorage(StorageHash)).Allow(ctx)
+	_, _ = NewSlidingWindowLimiter(client, "user:1").Allow(ctx)
+	_, _ = NewFixedWindowLimiter(client, "login", WithFixedWindowPrefix("auth"), WithFixedWindowWindow(time.Minute)).Allow(ctx)
+	sharded := NewShardedTokenBucketLimiter(client, "users", WithShardedTokenBucketCount(4))
+	_, _ = sharded.Allow(ctx, "u1")
+	_, _ = NewTokenBucketLimiter(client, "user:1", WithTokenBucketStats(time.Minute)).Allow(ctx)
+
+	keys, err := ScanKeys(ctx, client, "user*")
+	assert.NoError(t, err)
+	assert.Equal(t, []KeyInfo{
+		{Key: "user:1", Type: "sliding_window", Shard: -1},
+		{Key: "user:1", Type: "token_bucket", Shard: -1},
+		{Key: "user:2", Type: "token_bucket", Shard: -1},
+		{Key: "users", Type: "token_bucket", Shard: sharded.pick("u1")},
+	}, keys)
+
+	// 自定义前缀需要登记后才能识别
+	keys, err = ScanKeys(ctx, client, "login")
+	assert.NoError(t, err)
+	assert.Empty(t, keys)
+
+	keys, err = NewKeyScanner(client, WithKeyScannerPrefix("auth", "fixed_window")).ScanKeys(ctx, "")
+	assert.NoError(t, err)
+	assert.Len(t, keys, 5)
+	assert.Contains(t, keys, KeyInfo{Key: "login", Type: "fixed_window", Shard: -1})
+}
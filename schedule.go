@@ -0,0 +1,331 @@
+package limiter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// ScheduleWindow 是时间表中的一个时段：在 Days 的 [Start, End) 内使用 Limits。
+type ScheduleWindow struct {
+	// Days 生效的星期（0 为周日），为空表示每天
+	Days []time.Weekday `json:"days,omitempty" yaml:"days,omitempty"`
+	// Start 开始时间，"HH:MM" 格式
+	Start string `json:"start" yaml:"start"`
+	// End 结束时间（不含），"HH:MM" 格式；不大于 Start 时表示跨午夜，Days 按开始的那一天判断
+	End string `json:"end" yaml:"end"`
+	// Limits 该时段使用的限流参数
+	Limits LimitConfig `json:"limits" yaml:"limits"`
+}
+
+// Schedule 是按一天中的时段切换限流参数的时间表，例如工作时间 1000/s、夜间 200/s。
+// 多个时段重叠时排在前面的优先，不在任何时段内时使用 Default。
+type Schedule struct {
+	// Timezone 时段所在的时区（IANA 名称，如 "Asia/Shanghai"），默认 UTC
+	Timezone string `json:"timezone,omitempty" yaml:"timezone,omitempty"`
+	// Default 不在任何时段内时使用的限流参数，需要覆盖各时段修改过的全部字段
+	Default LimitConfig `json:"default" yaml:"default"`
+	// Windows 时段列表
+	Windows []ScheduleWindow `json:"windows,omitempty" yaml:"windows,omitempty"`
+}
+
+// compiledWindow 是解析后的时段，start / end 为当天的第几分钟。
+type compiledWindow struct {
+	days       map[time.Weekday]bool
+	start, end int
+	limits     LimitConfig
+}
+
+// compiledSchedule 是解析后的时间表。
+type compiledSchedule struct {
+	loc     *time.Location
+	def     LimitConfig
+	windows []compiledWindow
+}
+
+// compile 校验并解析时间表。
+func (s Schedule) compile() (*compiledSchedule, error) {
+	loc := time.UTC
+	if s.Timezone != "" {
+		var err error
+		if loc, err = time.LoadLocation(s.Timezone); err != nil {
+			return nil, fmt.Errorf("schedule: %v", err)
+		}
+	}
+	if err := s.Default.validate(); err != nil {
+		return nil, fmt.Errorf("schedule: default: %w", err)
+	}
+	// Reconfigure 忽略零值字段，Default 为空时离开时段后参数不会恢复
+	if s.Default == (LimitConfig{}) && len(s.Windows) > 0 {
+		return nil, fmt.Errorf("schedule: default limits are empty")
+	}
+
+	cs := &compiledSchedule{loc: loc, def: s.Default}
+	for i, w := range s.Windows {
+		start, err := parseClock(w.Start)
+		if err != nil {
+			return nil, fmt.Errorf("schedule: window %d: %w", i, err)
+		}
+		end, err := parseClock(w.End)
+		if err != nil {
+			return nil, fmt.Errorf("schedule: window %d: %w", i, err)
+		}
+		if err := w.Limits.validate(); err != nil {
+			return nil, fmt.Errorf("schedule: window %d: %w", i, err)
+		}
+		cw := compiledWindow{start: start, end: end, limits: w.Limits}
+		if len(w.Days) > 0 {
+			cw.days = make(map[time.Weekday]bool, len(w.Days))
+			for _, d := range w.Days {
+				cw.days[d] = true
+			}
+		}
+		cs.windows = append(cs.windows, cw)
+	}
+	return cs, nil
+}
+
+// parseClock 把 "HH:MM" 解析为当天的第几分钟。
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// Active 返回 t 时刻生效的限流参数。
+func (s Schedule) Active(t time.Time) (LimitConfig, error) {
+	cs, err := s.compile()
+	if err != nil {
+		return LimitConfig{}, err
+	}
+	_, c := cs.active(t)
+	return c, nil
+}
+
+// active 返回 t 时刻生效的时段下标（-1 表示 Default）及其限流参数。
+func (cs *compiledSchedule) active(t time.Time) (int, LimitConfig) {
+	t = t.In(cs.loc)
+	minute := t.Hour()*60 + t.Minute()
+	today := t.Weekday()
+	yesterday := (today + 6) % 7
+
+	for i, w := range cs.windows {
+		var in bool
+		switch {
+		case w.start < w.end:
+			in = minute >= w.start && minute < w.end && w.onDay(today)
+		default:
+			// 跨午夜：开始当天的 [start, 24:00) 或开始次日的 [00:00, end)
+			in = (minute >= w.start && w.onDay(today)) || (minute < w.end && w.onDay(yesterday))
+		}
+		if in {
+			return i, w.limits
+		}
+	}
+	return -1, cs.def
+}
+
+// onDay 判断时段是否在 d 这一天开始。
+func (w compiledWindow) onDay(d time.Weekday) bool {
+	return w.days == nil || w.days[d]
+}
+
+// ScheduledTarget 是可以被 ScheduledLimiter 按时间表调整参数的限流器，
+// 例如 TokenBucketLimiter、LeakyBucketLimiter、SingleSlidingWindowLimiter。
+type ScheduledTarget interface {
+	RateLimiter
+	Reconfigurable
+}
+
+// scheduleUnapplied 表示时间表还没有应用到限流器上。
+const scheduleUnapplied = -2
+
+// ScheduledLimiter 按时间表在不同时段切换被包装限流器的参数，例如工作时间 1000/s、夜间 200/s。
+//
+// 每次调用时用本地时钟判断当前时段，时段变化时通过 Reconfigure 替换参数，不额外访问 Redis。
+// 配置 Key 后时间表以 JSON 存放在 Redis 中（PublishSchedule 写入），各实例每隔 RefreshInterval 重新加载一次，
+// 保证所有实例使用同一份时间表；加载失败时继续使用旧时间表。
+//
+// 被包装的限流器不应再由 ConfigWatcher 修改参数，否则两者会互相覆盖。
+type ScheduledLimiter struct {
+	limiter ScheduledTarget
+	client  *redis.Client
+
+	// Key 存放时间表 JSON 的 Redis key（可选），为空表示只使用本地时间表
+	Key string
+	// RefreshInterval 从 Redis 重新加载时间表的间隔，默认 1 分钟
+	RefreshInterval time.Duration
+	// Clock 时钟，默认 SystemClock
+	Clock Clock
+	// OnError 时间表加载或参数应用失败时的回调（可选），默认忽略
+	OnError func(err error)
+
+	mu       sync.Mutex
+	schedule *compiledSchedule
+	active   int
+	loadedAt time.Time
+
+	// refreshing 保证同一时刻只有一个调用方在重新加载时间表
+	refreshing sync.Mutex
+}
+
+var _ RateLimiter = (*ScheduledLimiter)(nil)
+
+// NewScheduledLimiter 按 schedule 调整 l 的参数；时间表无效时 panic。
+func NewScheduledLimiter(l ScheduledTarget, schedule Schedule, opts ...ScheduledOption) *ScheduledLimiter {
+	if l == nil {
+		panic("schedule: limiter is nil")
+	}
+	cs, err := schedule.compile()
+	if err != nil {
+		panic(err.Error())
+	}
+
+	s := &ScheduledLimiter{
+		limiter:         l,
+		RefreshInterval: time.Minute,
+		Clock:           SystemClock,
+		schedule:        cs,
+		active:          scheduleUnapplied,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.Key != "" && s.client == nil {
+		panic("schedule: redis client is nil")
+	}
+	return s
+}
+
+// SetSchedule 替换本实例的时间表，立即生效。
+func (s *ScheduledLimiter) SetSchedule(schedule Schedule) error {
+	cs, err := schedule.compile()
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.schedule, s.active = cs, scheduleUnapplied
+	s.mu.Unlock()
+	return nil
+}
+
+// PublishSchedule 把时间表写入 Redis 并在本实例立即生效，其他实例在下一次加载后生效。
+func (s *ScheduledLimiter) PublishSchedule(ctx context.Context, schedule Schedule) error {
+	if s.Key == "" {
+		return fmt.Errorf("schedule: redis key is not configured")
+	}
+	if _, err := schedule.compile(); err != nil {
+		return err
+	}
+	data, err := json.Marshal(schedule)
+	if err != nil {
+		return err
+	}
+	if err := s.client.Set(ctx, s.Key, data, 0).Err(); err != nil {
+		return err
+	}
+	return s.Refresh(ctx)
+}
+
+// Refresh 立即从 Redis 重新加载时间表；Key 为空或 Redis 中还没有时间表时保持当前时间表。
+func (s *ScheduledLimiter) Refresh(ctx context.Context) error {
+	if s.Key == "" {
+		return nil
+	}
+	data, err := s.client.Get(ctx, s.Key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		s.mu.Lock()
+		s.loadedAt = time.Now()
+		s.mu.Unlock()
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var schedule Schedule
+	if err := json.Unmarshal(data, &schedule); err != nil {
+		return fmt.Errorf("schedule: %v", err)
+	}
+	cs, err := schedule.compile()
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.schedule, s.active, s.loadedAt = cs, scheduleUnapplied, time.Now()
+	s.mu.Unlock()
+	return nil
+}
+
+// Active 返回当前生效的限流参数，时段变化时先把参数应用到被包装的限流器上。
+func (s *ScheduledLimiter) Active(ctx context.Context) LimitConfig {
+	if s.Key != "" {
+		s.mu.Lock()
+		stale := time.Since(s.loadedAt) >= s.RefreshInterval
+		s.mu.Unlock()
+
+		// 缓存过期时由一个调用方重新加载，其他调用方继续使用旧时间表
+		if stale && s.refreshing.TryLock() {
+			if err := s.Refresh(ctx); err != nil {
+				s.report(fmt.Errorf("schedule: refresh: %w", err))
+			}
+			s.refreshing.Unlock()
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	idx, c := s.schedule.active(s.Clock.Now())
+	if idx != s.active {
+		if err := s.limiter.Reconfigure(c); err != nil {
+			s.report(fmt.Errorf("schedule: reconfigure: %w", err))
+		} else {
+			s.active = idx
+		}
+	}
+	return c
+}
+
+// report 把错误交给 OnError。
+func (s *ScheduledLimiter) report(err error) {
+	if s.OnError != nil {
+		s.OnError(err)
+	}
+}
+
+func (s *ScheduledLimiter) Allow(ctx context.Context) (bool, error) {
+	s.Active(ctx)
+	return s.limiter.Allow(ctx)
+}
+
+func (s *ScheduledLimiter) AllowWithResult(ctx context.Context) (Result, error) {
+	s.Active(ctx)
+	return s.limiter.AllowWithResult(ctx)
+}
+
+func (s *ScheduledLimiter) AllowN(ctx context.Context, n int64) (bool, error) {
+	s.Active(ctx)
+	return s.limiter.AllowN(ctx, n)
+}
+
+func (s *ScheduledLimiter) Wait(ctx context.Context, maxWait time.Duration) error {
+	s.Active(ctx)
+	return s.limiter.Wait(ctx, maxWait)
+}
+
+func (s *ScheduledLimiter) State(ctx context.Context) (LimiterState, error) {
+	s.Active(ctx)
+	return s.limiter.State(ctx)
+}
+
+func (s *ScheduledLimiter) Reset(ctx context.Context) error {
+	return s.limiter.Reset(ctx)
+}
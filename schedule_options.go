@@ -0,0 +1,43 @@
+package limiter
+
+import (
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// ScheduledOption 为按时间表切换参数的限流器的配置项。
+type ScheduledOption func(*ScheduledLimiter)
+
+// WithScheduledRedis 把时间表存放在 Redis 的 key 中，所有实例共享同一份时间表，见 PublishSchedule。
+func WithScheduledRedis(client *redis.Client, key string) ScheduledOption {
+	return func(s *ScheduledLimiter) {
+		s.client = client
+		s.Key = key
+	}
+}
+
+// WithScheduledRefreshInterval 设置从 Redis 重新加载时间表的间隔。
+func WithScheduledRefreshInterval(d time.Duration) ScheduledOption {
+	return func(s *ScheduledLimiter) {
+		if d > 0 {
+			s.RefreshInterval = d
+		}
+	}
+}
+
+// WithScheduledClock 设置判断当前时段使用的时钟，通常用于测试。
+func WithScheduledClock(c Clock) ScheduledOption {
+	return func(s *ScheduledLimiter) {
+		if c != nil {
+			s.Clock = c
+		}
+	}
+}
+
+// WithScheduledErrorHandler 设置时间表加载或参数应用失败时的回调，例如记录日志。
+func WithScheduledErrorHandler(fn func(err error)) ScheduledOption {
+	return func(s *ScheduledLimiter) {
+		s.OnError = fn
+	}
+}
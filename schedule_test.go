@@ -0,0 +1,91 @@
+package limiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSchedule_Active(t *testing.T) {
+	s := Schedule{
+		Timezone: "Asia/Shanghai",
+		Default:  LimitConfig{Rate: 200, Capacity: 200},
+		Windows: []ScheduleWindow{
+			{
+				Days:   []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday},
+				Start:  "09:00",
+				End:    "18:00",
+				Limits: LimitConfig{Rate: 1000, Capacity: 1000},
+			},
+			// 周五晚上到周六早上的批处理窗口，跨午夜
+			{Days: []time.Weekday{time.Friday}, Start: "22:00", End: "06:00", Limits: LimitConfig{Rate: 50, Capacity: 50}},
+		},
+	}
+	loc, err := time.LoadLocation("Asia/Shanghai")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	cases := map[string]struct {
+		at   time.Time
+		rate float64
+	}{
+		"weekday business hours": {time.Date(2026, 1, 5, 9, 0, 0, 0, loc), 1000}, // 周一
+		"weekday evening":        {time.Date(2026, 1, 5, 18, 0, 0, 0, loc), 200},
+		"weekend daytime":        {time.Date(2026, 1, 10, 10, 0, 0, 0, loc), 200}, // 周六
+		"friday night":           {time.Date(2026, 1, 9, 23, 30, 0, 0, loc), 50},
+		"saturday early morning": {time.Date(2026, 1, 10, 5, 59, 0, 0, loc), 50},
+		"sunday early morning":   {time.Date(2026, 1, 11, 5, 0, 0, 0, loc), 200},
+		"utc instant":            {time.Date(2026, 1, 5, 2, 0, 0, 0, time.UTC), 1000}, // 北京时间 10:00
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			cfg, err := s.Active(c.at)
+			assert.NoError(t, err)
+			assert.Equal(t, c.rate, cfg.Rate)
+		})
+	}
+
+	_, err = Schedule{Windows: []ScheduleWindow{{Start: "9:00", End: "18:00"}}}.Active(time.Now())
+	assert.Error(t, err)
+	_, err = Schedule{Default: LimitConfig{Rate: 1}, Windows: []ScheduleWindow{{Start: "25:00", End: "18:00"}}}.Active(time.Now())
+	assert.Error(t, err)
+}
+
+func TestScheduledLimiter(t *testing.T) {
+	client := newSpecClient(t)
+	ctx := context.Background()
+
+	now := time.Date(2026, 1, 5, 8, 59, 0, 0, time.UTC)
+	clock := ClockFunc(func() time.Time { return now })
+	schedule := Schedule{
+		Default: LimitConfig{Rate: 200, Capacity: 200},
+		Windows: []ScheduleWindow{{Start: "09:00", End: "18:00", Limits: LimitConfig{Rate: 1000, Capacity: 1000}}},
+	}
+
+	tb := NewTokenBucketLimiter(client, "scheduled", WithTokenBucketRate(1), WithTokenBucketCapacity(1))
+	s := NewScheduledLimiter(tb, schedule, WithScheduledClock(clock), WithScheduledRedis(client, "schedule:api"))
+
+	ok, err := s.Allow(ctx)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, float64(200), tb.Capacity)
+
+	now = now.Add(time.Minute)
+	assert.Equal(t, float64(1000), s.Active(ctx).Rate)
+	assert.Equal(t, float64(1000), tb.Rate)
+
+	// 通过 Redis 下发新的时间表，其他实例刷新后生效
+	other := NewScheduledLimiter(NewTokenBucketLimiter(client, "scheduled"), schedule,
+		WithScheduledClock(clock), WithScheduledRedis(client, "schedule:api"))
+	schedule.Windows[0].Limits = LimitConfig{Rate: 500, Capacity: 500}
+	assert.NoError(t, other.PublishSchedule(ctx, schedule))
+	assert.Equal(t, float64(500), other.Active(ctx).Rate)
+
+	assert.Equal(t, float64(1000), s.Active(ctx).Rate)
+	assert.NoError(t, s.Refresh(ctx))
+	assert.Equal(t, float64(500), s.Active(ctx).Rate)
+	assert.Equal(t, float64(500), tb.Capacity)
+}
@@ -0,0 +1,162 @@
+package limiter
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// scripts 为本包全部 Lua 脚本的注册表，key 为脚本名称。
+// 新增脚本时需要同步登记，ScriptManager 才能预加载它。
+var scripts = map[string]*redis.Script{
+	"token_bucket":           tokenBucketScript,
+	"token_bucket_refund":    tokenBucketRefundScript,
+	"hierarchical":           hierarchicalScript,
+	"leaky_bucket":           leakyBucketScript,
+	"leaky_bucket_refund":    leakyBucketRefundScript,
+	"sliding_window":         slidingWindowScript,
+	"sliding_window_state":   slidingWindowStateScript,
+	"sliding_window_counter": slidingWindowCounterScript,
+	"fixed_window":           fixedWindowScript,
+	"concurrency_acquire":    concurrencyAcquireScript,
+	"concurrency_extend":     concurrencyExtendScript,
+	"concurrency_state":      concurrencyStateScript,
+	"composite":              compositeScript,
+	"overage":                overageScript,
+	"adaptive":               adaptiveScript,
+	"quota":                  quotaScript,
+	"quota_adjust":           quotaAdjustScript,
+	"multi":                  multiScript,
+	"ban":                    banScript,
+	"fair_queue":             fairQueueScript,
+	"once":                   onceScript,
+	"cardinality":            cardinalityScript,
+	"bucket_state":           bucketStateScript,
+}
+
+// ScriptHashes 返回全部脚本名称到 SHA1 的映射，
+// 便于在其他包中编写 redismock 的 ExpectEvalSha 断言。
+func ScriptHashes() map[string]string {
+	out := make(map[string]string, len(scripts))
+	for name, s := range scripts {
+		out[name] = s.Hash()
+	}
+	return out
+}
+
+// ScriptManager 负责把全部 Lua 脚本预加载到 Redis：
+//   - Load 在启动时执行 SCRIPT LOAD，避免 Redis 重启后的第一次调用传输完整脚本
+//   - Ensure 通过 SCRIPT EXISTS 检查并补齐缺失的脚本，适合在主从切换后调用
+//   - Watch 按固定间隔调用 Ensure
+//
+// 各限流器执行脚本时总是先 EVALSHA，遇到 NOSCRIPT 再退回 EVAL（见 Run），
+// 因此即使未预加载，也只会有一次额外的往返。
+type ScriptManager struct {
+	client redis.Scripter
+
+	// OnError Watch 中 Ensure 失败时的回调（可选）
+	OnError func(err error)
+}
+
+// NewScriptManager 创建一个脚本管理器。client 可以是 *redis.Client 或 *redis.ClusterClient，
+// 后者的 SCRIPT LOAD 会下发到所有主节点。
+func NewScriptManager(client redis.Scripter, opts ...ScriptManagerOption) *ScriptManager {
+	if client == nil {
+		panic("script manager: redis client is nil")
+	}
+	m := &ScriptManager{client: client}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// names 返回排序后的脚本名称，保证加载顺序稳定。
+func (m *ScriptManager) names() []string {
+	return scriptNames()
+}
+
+// scriptNames 返回排序后的全部脚本名称。
+func scriptNames() []string {
+	names := make([]string, 0, len(scripts))
+	for name := range scripts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Hashes 返回全部脚本名称到 SHA1 的映射。
+func (m *ScriptManager) Hashes() map[string]string {
+	return ScriptHashes()
+}
+
+// Load 对全部脚本执行 SCRIPT LOAD。
+func (m *ScriptManager) Load(ctx context.Context) error {
+	for _, name := range m.names() {
+		if err := scripts[name].Load(ctx, m.client).Err(); err != nil {
+			return fmt.Errorf("script manager: load %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// Ensure 检查全部脚本是否已加载，只对缺失的脚本执行 SCRIPT LOAD，返回补齐的脚本数量。
+func (m *ScriptManager) Ensure(ctx context.Context) (int, error) {
+	names := m.names()
+	hashes := make([]string, len(names))
+	for i, name := range names {
+		hashes[i] = scripts[name].Hash()
+	}
+
+	exists, err := m.client.ScriptExists(ctx, hashes...).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	loaded := 0
+	for i, ok := range exists {
+		if ok {
+			continue
+		}
+		if err := scripts[names[i]].Load(ctx, m.client).Err(); err != nil {
+			return loaded, fmt.Errorf("script manager: load %s: %w", names[i], err)
+		}
+		loaded++
+	}
+	return loaded, nil
+}
+
+// Watch 每隔 interval 调用一次 Ensure，直到 ctx 取消。出错时调用 OnError 并继续。
+func (m *ScriptManager) Watch(ctx context.Context, interval time.Duration) error {
+	if interval <= 0 {
+		return fmt.Errorf("script manager: interval must > 0")
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if _, err := m.Ensure(ctx); err != nil && m.OnError != nil {
+				m.OnError(err)
+			}
+		}
+	}
+}
+
+// Run 按名称执行脚本：先 EVALSHA，遇到 NOSCRIPT 时退回 EVAL。
+func (m *ScriptManager) Run(ctx context.Context, name string, keys []string, args ...interface{}) *redis.Cmd {
+	s, ok := scripts[name]
+	if !ok {
+		cmd := redis.NewCmd(ctx)
+		cmd.SetErr(fmt.Errorf("script manager: unknown script %q", name))
+		return cmd
+	}
+	return s.Run(ctx, m.client, keys, args...)
+}
@@ -0,0 +1,11 @@
+package limiter
+
+// ScriptManagerOption 为脚本管理器的配置项。
+type ScriptManagerOption func(*ScriptManager)
+
+// WithScriptManagerErrorHandler 设置 Watch 中补齐脚本失败时的回调，例如记录日志。
+func WithScriptManagerErrorHandler(fn func(err error)) ScriptManagerOption {
+	return func(m *ScriptManager) {
+		m.OnError = fn
+	}
+}
@@ -0,0 +1,35 @@
+package limiter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScriptManager(t *testing.T) {
+	client := newSpecClient(t)
+	ctx := context.Background()
+
+	m := NewScriptManager(client)
+	assert.Equal(t, tokenBucketScript.Hash(), m.Hashes()["token_bucket"])
+
+	assert.NoError(t, m.Load(ctx))
+	n, err := m.Ensure(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+
+	// 模拟 Redis 重启 / 主从切换后脚本缓存丢失
+	assert.NoError(t, client.ScriptFlush(ctx).Err())
+	n, err = m.Ensure(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, len(scripts), n)
+
+	// NOSCRIPT 时退回 EVAL
+	assert.NoError(t, client.ScriptFlush(ctx).Err())
+	res, err := m.Run(ctx, "fixed_window", []string{"fw:{m}:count"}, 1000, 1, 1).Result()
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{int64(1), int64(0), int64(0)}, res)
+
+	assert.Error(t, m.Run(ctx, "missing", nil).Err())
+}
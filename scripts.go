@@ -1,6 +1,125 @@
 package limiter
 
-import "github.com/go-redis/redis/v8"
+// luaJitterTTL 是各脚本共用的 Lua 片段，用于给 key 的 TTL 增加 [0, ratio] 的抖动。
+// 抖动只延长不缩短：TTL 通常按状态自然恢复所需的时间设置（例如滑动窗口的窗口长度），
+// 提前过期会丢失仍然有效的限流状态。
+// 抖动因子由 sha1(seed) 推导：同一 key 在同一毫秒内结果稳定（便于复现），
+// 不同 key 之间则均匀分散，避免大批 key 在同一时刻集中过期。
+const luaJitterTTL = `
+local function jitterTTL(ttl, ratio, seed)
+  if ratio == nil or ratio <= 0 then
+    return ttl
+  end
+  local h = tonumber(string.sub(redis.sha1hex(seed), 1, 8), 16)
+  -- 映射到 [0, 1]
+  local factor = h / 4294967295
+  return math.floor(ttl * (1 + ratio * factor))
+end
+`
+
+// luaServerTime 是各脚本共用的 Lua 片段，用于确定“当前时间”：
+// 调用方传入的 nowMs > 0 时直接使用（应用时钟）；否则调用 Redis TIME，
+// 所有应用实例共享同一个时钟源，不受各自机器时钟偏差的影响。
+// TIME 是非确定性命令，Redis 5 以前需要先调用 replicate_commands() 切换为按效果复制，
+// Redis 7 起该调用为空操作。
+const luaServerTime = `
+local function resolveNow(now)
+  if now ~= nil and now > 0 then
+    return now
+  end
+  if redis.replicate_commands ~= nil then
+    redis.replicate_commands()
+  end
+  local t = redis.call("TIME")
+  return tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+end
+`
+
+// luaBucketState 是令牌桶 / 漏桶共用的 Lua 片段，负责读写桶内数值与上次更新时间：
+//   - KEYS[1] ~= KEYS[2]：两个 string key（StorageString）
+//   - KEYS[1] == KEYS[2]：同一个 hash 的 field 与 "ts" 两个字段（StorageHash）
+const luaBucketState = `
+local hashState = KEYS[1] == KEYS[2]
+
+local function loadState(field)
+  if hashState then
+    local v = redis.call("HMGET", KEYS[1], field, "ts")
+    return tonumber(v[1]), tonumber(v[2])
+  end
+  return tonumber(redis.call("GET", KEYS[1])), tonumber(redis.call("GET", KEYS[2]))
+end
+
+local function saveState(field, value, ts, ttl)
+  if hashState then
+    redis.call("HSET", KEYS[1], field, value, "ts", ts)
+    redis.call("PEXPIRE", KEYS[1], ttl)
+    return
+  end
+  redis.call("SET", KEYS[1], value, "PX", ttl)
+  redis.call("SET", KEYS[2], ts, "PX", ttl)
+end
+`
+
+// bucketStateScript 只读地一次取出令牌桶 / 漏桶 State 所需的全部数据，避免多次 GET 之间状态被并发修改：
+//   - KEYS[1], KEYS[2]：桶内数值与上次更新时间（同 luaBucketState）
+//   - 其后依次为可选的覆盖配置 hash（ARGV[2] == "1"）与统计计数器 allowed / denied（ARGV[3] == "1"）
+//   - ARGV[1]：hash 存储模式下桶内数值的字段名（tokens / level）
+//   - ARGV[4]：当前时间（毫秒；0 表示使用 Redis TIME），与限流脚本使用同一个时钟源
+//
+// 返回 {value, ts, overrideRate, overrideCapacity, allowed, denied, grant, now}，不存在的值为 nil；
+// grant 为令牌桶最小间隔模式记录的上次放行时间，仅 hash 存储模式下读取；now 为计算状态所用的当前时间。
+// 所有 key 共用 {key} 作为 hash tag，Redis Cluster 中同样是一次往返。
+var bucketStateScript = newScript(luaServerTime + `
+local now = resolveNow(tonumber(ARGV[4]))
+
+local vals
+if KEYS[1] == KEYS[2] then
+  vals = redis.call("HMGET", KEYS[1], ARGV[1], "ts", "grant")
+else
+  vals = {redis.call("GET", KEYS[1]), redis.call("GET", KEYS[2]), false}
+end
+
+local n = 3
+local override = {false, false}
+if ARGV[2] == "1" then
+  override = redis.call("HMGET", KEYS[n], "rate", "capacity")
+  n = n + 1
+end
+
+local stats = {false, false}
+if ARGV[3] == "1" then
+  stats = {redis.call("GET", KEYS[n]), redis.call("GET", KEYS[n + 1])}
+end
+
+return {vals[1], vals[2], override[1], override[2], stats[1], stats[2], vals[3], now}
+`)
+
+// luaLimitOverride 是令牌桶/漏桶共用的 Lua 片段，用于读取按 key 覆盖的限流参数。
+// 调用方开启覆盖模式时会额外传入 KEYS[3]（覆盖配置 hash，字段 rate / capacity），
+// hash 中存在的字段优先于 ARGV 中的默认值；未开启时 KEYS[3] 为空，脚本行为不变。
+// 覆盖模式下的返回值会在末尾追加实际生效的容量（向下取整）。
+const luaLimitOverride = `
+local function limitOverride(rate, capacity)
+  if KEYS[3] == nil then
+    return rate, capacity
+  end
+  local o = redis.call("HMGET", KEYS[3], "rate", "capacity")
+  if o[1] then
+    rate = tonumber(o[1])
+  end
+  if o[2] then
+    capacity = tonumber(o[2])
+  end
+  return rate, capacity
+end
+
+local function withLimit(reply, capacity)
+  if KEYS[3] ~= nil then
+    reply[#reply + 1] = math.floor(capacity)
+  end
+  return reply
+end
+`
 
 // tokenBucketScript 使用 Redis + Lua 实现原子化令牌桶逻辑：
 //   - 支持毫秒级 refill
@@ -8,54 +127,318 @@ import "github.com/go-redis/redis/v8"
 //   - 不足时拒绝并不修改状态
 //
 // KEYS[1] = tokensKey（当前 token 数，浮点数）
-// KEYS[2] = tsKey    （上次更新时间，毫秒时间戳）
+// KEYS[2] = tsKey    （上次更新时间，毫秒时间戳；与 KEYS[1] 相同时表示 hash 存储，见 luaBucketState）
+// KEYS[3] = overrideKey（可选，按 key 覆盖 rate / capacity 的 hash）
 //
-// ARGV[1] = nowMs    （当前时间，毫秒）
+// ARGV[1] = nowMs    （当前时间，毫秒；0 表示使用 Redis TIME）
 // ARGV[2] = rate     （生成速率，token/sec）
 // ARGV[3] = capacity （桶容量）
 // ARGV[4] = req      （本次请求需要的 token 数，通常为 1）
 // ARGV[5] = ttlMs    （key 过期时间，毫秒，用于清理闲置 key）
-var tokenBucketScript = redis.NewScript(`
+// ARGV[6] = jitter   （TTL 抖动比例，0 表示不抖动）
+// ARGV[7] = dryRun   （可选，1 表示只判定不扣减，放行时 remaining 为当前 token 数）
+// ARGV[8] = lend     （可选，可用比例 0~1：本次判定至少保留 capacity*(1-lend) 个 token，用于分片借用与优先级预留）
+// ARGV[9] = warmUpMs （可选，预热时长，毫秒；仅 hash 存储可用，预热起点记录在 warm 字段）
+// ARGV[10] = initial （可选，新桶的初始 token 数，不超过 capacity；缺省为满桶）
+// ARGV[11] = debt    （可选，允许透支的 token 数：token 数最低可以降到 -debt，由后续 refill 偿还）
+// ARGV[12] = minIntervalMs（可选，两次放行之间的最小间隔，毫秒；仅 hash 存储可用，上次放行时间记录在 grant 字段）
+//
+// 统计与按请求 ID 去重的参数追加在末尾，见 withStats / withIdempotency。
+//
+// 预热：桶闲置超过 warmUpMs（或首次使用）后视为“冷启动”，最多保留 capacity/3 个 token，
+// 补充速率从 rate/3 起在 warmUpMs 内线性爬升到 rate，避免闲置后流量一涌而入。
+//
+// 返回：{allowed, remaining, retryAfterMs, skewMs[, capacity]}
+//   - remaining：判定后还能放行的 token 数，即桶内 token 数加上剩余的透支额度（向下取整）
+//   - retryAfterMs：被拒绝时补足 req 个 token 还需等待的毫秒数，放行时为 0
+//   - skewMs：调用方时钟相对已记录时间戳的回拨量（毫秒）
+var tokenBucketScript = newScript(withIdempotency(withStats(luaServerTime + luaJitterTTL + luaBucketState + luaLimitOverride + `
 local tokensKey = KEYS[1]
-local tsKey     = KEYS[2]
 
-local now      = tonumber(ARGV[1])
+local now      = resolveNow(tonumber(ARGV[1]))
 local rate     = tonumber(ARGV[2])
 local capacity = tonumber(ARGV[3])
 local req      = tonumber(ARGV[4])
 local ttl      = tonumber(ARGV[5])
+local jitter   = tonumber(ARGV[6])
+local dryRun   = ARGV[7] == "1"
+local lend     = tonumber(ARGV[8])
+local warmUp   = tonumber(ARGV[9]) or 0
+local initial  = tonumber(ARGV[10])
+local debt     = tonumber(ARGV[11]) or 0
+local minGap   = tonumber(ARGV[12]) or 0
 
--- 当前 token 数（第一次使用则默认为满桶）
-local tokens = tonumber(redis.call("GET", tokensKey)) or capacity
+-- 冷启动时的速率/容量比例（与 Guava SmoothWarmingUp 的 coldFactor 3 一致）
+local cold = 1 / 3
+
+rate, capacity = limitOverride(rate, capacity)
+
+-- 新桶（首次使用或 key 已过期）的初始 token 数，默认满桶
+if initial == nil or initial > capacity then
+  initial = capacity
+end
+
+-- 借出模式下需要为本桶自身的流量保留的 token 数
+local reserve = 0
+if lend ~= nil then
+  reserve = capacity * (1 - lend)
+end
+
+local tokens, lastTs = loadState("tokens")
+local fresh = tokens == nil
+-- 当前 token 数为空表示第一次使用，refill 时按 initial 处理
 -- 上次更新时间（第一次使用则认为“当前时间”）
-local lastTs = tonumber(redis.call("GET", tsKey)) or now
+lastTs = lastTs or now
+
+-- 时钟回拨保护：时间戳只增不减。
+-- 若调用方时钟落后于 Redis 中记录的最大时间戳，则按 lastTs 计算，
+-- 并把回拨量返回给调用方；这样一个时钟偏慢的客户端不会把 ts 写小，
+-- 进而让其他客户端多 refill。
+local skew = 0
+if now < lastTs then
+  skew = lastTs - now
+  now = lastTs
+end
 
 -- 计算从 lastTs 到 now 的时间差（毫秒）
 local delta = now - lastTs
-if delta < 0 then
-  delta = 0
-end
 
 -- 根据时间差进行 refill：newTokens = rate * delta / 1000
 local refill = (delta * rate) / 1000
-tokens = tokens + refill
+
+-- 预热模式：冷启动时重置预热起点，预热期内按爬升中的速率积分 refill
+local warm = nil
+local curRate = rate
+if warmUp > 0 then
+  warm = tonumber(redis.call("HGET", tokensKey, "warm"))
+  if tokens == nil or delta >= warmUp then
+    warm = now
+    tokens = math.min((tokens or initial) + refill, capacity * cold)
+    refill = 0
+  else
+    warm = warm or (now - warmUp)
+    local done = warm + warmUp
+    local from = lastTs
+    local weighted = 0
+    if from < done then
+      local to = math.min(now, done)
+      local f0 = (from - warm) / warmUp
+      local f1 = (to - warm) / warmUp
+      weighted = (to - from) * (cold + (1 - cold) * (f0 + f1) / 2)
+      from = to
+    end
+    weighted = weighted + (now - from)
+    refill = (weighted * rate) / 1000
+  end
+  curRate = rate * (cold + (1 - cold) * math.min((now - warm) / warmUp, 1))
+end
+
+tokens = (tokens or initial) + refill
 if tokens > capacity then
   tokens = capacity
 end
 
--- 判断是否有足够的令牌
-if tokens < req then
-  return 0
+-- 最小间隔：距离上次放行不足 minGap 时拒绝，等待时间取间隔剩余部分
+if minGap > 0 then
+  local lastGrant = tonumber(redis.call("HGET", tokensKey, "grant"))
+  if lastGrant ~= nil and now - lastGrant < minGap then
+    return withLimit({0, math.floor(tokens + debt), lastGrant + minGap - now, skew}, capacity)
+  end
+end
+
+-- 判断是否有足够的令牌，不足时返回补足所需的等待时间
+-- 透支模式下 token 数最低可以降到 -debt
+if tokens - req < reserve - debt then
+  -- 不满的新桶被拒绝时也要落盘，否则每次都从 initial 重新开始，token 永远攒不起来
+  if fresh and initial < capacity and not dryRun then
+    saveState("tokens", tokens, now, jitterTTL(ttl, jitter, tokensKey .. now))
+    if warm ~= nil then
+      redis.call("HSET", tokensKey, "warm", warm)
+    end
+  end
+  local retryAfter = math.ceil((req + reserve - debt - tokens) * 1000 / curRate)
+  return withLimit({0, math.floor(tokens + debt), retryAfter, skew}, capacity)
+end
+
+-- 预检模式：只判定，不扣减也不回写
+if dryRun then
+  return withLimit({1, math.floor(tokens + debt), 0, skew}, capacity)
 end
 
 -- 消耗令牌
 tokens = tokens - req
 
--- 回写最新 token 数及时间戳，并设置 TTL
-redis.call("SET", tokensKey, tokens, "PX", ttl)
-redis.call("SET", tsKey, now, "PX", ttl)
+-- 回写最新 token 数及时间戳，并设置（带抖动的）TTL
+ttl = jitterTTL(ttl, jitter, tokensKey .. now)
+saveState("tokens", tokens, now, ttl)
+if warm ~= nil then
+  redis.call("HSET", tokensKey, "warm", warm)
+end
+if minGap > 0 then
+  redis.call("HSET", tokensKey, "grant", now)
+end
+
+return withLimit({1, math.floor(tokens + debt), 0, skew}, capacity)
+`)))
 
-return 1
+// tokenBucketRefundScript 把未用完的 token 归还给令牌桶（不超过容量）。
+// 桶不存在时视为满桶，无需归还；归还时保留原有 TTL。
+//
+// KEYS[1] = tokensKey
+// KEYS[2] = 可选，tsKey；与 KEYS[1] 相同时表示 hash 存储（tokens 字段）
+// KEYS[3] = 可选，按 key 覆盖配置的 hash（此处只读取 capacity）
+//
+// ARGV[1] = n        （归还的 token 数）
+// ARGV[2] = capacity （桶容量）
+//
+// 返回：归还后桶内 token 数（向下取整）
+var tokenBucketRefundScript = newScript(luaLimitOverride + `
+local tokensKey = KEYS[1]
+local n         = tonumber(ARGV[1])
+local _, capacity = limitOverride(0, tonumber(ARGV[2]))
+
+local hashState = KEYS[1] == KEYS[2]
+
+local tokens
+if hashState then
+  tokens = tonumber(redis.call("HGET", tokensKey, "tokens"))
+else
+  tokens = tonumber(redis.call("GET", tokensKey))
+end
+if tokens == nil then
+  return math.floor(capacity)
+end
+
+tokens = tokens + n
+if tokens > capacity then
+  tokens = capacity
+end
+
+if hashState then
+  -- HSET 不会清除 key 的过期时间
+  redis.call("HSET", tokensKey, "tokens", tokens)
+  return math.floor(tokens)
+end
+
+local pttl = redis.call("PTTL", tokensKey)
+if pttl > 0 then
+  redis.call("SET", tokensKey, tokens, "PX", pttl)
+else
+  redis.call("SET", tokensKey, tokens)
+end
+return math.floor(tokens)
+`)
+
+// leakyBucketRefundScript 降低漏桶水位，用于归还已放行但提前中止的请求（不低于 0）。
+// 只修改水位，不修改时间戳：之后的漏水仍按原时间戳计算，效果等同于这 n 单位从未放入。
+// 桶不存在时视为空桶，无需归还；归还时保留原有 TTL。
+//
+// KEYS[1] = levelKey
+// KEYS[2] = tsKey；与 KEYS[1] 相同时表示 hash 存储（level 字段）
+//
+// ARGV[1] = n （归还的水量）
+//
+// 返回：归还后的水位（向上取整）
+var leakyBucketRefundScript = newScript(`
+local levelKey = KEYS[1]
+local n        = tonumber(ARGV[1])
+
+local hashState = KEYS[1] == KEYS[2]
+
+local level
+if hashState then
+  level = tonumber(redis.call("HGET", levelKey, "level"))
+else
+  level = tonumber(redis.call("GET", levelKey))
+end
+if level == nil then
+  return 0
+end
+
+level = level - n
+if level < 0 then
+  level = 0
+end
+
+if hashState then
+  redis.call("HSET", levelKey, "level", level)
+  return math.ceil(level)
+end
+
+local pttl = redis.call("PTTL", levelKey)
+if pttl > 0 then
+  redis.call("SET", levelKey, level, "PX", pttl)
+else
+  redis.call("SET", levelKey, level)
+end
+return math.ceil(level)
+`)
+
+// hierarchicalScript 在一次调用中同时扣减子桶（例如单个租户）与父桶（例如整个接口）的令牌：
+//   - 两个桶各自按令牌桶规则 refill，并各自做时钟回拨保护
+//   - 子桶不足 -> 拒绝；父桶不足 -> 拒绝，且子桶不扣减（相当于把子桶已扣的令牌退回）
+//   - 两个桶都足够时同时扣减
+//
+// KEYS[1] = childTokensKey
+// KEYS[2] = childTsKey
+// KEYS[3] = parentTokensKey
+// KEYS[4] = parentTsKey
+//
+// ARGV[1] = nowMs（0 表示使用 Redis TIME）
+// ARGV[2] = childRate
+// ARGV[3] = childCapacity
+// ARGV[4] = parentRate
+// ARGV[5] = parentCapacity
+// ARGV[6] = req
+// ARGV[7] = ttlMs
+// ARGV[8] = jitter
+//
+// 返回：{allowed, remaining, retryAfterMs, deniedBy}
+//   - remaining：子桶与父桶中较小的剩余 token 数（向下取整）
+//   - deniedBy：0 放行，1 子桶拒绝，2 父桶拒绝
+var hierarchicalScript = newScript(luaServerTime + luaJitterTTL + `
+local now = resolveNow(tonumber(ARGV[1]))
+local req = tonumber(ARGV[6])
+local ttl = tonumber(ARGV[7])
+local jitter = tonumber(ARGV[8])
+
+local function refill(tokensKey, tsKey, rate, capacity)
+  local tokens = tonumber(redis.call("GET", tokensKey)) or capacity
+  local lastTs = tonumber(redis.call("GET", tsKey)) or now
+  local ts = now
+  if ts < lastTs then
+    ts = lastTs
+  end
+  tokens = math.min(tokens + (ts - lastTs) * rate / 1000, capacity)
+  return tokens, ts
+end
+
+local childRate, childCapacity = tonumber(ARGV[2]), tonumber(ARGV[3])
+local parentRate, parentCapacity = tonumber(ARGV[4]), tonumber(ARGV[5])
+
+local child, childTs = refill(KEYS[1], KEYS[2], childRate, childCapacity)
+local parent, parentTs = refill(KEYS[3], KEYS[4], parentRate, parentCapacity)
+
+if child < req then
+  local retry = math.ceil((req - child) * 1000 / childRate)
+  return {0, math.floor(math.min(child, parent)), retry, 1}
+end
+if parent < req then
+  local retry = math.ceil((req - parent) * 1000 / parentRate)
+  return {0, math.floor(math.min(child, parent)), retry, 2}
+end
+
+child = child - req
+parent = parent - req
+
+local childTTL = jitterTTL(ttl, jitter, KEYS[1] .. now)
+redis.call("SET", KEYS[1], child, "PX", childTTL)
+redis.call("SET", KEYS[2], childTs, "PX", childTTL)
+local parentTTL = jitterTTL(ttl, jitter, KEYS[3] .. now)
+redis.call("SET", KEYS[3], parent, "PX", parentTTL)
+redis.call("SET", KEYS[4], parentTs, "PX", parentTTL)
+
+return {1, math.floor(math.min(child, parent)), 0, 0}
 `)
 
 // leakyBucketScript 实现“漏桶”算法的核心逻辑，保证在 Redis 端原子执行。
@@ -66,33 +449,52 @@ return 1
 //	else level(t) = level(t) + req -> 允许
 //
 // KEYS[1] = bucket level key (string，存当前水位，浮点数)
-// KEYS[2] = ts key          (string，存上次更新时间，毫秒时间戳)
+// KEYS[2] = ts key          (string，存上次更新时间，毫秒时间戳；与 KEYS[1] 相同时表示 hash 存储)
+// KEYS[3] = override key    (可选，按 key 覆盖 rate / capacity 的 hash)
 //
-// ARGV[1] = nowMs      (当前时间，毫秒)
+// ARGV[1] = nowMs      (当前时间，毫秒；0 表示使用 Redis TIME)
 // ARGV[2] = leakRate   (泄漏速率，单位：单位/秒)
 // ARGV[3] = capacity   (桶容量，最大水位)
 // ARGV[4] = reqTokens  (本次请求消耗多少单位，一般为1)
 // ARGV[5] = ttlMs      (key 过期时间，毫秒)
-var leakyBucketScript = redis.NewScript(`
+// ARGV[6] = jitter     (TTL 抖动比例，0 表示不抖动)
+// ARGV[7] = partial    (1 表示部分放行：尽量放入 req 中能容纳的部分)
+// ARGV[8] = dryRun     (可选，1 表示只判定不写入，放行时 remaining 为当前剩余空间)
+//
+// 返回：{result, remaining, retryAfterMs, skewMs[, capacity]}
+//   - result：普通模式下 1/0 表示是否放行；部分放行模式下为实际放入的数量（0 表示一个都放不下）
+//   - remaining：判定后桶内剩余空间（向下取整）
+//   - retryAfterMs：被拒绝时桶内腾出足够空间还需等待的毫秒数，放行时为 0
+//   - skewMs：调用方时钟相对已记录时间戳的回拨量（毫秒）
+var leakyBucketScript = newScript(withStats(luaServerTime + luaJitterTTL + luaBucketState + luaLimitOverride + `
 local bucketKey = KEYS[1]
-local tsKey     = KEYS[2]
 
-local now       = tonumber(ARGV[1])
+local now       = resolveNow(tonumber(ARGV[1]))
 local leakRate  = tonumber(ARGV[2])
 local capacity  = tonumber(ARGV[3])
 local req       = tonumber(ARGV[4])
 local ttl       = tonumber(ARGV[5])
+local jitter    = tonumber(ARGV[6])
+local partial   = tonumber(ARGV[7]) == 1
+local dryRun    = ARGV[8] == "1"
 
+leakRate, capacity = limitOverride(leakRate, capacity)
+
+local level, lastTs = loadState("level")
 -- 当前水位（如果不存在，则视为0）
-local level = tonumber(redis.call("GET", bucketKey)) or 0
+level = level or 0
 -- 上次更新时间（如果不存在，则视为当前时间）
-local lastTs = tonumber(redis.call("GET", tsKey)) or now
+lastTs = lastTs or now
+
+-- 时钟回拨保护：时间戳只增不减（与令牌桶一致）
+local skew = 0
+if now < lastTs then
+  skew = lastTs - now
+  now = lastTs
+end
 
 -- 计算时间差，单位毫秒
 local delta = now - lastTs
-if delta < 0 then
-  delta = 0
-end
 
 -- 按时间差计算应泄漏的水量：leak = leakRate * delta / 1000
 local leak = (delta * leakRate) / 1000
@@ -101,45 +503,71 @@ if level < 0 then
   level = 0
 end
 
--- 判断本次请求能否放入桶中
-if level + req > capacity then
-  -- 超出容量，拒绝
-  return 0
+-- 部分放行模式：只放入桶中剩余空间能容纳的整数部分
+local admitted = req
+local need = req
+if partial then
+  admitted = math.min(req, math.floor(capacity - level))
+  need = 1
+end
+if admitted <= 0 or level + admitted > capacity then
+  -- 超出容量，拒绝；返回腾出 need 个单位空间所需的等待时间
+  local retryAfter = math.ceil((level + need - capacity) * 1000 / leakRate)
+  return withLimit({0, math.floor(capacity - level), retryAfter, skew}, capacity)
+end
+
+-- 预检模式：只判定，不写入
+if dryRun then
+  return withLimit({1, math.floor(capacity - level), 0, skew}, capacity)
 end
 
 -- 接受本次请求：增加水位
-level = level + req
+level = level + admitted
 
--- 写回 Redis，并设置 TTL，防止 key 永久存在
-redis.call("SET", bucketKey, level, "PX", ttl)
-redis.call("SET", tsKey, now, "PX", ttl)
+-- 写回 Redis，并设置（带抖动的）TTL，防止 key 永久存在
+ttl = jitterTTL(ttl, jitter, bucketKey .. now)
+saveState("level", level, now, ttl)
 
-return 1
-`)
+local result = 1
+if partial then
+  result = admitted
+end
+return withLimit({result, math.floor(capacity - level), 0, skew}, capacity)
+`))
 
 // slidingWindowScript 使用 ZSET + Lua 实现“精确滑动窗口”限流。
 // 算法：
 //   - 每次请求：
 //     1) 删除窗口外的记录：ZREMRANGEBYSCORE key 0 (now-window)
 //     2) 统计窗口内记录数：count = ZCARD
-//     3) 若 count >= limit -> 拒绝
-//     4) 否则：ZADD now member，并设置 TTL
+//     3) 若 count + req > limit -> 整体拒绝（不会只写入一部分）
+//     4) 否则：一次写入 req 个 member，并设置 TTL
 //
 // KEYS[1] = logKey (ZSET，用于存储请求时间戳)
 // KEYS[2] = seqKey (String，自增序列，保证 member 唯一)
 //
-// ARGV[1] = nowMs    (当前时间，毫秒)
+// ARGV[1] = nowMs    (当前时间，毫秒；0 表示使用 Redis TIME)
 // ARGV[2] = windowMs (窗口大小，毫秒)
 // ARGV[3] = limit    (窗口内最大允许请求数)
 // ARGV[4] = ttlMs    (key 过期时间，毫秒)
-var slidingWindowScript = redis.NewScript(`
+// ARGV[5] = jitter   (TTL 抖动比例，0 表示不抖动)
+// ARGV[6] = req      (本次请求占用的名额数，调用方保证 1 <= req <= limit)
+// ARGV[7] = dryRun   (可选，1 表示只判定不写入，放行时 remaining 为当前剩余名额)
+//
+// 返回：{allowed, remaining, retryAfterMs}
+//   - remaining：判定后窗口内剩余名额
+//   - retryAfterMs：被拒绝时窗口内腾出 req 个名额还需的毫秒数，放行时为 0
+var slidingWindowScript = newScript(withStats(luaServerTime + luaJitterTTL + `
 local logKey = KEYS[1]
 local seqKey = KEYS[2]
 
-local now    = tonumber(ARGV[1])
+local now    = resolveNow(tonumber(ARGV[1]))
 local window = tonumber(ARGV[2])
 local limit  = tonumber(ARGV[3])
 local ttl    = tonumber(ARGV[4])
+local jitter = tonumber(ARGV[5])
+local req    = tonumber(ARGV[6])
+local dryRun = ARGV[7] == "1"
 
 local minScore = now - window
 
@@ -148,20 +576,1005 @@ redis.call("ZREMRANGEBYSCORE", logKey, 0, minScore)
 
 -- 窗口内当前请求数量
 local count = redis.call("ZCARD", logKey)
-if count >= limit then
-  return 0
+if count + req > limit then
+  -- 需要最早的 count+req-limit 条记录滑出窗口后才有足够空位
+  local retryAfter = 0
+  local k = count + req - limit
+  local oldest = redis.call("ZRANGE", logKey, k - 1, k - 1, "WITHSCORES")
+  if oldest[2] then
+    retryAfter = math.max(math.ceil(tonumber(oldest[2]) + window - now), 0)
+  end
+  return {0, math.max(limit - count, 0), retryAfter}
 end
 
--- 为本次请求生成唯一 member
-local seq = redis.call("INCR", seqKey)
-local member = now .. "-" .. seq
+-- 预检模式：只判定，不写入
+if dryRun then
+  return {1, limit - count, 0}
+end
 
--- 写入本次请求
-redis.call("ZADD", logKey, now, member)
+-- 为本次请求生成 req 个唯一 member 并分批写入：
+-- unpack 的参数个数受 Lua C 栈（LUAI_MAXCSTACK = 8000）限制，每批最多 1000 个 member
+local seq = redis.call("INCRBY", seqKey, req)
+local args = {}
+for i = 1, req do
+  args[#args + 1] = now
+  args[#args + 1] = now .. "-" .. (seq - req + i)
+  if #args == 2000 or i == req then
+    redis.call("ZADD", logKey, unpack(args))
+    args = {}
+  end
+end
 
--- 设置 TTL，避免 key 泄漏
+-- 设置（带抖动的）TTL，避免 key 泄漏
+ttl = jitterTTL(ttl, jitter, logKey .. now)
 redis.call("PEXPIRE", logKey, ttl)
 redis.call("PEXPIRE", seqKey, ttl)
 
+return {1, limit - count - req, 0}
+`))
+
+// slidingWindowStateScript 只读地统计滑动窗口内的请求数，与限流脚本使用同一个时钟源：
+//   - 窗口内记录为 score >= now - window 的元素（与限流脚本的清理边界一致）
+//   - 窗口已满时额外返回从新到旧第 limit 条记录的 score：它滑出窗口后才会腾出空位
+//
+// KEYS[1] = logKey
+//
+// ARGV[1] = nowMs    (当前时间，毫秒；0 表示使用 Redis TIME)
+// ARGV[2] = windowMs (窗口大小，毫秒)
+// ARGV[3] = limit    (窗口内最大允许请求数)
+//
+// 返回：{count, blockingScore, now}，窗口未满时 blockingScore 为 nil
+var slidingWindowStateScript = newScript(luaServerTime + `
+local logKey = KEYS[1]
+
+local now    = resolveNow(tonumber(ARGV[1]))
+local window = tonumber(ARGV[2])
+local limit  = tonumber(ARGV[3])
+local minScore = now - window
+
+local count = redis.call("ZCOUNT", logKey, minScore, "+inf")
+local blocking = false
+if count >= limit then
+  local r = redis.call("ZREVRANGEBYSCORE", logKey, "+inf", minScore, "WITHSCORES", "LIMIT", limit - 1, 1)
+  if #r == 2 then
+    blocking = r[2]
+  end
+end
+
+return {count, blocking, now}
+`)
+
+// slidingWindowCounterScript 实现“滑动窗口计数器”（分桶近似）限流。
+// 窗口被切分为 buckets 个固定大小的子桶，计数存放在同一个 hash 中（field 为子桶序号），
+// 内存占用与 limit 无关，只与子桶数量有关。
+//
+// 算法：
+//   - cur = floor(now / bucketMs)，窗口覆盖 (now-window, now]
+//   - 最老的子桶 cur-buckets 只有一部分落在窗口内，按重叠比例 w 线性折算：
+//     count = w * c[cur-buckets] + sum(c[cur-buckets+1 .. cur])
+//   - count + req > limit -> 拒绝；否则 c[cur] += req
+//
+// KEYS[1] = bucketsKey (hash，field 为子桶序号，value 为计数)
+//
+// ARGV[1] = nowMs    (当前时间，毫秒；0 表示使用 Redis TIME)
+// ARGV[2] = bucketMs (子桶大小，毫秒)
+// ARGV[3] = buckets  (子桶数量，window = bucketMs * buckets)
+// ARGV[4] = limit    (窗口内最大允许请求数)
+// ARGV[5] = req      (本次请求占用的名额数)
+// ARGV[6] = ttlMs    (key 过期时间，毫秒)
+// ARGV[7] = jitter   (TTL 抖动比例，0 表示不抖动)
+// ARGV[8] = dryRun   (可选，1 表示只判定不计数，放行时 remaining 为当前估算剩余名额)
+//
+// 返回：{allowed, remaining, retryAfterMs}
+//   - retryAfterMs：被拒绝时按当前计数推算、估算值降到足以容纳 req 所需的毫秒数
+var slidingWindowCounterScript = newScript(withStats(luaServerTime + luaJitterTTL + `
+local key = KEYS[1]
+
+local now     = resolveNow(tonumber(ARGV[1]))
+local size    = tonumber(ARGV[2])
+local buckets = tonumber(ARGV[3])
+local limit   = tonumber(ARGV[4])
+local req     = tonumber(ARGV[5])
+local ttl     = tonumber(ARGV[6])
+local jitter  = tonumber(ARGV[7])
+local dryRun  = ARGV[8] == "1"
+
+local cur = math.floor(now / size)
+local oldest = cur - buckets
+
+-- 子桶序号是很大的整数，用字符串作为 table 的 key，避免稀疏数组
+local function field(b)
+  return string.format("%d", b)
+end
+
+-- 读取全部子桶，并清理已完全滑出窗口的子桶
+local raw = redis.call("HGETALL", key)
+local counts = {}
+local stale = {}
+for i = 1, #raw, 2 do
+  if tonumber(raw[i]) < oldest then
+    stale[#stale + 1] = raw[i]
+  else
+    counts[raw[i]] = tonumber(raw[i + 1])
+  end
+end
+if #stale > 0 then
+  redis.call("HDEL", key, unpack(stale))
+end
+
+-- 最老子桶落在窗口内的比例
+local w = ((cur + 1) * size - now) / size
+local count = w * (counts[field(oldest)] or 0)
+for b = oldest + 1, cur do
+  count = count + (counts[field(b)] or 0)
+end
+
+if count + req > limit then
+  -- 按时间推进，依次让最老的子桶滑出窗口，直到估算值足够容纳 req
+  local excess = count + req - limit
+  local elapsed = 0
+  local retryAfter = nil
+  for k = 0, buckets do
+    local c = counts[field(oldest + k)] or 0
+    local weight = 1
+    if k == 0 then
+      weight = w
+    end
+    if c > 0 and c * weight >= excess then
+      retryAfter = elapsed + excess * size / c
+      break
+    end
+    excess = excess - c * weight
+    elapsed = elapsed + weight * size
+  end
+  if retryAfter == nil then
+    retryAfter = elapsed
+  end
+  return {0, math.max(math.floor(limit - count), 0), math.ceil(retryAfter)}
+end
+
+-- 预检模式：只判定，不计数
+if dryRun then
+  return {1, math.floor(limit - count), 0}
+end
+
+redis.call("HINCRBY", key, field(cur), req)
+ttl = jitterTTL(ttl, jitter, key .. now)
+redis.call("PEXPIRE", key, ttl)
+
+return {1, math.floor(limit - count - req), 0}
+`))
+
+// fixedWindowScript 实现固定窗口计数（INCRBY + PEXPIRE）：
+//   - 窗口从该 key 第一次被计数开始，持续 windowMs，过期后自动开启新窗口
+//   - count + req > limit -> 拒绝，不修改计数
+//   - 否则 INCRBY；新窗口的第一次计数设置过期时间
+//
+// KEYS[1] = countKey
+//
+// ARGV[1] = windowMs (窗口大小，毫秒)
+// ARGV[2] = limit    (窗口内最大允许请求数)
+// ARGV[3] = req      (本次请求数量)
+// ARGV[4] = dryRun   (可选，1 表示只判定不计数，放行时 remaining 为当前剩余名额)
+//
+// 返回：{allowed, remaining, retryAfterMs}
+//   - retryAfterMs：被拒绝时距离当前窗口结束的毫秒数，放行时为 0
+var fixedWindowScript = newScript(withStats(`
+local countKey = KEYS[1]
+
+local window = tonumber(ARGV[1])
+local limit  = tonumber(ARGV[2])
+local req    = tonumber(ARGV[3])
+local dryRun = ARGV[4] == "1"
+
+local count = tonumber(redis.call("GET", countKey)) or 0
+if count + req > limit then
+  local ttl = redis.call("PTTL", countKey)
+  if ttl < 0 then
+    ttl = 0
+  end
+  return {0, math.max(limit - count, 0), ttl}
+end
+
+-- 预检模式：只判定，不计数
+if dryRun then
+  return {1, limit - count, 0}
+end
+
+count = redis.call("INCRBY", countKey, req)
+-- 新窗口（或异常丢失了过期时间的 key）设置过期时间
+if count == req or redis.call("PTTL", countKey) < 0 then
+  redis.call("PEXPIRE", countKey, window)
+end
+
+return {1, limit - count, 0}
+`))
+
+// cardinalityScript 限制窗口内出现的不同成员数量：
+//   - 成员已出现过 -> 放行，不重复计数
+//   - 不同成员数 >= limit -> 拒绝，retryAfter 为距离窗口结束的毫秒数
+//   - 否则记录成员；窗口从第一个成员出现开始，新窗口设置过期时间
+//
+// 精确模式使用 SET（SISMEMBER / SCARD / SADD）；近似模式使用 HyperLogLog：
+// 未达上限时直接 PFADD，已达上限时把 HLL 复制到 probeKey 上试探 PFADD，
+// 寄存器未变化说明成员（大概率）已出现过，放行但不修改原 HLL。
+//
+// KEYS[1] = membersKey
+// KEYS[2] = probeKey（仅近似模式使用）
+//
+// ARGV[1] = windowMs (窗口大小，毫秒)
+// ARGV[2] = limit    (窗口内允许的不同成员数)
+// ARGV[3] = member
+// ARGV[4] = approx   (1 表示使用 HyperLogLog)
+//
+// 返回：{allowed, remaining, retryAfterMs}
+var cardinalityScript = newScript(`
+local membersKey = KEYS[1]
+local probeKey   = KEYS[2]
+
+local window = tonumber(ARGV[1])
+local limit  = tonumber(ARGV[2])
+local member = ARGV[3]
+local approx = ARGV[4] == "1"
+
+local function windowLeft()
+  local ttl = redis.call("PTTL", membersKey)
+  if ttl < 0 then
+    return 0
+  end
+  return ttl
+end
+
+local count
+if approx then
+  count = redis.call("PFCOUNT", membersKey)
+  if count >= limit then
+    redis.call("COPY", membersKey, probeKey, "REPLACE")
+    local added = redis.call("PFADD", probeKey, member)
+    redis.call("DEL", probeKey)
+    if added == 1 then
+      return {0, 0, windowLeft()}
+    end
+    return {1, 0, 0}
+  end
+  redis.call("PFADD", membersKey, member)
+  count = redis.call("PFCOUNT", membersKey)
+else
+  count = redis.call("SCARD", membersKey)
+  if redis.call("SISMEMBER", membersKey, member) == 1 then
+    return {1, math.max(limit - count, 0), 0}
+  end
+  if count >= limit then
+    return {0, 0, windowLeft()}
+  end
+  redis.call("SADD", membersKey, member)
+  count = count + 1
+end
+
+-- 新窗口（或异常丢失了过期时间的 key）设置过期时间
+if redis.call("PTTL", membersKey) < 0 then
+  redis.call("PEXPIRE", membersKey, window)
+end
+
+return {1, math.max(limit - count, 0), 0}
+`)
+
+// concurrencyAcquireScript 实现分布式信号量的获取：
+//   - ZSET 中 member 为租约 token，score 为租约到期时间（毫秒）
+//   - 先清理已过期的租约（持有者崩溃、未调用 Release 的情况）
+//   - 在途数量 >= limit -> 拒绝，retryAfter 为最早到期租约的剩余时间
+//   - 否则写入新租约
+//
+// KEYS[1] = leasesKey
+//
+// ARGV[1] = now     (当前时间，毫秒；0 表示使用 Redis TIME)
+// ARGV[2] = leaseMs (租约时长，毫秒)
+// ARGV[3] = limit   (最大并发数)
+// ARGV[4] = token   (新租约 token)
+//
+// 返回：{allowed, remaining, retryAfterMs}
+var concurrencyAcquireScript = newScript(luaServerTime + `
+local leasesKey = KEYS[1]
+
+local now   = resolveNow(tonumber(ARGV[1]))
+local lease = tonumber(ARGV[2])
+local limit = tonumber(ARGV[3])
+local token = ARGV[4]
+
+redis.call("ZREMRANGEBYSCORE", leasesKey, "-inf", now)
+
+local count = redis.call("ZCARD", leasesKey)
+if count >= limit then
+  local retry = 0
+  local oldest = redis.call("ZRANGE", leasesKey, 0, 0, "WITHSCORES")
+  if #oldest == 2 then
+    retry = math.max(tonumber(oldest[2]) - now, 0)
+  end
+  return {0, 0, retry}
+end
+
+redis.call("ZADD", leasesKey, now + lease, token)
+-- 新租约的到期时间总是最晚的，key 的过期时间与之对齐
+redis.call("PEXPIRE", leasesKey, lease)
+
+return {1, limit - count - 1, 0}
+`)
+
+// concurrencyExtendScript 为仍然有效的租约续期。
+//
+// KEYS[1] = leasesKey
+//
+// ARGV[1] = now     (当前时间，毫秒；0 表示使用 Redis TIME)
+// ARGV[2] = leaseMs (租约时长，毫秒)
+// ARGV[3] = token
+//
+// 返回：1 续期成功，0 租约不存在或已过期
+var concurrencyExtendScript = newScript(luaServerTime + `
+local leasesKey = KEYS[1]
+
+local now   = resolveNow(tonumber(ARGV[1]))
+local lease = tonumber(ARGV[2])
+local token = ARGV[3]
+
+local expireAt = tonumber(redis.call("ZSCORE", leasesKey, token))
+if expireAt == nil or expireAt <= now then
+  redis.call("ZREM", leasesKey, token)
+  return 0
+end
+
+redis.call("ZADD", leasesKey, now + lease, token)
+redis.call("PEXPIRE", leasesKey, lease)
 return 1
 `)
+
+// concurrencyStateScript 只读地统计在途（未过期）的租约数，与获取名额使用同一个时钟源。
+//
+// KEYS[1] = leasesKey
+//
+// ARGV[1] = now (当前时间，毫秒；0 表示使用 Redis TIME)
+//
+// 返回：{used, oldestExpireAt, now}，没有在途租约时 oldestExpireAt 为 0
+var concurrencyStateScript = newScript(luaServerTime + `
+local leasesKey = KEYS[1]
+
+local now = resolveNow(tonumber(ARGV[1]))
+
+local used = redis.call("ZCOUNT", leasesKey, "(" .. now, "+inf")
+local oldestAt = 0
+local oldest = redis.call("ZRANGEBYSCORE", leasesKey, "(" .. now, "+inf", "WITHSCORES", "LIMIT", 0, 1)
+if #oldest == 2 then
+  oldestAt = tonumber(oldest[2])
+end
+
+return {used, oldestAt, now}
+`)
+
+// compositeScript 在一次调用中原子地检查并扣减多条固定窗口规则：
+//   - 先检查全部规则，任意一条 count + req > limit 即整体拒绝，不修改任何计数
+//     （相当于“全部扣减失败后回滚”，但无需真正写入再撤销）
+//   - 全部通过后逐条 INCRBY，新窗口设置过期时间
+//
+// KEYS[i] = 第 i 条规则的计数 key
+//
+// ARGV[1]       = req      (本次请求数量)
+// ARGV[2i]      = windowMs (第 i 条规则的窗口大小，毫秒)
+// ARGV[2i + 1]  = limit    (第 i 条规则的窗口上限)
+//
+// 返回：{allowed, remaining, retryAfterMs, deniedIndex}
+//   - remaining：所有规则中最小的剩余名额
+//   - deniedIndex：拒绝时为需要等待最久的规则下标（从 0 开始），放行时为 -1
+var compositeScript = newScript(`
+local req = tonumber(ARGV[1])
+
+local counts = {}
+local remaining = -1
+local retry = 0
+local denied = -1
+
+for i, key in ipairs(KEYS) do
+  local limit = tonumber(ARGV[2 * i + 1])
+  local count = tonumber(redis.call("GET", key)) or 0
+  counts[i] = count
+
+  if count + req > limit then
+    local ttl = redis.call("PTTL", key)
+    if ttl < 0 then
+      ttl = 0
+    end
+    if denied < 0 or ttl > retry then
+      retry = ttl
+      denied = i - 1
+    end
+  end
+
+  local left = math.max(limit - count, 0)
+  if remaining < 0 or left < remaining then
+    remaining = left
+  end
+end
+
+if denied >= 0 then
+  return {0, remaining, retry, denied}
+end
+
+remaining = -1
+for i, key in ipairs(KEYS) do
+  local window = tonumber(ARGV[2 * i])
+  local limit  = tonumber(ARGV[2 * i + 1])
+  local count  = redis.call("INCRBY", key, req)
+  if count == req or redis.call("PTTL", key) < 0 then
+    redis.call("PEXPIRE", key, window)
+  end
+
+  local left = limit - count
+  if remaining < 0 or left < remaining then
+    remaining = left
+  end
+end
+
+return {1, remaining, 0, -1}
+`)
+
+// overageScript 实现“软上限 + 硬上限”的两段式固定窗口计数：
+//   - used + req > hard  -> 拒绝，不修改计数
+//   - used + req > soft  -> 允许，但标记为超额（overage），用于超额计费
+//   - 其他               -> 正常允许
+//
+// 窗口起点在脚本内由当前时间计算（默认为 Redis TIME），计数 key 为 KEYS[1] 加上窗口起点后缀，
+// 与 KEYS[1] 共用同一个 hash tag，因此落在同一个 slot；各实例的时钟偏差不会把同一个窗口拆成两个 key。
+//
+// KEYS[1] = baseKey（计数 key 的公共前缀，例如 "ovg:{tenant}"）
+//
+// ARGV[1] = nowMs    （当前时间，毫秒；0 表示使用 Redis TIME）
+// ARGV[2] = windowMs （窗口大小，毫秒）
+// ARGV[3] = soft     （软上限）
+// ARGV[4] = hard     （硬上限）
+// ARGV[5] = req      （本次请求数量）
+// ARGV[6] = op       （可选，"state" 只读取当前窗口的用量，"reset" 删除当前窗口的计数）
+//
+// 返回：{allowed, used, flagged, windowStartMs, nowMs}
+var overageScript = newScript(luaServerTime + `
+local now    = resolveNow(tonumber(ARGV[1]))
+local window = tonumber(ARGV[2])
+local soft   = tonumber(ARGV[3])
+local hard   = tonumber(ARGV[4])
+local req    = tonumber(ARGV[5])
+local op     = ARGV[6]
+
+local start    = now - now % window
+local countKey = KEYS[1] .. ":" .. string.format("%d", start)
+
+if op == "reset" then
+  redis.call("DEL", countKey)
+  return {1, 0, 0, start, now}
+end
+
+local used = tonumber(redis.call("GET", countKey)) or 0
+
+if op == "state" then
+  local flagged = 0
+  if used > soft then
+    flagged = 1
+  end
+  return {1, used, flagged, start, now}
+end
+
+-- 超过硬上限：拒绝，计数保持不变
+if used + req > hard then
+  return {0, used, 1, start, now}
+end
+
+used = redis.call("INCRBY", countKey, req)
+-- key 在窗口结束后再保留 1 秒，避免边界上的读写拿不到数据
+redis.call("PEXPIRE", countKey, start + window - now + 1000)
+
+local flagged = 0
+if used > soft then
+  flagged = 1
+end
+
+return {1, used, flagged, start, now}
+`)
+
+// adaptiveScript 按 AIMD（加性增、乘性减）更新覆盖配置 hash 中的 rate 字段：
+//   - 成功：rate += increase，不超过 maxRate
+//   - 失败：rate *= decrease，不低于 minRate；距上次减速不足 cooldownMs 时不重复减速，
+//     避免一次故障引发的一串失败把速率直接压到下限
+//
+// KEYS[1] = overrideKey（与被包装限流器共用的覆盖配置 hash）
+//
+// ARGV[1] = now        (当前时间，毫秒；0 表示使用 Redis TIME)
+// ARGV[2] = ok         (1 成功 / 0 失败)
+// ARGV[3] = initial    (hash 中还没有 rate 时的初始速率)
+// ARGV[4] = minRate
+// ARGV[5] = maxRate
+// ARGV[6] = increase   (每次成功增加的速率)
+// ARGV[7] = decrease   (每次失败乘以的系数，0~1)
+// ARGV[8] = cooldownMs (两次减速之间的最小间隔，毫秒)
+//
+// 返回：更新后的速率（字符串，避免小数被 Redis 截断为整数）
+var adaptiveScript = newScript(luaServerTime + `
+local key = KEYS[1]
+
+local now      = resolveNow(tonumber(ARGV[1]))
+local ok       = ARGV[2] == "1"
+local rate     = tonumber(redis.call("HGET", key, "rate")) or tonumber(ARGV[3])
+local minRate  = tonumber(ARGV[4])
+local maxRate  = tonumber(ARGV[5])
+local increase = tonumber(ARGV[6])
+local decrease = tonumber(ARGV[7])
+local cooldown = tonumber(ARGV[8])
+
+if ok then
+  rate = math.min(rate + increase, maxRate)
+else
+  local last = tonumber(redis.call("HGET", key, "decreased_at")) or 0
+  if now - last < cooldown then
+    return tostring(rate)
+  end
+  rate = math.max(rate * decrease, minRate)
+  redis.call("HSET", key, "decreased_at", now)
+end
+
+redis.call("HSET", key, "rate", tostring(rate))
+return tostring(rate)
+`)
+
+// quotaScript 实现按自然周期（天 / 周 / 月）计数的配额：
+//   - 每个周期一个 hash，字段 used 为已用量、bonus 为管理员追加的额度、carry 为从上个周期结转的额度
+//   - used + req > limit + bonus + carry -> 拒绝，不修改计数
+//   - 否则 HINCRBY used，并把过期时间设为周期结束后再保留一段时间
+//
+// 开启结转（maxCarry > 0）时，本周期第一次计数会读取上个周期的 hash，
+// 把未用完的额度（不超过 maxCarry）写入 carry 字段，此后本周期不再重新计算。
+// 上个周期的 key 不存在（从未使用或已过期）时不结转。
+//
+// 周期的起止时间由调用方按时区计算，体现在 KEYS 的名字与 ARGV[3] 中。
+//
+// KEYS[1] = periodKey
+// KEYS[2] = prevPeriodKey（可选，开启结转时传入）
+//
+// ARGV[1] = limit      （每个周期的配额）
+// ARGV[2] = req        （本次请求数量，0 表示只查询）
+// ARGV[3] = expireAtMs （key 的过期时间点，毫秒时间戳）
+// ARGV[4] = dryRun     （可选，1 表示只判定不计数，放行时 remaining 为当前剩余额度）
+// ARGV[5] = maxCarry   （可选，每个周期最多结转的额度，0 表示不结转）
+//
+// 返回：{allowed, remaining, total, used}
+//   - total：本周期的总额度（limit + bonus + carry）
+var quotaScript = newScript(`
+local key = KEYS[1]
+
+local limit    = tonumber(ARGV[1])
+local req      = tonumber(ARGV[2])
+local expireAt = tonumber(ARGV[3])
+local dryRun   = ARGV[4] == "1"
+local maxCarry = tonumber(ARGV[5]) or 0
+
+local v = redis.call("HMGET", key, "used", "bonus", "carry")
+local used  = tonumber(v[1]) or 0
+local total = limit + (tonumber(v[2]) or 0)
+
+-- 结转：本周期还没有 carry 字段时，按上个周期的剩余额度计算
+local carry = tonumber(v[3])
+local newCarry = false
+if maxCarry > 0 and carry == nil then
+  carry = 0
+  newCarry = true
+  if KEYS[2] and redis.call("EXISTS", KEYS[2]) == 1 then
+    local p = redis.call("HMGET", KEYS[2], "used", "bonus", "carry")
+    local unused = limit + (tonumber(p[2]) or 0) + (tonumber(p[3]) or 0) - (tonumber(p[1]) or 0)
+    carry = math.min(math.max(unused, 0), maxCarry)
+  end
+end
+total = total + (carry or 0)
+
+if used + req > total then
+  return {0, math.max(total - used, 0), total, used}
+end
+
+-- 预检模式：只判定，不计数
+if dryRun then
+  return {1, total - used, total, used}
+end
+
+redis.call("HINCRBY", key, "used", req)
+if newCarry then
+  redis.call("HSET", key, "carry", carry)
+end
+redis.call("PEXPIREAT", key, expireAt)
+
+return {1, total - used - req, total, used + req}
+`)
+
+// quotaAdjustScript 原子地调整当前周期的额度（字段含义见 quotaScript），用于 Grant / Revoke / Transfer：
+//   - KEYS[1] 的 bonus 增加 delta；delta < 0 时要求 KEYS[1] 的剩余额度不少于 -delta，否则不做任何修改
+//   - 传入 KEYS[2] 时，KEYS[2] 的 bonus 同时减少 delta（即从 KEYS[1] 转给 KEYS[2]）
+//
+// 剩余额度 = limit + bonus + carry - used。开启结转但本周期尚未计数时 carry 还没有固定，按 0 计算。
+//
+// KEYS[1] = periodKey
+// KEYS[2] = 对方的 periodKey（可选，Transfer 时传入）
+//
+// ARGV[1] = limit      （每个周期的配额）
+// ARGV[2] = delta      （KEYS[1] 的额度变化量）
+// ARGV[3] = expireAtMs （key 的过期时间点，毫秒时间戳）
+//
+// 返回：{ok, remaining}
+//   - remaining：KEYS[1] 调整后（失败时为当前）的剩余额度
+var quotaAdjustScript = newScript(`
+local limit    = tonumber(ARGV[1])
+local delta    = tonumber(ARGV[2])
+local expireAt = tonumber(ARGV[3])
+
+local function remaining(key)
+  local v = redis.call("HMGET", key, "used", "bonus", "carry")
+  return limit + (tonumber(v[2]) or 0) + (tonumber(v[3]) or 0) - (tonumber(v[1]) or 0)
+end
+
+if delta < 0 then
+  local left = remaining(KEYS[1])
+  if left < -delta then
+    return {0, math.max(left, 0)}
+  end
+end
+
+redis.call("HINCRBY", KEYS[1], "bonus", delta)
+redis.call("PEXPIREAT", KEYS[1], expireAt)
+if KEYS[2] then
+  redis.call("HINCRBY", KEYS[2], "bonus", -delta)
+  redis.call("PEXPIREAT", KEYS[2], expireAt)
+end
+
+return {1, remaining(KEYS[1])}
+`)
+
+// multiScript 在一次调用中原子地从多个限流器消耗许可（全部成功或全部不消耗）：
+//   - 先按顺序检查每个限流器（令牌桶 / 漏桶 / 固定窗口 / 滑动窗口），只计算不写入
+//   - 任意一个不满足即整体拒绝，不修改任何计数；全部满足后再依次写入
+//
+// 各算法的判定语义与对应的单独脚本一致（不含预检、借用、预热、部分放行等扩展）；
+// 滑动窗口在检查阶段会清理窗口外的旧记录，这不影响计数。
+// 每个限流器在 ARGV 中占一段：algo, req, nkeys, nargs, args...，并按顺序占用 nkeys 个 KEYS：
+//   - tb / lb：KEYS = valueKey, tsKey[, overrideKey]；args = nowMs, rate, capacity, ttlMs, jitter
+//   - fw：KEYS = countKey；args = windowMs, limit
+//   - sw：KEYS = logKey, seqKey；args = nowMs, windowMs, limit, ttlMs, jitter
+//
+// nowMs 为 0 表示使用 Redis TIME（同一次调用内只取一次）。
+//
+// ARGV[1] = 限流器个数
+//
+// 返回：{allowed, deniedIndex, retryAfterMs, ok_1, remaining_1, retryAfterMs_1, limit_1, ...}
+//   - deniedIndex：拒绝时为需要等待最久的限流器下标（从 0 开始），放行时为 -1
+//   - ok_i：第 i 个限流器单独判定是否满足
+//   - remaining_i：整体放行时为扣减后的剩余额度，整体拒绝时为当前剩余额度
+var multiScript = newScript(luaServerTime + luaJitterTTL + `
+local serverNow = nil
+local function nowOf(ms)
+  ms = tonumber(ms)
+  if ms > 0 then
+    return ms
+  end
+  if serverNow == nil then
+    serverNow = resolveNow(0)
+  end
+  return serverNow
+end
+
+local function checkBucket(algo, keys, args, req)
+  local vk, tk = keys[1], keys[2]
+  local now      = nowOf(args[1])
+  local rate     = tonumber(args[2])
+  local capacity = tonumber(args[3])
+  local ttl      = tonumber(args[4])
+  local jitter   = tonumber(args[5])
+
+  if keys[3] then
+    local o = redis.call("HMGET", keys[3], "rate", "capacity")
+    rate = tonumber(o[1]) or rate
+    capacity = tonumber(o[2]) or capacity
+  end
+
+  local field = "level"
+  if algo == "tb" then
+    field = "tokens"
+  end
+  local value, lastTs
+  if vk == tk then
+    local v = redis.call("HMGET", vk, field, "ts")
+    value, lastTs = tonumber(v[1]), tonumber(v[2])
+  else
+    value, lastTs = tonumber(redis.call("GET", vk)), tonumber(redis.call("GET", tk))
+  end
+  lastTs = lastTs or now
+  if now < lastTs then
+    now = lastTs
+  end
+  local delta = (now - lastTs) * rate / 1000
+
+  local save = function(v)
+    ttl = jitterTTL(ttl, jitter, vk .. now)
+    if vk == tk then
+      redis.call("HSET", vk, field, v, "ts", now)
+      redis.call("PEXPIRE", vk, ttl)
+      return
+    end
+    redis.call("SET", vk, v, "PX", ttl)
+    redis.call("SET", tk, now, "PX", ttl)
+  end
+
+  if algo == "tb" then
+    local tokens = math.min((value or capacity) + delta, capacity)
+    if tokens < req then
+      return false, math.floor(tokens), math.ceil((req - tokens) * 1000 / rate), capacity
+    end
+    return true, math.floor(tokens - req), 0, capacity, function() save(tokens - req) end
+  end
+
+  local level = math.max((value or 0) - delta, 0)
+  if level + req > capacity then
+    return false, math.floor(capacity - level), math.ceil((level + req - capacity) * 1000 / rate), capacity
+  end
+  return true, math.floor(capacity - level - req), 0, capacity, function() save(level + req) end
+end
+
+local function checkFixed(keys, args, req)
+  local key    = keys[1]
+  local window = tonumber(args[1])
+  local limit  = tonumber(args[2])
+
+  local count = tonumber(redis.call("GET", key)) or 0
+  if count + req > limit then
+    return false, math.max(limit - count, 0), math.max(redis.call("PTTL", key), 0), limit
+  end
+  return true, limit - count - req, 0, limit, function()
+    local c = redis.call("INCRBY", key, req)
+    if c == req or redis.call("PTTL", key) < 0 then
+      redis.call("PEXPIRE", key, window)
+    end
+  end
+end
+
+local function checkSliding(keys, args, req)
+  local logKey, seqKey = keys[1], keys[2]
+  local now    = nowOf(args[1])
+  local window = tonumber(args[2])
+  local limit  = tonumber(args[3])
+  local ttl    = tonumber(args[4])
+  local jitter = tonumber(args[5])
+
+  redis.call("ZREMRANGEBYSCORE", logKey, 0, now - window)
+  local count = redis.call("ZCARD", logKey)
+  if count + req > limit then
+    local retryAfter = 0
+    local k = count + req - limit
+    local oldest = redis.call("ZRANGE", logKey, k - 1, k - 1, "WITHSCORES")
+    if oldest[2] then
+      retryAfter = math.max(math.ceil(tonumber(oldest[2]) + window - now), 0)
+    end
+    return false, math.max(limit - count, 0), retryAfter, limit
+  end
+  return true, limit - count - req, 0, limit, function()
+    local seq = redis.call("INCRBY", seqKey, req)
+    local members = {}
+    for i = 1, req do
+      members[#members + 1] = now
+      members[#members + 1] = now .. "-" .. (seq - req + i)
+      -- 分批写入，避免 unpack 超出 Lua C 栈限制
+      if #members == 2000 or i == req then
+        redis.call("ZADD", logKey, unpack(members))
+        members = {}
+      end
+    end
+    ttl = jitterTTL(ttl, jitter, logKey .. now)
+    redis.call("PEXPIRE", logKey, ttl)
+    redis.call("PEXPIRE", seqKey, ttl)
+  end
+end
+
+local out = {1, -1, 0}
+local commits, granted = {}, {}
+local ki, ai = 1, 2
+
+for i = 1, tonumber(ARGV[1]) do
+  local algo  = ARGV[ai]
+  local req   = tonumber(ARGV[ai + 1])
+  local nkeys = tonumber(ARGV[ai + 2])
+  local nargs = tonumber(ARGV[ai + 3])
+
+  local keys, args = {}, {}
+  for j = 1, nkeys do
+    keys[j] = KEYS[ki]
+    ki = ki + 1
+  end
+  for j = 1, nargs do
+    args[j] = ARGV[ai + 3 + j]
+  end
+  ai = ai + 4 + nargs
+
+  local ok, remaining, retry, limit, commit
+  if algo == "tb" or algo == "lb" then
+    ok, remaining, retry, limit, commit = checkBucket(algo, keys, args, req)
+  elseif algo == "fw" then
+    ok, remaining, retry, limit, commit = checkFixed(keys, args, req)
+  elseif algo == "sw" then
+    ok, remaining, retry, limit, commit = checkSliding(keys, args, req)
+  else
+    return redis.error_reply("unknown algorithm " .. tostring(algo))
+  end
+
+  if ok then
+    commits[#commits + 1] = commit
+    granted[i] = req
+    out[#out + 1] = 1
+  else
+    if out[1] == 1 or retry > out[3] then
+      out[1], out[2], out[3] = 0, i - 1, retry
+    end
+    out[#out + 1] = 0
+  end
+  out[#out + 1] = remaining
+  out[#out + 1] = retry
+  out[#out + 1] = math.floor(limit)
+end
+
+if out[1] == 0 then
+  -- 整体拒绝：满足条件的限流器也没有扣减，剩余额度还原为扣减前
+  for i, req in pairs(granted) do
+    out[4 * i + 1] = out[4 * i + 1] + req
+  end
+  return out
+end
+
+for _, commit in ipairs(commits) do
+  commit()
+end
+return out
+`)
+
+// banScript 记录一次被拒绝，并在短时间内被拒绝次数过多时封禁该 key：
+//   - strikes 在 windowMs 内累计被拒绝次数（第一次计数时设置过期时间）
+//   - strikes > threshold 时清零 strikes，封禁等级 level 加 1，
+//     封禁时长为 base * 2^(level-1)，不超过 max
+//   - level 在封禁结束后再保留 memoryMs，期间再次被封禁时时长继续翻倍
+//
+// KEYS[1] = strikesKey
+// KEYS[2] = levelKey
+// KEYS[3] = banKey（存在即表示被封禁，value 为封禁等级）
+//
+// ARGV[1] = threshold （窗口内允许的被拒绝次数）
+// ARGV[2] = windowMs  （统计窗口，毫秒）
+// ARGV[3] = baseMs    （第一次封禁的时长，毫秒）
+// ARGV[4] = maxMs     （封禁时长上限，毫秒）
+// ARGV[5] = memoryMs  （封禁结束后封禁等级的保留时长，毫秒）
+//
+// 返回：本次触发的封禁时长（毫秒），未触发封禁时为 0
+var banScript = newScript(`
+local threshold = tonumber(ARGV[1])
+local window    = tonumber(ARGV[2])
+local base      = tonumber(ARGV[3])
+local maxBan    = tonumber(ARGV[4])
+local memory    = tonumber(ARGV[5])
+
+local strikes = redis.call("INCR", KEYS[1])
+if strikes == 1 then
+  redis.call("PEXPIRE", KEYS[1], window)
+end
+if strikes <= threshold then
+  return 0
+end
+
+redis.call("DEL", KEYS[1])
+local level = redis.call("INCR", KEYS[2])
+local ttl = math.floor(math.min(base * 2 ^ (level - 1), maxBan))
+redis.call("SET", KEYS[3], level, "PX", ttl)
+redis.call("PEXPIRE", KEYS[2], ttl + memory)
+return ttl
+`)
+
+// onceScript 为 OnceLimiter 争夺本周期的执行权：
+//   - 周期序号 slot = floor(now / interval)
+//   - key 中记录的周期序号 >= slot 时说明本周期已有实例执行过，拒绝
+//   - 否则写入 slot，并在本周期结束时过期
+//
+// KEYS[1] = onceKey
+//
+// ARGV[1] = nowMs      （0 表示使用 Redis TIME）
+// ARGV[2] = intervalMs （周期长度，毫秒）
+//
+// 返回：{是否拿到执行权(1/0), 距离下一个周期开始的毫秒数}
+var onceScript = newScript(luaServerTime + `
+local now      = resolveNow(tonumber(ARGV[1]))
+local interval = tonumber(ARGV[2])
+
+local slot = math.floor(now / interval)
+local left = (slot + 1) * interval - now
+if left < 1 then
+  left = 1
+end
+
+local last = tonumber(redis.call("GET", KEYS[1]))
+if last ~= nil and last >= slot then
+  return {0, left}
+end
+
+redis.call("SET", KEYS[1], slot, "PX", left)
+return {1, left}
+`)
+
+// fairQueueScript 为 FairLimiter 排队：领取（或保持）排队号，并返回是否排在队首。
+//
+// KEYS[1] = queueKey（ZSET，member 为排队号，score 为领号顺序）
+// KEYS[2] = leaseKey（ZSET，member 为排队号，score 为该等待者放弃等待的时间，毫秒）
+// KEYS[3] = seqKey  （领号序列）
+//
+// ARGV[1] = nowMs   （0 表示使用 Redis TIME）
+// ARGV[2] = ticket
+// ARGV[3] = leaseMs （首次领号时排队号的保留时长，毫秒；过了截止时间仍在队列中视为已失联）
+//
+// 三个 key 的过期时间为排队号剩余的保留时长再加 1 分钟。
+//
+// 返回：{是否排在队首(1/0), 前面排队的人数}
+var fairQueueScript = newScript(luaServerTime + `
+local queueKey = KEYS[1]
+local leaseKey = KEYS[2]
+local seqKey   = KEYS[3]
+
+local now    = resolveNow(tonumber(ARGV[1]))
+local ticket = ARGV[2]
+local lease  = tonumber(ARGV[3])
+
+-- 清理已过截止时间的排队号（等待者崩溃或未能正常离队）
+local expired = redis.call("ZRANGEBYSCORE", leaseKey, "-inf", now)
+if #expired > 0 then
+  redis.call("ZREM", queueKey, unpack(expired))
+  redis.call("ZREMRANGEBYSCORE", leaseKey, "-inf", now)
+end
+
+-- 首次调用时领号，之后保持原有顺序与截止时间
+local expireAt = tonumber(redis.call("ZSCORE", leaseKey, ticket))
+if not expireAt then
+  local seq = redis.call("INCR", seqKey)
+  expireAt = now + lease
+  redis.call("ZADD", queueKey, seq, ticket)
+  redis.call("ZADD", leaseKey, expireAt, ticket)
+end
+
+local ttl = math.max(expireAt - now, 0) + 60000
+
+redis.call("PEXPIRE", queueKey, ttl)
+redis.call("PEXPIRE", leaseKey, ttl)
+redis.call("PEXPIRE", seqKey, ttl)
+
+local ahead = redis.call("ZRANK", queueKey, ticket)
+if ahead == 0 then
+  return {1, 0}
+end
+return {0, ahead}
+`)
+
+// scriptNow 返回传给脚本的当前时间（毫秒）。
+// serverTime 为 true 时返回 0，由脚本改用 Redis TIME（见 luaServerTime），否则取 clock 的时间。
+func scriptNow(serverTime bool, clock Clock) int64 {
+	if serverTime {
+		return 0
+	}
+	return clock.Now().UnixMilli()
+}
+
+// scriptInts 将脚本返回的整数数组解析为 []int64，长度不为 n 时返回 false。
+func scriptInts(res interface{}, n int) ([]int64, bool) {
+	vals, ok := res.([]interface{})
+	if !ok || len(vals) != n {
+		return nil, false
+	}
+	out := make([]int64, n)
+	for i, v := range vals {
+		iv, ok := v.(int64)
+		if !ok {
+			return nil, false
+		}
+		out[i] = iv
+	}
+	return out, true
+}
@@ -0,0 +1,181 @@
+package limiter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// ShadowLimiter 以“影子模式”运行被包装的限流器：照常执行判定并记录结果，
+// 但无论结果如何都放行（Redis 出错时也放行）。用于在强制执行新限额之前，
+// 先用线上真实流量验证它会拒绝多少请求。
+//
+// 判定结果可以通过以下方式观察：
+//   - Counts 返回本实例的放行 / 本应拒绝次数
+//   - OnDecision 回调，便于接入日志或指标
+//   - 开启 WithShadowCounters 后同时写入 Redis 计数器（"shadow:{key}:allowed" / "denied"），汇总所有实例
+//
+// 验证完成后调用 SetShadow(false) 即可切换为正常执行，判定结果仍会被记录。
+type ShadowLimiter struct {
+	limiter RateLimiter
+
+	// OnDecision 每次判定后的回调（可选）。res 为被包装限流器的原始结果，err 为其返回的错误
+	OnDecision func(res Result, err error)
+	// OnError 写入 Redis 计数器失败时的回调（可选），计数失败不影响判定
+	OnError func(err error)
+
+	client     *redis.Client
+	key        string
+	Prefix     string        // Redis 计数器前缀，默认 "shadow"
+	CounterTTL time.Duration // Redis 计数器的过期时间，默认 24h，每次写入时刷新
+
+	shadow  atomic.Bool
+	allowed atomic.Int64
+	denied  atomic.Int64
+}
+
+var _ RateLimiter = (*ShadowLimiter)(nil)
+
+// NewShadowLimiter 以影子模式包装 l，默认开启影子模式。
+func NewShadowLimiter(l RateLimiter, opts ...ShadowOption) *ShadowLimiter {
+	if l == nil {
+		panic("shadow: limiter is nil")
+	}
+
+	s := &ShadowLimiter{
+		limiter:    l,
+		Prefix:     "shadow",
+		CounterTTL: 24 * time.Hour,
+	}
+	s.shadow.Store(true)
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// SetShadow 运行时切换影子模式：true 只记录不拦截，false 按判定结果正常拦截。
+func (s *ShadowLimiter) SetShadow(on bool) {
+	s.shadow.Store(on)
+}
+
+// Shadow 返回当前是否处于影子模式。
+func (s *ShadowLimiter) Shadow() bool {
+	return s.shadow.Load()
+}
+
+// Counts 返回本实例记录的放行次数与（本应）拒绝次数。
+func (s *ShadowLimiter) Counts() (allowed, denied int64) {
+	return s.allowed.Load(), s.denied.Load()
+}
+
+// counterKey 返回 Redis 计数器的 key。
+func (s *ShadowLimiter) counterKey(outcome string) string {
+	return fmt.Sprintf("%s:{%s}:%s", s.Prefix, s.key, outcome)
+}
+
+// Counters 读取 Redis 中所有实例汇总的放行次数与（本应）拒绝次数。
+func (s *ShadowLimiter) Counters(ctx context.Context) (allowed, denied int64, err error) {
+	if s.client == nil {
+		return 0, 0, fmt.Errorf("shadow: redis counters are not enabled")
+	}
+
+	vals, err := s.client.MGet(ctx, s.counterKey("allowed"), s.counterKey("denied")).Result()
+	if err != nil {
+		return 0, 0, err
+	}
+	out := [2]int64{}
+	for i, v := range vals {
+		str, ok := v.(string)
+		if !ok {
+			continue
+		}
+		out[i], err = strconv.ParseInt(str, 10, 64)
+		if err != nil {
+			return 0, 0, fmt.Errorf("shadow: invalid counter: %v", err)
+		}
+	}
+	return out[0], out[1], nil
+}
+
+// record 记录一次判定，并返回影子模式下应当交给调用方的结果。
+func (s *ShadowLimiter) record(ctx context.Context, res Result, err error) (Result, error) {
+	if err == nil {
+		outcome := "allowed"
+		if res.Allowed {
+			s.allowed.Add(1)
+		} else {
+			s.denied.Add(1)
+			outcome = "denied"
+		}
+		if s.client != nil {
+			key := s.counterKey(outcome)
+			_, cerr := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+				pipe.Incr(ctx, key)
+				pipe.PExpire(ctx, key, s.CounterTTL)
+				return nil
+			})
+			if cerr != nil && s.OnError != nil {
+				s.OnError(fmt.Errorf("shadow: record counter: %w", cerr))
+			}
+		}
+	}
+	if s.OnDecision != nil {
+		s.OnDecision(res, err)
+	}
+
+	if !s.shadow.Load() {
+		return res, err
+	}
+	res.Allowed = true
+	res.RetryAfter = 0
+	return res, nil
+}
+
+func (s *ShadowLimiter) Allow(ctx context.Context) (bool, error) {
+	res, err := s.AllowWithResult(ctx)
+	return res.Allowed, err
+}
+
+func (s *ShadowLimiter) AllowWithResult(ctx context.Context) (Result, error) {
+	res, err := s.limiter.AllowWithResult(ctx)
+	return s.record(ctx, res, err)
+}
+
+func (s *ShadowLimiter) AllowN(ctx context.Context, n int64) (bool, error) {
+	ok, err := s.limiter.AllowN(ctx, n)
+	res, err := s.record(ctx, Result{Allowed: ok}, err)
+	return res.Allowed, err
+}
+
+// Wait 影子模式下只判定一次并立即返回，不会真正等待；否则直接转发给被包装的限流器。
+func (s *ShadowLimiter) Wait(ctx context.Context, maxWait time.Duration) error {
+	if !s.shadow.Load() {
+		err := s.limiter.Wait(ctx, maxWait)
+		var le *LimitExceededError
+		switch {
+		case err == nil:
+			_, _ = s.record(ctx, Result{Allowed: true}, nil)
+		case errors.As(err, &le):
+			_, _ = s.record(ctx, Result{Remaining: le.Remaining, RetryAfter: le.RetryAfter}, nil)
+		default:
+			_, _ = s.record(ctx, Result{}, err)
+		}
+		return err
+	}
+	_, err := s.AllowWithResult(ctx)
+	return err
+}
+
+func (s *ShadowLimiter) State(ctx context.Context) (LimiterState, error) {
+	return s.limiter.State(ctx)
+}
+
+func (s *ShadowLimiter) Reset(ctx context.Context) error {
+	return s.limiter.Reset(ctx)
+}
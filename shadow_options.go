@@ -0,0 +1,62 @@
+package limiter
+
+import (
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// ShadowOption 为影子模式限流器的配置项。
+type ShadowOption func(*ShadowLimiter)
+
+// WithShadowMode 设置初始是否处于影子模式（默认 true）。
+// 传入 false 时按判定结果正常拦截，但仍然记录判定结果，之后可以通过 SetShadow 切换。
+func WithShadowMode(on bool) ShadowOption {
+	return func(s *ShadowLimiter) {
+		s.shadow.Store(on)
+	}
+}
+
+// WithShadowCounters 开启 Redis 计数器，汇总所有实例的判定结果。
+// key 为计数器使用的业务 key，通常与被包装限流器的 key 相同。
+func WithShadowCounters(client *redis.Client, key string) ShadowOption {
+	return func(s *ShadowLimiter) {
+		if client == nil || key == "" {
+			panic("shadow: counters require redis client and key")
+		}
+		s.client = client
+		s.key = key
+	}
+}
+
+// WithShadowCounterTTL 设置 Redis 计数器的过期时间。
+func WithShadowCounterTTL(d time.Duration) ShadowOption {
+	return func(s *ShadowLimiter) {
+		if d > 0 {
+			s.CounterTTL = d
+		}
+	}
+}
+
+// WithShadowPrefix 设置 Redis 计数器的前缀。
+func WithShadowPrefix(prefix string) ShadowOption {
+	return func(s *ShadowLimiter) {
+		if prefix != "" {
+			s.Prefix = prefix
+		}
+	}
+}
+
+// WithShadowDecisionHandler 设置每次判定后的回调，例如记录日志或上报指标。
+func WithShadowDecisionHandler(fn func(res Result, err error)) ShadowOption {
+	return func(s *ShadowLimiter) {
+		s.OnDecision = fn
+	}
+}
+
+// WithShadowErrorHandler 设置写入 Redis 计数器失败时的回调。
+func WithShadowErrorHandler(fn func(err error)) ShadowOption {
+	return func(s *ShadowLimiter) {
+		s.OnError = fn
+	}
+}
@@ -0,0 +1,69 @@
+package limiter
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/go-redis/redismock/v8"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShadowLimiter(t *testing.T) {
+	client := newSpecClient(t)
+	ctx := context.Background()
+
+	fw := NewFixedWindowLimiter(client, "shadow", WithFixedWindowLimit(2), WithFixedWindowWindow(time.Minute))
+	var decisions []bool
+	s := NewShadowLimiter(fw,
+		WithShadowCounters(client, "shadow"),
+		WithShadowDecisionHandler(func(res Result, err error) { decisions = append(decisions, res.Allowed) }))
+
+	// 影子模式：超过限额也放行，但记录为本应拒绝
+	for i := 0; i < 4; i++ {
+		res, err := s.AllowWithResult(ctx)
+		assert.NoError(t, err)
+		assert.True(t, res.Allowed)
+		assert.Equal(t, time.Duration(0), res.RetryAfter)
+	}
+	assert.NoError(t, s.Wait(ctx, 0))
+	assert.Equal(t, []bool{true, true, false, false, false}, decisions)
+
+	allowed, denied := s.Counts()
+	assert.Equal(t, int64(2), allowed)
+	assert.Equal(t, int64(3), denied)
+	allowed, denied, err := s.Counters(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), allowed)
+	assert.Equal(t, int64(3), denied)
+
+	// 切换为正常执行后按判定结果拦截，仍然计数
+	s.SetShadow(false)
+	ok, err := s.Allow(ctx)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	assert.True(t, errors.Is(s.Wait(ctx, 0), ErrLimiter))
+	_, denied = s.Counts()
+	assert.Equal(t, int64(5), denied)
+
+	_, _, err = NewShadowLimiter(fw).Counters(ctx)
+	assert.Error(t, err)
+}
+
+func TestShadowLimiter_RedisError(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer db.Close()
+	ctx := context.Background()
+
+	fw := NewFixedWindowLimiter(db, "shadow")
+	mock.ExpectEvalSha(fixedWindowScript.Hash(), []string{"fw:{shadow}:count"}, int64(60_000), int64(60), int64(1)).SetErr(redis.ErrClosed)
+
+	var got error
+	s := NewShadowLimiter(fw, WithShadowDecisionHandler(func(res Result, err error) { got = err }))
+	ok, err := s.Allow(ctx)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.ErrorIs(t, got, redis.ErrClosed)
+}
@@ -0,0 +1,112 @@
+package limiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShardedTokenBucket_Borrow(t *testing.T) {
+	mr := miniredis.RunT(t)
+	mr.SetTime(time.UnixMilli(1_700_000_000_000))
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	ctx := context.Background()
+
+	s := NewShardedTokenBucketLimiter(client, "borrow", WithShardedTokenBucketCount(2),
+		WithShardedTokenBucket(WithTokenBucketRate(2), WithTokenBucketCapacity(20)))
+	sibling := s.shards[(s.pick("u1")+1)%2]
+
+	ok, err := s.AllowN(ctx, "u1", 10)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	// 未开启借用：本分片耗尽即拒绝
+	res, err := s.AllowWithResult(ctx, "u1")
+	assert.NoError(t, err)
+	assert.False(t, res.Allowed)
+	assert.Greater(t, res.RetryAfter, time.Duration(0))
+
+	assert.Error(t, s.SetBorrowBudget(1.5))
+	assert.NoError(t, s.SetBorrowBudget(0.5))
+
+	// 相邻分片最多借出一半容量（5 个）
+	for i := 0; i < 5; i++ {
+		ok, err = s.Allow(ctx, "u1")
+		assert.NoError(t, err)
+		assert.True(t, ok)
+	}
+	ok, err = s.Allow(ctx, "u1")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	// State 在本地模拟 refill，需与 miniredis 的时间保持一致
+	sibling.Clock = ClockFunc(func() time.Time { return time.UnixMilli(1_700_000_000_000) })
+	st, err := sibling.State(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, float64(5), st.Remaining)
+
+	// 保留给相邻分片自身流量的部分不受影响
+	res, err = sibling.AllowNWithResult(ctx, 5)
+	assert.NoError(t, err)
+	assert.True(t, res.Allowed)
+}
+
+func TestShardedTokenBucket_BorrowReportsFinalOutcome(t *testing.T) {
+	client := newSpecClient(t)
+	ctx := context.Background()
+
+	var allowed, denied int
+	hooks := HookFuncs{
+		Allow: func(context.Context, HookEvent) { allowed++ },
+		Deny:  func(context.Context, HookEvent) { denied++ },
+	}
+	s := NewShardedTokenBucketLimiter(client, "borrow-hooks", WithShardedTokenBucketCount(2),
+		WithShardedTokenBucketBorrowBudget(0.5),
+		WithShardedTokenBucket(WithTokenBucketRate(0.001), WithTokenBucketCapacity(8),
+			WithTokenBucketHooks(hooks), WithTokenBucketStats(time.Hour)))
+	home := s.shards[s.pick("u1")]
+
+	ok, err := s.AllowN(ctx, "u1", 4)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	// 本分片耗尽后向相邻分片借到 1 个：只记为 1 次放行
+	ok, err = s.Allow(ctx, "u1")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, 2, allowed)
+	assert.Zero(t, denied)
+
+	// 批量判定同样只按最终结果记录：借到第 2 个后借用额度耗尽，下一个被拒绝
+	out, err := s.AllowKeys(ctx, []string{"u1", "u1"})
+	assert.NoError(t, err)
+	assert.True(t, out[0].Allowed)
+	assert.False(t, out[1].Allowed)
+	assert.Equal(t, 3, allowed)
+	assert.Equal(t, 1, denied)
+
+	stats, err := home.Stats(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(6), stats.Allowed)
+	assert.Equal(t, int64(1), stats.Denied)
+}
+
+func TestShardedTokenBucket_BorrowReplicate(t *testing.T) {
+	client := newSpecClient(t)
+
+	// 每个分片都是完整限额时借用会突破全局限额，不允许开启
+	s := NewShardedTokenBucketLimiter(client, "borrow-replicate",
+		WithShardedTokenBucketScaling(ShardReplicate))
+	assert.Error(t, s.SetBorrowBudget(0.5))
+	assert.NoError(t, s.SetBorrowBudget(0))
+	assert.Zero(t, s.BorrowBudget())
+
+	assert.Panics(t, func() {
+		NewShardedTokenBucketLimiter(client, "borrow-replicate",
+			WithShardedTokenBucketScaling(ShardReplicate), WithShardedTokenBucketBorrowBudget(0.5))
+	})
+}
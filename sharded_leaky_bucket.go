@@ -3,7 +3,6 @@ package limiter
 import (
 	"context"
 	"fmt"
-	"hash/fnv"
 	"time"
 
 	"github.com/go-redis/redis/v8"
@@ -13,21 +12,31 @@ import (
 // 通过多个 LeakyBucketLimiter 分摊压力，提升吞吐能力。
 // 使用 shardKey 做路由（例如 userID、IP、tenantID）。
 type ShardedLeakyBucketLimiter struct {
+	client *redis.Client
+	key    string
 	shards []*LeakyBucketLimiter
 	count  int
+
+	// keyTemplate 分片 key 的格式，依次接收全局 key 与分片序号，默认 "%s:shard:%d"
+	keyTemplate string
+	// scaling 限额在分片间的分配方式，默认 ShardDivide
+	scaling ShardScaling
+	// hash shardKey 到分片序号的映射，默认 FNVHash
+	hash ShardHash
+	// opts 每个分片的漏桶配置
+	opts []LeakyBucketOption
 }
 
 // NewShardedLeakyBucketLimiter 创建一个分片漏桶限流器。
-// 说明：
-//   - key 是全局逻辑 key，实际每个 shard 会在 key 后增加 ":shard:i"
-//   - shardCount 为分片数量，会直接影响单 shard 的 LeakRate/Capacity
-//   - opts 为基础 LeakyBucket 配置（LeakRate/Capacity/TTL/Prefix等）
-//     然后内部会将 LeakRate 和 Capacity 均摊到各 shard 上。
+//   - client: Redis 客户端
+//   - key:    全局业务 key（例如 "api:/v1/chat"）
+//   - opts:   分片配置（分片数量、分片 key 格式，以及每个分片的漏桶配置）
+//     注意：LeakRate 和 Capacity 会在内部按分片数均分到每个 shard 上，
+//     可通过 scaling 配置改为每个 shard 使用完整限额（ShardReplicate）。
 func NewShardedLeakyBucketLimiter(
 	client *redis.Client,
 	key string,
-	shardCount int,
-	opts ...LeakyBucketOption,
+	opts ...ShardedLeakyBucketOption,
 ) *ShardedLeakyBucketLimiter {
 
 	if client == nil {
@@ -36,46 +45,42 @@ func NewShardedLeakyBucketLimiter(
 	if key == "" {
 		panic("sharded leaky bucket: key is empty")
 	}
-	if shardCount <= 0 {
-		shardCount = 16
-	}
-
-	shards := make([]*LeakyBucketLimiter, shardCount)
 
-	for i := 0; i < shardCount; i++ {
-		shardKey := fmt.Sprintf("%s:shard:%d", key, i)
+	s := &ShardedLeakyBucketLimiter{
+		client:      client,
+		key:         key,
+		count:       16,
+		keyTemplate: "%s:shard:%d",
+		hash:        FNVHash,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
 
-		// 复制外部的 opts，避免多 shard 间共享同一个 slice 产生副作用
-		innerOpts := append([]LeakyBucketOption{}, opts...)
+	s.shards = make([]*LeakyBucketLimiter, s.count)
+	for i := 0; i < s.count; i++ {
+		// 拷贝一份 opts，避免对原切片产生副作用
+		innerOpts := append([]LeakyBucketOption{}, s.opts...)
 
-		// 通过 Custom Option，在每个 shard 上执行“均摊速率与容量”的逻辑。
+		// 使用 Custom Option 在每个 shard 上均摊限额（仅 ShardDivide 策略），均分后的容量至少为 1
 		innerOpts = append(innerOpts, WithLeakyBucketCustom(func(l *LeakyBucketLimiter) {
-			// 按 shardCount 均分 LeakRate 和 Capacity
-			l.LeakRate = l.LeakRate / float64(shardCount)
-			if l.LeakRate <= 0 {
-				l.LeakRate = 1 // 最小保护值
-			}
-			l.Capacity = l.Capacity / float64(shardCount)
-			if l.Capacity <= 0 {
-				l.Capacity = 1
-			}
+			l.LeakRate, _ = shardFloat(s.scaling, l.LeakRate, s.count, false)
+			l.Capacity, _ = shardFloat(s.scaling, l.Capacity, s.count, true)
 		}))
 
-		shards[i] = NewLeakyBucketLimiter(client, shardKey, innerOpts...)
+		s.shards[i] = NewLeakyBucketLimiter(client, s.shardKey(i), innerOpts...)
 	}
+	return s
+}
 
-	return &ShardedLeakyBucketLimiter{
-		shards: shards,
-		count:  shardCount,
-	}
+// shardKey 返回第 i 个分片的业务 key。
+func (s *ShardedLeakyBucketLimiter) shardKey(i int) string {
+	return fmt.Sprintf(s.keyTemplate, s.key, i)
 }
 
-// pick 根据 shardKey 计算落在哪个 shard 上。
-// 使用 FNV-1a 哈希，简单且足够均匀。
+// pick 根据 shardKey 选择某一个 shard，默认使用 FNV-1a 哈希，可通过 hash 配置替换。
 func (s *ShardedLeakyBucketLimiter) pick(shardKey string) int {
-	h := fnv.New32a()
-	_, _ = h.Write([]byte(shardKey))
-	return int(h.Sum32()) % s.count
+	return shardIndex(s.hash, shardKey, s.count)
 }
 
 // Allow 尝试对指定 shardKey 获取一个许可。
@@ -85,12 +90,34 @@ func (s *ShardedLeakyBucketLimiter) Allow(ctx context.Context, shardKey string)
 	return s.shards[idx].Allow(ctx)
 }
 
+// AllowWithResult 对指定 shardKey 尝试获取 1 个许可，并返回剩余额度及重试等待时间。
+func (s *ShardedLeakyBucketLimiter) AllowWithResult(ctx context.Context, shardKey string) (Result, error) {
+	idx := s.pick(shardKey)
+	return s.shards[idx].AllowWithResult(ctx)
+}
+
 // AllowN 尝试对指定 shardKey 获取 n 个许可。
 func (s *ShardedLeakyBucketLimiter) AllowN(ctx context.Context, shardKey string, n int64) (bool, error) {
 	idx := s.pick(shardKey)
 	return s.shards[idx].AllowN(ctx, n)
 }
 
+// AllowUpToN 对指定 shardKey 尝试获取最多 n 个许可，返回实际获取的数量。
+func (s *ShardedLeakyBucketLimiter) AllowUpToN(ctx context.Context, shardKey string, n int64) (int64, error) {
+	idx := s.pick(shardKey)
+	return s.shards[idx].AllowUpToN(ctx, n)
+}
+
+// AllowKeys 对每个 shardKey 各获取 1 个许可，各分片的脚本调用合并到一个 pipeline 中，一次往返完成。
+// 返回与 shardKeys 一一对应的结果，err 汇总全部出错 shardKey 的错误，各自的错误见 KeyResult.Err。
+func (s *ShardedLeakyBucketLimiter) AllowKeys(ctx context.Context, shardKeys []string) ([]KeyResult, error) {
+	limiters := make([]RateLimiter, len(shardKeys))
+	for i, shardKey := range shardKeys {
+		limiters[i] = s.shards[s.pick(shardKey)]
+	}
+	return allowAll(ctx, shardKeys, limiters)
+}
+
 // Wait 阻塞直到 shardKey 对应的漏桶中腾出空间。
 func (s *ShardedLeakyBucketLimiter) Wait(ctx context.Context, shardKey string, maxWait time.Duration) error {
 	idx := s.pick(shardKey)
@@ -101,5 +128,88 @@ func (s *ShardedLeakyBucketLimiter) Wait(ctx context.Context, shardKey string, m
 // 注意：这是“某一个 shard 的状态”，而不是全局聚合结果。
 func (s *ShardedLeakyBucketLimiter) State(ctx context.Context, shardKey string) (LimiterState, error) {
 	idx := s.pick(shardKey)
-	return s.shards[idx].State(ctx)
+	st, err := s.shards[idx].State(ctx)
+	if err != nil {
+		return LimiterState{}, err
+	}
+	st.Shard = idx
+	return st, nil
+}
+
+// GlobalState 通过一次 pipeline 读取全部分片的状态，返回聚合后的全局状态及各分片的状态，
+// 用于监控面板展示真实的整体使用率。分片之间不是原子快照。
+func (s *ShardedLeakyBucketLimiter) GlobalState(ctx context.Context) (ShardedState, error) {
+	return collectShardStates(ctx, s.client, s.key, s.shards)
+}
+
+// Reset 重置 shardKey 对应分片的状态。
+func (s *ShardedLeakyBucketLimiter) Reset(ctx context.Context, shardKey string) error {
+	idx := s.pick(shardKey)
+	return s.shards[idx].Reset(ctx)
+}
+
+// ResetAll 逐个重置所有分片。各分片的 key 不在同一个 slot，
+// 因此每个分片内部是原子的，分片之间不保证原子性。
+func (s *ShardedLeakyBucketLimiter) ResetAll(ctx context.Context) error {
+	for _, shard := range s.shards {
+		if err := shard.Reset(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetRate 在运行时修改全局泄漏速率，内部按分片数均分到每个 shard（与构造时的换算一致）；
+// ShardReplicate 策略下每个 shard 直接使用该值。
+func (s *ShardedLeakyBucketLimiter) SetRate(rate float64) error {
+	if rate <= 0 {
+		return fmt.Errorf("sharded leaky bucket: rate must > 0")
+	}
+	perShard, _ := shardFloat(s.scaling, rate, s.count, false)
+	for _, shard := range s.shards {
+		if err := shard.SetRate(perShard); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetCapacity 在运行时修改全局容量，内部按分片数均分到每个 shard（与构造时的换算一致）；
+// ShardReplicate 策略下每个 shard 直接使用该值。均分后小于 1 时返回错误。
+func (s *ShardedLeakyBucketLimiter) SetCapacity(capacity float64) error {
+	if capacity <= 0 {
+		return fmt.Errorf("sharded leaky bucket: capacity must > 0")
+	}
+	perShard, err := shardFloat(s.scaling, capacity, s.count, true)
+	if err != nil {
+		return fmt.Errorf("sharded leaky bucket: capacity %w", err)
+	}
+	for _, shard := range s.shards {
+		if err := shard.SetCapacity(perShard); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Reconfigure 按分片策略换算全局速率和容量后应用到每个 shard，实现 Reconfigurable。
+// 每个 shard 内部是原子的，shard 之间会有极短的新旧参数并存。
+func (s *ShardedLeakyBucketLimiter) Reconfigure(c LimitConfig) error {
+	if err := c.validate(); err != nil {
+		return err
+	}
+	per := c
+	per.Rate, _ = shardFloat(s.scaling, c.Rate, s.count, false)
+	if c.Capacity > 0 {
+		var err error
+		if per.Capacity, err = shardFloat(s.scaling, c.Capacity, s.count, true); err != nil {
+			return fmt.Errorf("sharded leaky bucket: capacity %w", err)
+		}
+	}
+	for _, shard := range s.shards {
+		if err := shard.Reconfigure(per); err != nil {
+			return err
+		}
+	}
+	return nil
 }
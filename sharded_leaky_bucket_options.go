@@ -0,0 +1,48 @@
+package limiter
+
+// ShardedLeakyBucketOption 为分片漏桶的配置项。
+type ShardedLeakyBucketOption func(*ShardedLeakyBucketLimiter)
+
+// WithShardedLeakyBucketCount 设置分片数量，默认 16。
+func WithShardedLeakyBucketCount(count int) ShardedLeakyBucketOption {
+	return func(s *ShardedLeakyBucketLimiter) {
+		if count > 0 {
+			s.count = count
+		}
+	}
+}
+
+// WithShardedLeakyBucketKeyTemplate 设置分片 key 的格式，默认 "%s:shard:%d"。
+// 模板按 fmt 格式依次接收全局 key（%s）与分片序号（%d）。
+func WithShardedLeakyBucketKeyTemplate(template string) ShardedLeakyBucketOption {
+	return func(s *ShardedLeakyBucketLimiter) {
+		if template != "" {
+			s.keyTemplate = template
+		}
+	}
+}
+
+// WithShardedLeakyBucket 设置每个分片的漏桶配置（全局 LeakRate/Capacity/TTL/Prefix 等）。
+// 可多次调用，配置按顺序追加。
+func WithShardedLeakyBucket(opts ...LeakyBucketOption) ShardedLeakyBucketOption {
+	return func(s *ShardedLeakyBucketLimiter) {
+		s.opts = append(s.opts, opts...)
+	}
+}
+
+// WithShardedLeakyBucketScaling 设置限额在分片间的分配方式，默认 ShardDivide（均分）。
+func WithShardedLeakyBucketScaling(scaling ShardScaling) ShardedLeakyBucketOption {
+	return func(s *ShardedLeakyBucketLimiter) {
+		s.scaling = scaling
+	}
+}
+
+// WithShardedLeakyBucketHash 设置 shardKey 到分片序号的哈希函数，默认 FNVHash。
+// 需要在线调整分片数时可使用 JumpHash 减少 key 迁移；也可以传入自定义函数处理预先哈希好的标识。
+func WithShardedLeakyBucketHash(hash ShardHash) ShardedLeakyBucketOption {
+	return func(s *ShardedLeakyBucketLimiter) {
+		if hash != nil {
+			s.hash = hash
+		}
+	}
+}
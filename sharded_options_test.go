@@ -0,0 +1,123 @@
+package limiter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShardedOptions(t *testing.T) {
+	client := newSpecClient(t)
+	ctx := context.Background()
+
+	tb := NewShardedTokenBucketLimiter(client, "opts",
+		WithShardedTokenBucketCount(3),
+		WithShardedTokenBucketKeyTemplate("%s#%d"),
+		WithShardedTokenBucketBorrowBudget(0.3),
+		WithShardedTokenBucket(WithTokenBucketCapacity(30)),
+		WithShardedTokenBucket(WithTokenBucketPrefix("tb")),
+	)
+	assert.Len(t, tb.shards, 3)
+	assert.Equal(t, "opts#2", tb.shards[2].Key)
+	assert.Equal(t, float64(10), tb.shards[0].Capacity)
+	assert.Equal(t, 0.3, tb.BorrowBudget())
+
+	ok, err := tb.Allow(ctx, "u1")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	n, _ := client.Exists(ctx, "tb:{"+tb.shards[tb.pick("u1")].Key+"}:tokens").Result()
+	assert.Equal(t, int64(1), n)
+
+	lb := NewShardedLeakyBucketLimiter(client, "opts", WithShardedLeakyBucketKeyTemplate("%s-%d"))
+	assert.Len(t, lb.shards, 16)
+	assert.Equal(t, "opts-15", lb.shards[15].Key)
+
+	sw := NewShardedSlidingWindowLimiter(client, "opts",
+		WithShardedSlidingWindowCount(2),
+		WithShardedSlidingWindow(WithSlidingWindowLimit(10)))
+	assert.Equal(t, "opts:shard:1", sw.shards[1].Key)
+	assert.Equal(t, int64(5), sw.shards[1].Limit)
+}
+
+func TestShardedScaling(t *testing.T) {
+	client := newSpecClient(t)
+
+	tb := NewShardedTokenBucketLimiter(client, "scaling",
+		WithShardedTokenBucketCount(4),
+		WithShardedTokenBucketScaling(ShardReplicate),
+		WithShardedTokenBucket(WithTokenBucketRate(8), WithTokenBucketCapacity(100)))
+	assert.Equal(t, float64(8), tb.shards[3].Rate)
+	assert.Equal(t, float64(100), tb.shards[3].Capacity)
+	assert.NoError(t, tb.SetCapacity(40))
+	assert.Equal(t, float64(40), tb.shards[0].Capacity)
+
+	lb := NewShardedLeakyBucketLimiter(client, "scaling",
+		WithShardedLeakyBucketCount(4),
+		WithShardedLeakyBucketScaling(ShardReplicate),
+		WithShardedLeakyBucket(WithLeakyBucketRate(8), WithLeakyBucketCapacity(100)))
+	assert.Equal(t, float64(100), lb.shards[1].Capacity)
+	assert.NoError(t, lb.Reconfigure(LimitConfig{Rate: 20}))
+	assert.Equal(t, float64(20), lb.shards[1].LeakRate)
+
+	sw := NewShardedSlidingWindowLimiter(client, "scaling",
+		WithShardedSlidingWindowCount(4),
+		WithShardedSlidingWindowScaling(ShardReplicate),
+		WithShardedSlidingWindow(WithSlidingWindowLimit(10)))
+	assert.Equal(t, int64(10), sw.shards[2].Limit)
+
+	// 默认均分
+	div := NewShardedSlidingWindowLimiter(client, "scaling",
+		WithShardedSlidingWindowCount(4),
+		WithShardedSlidingWindow(WithSlidingWindowLimit(10)))
+	assert.Equal(t, int64(2), div.shards[2].Limit)
+	assert.NoError(t, div.SetLimit(40))
+	assert.Equal(t, int64(10), div.shards[2].Limit)
+}
+
+func TestShardedDivideConsistent(t *testing.T) {
+	client := newSpecClient(t)
+
+	// 构造与 SetRate / Reconfigure 使用同一套换算：8/s 分到 16 个分片为每片 0.5/s，重复设置同一个值不改变分片限额
+	tb := NewShardedTokenBucketLimiter(client, "divide",
+		WithShardedTokenBucketCount(16),
+		WithShardedTokenBucket(WithTokenBucketRate(8), WithTokenBucketCapacity(32)))
+	assert.Equal(t, 0.5, tb.shards[0].Rate)
+	assert.NoError(t, tb.SetRate(8))
+	assert.NoError(t, tb.SetCapacity(32))
+	assert.Equal(t, 0.5, tb.shards[0].Rate)
+	assert.Equal(t, float64(2), tb.shards[0].Capacity)
+
+	lb := NewShardedLeakyBucketLimiter(client, "divide",
+		WithShardedLeakyBucketCount(16),
+		WithShardedLeakyBucket(WithLeakyBucketRate(8), WithLeakyBucketCapacity(32)))
+	assert.NoError(t, lb.Reconfigure(LimitConfig{Rate: 8, Capacity: 32}))
+	assert.Equal(t, 0.5, lb.shards[0].LeakRate)
+	assert.Equal(t, float64(2), lb.shards[0].Capacity)
+
+	// 均分后小于 1 的容量 / 上限被拒绝，而不是向上取整放大全局限额
+	assert.Error(t, tb.SetCapacity(8))
+	assert.Equal(t, float64(2), tb.shards[0].Capacity)
+	assert.Error(t, lb.SetCapacity(8))
+	sw := NewShardedSlidingWindowLimiter(client, "divide",
+		WithShardedSlidingWindowCount(16),
+		WithShardedSlidingWindow(WithSlidingWindowLimit(32)))
+	assert.Error(t, sw.SetLimit(8))
+	assert.Error(t, sw.Reconfigure(LimitConfig{Limit: 8}))
+	assert.Equal(t, int64(2), sw.shards[0].Limit)
+
+	// 构造时不 panic：均分后小于 1 的容量 / 上限按每个分片 1 处理
+	small := NewShardedTokenBucketLimiter(client, "divide-small",
+		WithShardedTokenBucket(WithTokenBucketCapacity(10)))
+	assert.Equal(t, float64(1), small.shards[0].Capacity)
+	ok, err := small.Allow(context.Background(), "u1")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	smallLB := NewShardedLeakyBucketLimiter(client, "divide-small",
+		WithShardedLeakyBucket(WithLeakyBucketCapacity(10)))
+	assert.Equal(t, float64(1), smallLB.shards[0].Capacity)
+	smallSW := NewShardedSlidingWindowLimiter(client, "divide-small",
+		WithShardedSlidingWindowCount(16),
+		WithShardedSlidingWindow(WithSlidingWindowLimit(8)))
+	assert.Equal(t, int64(1), smallSW.shards[0].Limit)
+}
@@ -3,7 +3,6 @@ package limiter
 import (
 	"context"
 	"fmt"
-	"hash/fnv"
 	"time"
 
 	"github.com/go-redis/redis/v8"
@@ -13,21 +12,31 @@ import (
 // 将一个全局限流拆成多个滑动窗口 shard，使用 shardKey 路由请求。
 // 典型场景：针对某个 API，按用户 ID/IP 分 shard 做限流，避免单 key 热点。
 type ShardedSlidingWindowLimiter struct {
+	client *redis.Client
+	key    string
 	shards []*SingleSlidingWindowLimiter
 	count  int
+
+	// keyTemplate 分片 key 的格式，依次接收全局 key 与分片序号，默认 "%s:shard:%d"
+	keyTemplate string
+	// scaling 限额在分片间的分配方式，默认 ShardDivide
+	scaling ShardScaling
+	// hash shardKey 到分片序号的映射，默认 FNVHash
+	hash ShardHash
+	// opts 每个分片的滑动窗口配置
+	opts []SlidingWindowOption
 }
 
 // NewShardedSlidingWindowLimiter 创建一个分片滑动窗口限流器。
 //   - client: Redis 客户端
-//   - key:    全局业务 key，例如 "api:/v1/chat"
-//   - shardCount: 分片数量，传 <=0 默认使用 16
-//   - opts:   滑动窗口参数（Window/Limit/TTL/Prefix 等）
-//     注意：Limit 会在内部按 shardCount 均分。
+//   - key:    全局业务 key（例如 "api:/v1/chat"）
+//   - opts:   分片配置（分片数量、分片 key 格式，以及每个分片的滑动窗口配置）
+//     注意：Limit 会在内部按分片数均分到每个 shard 上，
+//     可通过 scaling 配置改为每个 shard 使用完整限额（ShardReplicate）。
 func NewShardedSlidingWindowLimiter(
 	client *redis.Client,
 	key string,
-	shardCount int,
-	opts ...SlidingWindowOption,
+	opts ...ShardedSlidingWindowOption,
 ) *ShardedSlidingWindowLimiter {
 
 	if client == nil {
@@ -36,39 +45,41 @@ func NewShardedSlidingWindowLimiter(
 	if key == "" {
 		panic("sharded sliding window: key is empty")
 	}
-	if shardCount <= 0 {
-		shardCount = 16
-	}
-
-	shards := make([]*SingleSlidingWindowLimiter, shardCount)
 
-	for i := 0; i < shardCount; i++ {
-		shardKey := fmt.Sprintf("%s:shard:%d", key, i)
+	s := &ShardedSlidingWindowLimiter{
+		client:      client,
+		key:         key,
+		count:       16,
+		keyTemplate: "%s:shard:%d",
+		hash:        FNVHash,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
 
-		innerOpts := append([]SlidingWindowOption{}, opts...)
+	s.shards = make([]*SingleSlidingWindowLimiter, s.count)
+	for i := 0; i < s.count; i++ {
+		// 拷贝一份 opts，避免对原切片产生副作用
+		innerOpts := append([]SlidingWindowOption{}, s.opts...)
 
-		// 通过 Custom Option 在每个 shard 上均摊 Limit。
+		// 使用 Custom Option 在每个 shard 上均摊限额（仅 ShardDivide 策略），均分后的上限至少为 1
 		innerOpts = append(innerOpts, WithSlidingWindowCustom(func(l *SingleSlidingWindowLimiter) {
-			l.Limit = l.Limit / int64(shardCount)
-			if l.Limit <= 0 {
-				l.Limit = 1
-			}
+			l.Limit, _ = shardInt(s.scaling, l.Limit, s.count)
 		}))
 
-		shards[i] = NewSlidingWindowLimiter(client, shardKey, innerOpts...)
+		s.shards[i] = NewSlidingWindowLimiter(client, s.shardKey(i), innerOpts...)
 	}
+	return s
+}
 
-	return &ShardedSlidingWindowLimiter{
-		shards: shards,
-		count:  shardCount,
-	}
+// shardKey 返回第 i 个分片的业务 key。
+func (s *ShardedSlidingWindowLimiter) shardKey(i int) string {
+	return fmt.Sprintf(s.keyTemplate, s.key, i)
 }
 
-// pick 根据 shardKey 选择一个 shard。
+// pick 根据 shardKey 选择某一个 shard，默认使用 FNV-1a 哈希，可通过 hash 配置替换。
 func (s *ShardedSlidingWindowLimiter) pick(shardKey string) int {
-	h := fnv.New32a()
-	_, _ = h.Write([]byte(shardKey))
-	return int(h.Sum32()) % s.count
+	return shardIndex(s.hash, shardKey, s.count)
 }
 
 // Allow 对指定 shardKey 尝试通过一个请求。
@@ -77,12 +88,28 @@ func (s *ShardedSlidingWindowLimiter) Allow(ctx context.Context, shardKey string
 	return s.shards[idx].Allow(ctx)
 }
 
+// AllowWithResult 对指定 shardKey 尝试获取 1 个许可，并返回剩余额度及重试等待时间。
+func (s *ShardedSlidingWindowLimiter) AllowWithResult(ctx context.Context, shardKey string) (Result, error) {
+	idx := s.pick(shardKey)
+	return s.shards[idx].AllowWithResult(ctx)
+}
+
 // AllowN 对指定 shardKey 尝试通过 n 个请求。
 func (s *ShardedSlidingWindowLimiter) AllowN(ctx context.Context, shardKey string, n int64) (bool, error) {
 	idx := s.pick(shardKey)
 	return s.shards[idx].AllowN(ctx, n)
 }
 
+// AllowKeys 对每个 shardKey 各获取 1 个许可，各分片的脚本调用合并到一个 pipeline 中，一次往返完成。
+// 返回与 shardKeys 一一对应的结果，err 汇总全部出错 shardKey 的错误，各自的错误见 KeyResult.Err。
+func (s *ShardedSlidingWindowLimiter) AllowKeys(ctx context.Context, shardKeys []string) ([]KeyResult, error) {
+	limiters := make([]RateLimiter, len(shardKeys))
+	for i, shardKey := range shardKeys {
+		limiters[i] = s.shards[s.pick(shardKey)]
+	}
+	return allowAll(ctx, shardKeys, limiters)
+}
+
 // Wait 对指定 shardKey 阻塞直到窗口中有空间，或 ctx 超时。
 func (s *ShardedSlidingWindowLimiter) Wait(ctx context.Context, shardKey string, maxWait time.Duration) error {
 	idx := s.pick(shardKey)
@@ -92,5 +119,81 @@ func (s *ShardedSlidingWindowLimiter) Wait(ctx context.Context, shardKey string,
 // State 返回 shardKey 对应分片的状态。
 func (s *ShardedSlidingWindowLimiter) State(ctx context.Context, shardKey string) (LimiterState, error) {
 	idx := s.pick(shardKey)
-	return s.shards[idx].State(ctx)
+	st, err := s.shards[idx].State(ctx)
+	if err != nil {
+		return LimiterState{}, err
+	}
+	st.Shard = idx
+	return st, nil
+}
+
+// GlobalState 通过一次 pipeline 读取全部分片的状态，返回聚合后的全局状态及各分片的状态，
+// 用于监控面板展示真实的整体使用率。分片之间不是原子快照。
+func (s *ShardedSlidingWindowLimiter) GlobalState(ctx context.Context) (ShardedState, error) {
+	return collectShardStates(ctx, s.client, s.key, s.shards)
+}
+
+// Reset 重置 shardKey 对应分片的状态。
+func (s *ShardedSlidingWindowLimiter) Reset(ctx context.Context, shardKey string) error {
+	idx := s.pick(shardKey)
+	return s.shards[idx].Reset(ctx)
+}
+
+// ResetAll 逐个重置所有分片。各分片的 key 不在同一个 slot，
+// 因此每个分片内部是原子的，分片之间不保证原子性。
+func (s *ShardedSlidingWindowLimiter) ResetAll(ctx context.Context) error {
+	for _, shard := range s.shards {
+		if err := shard.Reset(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetLimit 在运行时修改全局窗口上限，内部按分片数均分到每个 shard（向下取整，与构造时的换算一致）；
+// ShardReplicate 策略下每个 shard 直接使用该值。均分后小于 1 时返回错误。
+func (s *ShardedSlidingWindowLimiter) SetLimit(limit int64) error {
+	if limit <= 0 {
+		return fmt.Errorf("sharded sliding window: limit must > 0")
+	}
+	perShard, err := shardInt(s.scaling, limit, s.count)
+	if err != nil {
+		return fmt.Errorf("sharded sliding window: limit %w", err)
+	}
+	for _, shard := range s.shards {
+		if err := shard.SetLimit(perShard); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetWindow 在运行时修改所有分片的窗口大小。
+func (s *ShardedSlidingWindowLimiter) SetWindow(window time.Duration) error {
+	for _, shard := range s.shards {
+		if err := shard.SetWindow(window); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Reconfigure 按分片策略换算全局窗口上限后应用到每个 shard，实现 Reconfigurable。
+func (s *ShardedSlidingWindowLimiter) Reconfigure(c LimitConfig) error {
+	if err := c.validate(); err != nil {
+		return err
+	}
+	per := c
+	if c.Limit > 0 {
+		var err error
+		if per.Limit, err = shardInt(s.scaling, c.Limit, s.count); err != nil {
+			return fmt.Errorf("sharded sliding window: limit %w", err)
+		}
+	}
+	for _, shard := range s.shards {
+		if err := shard.Reconfigure(per); err != nil {
+			return err
+		}
+	}
+	return nil
 }
@@ -0,0 +1,48 @@
+package limiter
+
+// ShardedSlidingWindowOption 为分片滑动窗口的配置项。
+type ShardedSlidingWindowOption func(*ShardedSlidingWindowLimiter)
+
+// WithShardedSlidingWindowCount 设置分片数量，默认 16。
+func WithShardedSlidingWindowCount(count int) ShardedSlidingWindowOption {
+	return func(s *ShardedSlidingWindowLimiter) {
+		if count > 0 {
+			s.count = count
+		}
+	}
+}
+
+// WithShardedSlidingWindowKeyTemplate 设置分片 key 的格式，默认 "%s:shard:%d"。
+// 模板按 fmt 格式依次接收全局 key（%s）与分片序号（%d）。
+func WithShardedSlidingWindowKeyTemplate(template string) ShardedSlidingWindowOption {
+	return func(s *ShardedSlidingWindowLimiter) {
+		if template != "" {
+			s.keyTemplate = template
+		}
+	}
+}
+
+// WithShardedSlidingWindow 设置每个分片的滑动窗口配置（全局 Window/Limit/TTL/Prefix 等）。
+// 可多次调用，配置按顺序追加。
+func WithShardedSlidingWindow(opts ...SlidingWindowOption) ShardedSlidingWindowOption {
+	return func(s *ShardedSlidingWindowLimiter) {
+		s.opts = append(s.opts, opts...)
+	}
+}
+
+// WithShardedSlidingWindowScaling 设置限额在分片间的分配方式，默认 ShardDivide（均分）。
+func WithShardedSlidingWindowScaling(scaling ShardScaling) ShardedSlidingWindowOption {
+	return func(s *ShardedSlidingWindowLimiter) {
+		s.scaling = scaling
+	}
+}
+
+// WithShardedSlidingWindowHash 设置 shardKey 到分片序号的哈希函数，默认 FNVHash。
+// 需要在线调整分片数时可使用 JumpHash 减少 key 迁移；也可以传入自定义函数处理预先哈希好的标识。
+func WithShardedSlidingWindowHash(hash ShardHash) ShardedSlidingWindowOption {
+	return func(s *ShardedSlidingWindowLimiter) {
+		if hash != nil {
+			s.hash = hash
+		}
+	}
+}
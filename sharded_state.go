@@ -0,0 +1,67 @@
+package limiter
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// ShardedState 是分片限流器的全局状态。
+type ShardedState struct {
+	// Total 所有分片聚合后的状态：
+	//  - Level / Remaining / Capacity / Rate / AllowedTotal / DeniedTotal 为各分片之和
+	//  - LastUpdated 取最近一次更新的分片
+	//  - NextAvailableTime 取最早可放行的分片
+	//  - Key 为全局业务 key
+	Total LimiterState
+
+	// Shards 各分片各自的状态，下标即分片序号（同 LimiterState.Shard）。
+	Shards []LimiterState
+}
+
+// stateQueuer 是能把 State 的读取命令排进 pipeline 的分片。
+type stateQueuer interface {
+	queueState(ctx context.Context, pipe redis.Pipeliner) func() (LimiterState, error)
+}
+
+// collectShardStates 用一次 pipeline 读取全部分片的状态并聚合。
+// 各分片的 key 不在同一个 slot，因此这不是原子快照，只用于监控展示。
+func collectShardStates[S stateQueuer](ctx context.Context, client *redis.Client, key string, shards []S) (ShardedState, error) {
+	pipe := client.Pipeline()
+	results := make([]func() (LimiterState, error), len(shards))
+	for i, shard := range shards {
+		results[i] = shard.queueState(ctx, pipe)
+	}
+	// 单条命令的错误（包括未初始化分片的 redis.Nil）由各分片自行解析
+	_, _ = pipe.Exec(ctx)
+
+	st := ShardedState{
+		Total:  LimiterState{Key: key},
+		Shards: make([]LimiterState, len(shards)),
+	}
+	for i, result := range results {
+		shard, err := result()
+		if err != nil {
+			return ShardedState{}, fmt.Errorf("shard %d: %w", i, err)
+		}
+		shard.Shard = i
+		st.Shards[i] = shard
+
+		st.Total.Level += shard.Level
+		st.Total.Remaining += shard.Remaining
+		st.Total.Capacity += shard.Capacity
+		st.Total.Rate += shard.Rate
+		st.Total.AllowedTotal += shard.AllowedTotal
+		st.Total.DeniedTotal += shard.DeniedTotal
+		st.Total.Type = shard.Type
+		st.Total.Window = shard.Window
+		if shard.LastUpdated > st.Total.LastUpdated {
+			st.Total.LastUpdated = shard.LastUpdated
+		}
+		if i == 0 || shard.NextAvailableTime < st.Total.NextAvailableTime {
+			st.Total.NextAvailableTime = shard.NextAvailableTime
+		}
+	}
+	return st, nil
+}
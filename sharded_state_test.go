@@ -0,0 +1,76 @@
+package limiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShardedGlobalState(t *testing.T) {
+	client := newSpecClient(t)
+	ctx := context.Background()
+
+	t.Run("token_bucket", func(t *testing.T) {
+		s := NewShardedTokenBucketLimiter(client, "global", WithShardedTokenBucketCount(4),
+			WithShardedTokenBucket(WithTokenBucketRate(4), WithTokenBucketCapacity(40), WithTokenBucketStorage(StorageHash)))
+
+		ok, err := s.AllowN(ctx, "u1", 6)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+
+		st, err := s.GlobalState(ctx)
+		assert.NoError(t, err)
+		assert.Len(t, st.Shards, 4)
+		assert.Equal(t, "global", st.Total.Key)
+		assert.Equal(t, "token_bucket", st.Total.Type)
+		assert.Equal(t, float64(40), st.Total.Capacity)
+		assert.Equal(t, float64(4), st.Total.Rate)
+		assert.InDelta(t, 34, st.Total.Remaining, 0.5)
+		assert.InDelta(t, 4, st.Shards[s.pick("u1")].Remaining, 0.5)
+	})
+
+	t.Run("leaky_bucket", func(t *testing.T) {
+		s := NewShardedLeakyBucketLimiter(client, "global", WithShardedLeakyBucketCount(2),
+			WithShardedLeakyBucket(WithLeakyBucketRate(2), WithLeakyBucketCapacity(20)))
+
+		ok, err := s.AllowN(ctx, "u1", 5)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+
+		st, err := s.GlobalState(ctx)
+		assert.NoError(t, err)
+		assert.Len(t, st.Shards, 2)
+		assert.Equal(t, float64(20), st.Total.Capacity)
+		assert.InDelta(t, 5, st.Total.Level, 0.5)
+		assert.InDelta(t, 15, st.Total.Remaining, 0.5)
+	})
+
+	t.Run("sliding_window", func(t *testing.T) {
+		s := NewShardedSlidingWindowLimiter(client, "global", WithShardedSlidingWindowCount(3),
+			WithShardedSlidingWindow(WithSlidingWindowLimit(30), WithSlidingWindowWindow(time.Minute), WithSlidingWindowStats(time.Hour)))
+
+		for _, key := range []string{"a", "b", "c", "d"} {
+			ok, err := s.Allow(ctx, key)
+			assert.NoError(t, err)
+			assert.True(t, ok)
+		}
+
+		st, err := s.GlobalState(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, float64(30), st.Total.Capacity)
+		assert.Equal(t, float64(4), st.Total.Level)
+		assert.Equal(t, float64(26), st.Total.Remaining)
+		assert.Equal(t, int64(4), st.Total.AllowedTotal)
+		assert.Equal(t, time.Minute, st.Total.Window)
+		for i, shard := range st.Shards {
+			assert.Equal(t, i, shard.Shard)
+		}
+
+		one, err := s.State(ctx, "d")
+		assert.NoError(t, err)
+		assert.Equal(t, s.pick("d"), one.Shard)
+		assert.Equal(t, st.Shards[one.Shard].AllowedTotal, one.AllowedTotal)
+	})
+}
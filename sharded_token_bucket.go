@@ -2,8 +2,9 @@ package limiter
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"hash/fnv"
+	"sync"
 	"time"
 
 	"github.com/go-redis/redis/v8"
@@ -18,21 +19,36 @@ import (
 //   - 按 userID / IP / tenantID 做 shardKey 路由，
 //   - 每个 shard 使用全局 Rate/Capacity 的 1/N。
 type ShardedTokenBucketLimiter struct {
+	client *redis.Client
+	key    string
 	shards []*TokenBucketLimiter
 	count  int
+
+	// keyTemplate 分片 key 的格式，依次接收全局 key 与分片序号，默认 "%s:shard:%d"
+	keyTemplate string
+	// scaling 限额在分片间的分配方式，默认 ShardDivide
+	scaling ShardScaling
+	// hash shardKey 到分片序号的映射，默认 FNVHash
+	hash ShardHash
+	// opts 每个分片的令牌桶配置
+	opts []TokenBucketOption
+
+	// mu 保护 borrowBudget 的运行时修改
+	mu sync.RWMutex
+	// borrowBudget 借用比例（0~1），0 表示不借用，见 SetBorrowBudget。
+	borrowBudget float64
 }
 
 // NewShardedTokenBucketLimiter 创建一个分片令牌桶。
 //   - client: Redis 客户端
 //   - key:    全局业务 key（例如 "api:/v1/chat"）
-//   - shardCount: 分片数量（默认为16，如果传 <=0 则强制为16）
-//   - opts:   令牌桶配置（全局 Rate/Capacity/TTL/Prefix 等）
-//     注意：Rate 和 Capacity 会在内部按 shardCount 均分到每个 shard 上。
+//   - opts:   分片配置（分片数量、分片 key 格式，以及每个分片的令牌桶配置）
+//     注意：Rate 和 Capacity 会在内部按分片数均分到每个 shard 上（每个 shard 的容量至少为 1），
+//     可通过 scaling 配置改为每个 shard 使用完整限额（ShardReplicate）。
 func NewShardedTokenBucketLimiter(
 	client *redis.Client,
 	key string,
-	shardCount int,
-	opts ...TokenBucketOption,
+	opts ...ShardedTokenBucketOption,
 ) *ShardedTokenBucketLimiter {
 
 	if client == nil {
@@ -41,69 +57,258 @@ func NewShardedTokenBucketLimiter(
 	if key == "" {
 		panic("sharded token bucket: key is empty")
 	}
-	if shardCount <= 0 {
-		shardCount = 16
-	}
-
-	shards := make([]*TokenBucketLimiter, shardCount)
 
-	for i := 0; i < shardCount; i++ {
-		shardKey := fmt.Sprintf("%s:shard:%d", key, i)
+	s := &ShardedTokenBucketLimiter{
+		client:      client,
+		key:         key,
+		count:       16,
+		keyTemplate: "%s:shard:%d",
+		hash:        FNVHash,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.scaling == ShardReplicate && s.borrowBudget > 0 {
+		panic("sharded token bucket: borrow budget is not supported with ShardReplicate")
+	}
 
+	s.shards = make([]*TokenBucketLimiter, s.count)
+	for i := 0; i < s.count; i++ {
 		// 拷贝一份 opts，避免对原切片产生副作用
-		innerOpts := append([]TokenBucketOption{}, opts...)
+		innerOpts := append([]TokenBucketOption{}, s.opts...)
 
-		// 使用 Custom Option 在每个 shard 上缩放 rate/capacity
+		// 使用 Custom Option 在每个 shard 上均摊限额（仅 ShardDivide 策略），均分后的容量至少为 1
 		innerOpts = append(innerOpts, WithTokenBucketCustom(func(tb *TokenBucketLimiter) {
-			tb.Rate = tb.Rate / float64(shardCount)
-			if tb.Rate <= 0 {
-				tb.Rate = 1
-			}
-			tb.Capacity = tb.Capacity / float64(shardCount)
-			if tb.Capacity <= 0 {
-				tb.Capacity = 1
-			}
+			tb.Rate, _ = shardFloat(s.scaling, tb.Rate, s.count, false)
+			tb.Capacity, _ = shardFloat(s.scaling, tb.Capacity, s.count, true)
 		}))
 
-		shards[i] = NewTokenBucketLimiter(client, shardKey, innerOpts...)
+		s.shards[i] = NewTokenBucketLimiter(client, s.shardKey(i), innerOpts...)
 	}
+	return s
+}
 
-	return &ShardedTokenBucketLimiter{
-		shards: shards,
-		count:  shardCount,
-	}
+// shardKey 返回第 i 个分片的业务 key。
+func (s *ShardedTokenBucketLimiter) shardKey(i int) string {
+	return fmt.Sprintf(s.keyTemplate, s.key, i)
 }
 
-// pick 根据 shardKey 选择某一个 shard。
-// 使用 FNV-1a 哈希，简单且分布较均匀。
+// pick 根据 shardKey 选择某一个 shard，默认使用 FNV-1a 哈希，可通过 hash 配置替换。
 func (s *ShardedTokenBucketLimiter) pick(shardKey string) int {
-	h := fnv.New32a()
-	_, _ = h.Write([]byte(shardKey))
-	return int(h.Sum32()) % s.count
+	return shardIndex(s.hash, shardKey, s.count)
 }
 
 // Allow 对指定 shardKey 尝试获取 1 个 token。
 // 常见用法：shardedLimiter.Allow(ctx, userID)
 func (s *ShardedTokenBucketLimiter) Allow(ctx context.Context, shardKey string) (bool, error) {
-	idx := s.pick(shardKey)
-	return s.shards[idx].Allow(ctx)
+	return s.AllowN(ctx, shardKey, 1)
+}
+
+// AllowWithResult 对指定 shardKey 尝试获取 1 个许可，并返回剩余额度及重试等待时间。
+func (s *ShardedTokenBucketLimiter) AllowWithResult(ctx context.Context, shardKey string) (Result, error) {
+	return s.allowN(ctx, shardKey, 1)
 }
 
 // AllowN 对指定 shardKey 尝试获取 n 个 token。
 func (s *ShardedTokenBucketLimiter) AllowN(ctx context.Context, shardKey string, n int64) (bool, error) {
+	res, err := s.allowN(ctx, shardKey, n)
+	if err != nil {
+		return false, err
+	}
+	return res.Allowed, nil
+}
+
+// allowN 先在 shardKey 所在分片上获取 n 个 token；开启借用且该分片不足时，
+// 再向相邻分片借用（第二次脚本调用），借用成功时返回本分片的结果并标记为放行。
+// 开启借用时两次调用都不计统计、不触发钩子，由本分片按最终结果记录一次（见 settle）。
+func (s *ShardedTokenBucketLimiter) allowN(ctx context.Context, shardKey string, n int64) (Result, error) {
 	idx := s.pick(shardKey)
-	return s.shards[idx].AllowN(ctx, n)
+	home := s.shards[idx]
+	budget := s.BorrowBudget()
+	if budget <= 0 || s.count < 2 {
+		return home.AllowNWithResult(ctx, n)
+	}
+	if n <= 0 {
+		return Result{}, fmt.Errorf("sharded token bucket: n must > 0")
+	}
+
+	res, err := home.call(n, "", false).run(ctx)
+	if err == nil && !res.Allowed {
+		res, err = s.borrow(ctx, idx, n, budget, res)
+	}
+	home.settle(ctx, n, res, err)
+	return res, err
+}
+
+// borrow 向第 idx 个分片的相邻分片借用 n 个 token（不计统计、不触发钩子），
+// 借到时把本分片的拒绝结果 res 改为放行。
+func (s *ShardedTokenBucketLimiter) borrow(ctx context.Context, idx int, n int64, budget float64, res Result) (Result, error) {
+	lent, err := s.shards[(idx+1)%s.count].call(n, "", false, 0, budget).run(ctx)
+	if err != nil {
+		return Result{}, err
+	}
+	if lent.Allowed {
+		res.Allowed = true
+		res.RetryAfter = 0
+	}
+	return res, nil
+}
+
+// AllowKeys 对每个 shardKey 各获取 1 个 token，各分片的脚本调用合并到一个 pipeline 中，一次往返完成。
+// 开启借用时，被拒绝的 shardKey 再逐个向相邻分片借用，统计与钩子只按每个 shardKey 的最终结果记录一次。
+// 返回与 shardKeys 一一对应的结果，err 汇总全部出错 shardKey 的错误，各自的错误见 KeyResult.Err。
+func (s *ShardedTokenBucketLimiter) AllowKeys(ctx context.Context, shardKeys []string) ([]KeyResult, error) {
+	idx := make([]int, len(shardKeys))
+	for i, shardKey := range shardKeys {
+		idx[i] = s.pick(shardKey)
+	}
+
+	budget := s.BorrowBudget()
+	if budget <= 0 || s.count < 2 {
+		limiters := make([]RateLimiter, len(shardKeys))
+		for i := range shardKeys {
+			limiters[i] = s.shards[idx[i]]
+		}
+		return allowAll(ctx, shardKeys, limiters)
+	}
+
+	calls := make([]scriptCall, len(shardKeys))
+	for i := range shardKeys {
+		calls[i] = s.shards[idx[i]].call(1, "", false)
+	}
+	out := runPipelined(ctx, calls)
+	var errs []error
+	for i := range out {
+		out[i].Key = shardKeys[i]
+		if out[i].Err == nil && !out[i].Allowed {
+			out[i].Result, out[i].Err = s.borrow(ctx, idx[i], 1, budget, out[i].Result)
+		}
+		s.shards[idx[i]].settle(ctx, 1, out[i].Result, out[i].Err)
+		if out[i].Err != nil {
+			errs = append(errs, fmt.Errorf("key %q: %w", out[i].Key, out[i].Err))
+		}
+	}
+	return out, errors.Join(errs...)
 }
 
-// Wait 对指定 shardKey 阻塞直到获取到一个 token 或 ctx 超时。
+// Wait 对指定 shardKey 阻塞直到获取到一个 token 或 ctx 超时。开启借用时每次重试都会尝试借用。
 func (s *ShardedTokenBucketLimiter) Wait(ctx context.Context, shardKey string, maxWait time.Duration) error {
-	idx := s.pick(shardKey)
-	return s.shards[idx].Wait(ctx, maxWait)
+	shard := s.shards[s.pick(shardKey)]
+	return waitForWake(ctx, shard.Key, "token_bucket", maxWait, waitStrategy(shard.WaitStrategy, shard.WaitJitter), shard.Wakeup, func(ctx context.Context) (Result, error) {
+		return s.allowN(ctx, shardKey, 1)
+	})
+}
+
+// SetBorrowBudget 设置分片借用比例（0~1），并发安全。
+// 流量倾斜时，被打满的分片可以向相邻分片借用 token，但每个分片最多借出自身容量的 ratio 倍，
+// 剩余部分保留给落在该分片上的流量。0（默认）表示关闭借用。
+// ShardReplicate 策略下每个分片本身就是完整限额，借用会突破全局限额，因此只能为 0。
+func (s *ShardedTokenBucketLimiter) SetBorrowBudget(ratio float64) error {
+	if ratio < 0 || ratio > 1 {
+		return fmt.Errorf("sharded token bucket: borrow budget must in [0, 1]")
+	}
+	if ratio > 0 && s.scaling == ShardReplicate {
+		return fmt.Errorf("sharded token bucket: borrow budget is not supported with ShardReplicate")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.borrowBudget = ratio
+	return nil
+}
+
+// BorrowBudget 返回当前的分片借用比例。
+func (s *ShardedTokenBucketLimiter) BorrowBudget() float64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.borrowBudget
 }
 
 // State 返回某个 shardKey 对应的 shard 的状态。
 // 注意：这不是“全局聚合状态”，而是“该 shard 的局部状态”。
 func (s *ShardedTokenBucketLimiter) State(ctx context.Context, shardKey string) (LimiterState, error) {
 	idx := s.pick(shardKey)
-	return s.shards[idx].State(ctx)
+	st, err := s.shards[idx].State(ctx)
+	if err != nil {
+		return LimiterState{}, err
+	}
+	st.Shard = idx
+	return st, nil
+}
+
+// GlobalState 通过一次 pipeline 读取全部分片的状态，返回聚合后的全局状态及各分片的状态，
+// 用于监控面板展示真实的整体使用率。分片之间不是原子快照。
+func (s *ShardedTokenBucketLimiter) GlobalState(ctx context.Context) (ShardedState, error) {
+	return collectShardStates(ctx, s.client, s.key, s.shards)
+}
+
+// Reset 重置 shardKey 对应分片的状态。
+func (s *ShardedTokenBucketLimiter) Reset(ctx context.Context, shardKey string) error {
+	idx := s.pick(shardKey)
+	return s.shards[idx].Reset(ctx)
+}
+
+// ResetAll 逐个重置所有分片。各分片的 key 不在同一个 slot，
+// 因此每个分片内部是原子的，分片之间不保证原子性。
+func (s *ShardedTokenBucketLimiter) ResetAll(ctx context.Context) error {
+	for _, shard := range s.shards {
+		if err := shard.Reset(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetRate 在运行时修改全局token 生成速率，内部按分片数均分到每个 shard（与构造时的换算一致）；
+// ShardReplicate 策略下每个 shard 直接使用该值。
+func (s *ShardedTokenBucketLimiter) SetRate(rate float64) error {
+	if rate <= 0 {
+		return fmt.Errorf("sharded token bucket: rate must > 0")
+	}
+	perShard, _ := shardFloat(s.scaling, rate, s.count, false)
+	for _, shard := range s.shards {
+		if err := shard.SetRate(perShard); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetCapacity 在运行时修改全局容量，内部按分片数均分到每个 shard（与构造时的换算一致）；
+// ShardReplicate 策略下每个 shard 直接使用该值。均分后小于 1 时返回错误。
+func (s *ShardedTokenBucketLimiter) SetCapacity(capacity float64) error {
+	if capacity <= 0 {
+		return fmt.Errorf("sharded token bucket: capacity must > 0")
+	}
+	perShard, err := shardFloat(s.scaling, capacity, s.count, true)
+	if err != nil {
+		return fmt.Errorf("sharded token bucket: capacity %w", err)
+	}
+	for _, shard := range s.shards {
+		if err := shard.SetCapacity(perShard); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Reconfigure 按分片策略换算全局速率和容量后应用到每个 shard，实现 Reconfigurable。
+// 每个 shard 内部是原子的，shard 之间会有极短的新旧参数并存。
+func (s *ShardedTokenBucketLimiter) Reconfigure(c LimitConfig) error {
+	if err := c.validate(); err != nil {
+		return err
+	}
+	per := c
+	per.Rate, _ = shardFloat(s.scaling, c.Rate, s.count, false)
+	if c.Capacity > 0 {
+		var err error
+		if per.Capacity, err = shardFloat(s.scaling, c.Capacity, s.count, true); err != nil {
+			return fmt.Errorf("sharded token bucket: capacity %w", err)
+		}
+	}
+	for _, shard := range s.shards {
+		if err := shard.Reconfigure(per); err != nil {
+			return err
+		}
+	}
+	return nil
 }
@@ -1,11 +1,65 @@
 package limiter
 
+// ShardedTokenBucketOption 为分片令牌桶的配置项。
 type ShardedTokenBucketOption func(*ShardedTokenBucketLimiter)
 
-func WithShardCount(count int) ShardedTokenBucketOption {
+// WithShardedTokenBucketCount 设置分片数量，默认 16。
+func WithShardedTokenBucketCount(count int) ShardedTokenBucketOption {
 	return func(s *ShardedTokenBucketLimiter) {
 		if count > 0 {
 			s.count = count
 		}
 	}
 }
+
+// WithShardedTokenBucketKeyTemplate 设置分片 key 的格式，默认 "%s:shard:%d"。
+// 模板按 fmt 格式依次接收全局 key（%s）与分片序号（%d）。
+func WithShardedTokenBucketKeyTemplate(template string) ShardedTokenBucketOption {
+	return func(s *ShardedTokenBucketLimiter) {
+		if template != "" {
+			s.keyTemplate = template
+		}
+	}
+}
+
+// WithShardedTokenBucketBorrowBudget 设置分片借用比例（0~1），见 SetBorrowBudget。
+// 与 ShardReplicate 同时使用时构造函数会 panic。
+func WithShardedTokenBucketBorrowBudget(ratio float64) ShardedTokenBucketOption {
+	return func(s *ShardedTokenBucketLimiter) {
+		if ratio >= 0 && ratio <= 1 {
+			s.borrowBudget = ratio
+		}
+	}
+}
+
+// WithShardedTokenBucket 设置每个分片的令牌桶配置（全局 Rate/Capacity/TTL/Prefix 等）。
+// 可多次调用，配置按顺序追加。
+func WithShardedTokenBucket(opts ...TokenBucketOption) ShardedTokenBucketOption {
+	return func(s *ShardedTokenBucketLimiter) {
+		s.opts = append(s.opts, opts...)
+	}
+}
+
+// WithShardedTokenBucketScaling 设置限额在分片间的分配方式，默认 ShardDivide（均分）。
+func WithShardedTokenBucketScaling(scaling ShardScaling) ShardedTokenBucketOption {
+	return func(s *ShardedTokenBucketLimiter) {
+		s.scaling = scaling
+	}
+}
+
+// WithShardedTokenBucketHash 设置 shardKey 到分片序号的哈希函数，默认 FNVHash。
+// 需要在线调整分片数时可使用 JumpHash 减少 key 迁移；也可以传入自定义函数处理预先哈希好的标识。
+func WithShardedTokenBucketHash(hash ShardHash) ShardedTokenBucketOption {
+	return func(s *ShardedTokenBucketLimiter) {
+		if hash != nil {
+			s.hash = hash
+		}
+	}
+}
+
+// WithShardCount 设置分片数量。
+//
+// Deprecated: 使用 WithShardedTokenBucketCount。
+func WithShardCount(count int) ShardedTokenBucketOption {
+	return WithShardedTokenBucketCount(count)
+}
@@ -0,0 +1,92 @@
+package limiter
+
+import (
+	"fmt"
+	"hash/fnv"
+)
+
+// ShardScaling 决定分片限流器如何把配置的限额分配到各个分片。
+type ShardScaling int
+
+const (
+	// ShardDivide 限额按分片数均分（默认）：所有分片加起来约等于一个全局限额，
+	// 用于把一个热点全局限流拆散到多个 key 上。
+	ShardDivide ShardScaling = iota
+	// ShardReplicate 每个分片都使用完整的限额：相当于按 shardKey 分别限流，
+	// 例如按 userID 路由时的“每个用户各自 100 QPS”。
+	// 注意多个 shardKey 可能落在同一个分片上并共享限额，分片数应远大于同时活跃的 shardKey 数。
+	ShardReplicate
+)
+
+// ShardHash 把 shardKey 映射为 [0, count) 之间的分片序号。
+// 返回值越界时会被取模到合法范围内。
+type ShardHash func(shardKey string, count int) int
+
+// FNVHash 使用 FNV-1a 哈希后取模，分布均匀，是分片限流器的默认哈希。
+// 分片数变化时几乎所有 shardKey 都会换到新的分片上。
+func FNVHash(shardKey string, count int) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(shardKey))
+	return int(h.Sum32() % uint32(count))
+}
+
+// JumpHash 先用 FNV-1a 把 shardKey 哈希为 64 位整数，再使用 jump consistent hash 选择分片。
+// 分片数从 n 调整为 n+1 时，只有约 1/(n+1) 的 shardKey 会迁移到新分片，
+// 适合需要在线调整分片数、又不希望限流状态大面积“重置”的场景。
+func JumpHash(shardKey string, count int) int {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(shardKey))
+	return JumpConsistentHash(h.Sum64(), count)
+}
+
+// JumpConsistentHash 为 Lamping & Veach 的 jump consistent hash，
+// 把 64 位整数 key 映射到 [0, buckets) 之间。
+// 调用方已有预先哈希好的标识（例如租户 ID）时，可以直接在自定义 ShardHash 中使用它。
+func JumpConsistentHash(key uint64, buckets int) int {
+	var b, j int64 = -1, 0
+	for j < int64(buckets) {
+		b = j
+		key = key*2862933555777941757 + 1
+		j = int64(float64(b+1) * (float64(int64(1)<<31) / float64((key>>33)+1)))
+	}
+	return int(b)
+}
+
+// shardIndex 使用 hash 计算分片序号，并把越界的返回值取模到 [0, count)。
+func shardIndex(hash ShardHash, shardKey string, count int) int {
+	idx := hash(shardKey, count) % count
+	if idx < 0 {
+		idx += count
+	}
+	return idx
+}
+
+// shardFloat 按分片策略把全局速率 / 容量换算为单个分片的值，构造函数与 SetXxx / Reconfigure 共用，
+// 同一个全局值在两条路径上总是得到相同的分片限额。ShardDivide 下按分片数精确均分，不做取整。
+//
+// minOne 为 true（容量）时，均分结果小于 1 意味着单个分片永远放行不了一个请求，此时返回 1 以及一个错误：
+// 向上取整会放大全局限额，SetXxx / Reconfigure 据此拒绝修改；构造函数沿用早期版本的行为，
+// 忽略错误并使用每个分片 1 的下限，不对合法的配置 panic。
+func shardFloat(scaling ShardScaling, v float64, count int, minOne bool) (float64, error) {
+	if scaling != ShardDivide {
+		return v, nil
+	}
+	per := v / float64(count)
+	if minOne && per < 1 {
+		return 1, fmt.Errorf("%v divided by %d shards is less than 1, reduce the shard count", v, count)
+	}
+	return per, nil
+}
+
+// shardInt 与 shardFloat 相同，用于整数上限：均分结果向下取整（全局限额不会被放大），
+// 小于 1 时返回 1 以及一个错误。
+func shardInt(scaling ShardScaling, v int64, count int) (int64, error) {
+	if scaling != ShardDivide {
+		return v, nil
+	}
+	per := v / int64(count)
+	if per < 1 {
+		return 1, fmt.Errorf("%d divided by %d shards is less than 1, reduce the shard count", v, count)
+	}
+	return per, nil
+}
@@ -0,0 +1,48 @@
+package limiter
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJumpHash(t *testing.T) {
+	const keys = 10000
+
+	moved := 0
+	for i := 0; i < keys; i++ {
+		key := fmt.Sprintf("user:%d", i)
+		before := JumpHash(key, 10)
+		after := JumpHash(key, 11)
+		assert.True(t, before >= 0 && before < 10)
+		if before != after {
+			// 扩容时 key 只会迁移到新增的分片上
+			assert.Equal(t, 10, after)
+			moved++
+		}
+	}
+	// 理论迁移比例约为 1/11
+	assert.InDelta(t, keys/11, moved, keys/50)
+
+	assert.Equal(t, 0, JumpConsistentHash(12345, 1))
+}
+
+func TestShardIndex(t *testing.T) {
+	assert.Equal(t, 2, shardIndex(func(string, int) int { return -3 }, "k", 5))
+	assert.Equal(t, 1, shardIndex(func(string, int) int { return 11 }, "k", 5))
+	assert.Equal(t, FNVHash("user:1", 16), shardIndex(FNVHash, "user:1", 16))
+}
+
+func TestShardedHashOption(t *testing.T) {
+	client := newSpecClient(t)
+
+	pinned := func(string, int) int { return 1 }
+	tb := NewShardedTokenBucketLimiter(client, "hash", WithShardedTokenBucketCount(4), WithShardedTokenBucketHash(pinned))
+	lb := NewShardedLeakyBucketLimiter(client, "hash", WithShardedLeakyBucketHash(JumpHash))
+	sw := NewShardedSlidingWindowLimiter(client, "hash", WithShardedSlidingWindowHash(pinned))
+
+	assert.Equal(t, 1, tb.pick("anything"))
+	assert.Equal(t, JumpHash("u1", 16), lb.pick("u1"))
+	assert.Equal(t, 1, sw.pick("anything"))
+}
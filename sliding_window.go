@@ -3,6 +3,9 @@ package limiter
 import (
 	"context"
 	"fmt"
+	"math"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/go-redis/redis/v8"
@@ -16,11 +19,35 @@ import (
 type SingleSlidingWindowLimiter struct {
 	client *redis.Client
 
+	// mu 保护 Limit/Window/TTL 的运行时修改，创建后请通过 SetLimit/SetWindow 修改
+	mu sync.RWMutex
+
 	Key    string        // 业务 key
 	Prefix string        // Redis key 前缀，默认 "sw"
 	Window time.Duration // 窗口大小，例如 1 * time.Minute
 	Limit  int64         // 窗口内最大允许请求数
 	TTL    time.Duration // key 过期时间，建议 >= Window * 2
+
+	// TTLJitter TTL 抖动比例（0~1），用于打散大量 key 的过期时间，默认 0（不抖动）
+	TTLJitter float64
+	// WaitJitter Wait 重试前的随机抖动比例（0~1）
+	WaitJitter float64
+	// WaitStrategy Wait 被拒绝后的等待策略（可选），默认按 RetryAfter 等待并叠加 WaitJitter，见 WaitStrategy。
+	WaitStrategy WaitStrategy
+
+	// ServerTime 为 true（默认）时脚本使用 Redis TIME 作为当前时间，
+	// 所有应用实例共享同一个时钟源；为 false 时使用本机时钟。
+	ServerTime bool
+
+	// Clock 时钟，默认 SystemClock。
+	Clock Clock
+
+	// StatsTTL 按 key 统计放行 / 拒绝数量时计数器的过期时间（每次写入时刷新），0 表示不统计（默认）。
+	// 计数由限流脚本原子累加到 "<Prefix>:{Key}:stats:allowed" / "denied"，通过 Stats 读取。
+	StatsTTL time.Duration
+
+	// Hooks 判定事件钩子（可选），每次放行 / 拒绝 / 出错时回调，见 Hooks。
+	Hooks Hooks
 }
 
 // NewSlidingWindowLimiter 创建一个单桶滑动窗口限流器。
@@ -38,12 +65,14 @@ func NewSlidingWindowLimiter(
 	}
 
 	l := &SingleSlidingWindowLimiter{
-		client: client,
-		Key:    key,
-		Prefix: "sw",
-		Window: 1 * time.Minute,
-		Limit:  60,
-		TTL:    2 * time.Minute,
+		client:     client,
+		Key:        key,
+		Prefix:     "sw",
+		Window:     1 * time.Minute,
+		Limit:      60,
+		TTL:        2 * time.Minute,
+		ServerTime: true,
+		Clock:      SystemClock,
 	}
 	for _, opt := range opts {
 		opt(l)
@@ -66,113 +95,237 @@ func (l *SingleSlidingWindowLimiter) Allow(ctx context.Context) (bool, error) {
 	return l.AllowN(ctx, 1)
 }
 
-// AllowN 尝试一次通过 n 个请求。
-// 精确滑动窗口场景下，一般 n=1；如果有 n>1 的需求，可以扩展脚本一次写入多个 member。
-// 这里为了简化与保持原子性，不支持 n>1。
+// AllowWithResult 尝试占一个名额，并返回窗口内剩余名额及重试等待时间。
+func (l *SingleSlidingWindowLimiter) AllowWithResult(ctx context.Context) (Result, error) {
+	return l.AllowNWithResult(ctx, 1)
+}
+
+// AllowN 尝试一次通过 n 个请求（例如一次发送 5 条短信）。
+// 脚本会原子地写入 n 条记录；窗口内剩余名额不足 n 时整体拒绝，不会只放行一部分。
 func (l *SingleSlidingWindowLimiter) AllowN(ctx context.Context, n int64) (bool, error) {
-	if n != 1 {
-		return false, fmt.Errorf("sliding window: AllowN only supports n=1 for now")
-	}
-
-	nowMs := float64(time.Now().UnixNano() / 1e6)
-	windowMs := l.Window.Milliseconds()
-	ttlMs := l.TTL.Milliseconds()
-
-	res, err := slidingWindowScript.Run(
-		ctx,
-		l.client,
-		[]string{l.logKey(), l.seqKey()},
-		nowMs,
-		windowMs,
-		l.Limit,
-		ttlMs,
-	).Result()
+	res, err := l.AllowNWithResult(ctx, n)
 	if err != nil {
 		return false, err
 	}
+	return res.Allowed, nil
+}
+
+// AllowNWithResult 尝试一次通过 n 个请求，并返回窗口内剩余名额及重试等待时间。
+func (l *SingleSlidingWindowLimiter) AllowNWithResult(ctx context.Context, n int64) (Result, error) {
+	res, err := l.eval(ctx, n, false)
+	fireHooks(ctx, l.Hooks, "sliding_window", l.Key, n, res, err)
+	return res, err
+}
+
+// Check 判断当前是否能通过 1 个请求，但不写入记录。
+func (l *SingleSlidingWindowLimiter) Check(ctx context.Context) (Result, error) {
+	return l.CheckN(ctx, 1)
+}
+
+// CheckN 判断当前是否能一次通过 n 个请求，但不写入记录。
+// 脚本同样会清理窗口外的旧记录并完成判定；放行时 Remaining 为当前剩余名额（未扣除 n）。
+func (l *SingleSlidingWindowLimiter) CheckN(ctx context.Context, n int64) (Result, error) {
+	return l.eval(ctx, n, true)
+}
+
+// eval 执行滑动窗口脚本；dryRun 为 true 时只判定不写入。
+func (l *SingleSlidingWindowLimiter) eval(ctx context.Context, n int64, dryRun bool) (Result, error) {
+	c, err := l.call(n, dryRun)
+	if err != nil {
+		return Result{}, err
+	}
+	return c.run(ctx)
+}
+
+// allowCall 返回一次 AllowN 判定（含钩子）的脚本调用，供 AllowKeys 合并到 pipeline 中执行。
+func (l *SingleSlidingWindowLimiter) allowCall(n int64) (scriptCall, error) {
+	c, err := l.call(n, false)
+	if err != nil {
+		return scriptCall{}, err
+	}
+	return c.withHooks(l.Hooks, "sliding_window", l.Key, n), nil
+}
+
+// call 准备一次滑动窗口脚本调用。
+func (l *SingleSlidingWindowLimiter) call(n int64, dryRun bool) (scriptCall, error) {
+	limit, window, ttl := l.limits()
+	if n <= 0 {
+		return scriptCall{}, fmt.Errorf("sliding window: n must > 0")
+	}
+	if n > limit {
+		return scriptCall{}, fmt.Errorf("sliding window: n must <= limit")
+	}
 
-	switch v := res.(type) {
-	case int64:
-		return v == 1, nil
-	case int:
-		return int64(v) == 1, nil
-	default:
-		return false, fmt.Errorf("sliding window: unexpected script result: %#v", res)
+	nowMs := float64(scriptNow(l.ServerTime, l.Clock))
+	windowMs := window.Milliseconds()
+	ttlMs := ttl.Milliseconds()
+
+	keys := []string{l.logKey(), l.seqKey()}
+	args := []interface{}{nowMs, windowMs, limit, ttlMs, l.TTLJitter, n}
+	if dryRun {
+		args = append(args, 1)
+	} else {
+		keys, args = appendStats(keys, args, statsBase(l.Prefix, l.Key), l.StatsTTL, n, false)
 	}
+
+	return scriptCall{
+		client: l.client,
+		script: slidingWindowScript,
+		keys:   keys,
+		args:   args,
+		parse: func(_ context.Context, res interface{}, err error) (Result, error) {
+			if err != nil {
+				return Result{}, err
+			}
+			vals, ok := scriptInts(res, 3)
+			if !ok {
+				return Result{}, fmt.Errorf("sliding window: unexpected script result: %#v", res)
+			}
+			return Result{
+				Allowed:    vals[0] == 1,
+				Limit:      float64(limit),
+				Remaining:  float64(vals[1]),
+				RetryAfter: time.Duration(vals[2]) * time.Millisecond,
+			}, nil
+		},
+	}, nil
 }
 
-// Wait 简单实现一个轮询等待：
-//   - 如果 Allow 返回 false，则 sleep 一段时间再重试。
-//   - 直到通过或 ctx 超时。
+// Wait 阻塞直到窗口中有空位，或 ctx 取消 / 超过 maxWait。
+// 被限流时脚本会找出需要滑出窗口的那条记录，按它滑出的精确时间 sleep 后再重试。
 func (l *SingleSlidingWindowLimiter) Wait(ctx context.Context, maxWait time.Duration) error {
-	maxWait = max(maxWait, 0)
-	deadline := time.Now().Add(maxWait)
+	return waitFor(ctx, l.Key, "sliding_window", maxWait, waitStrategy(l.WaitStrategy, l.WaitJitter), l.AllowWithResult)
+}
 
-	timer := time.NewTimer(time.Second)
-	defer timer.Stop()
+// Stats 返回按 key 统计的放行 / 拒绝数量，需要通过 WithSlidingWindowStats 开启统计。
+func (l *SingleSlidingWindowLimiter) Stats(ctx context.Context) (LimiterStats, error) {
+	return readStats(ctx, l.client, statsBase(l.Prefix, l.Key))
+}
 
-	for {
-		ok, err := l.Allow(ctx)
-		if err != nil {
-			return err
-		}
-		if ok {
-			return nil
-		}
-		if maxWait == 0 {
-			// 不等待，直接返回限流
-			return ErrLimiter
-		}
+// Reset 删除请求日志和序列 key，清空整个窗口。
+func (l *SingleSlidingWindowLimiter) Reset(ctx context.Context) error {
+	return l.client.Del(ctx, l.logKey(), l.seqKey()).Err()
+}
 
-		now := time.Now()
-		if now.After(deadline) {
-			return ErrTimeout
-		}
-		sleep := 10 * time.Millisecond
-		remain := time.Until(deadline)
-		if sleep > remain {
-			sleep = remain
-		}
-		timer.Reset(sleep)
+// State 返回当前滑动窗口内的请求数量等状态。窗口内的记录由只读脚本统计，
+// 与限流脚本使用同一个时钟源（ServerTime 时为 Redis TIME）。
+// 窗口已满时，NextAvailableTime 为腾出一个空位所需的那条记录滑出窗口的时间。
+func (l *SingleSlidingWindowLimiter) State(ctx context.Context) (LimiterState, error) {
+	limit, window, _ := l.limits()
+	res, err := slidingWindowStateScript.Run(ctx, l.client, []string{l.logKey()}, l.stateArgs(limit, window)...).Result()
+	st, err := l.stateFrom(limit, window, res, err)
+	return fillStats(ctx, l.client, statsBase(l.Prefix, l.Key), l.StatsTTL, st, err)
+}
 
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		case <-timer.C:
-		}
+// queueState 把 State 需要的读取命令排进 pipeline，返回的函数需在 Exec 之后调用。
+// pipeline 中使用 EVAL 而不是 EVALSHA，不会因为脚本尚未加载而失败。
+func (l *SingleSlidingWindowLimiter) queueState(ctx context.Context, pipe redis.Pipeliner) func() (LimiterState, error) {
+	limit, window, _ := l.limits()
+	cmd := slidingWindowStateScript.Eval(ctx, pipe, []string{l.logKey()}, l.stateArgs(limit, window)...)
+	stats := queueStats(ctx, pipe, statsBase(l.Prefix, l.Key), l.StatsTTL)
+
+	return func() (LimiterState, error) {
+		res, err := cmd.Result()
+		return stats(l.stateFrom(limit, window, res, err))
 	}
 }
 
-// State 返回当前滑动窗口内的请求数量等状态。
-func (l *SingleSlidingWindowLimiter) State(ctx context.Context) (LimiterState, error) {
-	now := float64(time.Now().UnixNano() / 1e6)
-	windowMs := l.Window.Milliseconds()
-	minScore := now - float64(windowMs)
+// stateArgs 返回 slidingWindowStateScript 的参数。
+func (l *SingleSlidingWindowLimiter) stateArgs(limit int64, window time.Duration) []interface{} {
+	return []interface{}{scriptNow(l.ServerTime, l.Clock), window.Milliseconds(), limit}
+}
 
-	// 统计 [minScore, +inf] 范围内的元素数量，即当前窗口内请求数。
-	card, err := l.client.ZCount(ctx, l.logKey(), fmt.Sprintf("%f", minScore), "+inf").Result()
+// stateFrom 根据 slidingWindowStateScript 的返回值计算当前状态。
+func (l *SingleSlidingWindowLimiter) stateFrom(limit int64, window time.Duration, res interface{}, err error) (LimiterState, error) {
 	if err != nil {
 		return LimiterState{}, err
 	}
+	vals, ok := res.([]interface{})
+	if !ok || len(vals) != 3 {
+		return LimiterState{}, fmt.Errorf("sliding window: unexpected state result: %#v", res)
+	}
+	card, ok1 := vals[0].(int64)
+	now, ok2 := vals[2].(int64)
+	if !ok1 || !ok2 {
+		return LimiterState{}, fmt.Errorf("sliding window: unexpected state result: %#v", res)
+	}
 
 	level := float64(card)
-	remaining := float64(l.Limit) - level
+	remaining := float64(limit) - level
 	if remaining < 0 {
 		remaining = 0
 	}
 
-	rate := float64(l.Limit) / l.Window.Seconds()
-
-	nowMsInt := time.Now().UnixMilli()
+	next := now
+	if s, ok := vals[1].(string); ok && card >= limit {
+		score, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return LimiterState{}, fmt.Errorf("sliding window: invalid score: %v", err)
+		}
+		next = max(int64(math.Ceil(score))+window.Milliseconds(), now)
+	}
 
 	return LimiterState{
 		Level:             level,
 		Remaining:         remaining,
-		Capacity:          float64(l.Limit),
-		Rate:              rate,
-		LastUpdated:       nowMsInt,
-		NextAvailableTime: nowMsInt, // 精确下一次可用时间可按需要进一步计算
+		Capacity:          float64(limit),
+		Rate:              float64(limit) / window.Seconds(),
+		LastUpdated:       now,
+		NextAvailableTime: next,
 		Type:              "sliding_window",
 		Key:               l.Key,
+		Window:            window,
 	}, nil
 }
+
+// limits 返回当前生效的窗口上限、窗口大小和 TTL 快照。
+func (l *SingleSlidingWindowLimiter) limits() (limit int64, window, ttl time.Duration) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.Limit, l.Window, l.TTL
+}
+
+// SetLimit 在运行时修改窗口内最大请求数，并发安全。
+// 调小后，窗口内已有的记录仍然有效，直到它们滑出窗口前新请求都会被拒绝。
+func (l *SingleSlidingWindowLimiter) SetLimit(limit int64) error {
+	if limit <= 0 {
+		return fmt.Errorf("sliding window: limit must > 0")
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.Limit = limit
+	return nil
+}
+
+// SetWindow 在运行时修改窗口大小（最小精度为毫秒），并发安全。
+// 若当前 TTL 小于新窗口的 2 倍，会同步调大 TTL，避免记录在窗口内提前过期。
+func (l *SingleSlidingWindowLimiter) SetWindow(window time.Duration) error {
+	if window < time.Millisecond {
+		return fmt.Errorf("sliding window: window must >= 1ms")
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.Window = window
+	l.TTL = max(l.TTL, 2*window)
+	return nil
+}
+
+// Reconfigure 在一次加锁内同时替换窗口上限和窗口大小，实现 Reconfigurable。
+func (l *SingleSlidingWindowLimiter) Reconfigure(c LimitConfig) error {
+	if err := c.validate(); err != nil {
+		return err
+	}
+	if c.WindowMs > 0 && c.window() < time.Millisecond {
+		return fmt.Errorf("sliding window: window must >= 1ms")
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if c.Limit > 0 {
+		l.Limit = c.Limit
+	}
+	if c.WindowMs > 0 {
+		l.Window = c.window()
+		l.TTL = max(l.TTL, 2*l.Window)
+	}
+	return nil
+}
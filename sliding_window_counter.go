@@ -0,0 +1,225 @@
+package limiter
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// SlidingWindowCounterLimiter 实现“滑动窗口计数器”（分桶近似）限流器。
+// 特点：
+//   - 窗口被切分为 Buckets 个固定子桶，计数存放在一个 Redis hash 中，内存占用为 O(Buckets)，
+//     与 Limit 无关，适合上万级别的窗口上限（ZSET 实现需要 O(Limit) 内存）
+//   - 最老的子桶按其落在窗口内的比例线性折算，误差不超过一个子桶的请求量
+//   - 子桶越多越精确，但 hash 字段也越多，默认 10 个
+type SlidingWindowCounterLimiter struct {
+	client *redis.Client
+
+	Key     string        // 业务 key
+	Prefix  string        // Redis key 前缀，默认 "swc"
+	Window  time.Duration // 窗口大小，例如 1 * time.Minute
+	Limit   int64         // 窗口内最大允许请求数
+	Buckets int64         // 子桶数量，默认 10
+	TTL     time.Duration // key 过期时间，建议 >= Window * 2
+
+	// TTLJitter TTL 抖动比例（0~1），用于打散大量 key 的过期时间，默认 0（不抖动）
+	TTLJitter float64
+	// WaitJitter Wait 重试前的随机抖动比例（0~1）
+	WaitJitter float64
+	// WaitStrategy Wait 被拒绝后的等待策略（可选），默认按 RetryAfter 等待并叠加 WaitJitter，见 WaitStrategy。
+	WaitStrategy WaitStrategy
+
+	// ServerTime 为 true（默认）时脚本使用 Redis TIME 作为当前时间，
+	// 所有应用实例共享同一个时钟源；为 false 时使用本机时钟。
+	ServerTime bool
+
+	// Clock 时钟，默认 SystemClock。
+	Clock Clock
+
+	// StatsTTL 按 key 统计放行 / 拒绝数量时计数器的过期时间（每次写入时刷新），0 表示不统计（默认）。
+	// 计数由限流脚本原子累加到 "<Prefix>:{Key}:stats:allowed" / "denied"，通过 Stats 读取。
+	StatsTTL time.Duration
+
+	// Hooks 判定事件钩子（可选），每次放行 / 拒绝 / 出错时回调，见 Hooks。
+	Hooks Hooks
+}
+
+// NewSlidingWindowCounterLimiter 创建一个滑动窗口计数器限流器。
+func NewSlidingWindowCounterLimiter(
+	client *redis.Client,
+	key string,
+	opts ...SlidingWindowCounterOption,
+) *SlidingWindowCounterLimiter {
+
+	if client == nil {
+		panic("sliding window counter: redis client is nil")
+	}
+	if key == "" {
+		panic("sliding window counter: key is empty")
+	}
+
+	l := &SlidingWindowCounterLimiter{
+		client:     client,
+		Key:        key,
+		Prefix:     "swc",
+		Window:     1 * time.Minute,
+		Limit:      60,
+		Buckets:    10,
+		TTL:        2 * time.Minute,
+		ServerTime: true,
+		Clock:      SystemClock,
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	if l.Window.Milliseconds() < l.Buckets {
+		panic("sliding window counter: each bucket must be at least 1ms")
+	}
+	return l
+}
+
+// bucketsKey 返回存放子桶计数的 hash key。
+func (l *SlidingWindowCounterLimiter) bucketsKey() string {
+	return fmt.Sprintf("%s:{%s}:buckets", l.Prefix, l.Key)
+}
+
+// bucketMs 返回子桶大小（毫秒）。
+func (l *SlidingWindowCounterLimiter) bucketMs() int64 {
+	return l.Window.Milliseconds() / l.Buckets
+}
+
+// Allow 尝试占一个名额。
+func (l *SlidingWindowCounterLimiter) Allow(ctx context.Context) (bool, error) {
+	return l.AllowN(ctx, 1)
+}
+
+// AllowWithResult 尝试占一个名额，并返回估算的剩余名额及重试等待时间。
+func (l *SlidingWindowCounterLimiter) AllowWithResult(ctx context.Context) (Result, error) {
+	return l.AllowNWithResult(ctx, 1)
+}
+
+// AllowN 尝试一次占用 n 个名额，不足时整体拒绝。
+func (l *SlidingWindowCounterLimiter) AllowN(ctx context.Context, n int64) (bool, error) {
+	res, err := l.AllowNWithResult(ctx, n)
+	if err != nil {
+		return false, err
+	}
+	return res.Allowed, nil
+}
+
+// AllowNWithResult 尝试一次占用 n 个名额，并返回估算的剩余名额及重试等待时间。
+func (l *SlidingWindowCounterLimiter) AllowNWithResult(ctx context.Context, n int64) (Result, error) {
+	res, err := l.eval(ctx, n, false)
+	fireHooks(ctx, l.Hooks, "sliding_window_counter", l.Key, n, res, err)
+	return res, err
+}
+
+// Check 判断当前是否能占用 1 个名额，但不计数。
+func (l *SlidingWindowCounterLimiter) Check(ctx context.Context) (Result, error) {
+	return l.CheckN(ctx, 1)
+}
+
+// CheckN 判断当前是否能一次占用 n 个名额，但不计数。
+// 脚本同样会清理过期子桶并完成估算；放行时 Remaining 为当前估算剩余名额（未扣除 n）。
+func (l *SlidingWindowCounterLimiter) CheckN(ctx context.Context, n int64) (Result, error) {
+	return l.eval(ctx, n, true)
+}
+
+// eval 执行滑动窗口计数脚本；dryRun 为 true 时只判定不计数。
+func (l *SlidingWindowCounterLimiter) eval(ctx context.Context, n int64, dryRun bool) (Result, error) {
+	if n <= 0 {
+		return Result{}, fmt.Errorf("sliding window counter: n must > 0")
+	}
+	if n > l.Limit {
+		return Result{}, fmt.Errorf("sliding window counter: n must <= limit")
+	}
+
+	nowMs := float64(scriptNow(l.ServerTime, l.Clock))
+
+	keys := []string{l.bucketsKey()}
+	args := []interface{}{nowMs, l.bucketMs(), l.Buckets, l.Limit, n, l.TTL.Milliseconds(), l.TTLJitter}
+	if dryRun {
+		args = append(args, 1)
+	} else {
+		keys, args = appendStats(keys, args, statsBase(l.Prefix, l.Key), l.StatsTTL, n, false)
+	}
+
+	res, err := slidingWindowCounterScript.Run(ctx, l.client, keys, args...).Result()
+	if err != nil {
+		return Result{}, err
+	}
+
+	vals, ok := scriptInts(res, 3)
+	if !ok {
+		return Result{}, fmt.Errorf("sliding window counter: unexpected script result: %#v", res)
+	}
+	return Result{
+		Allowed:    vals[0] == 1,
+		Limit:      float64(l.Limit),
+		Remaining:  float64(vals[1]),
+		RetryAfter: time.Duration(vals[2]) * time.Millisecond,
+	}, nil
+}
+
+// Wait 阻塞直到获得一个名额，或 ctx 取消 / 超过 maxWait。
+func (l *SlidingWindowCounterLimiter) Wait(ctx context.Context, maxWait time.Duration) error {
+	return waitFor(ctx, l.Key, "sliding_window_counter", maxWait, waitStrategy(l.WaitStrategy, l.WaitJitter), l.AllowWithResult)
+}
+
+// Stats 返回按 key 统计的放行 / 拒绝数量，需要通过 WithSlidingWindowCounterStats 开启统计。
+func (l *SlidingWindowCounterLimiter) Stats(ctx context.Context) (LimiterStats, error) {
+	return readStats(ctx, l.client, statsBase(l.Prefix, l.Key))
+}
+
+// Reset 删除子桶 hash，清空整个窗口。
+func (l *SlidingWindowCounterLimiter) Reset(ctx context.Context) error {
+	return l.client.Del(ctx, l.bucketsKey()).Err()
+}
+
+// State 读取全部子桶，在本地按与脚本相同的方式估算当前窗口内的请求数。
+func (l *SlidingWindowCounterLimiter) State(ctx context.Context) (LimiterState, error) {
+	raw, err := l.client.HGetAll(ctx, l.bucketsKey()).Result()
+	if err != nil {
+		return LimiterState{}, err
+	}
+
+	counts := make(map[int64]float64, len(raw))
+	for field, val := range raw {
+		b, err := strconv.ParseInt(field, 10, 64)
+		if err != nil {
+			return LimiterState{}, fmt.Errorf("sliding window counter: invalid bucket: %v", err)
+		}
+		c, err := strconv.ParseFloat(val, 64)
+		if err != nil {
+			return LimiterState{}, fmt.Errorf("sliding window counter: invalid count: %v", err)
+		}
+		counts[b] = c
+	}
+
+	now := l.Clock.Now().UnixMilli()
+	size := float64(l.bucketMs())
+	cur := int64(math.Floor(float64(now) / size))
+	oldest := cur - l.Buckets
+
+	w := (float64(cur+1)*size - float64(now)) / size
+	level := w * counts[oldest]
+	for b := oldest + 1; b <= cur; b++ {
+		level += counts[b]
+	}
+
+	return fillStats(ctx, l.client, statsBase(l.Prefix, l.Key), l.StatsTTL, LimiterState{
+		Level:             level,
+		Remaining:         math.Max(math.Floor(float64(l.Limit)-level), 0),
+		Capacity:          float64(l.Limit),
+		Rate:              float64(l.Limit) / l.Window.Seconds(),
+		LastUpdated:       now,
+		NextAvailableTime: now,
+		Type:              "sliding_window_counter",
+		Key:               l.Key,
+		Window:            l.Window,
+	}, nil)
+}
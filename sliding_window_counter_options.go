@@ -0,0 +1,115 @@
+package limiter
+
+import "time"
+
+// SlidingWindowCounterOption 为滑动窗口计数器限流器的配置项。
+// 使用 SlidingWindowCounter 前缀，避免与其他限流器的 Option 冲突。
+type SlidingWindowCounterOption func(*SlidingWindowCounterLimiter)
+
+// WithSlidingWindowCounterWindow 设置窗口大小。
+func WithSlidingWindowCounterWindow(d time.Duration) SlidingWindowCounterOption {
+	return func(l *SlidingWindowCounterLimiter) {
+		if d > 0 {
+			l.Window = d
+		}
+	}
+}
+
+// WithSlidingWindowCounterLimit 设置窗口内允许的最大请求数。
+func WithSlidingWindowCounterLimit(limit int64) SlidingWindowCounterOption {
+	return func(l *SlidingWindowCounterLimiter) {
+		if limit > 0 {
+			l.Limit = limit
+		}
+	}
+}
+
+// WithSlidingWindowCounterBuckets 设置子桶数量，子桶越多越精确。
+func WithSlidingWindowCounterBuckets(n int64) SlidingWindowCounterOption {
+	return func(l *SlidingWindowCounterLimiter) {
+		if n > 0 {
+			l.Buckets = n
+		}
+	}
+}
+
+// WithSlidingWindowCounterTTL 设置 Redis key 的 TTL。
+func WithSlidingWindowCounterTTL(ttl time.Duration) SlidingWindowCounterOption {
+	return func(l *SlidingWindowCounterLimiter) {
+		if ttl > 0 {
+			l.TTL = ttl
+		}
+	}
+}
+
+// WithSlidingWindowCounterTTLJitter 设置 TTL 抖动比例（TTL 延长 0~ratio），取值范围 [0, 1)。
+func WithSlidingWindowCounterTTLJitter(ratio float64) SlidingWindowCounterOption {
+	return func(l *SlidingWindowCounterLimiter) {
+		if ratio < 0 || ratio >= 1 {
+			panic("sliding window counter: ttl jitter must be in [0, 1)")
+		}
+		l.TTLJitter = ratio
+	}
+}
+
+// WithSlidingWindowCounterWaitJitter 设置 Wait 重试前的随机抖动比例，取值范围 [0, 1]。
+func WithSlidingWindowCounterWaitJitter(ratio float64) SlidingWindowCounterOption {
+	return func(l *SlidingWindowCounterLimiter) {
+		if ratio < 0 || ratio > 1 {
+			panic("sliding window counter: wait jitter must be in [0, 1]")
+		}
+		l.WaitJitter = ratio
+	}
+}
+
+// WithSlidingWindowCounterWaitStrategy 设置 Wait 被拒绝后的等待策略，例如 ExponentialWait、NewPacedWait。
+// 设置后 WaitJitter 不再生效，抖动由策略自身负责。
+func WithSlidingWindowCounterWaitStrategy(s WaitStrategy) SlidingWindowCounterOption {
+	return func(l *SlidingWindowCounterLimiter) {
+		l.WaitStrategy = s
+	}
+}
+
+// WithSlidingWindowCounterServerTime 设置是否使用 Redis TIME 作为脚本中的当前时间，默认开启。
+// 关闭后使用本机时钟，各应用实例的时钟偏差会直接影响 refill 与窗口边界。
+func WithSlidingWindowCounterServerTime(enabled bool) SlidingWindowCounterOption {
+	return func(l *SlidingWindowCounterLimiter) {
+		l.ServerTime = enabled
+	}
+}
+
+// WithSlidingWindowCounterClock 设置时钟，通常用于测试中控制时间。
+// 注入时钟后脚本改用该时钟传入的时间（相当于 WithSlidingWindowCounterServerTime(false)）。
+func WithSlidingWindowCounterClock(c Clock) SlidingWindowCounterOption {
+	return func(l *SlidingWindowCounterLimiter) {
+		if c != nil {
+			l.Clock = c
+			l.ServerTime = false
+		}
+	}
+}
+
+// WithSlidingWindowCounterPrefix 设置 Redis key 前缀。
+func WithSlidingWindowCounterPrefix(prefix string) SlidingWindowCounterOption {
+	return func(l *SlidingWindowCounterLimiter) {
+		if prefix != "" {
+			l.Prefix = prefix
+		}
+	}
+}
+
+// WithSlidingWindowCounterStats 开启按 key 统计放行 / 拒绝数量，ttl 为计数器的过期时间（每次写入时刷新）。
+func WithSlidingWindowCounterStats(ttl time.Duration) SlidingWindowCounterOption {
+	return func(l *SlidingWindowCounterLimiter) {
+		if ttl > 0 {
+			l.StatsTTL = ttl
+		}
+	}
+}
+
+// WithSlidingWindowCounterHooks 设置判定事件钩子，用于接入日志、指标或告警。
+func WithSlidingWindowCounterHooks(h Hooks) SlidingWindowCounterOption {
+	return func(l *SlidingWindowCounterLimiter) {
+		l.Hooks = h
+	}
+}
@@ -0,0 +1,47 @@
+package limiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlidingWindowCounterLimiter(t *testing.T) {
+	client := newSpecClient(t)
+	ctx := context.Background()
+
+	l := NewSlidingWindowCounterLimiter(client, "feed",
+		WithSlidingWindowCounterWindow(time.Hour),
+		WithSlidingWindowCounterLimit(10000),
+		WithSlidingWindowCounterBuckets(60),
+	)
+
+	ok, err := l.AllowN(ctx, 9999)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	res, err := l.AllowNWithResult(ctx, 2)
+	assert.NoError(t, err)
+	assert.False(t, res.Allowed)
+	assert.Equal(t, float64(1), res.Remaining)
+	assert.Greater(t, res.RetryAfter, time.Duration(0))
+
+	st, err := l.State(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, float64(9999), st.Level)
+
+	// 内存占用与 limit 无关：只有一个子桶字段
+	assert.Equal(t, int64(1), client.HLen(ctx, l.bucketsKey()).Val())
+
+	assert.NoError(t, l.Reset(ctx))
+	ok, _ = l.AllowN(ctx, 10000)
+	assert.True(t, ok)
+
+	assert.Panics(t, func() {
+		NewSlidingWindowCounterLimiter(client, "bad",
+			WithSlidingWindowCounterWindow(5*time.Millisecond),
+			WithSlidingWindowCounterBuckets(10))
+	})
+}
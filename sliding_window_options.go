@@ -33,6 +33,54 @@ func WithSlidingWindowTTL(ttl time.Duration) SlidingWindowOption {
 	}
 }
 
+// WithSlidingWindowTTLJitter 设置 TTL 抖动比例（TTL 延长 0~ratio），取值范围 [0, 1)。
+func WithSlidingWindowTTLJitter(ratio float64) SlidingWindowOption {
+	return func(l *SingleSlidingWindowLimiter) {
+		if ratio < 0 || ratio >= 1 {
+			panic("sliding window: ttl jitter must be in [0, 1)")
+		}
+		l.TTLJitter = ratio
+	}
+}
+
+// WithSlidingWindowWaitJitter 设置 Wait 重试前的随机抖动比例，取值范围 [0, 1]。
+// 大量 goroutine 等待同一个 key 时，抖动可以把它们的重试时间错开。
+func WithSlidingWindowWaitJitter(ratio float64) SlidingWindowOption {
+	return func(l *SingleSlidingWindowLimiter) {
+		if ratio < 0 || ratio > 1 {
+			panic("sliding window: wait jitter must be in [0, 1]")
+		}
+		l.WaitJitter = ratio
+	}
+}
+
+// WithSlidingWindowWaitStrategy 设置 Wait 被拒绝后的等待策略，例如 ExponentialWait、NewPacedWait。
+// 设置后 WaitJitter 不再生效，抖动由策略自身负责。
+func WithSlidingWindowWaitStrategy(s WaitStrategy) SlidingWindowOption {
+	return func(l *SingleSlidingWindowLimiter) {
+		l.WaitStrategy = s
+	}
+}
+
+// WithSlidingWindowServerTime 设置是否使用 Redis TIME 作为脚本中的当前时间，默认开启。
+// 关闭后使用本机时钟，各应用实例的时钟偏差会直接影响 refill 与窗口边界。
+func WithSlidingWindowServerTime(enabled bool) SlidingWindowOption {
+	return func(l *SingleSlidingWindowLimiter) {
+		l.ServerTime = enabled
+	}
+}
+
+// WithSlidingWindowClock 设置时钟，通常用于测试中控制时间。
+// 注入时钟后脚本改用该时钟传入的时间（相当于 WithSlidingWindowServerTime(false)）。
+func WithSlidingWindowClock(c Clock) SlidingWindowOption {
+	return func(l *SingleSlidingWindowLimiter) {
+		if c != nil {
+			l.Clock = c
+			l.ServerTime = false
+		}
+	}
+}
+
 // WithSlidingWindowPrefix 设置 Redis key 前缀。
 func WithSlidingWindowPrefix(prefix string) SlidingWindowOption {
 	return func(l *SingleSlidingWindowLimiter) {
@@ -49,3 +97,19 @@ func WithSlidingWindowCustom(fn func(*SingleSlidingWindowLimiter)) SlidingWindow
 		fn(l)
 	}
 }
+
+// WithSlidingWindowStats 开启按 key 统计放行 / 拒绝数量，ttl 为计数器的过期时间（每次写入时刷新）。
+func WithSlidingWindowStats(ttl time.Duration) SlidingWindowOption {
+	return func(l *SingleSlidingWindowLimiter) {
+		if ttl > 0 {
+			l.StatsTTL = ttl
+		}
+	}
+}
+
+// WithSlidingWindowHooks 设置判定事件钩子，用于接入日志、指标或告警。
+func WithSlidingWindowHooks(h Hooks) SlidingWindowOption {
+	return func(l *SingleSlidingWindowLimiter) {
+		l.Hooks = h
+	}
+}
@@ -38,7 +38,9 @@ func TestSingleSlidingWindowLimiter_AllowN(t *testing.T) {
 			int64(60_000), // windowMs
 			int64(60),     // limit
 			int64(120_000),
-		).SetVal(int64(1))
+			0.0,      // TTL jitter
+			int64(1), // req
+		).SetVal([]interface{}{int64(1), int64(59), int64(0)})
 
 		sw := NewSlidingWindowLimiter(
 			db,
@@ -74,6 +76,8 @@ func TestSingleSlidingWindowLimiter_AllowN(t *testing.T) {
 			int64(60_000), // windowMs
 			int64(60),     // limit
 			int64(120_000),
+			0.0,      // TTL jitter
+			int64(1), // req
 		).SetErr(redis.ErrClosed)
 
 		sw := NewSlidingWindowLimiter(
@@ -110,6 +114,8 @@ func TestSingleSlidingWindowLimiter_AllowN(t *testing.T) {
 			int64(60_000), // windowMs
 			int64(60),     // limit
 			int64(120_000),
+			0.0,      // TTL jitter
+			int64(1), // req
 		).SetVal("0")
 
 		sw := NewSlidingWindowLimiter(
@@ -160,7 +166,9 @@ func TestSingleSlidingWindowLimiter_Wait(t *testing.T) {
 			int64(60_000), // windowMs
 			int64(60),     // limit
 			int64(120_000),
-		).SetVal(0)
+			0.0,      // TTL jitter
+			int64(1), // req
+		).SetVal([]interface{}{int64(0), int64(0), int64(10)})
 		mock.CustomMatch(func(expected, actual []interface{}) error {
 			actual[5] = nowMs
 			if !reflect.DeepEqual(expected, actual) {
@@ -177,7 +185,9 @@ func TestSingleSlidingWindowLimiter_Wait(t *testing.T) {
 			int64(60_000), // windowMs
 			int64(60),     // limit
 			int64(120_000),
-		).SetVal(1)
+			0.0,      // TTL jitter
+			int64(1), // req
+		).SetVal([]interface{}{int64(1), int64(59), int64(0)})
 
 		err := sw.Wait(ctx, time.Second)
 		assert.Nil(t, err)
@@ -203,7 +213,9 @@ func TestSingleSlidingWindowLimiter_Wait(t *testing.T) {
 			int64(60_000), // windowMs
 			int64(60),     // limit
 			int64(120_000),
-		).SetVal(0)
+			0.0,      // TTL jitter
+			int64(1), // req
+		).SetVal([]interface{}{int64(0), int64(0), int64(10)})
 
 		err := sw.Wait(ctx, 0)
 		assert.ErrorIs(t, err, ErrLimiter)
@@ -223,31 +235,99 @@ func TestSingleSlidingWindowLimiter_State(t *testing.T) {
 		WithSlidingWindowTTL(2*time.Minute),
 	)
 
+	keys := []string{"sw:{login}:log"}
 	t.Run("SingleSlidingWindowLimiter_State_OK", func(t *testing.T) {
-		mock.CustomMatch(func(expected, actual []interface{}) error {
-			actual[2] = expected[2]
-			if !reflect.DeepEqual(expected, actual) {
-				return fmt.Errorf("expected %v, got %v", expected, actual)
-			}
-			return nil
-		}).ExpectZCount("sw:{login}:log", "", "+inf").SetVal(10)
+		now := time.Now().UnixMilli()
+		mock.ExpectEvalSha(slidingWindowStateScript.Hash(), keys, int64(0), int64(60000), int64(60)).
+			SetVal([]interface{}{int64(10), nil, now})
 
 		state, err := sw.State(ctx)
 		assert.Nil(t, err)
 		assert.Equal(t, float64(60), state.Capacity)
+		assert.Equal(t, float64(50), state.Remaining)
+		assert.Equal(t, now, state.NextAvailableTime)
 	})
 	t.Run("SingleSlidingWindowLimiter_State_Err", func(t *testing.T) {
-		mock.CustomMatch(func(expected, actual []interface{}) error {
-			actual[2] = expected[2]
-			if !reflect.DeepEqual(expected, actual) {
-				return fmt.Errorf("expected %v, got %v", expected, actual)
-			}
-			return nil
-		}).ExpectZCount("sw:{login}:log", "", "+inf").SetErr(redis.ErrClosed)
+		mock.ExpectEvalSha(slidingWindowStateScript.Hash(), keys, int64(0), int64(60000), int64(60)).SetErr(redis.ErrClosed)
 
 		state, err := sw.State(ctx)
 		assert.ErrorIs(t, err, redis.ErrClosed)
 		assert.Equal(t, float64(0), state.Capacity)
-
 	})
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSingleSlidingWindowLimiter_AllowN_Batch(t *testing.T) {
+	client := newSpecClient(t)
+	ctx := context.Background()
+
+	sw := NewSlidingWindowLimiter(client, "sms", WithSlidingWindowLimit(8))
+
+	ok, err := sw.AllowN(ctx, 5)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	// 剩余 3 个名额，不足 5 个时整体拒绝，不写入任何记录
+	res, err := sw.AllowNWithResult(ctx, 5)
+	assert.NoError(t, err)
+	assert.False(t, res.Allowed)
+	assert.Equal(t, float64(3), res.Remaining)
+	card, _ := client.ZCard(ctx, sw.logKey()).Result()
+	assert.Equal(t, int64(5), card)
+
+	_, err = sw.AllowN(ctx, 9)
+	assert.Error(t, err)
+
+	// 超过 Lua unpack 上限（约 4000 个 member）的批量请求分批写入
+	big := NewSlidingWindowLimiter(client, "bulk", WithSlidingWindowLimit(10000))
+	res, err = big.AllowNWithResult(ctx, 5000)
+	assert.NoError(t, err)
+	assert.True(t, res.Allowed)
+	assert.Equal(t, float64(5000), res.Remaining)
+	card, _ = client.ZCard(ctx, big.logKey()).Result()
+	assert.Equal(t, int64(5000), card)
+}
+
+func TestSingleSlidingWindowLimiter_State_NextAvailable(t *testing.T) {
+	client := newSpecClient(t)
+	ctx := context.Background()
+
+	now := time.UnixMilli(1_700_000_000_000)
+	sw := NewSlidingWindowLimiter(client, "sms",
+		WithSlidingWindowLimit(3), WithSlidingWindowWindow(10*time.Second),
+		WithSlidingWindowServerTime(false),
+		WithSlidingWindowClock(ClockFunc(func() time.Time { return now })))
+
+	for i := 0; i < 3; i++ {
+		_, err := sw.Allow(ctx)
+		assert.NoError(t, err)
+		now = now.Add(2 * time.Second)
+	}
+
+	// 未满时立即可用
+	_ = sw.SetLimit(4)
+	st, err := sw.State(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, now.UnixMilli(), st.NextAvailableTime)
+
+	// 已满时等到最早一条记录滑出窗口，与脚本返回的 RetryAfter 一致
+	_ = sw.SetLimit(3)
+	st, err = sw.State(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, now.Add(4*time.Second).UnixMilli(), st.NextAvailableTime)
+	res, err := sw.AllowWithResult(ctx)
+	assert.NoError(t, err)
+	assert.False(t, res.Allowed)
+	assert.Equal(t, 4*time.Second, res.RetryAfter)
+
+	// 调小上限后需要更多记录滑出窗口
+	_ = sw.SetLimit(2)
+	st, err = sw.State(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, now.Add(6*time.Second).UnixMilli(), st.NextAvailableTime)
+
+	// pipeline 读取（GlobalState）结果一致
+	gs, err := collectShardStates(ctx, client, "sms", []*SingleSlidingWindowLimiter{sw})
+	assert.NoError(t, err)
+	assert.Equal(t, st.NextAvailableTime, gs.Total.NextAvailableTime)
 }
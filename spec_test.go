@@ -0,0 +1,378 @@
+package limiter
+
+import (
+	"context"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+)
+
+// 本文件是各限流算法的“可执行规格”：
+//   - spec* 类型是纯 Go 的参考实现，语义与 scripts.go 中的 Lua 脚本一一对应；
+//   - TestSpec_* 在随机的时间序列（包含时钟回拨）下同时驱动参考实现与真实脚本，
+//     逐步比较判定结果和持久化的状态，保证脚本修改不会悄悄改变放行语义。
+//
+// 参考实现只描述“准入语义”，不包含 TTL：只要 TTL 大于 key 的空闲时间，TTL 不影响判定。
+
+// specTokenBucket 是 tokenBucketScript 的参考实现。
+type specTokenBucket struct {
+	rate, capacity float64
+
+	exists bool
+	tokens float64
+	lastTs float64
+}
+
+func (b *specTokenBucket) allow(now, req float64) bool {
+	return b.allowWithResult(now, req).Allowed
+}
+
+// allowWithResult 返回与脚本一致的 {allowed, remaining, retryAfter}。
+func (b *specTokenBucket) allowWithResult(now, req float64) Result {
+	tokens, lastTs := b.capacity, now
+	if b.exists {
+		tokens, lastTs = b.tokens, b.lastTs
+	}
+	// 时间戳只增不减：回拨的时钟被钳制到已记录的最大时间戳
+	now = math.Max(now, lastTs)
+	delta := now - lastTs
+	tokens = math.Min(tokens+delta*b.rate/1000, b.capacity)
+	if tokens < req {
+		// 拒绝时不修改状态
+		retryMs := math.Ceil((req - tokens) * 1000 / b.rate)
+		return Result{Remaining: math.Floor(tokens), RetryAfter: time.Duration(retryMs) * time.Millisecond}
+	}
+	b.exists, b.tokens, b.lastTs = true, tokens-req, now
+	return Result{Allowed: true, Remaining: math.Floor(b.tokens)}
+}
+
+// specLeakyBucket 是 leakyBucketScript 的参考实现。
+type specLeakyBucket struct {
+	leakRate, capacity float64
+
+	exists bool
+	level  float64
+	lastTs float64
+}
+
+// admit 返回实际放入的数量；partial=false 时要么全部放入，要么为 0。
+func (b *specLeakyBucket) admit(now, req float64, partial bool) float64 {
+	level, lastTs := 0.0, now
+	if b.exists {
+		level, lastTs = b.level, b.lastTs
+	}
+	now = math.Max(now, lastTs)
+	delta := now - lastTs
+	level = math.Max(level-delta*b.leakRate/1000, 0)
+
+	admitted := req
+	if partial {
+		admitted = math.Min(req, math.Floor(b.capacity-level))
+		if admitted <= 0 {
+			return 0
+		}
+	} else if level+req > b.capacity {
+		return 0
+	}
+	b.exists, b.level, b.lastTs = true, level+admitted, now
+	return admitted
+}
+
+// specSlidingWindow 是 slidingWindowScript 的参考实现。
+type specSlidingWindow struct {
+	window, limit float64
+
+	log []float64
+}
+
+func (w *specSlidingWindow) allow(now, req float64) Result {
+	// 无论是否放行，窗口外（score <= now-window）的记录都会被清理
+	kept := w.log[:0]
+	for _, ts := range w.log {
+		if ts > now-w.window {
+			kept = append(kept, ts)
+		}
+	}
+	w.log = kept
+	count := float64(len(w.log))
+	if count+req > w.limit {
+		// 最早的 count+req-limit 条记录滑出窗口后才有足够空位
+		sorted := slices.Clone(w.log)
+		slices.Sort(sorted)
+		k := int(count + req - w.limit)
+		retryMs := math.Max(math.Ceil(sorted[k-1]+w.window-now), 0)
+		return Result{Remaining: math.Max(w.limit-count, 0), RetryAfter: time.Duration(retryMs) * time.Millisecond}
+	}
+	for i := 0; i < int(req); i++ {
+		w.log = append(w.log, now)
+	}
+	return Result{Allowed: true, Remaining: w.limit - float64(len(w.log))}
+}
+
+// specSlidingWindowCounter 是 slidingWindowCounterScript 的参考实现。
+type specSlidingWindowCounter struct {
+	size, buckets, limit float64
+
+	counts map[float64]float64
+}
+
+func (c *specSlidingWindowCounter) allow(now, req float64) Result {
+	cur := math.Floor(now / c.size)
+	oldest := cur - c.buckets
+	for b := range c.counts {
+		if b < oldest {
+			delete(c.counts, b)
+		}
+	}
+
+	w := ((cur+1)*c.size - now) / c.size
+	count := w * c.counts[oldest]
+	for b := oldest + 1; b <= cur; b++ {
+		count += c.counts[b]
+	}
+
+	if count+req > c.limit {
+		excess := count + req - c.limit
+		elapsed := 0.0
+		retry := math.NaN()
+		for k := 0.0; k <= c.buckets; k++ {
+			cnt := c.counts[oldest+k]
+			weight := 1.0
+			if k == 0 {
+				weight = w
+			}
+			if cnt > 0 && cnt*weight >= excess {
+				retry = elapsed + excess*c.size/cnt
+				break
+			}
+			excess -= cnt * weight
+			elapsed += weight * c.size
+		}
+		if math.IsNaN(retry) {
+			retry = elapsed
+		}
+		return Result{
+			Remaining:  math.Max(math.Floor(c.limit-count), 0),
+			RetryAfter: time.Duration(math.Ceil(retry)) * time.Millisecond,
+		}
+	}
+
+	c.counts[cur] += req
+	return Result{Allowed: true, Remaining: math.Floor(c.limit - count - req)}
+}
+
+// specOverage 是 overageScript 的参考实现。
+type specOverage struct {
+	soft, hard int64
+
+	used int64
+}
+
+func (o *specOverage) allow(req int64) (allowed, flagged bool) {
+	if o.used+req > o.hard {
+		return false, true
+	}
+	o.used += req
+	return true, o.used > o.soft
+}
+
+// specSchedule 生成一个随机的时间序列：大多数情况下时间前进 0~200ms，
+// 偶尔出现最多 1s 的时钟回拨，用于覆盖 delta < 0 的分支。
+func specSchedule(r *rand.Rand, steps int) []float64 {
+	now := float64(1_700_000_000_000)
+	out := make([]float64, steps)
+	for i := range out {
+		if r.IntN(20) == 0 {
+			now -= float64(r.IntN(1000))
+		} else {
+			now += float64(r.IntN(200))
+		}
+		out[i] = now
+	}
+	return out
+}
+
+func newSpecClient(t *testing.T) *redis.Client {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	return client
+}
+
+func getFloat(t *testing.T, client *redis.Client, key string) float64 {
+	t.Helper()
+	s, err := client.Get(context.Background(), key).Result()
+	if err != nil {
+		t.Fatalf("get %s: %v", key, err)
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		t.Fatalf("parse %s: %v", key, err)
+	}
+	return v
+}
+
+func TestSpec_TokenBucket(t *testing.T) {
+	client := newSpecClient(t)
+	ctx := context.Background()
+	r := rand.New(rand.NewPCG(1, 2))
+
+	tb := NewTokenBucketLimiter(client, "spec", WithTokenBucketRate(20), WithTokenBucketCapacity(5))
+	ref := &specTokenBucket{rate: tb.Rate, capacity: tb.Capacity}
+
+	for i, now := range specSchedule(r, 500) {
+		req := float64(1 + r.IntN(3))
+		res, err := tokenBucketScript.Run(ctx, client,
+			[]string{tb.tokensKey(), tb.tsKey()},
+			now, tb.Rate, tb.Capacity, req, int64(3_600_000), 0.0,
+		).Int64Slice()
+		assert.NoError(t, err)
+
+		want := ref.allowWithResult(now, req)
+		got := Result{
+			Allowed:    res[0] == 1,
+			Remaining:  float64(res[1]),
+			RetryAfter: time.Duration(res[2]) * time.Millisecond,
+		}
+		if !assert.Equal(t, want, got, "step %d now=%.0f req=%.0f", i, now, req) {
+			return
+		}
+		if ref.exists {
+			assert.InDelta(t, ref.tokens, getFloat(t, client, tb.tokensKey()), 1e-9, "step %d", i)
+			assert.Equal(t, ref.lastTs, getFloat(t, client, tb.tsKey()), "step %d", i)
+		}
+	}
+}
+
+func TestSpec_LeakyBucket(t *testing.T) {
+	client := newSpecClient(t)
+	ctx := context.Background()
+	r := rand.New(rand.NewPCG(3, 4))
+
+	lb := NewLeakyBucketLimiter(client, "spec", WithLeakyBucketRate(20), WithLeakyBucketCapacity(5))
+	ref := &specLeakyBucket{leakRate: lb.LeakRate, capacity: lb.Capacity}
+
+	for i, now := range specSchedule(r, 500) {
+		req := float64(1 + r.IntN(3))
+		partial := r.IntN(2) == 0
+		res, err := leakyBucketScript.Run(ctx, client,
+			[]string{lb.bucketKey(), lb.tsKey()},
+			now, lb.LeakRate, lb.Capacity, req, int64(3_600_000), 0.0, partial,
+		).Int64Slice()
+		assert.NoError(t, err)
+
+		want := ref.admit(now, req, partial)
+		if !partial && want > 0 {
+			// 普通模式下脚本只返回 1/0
+			want = 1
+		}
+		if !assert.Equal(t, want, float64(res[0]), "step %d now=%.0f req=%.0f partial=%v", i, now, req, partial) {
+			return
+		}
+		if ref.exists {
+			assert.InDelta(t, ref.level, getFloat(t, client, lb.bucketKey()), 1e-9, "step %d", i)
+		}
+	}
+}
+
+func TestSpec_SlidingWindow(t *testing.T) {
+	client := newSpecClient(t)
+	ctx := context.Background()
+	r := rand.New(rand.NewPCG(5, 6))
+
+	sw := NewSlidingWindowLimiter(client, "spec",
+		WithSlidingWindowWindow(time.Second), WithSlidingWindowLimit(8))
+	ref := &specSlidingWindow{window: float64(sw.Window.Milliseconds()), limit: float64(sw.Limit)}
+
+	for i, now := range specSchedule(r, 500) {
+		req := int64(1 + r.IntN(3))
+		res, err := slidingWindowScript.Run(ctx, client,
+			[]string{sw.logKey(), sw.seqKey()},
+			now, sw.Window.Milliseconds(), sw.Limit, int64(3_600_000), 0.0, req,
+		).Int64Slice()
+		assert.NoError(t, err)
+
+		want := ref.allow(now, float64(req))
+		got := Result{
+			Allowed:    res[0] == 1,
+			Remaining:  float64(res[1]),
+			RetryAfter: time.Duration(res[2]) * time.Millisecond,
+		}
+		if !assert.Equal(t, want, got, "step %d now=%.0f", i, now) {
+			return
+		}
+		card, err := client.ZCard(ctx, sw.logKey()).Result()
+		assert.NoError(t, err)
+		assert.Equal(t, int64(len(ref.log)), card, "step %d", i)
+	}
+}
+
+func TestSpec_SlidingWindowCounter(t *testing.T) {
+	client := newSpecClient(t)
+	ctx := context.Background()
+	r := rand.New(rand.NewPCG(9, 10))
+
+	l := NewSlidingWindowCounterLimiter(client, "spec",
+		WithSlidingWindowCounterWindow(time.Second),
+		WithSlidingWindowCounterLimit(20),
+		WithSlidingWindowCounterBuckets(5))
+	ref := &specSlidingWindowCounter{
+		size:    float64(l.bucketMs()),
+		buckets: float64(l.Buckets),
+		limit:   float64(l.Limit),
+		counts:  map[float64]float64{},
+	}
+
+	for i, now := range specSchedule(r, 500) {
+		req := int64(1 + r.IntN(3))
+		res, err := slidingWindowCounterScript.Run(ctx, client,
+			[]string{l.bucketsKey()},
+			now, l.bucketMs(), l.Buckets, l.Limit, req, int64(3_600_000), 0.0,
+		).Int64Slice()
+		assert.NoError(t, err)
+
+		want := ref.allow(now, float64(req))
+		got := Result{
+			Allowed:    res[0] == 1,
+			Remaining:  float64(res[1]),
+			RetryAfter: time.Duration(res[2]) * time.Millisecond,
+		}
+		if !assert.Equal(t, want, got, "step %d now=%.0f", i, now) {
+			return
+		}
+		n, err := client.HLen(ctx, l.bucketsKey()).Result()
+		assert.NoError(t, err)
+		assert.Equal(t, int64(len(ref.counts)), n, "step %d", i)
+	}
+}
+
+func TestSpec_Overage(t *testing.T) {
+	client := newSpecClient(t)
+	ctx := context.Background()
+	r := rand.New(rand.NewPCG(7, 8))
+
+	ref := &specOverage{soft: 10, hard: 20}
+	for i := 0; i < 100; i++ {
+		req := int64(1 + r.IntN(4))
+		res, err := overageScript.Run(ctx, client,
+			[]string{"ovg:{spec}"},
+			int64(1_000), int64(3_600_000), ref.soft, ref.hard, req,
+		).Slice()
+		assert.NoError(t, err)
+
+		allowed, flagged := ref.allow(req)
+		if !assert.Equal(t, allowed, res[0] == int64(1), "step %d req=%d", i, req) {
+			return
+		}
+		assert.Equal(t, flagged, res[2] == int64(1), "step %d", i)
+		assert.Equal(t, ref.used, res[1], "step %d", i)
+	}
+}
@@ -0,0 +1,164 @@
+package limiter
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// LimiterStats 是按 key 统计的放行 / 拒绝数量，按请求的许可数（n）累计，
+// 可以与配置的限额对比，观察真实需求。
+type LimiterStats struct {
+	// Allowed 被放行的许可数
+	Allowed int64
+	// Denied 被拒绝的许可数
+	Denied int64
+}
+
+// withStats 为限流脚本加上可选的统计能力，开启统计时调用方在参数末尾追加：
+//   - KEYS：statsAllowedKey, statsDeniedKey
+//   - ARGV："stats:<ttlMs>:<n>"，部分放行模式为 "stats:<ttlMs>:<n>:partial"
+//
+// 包装后的脚本先把这些参数从 KEYS / ARGV 中移除，原脚本看到的参数布局保持不变；
+// 原脚本返回后按返回值第一个元素（放行标记）是否大于 0 把 n 累加到对应计数器并刷新 TTL。
+// 部分放行模式下第一个元素为实际放入的数量，放入的部分计为放行，其余计为拒绝。
+// 未追加统计参数时脚本行为与原脚本完全相同。
+func withStats(body string) string {
+	return `
+local statsTTL, statsN, statsPartial, statsAllowed, statsDenied
+do
+  local m = ARGV[#ARGV]
+  if type(m) == "string" and string.sub(m, 1, 6) == "stats:" then
+    local ttl, n, mode = string.match(m, "^stats:(%d+):(%d+):?(%a*)$")
+    statsTTL, statsN, statsPartial = tonumber(ttl), tonumber(n), mode == "partial"
+    ARGV[#ARGV] = nil
+    statsDenied = table.remove(KEYS)
+    statsAllowed = table.remove(KEYS)
+  end
+end
+
+local function limiterBody()
+` + body + `
+end
+
+local res = limiterBody()
+if statsTTL then
+  local allowed = 0
+  if statsPartial then
+    allowed = math.min(tonumber(res[1]), statsN)
+  elseif tonumber(res[1]) > 0 then
+    allowed = statsN
+  end
+  local counts = {{statsAllowed, allowed}, {statsDenied, statsN - allowed}}
+  for _, c in ipairs(counts) do
+    if c[2] > 0 then
+      redis.call("INCRBY", c[1], c[2])
+      redis.call("PEXPIRE", c[1], statsTTL)
+    end
+  end
+end
+return res
+`
+}
+
+// statsBase 返回统计计数器 key 的公共部分，形如 "tbucket:{key}"。
+// 包含限流器的 Prefix（各算法的默认值不同），同一个业务 key 上的不同限流器各自计数。
+func statsBase(prefix, key string) string {
+	return fmt.Sprintf("%s:{%s}", prefix, key)
+}
+
+// statsKey 返回统计计数器的 key，形如 "tbucket:{key}:stats:allowed"，base 见 statsBase。
+// 与限流状态共用 {key} 作为 hash tag，保证 Redis Cluster 中脚本访问的 key 落在同一 slot。
+func statsKey(base, outcome string) string {
+	return base + ":stats:" + outcome
+}
+
+// appendStats 在 ttl > 0 时把统计参数追加到脚本的 KEYS / ARGV 末尾，见 withStats。
+// partial 表示脚本返回的第一个元素为实际放入的数量（漏桶部分放行模式）。
+func appendStats(keys []string, args []interface{}, base string, ttl time.Duration, n int64, partial bool) ([]string, []interface{}) {
+	if ttl <= 0 {
+		return keys, args
+	}
+	keys = append(keys, statsKey(base, "allowed"), statsKey(base, "denied"))
+	marker := fmt.Sprintf("stats:%d:%d", ttl.Milliseconds(), n)
+	if partial {
+		marker += ":partial"
+	}
+	args = append(args, marker)
+	return keys, args
+}
+
+// recordStats 在脚本之外直接累加统计计数器并刷新 TTL，用于一次判定拆成多次脚本调用的场景（例如合并窗口）。
+func recordStats(ctx context.Context, client *redis.Client, base string, ttl time.Duration, allowed, denied int64) error {
+	_, err := client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for outcome, n := range map[string]int64{"allowed": allowed, "denied": denied} {
+			if n > 0 {
+				pipe.IncrBy(ctx, statsKey(base, outcome), n)
+				pipe.PExpire(ctx, statsKey(base, outcome), ttl)
+			}
+		}
+		return nil
+	})
+	return err
+}
+
+// readStats 读取 base 对应的统计计数器，不存在时为 0。
+func readStats(ctx context.Context, client *redis.Client, base string) (LimiterStats, error) {
+	return parseStats(client.MGet(ctx, statsKey(base, "allowed"), statsKey(base, "denied")).Result())
+}
+
+// fillStats 在开启统计（ttl > 0）时读取计数器，填入 State 结果的 AllowedTotal / DeniedTotal。
+func fillStats(ctx context.Context, client *redis.Client, base string, ttl time.Duration, st LimiterState, err error) (LimiterState, error) {
+	if err != nil || ttl <= 0 {
+		return st, err
+	}
+	stats, err := readStats(ctx, client, base)
+	if err != nil {
+		return LimiterState{}, err
+	}
+	st.AllowedTotal, st.DeniedTotal = stats.Allowed, stats.Denied
+	return st, nil
+}
+
+// queueStats 是 fillStats 的 pipeline 版本：开启统计时把计数器的读取排进 pipeline，
+// 返回的函数需在 Exec 之后调用。
+func queueStats(ctx context.Context, pipe redis.Pipeliner, base string, ttl time.Duration) func(LimiterState, error) (LimiterState, error) {
+	if ttl <= 0 {
+		return func(st LimiterState, err error) (LimiterState, error) { return st, err }
+	}
+	cmd := pipe.MGet(ctx, statsKey(base, "allowed"), statsKey(base, "denied"))
+	return func(st LimiterState, err error) (LimiterState, error) {
+		if err != nil {
+			return st, err
+		}
+		stats, err := parseStats(cmd.Result())
+		if err != nil {
+			return LimiterState{}, err
+		}
+		st.AllowedTotal, st.DeniedTotal = stats.Allowed, stats.Denied
+		return st, nil
+	}
+}
+
+// parseStats 解析 MGET allowed / denied 的结果。
+func parseStats(vals []interface{}, err error) (LimiterStats, error) {
+	if err != nil {
+		return LimiterStats{}, err
+	}
+
+	out := [2]int64{}
+	for i, v := range vals {
+		s, ok := v.(string)
+		if !ok {
+			continue
+		}
+		out[i], err = strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return LimiterStats{}, fmt.Errorf("stats: invalid counter: %v", err)
+		}
+	}
+	return LimiterStats{Allowed: out[0], Denied: out[1]}, nil
+}
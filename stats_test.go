@@ -0,0 +1,105 @@
+package limiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type statser interface {
+	checker
+	Stats(ctx context.Context) (LimiterStats, error)
+	State(ctx context.Context) (LimiterState, error)
+}
+
+func TestLimiterStats(t *testing.T) {
+	client := newSpecClient(t)
+	ctx := context.Background()
+
+	cases := map[string]statser{
+		"token_bucket": NewTokenBucketLimiter(client, "tb", WithTokenBucketRate(0.001), WithTokenBucketCapacity(5),
+			WithTokenBucketStats(time.Hour)),
+		"leaky_bucket": NewLeakyBucketLimiter(client, "lb", WithLeakyBucketRate(0.001), WithLeakyBucketCapacity(5),
+			WithLeakyBucketStorage(StorageHash), WithLeakyBucketStats(time.Hour)),
+		"sliding_window": NewSlidingWindowLimiter(client, "sw", WithSlidingWindowLimit(5),
+			WithSlidingWindowStats(time.Hour)),
+		"sliding_window_counter": NewSlidingWindowCounterLimiter(client, "swc", WithSlidingWindowCounterLimit(5),
+			WithSlidingWindowCounterStats(time.Hour)),
+		"fixed_window": NewFixedWindowLimiter(client, "fw", WithFixedWindowLimit(5), WithFixedWindowWindow(time.Minute),
+			WithFixedWindowStats(time.Hour)),
+	}
+
+	for name, l := range cases {
+		t.Run(name, func(t *testing.T) {
+			res, err := l.AllowNWithResult(ctx, 4)
+			assert.NoError(t, err)
+			assert.True(t, res.Allowed)
+			assert.Equal(t, float64(1), res.Remaining)
+
+			res, err = l.AllowNWithResult(ctx, 3)
+			assert.NoError(t, err)
+			assert.False(t, res.Allowed)
+
+			// 预检不计入统计
+			_, err = l.CheckN(ctx, 1)
+			assert.NoError(t, err)
+
+			st, err := l.Stats(ctx)
+			assert.NoError(t, err)
+			assert.Equal(t, LimiterStats{Allowed: 4, Denied: 3}, st)
+
+			// 开启统计时 State 同时返回累计计数
+			state, err := l.State(ctx)
+			assert.NoError(t, err)
+			assert.Equal(t, int64(4), state.AllowedTotal)
+			assert.Equal(t, int64(3), state.DeniedTotal)
+		})
+	}
+
+	key := statsKey(statsBase("tbucket", "tb"), "allowed")
+	ttl, err := client.PTTL(ctx, key).Result()
+	assert.NoError(t, err)
+	assert.Equal(t, time.Hour, ttl)
+
+	// 未开启统计时不写入计数器
+	plain := NewFixedWindowLimiter(client, "plain")
+	_, err = plain.Allow(ctx)
+	assert.NoError(t, err)
+	st, err := plain.Stats(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, LimiterStats{}, st)
+
+	// 同一个业务 key 上的不同限流器各自计数
+	fw := NewFixedWindowLimiter(client, "shared", WithFixedWindowLimit(5), WithFixedWindowStats(time.Hour))
+	sw := NewSlidingWindowLimiter(client, "shared", WithSlidingWindowLimit(5), WithSlidingWindowStats(time.Hour))
+	_, err = fw.AllowN(ctx, 2)
+	assert.NoError(t, err)
+	_, err = sw.AllowN(ctx, 3)
+	assert.NoError(t, err)
+	st, err = fw.Stats(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, LimiterStats{Allowed: 2}, st)
+	st, err = sw.Stats(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, LimiterStats{Allowed: 3}, st)
+
+	// 漏桶部分放行：实际放入的部分计为放行，其余计为拒绝
+	lb := NewLeakyBucketLimiter(client, "partial", WithLeakyBucketRate(0.001), WithLeakyBucketCapacity(5),
+		WithLeakyBucketStats(time.Hour))
+	admitted, err := lb.AllowUpToN(ctx, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), admitted)
+	admitted, err = lb.AllowUpToN(ctx, 4)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), admitted)
+	st, err = lb.Stats(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, LimiterStats{Allowed: 5, Denied: 2}, st)
+}
+
+func TestLimiterState_String(t *testing.T) {
+	s := LimiterState{Type: "sliding_window", Key: "sms", Window: time.Minute, Shard: 3, AllowedTotal: 7, DeniedTotal: 2}
+	assert.Contains(t, s.String(), "window=1m0s; shard=3; allowed_total=7; denied_total=2")
+}
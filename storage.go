@@ -0,0 +1,79 @@
+package limiter
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// StorageMode 为令牌桶 / 漏桶状态在 Redis 中的存储方式。
+type StorageMode int
+
+const (
+	// StorageString 桶内数值与上次更新时间分别存放在两个 string key 中（默认）。
+	StorageString StorageMode = iota
+	// StorageHash 桶内数值与上次更新时间存放在同一个 hash 的两个字段中：
+	// key 数量减半，且每个桶只有一个 key，不再依赖 hash tag 把多个 key 放进同一个 slot。
+	StorageHash
+)
+
+// bucketSnapshot 是 bucketStateScript 读出的一致快照，不存在的值为空串。
+type bucketSnapshot struct {
+	value    string
+	ts       string
+	override LimitOverride
+	stats    LimiterStats
+	grant    string
+	now      int64 // 脚本返回的当前时间（毫秒）
+}
+
+// bucketStateArgs 构造 bucketStateScript 的参数：overrideKey 为空表示未开启覆盖，statsBaseKey 为空表示未开启统计，
+// now 为 0 表示使用 Redis TIME（见 scriptNow）。
+func bucketStateArgs(valueKey, tsKey, field, overrideKey, statsBaseKey string, now int64) ([]string, []interface{}) {
+	keys := []string{valueKey, tsKey}
+	args := []interface{}{field, "0", "0", now}
+	if overrideKey != "" {
+		keys = append(keys, overrideKey)
+		args[1] = "1"
+	}
+	if statsBaseKey != "" {
+		keys = append(keys, statsKey(statsBaseKey, "allowed"), statsKey(statsBaseKey, "denied"))
+		args[2] = "1"
+	}
+	return keys, args
+}
+
+// parseBucketSnapshot 解析 bucketStateScript 的返回值。
+func parseBucketSnapshot(res interface{}, err error) (bucketSnapshot, error) {
+	if err != nil {
+		return bucketSnapshot{}, err
+	}
+	vals, ok := res.([]interface{})
+	if !ok || len(vals) != 8 {
+		return bucketSnapshot{}, fmt.Errorf("bucket state: unexpected script result: %#v", res)
+	}
+
+	var snap bucketSnapshot
+	snap.value, _ = vals[0].(string)
+	snap.ts, _ = vals[1].(string)
+	snap.grant, _ = vals[6].(string)
+	if snap.now, ok = vals[7].(int64); !ok {
+		return bucketSnapshot{}, fmt.Errorf("bucket state: unexpected script result: %#v", res)
+	}
+	if snap.override, _, err = parseOverride(vals[2:4], nil); err != nil {
+		return bucketSnapshot{}, err
+	}
+	if snap.stats, err = parseStats(vals[4:6], nil); err != nil {
+		return bucketSnapshot{}, err
+	}
+	return snap, nil
+}
+
+// deleteBucket 删除桶的全部状态 key。
+func deleteBucket(ctx context.Context, client *redis.Client, valueKey, tsKey string) error {
+	if valueKey == tsKey {
+		return client.Del(ctx, valueKey).Err()
+	}
+	return client.Del(ctx, valueKey, tsKey).Err()
+}
@@ -0,0 +1,111 @@
+package limiter
+
+import (
+	"context"
+	"math/rand/v2"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestStorageHash 对比两种存储方式：同样的请求序列应得到完全相同的判定结果。
+func TestStorageHash(t *testing.T) {
+	client := newSpecClient(t)
+	ctx := context.Background()
+	r := rand.New(rand.NewPCG(7, 7))
+
+	now := time.UnixMilli(1_700_000_000_000)
+	clock := ClockFunc(func() time.Time { return now })
+
+	tbOpts := []TokenBucketOption{
+		WithTokenBucketRate(5), WithTokenBucketCapacity(10),
+		WithTokenBucketTTL(time.Hour), WithTokenBucketClock(clock),
+	}
+	tbString := NewTokenBucketLimiter(client, "tb-string", tbOpts...)
+	tbHash := NewTokenBucketLimiter(client, "tb-hash", append(tbOpts, WithTokenBucketStorage(StorageHash))...)
+
+	lbOpts := []LeakyBucketOption{
+		WithLeakyBucketRate(5), WithLeakyBucketCapacity(10),
+		WithLeakyBucketTTL(time.Hour), WithLeakyBucketClock(clock),
+	}
+	lbString := NewLeakyBucketLimiter(client, "lb-string", lbOpts...)
+	lbHash := NewLeakyBucketLimiter(client, "lb-hash", append(lbOpts, WithLeakyBucketStorage(StorageHash))...)
+
+	for i := 0; i < 200; i++ {
+		now = now.Add(time.Duration(r.IntN(300)) * time.Millisecond)
+		n := int64(r.IntN(3) + 1)
+
+		want, err := tbString.AllowNWithResult(ctx, n)
+		assert.NoError(t, err)
+		got, err := tbHash.AllowNWithResult(ctx, n)
+		assert.NoError(t, err)
+		assert.Equal(t, want, got, "token bucket step %d", i)
+
+		want, err = lbString.AllowNWithResult(ctx, n)
+		assert.NoError(t, err)
+		got, err = lbHash.AllowNWithResult(ctx, n)
+		assert.NoError(t, err)
+		assert.Equal(t, want, got, "leaky bucket step %d", i)
+	}
+
+	wantState, err := tbString.State(ctx)
+	assert.NoError(t, err)
+	gotState, err := tbHash.State(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, wantState.Level, gotState.Level)
+
+	keys, _ := client.Keys(ctx, "*hash*").Result()
+	assert.ElementsMatch(t, []string{"tbucket:{tb-hash}:state", "lb:{lb-hash}:state"}, keys)
+
+	assert.NoError(t, tbHash.Reset(ctx))
+	assert.NoError(t, lbHash.Reset(ctx))
+	keys, _ = client.Keys(ctx, "*hash*").Result()
+	assert.Empty(t, keys)
+}
+
+// TestBucketStateSnapshot 确认令牌桶 / 漏桶的 State 连同覆盖配置与统计计数器只需一次脚本调用。
+func TestBucketStateSnapshot(t *testing.T) {
+	client := newSpecClient(t)
+	ctx := context.Background()
+
+	for _, storage := range []StorageMode{StorageString, StorageHash} {
+		tb := NewTokenBucketLimiter(client, "snap-tb", WithTokenBucketRate(1), WithTokenBucketCapacity(10),
+			WithTokenBucketStorage(storage), WithTokenBucketOverrides("ovr"), WithTokenBucketStats(time.Hour))
+		lb := NewLeakyBucketLimiter(client, "snap-lb", WithLeakyBucketRate(1), WithLeakyBucketCapacity(10),
+			WithLeakyBucketStorage(storage), WithLeakyBucketOverrides("ovr"), WithLeakyBucketStats(time.Hour))
+		assert.NoError(t, tb.SetOverride(ctx, LimitOverride{Capacity: 20}))
+		assert.NoError(t, lb.SetOverride(ctx, LimitOverride{Capacity: 20}))
+
+		ok, err := tb.AllowN(ctx, 5)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		ok, err = lb.AllowN(ctx, 5)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+
+		hook := &countingHook{}
+		client.AddHook(hook)
+
+		st, err := tb.State(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, float64(20), st.Capacity)
+		assert.InDelta(t, 15, st.Remaining, 0.5)
+		assert.Equal(t, int64(5), st.AllowedTotal)
+
+		st, err = lb.State(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, float64(20), st.Capacity)
+		assert.InDelta(t, 5, st.Level, 0.5)
+		assert.Equal(t, int64(5), st.AllowedTotal)
+
+		// 第一次调用脚本未加载时会多一次 EVAL 重试
+		assert.LessOrEqual(t, hook.n.Load(), int64(3))
+		hook.n.Store(0)
+		_, err = tb.State(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), hook.n.Load())
+
+		client.FlushAll(ctx)
+	}
+}
@@ -0,0 +1,74 @@
+package limiter
+
+import (
+	"context"
+	"time"
+)
+
+// TakeAdapter 为从 go.uber.org/ratelimit 迁移的调用方提供阻塞式的 Take：
+//
+//	rl := limiter.NewTakeAdapter(lb)
+//	for _, job := range jobs {
+//		rl.Take()
+//		process(job)
+//	}
+//
+// 节奏由漏桶控制：漏桶容量为 1 时相邻两次 Take 严格间隔 1/LeakRate（相当于 ratelimit.WithoutSlack），
+// 容量更大时允许积攒的突发即为容量（相当于 slack）。与 ratelimit 不同的是，节奏由 Redis 中的漏桶统一协调，
+// 多个实例共同按 LeakRate 匀速推进，而不是每个实例各自按 LeakRate 推进。
+//
+// Take 没有返回错误的途径：Redis 出错时按 FailOpen 决定立即返回还是间隔 ErrorBackoff 后重试，错误通过 OnError 上报。
+// 需要取消或感知错误时使用 TakeContext。
+type TakeAdapter struct {
+	lb *LeakyBucketLimiter
+
+	// FailOpen 为 true 时 Redis 出错立即放行，默认 false（间隔 ErrorBackoff 重试，直到成功）
+	FailOpen bool
+	// ErrorBackoff Redis 出错后重试的间隔，默认 100ms
+	ErrorBackoff time.Duration
+	// OnError Redis 出错时的回调（可选）
+	OnError func(err error)
+}
+
+// NewTakeAdapter 在漏桶 lb 之上创建 Take 适配器。
+func NewTakeAdapter(lb *LeakyBucketLimiter, opts ...TakeOption) *TakeAdapter {
+	if lb == nil {
+		panic("take: leaky bucket is nil")
+	}
+
+	a := &TakeAdapter{
+		lb:           lb,
+		ErrorBackoff: 100 * time.Millisecond,
+	}
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a
+}
+
+// Take 阻塞直到轮到本次调用，返回放行的时间。
+func (a *TakeAdapter) Take() time.Time {
+	for {
+		t, err := a.TakeContext(context.Background())
+		if err == nil {
+			return t
+		}
+		if a.OnError != nil {
+			a.OnError(err)
+		}
+		if a.FailOpen {
+			return time.Now()
+		}
+		time.Sleep(a.ErrorBackoff)
+	}
+}
+
+// TakeContext 阻塞直到轮到本次调用或 ctx 取消，返回放行的时间。
+// ctx 带截止时间且预计等待时间超出截止时间时立即返回 ErrTimeout。
+func (a *TakeAdapter) TakeContext(ctx context.Context) (time.Time, error) {
+	maxWait := ContextMaxWait(ctx)
+	if err := a.lb.Wait(ctx, maxWait); err != nil {
+		return time.Time{}, err
+	}
+	return time.Now(), nil
+}
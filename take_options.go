@@ -0,0 +1,29 @@
+package limiter
+
+import "time"
+
+// TakeOption 为 TakeAdapter 的配置项。
+type TakeOption func(*TakeAdapter)
+
+// WithTakeFailOpen 设置 Redis 出错时 Take 是否立即放行，默认 false（重试直到成功）。
+func WithTakeFailOpen(failOpen bool) TakeOption {
+	return func(a *TakeAdapter) {
+		a.FailOpen = failOpen
+	}
+}
+
+// WithTakeErrorBackoff 设置 Redis 出错后重试的间隔，默认 100ms。
+func WithTakeErrorBackoff(d time.Duration) TakeOption {
+	return func(a *TakeAdapter) {
+		if d > 0 {
+			a.ErrorBackoff = d
+		}
+	}
+}
+
+// WithTakeErrorHandler 设置 Redis 出错时的回调。
+func WithTakeErrorHandler(fn func(err error)) TakeOption {
+	return func(a *TakeAdapter) {
+		a.OnError = fn
+	}
+}
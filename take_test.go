@@ -0,0 +1,40 @@
+package limiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTakeAdapter(t *testing.T) {
+	client := newSpecClient(t)
+	ctx := context.Background()
+
+	// 容量为 1：相邻两次 Take 严格间隔 1/LeakRate
+	lb := NewLeakyBucketLimiter(client, "take", WithLeakyBucketRate(50), WithLeakyBucketCapacity(1))
+	rl := NewTakeAdapter(lb)
+
+	prev := rl.Take()
+	for i := 0; i < 3; i++ {
+		now := rl.Take()
+		assert.GreaterOrEqual(t, now.Sub(prev), 15*time.Millisecond)
+		prev = now
+	}
+
+	cctx, cancel := context.WithTimeout(ctx, 5*time.Millisecond)
+	defer cancel()
+	_, err := rl.TakeContext(cctx)
+	assert.ErrorIs(t, err, ErrTimeout)
+
+	// Redis 出错时按 FailOpen 处理
+	broken := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1"})
+	defer broken.Close()
+	var errs int
+	failed := NewTakeAdapter(NewLeakyBucketLimiter(broken, "take"),
+		WithTakeFailOpen(true), WithTakeErrorHandler(func(error) { errs++ }))
+	assert.WithinDuration(t, time.Now(), failed.Take(), time.Second)
+	assert.Equal(t, 1, errs)
+}
@@ -0,0 +1,122 @@
+package limiter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Throttle 是面向 Kafka / 消息队列消费者的节流助手：每处理一条（一批）消息前调用 Pace（PaceN），
+// 按限流器的速率阻塞，消费速度自然被压到限额以内，而不必自己拼装 Allow + sleep 的循环：
+//
+//	th := limiter.NewThrottle(l)
+//	for msg := range msgs {
+//		if err := th.Pace(ctx, msg.Tenant); err != nil {
+//			return err
+//		}
+//		handle(msg)
+//	}
+//
+// shardKey 通常取分区、租户等维度，l 可以是分片限流器、KeyedLimiter 等任意 RateShardedLimiter。
+type Throttle struct {
+	l RateShardedLimiter
+
+	// MaxWait 每次 Pace 最多等待多久，默认不限制（只受 ctx 约束）
+	MaxWait time.Duration
+	// MaxBatch PaceN 每次向限流器申请的最大数量，0（默认）表示取限流器的容量。
+	// 一批消息超过容量时会被拆成多次申请，而不是因为永远无法一次满足而卡死。
+	MaxBatch int64
+	// FailOpen 为 true 时限流器出错（例如 Redis 不可用）直接放行，默认 false（返回错误）
+	FailOpen bool
+	// OnError 限流器出错时的回调（可选）
+	OnError func(shardKey string, err error)
+}
+
+// NewThrottle 基于 l 创建消费者节流助手。
+func NewThrottle(l RateShardedLimiter, opts ...ThrottleOption) *Throttle {
+	if l == nil {
+		panic("throttle: limiter is nil")
+	}
+
+	t := &Throttle{
+		l:       l,
+		MaxWait: InfDuration,
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+// Pace 阻塞直到 shardKey 获得 1 个许可，或 ctx 取消 / 超过 MaxWait。
+func (t *Throttle) Pace(ctx context.Context, shardKey string) error {
+	return t.handle(shardKey, t.l.Wait(ctx, shardKey, t.MaxWait))
+}
+
+// PaceN 阻塞直到 shardKey 一共获得 n 个许可，适合一次拉取一批消息的消费者。
+// n 超过 MaxBatch（默认为限流器容量）时分多次申请，每次按限流器给出的时间精确等待；
+// 中途出错或超时返回时，已经申请到的许可不会归还。
+func (t *Throttle) PaceN(ctx context.Context, shardKey string, n int64) error {
+	if n <= 0 {
+		return fmt.Errorf("throttle: n must > 0")
+	}
+
+	batch := t.MaxBatch
+	if batch <= 0 {
+		st, err := t.l.State(ctx, shardKey)
+		if err != nil {
+			return t.handle(shardKey, err)
+		}
+		batch = max(int64(st.Capacity), 1)
+	}
+
+	deadline := time.Now().Add(t.MaxWait)
+	for n > 0 {
+		chunk := min(n, batch)
+		err := waitFor(ctx, shardKey, "throttle", time.Until(deadline), nil, func(ctx context.Context) (Result, error) {
+			return t.allowN(ctx, shardKey, chunk)
+		})
+		if err := t.handle(shardKey, err); err != nil {
+			return err
+		}
+		n -= chunk
+	}
+	return nil
+}
+
+// allowN 申请 n 个许可，被拒绝时根据 State 推算重试等待时间。
+func (t *Throttle) allowN(ctx context.Context, shardKey string, n int64) (Result, error) {
+	if n == 1 {
+		return t.l.AllowWithResult(ctx, shardKey)
+	}
+
+	ok, err := t.l.AllowN(ctx, shardKey, n)
+	if err != nil || ok {
+		return Result{Allowed: ok}, err
+	}
+	st, err := t.l.State(ctx, shardKey)
+	if err != nil {
+		return Result{}, err
+	}
+	// NextAvailableTime 只保证 1 个许可，按缺口与速率估算凑齐 n 个所需的时间，避免反复空转
+	retryAfter := max(time.Until(time.UnixMilli(st.NextAvailableTime)), 0)
+	if st.Rate > 0 && float64(n) > st.Remaining {
+		retryAfter = max(retryAfter, time.Duration((float64(n)-st.Remaining)/st.Rate*float64(time.Second)))
+	}
+	return Result{Limit: st.Capacity, Remaining: st.Remaining, RetryAfter: retryAfter}, nil
+}
+
+// handle 按 FailOpen 处理限流器错误；限流、超时与 ctx 取消总是原样返回。
+func (t *Throttle) handle(shardKey string, err error) error {
+	if err == nil || errors.Is(err, ErrLimiter) || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	if t.OnError != nil {
+		t.OnError(shardKey, err)
+	}
+	if t.FailOpen {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,39 @@
+package limiter
+
+import "time"
+
+// ThrottleOption 为 Throttle 的配置项。
+type ThrottleOption func(*Throttle)
+
+// WithThrottleMaxWait 设置每次 Pace 最多等待多久，默认不限制（只受 ctx 约束）。
+func WithThrottleMaxWait(d time.Duration) ThrottleOption {
+	return func(t *Throttle) {
+		if d > 0 {
+			t.MaxWait = d
+		}
+	}
+}
+
+// WithThrottleMaxBatch 设置 PaceN 每次向限流器申请的最大数量，默认取限流器的容量。
+func WithThrottleMaxBatch(n int64) ThrottleOption {
+	return func(t *Throttle) {
+		if n <= 0 {
+			panic("throttle: max batch must > 0")
+		}
+		t.MaxBatch = n
+	}
+}
+
+// WithThrottleFailOpen 设置限流器出错时是否直接放行，默认 false（返回错误）。
+func WithThrottleFailOpen(failOpen bool) ThrottleOption {
+	return func(t *Throttle) {
+		t.FailOpen = failOpen
+	}
+}
+
+// WithThrottleErrorHandler 设置限流器出错时的回调。
+func WithThrottleErrorHandler(fn func(shardKey string, err error)) ThrottleOption {
+	return func(t *Throttle) {
+		t.OnError = fn
+	}
+}
@@ -0,0 +1,42 @@
+package limiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestThrottle(t *testing.T) {
+	client := newSpecClient(t)
+	ctx := context.Background()
+
+	l := NewShardedTokenBucketLimiter(client, "consumer", WithShardedTokenBucketCount(1),
+		WithShardedTokenBucket(WithTokenBucketRate(100), WithTokenBucketCapacity(10)))
+	th := NewThrottle(l)
+
+	// 一批 25 条超过容量 10，拆成 10 + 10 + 5 依次申请
+	start := time.Now()
+	assert.NoError(t, th.PaceN(ctx, "p1", 25))
+	assert.GreaterOrEqual(t, time.Since(start), 140*time.Millisecond)
+
+	assert.NoError(t, th.Pace(ctx, "p1"))
+	assert.Error(t, th.PaceN(ctx, "p1", 0))
+
+	// 超过 MaxWait 时返回 ErrTimeout
+	assert.NoError(t, l.ResetAll(ctx))
+	short := NewThrottle(l, WithThrottleMaxWait(5*time.Millisecond), WithThrottleMaxBatch(5))
+	assert.NoError(t, short.PaceN(ctx, "p2", 10))
+	assert.ErrorIs(t, short.PaceN(ctx, "p2", 5), ErrTimeout)
+
+	// 限流器出错时按 FailOpen 处理
+	broken := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1"})
+	defer broken.Close()
+	failed := NewShardedTokenBucketLimiter(broken, "consumer")
+	var errs int
+	assert.Error(t, NewThrottle(failed, WithThrottleErrorHandler(func(string, error) { errs++ })).Pace(ctx, "p1"))
+	assert.NoError(t, NewThrottle(failed, WithThrottleFailOpen(true)).PaceN(ctx, "p1", 3))
+	assert.Equal(t, 1, errs)
+}
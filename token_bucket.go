@@ -2,9 +2,10 @@ package limiter
 
 import (
 	"context"
-	"errors"
 	"fmt"
+	"math"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/go-redis/redis/v8"
@@ -18,12 +19,85 @@ import (
 type TokenBucketLimiter struct {
 	client *redis.Client
 
+	// mu 保护 Rate/Capacity 的运行时修改，创建后请通过 SetRate/SetCapacity 修改
+	mu sync.RWMutex
+
 	Key    string // 业务 key，例如 "api:/v1/login"、"user:123"
 	Prefix string // Redis key 前缀，默认 "tbucket"
 
 	Rate     float64       // token 生成速率，单位：token/sec
 	Capacity float64       // 桶容量（最大 token 数）
 	TTL      time.Duration // Redis key 过期时间，建议略大于典型空闲时间
+
+	// TTLJitter TTL 抖动比例（0~1），例如 0.1 表示在 TTL 基础上随机延长 0~10%。
+	// 大量 key 同时创建时，可避免它们在同一时刻集中过期。默认 0（不抖动）。
+	TTLJitter float64
+
+	// WaitJitter Wait 重试前的随机抖动比例（0~1），在计算出的等待时长上随机增加 0~WaitJitter 倍。
+	WaitJitter float64
+	// WaitStrategy Wait 被拒绝后的等待策略（可选），默认按 RetryAfter 等待并叠加 WaitJitter，见 WaitStrategy。
+	WaitStrategy WaitStrategy
+	// Wakeup Pub/Sub 唤醒器（可选）。设置后 ReturnN / Reset 会唤醒本 key 上阻塞中的 Wait，见 Wakeup。
+	Wakeup *Wakeup
+
+	// ClockSkewThreshold 时钟回拨告警阈值：脚本检测到的回拨量超过该值时触发 OnClockSkew。
+	ClockSkewThreshold time.Duration
+	// OnClockSkew 时钟回拨回调（可选）。无论是否设置，脚本都会把回拨的时间钳制到已记录的最大时间戳。
+	OnClockSkew func(key string, skew time.Duration)
+
+	// OverridePrefix 按 key 覆盖配置的 hash 前缀（例如 "limits"），为空表示不开启覆盖模式。
+	// 开启后脚本会优先读取 "<OverridePrefix>:{Key}" 中的 rate / capacity。
+	OverridePrefix string
+
+	// ServerTime 为 true（默认）时脚本使用 Redis TIME 作为当前时间，
+	// 所有应用实例共享同一个时钟源；为 false 时使用本机时钟。
+	ServerTime bool
+
+	// Clock 时钟，默认 SystemClock。
+	Clock Clock
+
+	// Storage 状态的存储方式，默认 StorageString。
+	Storage StorageMode
+
+	// WarmUp 预热时长，0 表示不预热（默认）。
+	// 开启后桶闲置超过 WarmUp 再次使用时会“冷启动”：最多只有 Capacity/3 个 token，
+	// 补充速率从 Rate/3 起在 WarmUp 内线性爬升到 Rate。预热起点记录在 hash 中，
+	// 因此要求 Storage 为 StorageHash。
+	WarmUp time.Duration
+
+	// InitialTokens 新桶（首次使用或 key 过期后重新创建）的初始 token 数，负数表示满桶（默认 -1）。
+	// 满桶允许一个全新的 key 立即突发 Capacity 个请求；对滥用敏感的接口（登录、短信）可以设为 0，
+	// 让新 key 只能按 Rate 逐步获得 token。超过 Capacity 时按 Capacity 处理。
+	InitialTokens float64
+
+	// MaxDebt 允许透支的 token 数，0 表示不透支（默认）。
+	// 开启后 token 不足时仍然放行，token 数最低可以降到 -MaxDebt，之后由 refill 先偿还透支再积累，
+	// 短时突发时延迟敏感的请求不会被拒绝，长期平均速率仍然是 Rate。
+	// 此时 Result.Remaining / LimiterState.Remaining 包含剩余的透支额度，LimiterState.Level 可能为负数。
+	MaxDebt float64
+
+	// MinInterval 同一个 key 两次放行之间的最小间隔，0 表示不限制（默认）。
+	// 与 Rate / Capacity 同时生效，例如“每小时最多 5 条短信，且两条之间至少间隔 60 秒”。
+	// 一次 AllowN 视为一次放行；上次放行时间记录在 hash 中，因此要求 Storage 为 StorageHash，且不能与合并模式同时使用。
+	MinInterval time.Duration
+
+	// StatsTTL 按 key 统计放行 / 拒绝数量时计数器的过期时间（每次写入时刷新），0 表示不统计（默认）。
+	// 计数由限流脚本原子累加到 "<Prefix>:{Key}:stats:allowed" / "denied"，通过 Stats 读取。
+	StatsTTL time.Duration
+
+	// Hooks 判定事件钩子（可选），每次放行 / 拒绝 / 出错时回调，见 Hooks。
+	Hooks Hooks
+
+	// IdempotencyTTL AllowIdempotent 记录请求 ID 判定结果的保留时长，默认 10 分钟。
+	// 应大于上游对同一条消息的最长重试间隔，过期后同一个请求 ID 会被当作新请求重新扣减。
+	IdempotencyTTL time.Duration
+
+	// CoalesceWindow 合并窗口，0 表示不合并（默认）。开启后同一进程内并发的 Allow / Wait
+	// 在窗口内攒成一批，只执行一次脚本，结果在本地分发，见 coalesce。
+	CoalesceWindow time.Duration
+
+	cmu   sync.Mutex     // 保护 batch
+	batch *coalesceBatch // 正在攒批的请求
 }
 
 // NewTokenBucketLimiter 创建一个单桶令牌桶限流器。
@@ -45,17 +119,31 @@ func NewTokenBucketLimiter(
 	}
 
 	tb := &TokenBucketLimiter{
-		client:   client,
-		Key:      key,
-		Prefix:   "tbucket",
-		Rate:     100,             // 默认速率：100 token/sec
-		Capacity: 100,             // 默认容量：100
-		TTL:      2 * time.Second, // 默认 TTL：2 秒
+		client:     client,
+		Key:        key,
+		Prefix:     "tbucket",
+		Rate:       100,             // 默认速率：100 token/sec
+		Capacity:   100,             // 默认容量：100
+		TTL:        2 * time.Second, // 默认 TTL：2 秒
+		ServerTime: true,            // 默认使用 Redis TIME
+		Clock:      SystemClock,
+
+		IdempotencyTTL: 10 * time.Minute,
+		InitialTokens:  -1,
 	}
 
 	for _, opt := range opts {
 		opt(tb)
 	}
+	if tb.WarmUp > 0 && tb.Storage != StorageHash {
+		panic("token bucket: warm-up requires StorageHash")
+	}
+	if tb.MinInterval > 0 && tb.Storage != StorageHash {
+		panic("token bucket: min interval requires StorageHash")
+	}
+	if tb.MinInterval > 0 && tb.CoalesceWindow > 0 {
+		panic("token bucket: min interval cannot be combined with coalesce")
+	}
 	return tb
 }
 
@@ -70,162 +158,484 @@ func (tb *TokenBucketLimiter) tsKey() string {
 	return fmt.Sprintf("%s:{%s}:ts", tb.Prefix, tb.Key)
 }
 
+// stateKey 返回 hash 存储模式下保存 tokens / ts 两个字段的 key。
+func (tb *TokenBucketLimiter) stateKey() string {
+	return fmt.Sprintf("%s:{%s}:state", tb.Prefix, tb.Key)
+}
+
+// stateKeys 返回脚本的 KEYS[1] / KEYS[2]。
+// hash 存储模式下两者为同一个 key，脚本据此改用 hash 字段读写。
+func (tb *TokenBucketLimiter) stateKeys() (string, string) {
+	if tb.Storage == StorageHash {
+		return tb.stateKey(), tb.stateKey()
+	}
+	return tb.tokensKey(), tb.tsKey()
+}
+
+// overrideKey 返回按 key 覆盖配置的 hash key。
+func (tb *TokenBucketLimiter) overrideKey() string {
+	return overrideKey(tb.OverridePrefix, tb.Key)
+}
+
 // Allow 尝试获取 1 个 token。
 func (tb *TokenBucketLimiter) Allow(ctx context.Context) (bool, error) {
 	return tb.AllowN(ctx, 1)
 }
 
+// AllowWithResult 尝试获取 1 个 token，并返回剩余 token 数及重试等待时间。
+func (tb *TokenBucketLimiter) AllowWithResult(ctx context.Context) (Result, error) {
+	return tb.AllowNWithResult(ctx, 1)
+}
+
 // AllowN 尝试一次获取 n 个 token。
 func (tb *TokenBucketLimiter) AllowN(ctx context.Context, n int64) (bool, error) {
+	res, err := tb.AllowNWithResult(ctx, n)
+	if err != nil {
+		return false, err
+	}
+	return res.Allowed, nil
+}
+
+// AllowNWithResult 尝试一次获取 n 个 token，并返回剩余 token 数及重试等待时间。
+func (tb *TokenBucketLimiter) AllowNWithResult(ctx context.Context, n int64) (Result, error) {
+	if n <= 0 {
+		return Result{}, fmt.Errorf("token bucket: n must > 0")
+	}
+	var res Result
+	var err error
+	if tb.CoalesceWindow > 0 {
+		res, err = tb.coalesce(ctx, n)
+	} else {
+		res, err = tb.eval(ctx, n)
+	}
+	fireHooks(ctx, tb.Hooks, "token_bucket", tb.Key, n, res, err)
+	return res, err
+}
+
+// AllowIdempotent 以 requestID 去重地获取 1 个 token，见 AllowNIdempotent。
+func (tb *TokenBucketLimiter) AllowIdempotent(ctx context.Context, requestID string) (Result, error) {
+	return tb.AllowNIdempotent(ctx, requestID, 1)
+}
+
+// AllowNIdempotent 以 requestID 去重地一次获取 n 个 token，用于至少一次投递、会重试同一条消息的场景。
+// 同一个 requestID 第一次被放行后，IdempotencyTTL 内的重试直接返回当时的判定（Result.Duplicate 为 true），
+// 不再重复扣减；判定与记录在同一个脚本中完成，并发的重试也只会扣减一次。
+// 被拒绝的请求不做记录，重试时重新判定。
+func (tb *TokenBucketLimiter) AllowNIdempotent(ctx context.Context, requestID string, n int64) (Result, error) {
+	if requestID == "" {
+		return Result{}, fmt.Errorf("token bucket: request id is empty")
+	}
+	if n <= 0 {
+		return Result{}, fmt.Errorf("token bucket: n must > 0")
+	}
+	res, err := tb.run(ctx, n, requestID)
+	if err != nil || !res.Duplicate {
+		fireHooks(ctx, tb.Hooks, "token_bucket", tb.Key, n, res, err)
+	}
+	return res, err
+}
+
+// Check 判断当前是否能获取 1 个 token，但不消耗。
+func (tb *TokenBucketLimiter) Check(ctx context.Context) (Result, error) {
+	return tb.CheckN(ctx, 1)
+}
+
+// CheckN 判断当前是否能一次获取 n 个 token，但不消耗。
+// 脚本同样会按流逝时间补充令牌并完成判定，只是不回写状态；
+// 放行时 Remaining 为当前桶内的 token 数（未扣除 n）。
+// 预检与随后的 Allow 之间没有原子性保证，只适合用于展示或提前拒绝。
+func (tb *TokenBucketLimiter) CheckN(ctx context.Context, n int64) (Result, error) {
+	if n <= 0 {
+		return Result{}, fmt.Errorf("token bucket: n must > 0")
+	}
+	return tb.eval(ctx, n, 1)
+}
+
+// lendN 从本桶取走 n 个 token，但至少保留 capacity*(1-ratio) 个。
+// 用于分片借用（为本桶自身流量保留）与优先级预留（为更高优先级保留）。
+func (tb *TokenBucketLimiter) lendN(ctx context.Context, n int64, ratio float64) (Result, error) {
+	res, err := tb.eval(ctx, n, 0, ratio)
+	fireHooks(ctx, tb.Hooks, "token_bucket", tb.Key, n, res, err)
+	return res, err
+}
+
+// settle 按最终结果记录一次由多次脚本调用组成的判定（各次调用均不计统计、不触发钩子，例如分片借用）：
+// 开启统计时累加一次计数器，并触发一次钩子。统计只用于观测，写入失败不影响判定。
+func (tb *TokenBucketLimiter) settle(ctx context.Context, n int64, res Result, err error) {
+	if err == nil && tb.StatsTTL > 0 {
+		allowed, denied := int64(0), n
+		if res.Allowed {
+			allowed, denied = n, 0
+		}
+		_ = recordStats(ctx, tb.client, statsBase(tb.Prefix, tb.Key), tb.StatsTTL, allowed, denied)
+	}
+	fireHooks(ctx, tb.Hooks, "token_bucket", tb.Key, n, res, err)
+}
+
+// eval 执行令牌桶脚本，extra 依次作为可选的 dryRun / lend 参数追加到 ARGV。调用方保证 n > 0。
+func (tb *TokenBucketLimiter) eval(ctx context.Context, n int64, extra ...interface{}) (Result, error) {
+	return tb.run(ctx, n, "", extra...)
+}
+
+// run 执行令牌桶脚本，requestID 非空时按请求 ID 去重（见 withIdempotency）。
+func (tb *TokenBucketLimiter) run(ctx context.Context, n int64, requestID string, extra ...interface{}) (Result, error) {
+	return tb.call(n, requestID, true, extra...).run(ctx)
+}
+
+// allowCall 返回一次 AllowN 判定（含钩子）的脚本调用，供 AllowKeys 合并到 pipeline 中执行。
+func (tb *TokenBucketLimiter) allowCall(n int64) (scriptCall, error) {
 	if n <= 0 {
-		return false, fmt.Errorf("token bucket: n must > 0")
+		return scriptCall{}, fmt.Errorf("token bucket: n must > 0")
 	}
+	return tb.call(n, "", true).withHooks(tb.Hooks, "token_bucket", tb.Key, n), nil
+}
 
-	nowMs := float64(time.Now().UnixNano() / 1e6)
+// call 准备一次令牌桶脚本调用，参数含义见 run。
+// stats 为 false 时不在脚本中统计本次判定，由调用方自行记录（见 flushBatch）。
+func (tb *TokenBucketLimiter) call(n int64, requestID string, stats bool, extra ...interface{}) scriptCall {
+	rate, capacity := tb.limits()
+	nowMs := float64(scriptNow(tb.ServerTime, tb.Clock))
 	ttlMs := tb.TTL.Milliseconds()
 
-	res, err := tokenBucketScript.Run(
-		ctx,
-		tb.client,
-		[]string{tb.tokensKey(), tb.tsKey()},
-		nowMs,
-		tb.Rate,
-		tb.Capacity,
-		float64(n),
-		ttlMs,
-	).Result()
-	if err != nil {
-		return false, err
+	valueKey, tsKey := tb.stateKeys()
+	keys := []string{valueKey, tsKey}
+	if tb.OverridePrefix != "" {
+		keys = append(keys, tb.overrideKey())
+	}
+
+	args := []interface{}{nowMs, rate, capacity, float64(n), ttlMs, tb.TTLJitter}
+	// 可选参数 ARGV[7..12] 依次为 dryRun、lend、warmUpMs、initial、debt、minIntervalMs，
+	// 只追加到最后一个使用的参数为止，前面未使用的补默认值
+	opt := []interface{}{0, "", tb.WarmUp.Milliseconds(), "", tb.MaxDebt, tb.MinInterval.Milliseconds()}
+	copy(opt, extra)
+	used := len(extra)
+	if tb.WarmUp > 0 {
+		used = 3
+	}
+	if tb.InitialTokens >= 0 {
+		opt[3] = tb.InitialTokens
+		used = 4
+	}
+	if tb.MaxDebt > 0 {
+		used = 5
+	}
+	if tb.MinInterval > 0 {
+		used = 6
+	}
+	args = append(args, opt[:used]...)
+	nkeys := len(keys)
+	if dryRun := len(extra) > 0 && extra[0] == 1; stats && !dryRun {
+		keys, args = appendStats(keys, args, statsBase(tb.Prefix, tb.Key), tb.StatsTTL, n, false)
+	}
+	nvals := nkeys + 2
+	if requestID != "" {
+		keys, args = appendIdempotency(keys, args, idempotencyKey(tb.Prefix, tb.Key, requestID), tb.IdempotencyTTL)
+		nvals++
+	}
+
+	return scriptCall{
+		client: tb.client,
+		script: tokenBucketScript,
+		keys:   keys,
+		args:   args,
+		parse: func(_ context.Context, res interface{}, err error) (Result, error) {
+			if err != nil {
+				return Result{}, err
+			}
+			vals, ok := scriptInts(res, nvals)
+			if !ok {
+				return Result{}, fmt.Errorf("token bucket: unexpected script result: %#v", res)
+			}
+			capacity := capacity
+			if nkeys > 2 {
+				capacity = float64(vals[4])
+			}
+			duplicate := requestID != "" && vals[nvals-1] == 1
+			if !duplicate {
+				tb.reportClockSkew(vals[3])
+			}
+			return Result{
+				Allowed:    vals[0] == 1,
+				Limit:      capacity,
+				Remaining:  float64(vals[1]),
+				RetryAfter: time.Duration(vals[2]) * time.Millisecond,
+				Duplicate:  duplicate,
+			}, nil
+		},
+	}
+}
+
+// ReturnN 把 n 个 token 归还给令牌桶（不超过容量）。
+// 用于已放行的操作提前中止（例如下游校验失败、请求被取消）时退还额度。
+// 桶不存在（已过期或被 Reset）时视为满桶，不做任何修改。
+func (tb *TokenBucketLimiter) ReturnN(ctx context.Context, n int64) error {
+	if n <= 0 {
+		return fmt.Errorf("token bucket: n must > 0")
 	}
+	_, capacity := tb.limits()
 
-	switch v := res.(type) {
-	case int64:
-		return v == 1, nil
-	case int:
-		return int64(v) == 1, nil
-	default:
-		return false, fmt.Errorf("token bucket: unexpected script result: %#v", res)
+	valueKey, tsKey := tb.stateKeys()
+	keys := []string{valueKey, tsKey}
+	if tb.OverridePrefix != "" {
+		keys = append(keys, tb.overrideKey())
+	}
+	if err := tokenBucketRefundScript.Run(ctx, tb.client, keys, n, capacity).Err(); err != nil {
+		return err
+	}
+	tb.Wakeup.notify(ctx, tb.Key)
+	return nil
+}
+
+// reportClockSkew 在回拨量超过阈值时触发 OnClockSkew 回调。
+func (tb *TokenBucketLimiter) reportClockSkew(skewMs int64) {
+	if tb.OnClockSkew == nil || skewMs <= 0 {
+		return
+	}
+	skew := time.Duration(skewMs) * time.Millisecond
+	if skew > tb.ClockSkewThreshold {
+		tb.OnClockSkew(tb.Key, skew)
 	}
 }
 
 // Wait 阻塞直到成功获取 1 个 token 或 ctx 取消。
-// 实现策略：循环调用 Allow，若被限流则 sleep 一小段时间。
+// 被限流时按脚本计算出的“补足 token 所需时间”精确 sleep 后重试，而不是固定间隔轮询。
 func (tb *TokenBucketLimiter) Wait(ctx context.Context, maxWait time.Duration) error {
-	maxWait = max(maxWait, 0)
-
-	deadline := time.Now().Add(maxWait)
+	return waitForWake(ctx, tb.Key, "token_bucket", maxWait, waitStrategy(tb.WaitStrategy, tb.WaitJitter), tb.Wakeup, tb.AllowWithResult)
+}
 
-	timer := time.NewTimer(time.Second)
-	defer timer.Stop()
-	for {
-		ok, err := tb.Allow(ctx)
-		if err != nil {
-			return err
-		}
-		if ok {
-			return nil
-		}
-		if maxWait == 0 {
-			// 不等待，直接返回限流
-			return ErrLimiter
+// WaitN 阻塞直到一次性获取 n 个 token，或 ctx 取消 / 超过 maxWait。
+// 每次被拒绝时按脚本根据缺口与速率算出的等待时间 sleep，而不是循环调用 AllowN。
+// n 超过桶容量（透支模式下为容量加透支额度）时永远无法满足，直接返回错误。
+func (tb *TokenBucketLimiter) WaitN(ctx context.Context, n int64, maxWait time.Duration) error {
+	return waitForWake(ctx, tb.Key, "token_bucket", maxWait, waitStrategy(tb.WaitStrategy, tb.WaitJitter), tb.Wakeup, func(ctx context.Context) (Result, error) {
+		res, err := tb.AllowNWithResult(ctx, n)
+		if err == nil && !res.Allowed && float64(n) > res.Limit+tb.MaxDebt {
+			return Result{}, fmt.Errorf("token bucket: n must <= capacity")
 		}
+		return res, err
+	})
+}
 
-		now := time.Now()
-		if now.After(deadline) {
-			return ErrTimeout
-		}
-		sleep := 10 * time.Millisecond
-		remain := time.Until(deadline)
-		if sleep > remain {
-			sleep = remain
-		}
-		timer.Reset(sleep)
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		case <-timer.C:
-		}
+// Stats 返回按 key 统计的放行 / 拒绝数量，需要通过 WithTokenBucketStats 开启统计。
+func (tb *TokenBucketLimiter) Stats(ctx context.Context) (LimiterStats, error) {
+	return readStats(ctx, tb.client, statsBase(tb.Prefix, tb.Key))
+}
+
+// Reset 删除 tokens 和 ts 两个 key（hash 存储模式下为一个 key），令牌桶回到初始状态：
+// 默认为满桶，设置了 InitialTokens 时为 InitialTokens 个 token。
+// 两个 key 共享同一个 hash tag，一条 DEL 命令即可原子删除。
+func (tb *TokenBucketLimiter) Reset(ctx context.Context) error {
+	valueKey, tsKey := tb.stateKeys()
+	if err := deleteBucket(ctx, tb.client, valueKey, tsKey); err != nil {
+		return err
 	}
+	tb.Wakeup.notify(ctx, tb.Key)
+	return nil
 }
 
 // State 返回当前令牌桶的状态。
-// 这里会从 Redis 读出 tokens 和 ts，并在本地模拟一次 refill，以获得“理论上的当前 token 数”。
+// tokens / ts（以及覆盖配置、统计计数器）由只读脚本一次读出，得到一致的快照，
+// 再以脚本返回的当前时间（ServerTime 时为 Redis TIME）模拟一次 refill，以获得“理论上的当前 token 数”。
 func (tb *TokenBucketLimiter) State(ctx context.Context) (LimiterState, error) {
-	tokensStr, err := tb.client.Get(ctx, tb.tokensKey()).Result()
-	if errors.Is(err, redis.Nil) {
-		// 桶未初始化，视为“满桶”状态
-		now := time.Now().UnixMilli()
-		return LimiterState{
-			Level:             tb.Capacity,
-			Remaining:         tb.Capacity,
-			Capacity:          tb.Capacity,
-			Rate:              tb.Rate,
-			LastUpdated:       now,
-			NextAvailableTime: now,
-			Type:              "token_bucket",
-			Key:               tb.Key,
-		}, nil
+	keys, args := tb.stateArgs()
+	return tb.stateFrom(parseBucketSnapshot(bucketStateScript.Run(ctx, tb.client, keys, args...).Result()))
+}
+
+// queueState 把 State 的读取脚本排进 pipeline，返回的函数需在 Exec 之后调用。
+// pipeline 中使用 EVAL 而不是 EVALSHA，不会因为脚本尚未加载而失败。
+func (tb *TokenBucketLimiter) queueState(ctx context.Context, pipe redis.Pipeliner) func() (LimiterState, error) {
+	keys, args := tb.stateArgs()
+	cmd := bucketStateScript.Eval(ctx, pipe, keys, args...)
+	return func() (LimiterState, error) {
+		return tb.stateFrom(parseBucketSnapshot(cmd.Result()))
 	}
-	if err != nil {
-		return LimiterState{}, err
+}
+
+// stateArgs 返回读取状态快照的脚本参数。
+func (tb *TokenBucketLimiter) stateArgs() ([]string, []interface{}) {
+	valueKey, tsKey := tb.stateKeys()
+	var overrideKey, statsBaseKey string
+	if tb.OverridePrefix != "" {
+		overrideKey = tb.overrideKey()
 	}
+	if tb.StatsTTL > 0 {
+		statsBaseKey = statsBase(tb.Prefix, tb.Key)
+	}
+	return bucketStateArgs(valueKey, tsKey, "tokens", overrideKey, statsBaseKey, scriptNow(tb.ServerTime, tb.Clock))
+}
 
-	tsStr, err := tb.client.Get(ctx, tb.tsKey()).Result()
+// stateFrom 根据读出的快照与脚本返回的当前时间模拟一次 refill，计算当前状态。
+// 与限流脚本一致：tokens 不存在时视为满桶，ts 不存在时视为当前时间。
+func (tb *TokenBucketLimiter) stateFrom(snap bucketSnapshot, err error) (LimiterState, error) {
 	if err != nil {
 		return LimiterState{}, err
 	}
+	rate, capacity := snap.override.apply(tb.limits())
 
-	tokens, err := strconv.ParseFloat(tokensStr, 64)
-	if err != nil {
-		return LimiterState{}, fmt.Errorf("token bucket: invalid tokens: %v", err)
+	if snap.value == "" {
+		// 桶未初始化，视为初始状态（默认满桶）
+		now := snap.now
+		level := capacity
+		if tb.InitialTokens >= 0 {
+			level = min(tb.InitialTokens, capacity)
+		}
+		remaining := level + tb.MaxDebt
+		next := now
+		if remaining < 1 {
+			next += int64(math.Ceil((1 - remaining) / rate * 1000))
+		}
+		return LimiterState{
+			Level:             level,
+			Remaining:         remaining,
+			Capacity:          capacity,
+			Rate:              rate,
+			LastUpdated:       now,
+			NextAvailableTime: next,
+			Type:              "token_bucket",
+			Key:               tb.Key,
+			AllowedTotal:      snap.stats.Allowed,
+			DeniedTotal:       snap.stats.Denied,
+		}, nil
 	}
-	lastTs, err := strconv.ParseInt(tsStr, 10, 64)
+
+	tokens, err := strconv.ParseFloat(snap.value, 64)
 	if err != nil {
-		return LimiterState{}, fmt.Errorf("token bucket: invalid ts: %v", err)
+		return LimiterState{}, fmt.Errorf("token bucket: invalid tokens: %v", err)
 	}
 
-	now := time.Now()
-	nowMs := now.UnixNano() / 1e6
+	now := time.UnixMilli(snap.now)
+	nowMs := snap.now
+	lastTs := nowMs
+	if snap.ts != "" {
+		lastTs, err = strconv.ParseInt(snap.ts, 10, 64)
+		if err != nil {
+			return LimiterState{}, fmt.Errorf("token bucket: invalid ts: %v", err)
+		}
+	}
 	deltaMs := float64(nowMs - lastTs)
 	if deltaMs < 0 {
 		deltaMs = 0
 	}
 
-	// 在本地模拟 refill
-	refill := (deltaMs * tb.Rate) / 1000
+	// 模拟 refill
+	refill := (deltaMs * rate) / 1000
 	tokens += refill
-	if tokens > tb.Capacity {
-		tokens = tb.Capacity
+	if tokens > capacity {
+		tokens = capacity
 	}
 
-	// 对于令牌桶，我们把“可用 token 数”作为 Level/Remaining
+	// 对于令牌桶，我们把“可用 token 数”作为 Level/Remaining；
+	// 透支模式下 Level 可能为负数，Remaining 包含剩余的透支额度
 	level := tokens
-	if level < 0 {
-		level = 0
-	}
+	remaining := max(tokens+tb.MaxDebt, 0)
 
-	// 下一次可用时间：如果当前 token >= 1，则现在即可。
+	// 下一次可用时间：如果当前还能放行 1 个 token，则现在即可。
 	// 否则需要计算补足到 1 个 token 所需时间。
 	var next time.Time
-	if level >= 1 {
+	if remaining >= 1 {
 		next = now
 	} else {
-		need := 1 - level
-		waitSec := need / tb.Rate
+		need := 1 - remaining
+		waitSec := need / rate
 		if waitSec < 0 {
 			waitSec = 0
 		}
 		next = now.Add(time.Duration(waitSec * float64(time.Second)))
 	}
 
+	// 最小间隔模式：下一次可用时间不早于上次放行时间 + MinInterval
+	if tb.MinInterval > 0 && snap.grant != "" {
+		grant, err := strconv.ParseInt(snap.grant, 10, 64)
+		if err != nil {
+			return LimiterState{}, fmt.Errorf("token bucket: invalid grant: %v", err)
+		}
+		if t := time.UnixMilli(grant).Add(tb.MinInterval); t.After(next) {
+			next = t
+		}
+	}
+
 	return LimiterState{
 		Level:             level,
-		Remaining:         level,
-		Capacity:          tb.Capacity,
-		Rate:              tb.Rate,
+		Remaining:         remaining,
+		Capacity:          capacity,
+		Rate:              rate,
 		LastUpdated:       lastTs,
 		NextAvailableTime: next.UnixMilli(),
 		Type:              "token_bucket",
 		Key:               tb.Key,
+		AllowedTotal:      snap.stats.Allowed,
+		DeniedTotal:       snap.stats.Denied,
 	}, nil
 }
+
+// limits 返回当前生效的速率和容量快照。
+func (tb *TokenBucketLimiter) limits() (rate, capacity float64) {
+	tb.mu.RLock()
+	defer tb.mu.RUnlock()
+	return tb.Rate, tb.Capacity
+}
+
+// SetRate 在运行时修改 token 生成速率（token/sec），对下一次判定立即生效，并发安全。
+// 正在 Wait 的调用方无需重建限流器，会在下一次重试时使用新速率。
+func (tb *TokenBucketLimiter) SetRate(rate float64) error {
+	if rate <= 0 {
+		return fmt.Errorf("token bucket: rate must > 0")
+	}
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	tb.Rate = rate
+	return nil
+}
+
+// SetCapacity 在运行时修改桶容量，并发安全。
+// 容量调小时，桶内多出的 token 会在下一次判定时被脚本截断到新容量。
+func (tb *TokenBucketLimiter) SetCapacity(capacity float64) error {
+	if capacity <= 0 {
+		return fmt.Errorf("token bucket: capacity must > 0")
+	}
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	tb.Capacity = capacity
+	return nil
+}
+
+// SetOverride 为当前 key 写入覆盖配置（例如给大客户单独放宽限额），无需重新部署即可生效。
+// 需要先通过 WithTokenBucketOverrides 开启覆盖模式。
+func (tb *TokenBucketLimiter) SetOverride(ctx context.Context, o LimitOverride) error {
+	if tb.OverridePrefix == "" {
+		return ErrOverrideDisabled
+	}
+	return setOverride(ctx, tb.client, tb.overrideKey(), o)
+}
+
+// ClearOverride 删除当前 key 的覆盖配置，恢复使用默认参数。
+func (tb *TokenBucketLimiter) ClearOverride(ctx context.Context) error {
+	if tb.OverridePrefix == "" {
+		return ErrOverrideDisabled
+	}
+	return tb.client.Del(ctx, tb.overrideKey()).Err()
+}
+
+// GetOverride 读取当前 key 的覆盖配置，不存在时 ok 为 false。
+func (tb *TokenBucketLimiter) GetOverride(ctx context.Context) (o LimitOverride, ok bool, err error) {
+	if tb.OverridePrefix == "" {
+		return LimitOverride{}, false, ErrOverrideDisabled
+	}
+	return getOverride(ctx, tb.client, tb.overrideKey())
+}
+
+// Reconfigure 在一次加锁内同时替换速率和容量，实现 Reconfigurable。
+func (tb *TokenBucketLimiter) Reconfigure(c LimitConfig) error {
+	if err := c.validate(); err != nil {
+		return err
+	}
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	if c.Rate > 0 {
+		tb.Rate = c.Rate
+	}
+	if c.Capacity > 0 {
+		tb.Capacity = c.Capacity
+	}
+	return nil
+}
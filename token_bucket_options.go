@@ -35,6 +35,122 @@ func WithTokenBucketTTL(ttl time.Duration) TokenBucketOption {
 	}
 }
 
+// WithTokenBucketTTLJitter 设置 TTL 抖动比例（TTL 延长 0~ratio），取值范围 [0, 1)。
+// 例如 0.1 表示实际 TTL 落在 [0.9*TTL, 1.1*TTL] 区间内。
+func WithTokenBucketTTLJitter(ratio float64) TokenBucketOption {
+	return func(tb *TokenBucketLimiter) {
+		if ratio < 0 || ratio >= 1 {
+			panic("token bucket: ttl jitter must be in [0, 1)")
+		}
+		tb.TTLJitter = ratio
+	}
+}
+
+// WithTokenBucketClockSkew 设置时钟回拨告警：当调用方时钟落后于 Redis 中记录的时间戳超过 threshold 时，
+// 调用 fn（例如记录日志或上报指标）。回拨本身总会被脚本钳制，不会影响其他客户端的 refill。
+// 默认使用 Redis TIME 时不会出现回拨，仅在 WithTokenBucketServerTime(false) 时有意义。
+func WithTokenBucketClockSkew(threshold time.Duration, fn func(key string, skew time.Duration)) TokenBucketOption {
+	return func(tb *TokenBucketLimiter) {
+		tb.ClockSkewThreshold = max(threshold, 0)
+		tb.OnClockSkew = fn
+	}
+}
+
+// WithTokenBucketWaitJitter 设置 Wait 重试前的随机抖动比例，取值范围 [0, 1]。
+// 大量 goroutine 等待同一个 key 时，抖动可以把它们的重试时间错开。
+func WithTokenBucketWaitJitter(ratio float64) TokenBucketOption {
+	return func(tb *TokenBucketLimiter) {
+		if ratio < 0 || ratio > 1 {
+			panic("token bucket: wait jitter must be in [0, 1]")
+		}
+		tb.WaitJitter = ratio
+	}
+}
+
+// WithTokenBucketWaitStrategy 设置 Wait 被拒绝后的等待策略，例如 ExponentialWait、NewPacedWait。
+// 设置后 WaitJitter 不再生效，抖动由策略自身负责。
+func WithTokenBucketWaitStrategy(s WaitStrategy) TokenBucketOption {
+	return func(tb *TokenBucketLimiter) {
+		tb.WaitStrategy = s
+	}
+}
+
+// WithTokenBucketWakeup 设置 Pub/Sub 唤醒器：ReturnN / Reset 释放额度后立即唤醒等待中的 Wait，
+// 不必等到 RetryAfter。多个限流器可以共享同一个 Wakeup。
+func WithTokenBucketWakeup(w *Wakeup) TokenBucketOption {
+	return func(tb *TokenBucketLimiter) {
+		tb.Wakeup = w
+	}
+}
+
+// WithTokenBucketServerTime 设置是否使用 Redis TIME 作为脚本中的当前时间，默认开启。
+// 关闭后使用本机时钟，各应用实例的时钟偏差会直接影响 refill 与窗口边界。
+func WithTokenBucketServerTime(enabled bool) TokenBucketOption {
+	return func(tb *TokenBucketLimiter) {
+		tb.ServerTime = enabled
+	}
+}
+
+// WithTokenBucketClock 设置时钟，通常用于测试中控制时间。
+// 注入时钟后脚本改用该时钟传入的时间（相当于 WithTokenBucketServerTime(false)）。
+func WithTokenBucketClock(c Clock) TokenBucketOption {
+	return func(tb *TokenBucketLimiter) {
+		if c != nil {
+			tb.Clock = c
+			tb.ServerTime = false
+		}
+	}
+}
+
+// WithTokenBucketStorage 设置状态的存储方式，参见 StorageString / StorageHash。
+// 切换存储方式后，旧方式下的状态不会被迁移，相当于一次 Reset。
+func WithTokenBucketStorage(mode StorageMode) TokenBucketOption {
+	return func(tb *TokenBucketLimiter) {
+		tb.Storage = mode
+	}
+}
+
+// WithTokenBucketWarmUp 开启预热模式：闲置超过 d 后冷启动，速率在 d 内逐步恢复到 Rate。
+// 预热需要 hash 存储，该选项会同时把 Storage 设为 StorageHash。
+func WithTokenBucketWarmUp(d time.Duration) TokenBucketOption {
+	return func(tb *TokenBucketLimiter) {
+		if d > 0 {
+			tb.WarmUp = d
+			tb.Storage = StorageHash
+		}
+	}
+}
+
+// WithTokenBucketInitialTokens 设置新桶的初始 token 数：0 表示空桶，>= Capacity 等价于满桶（默认）。
+// key 过期后重新创建的桶同样从 n 开始。
+func WithTokenBucketInitialTokens(n float64) TokenBucketOption {
+	return func(tb *TokenBucketLimiter) {
+		if n >= 0 {
+			tb.InitialTokens = n
+		}
+	}
+}
+
+// WithTokenBucketDebt 开启透支模式：token 不足时最多透支 debt 个 token，由后续 refill 偿还。
+func WithTokenBucketDebt(debt float64) TokenBucketOption {
+	return func(tb *TokenBucketLimiter) {
+		if debt >= 0 {
+			tb.MaxDebt = debt
+		}
+	}
+}
+
+// WithTokenBucketMinInterval 设置同一个 key 两次放行之间的最小间隔，与速率限制同时生效。
+// 上次放行时间记录在 hash 中，该选项会同时把 Storage 设为 StorageHash。
+func WithTokenBucketMinInterval(d time.Duration) TokenBucketOption {
+	return func(tb *TokenBucketLimiter) {
+		if d > 0 {
+			tb.MinInterval = d
+			tb.Storage = StorageHash
+		}
+	}
+}
+
 // WithTokenBucketPrefix 设置 Redis key 的前缀。
 func WithTokenBucketPrefix(prefix string) TokenBucketOption {
 	return func(tb *TokenBucketLimiter) {
@@ -51,3 +167,49 @@ func WithTokenBucketCustom(fn func(*TokenBucketLimiter)) TokenBucketOption {
 		fn(tb)
 	}
 }
+
+// WithTokenBucketOverrides 开启按 key 覆盖模式：脚本会优先读取 "<prefix>:{key}" hash 中的 rate / capacity。
+// prefix 为空时使用默认前缀 "limits"。
+func WithTokenBucketOverrides(prefix string) TokenBucketOption {
+	return func(tb *TokenBucketLimiter) {
+		if prefix == "" {
+			prefix = "limits"
+		}
+		tb.OverridePrefix = prefix
+	}
+}
+
+// WithTokenBucketStats 开启按 key 统计放行 / 拒绝数量，ttl 为计数器的过期时间（每次写入时刷新）。
+func WithTokenBucketStats(ttl time.Duration) TokenBucketOption {
+	return func(tb *TokenBucketLimiter) {
+		if ttl > 0 {
+			tb.StatsTTL = ttl
+		}
+	}
+}
+
+// WithTokenBucketIdempotencyTTL 设置 AllowIdempotent 记录请求 ID 判定结果的保留时长。
+func WithTokenBucketIdempotencyTTL(ttl time.Duration) TokenBucketOption {
+	return func(tb *TokenBucketLimiter) {
+		if ttl >= time.Millisecond {
+			tb.IdempotencyTTL = ttl
+		}
+	}
+}
+
+// WithTokenBucketCoalesce 开启并发请求合并：window 内同一进程对本 key 的 Allow / Wait 只执行一次脚本。
+// window 通常取 1~5ms，会给每个请求增加最多 window 的延迟。
+func WithTokenBucketCoalesce(window time.Duration) TokenBucketOption {
+	return func(tb *TokenBucketLimiter) {
+		if window >= 0 {
+			tb.CoalesceWindow = window
+		}
+	}
+}
+
+// WithTokenBucketHooks 设置判定事件钩子，用于接入日志、指标或告警。
+func WithTokenBucketHooks(h Hooks) TokenBucketOption {
+	return func(tb *TokenBucketLimiter) {
+		tb.Hooks = h
+	}
+}
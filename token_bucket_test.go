@@ -3,10 +3,13 @@ package limiter
 import (
 	"context"
 	"fmt"
+	"reflect"
+	"strconv"
 	"testing"
 	"time"
 
 	"github.com/agiledragon/gomonkey/v2"
+	"github.com/alicebob/miniredis/v2"
 	"github.com/go-redis/redis/v8"
 	"github.com/go-redis/redismock/v8"
 	"github.com/stretchr/testify/assert"
@@ -20,7 +23,8 @@ func TestTokenBucket_Allow(t *testing.T) {
 		// 使用你的 tokenBucketScript.Hash() 计算脚本 SHA
 		sha := tokenBucketScript.Hash() // 你需要暴露该方法，见下方说明
 
-		nowMs := float64(time.Now().UnixNano() / 1e6)
+		// 默认使用 Redis TIME，传给脚本的 nowMs 为 0
+		nowMs := 0.0
 
 		// 匹配脚本运行参数
 		mock.ExpectEvalSha(
@@ -34,7 +38,8 @@ func TestTokenBucket_Allow(t *testing.T) {
 			100.0, // Capacity
 			1.0,   // Request tokens
 			int64(2000),
-		).SetVal(int64(1))
+			0.0, // TTL jitter
+		).SetVal([]interface{}{int64(1), int64(99), int64(0), int64(0)})
 
 		tb := NewTokenBucketLimiter(
 			db,
@@ -59,26 +64,283 @@ func TestTokenBucket_Allow(t *testing.T) {
 	})
 }
 
+func TestTokenBucket_ServerTime(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	ctx := context.Background()
+
+	serverNow := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	mr.SetTime(serverNow)
+
+	tb := NewTokenBucketLimiter(client, "server-time")
+	ok, err := tb.Allow(ctx)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	ts, _ := mr.Get(tb.tsKey())
+	assert.Equal(t, strconv.FormatInt(serverNow.UnixMilli(), 10), ts)
+
+	local := NewTokenBucketLimiter(client, "local-time", WithTokenBucketServerTime(false))
+	ok, err = local.Allow(ctx)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	ts, _ = mr.Get(local.tsKey())
+	ms, _ := strconv.ParseInt(ts, 10, 64)
+	assert.InDelta(t, time.Now().UnixMilli(), ms, 1000)
+}
+
+func TestTokenBucket_Clock(t *testing.T) {
+	client := newSpecClient(t)
+	ctx := context.Background()
+
+	now := time.UnixMilli(1_700_000_000_000)
+	tb := NewTokenBucketLimiter(client, "clock",
+		WithTokenBucketRate(1), WithTokenBucketCapacity(2), WithTokenBucketTTL(time.Hour),
+		WithTokenBucketClock(ClockFunc(func() time.Time { return now })),
+	)
+
+	ok, _ := tb.AllowN(ctx, 2)
+	assert.True(t, ok)
+	res, err := tb.AllowWithResult(ctx)
+	assert.NoError(t, err)
+	assert.False(t, res.Allowed)
+	assert.Equal(t, time.Second, res.RetryAfter)
+
+	// 时钟前进 1 秒，恰好补充 1 个 token
+	now = now.Add(time.Second)
+	ok, _ = tb.Allow(ctx)
+	assert.True(t, ok)
+	ok, _ = tb.Allow(ctx)
+	assert.False(t, ok)
+
+	// 注入时钟后 redismock 可以精确匹配 nowMs
+	db, mock := redismock.NewClientMock()
+	mtb := NewTokenBucketLimiter(db, "clock",
+		WithTokenBucketClock(ClockFunc(func() time.Time { return now })))
+	mock.ExpectEvalSha(
+		tokenBucketScript.Hash(),
+		[]string{"tbucket:{clock}:tokens", "tbucket:{clock}:ts"},
+		float64(now.UnixMilli()), 100.0, 100.0, 1.0, int64(2000), 0.0,
+	).SetVal([]interface{}{int64(1), int64(99), int64(0), int64(0)})
+	ok, err = mtb.Allow(ctx)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTokenBucket_InitialTokens(t *testing.T) {
+	client := newSpecClient(t)
+	ctx := context.Background()
+
+	now := time.UnixMilli(1_700_000_000_000)
+	clock := ClockFunc(func() time.Time { return now })
+
+	// 空桶起步：新 key 不能突发，只能按速率获得 token
+	empty := NewTokenBucketLimiter(client, "initial-empty",
+		WithTokenBucketRate(2), WithTokenBucketCapacity(10), WithTokenBucketTTL(time.Hour),
+		WithTokenBucketClock(clock), WithTokenBucketInitialTokens(0))
+
+	st, err := empty.State(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, float64(0), st.Level)
+	assert.Equal(t, now.Add(500*time.Millisecond).UnixMilli(), st.NextAvailableTime)
+
+	res, err := empty.AllowWithResult(ctx)
+	assert.NoError(t, err)
+	assert.False(t, res.Allowed)
+	assert.Equal(t, 500*time.Millisecond, res.RetryAfter)
+
+	now = now.Add(500 * time.Millisecond)
+	ok, err := empty.Allow(ctx)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	// 指定初始水位
+	partial := NewTokenBucketLimiter(client, "initial-partial",
+		WithTokenBucketRate(1), WithTokenBucketCapacity(10), WithTokenBucketTTL(time.Hour),
+		WithTokenBucketClock(clock), WithTokenBucketInitialTokens(3))
+	ok, _ = partial.AllowN(ctx, 3)
+	assert.True(t, ok)
+	ok, _ = partial.Allow(ctx)
+	assert.False(t, ok)
+
+	// Reset 回到初始水位而不是满桶
+	assert.NoError(t, partial.Reset(ctx))
+	st, err = partial.State(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, float64(3), st.Level)
+
+	// 超过容量按满桶处理，预热模式下同样生效
+	full := NewTokenBucketLimiter(client, "initial-full",
+		WithTokenBucketCapacity(5), WithTokenBucketClock(clock), WithTokenBucketInitialTokens(100))
+	ok, _ = full.AllowN(ctx, 5)
+	assert.True(t, ok)
+
+	warm := NewTokenBucketLimiter(client, "initial-warm",
+		WithTokenBucketRate(1), WithTokenBucketCapacity(30), WithTokenBucketTTL(time.Hour),
+		WithTokenBucketClock(clock), WithTokenBucketWarmUp(time.Minute), WithTokenBucketInitialTokens(0))
+	ok, _ = warm.Allow(ctx)
+	assert.False(t, ok)
+}
+
+func TestTokenBucket_Debt(t *testing.T) {
+	client := newSpecClient(t)
+	ctx := context.Background()
+
+	now := time.UnixMilli(1_700_000_000_000)
+	tb := NewTokenBucketLimiter(client, "debt",
+		WithTokenBucketRate(1), WithTokenBucketCapacity(2), WithTokenBucketTTL(time.Hour),
+		WithTokenBucketClock(ClockFunc(func() time.Time { return now })), WithTokenBucketDebt(3))
+
+	// 满桶 2 个 token，再透支 3 个
+	res, err := tb.AllowNWithResult(ctx, 4)
+	assert.NoError(t, err)
+	assert.True(t, res.Allowed)
+	assert.Equal(t, float64(1), res.Remaining)
+
+	res, err = tb.AllowWithResult(ctx)
+	assert.NoError(t, err)
+	assert.True(t, res.Allowed)
+	assert.Equal(t, float64(0), res.Remaining)
+
+	st, err := tb.State(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, float64(-3), st.Level)
+	assert.Equal(t, float64(0), st.Remaining)
+
+	// 透支额度用尽：需要先偿还 1 个 token
+	res, err = tb.AllowWithResult(ctx)
+	assert.NoError(t, err)
+	assert.False(t, res.Allowed)
+	assert.Equal(t, time.Second, res.RetryAfter)
+
+	// 长期速率不变：3 秒后偿还完透支，桶内仍为 0 个 token
+	now = now.Add(3 * time.Second)
+	st, err = tb.State(ctx)
+	assert.NoError(t, err)
+	assert.InDelta(t, 0, st.Level, 0.001)
+	assert.InDelta(t, 3, st.Remaining, 0.001)
+
+	assert.ErrorContains(t, tb.WaitN(ctx, 6, time.Second), "capacity")
+}
+
+func TestTokenBucket_MinInterval(t *testing.T) {
+	client := newSpecClient(t)
+	ctx := context.Background()
+
+	now := time.UnixMilli(1_700_000_000_000)
+	// 每小时 5 次，且两次之间至少间隔 60 秒
+	tb := NewTokenBucketLimiter(client, "sms",
+		WithTokenBucketRate(5.0/3600), WithTokenBucketCapacity(5), WithTokenBucketTTL(2*time.Hour),
+		WithTokenBucketClock(ClockFunc(func() time.Time { return now })), WithTokenBucketMinInterval(time.Minute))
+	assert.Equal(t, StorageHash, tb.Storage)
+
+	ok, err := tb.Allow(ctx)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	// 桶内还有 token，但间隔不足
+	now = now.Add(20 * time.Second)
+	res, err := tb.AllowWithResult(ctx)
+	assert.NoError(t, err)
+	assert.False(t, res.Allowed)
+	assert.Equal(t, 40*time.Second, res.RetryAfter)
+
+	// State 的下一次可用时间考虑最小间隔
+	st, err := tb.State(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, now.Add(40*time.Second).UnixMilli(), st.NextAvailableTime)
+
+	// 预检不记录放行时间：预检通过后紧接着的真实请求同样放行
+	now = now.Add(40 * time.Second)
+	res, err = tb.CheckN(ctx, 1)
+	assert.NoError(t, err)
+	assert.True(t, res.Allowed)
+	ok, _ = tb.Allow(ctx)
+	assert.True(t, ok)
+
+	// 速率限制同时生效：5 次用完后按速率等待
+	for i := 0; i < 3; i++ {
+		now = now.Add(time.Minute)
+		ok, _ = tb.Allow(ctx)
+		assert.True(t, ok)
+	}
+	now = now.Add(time.Minute)
+	res, err = tb.AllowWithResult(ctx)
+	assert.NoError(t, err)
+	assert.False(t, res.Allowed)
+	assert.Greater(t, res.RetryAfter, time.Minute)
+
+	assert.Panics(t, func() {
+		NewTokenBucketLimiter(client, "sms", WithTokenBucketMinInterval(time.Minute), WithTokenBucketCoalesce(time.Millisecond))
+	})
+}
+
+func TestTokenBucket_ClockSkew(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	ctx := context.Background()
+
+	// nowMs 依赖当前时间，不参与匹配
+	ignoreNow := func(expected, actual []interface{}) error {
+		actual[5] = expected[5]
+		if !reflect.DeepEqual(expected, actual) {
+			return fmt.Errorf("expected %v, got %v", expected, actual)
+		}
+		return nil
+	}
+
+	var gotKey string
+	var gotSkew time.Duration
+	tb := NewTokenBucketLimiter(
+		db,
+		"skew",
+		WithTokenBucketClockSkew(time.Second, func(key string, skew time.Duration) {
+			gotKey, gotSkew = key, skew
+		}),
+	)
+
+	mock.CustomMatch(ignoreNow).ExpectEvalSha(
+		tokenBucketScript.Hash(),
+		[]string{"tbucket:{skew}:tokens", "tbucket:{skew}:ts"},
+		0.0, 100.0, 100.0, 1.0, int64(2000), 0.0,
+	).SetVal([]interface{}{int64(1), int64(99), int64(0), int64(500)})
+
+	ok, err := tb.Allow(ctx)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "", gotKey, "skew below threshold should not be reported")
+
+	mock.CustomMatch(ignoreNow).ExpectEvalSha(
+		tokenBucketScript.Hash(),
+		[]string{"tbucket:{skew}:tokens", "tbucket:{skew}:ts"},
+		0.0, 100.0, 100.0, 1.0, int64(2000), 0.0,
+	).SetVal([]interface{}{int64(0), int64(0), int64(10), int64(5000)})
+
+	ok, err = tb.Allow(ctx)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, "skew", gotKey)
+	assert.Equal(t, 5*time.Second, gotSkew)
+}
+
 func TestTokenBucket_State(t *testing.T) {
 	db, mock := redismock.NewClientMock()
 	ctx := context.Background()
 
+	keys := []string{"tbucket:{state}:tokens", "tbucket:{state}:ts"}
+	tb := NewTokenBucketLimiter(
+		db,
+		"state",
+		WithTokenBucketRate(100),
+		WithTokenBucketCapacity(100),
+	)
+
 	t.Run("TokenBucket_State_ok", func(t *testing.T) {
 		now := time.Now().UnixMilli()
 
-		// 模拟 tokensKey = "50"
-		mock.ExpectGet("tbucket:{state}:tokens").SetVal("50")
-		// 上次更新时间 tsKey = now
-		mock.ExpectGet("tbucket:{state}:ts").SetVal(
-			fmt.Sprintf("%d", now),
-		)
-
-		tb := NewTokenBucketLimiter(
-			db,
-			"state",
-			WithTokenBucketRate(100),
-			WithTokenBucketCapacity(100),
-		)
+		// 一次脚本调用读出 tokens = 50 与上次更新时间 ts = now
+		mock.ExpectEvalSha(bucketStateScript.Hash(), keys, "tokens", "0", "0", int64(0)).
+			SetVal([]interface{}{"50", fmt.Sprintf("%d", now), nil, nil, nil, nil, nil, now})
 
 		s, err := tb.State(ctx)
 		if err != nil {
@@ -94,14 +356,9 @@ func TestTokenBucket_State(t *testing.T) {
 		}
 	})
 	t.Run("TokenBucket_State_fail", func(t *testing.T) {
-		// 模拟 tokensKey = "50"
-		mock.ExpectGet("tbucket:{state}:tokens").SetErr(redis.Nil)
-		tb := NewTokenBucketLimiter(
-			db,
-			"state",
-			WithTokenBucketRate(100),
-			WithTokenBucketCapacity(100),
-		)
+		// 桶未初始化，视为满桶
+		mock.ExpectEvalSha(bucketStateScript.Hash(), keys, "tokens", "0", "0", int64(0)).
+			SetVal([]interface{}{nil, nil, nil, nil, nil, nil, nil, time.Now().UnixMilli()})
 
 		s, err := tb.State(ctx)
 		if err != nil {
@@ -110,25 +367,99 @@ func TestTokenBucket_State(t *testing.T) {
 		assert.Equal(t, s.Level, float64(100))
 	})
 
-	t.Run("TokenBucket_State_tokens_fail", func(t *testing.T) {
-		// 模拟 tokensKey = "50"
-		mock.ExpectGet("tbucket:{state}:tokens").SetVal("50")
-		// 上次更新时间 tsKey = now
-		mock.ExpectGet("tbucket:{state}:ts").SetErr(redis.Nil)
-
-		tb := NewTokenBucketLimiter(
-			db,
-			"state",
-			WithTokenBucketRate(100),
-			WithTokenBucketCapacity(100),
-		)
+	t.Run("TokenBucket_State_tokens_only", func(t *testing.T) {
+		// 只有 tokens 没有 ts 时与限流脚本一致，视为刚刚更新过
+		mock.ExpectEvalSha(bucketStateScript.Hash(), keys, "tokens", "0", "0", int64(0)).
+			SetVal([]interface{}{"50", nil, nil, nil, nil, nil, nil, time.Now().UnixMilli()})
 
 		s, err := tb.State(ctx)
-		assert.ErrorIs(t, err, redis.Nil)
-		assert.Equal(t, s.Level, float64(0))
+		assert.NoError(t, err)
+		assert.Equal(t, float64(50), s.Level)
+	})
+
+	t.Run("TokenBucket_State_redis_error", func(t *testing.T) {
+		mock.ExpectEvalSha(bucketStateScript.Hash(), keys, "tokens", "0", "0", int64(0)).SetErr(redis.ErrClosed)
+
+		_, err := tb.State(ctx)
+		assert.ErrorIs(t, err, redis.ErrClosed)
+		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 }
 
+func TestTokenBucket_Reset(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	ctx := context.Background()
+
+	tb := NewTokenBucketLimiter(db, "reset")
+	mock.ExpectDel("tbucket:{reset}:tokens", "tbucket:{reset}:ts").SetVal(2)
+	assert.NoError(t, tb.Reset(ctx))
+
+	mock.ExpectDel("tbucket:{reset}:tokens", "tbucket:{reset}:ts").SetErr(redis.ErrClosed)
+	assert.ErrorIs(t, tb.Reset(ctx), redis.ErrClosed)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestShardedTokenBucket_ResetAll(t *testing.T) {
+	client := newSpecClient(t)
+	ctx := context.Background()
+
+	s := NewShardedTokenBucketLimiter(client, "reset", WithShardedTokenBucketCount(4),
+		WithShardedTokenBucket(WithTokenBucketRate(0.001), WithTokenBucketCapacity(4), WithTokenBucketTTL(time.Minute)))
+	for _, k := range []string{"a", "b", "c", "d", "e"} {
+		for {
+			if ok, _ := s.Allow(ctx, k); !ok {
+				break
+			}
+		}
+	}
+
+	assert.NoError(t, s.Reset(ctx, "a"))
+	ok, _ := s.Allow(ctx, "a")
+	assert.True(t, ok)
+
+	assert.NoError(t, s.ResetAll(ctx))
+	keys, _ := client.Keys(ctx, "*").Result()
+	assert.Empty(t, keys)
+}
+
+func TestTokenBucket_SetRate(t *testing.T) {
+	client := newSpecClient(t)
+	ctx := context.Background()
+
+	tb := NewTokenBucketLimiter(client, "set-rate",
+		WithTokenBucketRate(1), WithTokenBucketCapacity(1), WithTokenBucketTTL(time.Minute))
+
+	ok, _ := tb.Allow(ctx)
+	assert.True(t, ok)
+	res, err := tb.AllowWithResult(ctx)
+	assert.NoError(t, err)
+	assert.False(t, res.Allowed)
+
+	// 提高速率后，补足 1 个 token 的等待时间随之缩短
+	assert.NoError(t, tb.SetRate(1000))
+	assert.NoError(t, tb.SetCapacity(10))
+	res, err = tb.AllowNWithResult(ctx, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, float64(10), res.Limit)
+	assert.LessOrEqual(t, res.RetryAfter, 10*time.Millisecond)
+
+	assert.Error(t, tb.SetRate(0))
+	assert.Error(t, tb.SetCapacity(-1))
+
+	// 与并发判定同时修改不应产生数据竞争
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 1; i <= 50; i++ {
+			_ = tb.SetRate(float64(i))
+		}
+	}()
+	for i := 0; i < 50; i++ {
+		_, _ = tb.Allow(ctx)
+	}
+	<-done
+}
+
 func TestTokenBucketLimiter_Wait(t *testing.T) {
 	db, _ := redismock.NewClientMock()
 	ctx := context.Background()
@@ -142,9 +473,9 @@ func TestTokenBucketLimiter_Wait(t *testing.T) {
 	)
 
 	t.Run("TokenBucket_Wait_ok", func(t *testing.T) {
-		patches := gomonkey.ApplyMethodSeq(tb, "AllowN", []gomonkey.OutputCell{
-			{Values: gomonkey.Params{false, nil}},
-			{Values: gomonkey.Params{true, nil}},
+		patches := gomonkey.ApplyMethodSeq(tb, "AllowNWithResult", []gomonkey.OutputCell{
+			{Values: gomonkey.Params{Result{Allowed: false}, nil}},
+			{Values: gomonkey.Params{Result{Allowed: true}, nil}},
 		})
 		defer patches.Reset()
 
@@ -154,8 +485,8 @@ func TestTokenBucketLimiter_Wait(t *testing.T) {
 	})
 
 	t.Run("TokenBucket_Wait_fail", func(t *testing.T) {
-		patches := gomonkey.ApplyMethodSeq(tb, "AllowN", []gomonkey.OutputCell{
-			{Values: gomonkey.Params{false, nil}},
+		patches := gomonkey.ApplyMethodSeq(tb, "AllowNWithResult", []gomonkey.OutputCell{
+			{Values: gomonkey.Params{Result{Allowed: false}, nil}},
 		})
 		defer patches.Reset()
 
@@ -164,3 +495,83 @@ func TestTokenBucketLimiter_Wait(t *testing.T) {
 		assert.Error(t, err, ErrTimeout)
 	})
 }
+
+func TestTTLJitter(t *testing.T) {
+	client := newSpecClient(t)
+	ctx := context.Background()
+
+	const ttl, jitter = time.Minute, 0.5
+	limiters := map[string]func(key string) (RateLimiter, string){
+		"token_bucket": func(key string) (RateLimiter, string) {
+			l := NewTokenBucketLimiter(client, key, WithTokenBucketTTL(ttl), WithTokenBucketTTLJitter(jitter))
+			k, _ := l.stateKeys()
+			return l, k
+		},
+		"leaky_bucket": func(key string) (RateLimiter, string) {
+			l := NewLeakyBucketLimiter(client, key, WithLeakyBucketTTL(ttl), WithLeakyBucketTTLJitter(jitter))
+			k, _ := l.stateKeys()
+			return l, k
+		},
+		"sliding_window": func(key string) (RateLimiter, string) {
+			l := NewSlidingWindowLimiter(client, key, WithSlidingWindowTTL(ttl), WithSlidingWindowTTLJitter(jitter))
+			return l, l.logKey()
+		},
+	}
+
+	for name, newLimiter := range limiters {
+		t.Run(name, func(t *testing.T) {
+			// 抖动只延长 TTL，且不同 key 的 TTL 被打散
+			seen := make(map[time.Duration]struct{})
+			for i := 0; i < 20; i++ {
+				l, key := newLimiter(fmt.Sprintf("jitter:%d", i))
+				_, err := l.Allow(ctx)
+				assert.NoError(t, err)
+
+				pttl, err := client.PTTL(ctx, key).Result()
+				assert.NoError(t, err)
+				assert.GreaterOrEqual(t, pttl, ttl)
+				assert.LessOrEqual(t, pttl, time.Duration(float64(ttl)*(1+jitter)))
+				seen[pttl] = struct{}{}
+			}
+			assert.Greater(t, len(seen), 1)
+		})
+	}
+}
+
+func TestState_ServerTime(t *testing.T) {
+	client := newSpecClient(t)
+	ctx := context.Background()
+
+	// 本机时钟快 1 小时，但判定与 State 都使用 Redis TIME：按本机时钟计算会得到满桶 / 空窗口
+	skewed := ClockFunc(func() time.Time { return time.Now().Add(time.Hour) })
+	type stater interface {
+		AllowN(ctx context.Context, n int64) (bool, error)
+		State(ctx context.Context) (LimiterState, error)
+	}
+	limiters := map[string]stater{
+		"token_bucket": NewTokenBucketLimiter(client, "skew", WithTokenBucketRate(0.001), WithTokenBucketCapacity(5),
+			WithTokenBucketClock(skewed), WithTokenBucketServerTime(true)),
+		"leaky_bucket": NewLeakyBucketLimiter(client, "skew", WithLeakyBucketRate(0.001), WithLeakyBucketCapacity(5),
+			WithLeakyBucketClock(skewed), WithLeakyBucketServerTime(true)),
+		"sliding_window": NewSlidingWindowLimiter(client, "skew", WithSlidingWindowLimit(5), WithSlidingWindowWindow(time.Minute),
+			WithSlidingWindowClock(skewed), WithSlidingWindowServerTime(true)),
+	}
+
+	for name, l := range limiters {
+		t.Run(name, func(t *testing.T) {
+			ok, err := l.AllowN(ctx, 5)
+			assert.NoError(t, err)
+			assert.True(t, ok)
+
+			st, err := l.State(ctx)
+			assert.NoError(t, err)
+			assert.InDelta(t, 0, st.Remaining, 0.01)
+			assert.InDelta(t, time.Now().UnixMilli(), st.LastUpdated, 1000)
+			if name != "leaky_bucket" {
+				// 漏桶的 NextAvailableTime 只在水位超过容量时才推迟
+				assert.Greater(t, st.NextAvailableTime, time.Now().Add(30*time.Second).UnixMilli())
+				assert.Less(t, st.NextAvailableTime, time.Now().Add(30*time.Minute).UnixMilli())
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package limiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenBucketLimiter_WarmUp(t *testing.T) {
+	client := newSpecClient(t)
+	ctx := context.Background()
+
+	now := time.UnixMilli(1_000_000)
+	tb := NewTokenBucketLimiter(client, "warm",
+		WithTokenBucketRate(10), WithTokenBucketCapacity(30), WithTokenBucketTTL(time.Minute),
+		WithTokenBucketWarmUp(3*time.Second),
+		WithTokenBucketClock(ClockFunc(func() time.Time { return now })))
+	assert.Equal(t, StorageHash, tb.Storage)
+
+	// 冷启动：只有 capacity/3 个 token
+	res, err := tb.AllowNWithResult(ctx, 1)
+	assert.NoError(t, err)
+	assert.True(t, res.Allowed)
+	assert.Equal(t, float64(9), res.Remaining)
+
+	// 冷启动速率为 rate/3，补 1 个 token 需要 300ms
+	res, err = tb.AllowNWithResult(ctx, 10)
+	assert.NoError(t, err)
+	assert.False(t, res.Allowed)
+	assert.Equal(t, 300*time.Millisecond, res.RetryAfter)
+
+	// 预热前半段平均速率为 rate/2：1.5s 补 7.5 个
+	now = now.Add(1500 * time.Millisecond)
+	res, err = tb.AllowNWithResult(ctx, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, float64(15), res.Remaining)
+
+	// 预热后半段平均速率为 rate*5/6：1.5s 补 12.5 个
+	now = now.Add(1500 * time.Millisecond)
+	res, err = tb.AllowNWithResult(ctx, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, float64(27), res.Remaining)
+
+	// 预热完成后按满速率补充
+	now = now.Add(200 * time.Millisecond)
+	res, err = tb.AllowNWithResult(ctx, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, float64(28), res.Remaining)
+
+	// 闲置超过预热时长后重新冷启动
+	now = now.Add(10 * time.Second)
+	res, err = tb.AllowNWithResult(ctx, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, float64(9), res.Remaining)
+
+	assert.Panics(t, func() {
+		NewTokenBucketLimiter(client, "warm", WithTokenBucketWarmUp(time.Second), WithTokenBucketStorage(StorageString))
+	})
+}
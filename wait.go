@@ -0,0 +1,239 @@
+package limiter
+
+import (
+	"context"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// WaitStrategy 决定 Wait 被拒绝后，下一次重试前 sleep 多久。
+//
+// 默认策略（RetryAfterWait）按脚本返回的 RetryAfter 精确等待；大量 goroutine 等待同一个 key 时，
+// 它们会在同一时刻被唤醒并一起打到 Redis，可以换成 ExponentialWait 或 PacedWait 把重试错开。
+// 实现需要并发安全：同一个策略会被所有调用 Wait 的 goroutine 共享。
+type WaitStrategy interface {
+	// Delay 返回第 attempt 次（从 0 开始）被拒绝后的等待时长，res 为本次判定结果。
+	Delay(attempt int, res Result) time.Duration
+}
+
+// WaitStrategyFunc 让普通函数实现 WaitStrategy。
+type WaitStrategyFunc func(attempt int, res Result) time.Duration
+
+// Delay 实现 WaitStrategy。
+func (f WaitStrategyFunc) Delay(attempt int, res Result) time.Duration {
+	return f(attempt, res)
+}
+
+// RetryAfterWait 按 RetryAfter 精确等待（至少 1ms），并叠加 [0, Jitter] 比例的随机抖动。
+// 这是各限流器 Wait 的默认策略，Jitter 取自 WaitJitter。
+type RetryAfterWait struct {
+	Jitter float64
+}
+
+// Delay 实现 WaitStrategy。
+func (w RetryAfterWait) Delay(_ int, res Result) time.Duration {
+	// 并发竞争下 RetryAfter 可能为 0，至少等待 1ms，避免空转
+	return jitterDelay(max(res.RetryAfter, time.Millisecond), w.Jitter)
+}
+
+// FixedWait 忽略 RetryAfter，以固定间隔轮询（至少 1ms），并叠加 [0, Jitter] 比例的随机抖动。
+// 适合 RetryAfter 不准确的场景，例如并发限流器的租约被提前 Release。
+type FixedWait struct {
+	Interval time.Duration
+	Jitter   float64
+}
+
+// Delay 实现 WaitStrategy。
+func (w FixedWait) Delay(int, Result) time.Duration {
+	return jitterDelay(max(w.Interval, time.Millisecond), w.Jitter)
+}
+
+// ExponentialWait 指数退避：第 attempt 次等待 Base * 2^attempt（不超过 Max），
+// 但不会早于 RetryAfter，再叠加 [0, Jitter] 比例的随机抖动。
+// 竞争越激烈、被拒绝次数越多的等待者重试得越稀疏。
+type ExponentialWait struct {
+	Base   time.Duration // 初始等待时长，默认 10ms
+	Max    time.Duration // 单次退避上限（RetryAfter 更大时以 RetryAfter 为准），默认 5s
+	Jitter float64
+}
+
+// Delay 实现 WaitStrategy。
+func (w ExponentialWait) Delay(attempt int, res Result) time.Duration {
+	base, maxDelay := w.Base, w.Max
+	if base <= 0 {
+		base = 10 * time.Millisecond
+	}
+	if maxDelay <= 0 {
+		maxDelay = 5 * time.Second
+	}
+
+	d := base << min(attempt, 30)
+	if d <= 0 || d > maxDelay {
+		d = maxDelay
+	}
+	return jitterDelay(max(d, res.RetryAfter), w.Jitter)
+}
+
+// PacedWait 把共享该策略的所有等待者的重试按 Rate 次/秒排队：
+// 每次重试在 RetryAfter 之后、且与上一次排定的重试至少间隔 1/Rate，
+// 保证本进程对 Redis 的重试流量不超过 Rate，即使成千上万个 goroutine 同时在等。
+//
+// 排定的时间点超出 maxWait 时 Wait 会直接返回超时，该时间点不会被释放。
+type PacedWait struct {
+	interval time.Duration
+
+	mu   sync.Mutex
+	next time.Time
+}
+
+// NewPacedWait 创建按 rate 次/秒排队重试的等待策略，rate 必须 > 0。
+func NewPacedWait(rate float64) *PacedWait {
+	if rate <= 0 {
+		panic("paced wait: rate must > 0")
+	}
+	return &PacedWait{interval: time.Duration(float64(time.Second) / rate)}
+}
+
+// Delay 实现 WaitStrategy。
+func (w *PacedWait) Delay(_ int, res Result) time.Duration {
+	now := time.Now()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	at := now.Add(max(res.RetryAfter, time.Millisecond))
+	if at.Before(w.next) {
+		at = w.next
+	}
+	w.next = at.Add(w.interval)
+	return at.Sub(now)
+}
+
+// jitterDelay 在 d 上随机增加 [0, jitter] 比例的时长。
+func jitterDelay(d time.Duration, jitter float64) time.Duration {
+	if jitter > 0 {
+		d += time.Duration(float64(d) * jitter * rand.Float64())
+	}
+	return d
+}
+
+// UntilDeadline 作为 maxWait 传给 Wait 时，等待预算取自 ctx 的截止时间。各限流器 Wait 的 maxWait 约定如下：
+//   - 0：不等待，被拒绝时立即返回匹配 ErrLimiter 的错误
+//   - > 0：最多等待 maxWait，预计等待时间超出时提前返回匹配 ErrTimeout 的错误
+//   - < 0（即 UntilDeadline）：按 ctx 的剩余时间等待；ctx 没有截止时间时一直等到获得许可或 ctx 取消
+const UntilDeadline time.Duration = -1
+
+// ContextMaxWait 把 ctx 的截止时间换算为 maxWait：没有截止时间时返回 InfDuration，已过截止时间时返回 0。
+func ContextMaxWait(ctx context.Context) time.Duration {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return InfDuration
+	}
+	return max(time.Until(deadline), 0)
+}
+
+// WaitContext 阻塞直到 l 放行或 ctx 结束，等待预算取自 ctx 的截止时间，等价于 l.Wait(ctx, UntilDeadline)。
+func WaitContext(ctx context.Context, l RateLimiter) error {
+	return l.Wait(ctx, UntilDeadline)
+}
+
+// resolveMaxWait 把 UntilDeadline（负数）换算为 ctx 的剩余时间。
+func resolveMaxWait(ctx context.Context, maxWait time.Duration) time.Duration {
+	if maxWait < 0 {
+		return ContextMaxWait(ctx)
+	}
+	return maxWait
+}
+
+// waitStrategy 返回限流器生效的等待策略：未配置 WaitStrategy 时按 RetryAfter 等待并叠加 WaitJitter。
+func waitStrategy(s WaitStrategy, jitter float64) WaitStrategy {
+	if s != nil {
+		return s
+	}
+	return RetryAfterWait{Jitter: jitter}
+}
+
+// waitFor 是各限流器 Wait 的公共实现：
+//   - 反复调用 try 尝试获取许可；
+//   - 被拒绝时按 strategy 计算的时长 sleep，nil 表示 RetryAfterWait（按脚本返回的 RetryAfter 精确等待，
+//     不叠加抖动），而不是固定间隔轮询 Redis；
+//   - maxWait 为 0 时不等待，直接返回 *LimitExceededError（匹配 ErrLimiter）；为负数时取 ctx 的剩余时间（见 UntilDeadline）；
+//   - 预计等待时间超出 maxWait 时提前返回 Timeout 为 true 的 *LimitExceededError（同时匹配 ErrTimeout），
+//     不做无意义的等待。
+//
+// key / algorithm 仅用于填充返回的错误。
+func waitFor(
+	ctx context.Context,
+	key, algorithm string,
+	maxWait time.Duration,
+	strategy WaitStrategy,
+	try func(ctx context.Context) (Result, error),
+) error {
+	return waitForWake(ctx, key, algorithm, maxWait, strategy, nil, try)
+}
+
+// waitForWake 与 waitFor 相同，但配置了 Wakeup 时，sleep 期间收到 key 的唤醒消息会提前结束 sleep 立即重试。
+// 第一次被拒绝后才订阅唤醒频道，第一次尝试就放行的 Wait 不产生任何 Pub/Sub 往返；
+// 订阅生效前发出的唤醒消息会错过，此时仍按 strategy 计算的时长重试。
+// 由于额度可能被提前释放，预计等待时间超出 maxWait 时不会提前返回，而是等到 maxWait 用尽。
+// w 为 nil 时等价于 waitFor。
+func waitForWake(
+	ctx context.Context,
+	key, algorithm string,
+	maxWait time.Duration,
+	strategy WaitStrategy,
+	w *Wakeup,
+	try func(ctx context.Context) (Result, error),
+) error {
+	maxWait = resolveMaxWait(ctx, maxWait)
+	deadline := time.Now().Add(maxWait)
+
+	if strategy == nil {
+		strategy = RetryAfterWait{}
+	}
+
+	timer := time.NewTimer(time.Second)
+	defer timer.Stop()
+
+	var wake <-chan struct{}
+	for attempt := 0; ; attempt++ {
+		res, err := try(ctx)
+		if err != nil {
+			return err
+		}
+		if res.Allowed {
+			return nil
+		}
+		if maxWait == 0 {
+			// 不等待，直接返回限流
+			return newLimitExceededError(key, algorithm, res, false)
+		}
+		if w != nil && wake == nil {
+			ch, stop := w.listen(key)
+			defer stop()
+			wake = ch
+		}
+
+		sleep := strategy.Delay(attempt, res)
+		last := false
+		if time.Now().Add(sleep).After(deadline) {
+			if w == nil {
+				return newLimitExceededError(key, algorithm, res, true)
+			}
+			// 额度可能被提前释放，等到 maxWait 用尽前仍有机会被唤醒
+			sleep, last = time.Until(deadline), true
+		}
+		timer.Reset(sleep)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+			if last {
+				return newLimitExceededError(key, algorithm, res, true)
+			}
+		case <-wake:
+			timer.Stop()
+		}
+	}
+}
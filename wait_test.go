@@ -0,0 +1,224 @@
+package limiter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWaitFor(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("WaitFor_sleep_retry_after", func(t *testing.T) {
+		calls := 0
+		start := time.Now()
+		err := waitFor(ctx, "k", "test", time.Second, nil, func(context.Context) (Result, error) {
+			calls++
+			if calls == 1 {
+				return Result{RetryAfter: 50 * time.Millisecond}, nil
+			}
+			return Result{Allowed: true}, nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, 2, calls)
+		assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+	})
+
+	t.Run("WaitFor_no_wait", func(t *testing.T) {
+		err := waitFor(ctx, "k", "test", 0, nil, func(context.Context) (Result, error) {
+			return Result{Remaining: 2, RetryAfter: time.Millisecond}, nil
+		})
+		assert.ErrorIs(t, err, ErrLimiter)
+		assert.NotErrorIs(t, err, ErrTimeout)
+		assert.NotErrorIs(t, err, context.DeadlineExceeded)
+
+		var le *LimitExceededError
+		assert.ErrorAs(t, err, &le)
+		assert.Equal(t, float64(2), le.Remaining)
+		assert.Equal(t, time.Millisecond, le.RetryAfter)
+	})
+
+	t.Run("WaitFor_retry_after_exceeds_budget", func(t *testing.T) {
+		start := time.Now()
+		err := waitFor(ctx, "k", "test", 100*time.Millisecond, nil, func(context.Context) (Result, error) {
+			return Result{RetryAfter: time.Minute}, nil
+		})
+		assert.ErrorIs(t, err, ErrTimeout)
+		assert.ErrorIs(t, err, ErrLimiter)
+		assert.Less(t, time.Since(start), 100*time.Millisecond)
+
+		var le *LimitExceededError
+		assert.ErrorAs(t, err, &le)
+		assert.Equal(t, "k", le.Key)
+		assert.Equal(t, "test", le.Algorithm)
+		assert.Equal(t, time.Minute, le.RetryAfter)
+
+		// 超时同时匹配 context.DeadlineExceeded，并可以取得建议的重试时间
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		retryAfter, ok := IsRetryable(err)
+		assert.True(t, ok)
+		assert.Equal(t, time.Minute, retryAfter)
+	})
+
+	t.Run("WaitFor_ctx_canceled", func(t *testing.T) {
+		cctx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+		defer cancel()
+		err := waitFor(cctx, "k", "test", time.Second, nil, func(context.Context) (Result, error) {
+			return Result{RetryAfter: 500 * time.Millisecond}, nil
+		})
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("WaitFor_until_deadline", func(t *testing.T) {
+		// 预计等待时间超出 ctx 剩余时间：立即返回超时
+		cctx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		err := waitFor(cctx, "k", "test", UntilDeadline, nil, func(context.Context) (Result, error) {
+			return Result{RetryAfter: time.Second}, nil
+		})
+		assert.ErrorIs(t, err, ErrTimeout)
+		assert.Less(t, time.Since(start), 100*time.Millisecond)
+
+		// 没有截止时间时不限制等待预算
+		calls := 0
+		err = waitFor(ctx, "k", "test", UntilDeadline, nil, func(context.Context) (Result, error) {
+			calls++
+			return Result{Allowed: calls > 1, RetryAfter: 10 * time.Millisecond}, nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, 2, calls)
+	})
+}
+
+func TestIsRetryable(t *testing.T) {
+	_, ok := IsRetryable(errors.New("redis down"))
+	assert.False(t, ok)
+	_, ok = IsRetryable(ErrCircuitOpen)
+	assert.False(t, ok)
+
+	d, ok := IsRetryable(fmt.Errorf("wrapped: %w", ErrLimiter))
+	assert.True(t, ok)
+	assert.Equal(t, time.Duration(0), d)
+
+	assert.ErrorIs(t, ErrTimeout, context.DeadlineExceeded)
+	assert.NotErrorIs(t, ErrLimiter, context.DeadlineExceeded)
+}
+
+func TestContextMaxWait(t *testing.T) {
+	assert.Equal(t, InfDuration, ContextMaxWait(context.Background()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	d := ContextMaxWait(ctx)
+	assert.Greater(t, d, 59*time.Second)
+	assert.LessOrEqual(t, d, time.Minute)
+
+	expired, cancel2 := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel2()
+	assert.Equal(t, time.Duration(0), ContextMaxWait(expired))
+}
+
+func TestWaitStrategy(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("WaitStrategy_default", func(t *testing.T) {
+		assert.Equal(t, 50*time.Millisecond, RetryAfterWait{}.Delay(0, Result{RetryAfter: 50 * time.Millisecond}))
+		assert.Equal(t, time.Millisecond, RetryAfterWait{}.Delay(0, Result{}))
+		assert.Equal(t, RetryAfterWait{Jitter: 0.2}, waitStrategy(nil, 0.2))
+	})
+
+	t.Run("WaitStrategy_fixed", func(t *testing.T) {
+		w := FixedWait{Interval: 20 * time.Millisecond}
+		assert.Equal(t, 20*time.Millisecond, w.Delay(3, Result{RetryAfter: time.Minute}))
+	})
+
+	t.Run("WaitStrategy_exponential", func(t *testing.T) {
+		w := ExponentialWait{Base: 10 * time.Millisecond, Max: 50 * time.Millisecond}
+		assert.Equal(t, 10*time.Millisecond, w.Delay(0, Result{}))
+		assert.Equal(t, 40*time.Millisecond, w.Delay(2, Result{}))
+		assert.Equal(t, 50*time.Millisecond, w.Delay(10, Result{}))
+		assert.Equal(t, 50*time.Millisecond, w.Delay(100, Result{}))
+		// 不会早于 RetryAfter
+		assert.Equal(t, time.Second, w.Delay(0, Result{RetryAfter: time.Second}))
+
+		d := ExponentialWait{Base: 10 * time.Millisecond, Jitter: 0.5}.Delay(1, Result{})
+		assert.GreaterOrEqual(t, d, 20*time.Millisecond)
+		assert.LessOrEqual(t, d, 30*time.Millisecond)
+	})
+
+	t.Run("WaitStrategy_paced", func(t *testing.T) {
+		w := NewPacedWait(100)
+		res := Result{RetryAfter: 5 * time.Millisecond}
+		d0, d1, d2 := w.Delay(0, res), w.Delay(0, res), w.Delay(0, res)
+		assert.InDelta(t, float64(5*time.Millisecond), float64(d0), float64(time.Millisecond))
+		// 后续等待者依次错开 1/Rate
+		assert.InDelta(t, float64(15*time.Millisecond), float64(d1), float64(time.Millisecond))
+		assert.InDelta(t, float64(25*time.Millisecond), float64(d2), float64(time.Millisecond))
+		assert.Panics(t, func() { NewPacedWait(0) })
+	})
+
+	t.Run("WaitStrategy_waitFor", func(t *testing.T) {
+		var attempts []int
+		strategy := WaitStrategyFunc(func(attempt int, _ Result) time.Duration {
+			attempts = append(attempts, attempt)
+			return time.Millisecond
+		})
+		calls := 0
+		err := waitFor(ctx, "k", "test", time.Second, strategy, func(context.Context) (Result, error) {
+			calls++
+			return Result{Allowed: calls == 3, RetryAfter: time.Minute}, nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []int{0, 1}, attempts)
+	})
+
+	t.Run("WaitStrategy_limiter", func(t *testing.T) {
+		client := newSpecClient(t)
+		tb := NewTokenBucketLimiter(client, "wait-strategy",
+			WithTokenBucketRate(1), WithTokenBucketCapacity(1),
+			WithTokenBucketWaitStrategy(FixedWait{Interval: 5 * time.Millisecond}))
+		assert.NoError(t, tb.Wait(ctx, time.Second))
+
+		// RetryAfter 约 1s，默认策略会立即放弃；固定间隔轮询则一直重试到 maxWait 用尽
+		start := time.Now()
+		assert.ErrorIs(t, tb.Wait(ctx, 30*time.Millisecond), ErrTimeout)
+		assert.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+	})
+}
+
+func TestWaitN(t *testing.T) {
+	client := newSpecClient(t)
+	ctx := context.Background()
+
+	tb := NewTokenBucketLimiter(client, "wait-n",
+		WithTokenBucketRate(100), WithTokenBucketCapacity(10), WithTokenBucketTTL(time.Minute))
+	ok, _ := tb.AllowN(ctx, 10)
+	assert.True(t, ok)
+
+	// 缺口 5 个 token，按 100 token/s 约需等待 50ms
+	start := time.Now()
+	assert.NoError(t, tb.WaitN(ctx, 5, time.Second))
+	assert.GreaterOrEqual(t, time.Since(start), 40*time.Millisecond)
+	assert.ErrorIs(t, tb.WaitN(ctx, 10, 10*time.Millisecond), ErrTimeout)
+	assert.ErrorContains(t, tb.WaitN(ctx, 11, time.Second), "capacity")
+
+	lb := NewLeakyBucketLimiter(client, "wait-n",
+		WithLeakyBucketRate(100), WithLeakyBucketCapacity(10), WithLeakyBucketTTL(time.Minute))
+	ok, _ = lb.AllowN(ctx, 10)
+	assert.True(t, ok)
+
+	start = time.Now()
+	assert.NoError(t, lb.WaitN(ctx, 5, time.Second))
+	assert.GreaterOrEqual(t, time.Since(start), 40*time.Millisecond)
+	assert.ErrorContains(t, lb.WaitN(ctx, 11, time.Second), "capacity")
+
+	// WaitContext 以 ctx 的截止时间为等待预算
+	tctx, cancel := context.WithTimeout(ctx, 200*time.Millisecond)
+	defer cancel()
+	assert.NoError(t, WaitContext(tctx, tb))
+}
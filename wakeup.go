@@ -0,0 +1,178 @@
+package limiter
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Wakeup 通过 Redis Pub/Sub 在额度被提前释放时唤醒阻塞中的 Wait。
+//
+// Wait 默认按 RetryAfter sleep，但有些额度会早于 RetryAfter 被释放：并发限流器的租约被 Release、
+// 令牌桶 / 漏桶被 ReturnN 归还、限流器被 Reset。配置了 Wakeup 的限流器会在这些操作后向
+// "wake:<key>" 频道发布一条消息，本进程中等待该 key 的 Wait 收到后立即重试，不必等到 RetryAfter，
+// 也不必用很短的间隔轮询 Redis。
+//
+// 每个进程只需一个 Wakeup，由多个限流器共享：它使用一条 Pub/Sub 连接，只订阅当前有人在等待的 key
+// （Wait 第一次被拒绝后才订阅，直接放行的 Wait 不产生 Pub/Sub 往返），最后一个等待者返回后自动退订。唤醒是尽力而为的（消息可能在订阅生效前发出或因断线丢失），
+// Wait 仍然以 RetryAfter 兜底，不会因此多等。使用完毕后需调用 Close。
+type Wakeup struct {
+	client *redis.Client
+
+	// Prefix 频道前缀，默认 "wake"
+	Prefix string
+	// SubscribeTimeout 单次 SUBSCRIBE / UNSUBSCRIBE 的超时时间，默认 1s
+	SubscribeTimeout time.Duration
+
+	mu     sync.Mutex
+	topics map[string]*wakeTopic // key -> 当前的等待者
+	pubsub *redis.PubSub
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewWakeup 创建 Pub/Sub 唤醒器，并启动后台分发协程。
+func NewWakeup(client *redis.Client, opts ...WakeupOption) *Wakeup {
+	if client == nil {
+		panic("wakeup: redis client is nil")
+	}
+
+	w := &Wakeup{
+		client:           client,
+		Prefix:           "wake",
+		SubscribeTimeout: time.Second,
+		topics:           make(map[string]*wakeTopic),
+		done:             make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+
+	// 不带频道创建，连接在第一次订阅时才建立
+	w.pubsub = client.Subscribe(context.Background())
+	go w.dispatch()
+	return w
+}
+
+// channel 返回 key 对应的频道名。
+func (w *Wakeup) channel(key string) string {
+	return fmt.Sprintf("%s:%s", w.Prefix, key)
+}
+
+// Notify 通知所有实例中等待 key 的 Wait 立即重试。w 为 nil 时不做任何事。
+func (w *Wakeup) Notify(ctx context.Context, key string) error {
+	if w == nil {
+		return nil
+	}
+	return w.client.Publish(ctx, w.channel(key), "1").Err()
+}
+
+// notify 在额度被释放后发布唤醒消息。释放本身已经成功，发布失败时等待者仍会按 RetryAfter 重试，因此忽略错误。
+func (w *Wakeup) notify(ctx context.Context, key string) {
+	_ = w.Notify(ctx, key)
+}
+
+// wakeTopic 是一个 key 的等待者集合及其订阅状态。
+// listeners 由 Wakeup.mu 保护；订阅 / 退订在 subMu 下按等待者进出的顺序执行，
+// 网络调用期间只持有该 key 自己的 subMu，不阻塞其他 key 的等待者与消息分发。
+type wakeTopic struct {
+	listeners map[chan struct{}]struct{}
+
+	subMu      sync.Mutex
+	subscribed bool
+}
+
+// listen 注册一个 key 的等待者，返回收到唤醒时可读的 channel 以及注销函数。
+// 同一个 key 的第一个等待者负责订阅频道，最后一个等待者离开时退订。
+// w 为 nil 时返回 nil channel（永远不会就绪）。
+func (w *Wakeup) listen(key string) (<-chan struct{}, func()) {
+	if w == nil {
+		return nil, func() {}
+	}
+
+	ch := make(chan struct{}, 1)
+	w.mu.Lock()
+	t, ok := w.topics[key]
+	if !ok {
+		t = &wakeTopic{listeners: make(map[chan struct{}]struct{})}
+		w.topics[key] = t
+	}
+	t.listeners[ch] = struct{}{}
+	w.mu.Unlock()
+
+	t.subMu.Lock()
+	if !t.subscribed {
+		// 订阅失败时等待者仍会按 RetryAfter 重试
+		w.subscribe(key, w.pubsub.Subscribe)
+		t.subscribed = true
+	}
+	t.subMu.Unlock()
+
+	return ch, func() {
+		w.mu.Lock()
+		delete(t.listeners, ch)
+		w.mu.Unlock()
+
+		t.subMu.Lock()
+		defer t.subMu.Unlock()
+
+		w.mu.Lock()
+		empty := len(t.listeners) == 0
+		w.mu.Unlock()
+		if !empty || !t.subscribed {
+			return
+		}
+		w.subscribe(key, w.pubsub.Unsubscribe)
+		t.subscribed = false
+
+		// 退订期间可能有新的等待者加入，它会在拿到 subMu 后重新订阅，此时 topic 需要保留
+		w.mu.Lock()
+		if len(t.listeners) == 0 && w.topics[key] == t {
+			delete(w.topics, key)
+		}
+		w.mu.Unlock()
+	}
+}
+
+// subscribe 以 SubscribeTimeout 为上限执行一次订阅或退订，调用方需持有对应 key 的 subMu。
+func (w *Wakeup) subscribe(key string, fn func(ctx context.Context, channels ...string) error) {
+	ctx, cancel := context.WithTimeout(context.Background(), w.SubscribeTimeout)
+	defer cancel()
+	_ = fn(ctx, w.channel(key))
+}
+
+// dispatch 把收到的唤醒消息分发给本进程中等待对应 key 的 Wait。
+func (w *Wakeup) dispatch() {
+	defer close(w.done)
+
+	prefix := w.Prefix + ":"
+	for msg := range w.pubsub.Channel() {
+		key := strings.TrimPrefix(msg.Channel, prefix)
+
+		w.mu.Lock()
+		if t, ok := w.topics[key]; ok {
+			for ch := range t.listeners {
+				select {
+				case ch <- struct{}{}:
+				default:
+				}
+			}
+		}
+		w.mu.Unlock()
+	}
+}
+
+// Close 关闭 Pub/Sub 连接并停止分发协程。仍在等待的 Wait 退化为按 RetryAfter 重试。
+func (w *Wakeup) Close() error {
+	var err error
+	w.closeOnce.Do(func() {
+		err = w.pubsub.Close()
+		<-w.done
+	})
+	return err
+}
@@ -0,0 +1,24 @@
+package limiter
+
+import "time"
+
+// WakeupOption 为 Wakeup 的配置项。
+type WakeupOption func(*Wakeup)
+
+// WithWakeupPrefix 设置唤醒频道的前缀，默认 "wake"。
+func WithWakeupPrefix(prefix string) WakeupOption {
+	return func(w *Wakeup) {
+		if prefix != "" {
+			w.Prefix = prefix
+		}
+	}
+}
+
+// WithWakeupSubscribeTimeout 设置单次 SUBSCRIBE / UNSUBSCRIBE 的超时时间，默认 1s。
+func WithWakeupSubscribeTimeout(d time.Duration) WakeupOption {
+	return func(w *Wakeup) {
+		if d > 0 {
+			w.SubscribeTimeout = d
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package limiter
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWakeup(t *testing.T) {
+	client := newSpecClient(t)
+	ctx := context.Background()
+
+	w := NewWakeup(client)
+	defer w.Close()
+
+	subscribed := func(key string, n int64) func() bool {
+		return func() bool {
+			m, _ := client.PubSubNumSub(ctx, w.channel(key)).Result()
+			return m[w.channel(key)] == n
+		}
+	}
+
+	t.Run("Wakeup_release", func(t *testing.T) {
+		l := NewConcurrencyLimiter(client, "export",
+			WithConcurrencyLimit(1), WithConcurrencyLease(time.Minute), WithConcurrencyWakeup(w))
+		token, err := l.Acquire(ctx)
+		assert.NoError(t, err)
+
+		done := make(chan error, 1)
+		start := time.Now()
+		go func() {
+			_, err := l.Wait(ctx, 30*time.Second)
+			done <- err
+		}()
+		assert.Eventually(t, subscribed("export", 1), time.Second, time.Millisecond)
+
+		// 租约一分钟后才到期，Release 后等待者被立即唤醒
+		assert.NoError(t, l.Release(ctx, token))
+		select {
+		case err := <-done:
+			assert.NoError(t, err)
+			assert.Less(t, time.Since(start), 5*time.Second)
+		case <-time.After(5 * time.Second):
+			t.Fatal("waiter was not woken up")
+		}
+
+		// 最后一个等待者返回后退订
+		assert.Eventually(t, subscribed("export", 0), time.Second, time.Millisecond)
+
+		// 没有人释放时等到 maxWait 用尽才超时，而不是按 RetryAfter 提前放弃
+		start = time.Now()
+		_, err = l.Wait(ctx, 30*time.Millisecond)
+		assert.ErrorIs(t, err, ErrTimeout)
+		assert.GreaterOrEqual(t, time.Since(start), 30*time.Millisecond)
+	})
+
+	t.Run("Wakeup_return", func(t *testing.T) {
+		tb := NewTokenBucketLimiter(client, "export",
+			WithTokenBucketRate(0.01), WithTokenBucketCapacity(1), WithTokenBucketWakeup(w))
+		ok, _ := tb.Allow(ctx)
+		assert.True(t, ok)
+
+		done := make(chan error, 1)
+		go func() { done <- tb.Wait(ctx, 200*time.Second) }()
+		assert.Eventually(t, subscribed("export", 1), time.Second, time.Millisecond)
+
+		assert.NoError(t, tb.ReturnN(ctx, 1))
+		select {
+		case err := <-done:
+			assert.NoError(t, err)
+		case <-time.After(5 * time.Second):
+			t.Fatal("waiter was not woken up")
+		}
+	})
+
+	t.Run("Wakeup_concurrent", func(t *testing.T) {
+		// 等待者并发进出：订阅 / 退订按顺序执行，最后一个等待者离开后频道被退订，分发不受阻塞
+		var wg sync.WaitGroup
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, stop := w.listen("busy")
+				assert.NoError(t, w.Notify(ctx, "busy"))
+				stop()
+			}()
+		}
+		wg.Wait()
+		assert.Eventually(t, subscribed("busy", 0), time.Second, time.Millisecond)
+	})
+
+	assert.Equal(t, time.Second, w.SubscribeTimeout)
+	short := NewWakeup(client, WithWakeupSubscribeTimeout(50*time.Millisecond))
+	assert.Equal(t, 50*time.Millisecond, short.SubscribeTimeout)
+	assert.NoError(t, short.Close())
+
+	// 未配置 Wakeup 时不做任何事
+	var nilWakeup *Wakeup
+	assert.NoError(t, nilWakeup.Notify(ctx, "export"))
+	wake, stop := nilWakeup.listen("export")
+	assert.Nil(t, wake)
+	stop()
+}
+
+func TestWakeup_Uncontended(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	ctx := context.Background()
+
+	w := NewWakeup(client)
+	defer w.Close()
+	tb := NewTokenBucketLimiter(client, "export",
+		WithTokenBucketRate(1), WithTokenBucketCapacity(5), WithTokenBucketWakeup(w))
+	// 预热：加载脚本，并记下一次 Allow 产生的命令数（脚本内的 redis.call 也会被计数）
+	_, err := tb.Allow(ctx)
+	assert.NoError(t, err)
+	before := mr.CommandCount()
+	_, err = tb.Allow(ctx)
+	assert.NoError(t, err)
+	allow := mr.CommandCount() - before
+
+	// 第一次尝试就放行的 Wait 与一次 Allow 的命令数相同，不订阅唤醒频道
+	before = mr.CommandCount()
+	assert.NoError(t, tb.Wait(ctx, time.Second))
+	assert.Equal(t, allow, mr.CommandCount()-before)
+
+	w.mu.Lock()
+	assert.Empty(t, w.topics)
+	w.mu.Unlock()
+}
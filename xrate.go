@@ -0,0 +1,205 @@
+package limiter
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+)
+
+// InfDuration 是 Reservation 无法满足时 Delay 返回的时长，与 x/time/rate 的 InfDuration 相同。
+const InfDuration = time.Duration(math.MaxInt64)
+
+// XRateAdapter 把任意 RateLimiter 包装成 golang.org/x/time/rate.Limiter 的常用形状
+// （Allow / AllowN / Wait / WaitN / Reserve / ReserveN / Limit / Burst / Tokens），
+// 让只依赖这些方法的代码无需修改即可切换为基于 Redis 的分布式限流。
+//
+// 与 x/time/rate 的差异：
+//   - 时间以 Redis（或被包装限流器的 Clock）为准，AllowN / ReserveN 的 t 参数被忽略；
+//   - 没有 ctx 的方法（Allow / Reserve / Limit 等）使用 Timeout 作为访问 Redis 的超时，
+//     Redis 出错时按 FailOpen 决定放行还是拒绝，错误通过 OnError 上报；
+//   - 分布式限流器无法预支未来的额度，额度不足时 Reserve 返回 OK() 为 false 的 Reservation，见 Reservation。
+type XRateAdapter struct {
+	l RateLimiter
+
+	// Timeout 没有 ctx 的方法访问 Redis 的超时，默认 1s
+	Timeout time.Duration
+	// FailOpen 为 true 时 Redis 出错放行，默认 false（拒绝）
+	FailOpen bool
+	// OnError Redis 出错时的回调（可选）
+	OnError func(err error)
+}
+
+// NewXRateAdapter 把 l 包装成 x/time/rate.Limiter 的形状。
+func NewXRateAdapter(l RateLimiter, opts ...XRateOption) *XRateAdapter {
+	if l == nil {
+		panic("xrate: limiter is nil")
+	}
+
+	a := &XRateAdapter{
+		l:       l,
+		Timeout: time.Second,
+	}
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a
+}
+
+// Allow 等价于 AllowN(time.Now(), 1)。
+func (a *XRateAdapter) Allow() bool {
+	return a.AllowN(time.Now(), 1)
+}
+
+// AllowN 判断现在能否一次通过 n 个请求，能则立即扣减。t 被忽略。
+func (a *XRateAdapter) AllowN(_ time.Time, n int) bool {
+	return a.ReserveN(time.Now(), n).OK()
+}
+
+// Wait 等价于 WaitN(ctx, 1)。
+func (a *XRateAdapter) Wait(ctx context.Context) error {
+	return a.WaitN(ctx, 1)
+}
+
+// WaitN 阻塞直到一次获得 n 个许可，或 ctx 取消。
+// 与 x/time/rate 一样，ctx 带截止时间且预计等待时间超出截止时间时立即返回错误。
+// n > 1 时要求被包装的限流器实现 WaitN（例如令牌桶、漏桶）。
+func (a *XRateAdapter) WaitN(ctx context.Context, n int) error {
+	maxWait := ContextMaxWait(ctx)
+
+	if n == 1 {
+		return a.l.Wait(ctx, maxWait)
+	}
+	if w, ok := a.l.(interface {
+		WaitN(ctx context.Context, n int64, maxWait time.Duration) error
+	}); ok {
+		return w.WaitN(ctx, int64(n), maxWait)
+	}
+	return fmt.Errorf("xrate: %T does not support WaitN", a.l)
+}
+
+// Reserve 等价于 ReserveN(time.Now(), 1)。
+func (a *XRateAdapter) Reserve() *Reservation {
+	return a.ReserveN(time.Now(), 1)
+}
+
+// ReserveN 尝试立即扣减 n 个许可，返回对应的 Reservation。t 被忽略。
+func (a *XRateAdapter) ReserveN(_ time.Time, n int) *Reservation {
+	ctx, cancel := context.WithTimeout(context.Background(), a.Timeout)
+	defer cancel()
+
+	res, err := a.allowN(ctx, int64(n))
+	if err != nil {
+		if a.OnError != nil {
+			a.OnError(err)
+		}
+		return &Reservation{ok: a.FailOpen}
+	}
+	if !res.Allowed {
+		return &Reservation{retryAfter: res.RetryAfter}
+	}
+	return &Reservation{ok: true, a: a, n: int64(n)}
+}
+
+// allowN 尽量通过一次脚本调用同时拿到判定结果与 RetryAfter。
+func (a *XRateAdapter) allowN(ctx context.Context, n int64) (Result, error) {
+	if l, ok := a.l.(interface {
+		AllowNWithResult(ctx context.Context, n int64) (Result, error)
+	}); ok {
+		return l.AllowNWithResult(ctx, n)
+	}
+	if n == 1 {
+		return a.l.AllowWithResult(ctx)
+	}
+	ok, err := a.l.AllowN(ctx, n)
+	return Result{Allowed: ok}, err
+}
+
+// Limit 返回每秒生成的许可数（来自 State 的 Rate），读取失败时返回 0。
+func (a *XRateAdapter) Limit() float64 {
+	return a.state().Rate
+}
+
+// Burst 返回一次最多能通过的许可数（来自 State 的 Capacity），读取失败时返回 0。
+func (a *XRateAdapter) Burst() int {
+	return int(a.state().Capacity)
+}
+
+// Tokens 返回当前剩余的许可数（来自 State 的 Remaining），读取失败时返回 0。
+func (a *XRateAdapter) Tokens() float64 {
+	return a.state().Remaining
+}
+
+// state 读取被包装限流器的状态，出错时上报并返回零值。
+func (a *XRateAdapter) state() LimiterState {
+	ctx, cancel := context.WithTimeout(context.Background(), a.Timeout)
+	defer cancel()
+
+	st, err := a.l.State(ctx)
+	if err != nil && a.OnError != nil {
+		a.OnError(err)
+	}
+	return st
+}
+
+// Reservation 对应 x/time/rate.Reservation。
+//
+// 分布式限流器无法像 x/time/rate 那样预支未来的额度：额度充足时许可已经扣减，OK 为 true、Delay 为 0；
+// 额度不足时不扣减任何额度，OK 为 false、Delay 为 InfDuration，RetryAfter 给出建议的重试时间。
+type Reservation struct {
+	ok         bool
+	retryAfter time.Duration
+
+	a *XRateAdapter
+	n int64
+}
+
+// OK 返回是否已经获得许可。
+func (r *Reservation) OK() bool {
+	return r.ok
+}
+
+// Delay 等价于 DelayFrom(time.Now())。
+func (r *Reservation) Delay() time.Duration {
+	return r.DelayFrom(time.Now())
+}
+
+// DelayFrom 已获得许可时返回 0，否则返回 InfDuration。
+func (r *Reservation) DelayFrom(time.Time) time.Duration {
+	if r.ok {
+		return 0
+	}
+	return InfDuration
+}
+
+// RetryAfter 返回未获得许可时建议的重试等待时长。
+func (r *Reservation) RetryAfter() time.Duration {
+	return r.retryAfter
+}
+
+// Cancel 等价于 CancelAt(time.Now())。
+func (r *Reservation) Cancel() {
+	r.CancelAt(time.Now())
+}
+
+// CancelAt 归还已获得的许可，要求被包装的限流器实现 ReturnN（例如令牌桶、漏桶），否则不做任何事。
+// 多次调用只会归还一次。
+func (r *Reservation) CancelAt(time.Time) {
+	if !r.ok || r.a == nil {
+		return
+	}
+	a := r.a
+	r.a = nil
+
+	l, ok := a.l.(interface {
+		ReturnN(ctx context.Context, n int64) error
+	})
+	if !ok {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), a.Timeout)
+	defer cancel()
+	if err := l.ReturnN(ctx, r.n); err != nil && a.OnError != nil {
+		a.OnError(err)
+	}
+}
@@ -0,0 +1,29 @@
+package limiter
+
+import "time"
+
+// XRateOption 为 XRateAdapter 的配置项。
+type XRateOption func(*XRateAdapter)
+
+// WithXRateTimeout 设置没有 ctx 的方法（Allow / Reserve / Limit 等）访问 Redis 的超时，默认 1s。
+func WithXRateTimeout(d time.Duration) XRateOption {
+	return func(a *XRateAdapter) {
+		if d > 0 {
+			a.Timeout = d
+		}
+	}
+}
+
+// WithXRateFailOpen 设置 Redis 出错时是否放行，默认 false（拒绝）。
+func WithXRateFailOpen(failOpen bool) XRateOption {
+	return func(a *XRateAdapter) {
+		a.FailOpen = failOpen
+	}
+}
+
+// WithXRateErrorHandler 设置 Redis 出错时的回调。
+func WithXRateErrorHandler(fn func(err error)) XRateOption {
+	return func(a *XRateAdapter) {
+		a.OnError = fn
+	}
+}
@@ -0,0 +1,61 @@
+package limiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestXRateAdapter(t *testing.T) {
+	client := newSpecClient(t)
+	ctx := context.Background()
+
+	tb := NewTokenBucketLimiter(client, "xrate", WithTokenBucketRate(100), WithTokenBucketCapacity(5))
+	a := NewXRateAdapter(tb)
+
+	assert.Equal(t, float64(100), a.Limit())
+	assert.Equal(t, 5, a.Burst())
+
+	assert.True(t, a.Allow())
+	assert.True(t, a.AllowN(time.Now(), 4))
+	assert.False(t, a.AllowN(time.Now(), 5))
+
+	r := a.Reserve()
+	assert.False(t, r.OK())
+	assert.Equal(t, InfDuration, r.Delay())
+	assert.Greater(t, r.RetryAfter(), time.Duration(0))
+
+	// 预计等待超出 ctx 截止时间时立即返回错误
+	cctx, cancel := context.WithTimeout(ctx, 5*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, a.WaitN(cctx, 5), ErrTimeout)
+
+	assert.NoError(t, a.Wait(ctx))
+	assert.NoError(t, a.WaitN(ctx, 2))
+
+	// Cancel 归还已获得的许可，多次调用只归还一次
+	assert.NoError(t, tb.Reset(ctx))
+	r = a.ReserveN(time.Now(), 3)
+	assert.True(t, r.OK())
+	assert.Zero(t, r.Delay())
+	assert.InDelta(t, 2, a.Tokens(), 0.5)
+	r.Cancel()
+	r.Cancel()
+	assert.InDelta(t, 5, a.Tokens(), 0.5)
+
+	// 不支持 WaitN 的限流器
+	sw := NewSlidingWindowLimiter(client, "xrate", WithSlidingWindowLimit(5))
+	assert.ErrorContains(t, NewXRateAdapter(sw).WaitN(ctx, 2), "does not support WaitN")
+
+	// Redis 出错时按 FailOpen 决定是否放行
+	broken := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1"})
+	defer broken.Close()
+	var errs int
+	failed := NewTokenBucketLimiter(broken, "xrate")
+	assert.False(t, NewXRateAdapter(failed, WithXRateErrorHandler(func(error) { errs++ })).Allow())
+	assert.True(t, NewXRateAdapter(failed, WithXRateFailOpen(true)).Allow())
+	assert.Equal(t, 1, errs)
+}